@@ -0,0 +1,41 @@
+#!/bin/bash
+# Anvil bootstrap - set up a brand-new Mac in one step
+#
+#   curl -fsSL https://github.com/0xjuanma/anvil/releases/latest/download/bootstrap.sh | ANVIL_CONFIG_REPO=username/dotfiles ANVIL_PROFILE=work bash
+#
+# Generated by 'anvil bootstrap'. Values below may be overridden through the environment.
+
+set -e
+
+ANVIL_CONFIG_REPO="${ANVIL_CONFIG_REPO:-}"
+ANVIL_CONFIG_BRANCH="${ANVIL_CONFIG_BRANCH:-main}"
+ANVIL_PROFILE="${ANVIL_PROFILE:-}"
+
+if [ -z "$ANVIL_CONFIG_REPO" ]; then
+    echo "❌ ANVIL_CONFIG_REPO is required (e.g. ANVIL_CONFIG_REPO=username/dotfiles)"
+    exit 1
+fi
+
+# Run every anvil prompt non-interactively
+export ANVIL_ASSUME_YES=true
+
+echo "🔨 Installing anvil..."
+curl -fsSL https://github.com/0xjuanma/anvil/releases/latest/download/install.sh | bash
+
+ANVIL_BIN="$(command -v anvil || echo /usr/local/bin/anvil)"
+
+echo "🔨 Initializing anvil with $ANVIL_CONFIG_REPO..."
+"$ANVIL_BIN" init --config-repo "$ANVIL_CONFIG_REPO" --branch "$ANVIL_CONFIG_BRANCH"
+
+echo "🔨 Pulling settings from $ANVIL_CONFIG_REPO..."
+"$ANVIL_BIN" config pull anvil
+"$ANVIL_BIN" config sync
+
+if [ -n "$ANVIL_PROFILE" ]; then
+    echo "🔨 Provisioning profile '$ANVIL_PROFILE'..."
+    "$ANVIL_BIN" provision "$ANVIL_PROFILE"
+else
+    echo "ℹ️  No ANVIL_PROFILE given, run 'anvil provision <profile>' to finish setup"
+fi
+
+echo "✅ Bootstrap complete"
@@ -0,0 +1,95 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package alias
+
+import (
+	"fmt"
+	"strings"
+
+	importcmd "github.com/0xjuanma/anvil/cmd/config/import"
+	"github.com/0xjuanma/anvil/cmd/config/pull"
+	"github.com/0xjuanma/anvil/cmd/config/push"
+	"github.com/0xjuanma/anvil/cmd/config/sync"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// Top-level shortcuts for the most common config subcommands
+var (
+	PullCmd   = newShortcutCmd(pull.PullCmd)
+	PushCmd   = newShortcutCmd(push.PushCmd)
+	SyncCmd   = newShortcutCmd(sync.SyncCmd)
+	ImportCmd = newShortcutCmd(importcmd.ImportCmd)
+)
+
+// reservedCommands are added by cobra at execution time and can't be overridden by aliases
+var reservedCommands = []string{"help", "completion"}
+
+// newShortcutCmd creates a top-level command that delegates to a config subcommand
+func newShortcutCmd(target *cobra.Command) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   target.Use,
+		Short: fmt.Sprintf("Shortcut for 'anvil config %s'", target.Name()),
+		Long:  target.Long,
+		Args:  target.Args,
+		Run:   target.Run,
+	}
+
+	// Share the target's flags so its Run reads the same values
+	cmd.Flags().AddFlagSet(target.Flags())
+	return cmd
+}
+
+// ExpandUserAlias expands a user-defined alias from settings.yaml into its full anvil invocation.
+// Built-in commands always take precedence, and aliases are only expanded one level deep.
+func ExpandUserAlias(root *cobra.Command, args []string) ([]string, bool) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") || isBuiltInCommand(root, args[0]) {
+		return args, false
+	}
+
+	aliases, err := config.GetAliases()
+	if err != nil {
+		return args, false
+	}
+
+	expansion, exists := aliases[args[0]]
+	if !exists {
+		return args, false
+	}
+
+	expanded := strings.Fields(strings.TrimPrefix(strings.TrimSpace(expansion), root.Name()+" "))
+	if len(expanded) == 0 {
+		return args, false
+	}
+
+	return append(expanded, args[1:]...), true
+}
+
+// isBuiltInCommand checks if a name matches a registered command or one of its aliases
+func isBuiltInCommand(root *cobra.Command, name string) bool {
+	for _, reserved := range reservedCommands {
+		if name == reserved {
+			return true
+		}
+	}
+	for _, cmd := range root.Commands() {
+		if cmd.Name() == name || cmd.HasAlias(name) {
+			return true
+		}
+	}
+	return false
+}
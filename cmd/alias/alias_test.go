@@ -0,0 +1,139 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package alias
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// newTestRoot creates a root command with the built-in commands the tests expand around
+func newTestRoot() *cobra.Command {
+	root := &cobra.Command{Use: "anvil"}
+	root.AddCommand(
+		&cobra.Command{Use: "install", Run: func(*cobra.Command, []string) {}},
+		&cobra.Command{Use: "doctor", Aliases: []string{"dr"}, Run: func(*cobra.Command, []string) {}},
+	)
+	return root
+}
+
+// setupAliases writes settings.yaml with the given aliases section to a temp HOME
+func setupAliases(t *testing.T, aliases string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.MkdirAll(filepath.Join(home, ".anvil"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	settings := "version: \"1\"\naliases:\n" + aliases
+	if err := os.WriteFile(filepath.Join(home, ".anvil", "settings.yaml"), []byte(settings), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config.InvalidateConfigCache()
+	t.Cleanup(config.InvalidateConfigCache)
+}
+
+func TestExpandUserAlias(t *testing.T) {
+	setupAliases(t, `  dev: "install dev --concurrent"
+  fix: "anvil doctor --fix"
+  install: "install essentials"
+  dr: "install dr"
+  nested: "dev"
+  empty: "  "
+`)
+	root := newTestRoot()
+
+	for _, tc := range []struct {
+		name         string
+		args         []string
+		want         []string
+		wantExpanded bool
+	}{
+		{name: "no args", args: nil, want: nil},
+		{name: "flag first", args: []string{"--version"}, want: []string{"--version"}},
+		{name: "expands with extra args", args: []string{"dev", "--dry-run"}, want: []string{"install", "dev", "--concurrent", "--dry-run"}, wantExpanded: true},
+		{name: "strips the root name", args: []string{"fix"}, want: []string{"doctor", "--fix"}, wantExpanded: true},
+		{name: "built-in command wins", args: []string{"install", "git"}, want: []string{"install", "git"}},
+		{name: "built-in alias wins", args: []string{"dr"}, want: []string{"dr"}},
+		{name: "expands one level only", args: []string{"nested"}, want: []string{"dev"}, wantExpanded: true},
+		{name: "empty expansion", args: []string{"empty"}, want: []string{"empty"}},
+		{name: "unknown name", args: []string{"deploy"}, want: []string{"deploy"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, expanded := ExpandUserAlias(root, tc.args)
+			if !reflect.DeepEqual(got, tc.want) || expanded != tc.wantExpanded {
+				t.Errorf("ExpandUserAlias(%v) = %v, %t, want %v, %t", tc.args, got, expanded, tc.want, tc.wantExpanded)
+			}
+		})
+	}
+}
+
+func TestIsBuiltInCommand(t *testing.T) {
+	root := newTestRoot()
+	for name, want := range map[string]bool{
+		"install":    true,
+		"doctor":     true,
+		"dr":         true,
+		"help":       true,
+		"completion": true,
+		"dev":        false,
+		"":           false,
+	} {
+		if got := isBuiltInCommand(root, name); got != want {
+			t.Errorf("isBuiltInCommand(%q) = %t, want %t", name, got, want)
+		}
+	}
+}
+
+func TestNewShortcutCmd(t *testing.T) {
+	var got bool
+	target := &cobra.Command{
+		Use:  "pull [app-name]",
+		Long: "Pull configs",
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			got, _ = cmd.Flags().GetBool("dry-run")
+		},
+	}
+	target.Flags().Bool("dry-run", false, "Show what would be pulled")
+
+	shortcut := newShortcutCmd(target)
+	if shortcut.Use != target.Use || shortcut.Long != target.Long || shortcut.Short != "Shortcut for 'anvil config pull'" {
+		t.Errorf("shortcut = %q, %q, %q", shortcut.Use, shortcut.Short, shortcut.Long)
+	}
+
+	shortcut.SetArgs([]string{"zsh", "--dry-run"})
+	if err := shortcut.Execute(); err != nil {
+		t.Fatalf("Execute() failed: %v", err)
+	}
+	if !got {
+		t.Error("the shortcut's --dry-run did not reach the target's Run")
+	}
+	if value, _ := target.Flags().GetBool("dry-run"); !value {
+		t.Error("the shortcut does not share the target's flag values")
+	}
+
+	shortcut.SetArgs([]string{"zsh", "nvim"})
+	if err := shortcut.Execute(); err == nil {
+		t.Error("the shortcut should keep the target's argument validation")
+	}
+}
@@ -0,0 +1,98 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bootstrap
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/0xjuanma/anvil/internal/audit"
+	"github.com/0xjuanma/anvil/internal/bootstrap"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/palantir"
+	"github.com/spf13/cobra"
+)
+
+// BootstrapCmd represents the bootstrap command
+var BootstrapCmd = &cobra.Command{
+	Use:   "bootstrap",
+	Short: "Generate a one-liner that sets up a new Mac with anvil",
+	Long:  constants.BOOTSTRAP_COMMAND_LONG_DESCRIPTION,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runBootstrapCommand(cmd); err != nil {
+			palantir.GetGlobalOutputHandler().PrintError("Bootstrap failed: %v", err)
+			os.Exit(errors.ExitCode(err))
+		}
+	},
+}
+
+// runBootstrapCommand prints the bootstrap one-liner or writes the script to a file
+func runBootstrapCommand(cmd *cobra.Command) error {
+	repo, _ := cmd.Flags().GetString("repo")
+	branch, _ := cmd.Flags().GetString("branch")
+	profile, _ := cmd.Flags().GetString("profile")
+	output, _ := cmd.Flags().GetString("output")
+
+	opts := bootstrap.Options{Repo: repo, Branch: branch, Profile: profile}
+	script, err := bootstrap.GenerateScript(opts)
+	if err != nil {
+		return errors.NewValidationError(constants.OpBootstrap, "generate-script", err)
+	}
+
+	o := palantir.GetGlobalOutputHandler()
+
+	if output == "-" {
+		fmt.Print(script)
+		return nil
+	}
+
+	if output != "" {
+		if audit.IsEnabled() {
+			o.PrintInfo("Audit mode - would write bootstrap script to %s", output)
+			audit.Record("bootstrap", "write-script", output, "")
+			return nil
+		}
+		if err := os.WriteFile(output, []byte(script), 0755); err != nil {
+			return errors.NewFileSystemError(constants.OpBootstrap, "write-script", err)
+		}
+		o.PrintSuccess(fmt.Sprintf("Bootstrap script written to %s", output))
+		o.PrintInfo("Host it anywhere and run: curl -fsSL <url> | bash")
+		return nil
+	}
+
+	o.PrintHeader("Bootstrap a New Mac")
+	o.PrintInfo("Run this on the new machine:")
+	fmt.Println()
+	fmt.Printf("  %s\n", bootstrap.OneLiner(opts))
+	fmt.Println()
+	o.PrintInfo("It installs anvil, runs init with your config repository, syncs %s and provisions the profile", constants.ANVIL_CONFIG_FILE)
+	o.PrintInfo("Use --output to write a script with these values baked in")
+	if profile == "" {
+		o.PrintWarning("No --profile given, the script will stop after syncing settings")
+	}
+	o.PrintInfo("Private config repositories need GITHUB_TOKEN set or SSH keys on the new machine")
+
+	return nil
+}
+
+func init() {
+	BootstrapCmd.Flags().String("repo", "", "Config repository to use (e.g. username/dotfiles)")
+	BootstrapCmd.Flags().String("branch", "", "Branch of the config repository (default: main)")
+	BootstrapCmd.Flags().String("profile", "", "Provisioning profile to apply")
+	BootstrapCmd.Flags().StringP("output", "o", "", "Write the script to a file instead of printing the one-liner ('-' for stdout)")
+}
@@ -21,6 +21,8 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/0xjuanma/anvil/internal/audit"
+	"github.com/0xjuanma/anvil/internal/brew"
 	"github.com/0xjuanma/anvil/internal/constants"
 	"github.com/0xjuanma/anvil/internal/errors"
 	"github.com/0xjuanma/anvil/internal/system"
@@ -30,9 +32,11 @@ import (
 )
 
 var CleanCmd = &cobra.Command{
-	Use:   "clean",
-	Short: "Clean all content inside .anvil directories",
-	Long:  constants.CLEAN_COMMAND_LONG_DESCRIPTION,
+	Use:       "clean [dotfiles]",
+	Short:     "Clean all content inside .anvil directories",
+	Long:      constants.CLEAN_COMMAND_LONG_DESCRIPTION,
+	Args:      cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+	ValidArgs: []string{dotfilesTarget},
 	Run: func(cmd *cobra.Command, args []string) {
 		if err := runCleanCommand(cmd, args); err != nil {
 			palantir.GetGlobalOutputHandler().PrintError("Clean failed: %v", err)
@@ -46,9 +50,37 @@ func runCleanCommand(cmd *cobra.Command, args []string) error {
 	// Get command flags
 	dryRun, _ := cmd.Flags().GetBool("dry-run")
 	force, _ := cmd.Flags().GetBool("force")
+	compact, _ := cmd.Flags().GetBool("compact")
+	stale, _ := cmd.Flags().GetBool("stale")
 	output := palantir.GetGlobalOutputHandler()
+
+	dotfilesOnly := len(args) > 0 && args[0] == dotfilesTarget
+	if compact {
+		if !dotfilesOnly {
+			return fmt.Errorf("--compact is only supported for the dotfiles target: anvil clean dotfiles --compact")
+		}
+		return runCompactDotfiles(cmd.Context(), dryRun)
+	}
+	if stale {
+		if dotfilesOnly {
+			return fmt.Errorf("--stale cannot be combined with the dotfiles target")
+		}
+		return runStaleStagingCleanup(dryRun)
+	}
+
 	output.PrintHeader("Cleaning Anvil Directories")
 
+	// The clone lives at github.local_path, which may be outside the .anvil directory
+	dotfilesPath := getDotfilesPath()
+	if dotfilesOnly {
+		itemsToClean := dotfilesItems(dotfilesPath)
+		if len(itemsToClean) == 0 {
+			output.PrintWarning("No dotfiles clone found at %s. Nothing to clean.", dotfilesPath)
+			return nil
+		}
+		return cleanItems(output, itemsToClean, dotfilesPath, force, dryRun)
+	}
+
 	// Get anvil directory path
 	anvilDir, err := getAnvilDirectoryPath()
 	if err != nil {
@@ -71,7 +103,11 @@ func runCleanCommand(cmd *cobra.Command, args []string) error {
 		output.PrintSuccess(fmt.Sprintf("No root directories found to clean. Only %s exists.", constants.ANVIL_CONFIG_FILE))
 		return nil
 	}
+	return cleanItems(output, itemsToClean, dotfilesPath, force, dryRun)
+}
 
+// cleanItems previews the items, asks for confirmation and cleans them
+func cleanItems(output palantir.OutputHandler, itemsToClean []string, dotfilesPath string, force, dryRun bool) error {
 	// Display what will be cleaned
 	displayCleanPreview(output, itemsToClean)
 
@@ -82,11 +118,14 @@ func runCleanCommand(cmd *cobra.Command, args []string) error {
 
 	if dryRun {
 		output.PrintInfo("DRY RUN: Would clean contents of %d root directories", len(itemsToClean))
+		for _, item := range itemsToClean {
+			audit.Record("clean", "remove-contents", item, "")
+		}
 		return nil
 	}
 
 	// Perform the actual cleaning
-	return performCleaning(output, itemsToClean)
+	return performCleaning(output, itemsToClean, dotfilesPath)
 }
 
 // getAnvilDirectoryPath returns the path to the .anvil directory
@@ -130,6 +169,36 @@ func getItemsToClean(anvilDir string) ([]string, error) {
 			continue
 		}
 
+		// Skip the machine-local settings overlay
+		if item.Name() == constants.LOCAL_CONFIG_FILE {
+			continue
+		}
+
+		// Skip the groups lock, without it later syncs can't tell which tools are new
+		if item.Name() == constants.GROUPS_LOCK_FILE {
+			continue
+		}
+
+		// Skip the push bases, without them later pushes can't detect changes from other machines
+		if item.Name() == constants.PUSH_BASES_FILE {
+			continue
+		}
+
+		// Skip the team layer, its protected settings are managed by the organization
+		if item.Name() == constants.TEAM_CONFIG_FILE {
+			continue
+		}
+
+		// Skip audit reports, they are evidence for compliance reviews
+		if item.Name() == audit.DirName {
+			continue
+		}
+
+		// Skip cask manifests, they are needed to find what removed casks leave behind
+		if item.Name() == brew.ManifestDirName {
+			continue
+		}
+
 		itemPath := filepath.Join(anvilDir, item.Name())
 		itemsToClean = append(itemsToClean, itemPath)
 	}
@@ -138,8 +207,8 @@ func getItemsToClean(anvilDir string) ([]string, error) {
 	return itemsToClean, nil
 }
 
-// performCleaning executes the actual cleaning process
-func performCleaning(output palantir.OutputHandler, itemsToClean []string) error {
+// performCleaning executes the actual cleaning process, removing the dotfiles clone entirely
+func performCleaning(output palantir.OutputHandler, itemsToClean []string, dotfilesPath string) error {
 	output.PrintStage("Cleaning directories and files")
 
 	spinner := charm.NewDotsSpinner(fmt.Sprintf("Cleaning %d items", len(itemsToClean)))
@@ -148,12 +217,12 @@ func performCleaning(output palantir.OutputHandler, itemsToClean []string) error
 	// Clean each item
 	cleanedCount := 0
 	for _, itemPath := range itemsToClean {
-		if err := cleanItem(itemPath); err != nil {
+		if err := cleanItem(itemPath, dotfilesPath); err != nil {
 			output.PrintWarning("Failed to clean %s: %v", filepath.Base(itemPath), err)
 			continue
 		}
 		cleanedCount++
-		displayCleanResult(output, itemPath)
+		displayCleanResult(output, itemPath, dotfilesPath)
 	}
 
 	if cleanedCount == len(itemsToClean) {
@@ -173,18 +242,16 @@ func performCleaning(output palantir.OutputHandler, itemsToClean []string) error
 	return nil
 }
 
-// cleanItem removes the contents of a directory or the file itself
-func cleanItem(itemPath string) error {
+// cleanItem removes the contents of a directory or the file itself, and the dotfiles clone as a whole
+func cleanItem(itemPath, dotfilesPath string) error {
 	info, err := os.Stat(itemPath)
 	if err != nil {
 		return fmt.Errorf("failed to stat item: %w", err)
 	}
 
 	if info.IsDir() {
-		itemName := filepath.Base(itemPath)
-
 		// Special handling for dotfiles directory - remove it completely
-		if itemName == "dotfiles" {
+		if filepath.Clean(itemPath) == filepath.Clean(dotfilesPath) {
 			// Remove the entire dotfiles directory to ensure clean git repository state
 			if err := os.RemoveAll(itemPath); err != nil {
 				return fmt.Errorf("failed to remove dotfiles directory: %w", err)
@@ -226,4 +293,6 @@ func cleanItem(itemPath string) error {
 func init() {
 	CleanCmd.Flags().BoolP("dry-run", "n", false, "Show what would be cleaned without actually deleting")
 	CleanCmd.Flags().BoolP("force", "f", false, "Skip confirmation prompt")
+	CleanCmd.Flags().Bool("compact", false, "Run git gc/prune on the local dotfiles clone instead of removing it")
+	CleanCmd.Flags().Bool("stale", false, "Only remove staging directories left behind by interrupted pulls and syncs")
 }
@@ -0,0 +1,103 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clean
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/0xjuanma/anvil/internal/audit"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/system"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+	"github.com/0xjuanma/anvil/internal/utils"
+	"github.com/0xjuanma/palantir"
+)
+
+// dotfilesTarget is the clean target for the local dotfiles clone
+const dotfilesTarget = "dotfiles"
+
+// dotfilesItems returns the local clone as the only item to clean, or nothing when it doesn't exist
+func dotfilesItems(dotfilesPath string) []string {
+	if _, err := os.Stat(dotfilesPath); err != nil {
+		return nil
+	}
+	return []string{dotfilesPath}
+}
+
+// getDotfilesPath returns the local clone path from settings, falling back to ~/.anvil/dotfiles
+func getDotfilesPath() string {
+	if cfg, err := config.LoadConfig(); err == nil && cfg.GitHub.LocalPath != "" {
+		return cfg.GitHub.LocalPath
+	}
+	return filepath.Join(config.GetAnvilConfigDirectory(), dotfilesTarget)
+}
+
+// runCompactDotfiles runs git gc and prune on the local dotfiles clone to reclaim disk space
+func runCompactDotfiles(ctx context.Context, dryRun bool) error {
+	output := palantir.GetGlobalOutputHandler()
+	output.PrintHeader("Compacting Dotfiles Repository")
+
+	dotfilesPath := getDotfilesPath()
+	if _, err := os.Stat(filepath.Join(dotfilesPath, ".git")); err != nil {
+		output.PrintWarning("No git repository found at %s. Nothing to compact.", dotfilesPath)
+		return nil
+	}
+
+	sizeBefore := getDirectorySize(dotfilesPath)
+	if dryRun {
+		output.PrintInfo("DRY RUN: Would run 'git gc --prune=now' in %s (current size: %s)", dotfilesPath, utils.FormatSize(sizeBefore))
+		audit.Record("clean", "compact-repository", dotfilesPath, "git reflog expire --expire=now --all && git gc --prune=now")
+		return nil
+	}
+
+	spinner := charm.NewDotsSpinner("Running git gc and pruning unreachable objects")
+	spinner.Start()
+
+	steps := [][]string{
+		{"reflog", "expire", "--expire=now", "--all"},
+		{"gc", "--prune=now", "--quiet"},
+	}
+	for _, step := range steps {
+		result, err := system.RunCommandInDirectoryWithTimeout(ctx, dotfilesPath, constants.GitCommand, step...)
+		if err != nil || !result.Success {
+			spinner.Error("Failed to compact dotfiles repository")
+			return errors.NewFileSystemError(constants.OpClean, "git-"+step[0],
+				fmt.Errorf("git %s failed: %s", step[0], result.Output))
+		}
+	}
+
+	sizeAfter := getDirectorySize(dotfilesPath)
+	spinner.Success(fmt.Sprintf("Compacted dotfiles repository: %s → %s", utils.FormatSize(sizeBefore), utils.FormatSize(sizeAfter)))
+	return nil
+}
+
+// getDirectorySize returns the total size in bytes of all files under a directory
+func getDirectorySize(path string) int64 {
+	var size int64
+	filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
@@ -22,6 +22,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
 	"github.com/0xjuanma/palantir"
 )
 
@@ -49,7 +50,7 @@ func handleUserConfirmation(output palantir.OutputHandler, force, dryRun bool, i
 	// Confirm deletion unless force flag is used
 	if !force && !dryRun {
 		confirmMsg := fmt.Sprintf("Are you sure you want to clean the contents of these %d root directories? This action cannot be undone", itemCount)
-		if !output.Confirm(confirmMsg) {
+		if !charm.Confirm(charm.ConfirmDelete, confirmMsg) {
 			output.PrintInfo("Clean operation cancelled.")
 			return false
 		}
@@ -58,14 +59,12 @@ func handleUserConfirmation(output palantir.OutputHandler, force, dryRun bool, i
 }
 
 // displayCleanResult shows the result of cleaning a specific item
-func displayCleanResult(output palantir.OutputHandler, itemPath string) {
+func displayCleanResult(output palantir.OutputHandler, itemPath, dotfilesPath string) {
 	itemName := filepath.Base(itemPath)
-	if info, err := os.Stat(itemPath); err == nil && info.IsDir() {
-		if itemName == "dotfiles" {
-			output.PrintSuccess("Removed dotfiles directory completely")
-		} else {
-			output.PrintSuccess("Cleaned contents of directory " + itemName)
-		}
+	if filepath.Clean(itemPath) == filepath.Clean(dotfilesPath) {
+		output.PrintSuccess("Removed dotfiles directory completely")
+	} else if info, err := os.Stat(itemPath); err == nil && info.IsDir() {
+		output.PrintSuccess("Cleaned contents of directory " + itemName)
 	} else {
 		output.PrintSuccess("Cleaned " + itemName)
 	}
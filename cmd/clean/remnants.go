@@ -0,0 +1,208 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clean
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/audit"
+	"github.com/0xjuanma/anvil/internal/brew"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/system"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+	"github.com/0xjuanma/palantir"
+	"github.com/spf13/cobra"
+)
+
+var RemnantsCmd = &cobra.Command{
+	Use:   "remnants [cask...]",
+	Short: "Find and remove what uninstalled casks left behind",
+	Long:  constants.CLEAN_REMNANTS_COMMAND_LONG_DESCRIPTION,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runRemnantsCommand(cmd, args); err != nil {
+			palantir.GetGlobalOutputHandler().PrintError("Clean remnants failed: %v", err)
+			return
+		}
+	},
+}
+
+// caskRemnants are the leftovers of one removed cask
+type caskRemnants struct {
+	manifest  brew.CaskManifest
+	leftovers []brew.Leftover
+}
+
+// runRemnantsCommand compares the manifests of casks anvil installed with what is still on disk
+func runRemnantsCommand(cmd *cobra.Command, args []string) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	force, _ := cmd.Flags().GetBool("force")
+	output := palantir.GetGlobalOutputHandler()
+	output.PrintHeader("Cask Remnants")
+
+	manifests, err := loadManifests(args)
+	if err != nil {
+		return errors.NewFileSystemError(constants.OpClean, "load-manifests", err)
+	}
+	if len(manifests) == 0 {
+		output.PrintInfo("No cask manifests recorded, they are captured when anvil installs a cask")
+		return nil
+	}
+
+	var found []caskRemnants
+	count := 0
+	for _, manifest := range manifests {
+		// Files of apps managed by another tool belong to that tool, even while it has them removed
+		if config.IsManagedExternally(manifest.Token) {
+			if len(args) > 0 {
+				output.PrintInfo("%s is managed externally, its files are left alone", manifest.Token)
+			}
+			continue
+		}
+		if brew.IsPackageInstalled(manifest.Token) {
+			if len(args) > 0 {
+				output.PrintInfo("%s is still installed, remove it with 'brew uninstall --cask %s' first", manifest.Token, manifest.Token)
+			}
+			continue
+		}
+
+		leftovers := manifest.Leftovers()
+		if len(leftovers) == 0 {
+			output.PrintSuccess(fmt.Sprintf("%s left nothing behind", manifest.Token))
+			if !dryRun {
+				if err := brew.RemoveCaskManifest(manifest.Token); err != nil {
+					output.PrintWarning("Failed to remove the manifest of %s: %v", manifest.Token, err)
+				}
+			}
+			continue
+		}
+
+		output.PrintStage(fmt.Sprintf("%s (installed %s)", manifest.Token, manifest.RecordedAt.Format("2006-01-02")))
+		for _, leftover := range leftovers {
+			output.PrintInfo("  %-12s %s", leftover.Kind, leftover.Path)
+		}
+		found = append(found, caskRemnants{manifest: manifest, leftovers: leftovers})
+		count += len(leftovers)
+	}
+
+	if len(found) == 0 {
+		output.PrintSuccess("No leftovers of removed casks found")
+		return nil
+	}
+
+	if dryRun {
+		output.PrintInfo("DRY RUN: Would move %d leftovers of %d casks to the Trash", count, len(found))
+		for _, cask := range found {
+			for _, leftover := range cask.leftovers {
+				audit.Record("clean", "remove-remnant", leftover.Path, cask.manifest.Token)
+			}
+		}
+		return nil
+	}
+
+	if !force && !charm.Confirm(charm.ConfirmDelete, fmt.Sprintf("Move %d leftovers of %d removed casks to the Trash?", count, len(found))) {
+		output.PrintInfo("Clean operation cancelled.")
+		return nil
+	}
+
+	failed := 0
+	for _, cask := range found {
+		remaining := 0
+		for _, leftover := range cask.leftovers {
+			if err := removeLeftover(leftover); err != nil {
+				output.PrintWarning("%s: %v", leftover.Path, err)
+				remaining++
+				continue
+			}
+			output.PrintSuccess(fmt.Sprintf("Moved %s to the Trash", leftover.Path))
+		}
+
+		if remaining == 0 {
+			if err := brew.RemoveCaskManifest(cask.manifest.Token); err != nil {
+				output.PrintWarning("Failed to remove the manifest of %s: %v", cask.manifest.Token, err)
+			}
+		}
+		failed += remaining
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d leftovers were not removed", failed, count)
+	}
+	return nil
+}
+
+// loadManifests returns the manifests of the named casks, or all recorded ones
+func loadManifests(tokens []string) ([]brew.CaskManifest, error) {
+	if len(tokens) == 0 {
+		return brew.ListCaskManifests()
+	}
+
+	var manifests []brew.CaskManifest
+	for _, token := range tokens {
+		name, _ := brew.ParsePackageName(token)
+		manifest, found, err := brew.LoadCaskManifest(name)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			palantir.GetGlobalOutputHandler().PrintWarning("No manifest recorded for %s, it was not installed as a cask by anvil", name)
+			continue
+		}
+		manifests = append(manifests, manifest)
+	}
+	return manifests, nil
+}
+
+// removeLeftover moves a leftover to the Trash. Receipts of installer packages and files
+// outside the home directory need administrator rights, so they are explained instead.
+func removeLeftover(leftover brew.Leftover) error {
+	if leftover.Kind == "pkg receipt" {
+		return fmt.Errorf("remove the receipt with 'sudo pkgutil --forget %s'", leftover.Path)
+	}
+
+	homeDir, err := system.GetHomeDir()
+	if err != nil {
+		return err
+	}
+	trashDir := filepath.Join(homeDir, ".Trash")
+	if err := os.MkdirAll(trashDir, 0700); err != nil {
+		return err
+	}
+
+	target := filepath.Join(trashDir, filepath.Base(leftover.Path))
+	if _, err := os.Lstat(target); err == nil {
+		target = fmt.Sprintf("%s %s", target, time.Now().Format("15.04.05"))
+	}
+	if err := os.Rename(leftover.Path, target); err != nil {
+		if os.IsPermission(err) || !strings.HasPrefix(leftover.Path, homeDir+string(filepath.Separator)) {
+			return fmt.Errorf("needs administrator rights, remove it with 'sudo rm -rf \"%s\"'", leftover.Path)
+		}
+		return err
+	}
+	return nil
+}
+
+func init() {
+	RemnantsCmd.Flags().BoolP("dry-run", "n", false, "List leftovers without removing them")
+	RemnantsCmd.Flags().BoolP("force", "f", false, "Skip confirmation prompt")
+	CleanCmd.AddCommand(RemnantsCmd)
+}
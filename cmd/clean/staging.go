@@ -0,0 +1,72 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clean
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/audit"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/utils"
+	"github.com/0xjuanma/palantir"
+)
+
+// staleStagingAge is how long a staging directory is left alone, younger ones may belong
+// to a pull or sync that is still running
+const staleStagingAge = time.Hour
+
+// runStaleStagingCleanup removes staging directories left behind by interrupted pulls and syncs
+func runStaleStagingCleanup(dryRun bool) error {
+	output := palantir.GetGlobalOutputHandler()
+	output.PrintHeader("Cleaning Stale Staging Directories")
+
+	tempDir := filepath.Join(config.GetAnvilConfigDirectory(), "temp")
+	stale, err := utils.StaleStagingDirs(tempDir, staleStagingAge)
+	if err != nil {
+		return errors.NewFileSystemError(constants.OpClean, "list-staging", err)
+	}
+	if len(stale) == 0 {
+		output.PrintSuccess("No stale staging directories found")
+		return nil
+	}
+
+	removed := 0
+	for _, dir := range stale {
+		size := utils.FormatSize(getDirectorySize(dir))
+		if dryRun {
+			output.PrintInfo("DRY RUN: Would remove %s (%s)", dir, size)
+			audit.Record("clean", "remove-staging", dir, "")
+			continue
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			output.PrintWarning("Failed to remove %s: %v", dir, err)
+			continue
+		}
+		output.PrintSuccess(fmt.Sprintf("Removed %s (%s)", filepath.Base(dir), size))
+		removed++
+	}
+
+	if !dryRun && removed < len(stale) {
+		return fmt.Errorf("%d of %d staging directories could not be removed", len(stale)-removed, len(stale))
+	}
+	return nil
+}
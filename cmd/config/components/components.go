@@ -0,0 +1,145 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/0xjuanma/anvil/internal/audit"
+	appcomponents "github.com/0xjuanma/anvil/internal/components"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+	"github.com/0xjuanma/palantir"
+	"github.com/spf13/cobra"
+)
+
+var ComponentsCmd = &cobra.Command{
+	Use:   "components [app-name]",
+	Short: "Verify the plugins and extensions listed in app configs are installed",
+	Long:  constants.COMPONENTS_COMMAND_LONG_DESCRIPTION,
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runComponentsCommand(cmd, args); err != nil {
+			palantir.GetGlobalOutputHandler().PrintError("Component check failed: %v", err)
+			return
+		}
+	},
+}
+
+// runComponentsCommand verifies component manifests for one app or every configured app
+func runComponentsCommand(cmd *cobra.Command, args []string) error {
+	output := palantir.GetGlobalOutputHandler()
+	install, _ := cmd.Flags().GetBool("install")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return errors.NewConfigurationError(constants.OpConfig, "load-config", err)
+	}
+
+	apps := make([]string, 0, len(cfg.Configs))
+	if len(args) == 1 {
+		if _, ok := cfg.Configs[args[0]]; !ok {
+			return fmt.Errorf("app '%s' has no config path in %s", args[0], constants.ANVIL_CONFIG_FILE)
+		}
+		apps = append(apps, args[0])
+	} else {
+		for app := range cfg.Configs {
+			apps = append(apps, app)
+		}
+		sort.Strings(apps)
+	}
+
+	output.PrintHeader("App Components")
+	checked := 0
+	for _, app := range apps {
+		path := cfg.Configs[app]
+		if len(appcomponents.For(app, path)) == 0 {
+			continue
+		}
+		checked++
+		if err := Reconcile(app, path, install, dryRun); err != nil {
+			output.PrintWarning("%s: %v", app, err)
+		}
+	}
+
+	if checked == 0 {
+		output.PrintInfo("No component manifests found in the configured app paths")
+		output.PrintInfo("Supported: Obsidian community plugins, VS Code and Cursor extensions (extensions.txt)")
+	}
+	return nil
+}
+
+// Reconcile reports the components an app config lists but this machine lacks, installing
+// them after confirmation when install is true, or only listing them with dryRun. Used by
+// 'config sync' after copying configs.
+func Reconcile(appName, configPath string, install, dryRun bool) error {
+	output := palantir.GetGlobalOutputHandler()
+	statuses, err := appcomponents.Verify(appName, configPath)
+	if err != nil {
+		return err
+	}
+
+	for _, status := range statuses {
+		name := status.Manifest.Name()
+		if len(status.Missing) == 0 {
+			output.PrintSuccess(fmt.Sprintf("%s: %s, all %d installed", appName, name, len(status.Wanted)))
+			continue
+		}
+
+		output.PrintWarning("%s: %s, %d of %d missing: %s", appName, name, len(status.Missing), len(status.Wanted), strings.Join(status.Missing, ", "))
+		if !install {
+			output.PrintInfo("Run 'anvil config components %s --install' to install them", appName)
+			continue
+		}
+		if dryRun {
+			output.PrintInfo("Dry run - would install %d missing %s", len(status.Missing), name)
+			for _, id := range status.Missing {
+				audit.Record("config components", "install-component", id, fmt.Sprintf("%s %s", appName, name))
+			}
+			continue
+		}
+		if !charm.Confirm(charm.ConfirmInstall, fmt.Sprintf("Install %d missing %s?", len(status.Missing), name)) {
+			continue
+		}
+
+		failed := 0
+		for _, id := range status.Missing {
+			spinner := charm.NewDotsSpinner(fmt.Sprintf("Installing %s", id))
+			spinner.Start()
+			if err := status.Manifest.Install(configPath, id); err != nil {
+				spinner.Error(fmt.Sprintf("%s: %v", id, err))
+				failed++
+				continue
+			}
+			spinner.Success(fmt.Sprintf("%s installed", id))
+		}
+		if failed > 0 {
+			output.PrintWarning("%d of %d %s failed to install", failed, len(status.Missing), name)
+		}
+	}
+	return nil
+}
+
+func init() {
+	ComponentsCmd.Flags().Bool("install", false, "Install missing components after confirmation")
+	ComponentsCmd.Flags().Bool("dry-run", false, "With --install, show the components that would be installed without installing them")
+}
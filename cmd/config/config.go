@@ -17,11 +17,22 @@ limitations under the License.
 package config
 
 import (
+	"github.com/0xjuanma/anvil/cmd/config/components"
+	"github.com/0xjuanma/anvil/cmd/config/conflicts"
+	"github.com/0xjuanma/anvil/cmd/config/defaults"
+	"github.com/0xjuanma/anvil/cmd/config/diff"
+	"github.com/0xjuanma/anvil/cmd/config/history"
+	"github.com/0xjuanma/anvil/cmd/config/hooks"
 	importcmd "github.com/0xjuanma/anvil/cmd/config/import"
+	"github.com/0xjuanma/anvil/cmd/config/paths"
 	"github.com/0xjuanma/anvil/cmd/config/pull"
 	"github.com/0xjuanma/anvil/cmd/config/push"
+	"github.com/0xjuanma/anvil/cmd/config/restore"
 	"github.com/0xjuanma/anvil/cmd/config/show"
+	"github.com/0xjuanma/anvil/cmd/config/snapshot"
 	"github.com/0xjuanma/anvil/cmd/config/sync"
+	"github.com/0xjuanma/anvil/cmd/config/timeline"
+	"github.com/0xjuanma/anvil/cmd/config/validate"
 	"github.com/0xjuanma/anvil/internal/constants"
 	"github.com/spf13/cobra"
 )
@@ -36,10 +47,23 @@ var ConfigCmd = &cobra.Command{
 }
 
 func init() {
-	// Add pull, push, show, sync, and import as sub-commands of config
+	// Add pull, push, diff, show, sync, rollback, import, history, conflicts, defaults, paths, components, snapshot-diff, restore-settings, validate and install-hooks as sub-commands of config
 	ConfigCmd.AddCommand(pull.PullCmd)
 	ConfigCmd.AddCommand(push.PushCmd)
+	ConfigCmd.AddCommand(diff.DiffCmd)
 	ConfigCmd.AddCommand(show.ShowCmd)
 	ConfigCmd.AddCommand(sync.SyncCmd)
+	ConfigCmd.AddCommand(sync.RollbackCmd)
 	ConfigCmd.AddCommand(importcmd.ImportCmd)
+	ConfigCmd.AddCommand(history.HistoryCmd)
+	ConfigCmd.AddCommand(timeline.TimelineCmd)
+	ConfigCmd.AddCommand(conflicts.ConflictsCmd)
+	ConfigCmd.AddCommand(defaults.DefaultsCmd)
+	ConfigCmd.AddCommand(paths.PathsCmd)
+	ConfigCmd.AddCommand(components.ComponentsCmd)
+	ConfigCmd.AddCommand(snapshot.SnapshotDiffCmd)
+	ConfigCmd.AddCommand(restore.RestoreSettingsCmd)
+	ConfigCmd.AddCommand(validate.ValidateCmd)
+	ConfigCmd.AddCommand(hooks.InstallHooksCmd)
+	ConfigCmd.AddCommand(hooks.HookCmd)
 }
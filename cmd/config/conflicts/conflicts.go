@@ -0,0 +1,114 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conflicts
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/0xjuanma/anvil/internal/audit"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+	"github.com/0xjuanma/palantir"
+	"github.com/spf13/cobra"
+)
+
+var ConflictsCmd = &cobra.Command{
+	Use:   "conflicts",
+	Short: "Find duplicate or inconsistently cased app names in settings.yaml",
+	Long:  constants.CONFLICTS_COMMAND_LONG_DESCRIPTION,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runConflictsCommand(cmd); err != nil {
+			palantir.GetGlobalOutputHandler().PrintError("Conflict check failed: %v", err)
+			return
+		}
+	},
+}
+
+// runConflictsCommand reports app name conflicts across settings sections and optionally cleans them up
+func runConflictsCommand(cmd *cobra.Command) error {
+	output := palantir.GetGlobalOutputHandler()
+	fix, _ := cmd.Flags().GetBool("fix")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	anvilConfig, err := config.LoadConfig()
+	if err != nil {
+		return errors.NewConfigurationError(constants.OpConfig, "load-config", err)
+	}
+
+	output.PrintHeader("App Name Conflicts")
+	conflicts := config.FindAppConflicts(anvilConfig)
+	if len(conflicts) == 0 {
+		output.PrintSuccess("No duplicate or conflicting app names found")
+		return nil
+	}
+
+	for _, conflict := range conflicts {
+		output.PrintWarning("%s", conflict.Name)
+		for _, issue := range conflict.Issues() {
+			output.PrintInfo("  • %s", issue)
+		}
+	}
+	fmt.Println()
+	output.PrintInfo("%d app(s) with conflicting entries", len(conflicts))
+
+	if !fix {
+		output.PrintInfo("Run 'anvil config conflicts --fix' to normalize names and remove duplicates")
+		return nil
+	}
+
+	changes := config.CleanupAppConflicts(anvilConfig)
+	output.PrintStage("Cleanup plan")
+	output.PrintInfo("  • Lowercase and trim app names in %s, %s and groups", config.SectionRequiredTools, config.SectionInstalledApps)
+	output.PrintInfo("  • Remove duplicates within each section")
+	output.PrintInfo("  • Drop %s entries already tracked by required tools or a group", config.SectionInstalledApps)
+	output.PrintInfo("%d entries will be renamed or removed", changes)
+
+	if dryRun {
+		output.PrintInfo("Dry run mode - %s was not modified", constants.ANVIL_CONFIG_FILE)
+		audit.Record("config conflicts", "cleanup-apps", config.GetAnvilConfigPath(), strings.Join(conflictNames(conflicts), ","))
+		return nil
+	}
+
+	if !charm.Confirm(charm.ConfirmSettings, fmt.Sprintf("Apply cleanup to %s?", constants.ANVIL_CONFIG_FILE)) {
+		output.PrintInfo("Cleanup cancelled by user")
+		return nil
+	}
+
+	if err := config.SaveConfig(anvilConfig); err != nil {
+		return errors.NewConfigurationError(constants.OpConfig, "save-config", err)
+	}
+
+	output.PrintSuccess(fmt.Sprintf("Cleaned up %d app entries in %s", changes, constants.ANVIL_CONFIG_FILE))
+	return nil
+}
+
+// conflictNames returns the normalized names of the conflicting apps
+func conflictNames(conflicts []config.AppConflict) []string {
+	names := make([]string, len(conflicts))
+	for i, conflict := range conflicts {
+		names[i] = conflict.Name
+	}
+	return names
+}
+
+func init() {
+	ConflictsCmd.Flags().Bool("fix", false, "Normalize app names and remove duplicate entries")
+	ConflictsCmd.Flags().Bool("dry-run", false, "Show the cleanup without modifying settings.yaml")
+}
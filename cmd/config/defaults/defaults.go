@@ -0,0 +1,194 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package defaults
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+	"github.com/0xjuanma/palantir"
+	"github.com/spf13/cobra"
+)
+
+var DefaultsCmd = &cobra.Command{
+	Use:   "defaults",
+	Short: "Manage default flags per command from settings.yaml",
+	Long:  constants.DEFAULTS_COMMAND_LONG_DESCRIPTION,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Show the effective default flags for each configured command",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runListCommand(cmd); err != nil {
+			palantir.GetGlobalOutputHandler().PrintError("Defaults failed: %v", err)
+		}
+	},
+}
+
+// Entry is a configured default flag resolved against the command tree
+type Entry struct {
+	Command string // Command path without the "anvil" prefix
+	Flag    string
+	Value   string // Value from settings.yaml
+	BuiltIn string // The flag's own default, empty when the flag is unknown
+	Problem string // Why the entry cannot be applied, empty when valid
+}
+
+// Apply sets flags the user did not pass on the command line to their defaults from
+// settings.yaml. Flags given on the command line always win. Entries that cannot be
+// applied are returned as errors.
+func Apply(cmd *cobra.Command) []error {
+	defaults, err := config.GetCommandDefaults()
+	if err != nil || len(defaults) == 0 {
+		return nil
+	}
+
+	key := commandKey(cmd)
+	flags := defaults.For(key)
+	var errs []error
+	for _, name := range sortedFlags(flags) {
+		flag := cmd.Flags().Lookup(strings.TrimLeft(name, "-"))
+		if flag == nil {
+			errs = append(errs, fmt.Errorf("defaults.%s: '%s' has no --%s flag", key, key, strings.TrimLeft(name, "-")))
+			continue
+		}
+		if flag.Changed {
+			continue
+		}
+		if err := flag.Value.Set(flags[name]); err != nil {
+			errs = append(errs, fmt.Errorf("defaults.%s.%s: invalid value '%s': %w", key, flag.Name, flags[name], err))
+		}
+	}
+	return errs
+}
+
+// Resolve matches every configured default against the commands under root
+func Resolve(root *cobra.Command, defaults config.CommandDefaults) []Entry {
+	var entries []Entry
+	for _, command := range defaults.Commands() {
+		flags := defaults[command]
+		target, rest, err := root.Find(strings.Fields(command))
+		known := err == nil && len(rest) == 0 && target != root
+
+		for _, name := range sortedFlags(flags) {
+			entry := Entry{Command: command, Flag: strings.TrimLeft(name, "-"), Value: flags[name]}
+			switch {
+			case !known:
+				entry.Problem = "unknown command"
+			case target.Flags().Lookup(entry.Flag) == nil && target.InheritedFlags().Lookup(entry.Flag) == nil:
+				entry.Problem = "unknown flag"
+			default:
+				flag := target.Flags().Lookup(entry.Flag)
+				if flag == nil {
+					flag = target.InheritedFlags().Lookup(entry.Flag)
+				}
+				entry.BuiltIn = flag.DefValue
+				if err := validateValue(flag.Value.Type(), entry.Value); err != nil {
+					entry.Problem = err.Error()
+				}
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// runListCommand prints the configured defaults and whether they apply
+func runListCommand(cmd *cobra.Command) error {
+	o := palantir.GetGlobalOutputHandler()
+	defaults, err := config.GetCommandDefaults()
+	if err != nil {
+		return errors.NewConfigurationError(constants.OpConfig, "load-config", err)
+	}
+	if len(defaults) == 0 {
+		o.PrintInfo("No command defaults configured. Add a 'defaults' section to %s, e.g.:", constants.ANVIL_CONFIG_FILE)
+		o.PrintInfo("  defaults:\n    install:\n      concurrent: true")
+		return nil
+	}
+
+	entries := Resolve(cmd.Root(), defaults)
+	var content strings.Builder
+	invalid := 0
+	for _, entry := range entries {
+		line := fmt.Sprintf("  anvil %-16s --%s=%s", entry.Command, entry.Flag, entry.Value)
+		if entry.Problem != "" {
+			invalid++
+			content.WriteString(fmt.Sprintf("%s  ✗ %s\n", line, entry.Problem))
+			continue
+		}
+		content.WriteString(fmt.Sprintf("%s  (built-in: %s)\n", line, displayDefault(entry.BuiltIn)))
+	}
+	fmt.Println(charm.RenderBox("Command Defaults", strings.TrimRight(content.String(), "\n"), charm.ActiveTheme().Accent, false))
+
+	if invalid > 0 {
+		o.PrintWarning("%d defaults cannot be applied, fix them in %s", invalid, constants.ANVIL_CONFIG_FILE)
+	}
+	o.PrintInfo("Flags passed on the command line override these defaults")
+	return nil
+}
+
+// validateValue checks a default against its flag type without touching the flag
+func validateValue(flagType, value string) error {
+	switch flagType {
+	case "bool":
+		if value != "true" && value != "false" {
+			return fmt.Errorf("expected true or false")
+		}
+	case "int":
+		if _, err := fmt.Sscanf(value, "%d", new(int)); err != nil {
+			return fmt.Errorf("expected a number")
+		}
+	}
+	return nil
+}
+
+// commandKey returns the command path without the root command name
+func commandKey(cmd *cobra.Command) string {
+	return strings.TrimSpace(strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()))
+}
+
+// sortedFlags returns flag names in a stable order
+func sortedFlags(flags map[string]string) []string {
+	names := make([]string, 0, len(flags))
+	for name := range flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// displayDefault renders an empty built-in default readably
+func displayDefault(value string) string {
+	if value == "" {
+		return `""`
+	}
+	return value
+}
+
+func init() {
+	DefaultsCmd.AddCommand(listCmd)
+}
@@ -0,0 +1,79 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package defaults
+
+import (
+	"testing"
+
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/spf13/cobra"
+)
+
+func newTestTree() (*cobra.Command, *cobra.Command) {
+	root := &cobra.Command{Use: "anvil"}
+	root.PersistentFlags().Bool("yes", false, "")
+	install := &cobra.Command{Use: "install", Run: func(*cobra.Command, []string) {}}
+	install.Flags().Bool("concurrent", false, "")
+	install.Flags().Int("workers", 2, "")
+	root.AddCommand(install)
+	return root, install
+}
+
+func TestResolve(t *testing.T) {
+	root, _ := newTestTree()
+	defaults := config.CommandDefaults{
+		"install": {"concurrent": "true", "workers": "many", "yes": "true", "bogus": "1"},
+		"missing": {"verbose": "true"},
+	}
+
+	got := map[string]Entry{}
+	for _, entry := range Resolve(root, defaults) {
+		got[entry.Command+"."+entry.Flag] = entry
+	}
+
+	tests := []struct {
+		key     string
+		builtIn string
+		problem string
+	}{
+		{"install.concurrent", "false", ""},
+		{"install.workers", "2", "expected a number"},
+		{"install.yes", "false", ""},
+		{"install.bogus", "", "unknown flag"},
+		{"missing.verbose", "", "unknown command"},
+	}
+	for _, tt := range tests {
+		entry, ok := got[tt.key]
+		if !ok {
+			t.Errorf("missing entry %s", tt.key)
+			continue
+		}
+		if entry.BuiltIn != tt.builtIn || entry.Problem != tt.problem {
+			t.Errorf("%s = (%q, %q), want (%q, %q)", tt.key, entry.BuiltIn, entry.Problem, tt.builtIn, tt.problem)
+		}
+	}
+}
+
+func TestCommandKey(t *testing.T) {
+	root, install := newTestTree()
+	if key := commandKey(install); key != "install" {
+		t.Errorf("commandKey(install) = %q, want install", key)
+	}
+	if key := commandKey(root); key != "" {
+		t.Errorf("commandKey(root) = %q, want empty", key)
+	}
+}
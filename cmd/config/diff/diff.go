@@ -0,0 +1,226 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diff
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/0xjuanma/anvil/internal/automation"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/github"
+	"github.com/0xjuanma/anvil/internal/utils"
+	"github.com/0xjuanma/palantir"
+	"github.com/spf13/cobra"
+)
+
+// maxDiffLines caps how much of a single file's diff is printed
+const maxDiffLines = 200
+
+var DiffCmd = &cobra.Command{
+	Use:   "diff [app-name]",
+	Short: "Show how local configs differ from the config repository",
+	Long:  constants.CONFIG_DIFF_COMMAND_LONG_DESCRIPTION,
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runDiffCommand(cmd, args); err != nil {
+			palantir.GetGlobalOutputHandler().PrintError("Diff failed: %v", err)
+			return
+		}
+	},
+}
+
+// runDiffCommand updates the local clone and prints a unified diff of the local config against it
+func runDiffCommand(cmd *cobra.Command, args []string) error {
+	output := palantir.GetGlobalOutputHandler()
+	nameOnly, _ := cmd.Flags().GetBool("name-only")
+
+	anvilConfig, err := config.LoadConfig()
+	if err != nil {
+		return errors.NewConfigurationError(constants.OpConfig, "load-config", err)
+	}
+	if anvilConfig.GitHub.ConfigRepo == "" {
+		return errors.NewConfigurationError(constants.OpConfig, "missing-repo",
+			fmt.Errorf("GitHub repository not configured. Please set 'github.config_repo' in your %s", constants.ANVIL_CONFIG_FILE))
+	}
+
+	appName := constants.ANVIL
+	if len(args) > 0 {
+		appName = args[0]
+	}
+	localPath, targetPath, err := resolvePaths(appName, anvilConfig)
+	if err != nil {
+		return err
+	}
+
+	output.PrintHeader(fmt.Sprintf("Diff '%s' Configuration", appName))
+	output.PrintInfo("Local: %s", localPath)
+	output.PrintInfo("Remote: %s/%s (%s)", anvilConfig.GitHub.ConfigRepo, targetPath, anvilConfig.GitHub.Branch)
+
+	githubClient := newGitHubClient(anvilConfig)
+	filter := anvilConfig.ConfigFilters[appName]
+	githubClient.Include, githubClient.Exclude = filter.Include, filter.Exclude
+	output.PrintStage("Updating local clone...")
+	changes, err := githubClient.CompareConfig(cmd.Context(), localPath, targetPath)
+	if err != nil {
+		return err
+	}
+
+	if changes.IsEmpty() {
+		output.PrintSuccess(fmt.Sprintf("%s matches the config repository", appName))
+		return nil
+	}
+
+	fmt.Println()
+	for _, path := range changes.Modified {
+		showChange("~", path, changes, githubClient.MaterializeSymlinks, nameOnly)
+	}
+	for _, path := range changes.Added {
+		showChange("+", path, changes, githubClient.MaterializeSymlinks, nameOnly)
+	}
+	for _, path := range changes.Removed {
+		showChange("-", path, changes, githubClient.MaterializeSymlinks, nameOnly)
+	}
+
+	output.PrintInfo("%d file(s) differ: %d modified, %d only local, %d only in the repository",
+		len(changes.Modified)+len(changes.Added)+len(changes.Removed), len(changes.Modified), len(changes.Added), len(changes.Removed))
+	pushTarget := ""
+	if appName != constants.ANVIL {
+		pushTarget = " " + appName
+	}
+	output.PrintInfo("Run 'anvil config push%s' to publish local changes, or 'anvil config sync%s' to take the repository's", pushTarget, pushTarget)
+	return nil
+}
+
+// resolvePaths returns the local config path and its location inside the config repository
+func resolvePaths(appName string, anvilConfig *config.AnvilConfig) (string, string, error) {
+	if appName == constants.ANVIL {
+		anvilSettingsPath := fmt.Sprintf("%s/%s", constants.ANVIL_CONFIG_DIR, constants.ANVIL_CONFIG_FILE)
+		return config.GetAnvilConfigPath(), anvilSettingsPath[1:], nil
+	}
+
+	if appName == automation.AppName {
+		dir := automation.Dir()
+		if err := automation.Export(dir); err != nil {
+			return "", "", errors.NewFileSystemError(constants.OpConfig, automation.AppName, err)
+		}
+		return dir, appName, nil
+	}
+
+	localPath, exists := anvilConfig.Configs[appName]
+	if !exists {
+		return "", "", errors.NewConfigurationError(constants.OpConfig, appName,
+			fmt.Errorf("app '%s' has no local path, add it under 'configs' in %s", appName, constants.ANVIL_CONFIG_FILE))
+	}
+
+	// Single-file configs are stored inside the app directory under their own name
+	if info, err := os.Stat(localPath); err == nil && !info.IsDir() {
+		return localPath, filepath.Join(appName, filepath.Base(localPath)), nil
+	}
+	return localPath, appName, nil
+}
+
+// showChange prints one changed file with its marker and, unless nameOnly, its unified diff
+func showChange(marker, path string, changes *github.ConfigChanges, materializeSymlinks, nameOnly bool) {
+	fmt.Printf("  %s %s\n", marker, path)
+	if nameOnly {
+		return
+	}
+
+	localFile := filepath.Join(changes.LocalRoot, path)
+	repoFile := filepath.Join(changes.RepoRoot, path)
+	remote, remoteOK := readSide(repoFile, materializeSymlinks)
+	local, localOK := readSide(localFile, materializeSymlinks)
+	if !remoteOK || !localOK {
+		fmt.Printf("    (diff unavailable, file could not be read)\n\n")
+		return
+	}
+	if strings.IndexByte(remote, 0) >= 0 || strings.IndexByte(local, 0) >= 0 {
+		fmt.Printf("    (binary file differs)\n\n")
+		return
+	}
+
+	oldLabel, newLabel := "remote/"+filepath.ToSlash(path), "local/"+filepath.ToSlash(path)
+	if marker == "+" {
+		oldLabel = os.DevNull
+	} else if marker == "-" {
+		newLabel = os.DevNull
+	}
+
+	text := strings.TrimRight(utils.UnifiedDiff(oldLabel, newLabel, remote, local), "\n")
+	if text == "" {
+		fmt.Printf("    (file mode changed)\n\n")
+		return
+	}
+
+	lines := strings.Split(text, "\n")
+	if len(lines) > maxDiffLines {
+		fmt.Println(utils.ColorizeDiff(strings.Join(lines[:maxDiffLines], "\n")))
+		fmt.Printf("    ... %d more line(s)\n\n", len(lines)-maxDiffLines)
+		return
+	}
+	fmt.Println(utils.ColorizeDiff(text))
+	fmt.Println()
+}
+
+// readSide returns a file's contents, an empty string when it does not exist, and a symlink's target
+// when symlinks are committed as links
+func readSide(path string, materializeSymlinks bool) (string, bool) {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return "", true
+	}
+	if err != nil {
+		return "", false
+	}
+	if utils.IsSymlink(info) && !materializeSymlinks {
+		target, err := os.Readlink(path)
+		return fmt.Sprintf("symlink -> %s\n", target), err == nil
+	}
+
+	content, err := os.ReadFile(path)
+	return string(content), err == nil
+}
+
+// newGitHubClient creates a GitHub client from the anvil configuration
+func newGitHubClient(anvilConfig *config.AnvilConfig) *github.GitHubClient {
+	var token string
+	if anvilConfig.GitHub.TokenEnvVar != "" {
+		token = os.Getenv(anvilConfig.GitHub.TokenEnvVar)
+	}
+
+	githubClient := github.NewGitHubClient(
+		anvilConfig.GitHub.ConfigRepo,
+		anvilConfig.GitHub.Branch,
+		anvilConfig.GitHub.LocalPath,
+		token,
+		anvilConfig.Git.SSHKeyPath,
+		anvilConfig.Git.Username,
+		anvilConfig.Git.Email,
+	)
+	githubClient.CloneDepth = anvilConfig.GitHub.CloneDepth
+	githubClient.MaterializeSymlinks = anvilConfig.GitHub.MaterializeSymlinks
+	return githubClient
+}
+
+func init() {
+	DiffCmd.Flags().Bool("name-only", false, "List the changed files without their diffs")
+}
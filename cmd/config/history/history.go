@@ -0,0 +1,275 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package history
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/audit"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/github"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+	"github.com/0xjuanma/palantir"
+	"github.com/spf13/cobra"
+)
+
+// partialBranchGrace is how long an unfinished push branch is kept before --prune treats it as abandoned
+const partialBranchGrace = 24 * time.Hour
+
+var HistoryCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List config push branches and delete merged or abandoned ones",
+	Long:  constants.HISTORY_COMMAND_LONG_DESCRIPTION,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runHistoryCommand(cmd); err != nil {
+			palantir.GetGlobalOutputHandler().PrintError("History failed: %v", err)
+			return
+		}
+	},
+}
+
+// runHistoryCommand lists push branches on the remote and optionally prunes old ones
+func runHistoryCommand(cmd *cobra.Command) error {
+	output := palantir.GetGlobalOutputHandler()
+	prune, _ := cmd.Flags().GetBool("prune")
+	cleanup, _ := cmd.Flags().GetBool("cleanup")
+	olderThan, _ := cmd.Flags().GetInt("older-than")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	anvilConfig, err := config.LoadConfig()
+	if err != nil {
+		return errors.NewConfigurationError(constants.OpConfig, "load-config", err)
+	}
+	if anvilConfig.GitHub.ConfigRepo == "" {
+		return errors.NewConfigurationError(constants.OpConfig, "missing-repo",
+			fmt.Errorf("GitHub repository not configured. Please set 'github.config_repo' in your %s", constants.ANVIL_CONFIG_FILE))
+	}
+
+	githubClient := newGitHubClient(anvilConfig)
+	if err := githubClient.CloneRepository(cmd.Context()); err != nil {
+		return fmt.Errorf("failed to clone repository: %w", err)
+	}
+
+	output.PrintHeader("Config Push History")
+	branches, err := githubClient.ListPushBranches(cmd.Context())
+	if err != nil {
+		return err
+	}
+	if len(branches) == 0 {
+		output.PrintInfo("No push branches found in %s", anvilConfig.GitHub.ConfigRepo)
+		return nil
+	}
+
+	for _, branch := range branches {
+		fmt.Println(formatBranch(branch))
+	}
+	fmt.Println()
+	output.PrintInfo("%d push branch(es) in %s", len(branches), anvilConfig.GitHub.ConfigRepo)
+
+	if !prune && !cleanup {
+		return nil
+	}
+
+	var selected []deletion
+	if prune {
+		for _, branch := range selectPrunable(branches, time.Duration(olderThan)*24*time.Hour, time.Now()) {
+			reason := fmt.Sprintf("older than %d day(s)", olderThan)
+			if branch.Partial {
+				reason = "abandoned unfinished push"
+			}
+			selected = append(selected, deletion{branch: branch, reason: reason})
+		}
+	}
+	if cleanup {
+		var minAge time.Duration
+		if cmd.Flags().Changed("older-than") {
+			minAge = time.Duration(olderThan) * 24 * time.Hour
+		}
+		merged := findMerged(cmd.Context(), githubClient, selectCleanable(branches, minAge, time.Now()))
+		selected = appendMissing(selected, merged)
+	}
+
+	if len(selected) == 0 {
+		output.PrintSuccess("No push branches to delete")
+		return nil
+	}
+
+	output.PrintStage(fmt.Sprintf("Deleting %d branch(es)...", len(selected)))
+	for _, item := range selected {
+		output.PrintInfo("  • %s (%s)", item.branch.Name, item.reason)
+	}
+
+	if dryRun {
+		output.PrintInfo("Dry run mode - no branches were deleted")
+		for _, item := range selected {
+			audit.Record("config history", "delete-branch", item.branch.Name, anvilConfig.GitHub.ConfigRepo)
+		}
+		return nil
+	}
+
+	if !charm.Confirm(charm.ConfirmDelete, fmt.Sprintf("Delete %d branch(es) from %s?", len(selected), anvilConfig.GitHub.ConfigRepo)) {
+		output.PrintInfo("Deletion cancelled by user")
+		return nil
+	}
+
+	var failed int
+	for _, item := range selected {
+		if err := githubClient.DeletePushBranch(cmd.Context(), item.branch.Name); err != nil {
+			output.PrintWarning("Failed to delete %s: %v", item.branch.Name, err)
+			failed++
+			continue
+		}
+		output.PrintSuccess(fmt.Sprintf("Deleted %s", item.branch.Name))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d branch(es) could not be deleted", failed)
+	}
+	return nil
+}
+
+// deletion is a push branch selected for deletion and why
+type deletion struct {
+	branch github.PushBranch
+	reason string
+}
+
+// findMerged returns the candidates merged into the configured branch, checked in the local
+// clone first and then with the GitHub API for squash and rebase merges
+func findMerged(ctx context.Context, githubClient *github.GitHubClient, candidates []github.PushBranch) []deletion {
+	output := palantir.GetGlobalOutputHandler()
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	localOnly := false
+	if err := githubClient.FetchPushBranches(ctx); err != nil {
+		output.PrintWarning("Could not fetch push branches, merges are checked with the GitHub API only: %v", err)
+	}
+
+	var merged []deletion
+	for _, branch := range candidates {
+		if githubClient.IsMergedLocally(ctx, branch.Name) {
+			merged = append(merged, deletion{branch: branch, reason: "merged"})
+			continue
+		}
+		if localOnly {
+			continue
+		}
+		pr, err := githubClient.MergedPushBranch(ctx, branch.Name)
+		if err != nil {
+			// Without the API only merge commits are detected, the rest are kept
+			output.PrintWarning("Squash and rebase merges can't be detected: %v", err)
+			localOnly = true
+			continue
+		}
+		if pr != nil {
+			merged = append(merged, deletion{branch: branch, reason: fmt.Sprintf("merged in #%d", pr.Number)})
+		}
+	}
+	return merged
+}
+
+// selectCleanable returns the finished push branches at least minAge old that --cleanup checks for
+// merges. Unfinished pushes are kept for resuming, and undated branches are kept when an age is set.
+func selectCleanable(branches []github.PushBranch, minAge time.Duration, now time.Time) []github.PushBranch {
+	var cleanable []github.PushBranch
+	for _, branch := range branches {
+		if branch.Partial {
+			continue
+		}
+		if minAge > 0 && (!branch.HasTime || now.Sub(branch.CreatedAt) < minAge) {
+			continue
+		}
+		cleanable = append(cleanable, branch)
+	}
+	return cleanable
+}
+
+// appendMissing adds the deletions whose branch isn't selected yet
+func appendMissing(selected, more []deletion) []deletion {
+	for _, item := range more {
+		if !slices.ContainsFunc(selected, func(existing deletion) bool { return existing.branch.Name == item.branch.Name }) {
+			selected = append(selected, item)
+		}
+	}
+	return selected
+}
+
+// newGitHubClient creates a GitHub client from the anvil configuration
+func newGitHubClient(anvilConfig *config.AnvilConfig) *github.GitHubClient {
+	var token string
+	if anvilConfig.GitHub.TokenEnvVar != "" {
+		token = os.Getenv(anvilConfig.GitHub.TokenEnvVar)
+	}
+
+	githubClient := github.NewGitHubClient(
+		anvilConfig.GitHub.ConfigRepo,
+		anvilConfig.GitHub.Branch,
+		anvilConfig.GitHub.LocalPath,
+		token,
+		anvilConfig.Git.SSHKeyPath,
+		anvilConfig.Git.Username,
+		anvilConfig.Git.Email,
+	)
+	githubClient.CloneDepth = anvilConfig.GitHub.CloneDepth
+	githubClient.BranchTimestampFormat = anvilConfig.GitHub.BranchTimestampFormat
+	return githubClient
+}
+
+// formatBranch renders a branch line with its creation date and resume marker
+func formatBranch(branch github.PushBranch) string {
+	created := "unknown date"
+	if branch.HasTime {
+		created = branch.CreatedAt.Format("2006-01-02 15:04")
+	}
+
+	line := fmt.Sprintf("  %-40s %s", branch.Name, created)
+	if branch.Partial {
+		line += "  (unfinished push, will be resumed)"
+	}
+	return line
+}
+
+// selectPrunable returns branches older than maxAge, plus unfinished push branches abandoned for over a day.
+// Branches without a recognizable timestamp are never pruned.
+func selectPrunable(branches []github.PushBranch, maxAge time.Duration, now time.Time) []github.PushBranch {
+	var prunable []github.PushBranch
+	for _, branch := range branches {
+		if !branch.HasTime {
+			continue
+		}
+		age := now.Sub(branch.CreatedAt)
+		if age > maxAge || (branch.Partial && age > partialBranchGrace) {
+			prunable = append(prunable, branch)
+		}
+	}
+	return prunable
+}
+
+func init() {
+	HistoryCmd.Flags().Bool("prune", false, "Delete push branches older than --older-than days and abandoned partial pushes")
+	HistoryCmd.Flags().Bool("cleanup", false, "Delete push branches merged into the configured branch")
+	HistoryCmd.Flags().Int("older-than", 30, "Age in days after which --prune deletes push branches, and the minimum age for --cleanup when set")
+	HistoryCmd.Flags().Bool("dry-run", false, "Show which branches would be deleted without deleting them")
+}
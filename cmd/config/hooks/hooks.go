@@ -0,0 +1,170 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/0xjuanma/anvil/internal/audit"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/github"
+	"github.com/0xjuanma/anvil/internal/system"
+	"github.com/0xjuanma/palantir"
+	"github.com/spf13/cobra"
+)
+
+var InstallHooksCmd = &cobra.Command{
+	Use:   "install-hooks",
+	Short: "Install git hooks that guard manual commits and pushes in the config repository",
+	Long:  constants.INSTALL_HOOKS_COMMAND_LONG_DESCRIPTION,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runInstallHooksCommand(cmd); err != nil {
+			palantir.GetGlobalOutputHandler().PrintError("Install hooks failed: %v", err)
+			os.Exit(errors.ExitCode(err))
+		}
+	},
+}
+
+// HookCmd is run by the installed hooks, git reads its exit code
+var HookCmd = &cobra.Command{
+	Use:       "hook <pre-commit|pre-push>",
+	Short:     "Run the checks of an installed git hook",
+	Hidden:    true,
+	Args:      cobra.MinimumNArgs(1),
+	ValidArgs: github.HookNames,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runHookCommand(cmd, args[0]); err != nil {
+			palantir.GetGlobalOutputHandler().PrintError("anvil %s check failed: %v", args[0], err)
+			os.Exit(errors.ExitCode(err))
+		}
+	},
+}
+
+// runInstallHooksCommand writes the hooks into the local clone of the config repository
+func runInstallHooksCommand(cmd *cobra.Command) error {
+	output := palantir.GetGlobalOutputHandler()
+	force, _ := cmd.Flags().GetBool("force")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	anvilConfig, err := config.LoadConfig()
+	if err != nil {
+		return errors.NewConfigurationError(constants.OpConfig, "load-config", err)
+	}
+	if anvilConfig.GitHub.ConfigRepo == "" {
+		return errors.NewConfigurationError(constants.OpConfig, "missing-repo",
+			fmt.Errorf("GitHub repository not configured. Please set 'github.config_repo' in your %s", constants.ANVIL_CONFIG_FILE))
+	}
+
+	// The hooks call this binary first so they keep working when anvil is not on git's PATH
+	executable, err := os.Executable()
+	if err != nil {
+		executable = constants.ANVIL
+	}
+
+	githubClient := newGitHubClient(anvilConfig, anvilConfig.GitHub.LocalPath)
+	if dryRun || audit.IsEnabled() {
+		planned, err := githubClient.PlanHooks(cmd.Context(), force)
+		if err != nil {
+			return err
+		}
+		output.PrintHeader("Config Repository Hooks")
+		for _, hook := range planned {
+			output.PrintInfo("Dry run - would install %s", hook.Path)
+			details := ""
+			if hook.BackedUp != "" {
+				details = "keeping the previous hook as " + hook.BackedUp
+				output.PrintInfo("  Previous hook would be kept as %s", hook.BackedUp)
+			}
+			audit.Record("config install-hooks", "write-hook", hook.Path, details)
+		}
+		return nil
+	}
+
+	installed, err := githubClient.InstallHooks(cmd.Context(), executable, force)
+	if err != nil {
+		return err
+	}
+
+	output.PrintHeader("Config Repository Hooks")
+	for _, hook := range installed {
+		output.PrintSuccess(fmt.Sprintf("Installed %s", hook.Path))
+		if hook.BackedUp != "" {
+			output.PrintInfo("  Previous hook kept as %s", hook.BackedUp)
+		}
+	}
+	fmt.Println()
+	output.PrintInfo("Commits scan staged files for secrets and check app sizes against the push limits")
+	if anvilConfig.GitHub.GenerateReadme {
+		output.PrintInfo("Commits also refresh the README index for the apps they touch")
+	}
+	output.PrintInfo("Pushes scan every pushed commit, including ones made with --no-verify")
+	return nil
+}
+
+// runHookCommand runs the checks of one hook inside the repository git is working on
+func runHookCommand(cmd *cobra.Command, name string) error {
+	result, _ := system.RunCommand(constants.GitCommand, "rev-parse", "--show-toplevel")
+	if !result.Success {
+		return errors.NewValidationError(constants.OpPush, "hook", fmt.Errorf("not inside a git repository"))
+	}
+	repoPath := strings.TrimSpace(result.Output)
+
+	// Limits and README settings come from settings.yaml, defaults apply on machines without it
+	anvilConfig, err := config.LoadConfig()
+	if err != nil {
+		anvilConfig = &config.AnvilConfig{}
+	}
+	githubClient := newGitHubClient(anvilConfig, repoPath)
+
+	switch name {
+	case github.HookPreCommit:
+		err = githubClient.RunPreCommitHook(cmd.Context())
+	case github.HookPrePush:
+		err = githubClient.RunPrePushHook(cmd.Context(), os.Stdin)
+	default:
+		return errors.NewValidationError(constants.OpPush, "hook",
+			fmt.Errorf("unknown hook %q, expected one of: %s", name, strings.Join(github.HookNames, ", ")))
+	}
+	return err
+}
+
+// newGitHubClient creates a client for the clone at localPath with the push limits from settings
+func newGitHubClient(anvilConfig *config.AnvilConfig, localPath string) *github.GitHubClient {
+	githubClient := github.NewGitHubClient(
+		anvilConfig.GitHub.ConfigRepo,
+		anvilConfig.GitHub.Branch,
+		localPath,
+		"",
+		anvilConfig.Git.SSHKeyPath,
+		anvilConfig.Git.Username,
+		anvilConfig.Git.Email,
+	)
+	githubClient.MaxPushSizeMB = anvilConfig.GitHub.MaxPushSizeMB
+	githubClient.MaxPushFiles = anvilConfig.GitHub.MaxPushFiles
+	githubClient.GenerateReadme = anvilConfig.GitHub.GenerateReadme
+	return githubClient
+}
+
+func init() {
+	InstallHooksCmd.Flags().Bool("force", false, "Replace existing hooks not written by anvil, keeping them as <name>.anvil-backup")
+	InstallHooksCmd.Flags().Bool("dry-run", false, "Show the hooks that would be written without writing them")
+}
@@ -0,0 +1,65 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package importcmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/0xjuanma/anvil/internal/brew"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/palantir"
+)
+
+// defaultBrewfileGroup is the group Brewfile entries are imported into without --group
+const defaultBrewfileGroup = "brewfile"
+
+// parseBrewfileImport reads a Brewfile into a single group. Lines anvil has no equivalent
+// for and names settings.yaml can't hold, such as versioned or tapped formulas, are reported
+// and left out.
+func parseBrewfileImport(filePath, groupName string) (*ImportConfig, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Brewfile: %w", err)
+	}
+
+	parsed, err := brew.ParseBrewfile(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Brewfile: %w", err)
+	}
+
+	output := palantir.GetGlobalOutputHandler()
+	for _, line := range parsed.Skipped {
+		output.PrintWarning("Skipping unsupported Brewfile line: %s", line)
+	}
+
+	validator := config.NewConfigValidator(nil)
+	var tools []string
+	for _, entry := range parsed.Entries {
+		if err := validator.ValidateAppName(entry); err != nil {
+			output.PrintWarning("Skipping '%s': not a valid settings.yaml entry", entry)
+			continue
+		}
+		tools = append(tools, entry)
+	}
+
+	importConfig := &ImportConfig{Groups: make(map[string][]string)}
+	if len(tools) > 0 {
+		importConfig.Groups[groupName] = tools
+	}
+	return importConfig, nil
+}
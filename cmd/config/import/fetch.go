@@ -26,6 +26,7 @@ import (
 	"time"
 
 	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/system"
 	"gopkg.in/yaml.v2"
 )
 
@@ -60,7 +61,7 @@ func fetchFromURL(ctx context.Context, fileURL string) (string, func(), error) {
 	req.Header.Set("User-Agent", "anvil-cli/1.0")
 
 	// Execute request
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := system.HTTPClient().Do(req)
 	if err != nil {
 		return "", nil, fmt.Errorf("failed to download file: %w", err)
 	}
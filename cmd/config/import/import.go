@@ -21,6 +21,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/0xjuanma/anvil/internal/audit"
 	"github.com/0xjuanma/anvil/internal/config"
 	"github.com/0xjuanma/anvil/internal/constants"
 	"github.com/0xjuanma/anvil/internal/errors"
@@ -31,10 +32,26 @@ import (
 
 var ImportCmd = &cobra.Command{
 	Use:   "import [file-or-url]",
-	Short: "Import groups from a local file or URL",
-	Long:  "Import tool groups from a local YAML file or remote URL into your anvil configuration",
-	Args:  cobra.ExactArgs(1),
+	Short: "Import groups from a local file, URL or Brewfile",
+	Long:  constants.IMPORT_COMMAND_LONG_DESCRIPTION,
+	Args:  cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
+		suggest, _ := cmd.Flags().GetBool("suggest")
+		if suggest {
+			if len(args) > 0 {
+				palantir.GetGlobalOutputHandler().PrintError("--suggest works on installed_apps and takes no file or URL")
+				return
+			}
+			if err := runSuggestCommand(); err != nil {
+				palantir.GetGlobalOutputHandler().PrintError("Suggesting groups failed: %v", err)
+			}
+			return
+		}
+		if len(args) == 0 {
+			palantir.GetGlobalOutputHandler().PrintError("Provide a file or URL to import, or use --suggest")
+			return
+		}
+
 		importPath := args[0]
 		if err := runImportCommand(cmd, importPath); err != nil {
 			palantir.GetGlobalOutputHandler().PrintError("Import failed: %v", err)
@@ -67,7 +84,13 @@ func runImportCommand(cmd *cobra.Command, importPath string) error {
 
 	// Stage 2: Parse and validate import data
 	output.PrintStage("Parsing import file...")
-	importData, err := parseImportFile(tempFile)
+	var importData *ImportConfig
+	if fromBrewfile, _ := cmd.Flags().GetBool("brewfile"); fromBrewfile {
+		groupName, _ := cmd.Flags().GetString("group")
+		importData, err = parseBrewfileImport(tempFile, groupName)
+	} else {
+		importData, err = parseImportFile(tempFile)
+	}
 	if err != nil {
 		return errors.NewConfigurationError(constants.OpConfig, "parse-import", err)
 	}
@@ -103,8 +126,16 @@ func runImportCommand(cmd *cobra.Command, importPath string) error {
 	output.PrintStage("Preparing import summary...")
 	displayImportSummary(importData.Groups)
 
+	if audit.IsEnabled() {
+		for groupName := range importData.Groups {
+			audit.Record("import", "add-group", groupName, importPath)
+		}
+		output.PrintInfo("Audit mode - would import %d groups", len(importData.Groups))
+		return nil
+	}
+
 	// Stage 6: Confirm import
-	if !output.Confirm("Proceed with importing these groups?") {
+	if !charm.Confirm(charm.ConfirmSettings, "Proceed with importing these groups?") {
 		output.PrintInfo("Import cancelled by user")
 		return nil
 	}
@@ -218,3 +249,9 @@ func importGroups(currentConfig *config.AnvilConfig, importGroups map[string][]s
 	// Save updated configuration
 	return config.SaveConfig(currentConfig)
 }
+
+func init() {
+	ImportCmd.Flags().Bool("suggest", false, "Suggest groups for installed_apps entries by category and review them")
+	ImportCmd.Flags().Bool("brewfile", false, "Read the file as a Brewfile and import its brew, cask and mas lines as one group")
+	ImportCmd.Flags().String("group", defaultBrewfileGroup, "Group name for --brewfile imports")
+}
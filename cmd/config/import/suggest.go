@@ -0,0 +1,230 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package importcmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/0xjuanma/anvil/internal/audit"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+	"github.com/0xjuanma/anvil/internal/utils"
+	"github.com/0xjuanma/palantir"
+	"github.com/charmbracelet/x/term"
+)
+
+// runSuggestCommand proposes groups for installed_apps entries and moves the accepted ones
+func runSuggestCommand() error {
+	output := palantir.GetGlobalOutputHandler()
+	output.PrintHeader("Suggest Groups")
+
+	currentConfig, err := config.LoadConfig()
+	if err != nil {
+		return errors.NewConfigurationError(constants.OpConfig, "load-config", err)
+	}
+
+	suggestions, uncategorized := config.SuggestGroups(currentConfig)
+	if len(suggestions) == 0 {
+		output.PrintInfo("No suggestions: none of the ungrouped installed_apps entries match a known category")
+		return nil
+	}
+	displaySuggestions(suggestions, uncategorized)
+
+	if audit.IsEnabled() {
+		for _, suggestion := range suggestions {
+			audit.Record("import", "suggest-group", suggestion.Group, strings.Join(suggestion.Apps, ", "))
+		}
+		output.PrintInfo("Audit mode - would suggest %d groups", len(suggestions))
+		return nil
+	}
+
+	accepted := suggestions
+	if !charm.AssumeYes() {
+		if !term.IsTerminal(os.Stdin.Fd()) {
+			output.PrintInfo("Run in a terminal to review the suggestions, or pass --yes to accept them all")
+			return nil
+		}
+		var ok bool
+		accepted, ok = reviewSuggestions(bufio.NewReader(os.Stdin), suggestions, currentConfig.Groups)
+		if !ok {
+			output.PrintInfo("Suggestions cancelled, nothing changed")
+			return nil
+		}
+	}
+	if len(accepted) == 0 {
+		output.PrintInfo("No suggestions accepted, nothing changed")
+		return nil
+	}
+
+	apps := 0
+	for _, suggestion := range accepted {
+		apps += len(suggestion.Apps)
+	}
+	if !charm.Confirm(charm.ConfirmSettings, fmt.Sprintf("Move %d apps into %d groups?", apps, len(accepted))) {
+		output.PrintInfo("Suggestions cancelled, nothing changed")
+		return nil
+	}
+
+	config.ApplyGroupSuggestions(currentConfig, accepted)
+	if err := config.SaveConfig(currentConfig); err != nil {
+		return errors.NewConfigurationError(constants.OpConfig, "save-groups", err)
+	}
+
+	output.PrintSuccess(fmt.Sprintf("Moved %d apps from installed_apps into %d groups", apps, len(accepted)))
+	for _, suggestion := range accepted {
+		output.PrintInfo("  anvil install %s", suggestion.Group)
+	}
+	return nil
+}
+
+// displaySuggestions shows a tree view of the suggested groups and the apps left as they are
+func displaySuggestions(suggestions []config.GroupSuggestion, uncategorized []string) {
+	output := palantir.GetGlobalOutputHandler()
+	fmt.Println("")
+	output.PrintInfo("📋 Suggested Groups:")
+	output.PrintInfo("═══════════════════")
+
+	for _, suggestion := range suggestions {
+		output.PrintInfo("├── 📁 %s", suggestionLabel(suggestion))
+		for i, app := range suggestion.Apps {
+			if i == len(suggestion.Apps)-1 {
+				output.PrintInfo("│   └── 🔧 %s", app)
+			} else {
+				output.PrintInfo("│   ├── 🔧 %s", app)
+			}
+		}
+		output.PrintInfo("│")
+	}
+
+	if len(uncategorized) > 0 {
+		output.PrintInfo("Left in installed_apps (no known category): %s", strings.Join(uncategorized, ", "))
+	}
+	fmt.Println("")
+}
+
+// suggestionLabel describes a suggestion as "name (category, N apps, target)"
+func suggestionLabel(suggestion config.GroupSuggestion) string {
+	target := "new group"
+	if suggestion.Existing {
+		target = "adds to existing group"
+	}
+	apps := fmt.Sprintf("%d apps", len(suggestion.Apps))
+	if len(suggestion.Apps) == 1 {
+		apps = "1 app"
+	}
+	return fmt.Sprintf("%s (%s, %s, %s)", suggestion.Group, suggestion.Title, apps, target)
+}
+
+// reviewSuggestions lets the user accept, rename, trim or skip each suggestion.
+// It returns false when input ends before every suggestion was reviewed.
+func reviewSuggestions(reader *bufio.Reader, suggestions []config.GroupSuggestion, groups config.AnvilGroups) ([]config.GroupSuggestion, bool) {
+	var accepted []config.GroupSuggestion
+	for _, suggestion := range suggestions {
+		for reviewing := true; reviewing; {
+			fmt.Printf("%s\n  %s\n", suggestionLabel(suggestion), strings.Join(suggestion.Apps, ", "))
+			fmt.Print("[a]ccept / [r]ename / [e]dit apps / [s]kip: ")
+			answer, err := reader.ReadString('\n')
+
+			switch strings.ToLower(strings.TrimSpace(answer)) {
+			case "a", "accept":
+				accepted = append(accepted, suggestion)
+				reviewing = false
+			case "s", "skip":
+				reviewing = false
+			case "r", "rename":
+				name, ok := promptGroupName(reader)
+				if !ok {
+					return nil, false
+				}
+				_, suggestion.Existing = groups[name]
+				suggestion.Group = name
+			case "e", "edit":
+				apps, ok := promptApps(reader, suggestion.Apps)
+				if !ok {
+					return nil, false
+				}
+				suggestion.Apps = apps
+			default:
+				if err != nil {
+					// Input closed, treat as cancel so nothing changes unattended
+					fmt.Println()
+					return nil, false
+				}
+			}
+		}
+		fmt.Println()
+	}
+	return accepted, true
+}
+
+// promptGroupName asks for a new group name until a valid one is given
+func promptGroupName(reader *bufio.Reader) (string, bool) {
+	validator := config.NewConfigValidator(nil)
+	for {
+		fmt.Print("Group name (existing groups are extended): ")
+		answer, err := reader.ReadString('\n')
+		name := strings.TrimSpace(answer)
+		if name != "" {
+			validationErr := validator.ValidateGroupName(name)
+			if validationErr == nil {
+				return name, true
+			}
+			fmt.Printf("  %v\n", validationErr)
+		}
+		if err != nil {
+			fmt.Println()
+			return "", false
+		}
+	}
+}
+
+// promptApps asks which of the suggested apps to keep, keeping all on an empty answer
+func promptApps(reader *bufio.Reader, apps []string) ([]string, bool) {
+	for i, app := range apps {
+		fmt.Printf("  %d. %s\n", i+1, app)
+	}
+	for {
+		fmt.Print("Apps to keep (e.g. 1,3-5), or press enter to keep all: ")
+		answer, err := reader.ReadString('\n')
+		answer = strings.TrimSpace(answer)
+		if answer == "" {
+			if err != nil {
+				fmt.Println()
+			}
+			return apps, err == nil
+		}
+
+		indexes, parseErr := utils.ParseSelection(answer, len(apps))
+		if parseErr == nil {
+			kept := make([]string, 0, len(indexes))
+			for _, index := range indexes {
+				kept = append(kept, apps[index])
+			}
+			return kept, true
+		}
+		if err != nil {
+			fmt.Println()
+			return nil, false
+		}
+		fmt.Printf("  %v\n", parseErr)
+	}
+}
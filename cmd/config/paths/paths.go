@@ -0,0 +1,362 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package paths
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/0xjuanma/anvil/internal/audit"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/system"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+	"github.com/0xjuanma/palantir"
+	"github.com/spf13/cobra"
+)
+
+// promptInput is where the editor reads commands from
+var promptInput io.Reader = os.Stdin
+
+var PathsCmd = &cobra.Command{
+	Use:   "paths",
+	Short: "Manage the configs entries mapping apps to local paths",
+	Long:  constants.PATHS_COMMAND_LONG_DESCRIPTION,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var editCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Add, edit and remove configs entries in an interactive table",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runEditCommand(cmd, bufio.NewReader(promptInput)); err != nil {
+			palantir.GetGlobalOutputHandler().PrintError("Paths edit failed: %v", err)
+		}
+	},
+}
+
+// pathEntry is one row of the editor, Path is stored expanded
+type pathEntry struct {
+	App  string
+	Path string
+}
+
+// editor holds the entries being edited and the ones loaded from settings.yaml
+type editor struct {
+	entries  []pathEntry
+	original map[string]string
+	reader   *bufio.Reader
+}
+
+// runEditCommand loads the configs entries and runs the editor until it is saved or quit
+func runEditCommand(cmd *cobra.Command, reader *bufio.Reader) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	output := palantir.GetGlobalOutputHandler()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return errors.NewConfigurationError(constants.OpConfig, "load-config", err)
+	}
+	ed := newEditor(cfg.Configs, reader)
+
+	output.PrintHeader("Config Paths")
+	for {
+		ed.render()
+		fmt.Print("[a]dd, [e]dit N, [r]emove N, [s]ave, [q]uit: ")
+		line, readErr := reader.ReadString('\n')
+		action, index, parseErr := parseEditCommand(line, len(ed.entries))
+		if readErr != nil && strings.TrimSpace(line) == "" {
+			fmt.Println()
+			action = "q"
+		} else if parseErr != nil {
+			output.PrintWarning("%v", parseErr)
+			continue
+		}
+
+		switch action {
+		case "a":
+			ed.add()
+		case "e":
+			ed.edit(index)
+		case "r":
+			removed := ed.entries[index]
+			ed.entries = append(ed.entries[:index], ed.entries[index+1:]...)
+			output.PrintInfo("Removed %s", removed.App)
+		case "s":
+			saved, err := ed.save(dryRun)
+			if err != nil || saved {
+				return err
+			}
+		case "q":
+			if ed.hasChanges() && readErr == nil && !charm.Confirm(charm.ConfirmSettings, "Discard unsaved changes?") {
+				continue
+			}
+			output.PrintInfo("No changes were written to %s", constants.ANVIL_CONFIG_FILE)
+			return nil
+		}
+	}
+}
+
+// newEditor returns an editor over configs, sorted by app name
+func newEditor(configs map[string]string, reader *bufio.Reader) *editor {
+	ed := &editor{original: make(map[string]string, len(configs)), reader: reader}
+	for app, path := range configs {
+		ed.original[app] = path
+		ed.entries = append(ed.entries, pathEntry{App: app, Path: path})
+	}
+	sort.Slice(ed.entries, func(i, j int) bool { return ed.entries[i].App < ed.entries[j].App })
+	return ed
+}
+
+// parseEditCommand reads an editor command, index is zero-based for edit and remove
+func parseEditCommand(line string, count int) (string, int, error) {
+	fields := strings.Fields(strings.ToLower(line))
+	if len(fields) == 0 {
+		return "", 0, fmt.Errorf("enter a command")
+	}
+
+	action := fields[0][:1]
+	switch action {
+	case "a", "s", "q":
+		return action, 0, nil
+	case "e", "r":
+		if len(fields) < 2 {
+			return "", 0, fmt.Errorf("add the row number, e.g. '%s 2'", action)
+		}
+		number, err := strconv.Atoi(fields[1])
+		if err != nil || number < 1 || number > count {
+			return "", 0, fmt.Errorf("row must be between 1 and %d", count)
+		}
+		return action, number - 1, nil
+	}
+	return "", 0, fmt.Errorf("unknown command '%s'", fields[0])
+}
+
+// render prints the entries with the result of validating each path
+func (ed *editor) render() {
+	if len(ed.entries) == 0 {
+		palantir.GetGlobalOutputHandler().PrintInfo("No configs entries yet, press 'a' to add one")
+		return
+	}
+
+	rows := make([][]string, 0, len(ed.entries))
+	for i, entry := range ed.entries {
+		status := "✓"
+		if problem := config.CheckConfigPath(entry.Path); problem != "" {
+			status = "✗ " + problem
+		}
+		rows = append(rows, []string{strconv.Itoa(i + 1), entry.App, displayPath(entry.Path), status})
+	}
+	fmt.Println(charm.RenderTable([]string{"#", "App", "Path", "Status"}, rows))
+}
+
+// add asks for a new app and its path
+func (ed *editor) add() {
+	app, ok := ed.prompt("App name: ", "")
+	if !ok {
+		return
+	}
+	if err := ed.checkName(app, -1); err != nil {
+		palantir.GetGlobalOutputHandler().PrintWarning("%v", err)
+		return
+	}
+	path, ok := ed.promptPath("")
+	if !ok {
+		return
+	}
+	ed.entries = append(ed.entries, pathEntry{App: app, Path: path})
+}
+
+// edit asks for a new name and path of a row, enter keeps the current value
+func (ed *editor) edit(index int) {
+	entry := ed.entries[index]
+	app, ok := ed.prompt(fmt.Sprintf("App name [%s]: ", entry.App), entry.App)
+	if !ok {
+		return
+	}
+	if err := ed.checkName(app, index); err != nil {
+		palantir.GetGlobalOutputHandler().PrintWarning("%v", err)
+		return
+	}
+	path, ok := ed.promptPath(entry.Path)
+	if !ok {
+		return
+	}
+	ed.entries[index] = pathEntry{App: app, Path: path}
+}
+
+// checkName validates an app name, which must not be used by another row than index
+func (ed *editor) checkName(app string, index int) error {
+	if err := config.ValidateConfigAppName(app); err != nil {
+		return err
+	}
+	for i, entry := range ed.entries {
+		if i != index && entry.App == app {
+			return fmt.Errorf("'%s' already has a configs entry, edit row %d instead", app, i+1)
+		}
+	}
+	return nil
+}
+
+// promptPath asks for a path, expands "~" and reports right away whether it exists. A path
+// that doesn't exist is only kept when confirmed, repo-only apps may not have one yet.
+func (ed *editor) promptPath(current string) (string, bool) {
+	output := palantir.GetGlobalOutputHandler()
+	label := "Path: "
+	if current != "" {
+		label = fmt.Sprintf("Path [%s]: ", displayPath(current))
+	}
+
+	for {
+		answer, ok := ed.prompt(label, current)
+		if !ok {
+			return "", false
+		}
+		path := config.ExpandConfigPath(answer)
+		problem := config.CheckConfigPath(path)
+		switch {
+		case problem == "":
+			output.PrintSuccess(fmt.Sprintf("%s exists", path))
+			return path, true
+		case problem == "does not exist":
+			if charm.Confirm(charm.ConfirmSettings, fmt.Sprintf("%s does not exist, keep it anyway?", path)) {
+				return path, true
+			}
+		default:
+			output.PrintWarning("%s: %s", answer, problem)
+		}
+	}
+}
+
+// prompt reads one answer, fallback is used for an empty answer and false means cancelled
+func (ed *editor) prompt(label, fallback string) (string, bool) {
+	fmt.Print(label)
+	answer, err := ed.reader.ReadString('\n')
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		if err != nil || fallback == "" {
+			return "", false
+		}
+		return fallback, true
+	}
+	return answer, true
+}
+
+// paths returns the edited entries as the configs map
+func (ed *editor) paths() map[string]string {
+	paths := make(map[string]string, len(ed.entries))
+	for _, entry := range ed.entries {
+		paths[entry.App] = entry.Path
+	}
+	return paths
+}
+
+// hasChanges reports whether the entries differ from settings.yaml
+func (ed *editor) hasChanges() bool {
+	added, changed, removed := diffPaths(ed.original, ed.paths())
+	return len(added)+len(changed)+len(removed) > 0
+}
+
+// save shows the changes and writes them to settings.yaml once confirmed. It returns true
+// when the editor is done, false to keep editing.
+func (ed *editor) save(dryRun bool) (bool, error) {
+	output := palantir.GetGlobalOutputHandler()
+	edited := ed.paths()
+	added, changed, removed := diffPaths(ed.original, edited)
+	if len(added)+len(changed)+len(removed) == 0 {
+		output.PrintInfo("No changes to save")
+		return true, nil
+	}
+
+	output.PrintStage("Changes to configs")
+	for _, app := range added {
+		output.PrintInfo("  + %s: %s", app, displayPath(edited[app]))
+	}
+	for _, app := range changed {
+		output.PrintInfo("  ~ %s: %s → %s", app, displayPath(ed.original[app]), displayPath(edited[app]))
+	}
+	for _, app := range removed {
+		output.PrintInfo("  - %s", app)
+	}
+
+	if dryRun {
+		output.PrintInfo("Dry run mode - %s was not modified", constants.ANVIL_CONFIG_FILE)
+		audit.Record("config paths edit", "update-configs", config.GetAnvilConfigPath(),
+			fmt.Sprintf("%d added, %d changed, %d removed", len(added), len(changed), len(removed)))
+		return true, nil
+	}
+	if !charm.Confirm(charm.ConfirmSettings, fmt.Sprintf("Save these changes to %s?", constants.ANVIL_CONFIG_FILE)) {
+		return false, nil
+	}
+
+	if err := config.SaveConfigPaths(edited); err != nil {
+		return true, errors.NewConfigurationError(constants.OpConfig, "save-config", err)
+	}
+	output.PrintSuccess(fmt.Sprintf("Updated %d configs entries in %s", len(added)+len(changed)+len(removed), constants.ANVIL_CONFIG_FILE))
+	return true, nil
+}
+
+// diffPaths compares two configs maps, each result sorted by app name
+func diffPaths(before, after map[string]string) (added, changed, removed []string) {
+	for app, path := range after {
+		previous, found := before[app]
+		switch {
+		case !found:
+			added = append(added, app)
+		case previous != path:
+			changed = append(changed, app)
+		}
+	}
+	for app := range before {
+		if _, found := after[app]; !found {
+			removed = append(removed, app)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(changed)
+	sort.Strings(removed)
+	return added, changed, removed
+}
+
+// displayPath shortens the home directory to "~" for display
+func displayPath(path string) string {
+	homeDir, err := system.GetHomeDir()
+	if err != nil || homeDir == "" {
+		return path
+	}
+	if path == homeDir {
+		return "~"
+	}
+	if rest, found := strings.CutPrefix(path, homeDir+string(os.PathSeparator)); found {
+		return "~/" + rest
+	}
+	return path
+}
+
+func init() {
+	PathsCmd.AddCommand(editCmd)
+	editCmd.Flags().Bool("dry-run", false, "Show the changes that would be saved without writing settings.yaml")
+}
@@ -0,0 +1,71 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package paths
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseEditCommand(t *testing.T) {
+	tests := []struct {
+		line   string
+		action string
+		index  int
+		ok     bool
+	}{
+		{"a\n", "a", 0, true},
+		{"edit 2", "e", 1, true},
+		{"r 3", "r", 2, true},
+		{"save", "s", 0, true},
+		{"e", "", 0, false},
+		{"r 4", "", 0, false},
+		{"x", "", 0, false},
+		{"  ", "", 0, false},
+	}
+	for _, tt := range tests {
+		action, index, err := parseEditCommand(tt.line, 3)
+		if (err == nil) != tt.ok || action != tt.action || index != tt.index {
+			t.Errorf("parseEditCommand(%q) = %q, %d, %v", tt.line, action, index, err)
+		}
+	}
+}
+
+func TestEditorChanges(t *testing.T) {
+	existing := t.TempDir()
+	ed := newEditor(map[string]string{"zsh": "/missing/.zshrc", "nvim": existing},
+		bufio.NewReader(strings.NewReader("ghostty\n"+existing+"\n")))
+	if ed.entries[0].App != "nvim" {
+		t.Errorf("entries not sorted: %+v", ed.entries)
+	}
+
+	ed.add()
+	if !ed.hasChanges() || len(ed.entries) != 3 {
+		t.Fatalf("add did not append an entry: %+v", ed.entries)
+	}
+	if err := ed.checkName("zsh", -1); err == nil {
+		t.Error("a duplicate app name should be rejected")
+	}
+
+	ed.entries = ed.entries[:1]
+	added, changed, removed := diffPaths(ed.original, ed.paths())
+	if len(added) != 0 || len(changed) != 0 || !reflect.DeepEqual(removed, []string{"zsh"}) {
+		t.Errorf("diffPaths = %v, %v, %v", added, changed, removed)
+	}
+}
@@ -0,0 +1,85 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pull
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/audit"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/i18n"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+	"github.com/0xjuanma/palantir"
+)
+
+// runPruneTemp removes pulled copies older than olderThan days, or than temp.max_age_days when zero.
+// Stale copies are still picked up by sync and push, so dropping them avoids applying old configs.
+func runPruneTemp(olderThan int, dryRun bool) error {
+	output := palantir.GetGlobalOutputHandler()
+	output.PrintHeader(i18n.T("pull.prune.title"))
+
+	maxAge := config.GetTempMaxAge()
+	if olderThan > 0 {
+		maxAge = time.Duration(olderThan) * 24 * time.Hour
+	}
+
+	now := time.Now()
+	stale, err := config.StaleTempEntries(maxAge, now)
+	if err != nil {
+		return errors.NewFileSystemError(constants.OpPull, "list-temp", err)
+	}
+	if len(stale) == 0 {
+		output.PrintSuccess(i18n.T("pull.prune.none", config.FormatAge(maxAge)))
+		return nil
+	}
+
+	output.PrintStage(i18n.T("pull.prune.found", len(stale), config.FormatAge(maxAge)))
+	for _, entry := range stale {
+		output.PrintInfo(i18n.T("pull.prune.entry"), entry.Name, config.FormatAge(entry.Age(now)), entry.ShortCommit())
+	}
+
+	if dryRun || audit.IsEnabled() {
+		output.PrintInfo(i18n.T("pull.prune.dry_run"))
+		for _, entry := range stale {
+			audit.Record("config pull", "remove-temp", entry.Path, fmt.Sprintf("pulled %s ago", config.FormatAge(entry.Age(now))))
+		}
+		return nil
+	}
+
+	if !charm.Confirm(charm.ConfirmDelete, i18n.T("pull.prune.confirm", len(stale))) {
+		output.PrintInfo(i18n.T("pull.prune.cancelled"))
+		return nil
+	}
+
+	var failed int
+	for _, entry := range stale {
+		if err := config.RemoveTempEntry(entry.Name); err != nil {
+			output.PrintWarning(i18n.T("pull.prune.remove_failed"), entry.Name, err)
+			failed++
+			continue
+		}
+		output.PrintSuccess(i18n.T("pull.prune.removed", entry.Name))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf(i18n.T("pull.prune.failed"), failed)
+	}
+	return nil
+}
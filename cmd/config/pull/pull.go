@@ -21,38 +21,73 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/0xjuanma/anvil/internal/audit"
 	"github.com/0xjuanma/anvil/internal/config"
 	"github.com/0xjuanma/anvil/internal/constants"
 	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/events"
 	"github.com/0xjuanma/anvil/internal/github"
+	"github.com/0xjuanma/anvil/internal/i18n"
 	"github.com/0xjuanma/anvil/internal/terminal/charm"
+	"github.com/0xjuanma/anvil/internal/timeline"
 	"github.com/0xjuanma/anvil/internal/utils"
 	"github.com/0xjuanma/palantir"
 	"github.com/spf13/cobra"
 )
 
+// defaultPullWorkers bounds concurrent directory copies when pulling several apps
+const defaultPullWorkers = 4
+
+// preparedRepos remembers repositories already fetched in this run and the commit they were
+// updated to, keyed by local path and branch
+var (
+	preparedRepos = make(map[string]string)
+	preparedMutex sync.Mutex
+)
+
 var PullCmd = &cobra.Command{
-	Use:   "pull [directory]",
+	Use:   "pull [directory...]",
 	Short: "Pull configuration files from a specific directory in GitHub repository",
 	Long:  constants.PULL_COMMAND_LONG_DESCRIPTION,
-	Args:  cobra.MaximumNArgs(1),
+	Args:  cobra.ArbitraryArgs,
 	Run: func(cmd *cobra.Command, args []string) {
 		if err := runPullCommand(cmd, args); err != nil {
-			palantir.GetGlobalOutputHandler().PrintError("Pull failed: %v", err)
+			palantir.GetGlobalOutputHandler().PrintError(i18n.T("pull.failed"), err)
 			return
 		}
 	},
 }
 
-// runPullCommand executes the configuration pull process for a specific directory
+// runPullCommand executes the configuration pull process for one or more directories
 func runPullCommand(cmd *cobra.Command, args []string) error {
+	all, _ := cmd.Flags().GetBool("all")
+	workers, _ := cmd.Flags().GetInt("workers")
+	if pruneTemp, _ := cmd.Flags().GetBool("prune-temp"); pruneTemp {
+		if all || len(args) > 0 {
+			return errors.NewValidationError(constants.OpPull, "args", fmt.Errorf("%s", i18n.T("pull.prune.with_targets")))
+		}
+		olderThan, _ := cmd.Flags().GetInt("older-than")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		return runPruneTemp(olderThan, dryRun)
+	}
+	if all && len(args) > 0 {
+		return errors.NewValidationError(constants.OpPull, "args", fmt.Errorf("%s", i18n.T("pull.all_with_targets")))
+	}
+
 	// Default to "anvil" if no argument provided
-	targetDir := constants.ANVIL
-	if len(args) > 0 {
-		targetDir = args[0]
+	targets := uniqueTargets(args)
+	if len(targets) == 0 && !all {
+		targets = []string{constants.ANVIL}
+	}
+
+	remote, _ := cmd.Flags().GetString("remote")
+	if err := config.UseRemote(remote); err != nil {
+		return errors.NewConfigurationError(constants.OpPull, "remote", err)
 	}
 
 	// Load configuration
@@ -65,22 +100,101 @@ func runPullCommand(cmd *cobra.Command, args []string) error {
 	if err := validateGitHubConfig(cfg); err != nil {
 		return err
 	}
+	for _, target := range targets {
+		if err := cfg.CheckAppMode(target, constants.OpPull); err != nil {
+			return errors.NewValidationError(constants.OpPull, target, err)
+		}
+	}
 	output := palantir.GetGlobalOutputHandler()
-	output.PrintHeader(fmt.Sprintf("Pull '%s' Configuration", targetDir))
-	output.PrintInfo("Repository: %s", cfg.GitHub.ConfigRepo)
-	output.PrintInfo("Branch: %s", cfg.GitHub.Branch)
-	output.PrintInfo("Target directory: %s", targetDir)
+	if len(targets) == 1 {
+		output.PrintHeader(i18n.T("pull.title_one", targets[0]))
+	} else {
+		output.PrintHeader(i18n.T("pull.title"))
+	}
+	if remote != "" {
+		output.PrintInfo(i18n.T("pull.remote"), remote)
+	}
+	output.PrintInfo(i18n.T("pull.repository"), cfg.GitHub.ConfigRepo)
+	output.PrintInfo(i18n.T("pull.branch"), cfg.GitHub.Branch)
+	if all {
+		output.PrintInfo(i18n.T("pull.targets_all"))
+	} else {
+		output.PrintInfo(i18n.T("pull.targets"), strings.Join(targets, ", "))
+	}
 	fmt.Println("")
 
+	if audit.IsEnabled() {
+		if all {
+			targets = []string{"*"}
+		}
+		for _, targetDir := range targets {
+			output.PrintInfo(i18n.T("pull.audit"), targetDir, filepath.Join(config.GetAnvilConfigDirectory(), "temp", targetDir))
+			audit.Record("pull", "download-config", targetDir,
+				fmt.Sprintf("from %s (branch %s)", cfg.GitHub.ConfigRepo, cfg.GitHub.Branch))
+		}
+		return nil
+	}
+
+	// Create context with timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	// Stages 1-4 run once per repository, later pulls in the same run reuse the fetched clone
+	if err := prepareRepository(ctx, cfg); err != nil {
+		return err
+	}
+
+	if all {
+		if targets, err = listRepoDirectories(cfg.GitHub.LocalPath); err != nil {
+			return errors.NewFileSystemError(constants.OpPull, "list-directories", err)
+		}
+		targets = skipBlockedTargets(cfg, targets)
+		if len(targets) == 0 {
+			output.PrintWarning(i18n.T("pull.no_directories"), cfg.GitHub.ConfigRepo)
+			return nil
+		}
+	}
+
+	if len(targets) > 1 {
+		return pullDirectories(cfg, targets, workers)
+	}
+
+	// Stage 5: Copy configuration directory
+	targetDir := targets[0]
+	output.PrintStage(i18n.T("pull.stage.copy_one"))
+	spinner := charm.NewDotsSpinner(i18n.T("pull.copying_one", targetDir))
+	spinner.Start()
+	tempDir, err := copyDirectoryToTemp(cfg, targetDir)
+	if err != nil {
+		spinner.Error(i18n.T("pull.copy_failed"))
+		return err
+	}
+	spinner.Success(i18n.T("pull.copied_one"))
+
+	displaySuccessMessage(targetDir, tempDir, cfg)
+	return nil
+}
+
+// prepareRepository checks authentication and access, then clones or updates the local repository.
+// The result is remembered, so pulling several directories in one run fetches only once.
+func prepareRepository(ctx context.Context, cfg *config.AnvilConfig) error {
+	output := palantir.GetGlobalOutputHandler()
+	preparedMutex.Lock()
+	defer preparedMutex.Unlock()
+	if _, ok := preparedRepos[repoKey(cfg)]; ok {
+		output.PrintInfo(i18n.T("pull.repo_reused"))
+		return nil
+	}
+
 	// Stage 1: Authentication check
-	output.PrintStage("Checking authentication...")
+	output.PrintStage(i18n.T("pull.stage.auth"))
 	token := ""
 	if cfg.GitHub.TokenEnvVar != "" {
 		token = os.Getenv(cfg.GitHub.TokenEnvVar)
 		if token != "" {
-			output.PrintSuccess(fmt.Sprintf("GitHub token found in environment variable: %s", cfg.GitHub.TokenEnvVar))
+			output.PrintSuccess(i18n.T("pull.token_found", cfg.GitHub.TokenEnvVar))
 		} else {
-			output.PrintWarning("No GitHub token found in %s - will attempt SSH authentication", cfg.GitHub.TokenEnvVar)
+			output.PrintWarning(i18n.T("pull.token_missing"), cfg.GitHub.TokenEnvVar)
 		}
 	}
 
@@ -94,109 +208,282 @@ func runPullCommand(cmd *cobra.Command, args []string) error {
 		cfg.Git.Username,
 		cfg.Git.Email,
 	)
-
-	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	defer cancel()
+	githubClient.CloneDepth = cfg.GitHub.CloneDepth
 
 	// Stage 2: Repository validation
-	output.PrintStage("Stage 2: Validating repository access...")
-	spinner := charm.NewCircleSpinner("Validating repository access and branch configuration")
+	output.PrintStage(i18n.T("pull.stage.validate"))
+	spinner := charm.NewCircleSpinner(i18n.T("pull.validating"))
 	spinner.Start()
 	if err := githubClient.ValidateRepository(ctx); err != nil {
-		spinner.Error("Repository validation failed")
+		spinner.Error(i18n.T("pull.validate_failed"))
 		// Provide additional context for repository validation errors
 		if strings.Contains(err.Error(), "Branch Configuration Error") {
 			fmt.Println("")
 			output.PrintError("%s", err.Error())
 			fmt.Println("")
-			output.PrintInfo("The repository exists but the configured branch is not available.")
-			output.PrintInfo("    You may need to:")
-			output.PrintInfo("    • Update the branch in your %s", constants.ANVIL_CONFIG_FILE)
-			output.PrintInfo("    • Or check the available branches in your repository")
+			output.PrintInfo(i18n.T("pull.branch_missing"))
+			output.PrintInfo(i18n.T("pull.may_need"))
+			output.PrintInfo(i18n.T("pull.update_branch"), constants.ANVIL_CONFIG_FILE)
+			output.PrintInfo(i18n.T("pull.check_branches"))
 			return fmt.Errorf("repository validation failed due to branch configuration issue")
 		}
 		return fmt.Errorf("failed to validate repository: %w", err)
 	}
-	spinner.Success("Repository access confirmed")
+	spinner.Success(i18n.T("pull.access_confirmed"))
 
 	// Stage 3: Clone/update repository
-	output.PrintStage("Stage 3: Cloning or updating repository...")
-	spinner = charm.NewDotsSpinner("Cloning or updating repository")
+	output.PrintStage(i18n.T("pull.stage.clone"))
+	spinner = charm.NewDotsSpinner(i18n.T("pull.cloning"))
 	spinner.Start()
+	githubClient.Progress = spinner.SetDetail
 	if err := githubClient.CloneRepository(ctx); err != nil {
-		spinner.Error("Clone failed")
+		spinner.Error(i18n.T("pull.clone_failed"))
 		// Provide additional context for clone errors
 		if strings.Contains(err.Error(), "Branch Configuration Error") {
 			fmt.Println("")
 			output.PrintError("%s", err.Error())
 			fmt.Println("")
-			output.PrintInfo("The repository exists but the configured branch is not available during clone.")
-			output.PrintInfo("    You may need to:")
-			output.PrintInfo("    • Update the branch in your %s", constants.ANVIL_CONFIG_FILE)
-			output.PrintInfo("    • Or delete the local repository at: %s", cfg.GitHub.LocalPath)
-			output.PrintInfo("      (It will be re-cloned with the correct branch)")
+			output.PrintInfo(i18n.T("pull.branch_missing_clone"))
+			output.PrintInfo(i18n.T("pull.may_need"))
+			output.PrintInfo(i18n.T("pull.update_branch"), constants.ANVIL_CONFIG_FILE)
+			output.PrintInfo(i18n.T("pull.delete_local"), cfg.GitHub.LocalPath)
+			output.PrintInfo(i18n.T("pull.recloned"))
 			return fmt.Errorf("clone failed due to branch configuration issue")
 		}
 		return fmt.Errorf("failed to clone repository: %w", err)
 	}
-	spinner.Success("Repository ready")
+	spinner.Success(i18n.T("pull.repo_ready"))
 
 	// Stage 4: Pull latest changes
-	output.PrintStage("Stage 4: Pulling latest changes...")
-	spinner = charm.NewDotsSpinner("Pulling latest changes")
+	output.PrintStage(i18n.T("pull.stage.pull"))
+	spinner = charm.NewDotsSpinner(i18n.T("pull.pulling"))
 	spinner.Start()
+	githubClient.Progress = spinner.SetDetail
 	if err := githubClient.PullChanges(ctx); err != nil {
-		spinner.Error("Pull failed")
+		spinner.Error(i18n.T("pull.pull_failed"))
 		// Provide additional context for branch configuration errors during pull
 		if strings.Contains(err.Error(), "Branch Configuration Error") {
 			output.PrintError("%s", err.Error())
 			fmt.Println("")
-			output.PrintInfo("The local repository exists but the configured branch is not available.")
-			output.PrintInfo("    You may need to:")
-			output.PrintInfo("    • Update the branch in your %s", constants.ANVIL_CONFIG_FILE)
-			output.PrintInfo("    • Or delete the local repository at: %s", cfg.GitHub.LocalPath)
-			output.PrintInfo("      (It will be re-cloned with the correct branch)")
+			output.PrintInfo(i18n.T("pull.branch_missing_local"))
+			output.PrintInfo(i18n.T("pull.may_need"))
+			output.PrintInfo(i18n.T("pull.update_branch"), constants.ANVIL_CONFIG_FILE)
+			output.PrintInfo(i18n.T("pull.delete_local"), cfg.GitHub.LocalPath)
+			output.PrintInfo(i18n.T("pull.recloned"))
 			return fmt.Errorf("pull failed due to branch configuration issue")
 		}
 		return fmt.Errorf("failed to pull changes: %w", err)
 	}
-	spinner.Success("Repository updated")
+	spinner.Success(i18n.T("pull.repo_updated"))
 
-	// Stage 5: Copy configuration directory
-	output.PrintStage("Stage 5: Copying configuration directory...")
-	spinner = charm.NewDotsSpinner(fmt.Sprintf("Copying %s directory", targetDir))
+	// The commit is only metadata for the pulled copies, a failure leaves it unknown
+	commit, _ := githubClient.HeadCommit(ctx)
+	preparedRepos[repoKey(cfg)] = commit
+	return nil
+}
+
+// repoKey identifies the local repository and branch a pull reads from
+func repoKey(cfg *config.AnvilConfig) string {
+	return cfg.GitHub.LocalPath + "@" + cfg.GitHub.Branch
+}
+
+// preparedCommit returns the commit the repository was updated to in this run
+func preparedCommit(cfg *config.AnvilConfig) string {
+	preparedMutex.Lock()
+	defer preparedMutex.Unlock()
+	return preparedRepos[repoKey(cfg)]
+}
+
+// pullResult is the outcome of copying one directory during a multi-directory pull
+type pullResult struct {
+	target  string
+	tempDir string
+	changes *utils.DirChanges // nil on a first pull
+	err     error
+}
+
+// pullDirectories copies several directories from the shared clone using a bounded worker pool
+func pullDirectories(cfg *config.AnvilConfig, targets []string, workers int) error {
+	output := palantir.GetGlobalOutputHandler()
+	if workers <= 0 {
+		workers = defaultPullWorkers
+	}
+
+	output.PrintStage(i18n.T("pull.stage.copy", len(targets)))
+	spinner := charm.NewDotsSpinner(i18n.T("pull.copying", len(targets), min(workers, len(targets))))
 	spinner.Start()
-	tempDir, err := copyDirectoryToTemp(cfg, targetDir)
-	if err != nil {
-		spinner.Error("Failed to copy configuration")
-		return err
+	results := copyDirectoriesToTemp(cfg, targets, workers)
+
+	failed := 0
+	for _, result := range results {
+		if result.err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		spinner.Warning(i18n.T("pull.copied_some", len(targets)-failed, len(targets)))
+	} else {
+		spinner.Success(i18n.T("pull.copied", len(targets)))
 	}
-	spinner.Success("Configuration directory copied to temp location")
 
-	displaySuccessMessage(targetDir, tempDir, cfg)
+	output.PrintHeader(i18n.T("pull.complete"))
+	for _, result := range results {
+		switch {
+		case result.err != nil:
+			output.PrintError("%s: %v", result.target, result.err)
+		case result.changes == nil:
+			output.PrintSuccess(i18n.T("pull.result.first", result.target))
+		case result.changes.IsEmpty():
+			output.PrintSuccess(i18n.T("pull.result.unchanged", result.target))
+		default:
+			output.PrintSuccess(i18n.T("pull.result.changed", result.target,
+				len(result.changes.Added), len(result.changes.Removed), len(result.changes.Modified)))
+		}
+	}
+	output.PrintInfo(i18n.T("pull.files_under"), filepath.Join(config.GetAnvilConfigDirectory(), "temp"))
+	output.PrintInfo(i18n.T("pull.single_hint"))
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d directories failed to pull", failed, len(targets))
+	}
 	return nil
 }
 
+// copyDirectoriesToTemp copies each target concurrently, returning results in target order
+func copyDirectoriesToTemp(cfg *config.AnvilConfig, targets []string, workers int) []pullResult {
+	results := make([]pullResult, len(targets))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			result := pullResult{target: target}
+			result.tempDir, result.err = copyDirectoryToTemp(cfg, target)
+			if result.err == nil {
+				previousDir := filepath.Join(filepath.Dir(result.tempDir), config.PreviousPullDir, target)
+				if _, err := os.Stat(previousDir); err == nil {
+					if changes, err := utils.CompareDirectories(previousDir, result.tempDir); err == nil {
+						result.changes = &changes
+					}
+				}
+			}
+			results[i] = result
+		}(i, target)
+	}
+	wg.Wait()
+	return results
+}
+
+// listRepoDirectories returns the visible top-level directories of the local repository
+func listRepoDirectories(repoPath string) ([]string, error) {
+	entries, err := os.ReadDir(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	var dirs []string
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			dirs = append(dirs, entry.Name())
+		}
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+// skipBlockedTargets drops the directories whose app mode forbids pulling them
+func skipBlockedTargets(cfg *config.AnvilConfig, targets []string) []string {
+	var allowed []string
+	for _, target := range targets {
+		if cfg.CheckAppMode(target, constants.OpPull) != nil {
+			palantir.GetGlobalOutputHandler().PrintInfo(i18n.T("pull.skipping_mode"), target, cfg.AppMode(target))
+			continue
+		}
+		allowed = append(allowed, target)
+	}
+	return allowed
+}
+
+// uniqueTargets drops repeated directory names while keeping their order
+func uniqueTargets(args []string) []string {
+	seen := make(map[string]bool, len(args))
+	var targets []string
+	for _, arg := range args {
+		arg = strings.Trim(arg, "/")
+		if arg != "" && !seen[arg] {
+			seen[arg] = true
+			targets = append(targets, arg)
+		}
+	}
+	return targets
+}
+
+// PullConfig pulls a configuration directory from the config repository, used by provisioning
+func PullConfig(targetDir string) error {
+	return runPullCommand(PullCmd, []string{targetDir})
+}
+
 func displaySuccessMessage(targetDir, tempDir string, cfg *config.AnvilConfig) {
 	o := palantir.GetGlobalOutputHandler()
-	o.PrintHeader("Pull Complete!")
-	o.PrintInfo("Configuration directory '%s' has been pulled from: %s", targetDir, cfg.GitHub.ConfigRepo)
-	o.PrintInfo("Files are available at: %s", tempDir)
+	o.PrintHeader(i18n.T("pull.complete"))
+	o.PrintInfo(i18n.T("pull.pulled_from"), targetDir, cfg.GitHub.ConfigRepo)
+	o.PrintInfo(i18n.T("pull.files_at"), tempDir)
+
+	// Re-pulls summarize what changed since the last pull, first pulls list the copied files
+	previousDir := filepath.Join(filepath.Dir(tempDir), config.PreviousPullDir, targetDir)
+	if _, err := os.Stat(previousDir); err == nil {
+		if err := displayChangesSincePrevious(previousDir, tempDir); err != nil {
+			o.PrintWarning(i18n.T("pull.compare_failed"), err)
+		}
+		return
+	}
 
 	// List the files that were copied
 	if err := listCopiedFiles(tempDir); err == nil {
 		// Files listed successfully
 	} else {
-		o.PrintWarning("Could not list copied files: %v", err)
+		o.PrintWarning(i18n.T("pull.list_failed"), err)
 	}
 }
 
+// displayChangesSincePrevious prints the files added, removed and modified since the previous pull
+func displayChangesSincePrevious(previousDir, tempDir string) error {
+	changes, err := utils.CompareDirectories(previousDir, tempDir)
+	if err != nil {
+		return err
+	}
+
+	o := palantir.GetGlobalOutputHandler()
+	fmt.Println("")
+	if changes.IsEmpty() {
+		o.PrintInfo(i18n.T("pull.no_changes"))
+		return nil
+	}
+
+	o.PrintInfo(i18n.T("pull.changes"))
+	for _, file := range changes.Added {
+		o.PrintInfo(i18n.T("pull.change.added"), file)
+	}
+	for _, file := range changes.Removed {
+		o.PrintInfo(i18n.T("pull.change.removed"), file)
+	}
+	for _, file := range changes.Modified {
+		o.PrintInfo(i18n.T("pull.change.modified"), file)
+	}
+	o.PrintInfo(i18n.T("pull.previous_kept"), previousDir)
+	o.PrintInfo(i18n.T("pull.compare_with"), previousDir, tempDir)
+	return nil
+}
+
 // validateGitHubConfig validates that GitHub configuration is properly set up
 func validateGitHubConfig(cfg *config.AnvilConfig) error {
 	if cfg.GitHub.ConfigRepo == "" {
 		return errors.NewConfigurationError(constants.OpPull, "validate-config",
-			fmt.Errorf("github.config_repo is not configured. Please edit %s/%s and set github.config_repo to your repository (e.g., 'username/dotfiles')",
+			fmt.Errorf(i18n.T("pull.config.no_repo"),
 				config.GetAnvilConfigDirectory(), constants.ANVIL_CONFIG_FILE))
 	}
 
@@ -217,19 +504,19 @@ Example:
 
 	if cfg.GitHub.LocalPath == "" {
 		return errors.NewConfigurationError(constants.OpPull, "validate-config",
-			fmt.Errorf("github.local_path is not configured"))
+			fmt.Errorf("%s", i18n.T("pull.config.no_local_path")))
 	}
 
 	output := palantir.GetGlobalOutputHandler()
 	// Provide guidance about branch configuration
 	if cfg.GitHub.Branch != "main" && cfg.GitHub.Branch != "master" {
-		output.PrintWarning("Note: You're using branch '%s'. Make sure this branch exists in your repository.", cfg.GitHub.Branch)
-		output.PrintInfo("💡 Common default branches are 'main' or 'master'")
+		output.PrintWarning(i18n.T("pull.config.branch_note"), cfg.GitHub.Branch)
+		output.PrintInfo(i18n.T("pull.config.default_branches"))
 	}
 
 	// Check if git is available
 	if cfg.Git.Username == "" || cfg.Git.Email == "" {
-		output.PrintWarning(fmt.Sprintf("Git user configuration is incomplete. Consider setting git.username and git.email in %s", constants.ANVIL_CONFIG_FILE))
+		output.PrintWarning(i18n.T("pull.config.git_incomplete"), constants.ANVIL_CONFIG_FILE)
 	}
 
 	return nil
@@ -243,35 +530,80 @@ func copyDirectoryToTemp(cfg *config.AnvilConfig, targetDir string) (string, err
 	// Check if source directory exists
 	if _, err := os.Stat(sourceDir); os.IsNotExist(err) {
 		return "", errors.NewConfigurationError(constants.OpPull, "source-directory",
-			fmt.Errorf("directory '%s' does not exist in repository %s", targetDir, cfg.GitHub.ConfigRepo))
+			fmt.Errorf(i18n.T("pull.config.missing_directory"), targetDir, cfg.GitHub.ConfigRepo))
 	}
 
 	// Create temp directory inside anvil config
-	tempBasedir := filepath.Join(config.GetAnvilConfigDirectory(), "temp")
+	tempBasedir := config.GetTempDirectory()
 	if err := utils.EnsureDirectory(tempBasedir); err != nil {
 		return "", errors.NewFileSystemError(constants.OpPull, "create-temp-dir", err)
 	}
 
-	// Destination directory
-	destDir := filepath.Join(tempBasedir, targetDir)
+	// Copy into a staging directory of this pull, then swap it in whole. Concurrent pulls
+	// and syncs never see a half-written copy, and the existing one is kept as the previous pull.
+	stagingDir, err := utils.NewStagingDir(tempBasedir, targetDir)
+	if err != nil {
+		return "", errors.NewFileSystemError(constants.OpPull, "create-staging-dir", err)
+	}
+	defer os.RemoveAll(stagingDir)
+
+	// Files left out by the app's include and exclude patterns are not pulled, even when
+	// another machine pushed them
+	stagedDir := filepath.Join(stagingDir, filepath.Base(targetDir))
+	options := utils.ConfigCopyOptions()
+	filter := cfg.ConfigFilters[targetDir]
+	options.Include, options.Exclude = filter.Include, filter.Exclude
+	if err := utils.CopyDirectory(sourceDir, stagedDir, options); err != nil {
+		return "", errors.NewFileSystemError(constants.OpPull, "copy-directory", err)
+	}
 
-	// Remove existing destination if it exists
-	if err := os.RemoveAll(destDir); err != nil {
-		return "", errors.NewFileSystemError(constants.OpPull, "remove-existing", err)
+	destDir := filepath.Join(tempBasedir, targetDir)
+	previousDir := filepath.Join(tempBasedir, config.PreviousPullDir, targetDir)
+	if err := utils.PromoteDirectory(stagedDir, destDir, previousDir); err != nil {
+		return "", errors.NewFileSystemError(constants.OpPull, "promote-staging-dir", err)
 	}
 
-	// Copy directory recursively
-	if err := utils.CopyDirectorySimple(sourceDir, destDir); err != nil {
-		return "", errors.NewFileSystemError(constants.OpPull, "copy-directory", err)
+	// Where the copy came from lets sync warn about stale copies and --prune-temp expire them
+	entry := config.TempEntry{
+		Name:     targetDir,
+		PulledAt: time.Now(),
+		Repo:     cfg.GitHub.ConfigRepo,
+		Branch:   cfg.GitHub.Branch,
+		Commit:   preparedCommit(cfg),
+	}
+	if compatibility, ok := github.LoadAppCompatibility(cfg.GitHub.LocalPath, targetDir); ok {
+		entry.AppVersion = config.AppVersionRequirement{MinAppVersion: compatibility.MinAppVersion, OnMismatch: compatibility.OnMismatch}
+	}
+	if err := config.RecordTempPull(entry); err != nil {
+		palantir.GetGlobalOutputHandler().PrintWarning(i18n.T("pull.metadata_failed"), targetDir, err)
 	}
+	if err := recordPushBase(cfg, targetDir, entry.Commit); err != nil {
+		palantir.GetGlobalOutputHandler().PrintWarning(i18n.T("pull.metadata_failed"), targetDir, err)
+	}
+	events.Publish(events.Pulled, targetDir, "")
+	timeline.Record(timeline.Entry{Action: timeline.Pulled, App: targetDir, Repo: entry.Repo, Branch: entry.Branch, Commit: entry.Commit})
 
 	return destDir, nil
 }
 
+// recordPushBase remembers where the pulled app directory stood, a later push from this
+// machine uses it to tell whether other machines changed the directory since
+func recordPushBase(cfg *config.AnvilConfig, targetDir, commit string) error {
+	if commit == "" {
+		return nil
+	}
+	client := github.NewGitHubClient(cfg.GitHub.ConfigRepo, cfg.GitHub.Branch, cfg.GitHub.LocalPath, "", "", "", "")
+	tree, err := client.AppTree(commit, targetDir)
+	if err != nil {
+		return err
+	}
+	return config.RecordPulledBase(targetDir, cfg.GitHub.ConfigRepo, cfg.GitHub.Branch, commit, tree)
+}
+
 // listCopiedFiles lists the files that were copied to the temp directory
 func listCopiedFiles(tempDir string) error {
 	fmt.Println("")
-	palantir.GetGlobalOutputHandler().PrintInfo("Copied files:")
+	palantir.GetGlobalOutputHandler().PrintInfo(i18n.T("pull.copied_files"))
 
 	return filepath.Walk(tempDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -295,4 +627,10 @@ func init() {
 	// Add flags for additional functionality
 	PullCmd.Flags().Bool("force", false, "Force pull even if local changes exist")
 	PullCmd.Flags().String("branch", "", "Override the branch to pull from")
+	PullCmd.Flags().String("remote", "", "Pull from a named repository from github.remotes instead of github.config_repo")
+	PullCmd.Flags().Bool("all", false, "Pull every configuration directory in the repository")
+	PullCmd.Flags().Int("workers", defaultPullWorkers, "Number of directories copied concurrently when pulling several")
+	PullCmd.Flags().Bool("prune-temp", false, "Remove pulled copies older than --older-than days instead of pulling")
+	PullCmd.Flags().Int("older-than", 0, "Age in days after which --prune-temp removes pulled copies (default temp.max_age_days or 30)")
+	PullCmd.Flags().Bool("dry-run", false, "Show which pulled copies --prune-temp would remove without deleting them")
 }
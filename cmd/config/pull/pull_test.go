@@ -0,0 +1,74 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pull
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/0xjuanma/anvil/internal/config"
+)
+
+func TestCopyDirectoriesToTemp(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	repo := t.TempDir()
+	for _, app := range []string{"zsh", "nvim", "cursor"} {
+		os.MkdirAll(filepath.Join(repo, app), 0755)
+		os.WriteFile(filepath.Join(repo, app, "config"), []byte(app), 0644)
+	}
+	os.MkdirAll(filepath.Join(repo, ".git"), 0755)
+	cfg := &config.AnvilConfig{GitHub: config.GitHubConfig{LocalPath: repo, ConfigRepo: "user/dotfiles"}}
+
+	targets, err := listRepoDirectories(repo)
+	if err != nil {
+		t.Fatalf("listRepoDirectories failed: %v", err)
+	}
+	if want := []string{"cursor", "nvim", "zsh"}; !slices.Equal(targets, want) {
+		t.Fatalf("targets = %v, want %v", targets, want)
+	}
+
+	results := copyDirectoriesToTemp(cfg, append(targets, "missing"), 2)
+	for i, result := range results[:3] {
+		if result.target != targets[i] || result.err != nil || result.changes != nil {
+			t.Errorf("first pull of %s: %+v", targets[i], result)
+		}
+	}
+	if results[3].err == nil {
+		t.Error("expected an error for a directory missing from the repository")
+	}
+
+	// A second pull compares against the previous copy
+	os.WriteFile(filepath.Join(repo, "zsh", "config"), []byte("changed"), 0644)
+	results = copyDirectoriesToTemp(cfg, []string{"zsh", "nvim"}, 2)
+	if changes := results[0].changes; changes == nil || !slices.Equal(changes.Modified, []string{"config"}) {
+		t.Errorf("zsh changes = %+v, want config modified", changes)
+	}
+	if changes := results[1].changes; changes == nil || !changes.IsEmpty() {
+		t.Errorf("nvim changes = %+v, want none", changes)
+	}
+}
+
+func TestUniqueTargets(t *testing.T) {
+	got := uniqueTargets([]string{"zsh", "nvim/", "zsh", "", "cursor"})
+	if want := []string{"zsh", "nvim", "cursor"}; !slices.Equal(got, want) {
+		t.Errorf("uniqueTargets = %v, want %v", got, want)
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/0xjuanma/anvil/internal/config"
 	"github.com/0xjuanma/anvil/internal/constants"
 	"github.com/0xjuanma/anvil/internal/github"
+	"github.com/0xjuanma/anvil/internal/i18n"
 	"github.com/0xjuanma/palantir"
 )
 
@@ -30,27 +31,27 @@ import (
 func showNewAppInfo(appName, configPath string) {
 	output := palantir.GetGlobalOutputHandler()
 	fmt.Println("")
-	output.PrintHeader("New App Addition")
-	output.PrintInfo("App: %s", appName)
-	output.PrintInfo("Local path: %s", configPath)
+	output.PrintHeader(i18n.T("push.new_app.title"))
+	output.PrintInfo(i18n.T("push.app"), appName)
+	output.PrintInfo(i18n.T("push.new_app.local_path"), configPath)
 	fmt.Println("")
-	output.PrintInfo("This app will be added to the repository for the first time.")
-	output.PrintInfo("All configuration files will be committed to a new branch.")
+	output.PrintInfo(i18n.T("push.new_app.first_time"))
+	output.PrintInfo(i18n.T("push.new_app.new_branch"))
 }
 
 // handleAppLocationError provides helpful error messages for app location resolution failures
 func handleAppLocationError(appName string, err error) error {
 	if strings.Contains(err.Error(), "not found in configs or temp directory") {
 		o := palantir.GetGlobalOutputHandler()
-		o.PrintError("App '%s' is not known to anvil\n", appName)
-		o.PrintInfo("💡 To push app configurations:\n")
-		o.PrintInfo("1. Configure the app's local config path in %s:\n", constants.ANVIL_CONFIG_FILE)
+		o.PrintError(i18n.T("push.unknown_app"), appName)
+		o.PrintInfo(i18n.T("push.unknown_app.hint"))
+		o.PrintInfo(i18n.T("push.unknown_app.configure"), constants.ANVIL_CONFIG_FILE)
 		o.PrintInfo("configs:")
 		o.PrintInfo("  %s: /path/to/your/%s/configs\n", appName, appName)
-		o.PrintInfo("2. Or pull the app's configs first to discover it:")
+		o.PrintInfo(i18n.T("push.unknown_app.pull"))
 		o.PrintInfo("   anvil config pull %s\n", appName)
-		o.PrintInfo("3. Then configure the local path in %s\n", constants.ANVIL_CONFIG_FILE)
-		o.PrintInfo("4. For completely new apps, ensure the local path exists and contains config files")
+		o.PrintInfo(i18n.T("push.unknown_app.then_configure"), constants.ANVIL_CONFIG_FILE)
+		o.PrintInfo(i18n.T("push.unknown_app.new"))
 		return fmt.Errorf("app not configured")
 	}
 
@@ -61,38 +62,46 @@ func handleAppLocationError(appName string, err error) error {
 func showSecurityWarning(privateRepo string) {
 	// 🚨 SECURITY WARNING: Remind users about private repository requirement
 	o := palantir.GetGlobalOutputHandler()
-	o.PrintWarning("SECURITY REMINDER: Configuration files may contain sensitive data")
-	o.PrintInfo("   • Personal file paths and system information")
-	o.PrintInfo("   • Private development environment details")
-	o.PrintInfo("🛡️ Anvil ENFORCES private repositories for security")
-	o.PrintInfo("   • Repository '%s' must be PRIVATE", privateRepo)
-	o.PrintInfo("   • Public repositories will be BLOCKED\n")
+	o.PrintWarning(i18n.T("push.security.reminder"))
+	o.PrintInfo(i18n.T("push.security.paths"))
+	o.PrintInfo(i18n.T("push.security.environment"))
+	o.PrintInfo(i18n.T("push.security.enforced"))
+	o.PrintInfo(i18n.T("push.security.private"), privateRepo)
+	o.PrintInfo(i18n.T("push.security.blocked"))
 }
 
 // displaySuccessMessage displays a success message after the push operation
-func displaySuccessMessage(appName string, result *github.PushConfigResult, diffSummary *github.DiffSummary, anvilConfig *config.AnvilConfig) {
+func displaySuccessMessage(appName string, result *github.PushConfigResult, diffSummary *github.DiffSummary, anvilConfig *config.AnvilConfig, pr *github.PullRequest) {
 	// Display full success message for actual push
 	o := palantir.GetGlobalOutputHandler()
-	o.PrintHeader("Push Complete!")
-	o.PrintSuccess(fmt.Sprintf("%s configuration push completed successfully!\n", appName))
-	o.PrintInfo("Push Summary:")
-	o.PrintInfo("  • Branch created: %s", result.BranchName)
-	o.PrintInfo("  • Commit message: %s", result.CommitMessage)
-	o.PrintInfo("  • Files committed: \n\n%s", diffSummary.GitStatOutput)
-	o.PrintInfo("🔗 Repository: %s", result.RepositoryURL)
-	o.PrintSuccess("You can now create a Pull Request on GitHub to merge these changes!")
-	o.PrintInfo("Direct link: %s/compare/%s...%s", result.RepositoryURL, anvilConfig.GitHub.Branch, result.BranchName)
+	o.PrintHeader(i18n.T("push.complete"))
+	o.PrintSuccess(i18n.T("push.completed", appName))
+	o.PrintInfo(i18n.T("push.summary"))
+	if anvilConfig.GitHub.AppBranches {
+		o.PrintInfo(i18n.T("push.summary.branch_updated"), result.BranchName)
+	} else {
+		o.PrintInfo(i18n.T("push.summary.branch_created"), result.BranchName)
+	}
+	o.PrintInfo(i18n.T("push.summary.commit_message"), result.CommitMessage)
+	o.PrintInfo(i18n.T("push.summary.files"), diffSummary.GitStatOutput)
+	o.PrintInfo(i18n.T("push.summary.repository"), result.RepositoryURL)
+	if pr != nil {
+		o.PrintInfo(i18n.T("push.summary.pull_request"), pr.URL)
+		return
+	}
+	o.PrintSuccess(i18n.T("push.summary.create_pr"))
+	o.PrintInfo(i18n.T("push.summary.compare_link"), result.RepositoryURL, anvilConfig.GitHub.Branch, result.BranchName)
 }
 
 // showDiffOutput displays diff information using Git's native output
 func showDiffOutput(diffSummary *github.DiffSummary) {
 	o := palantir.GetGlobalOutputHandler()
 	if diffSummary.TotalFiles == 0 {
-		o.PrintInfo("No changes detected")
+		o.PrintInfo(i18n.T("push.diff.none"))
 		return
 	}
 
-	o.PrintHeader("Changes to be pushed:")
+	o.PrintHeader(i18n.T("push.diff.title"))
 
 	// Show Git's native stat output directly
 	if diffSummary.GitStatOutput != "" {
@@ -104,12 +113,12 @@ func showDiffOutput(diffSummary *github.DiffSummary) {
 	if diffSummary.TotalFiles == 1 && diffSummary.FullDiff != "" {
 		lines := strings.Split(diffSummary.FullDiff, "\n")
 		if len(lines) <= 50 {
-			o.PrintInfo("📄 Full diff:\n")
+			o.PrintInfo(i18n.T("push.diff.full"))
 			o.PrintInfo(diffSummary.FullDiff)
 		} else {
-			o.PrintInfo("📄 Diff preview (first 50 lines):\n")
+			o.PrintInfo(i18n.T("push.diff.preview"))
 			o.PrintInfo(strings.Join(lines[:50], "\n"))
-			o.PrintInfo("\n... [diff truncated] ...")
+			o.PrintInfo(i18n.T("push.diff.truncated"))
 		}
 	}
 	fmt.Println("")
@@ -0,0 +1,119 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package push
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/github"
+	"github.com/0xjuanma/anvil/internal/i18n"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+	"github.com/0xjuanma/palantir"
+)
+
+// checkDivergence keeps a push from silently undoing what another machine pushed to the app
+// directory since this machine last pulled or pushed it. It explains what changed and asks
+// whether to merge those changes into the push, replacing them takes --force. False means the
+// push is cancelled.
+func checkDivergence(ctx context.Context, githubClient *github.GitHubClient, anvilConfig *config.AnvilConfig, appName string, force bool) bool {
+	output := palantir.GetGlobalOutputHandler()
+	base, found, err := config.GetPushBase(appName, anvilConfig.GitHub.ConfigRepo, anvilConfig.GitHub.Branch)
+	if err != nil {
+		output.PrintWarning(i18n.T("push.divergence.check_failed"), err)
+		return true
+	}
+	if !found {
+		return true // Never pulled or pushed from here, there is nothing to compare with
+	}
+	divergence, err := githubClient.CheckAppDivergence(ctx, appName, base.Commit, base.Tree, base.PushedTree)
+	if err != nil {
+		output.PrintWarning(i18n.T("push.divergence.check_failed"), err)
+		return true
+	}
+	if divergence == nil {
+		return true
+	}
+
+	showDivergence(divergence, base, anvilConfig.GitHub.Branch)
+	switch {
+	case force:
+		output.PrintWarning(i18n.T("push.divergence.forced"), appName)
+		return true
+	case divergence.CanMerge && charm.Confirm(charm.ConfirmPush, i18n.T("push.divergence.confirm_merge")):
+		githubClient.MergeBase = divergence.Base
+		return true
+	}
+
+	// Replacing their changes is never approved by a prompt, --yes could answer it unattended
+	output.PrintInfo(i18n.T("push.divergence.cancelled"))
+	output.PrintInfo(i18n.T("push.divergence.pull_hint"), appName, appName)
+	output.PrintInfo(i18n.T("push.divergence.force_hint"))
+	if cleanupErr := githubClient.CleanupStagedChanges(ctx); cleanupErr != nil {
+		output.PrintWarning(i18n.T("push.cleanup_failed"), cleanupErr)
+	}
+	return false
+}
+
+// showDivergence explains what moved the app directory and what each choice does
+func showDivergence(divergence *github.AppDivergence, base config.PushBase, branch string) {
+	output := palantir.GetGlobalOutputHandler()
+	output.PrintStage(i18n.T("push.stage.divergence"))
+	output.PrintWarning(i18n.T("push.divergence.changed"),
+		divergence.App, branch, base.RecordedAt.Format("2006-01-02 15:04"))
+	if len(divergence.Commits) > 0 {
+		output.PrintInfo(i18n.T("push.divergence.commits"))
+		for _, commit := range divergence.Commits {
+			output.PrintInfo("  • %s", commit)
+		}
+	}
+	if len(divergence.Files) > 0 {
+		output.PrintInfo(i18n.T("push.divergence.files"))
+		for _, file := range divergence.Files {
+			output.PrintInfo("  • %s", file)
+		}
+	}
+	fmt.Println("")
+	if divergence.CanMerge {
+		output.PrintInfo(i18n.T("push.divergence.merge_option"), branch)
+	}
+	output.PrintInfo(i18n.T("push.divergence.force_option"))
+}
+
+// recordPushedBase remembers the branch state a push was made against and the tree it pushed,
+// so the next push only flags changes made elsewhere
+func recordPushedBase(githubClient *github.GitHubClient, anvilConfig *config.AnvilConfig, appName string, result *github.PushConfigResult) {
+	commit, err := githubClient.BranchHead()
+	if err != nil {
+		return
+	}
+	tree, err := githubClient.AppTree(commit, appName)
+	if err != nil {
+		return
+	}
+	base := config.PushBase{
+		Repo:       anvilConfig.GitHub.ConfigRepo,
+		Branch:     anvilConfig.GitHub.Branch,
+		Commit:     commit,
+		Tree:       tree,
+		PushedTree: result.AppTree,
+	}
+	if err := config.RecordPushedBase(appName, base); err != nil {
+		palantir.GetGlobalOutputHandler().PrintWarning(i18n.T("push.divergence.record_failed"), appName, err)
+	}
+}
@@ -20,11 +20,20 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 
+	"github.com/0xjuanma/anvil/internal/audit"
+	"github.com/0xjuanma/anvil/internal/automation"
+	"github.com/0xjuanma/anvil/internal/components"
 	"github.com/0xjuanma/anvil/internal/config"
 	"github.com/0xjuanma/anvil/internal/constants"
 	"github.com/0xjuanma/anvil/internal/errors"
 	"github.com/0xjuanma/anvil/internal/github"
+	"github.com/0xjuanma/anvil/internal/i18n"
+	"github.com/0xjuanma/anvil/internal/pkgmanager"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
 	"github.com/0xjuanma/palantir"
 	"github.com/spf13/cobra"
 )
@@ -36,7 +45,7 @@ var PushCmd = &cobra.Command{
 	Args:  cobra.MaximumNArgs(1), // Accept 0 or 1 argument
 	Run: func(cmd *cobra.Command, args []string) {
 		if err := runPushCommand(cmd, args); err != nil {
-			palantir.GetGlobalOutputHandler().PrintError("Push failed: %v", err)
+			palantir.GetGlobalOutputHandler().PrintError(i18n.T("push.failed"), err)
 			return
 		}
 	},
@@ -54,22 +63,44 @@ func isNewAppAddition(appName string, anvilConfig *config.AnvilConfig) bool {
 	return false
 }
 
+// pullRequestOptions control the pull request opened after a push
+type pullRequestOptions struct {
+	create      bool
+	autoMerge   bool
+	mergeMethod string
+}
+
 // runPushCommand executes the configuration push process
 func runPushCommand(cmd *cobra.Command, args []string) error {
+	remote, _ := cmd.Flags().GetString("remote")
+	if err := config.UseRemote(remote); err != nil {
+		return errors.NewConfigurationError(constants.OpPush, "remote", err)
+	}
+	createPR, _ := cmd.Flags().GetBool("create-pr")
+	autoMerge, _ := cmd.Flags().GetBool("auto-merge")
+	mergeMethod, _ := cmd.Flags().GetString("merge-method")
+	if !slices.Contains(github.MergeMethods, mergeMethod) {
+		return errors.NewValidationError(constants.OpPush, "merge-method",
+			fmt.Errorf("unknown merge method '%s', use one of: %s", mergeMethod, strings.Join(github.MergeMethods, ", ")))
+	}
+	// Auto-merge needs a pull request to merge
+	prOptions := pullRequestOptions{create: createPR || autoMerge, autoMerge: autoMerge, mergeMethod: mergeMethod}
+	force, _ := cmd.Flags().GetBool("force")
+
 	// Option 2: App-specific config push
 	if len(args) > 0 {
 		appName := args[0]
-		return pushAppConfig(appName)
+		return pushAppConfig(appName, prOptions, force)
 	}
 
 	// Option 1: Anvil config push
-	return pushAnvilConfig()
+	return pushAnvilConfig(prOptions, force)
 }
 
 // pushAppConfig pushes application-specific configuration to the repository
-func pushAppConfig(appName string) error {
+func pushAppConfig(appName string, prOptions pullRequestOptions, force bool) error {
 	output := palantir.GetGlobalOutputHandler()
-	output.PrintHeader(fmt.Sprintf("Push '%s' Configuration", appName))
+	output.PrintHeader(i18n.T("push.title_app", appName))
 
 	// Stage 1: Load and validate configuration
 	anvilConfig, err := loadAndValidateConfig()
@@ -77,8 +108,17 @@ func pushAppConfig(appName string) error {
 		return err
 	}
 
+	if err := anvilConfig.CheckAppMode(appName, constants.OpPush); err != nil {
+		return errors.NewValidationError(constants.OpPush, appName, err)
+	}
+
 	// Stage 2: Resolve app location
-	configPath, err := resolveAppLocation(appName, anvilConfig)
+	var configPath string
+	if appName == automation.AppName {
+		configPath, err = exportAutomation()
+	} else {
+		configPath, err = resolveAppLocation(appName, anvilConfig)
+	}
 	if err != nil {
 		return err
 	}
@@ -96,6 +136,33 @@ func pushAppConfig(appName string) error {
 	if err != nil {
 		return err
 	}
+	// Files left out by the app's include and exclude patterns never reach the repository
+	filter := anvilConfig.ConfigFilters[appName]
+	githubClient.Include, githubClient.Exclude = filter.Include, filter.Exclude
+
+	if err := runPreflight(githubClient, configPath); err != nil {
+		return err
+	}
+
+	if audit.IsEnabled() {
+		recordAuditedPush(anvilConfig, appName, configPath)
+		return nil
+	}
+
+	// Refresh extension manifests so the pushed config lists what is installed here
+	if err := components.Capture(appName, configPath); err != nil {
+		output.PrintWarning(i18n.T("push.components_failed"), appName, err)
+	}
+
+	// Record the oldest app version these configs work with, an empty requirement clears it
+	githubClient.AppVersion = &github.AppCompatibility{}
+	if requirement, ok := anvilConfig.AppVersions[appName]; ok {
+		githubClient.AppVersion = &github.AppCompatibility{
+			AppVersion:    pkgmanager.InstalledVersion(appName),
+			MinAppVersion: requirement.MinAppVersion,
+			OnMismatch:    requirement.OnMismatch,
+		}
+	}
 
 	// Stage 5: Prepare and show diff
 	ctx := context.Background()
@@ -104,19 +171,38 @@ func pushAppConfig(appName string) error {
 		return err
 	}
 
-	// Stage 6: User confirmation
+	// Stage 6: User confirmation, after checking no other machine changed the app since
+	if !checkDivergence(ctx, githubClient, anvilConfig, appName, force) {
+		return nil
+	}
 	if !handleUserConfirmation(output, appName, githubClient, ctx) {
 		return nil
 	}
 
 	// Stage 7: Push configuration
-	return performPushOperation(githubClient, appName, configPath, diffSummary, anvilConfig, ctx)
+	return performPushOperation(githubClient, appName, configPath, diffSummary, anvilConfig, prOptions, ctx)
+}
+
+// runPreflight applies the size and secret checks before anything is copied into the local clone
+func runPreflight(githubClient *github.GitHubClient, configPath string) error {
+	if err := githubClient.CheckPushSize(configPath); err != nil {
+		return err
+	}
+	return githubClient.CheckSecrets(configPath)
+}
+
+// recordAuditedPush records the branch and pull request a push would create, without touching the repository
+func recordAuditedPush(anvilConfig *config.AnvilConfig, appName, sourcePath string) {
+	output := palantir.GetGlobalOutputHandler()
+	output.PrintInfo(i18n.T("push.audit"), appName, sourcePath, anvilConfig.GitHub.ConfigRepo)
+	audit.Record("push", "create-branch-and-pull-request", anvilConfig.GitHub.ConfigRepo,
+		fmt.Sprintf("%s from %s (base branch %s)", appName, sourcePath, anvilConfig.GitHub.Branch))
 }
 
 // loadAndValidateConfig loads and validates the anvil configuration
 func loadAndValidateConfig() (*config.AnvilConfig, error) {
 	output := palantir.GetGlobalOutputHandler()
-	output.PrintStage("Loading anvil configuration...")
+	output.PrintStage(i18n.T("push.stage.load"))
 
 	anvilConfig, err := config.LoadConfig()
 	if err != nil {
@@ -129,20 +215,20 @@ func loadAndValidateConfig() (*config.AnvilConfig, error) {
 			fmt.Errorf("GitHub repository not configured. Please set 'github.config_repo' in your %s", constants.ANVIL_CONFIG_FILE))
 	}
 
-	output.PrintSuccess("Configuration loaded successfully")
+	output.PrintSuccess(i18n.T("push.loaded"))
 	return anvilConfig, nil
 }
 
 // resolveAppLocation resolves the app configuration location
 func resolveAppLocation(appName string, anvilConfig *config.AnvilConfig) (string, error) {
 	output := palantir.GetGlobalOutputHandler()
-	output.PrintStage("Resolving app configuration location...")
+	output.PrintStage(i18n.T("push.stage.resolve"))
 
 	configPath, locationSource, err := config.ResolveAppLocation(appName)
 	if err != nil {
 		// Check if this is a new app addition
 		if isNewAppAddition(appName, anvilConfig) {
-			output.PrintInfo("🆕 New app '%s' detected - will be added to repository", appName)
+			output.PrintInfo(i18n.T("push.new_app_detected"), appName)
 			// Get the configured path for new apps
 			if localPath, exists := anvilConfig.Configs[appName]; exists {
 				configPath = localPath
@@ -156,33 +242,48 @@ func resolveAppLocation(appName string, anvilConfig *config.AnvilConfig) (string
 
 	// Handle different location sources
 	if locationSource == config.LocationTemp {
-		output.PrintWarning("App '%s' found in temp directory but not configured in settings\n", appName)
-		output.PrintInfo("💡 To push app configurations, you need to configure the local path in %s:\n", constants.ANVIL_CONFIG_FILE)
+		output.PrintWarning(i18n.T("push.temp_only"), appName)
+		output.PrintInfo(i18n.T("push.temp_only_hint"), constants.ANVIL_CONFIG_FILE)
 		output.PrintInfo("configs:")
 		output.PrintInfo("  %s: /path/to/your/%s/configs\n", appName, appName)
-		output.PrintInfo("This ensures anvil knows where to find your local configurations.")
-		output.PrintInfo("The temp directory (%s) contains pulled configs for review only.", configPath)
+		output.PrintInfo(i18n.T("push.temp_only_reason"))
+		output.PrintInfo(i18n.T("push.temp_only_review"), configPath)
 		return "", fmt.Errorf("app config path not configured in settings")
 	}
 
-	output.PrintSuccess("App configuration location resolved")
-	output.PrintInfo("Config path: %s", configPath)
+	output.PrintSuccess(i18n.T("push.resolved"))
+	output.PrintInfo(i18n.T("push.config_path"), configPath)
 	return configPath, nil
 }
 
+// exportAutomation writes LaunchAgents and the crontab to the staging directory pushed as the automation app
+func exportAutomation() (string, error) {
+	output := palantir.GetGlobalOutputHandler()
+	output.PrintStage(i18n.T("push.stage.export_automation"))
+
+	dir := automation.Dir()
+	if err := automation.Export(dir); err != nil {
+		return "", errors.NewFileSystemError(constants.OpPush, automation.AppName, err)
+	}
+
+	output.PrintSuccess(i18n.T("push.automation_exported"))
+	output.PrintInfo(i18n.T("push.config_path"), dir)
+	return dir, nil
+}
+
 // setupAuthentication sets up GitHub authentication
 func setupAuthentication(anvilConfig *config.AnvilConfig) (*github.GitHubClient, error) {
 	output := palantir.GetGlobalOutputHandler()
-	output.PrintStage("Setting up authentication...")
+	output.PrintStage(i18n.T("push.stage.auth"))
 
 	var token string
 	if anvilConfig.GitHub.TokenEnvVar != "" {
 		token = os.Getenv(anvilConfig.GitHub.TokenEnvVar)
 		if token == "" {
-			output.PrintWarning("GitHub token not found in environment variable: %s", anvilConfig.GitHub.TokenEnvVar)
-			output.PrintInfo("Proceeding with SSH authentication if available...")
+			output.PrintWarning(i18n.T("push.token_missing"), anvilConfig.GitHub.TokenEnvVar)
+			output.PrintInfo(i18n.T("push.ssh_fallback"))
 		} else {
-			output.PrintSuccess("GitHub token found in environment")
+			output.PrintSuccess(i18n.T("push.token_found"))
 		}
 	}
 
@@ -196,6 +297,15 @@ func setupAuthentication(anvilConfig *config.AnvilConfig) (*github.GitHubClient,
 		anvilConfig.Git.Username,
 		anvilConfig.Git.Email,
 	)
+	githubClient.CloneDepth = anvilConfig.GitHub.CloneDepth
+	githubClient.MaterializeSymlinks = anvilConfig.GitHub.MaterializeSymlinks
+	githubClient.BranchTimestampFormat = anvilConfig.GitHub.BranchTimestampFormat
+	githubClient.BranchIncludeHost = anvilConfig.GitHub.BranchIncludeHost
+	githubClient.AppBranches = anvilConfig.GitHub.AppBranches
+	githubClient.MaxPushSizeMB = anvilConfig.GitHub.MaxPushSizeMB
+	githubClient.MaxPushFiles = anvilConfig.GitHub.MaxPushFiles
+	githubClient.GenerateReadme = anvilConfig.GitHub.GenerateReadme
+	githubClient.APICacheDir = filepath.Join(config.GetAnvilConfigDirectory(), "cache", "github")
 
 	return githubClient, nil
 }
@@ -203,18 +313,18 @@ func setupAuthentication(anvilConfig *config.AnvilConfig) (*github.GitHubClient,
 // prepareDiffPreview prepares and shows the diff preview
 func prepareDiffPreview(githubClient *github.GitHubClient, appName, configPath string, ctx context.Context) (*github.DiffSummary, error) {
 	output := palantir.GetGlobalOutputHandler()
-	output.PrintStage(fmt.Sprintf("Preparing to push %s configuration...", appName))
-	output.PrintInfo("Repository: %s", githubClient.RepoURL)
-	output.PrintInfo("Branch: %s", githubClient.Branch)
-	output.PrintInfo("App: %s", appName)
-	output.PrintInfo("Local config path: %s", configPath)
+	output.PrintStage(i18n.T("push.stage.prepare_app", appName))
+	output.PrintInfo(i18n.T("push.repository"), githubClient.RepoURL)
+	output.PrintInfo(i18n.T("push.branch"), githubClient.Branch)
+	output.PrintInfo(i18n.T("push.app"), appName)
+	output.PrintInfo(i18n.T("push.local_path"), configPath)
 
 	// Add diff output before confirmation
-	output.PrintStage("Analyzing changes...")
+	output.PrintStage(i18n.T("push.stage.analyze"))
 	targetPath := fmt.Sprintf("%s/", appName)
 	diffSummary, err := githubClient.GetDiffPreview(ctx, configPath, targetPath)
 	if err != nil {
-		output.PrintWarning("Unable to generate diff preview: %v", err)
+		output.PrintWarning(i18n.T("push.diff_failed"), err)
 		return nil, nil
 	}
 
@@ -224,12 +334,12 @@ func prepareDiffPreview(githubClient *github.GitHubClient, appName, configPath s
 
 // handleUserConfirmation handles user confirmation for the push operation
 func handleUserConfirmation(output palantir.OutputHandler, appName string, githubClient *github.GitHubClient, ctx context.Context) bool {
-	output.PrintStage("Requesting user confirmation...")
-	if !output.Confirm(fmt.Sprintf("Do you want to push your %s configurations to the repository?", appName)) {
-		output.PrintInfo("Push cancelled by user")
+	output.PrintStage(i18n.T("push.stage.confirm"))
+	if !charm.Confirm(charm.ConfirmPush, i18n.T("push.confirm_app", appName)) {
+		output.PrintInfo(i18n.T("push.cancelled"))
 		// Clean up any staged changes from the diff preview
 		if cleanupErr := githubClient.CleanupStagedChanges(ctx); cleanupErr != nil {
-			output.PrintWarning("Failed to cleanup staged changes: %v", cleanupErr)
+			output.PrintWarning(i18n.T("push.cleanup_failed"), cleanupErr)
 		}
 		return false
 	}
@@ -237,15 +347,15 @@ func handleUserConfirmation(output palantir.OutputHandler, appName string, githu
 }
 
 // performPushOperation executes the actual push operation
-func performPushOperation(githubClient *github.GitHubClient, appName, configPath string, diffSummary *github.DiffSummary, anvilConfig *config.AnvilConfig, ctx context.Context) error {
+func performPushOperation(githubClient *github.GitHubClient, appName, configPath string, diffSummary *github.DiffSummary, anvilConfig *config.AnvilConfig, prOptions pullRequestOptions, ctx context.Context) error {
 	output := palantir.GetGlobalOutputHandler()
-	output.PrintStage(fmt.Sprintf("Pushing %s configuration to repository...", appName))
+	output.PrintStage(i18n.T("push.stage.push_app", appName))
 
 	result, err := githubClient.PushAppConfig(ctx, appName, configPath)
 	if err != nil {
 		// Clean up any staged changes in case of error
 		if cleanupErr := githubClient.CleanupStagedChanges(ctx); cleanupErr != nil {
-			output.PrintWarning("Failed to cleanup staged changes after error: %v", cleanupErr)
+			output.PrintWarning(i18n.T("push.cleanup_after_error_failed"), cleanupErr)
 		}
 		return errors.NewInstallationError(constants.OpPush, "push-app-config", err)
 	}
@@ -256,17 +366,47 @@ func performPushOperation(githubClient *github.GitHubClient, appName, configPath
 		return nil
 	}
 
-	displaySuccessMessage(appName, result, diffSummary, anvilConfig)
+	recordPushedBase(githubClient, anvilConfig, appName, result)
+	pr := openPullRequest(githubClient, result, prOptions, ctx)
+	displaySuccessMessage(appName, result, diffSummary, anvilConfig, pr)
 	return nil
 }
 
+// openPullRequest opens the pull request for a pushed branch when asked to. Failures are
+// warnings, the branch is pushed and the compare link still works.
+func openPullRequest(githubClient *github.GitHubClient, result *github.PushConfigResult, prOptions pullRequestOptions, ctx context.Context) *github.PullRequest {
+	if !prOptions.create {
+		return nil
+	}
+
+	output := palantir.GetGlobalOutputHandler()
+	output.PrintStage(i18n.T("push.stage.pull_request"))
+	pr, err := githubClient.OpenPullRequest(ctx, result, prOptions.autoMerge, prOptions.mergeMethod)
+	if pr == nil {
+		output.PrintWarning("%v", err)
+		return nil
+	}
+	if pr.Existing {
+		output.PrintSuccess(i18n.T("push.pr_existing", pr.Number, result.BranchName))
+	} else {
+		output.PrintSuccess(i18n.T("push.pr_created", pr.Number))
+	}
+	switch {
+	case err != nil:
+		output.PrintWarning("%v", err)
+	case prOptions.autoMerge:
+		output.PrintSuccess(i18n.T("push.auto_merge", prOptions.mergeMethod))
+	}
+	return pr
+}
+
 // pushAnvilConfig pushes the anvil settings.yaml to the repository
-func pushAnvilConfig() error {
+func pushAnvilConfig(prOptions pullRequestOptions, force bool) error {
 	output := palantir.GetGlobalOutputHandler()
-	output.PrintHeader("Push Anvil Configuration")
+	output.PrintHeader(i18n.T("push.title_anvil"))
 
 	// Stage 1: Load and validate configuration
-	output.PrintStage("Loading anvil configuration...")
+	output.PrintStage(i18n.T("push.stage.load"))
 	anvilConfig, err := config.LoadConfig()
 	if err != nil {
 		return errors.NewConfigurationError(constants.OpPush, "load-config", err)
@@ -277,20 +417,20 @@ func pushAnvilConfig() error {
 		return errors.NewConfigurationError(constants.OpPush, "missing-repo",
 			fmt.Errorf("GitHub repository not configured. Please set 'github.config_repo' in your %s", constants.ANVIL_CONFIG_FILE))
 	}
-	output.PrintSuccess("Configuration loaded successfully")
+	output.PrintSuccess(i18n.T("push.loaded"))
 
 	showSecurityWarning(anvilConfig.GitHub.ConfigRepo)
 
 	// Stage 2: Authentication setup
-	output.PrintStage("Setting up authentication...")
+	output.PrintStage(i18n.T("push.stage.auth"))
 	var token string
 	if anvilConfig.GitHub.TokenEnvVar != "" {
 		token = os.Getenv(anvilConfig.GitHub.TokenEnvVar)
 		if token == "" {
-			output.PrintWarning("GitHub token not found in environment variable: %s", anvilConfig.GitHub.TokenEnvVar)
-			output.PrintInfo("Proceeding with SSH authentication if available...\n")
+			output.PrintWarning(i18n.T("push.token_missing"), anvilConfig.GitHub.TokenEnvVar)
+			output.PrintInfo(i18n.T("push.ssh_fallback") + "\n")
 		} else {
-			output.PrintSuccess("GitHub token found in environment\n")
+			output.PrintSuccess(i18n.T("push.token_found") + "\n")
 		}
 	}
 
@@ -304,60 +444,88 @@ func pushAnvilConfig() error {
 		anvilConfig.Git.Username,
 		anvilConfig.Git.Email,
 	)
+	githubClient.CloneDepth = anvilConfig.GitHub.CloneDepth
+	githubClient.MaterializeSymlinks = anvilConfig.GitHub.MaterializeSymlinks
+	githubClient.BranchTimestampFormat = anvilConfig.GitHub.BranchTimestampFormat
+	githubClient.BranchIncludeHost = anvilConfig.GitHub.BranchIncludeHost
+	githubClient.AppBranches = anvilConfig.GitHub.AppBranches
+	githubClient.MaxPushSizeMB = anvilConfig.GitHub.MaxPushSizeMB
+	githubClient.MaxPushFiles = anvilConfig.GitHub.MaxPushFiles
+	githubClient.GenerateReadme = anvilConfig.GitHub.GenerateReadme
+	githubClient.APICacheDir = filepath.Join(config.GetAnvilConfigDirectory(), "cache", "github")
 
 	// Get settings file path
 	settingsPath := config.GetAnvilConfigPath()
 
-	output.PrintStage("Preparing to push anvil configuration...")
-	output.PrintInfo("Repository: %s", anvilConfig.GitHub.ConfigRepo)
-	output.PrintInfo("Branch: %s", anvilConfig.GitHub.Branch)
-	output.PrintInfo("Settings file: %s", settingsPath)
+	output.PrintStage(i18n.T("push.stage.prepare_anvil"))
+	output.PrintInfo(i18n.T("push.repository"), anvilConfig.GitHub.ConfigRepo)
+	output.PrintInfo(i18n.T("push.branch"), anvilConfig.GitHub.Branch)
+	output.PrintInfo(i18n.T("push.settings_file"), settingsPath)
+
+	if err := runPreflight(githubClient, settingsPath); err != nil {
+		return err
+	}
+
+	if audit.IsEnabled() {
+		recordAuditedPush(anvilConfig, constants.ANVIL, settingsPath)
+		return nil
+	}
 
 	// NEW: Add diff output before confirmation
-	output.PrintStage("Analyzing changes...")
+	output.PrintStage(i18n.T("push.stage.analyze"))
 	ctx := context.Background()
 	anvilSettingsPath := fmt.Sprintf("%s/%s", constants.ANVIL_CONFIG_DIR, constants.ANVIL_CONFIG_FILE)
 	diffSummary, err := githubClient.GetDiffPreview(ctx, settingsPath, anvilSettingsPath[1:])
 	if err != nil {
-		output.PrintWarning("Unable to generate diff preview: %v", err)
+		output.PrintWarning(i18n.T("push.diff_failed"), err)
 	} else {
 		showDiffOutput(diffSummary)
 	}
 
-	// Stage 3: User confirmation
-	output.PrintStage("Requesting user confirmation...")
-	if !output.Confirm("Do you want to push your anvil settings to the repository?") {
-		output.PrintInfo("Push cancelled by user")
+	// Stage 3: User confirmation, after checking no other machine changed the settings since
+	if !checkDivergence(ctx, githubClient, anvilConfig, constants.ANVIL, force) {
+		return nil
+	}
+	output.PrintStage(i18n.T("push.stage.confirm"))
+	if !charm.Confirm(charm.ConfirmPush, i18n.T("push.confirm_anvil")) {
+		output.PrintInfo(i18n.T("push.cancelled"))
 		// Clean up any staged changes from the diff preview
 		if cleanupErr := githubClient.CleanupStagedChanges(ctx); cleanupErr != nil {
-			output.PrintWarning("Failed to cleanup staged changes: %v", cleanupErr)
+			output.PrintWarning(i18n.T("push.cleanup_failed"), cleanupErr)
 		}
 		return nil
 	}
 
 	// Stage 4: Push configuration
-	output.PrintStage("Pushing configuration to repository...")
+	output.PrintStage(i18n.T("push.stage.push_anvil"))
 	result, err := githubClient.PushAnvilConfig(ctx, settingsPath)
 	if err != nil {
-		output.PrintError("Push failed: %v", err)
+		output.PrintError(i18n.T("push.failed"), err)
 		// Clean up any staged changes in case of error
 		if cleanupErr := githubClient.CleanupStagedChanges(ctx); cleanupErr != nil {
-			output.PrintWarning("Failed to cleanup staged changes after error: %v", cleanupErr)
+			output.PrintWarning(i18n.T("push.cleanup_after_error_failed"), cleanupErr)
 		}
 		return errors.NewInstallationError(constants.OpPush, "push-config", err)
 	}
 
 	// Check if no changes were detected (result will be nil)
 	if result == nil {
-		output.PrintSuccess("Configuration up-to-date (no changes)")
+		output.PrintSuccess(i18n.T("push.up_to_date"))
 		return nil
 	}
 
-	output.PrintSuccess("Configuration pushed successfully")
-	displaySuccessMessage(constants.ANVIL, result, diffSummary, anvilConfig)
+	output.PrintSuccess(i18n.T("push.pushed"))
+	recordPushedBase(githubClient, anvilConfig, constants.ANVIL, result)
+	pr := openPullRequest(githubClient, result, prOptions, ctx)
+	displaySuccessMessage(constants.ANVIL, result, diffSummary, anvilConfig, pr)
 
 	return nil
 }
 
 func init() {
+	PushCmd.Flags().String("remote", "", "Push to a named repository from github.remotes instead of github.config_repo")
+	PushCmd.Flags().Bool("create-pr", false, "Open a pull request for the pushed branch (needs a GitHub token)")
+	PushCmd.Flags().Bool("auto-merge", false, "Open a pull request and enable auto-merge, implies --create-pr")
+	PushCmd.Flags().String("merge-method", github.MergeMethodMerge, "Merge method used by --auto-merge: merge, squash or rebase")
+	PushCmd.Flags().Bool("force", false, "Push even when another machine changed the app since it was last pulled here, replacing their changes")
 }
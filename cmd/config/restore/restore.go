@@ -0,0 +1,138 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"fmt"
+
+	"github.com/0xjuanma/anvil/internal/audit"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+	"github.com/0xjuanma/palantir"
+	"github.com/spf13/cobra"
+)
+
+var RestoreSettingsCmd = &cobra.Command{
+	Use:   "restore-settings",
+	Short: "Restore settings.yaml from the automatic backup ring",
+	Long:  constants.RESTORE_SETTINGS_COMMAND_LONG_DESCRIPTION,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runRestoreSettingsCommand(cmd); err != nil {
+			palantir.GetGlobalOutputHandler().PrintError("Restore failed: %v", err)
+			return
+		}
+	},
+}
+
+// runRestoreSettingsCommand lists the settings backups or restores one of them
+func runRestoreSettingsCommand(cmd *cobra.Command) error {
+	output := palantir.GetGlobalOutputHandler()
+	list, _ := cmd.Flags().GetBool("list")
+	version, _ := cmd.Flags().GetInt("version")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	backups, err := config.ListSettingsBackups()
+	if err != nil {
+		return errors.NewFileSystemError(constants.OpConfig, "list-backups", err)
+	}
+	if len(backups) == 0 {
+		output.PrintInfo("No backups of %s yet, one is kept each time anvil rewrites it", constants.ANVIL_CONFIG_FILE)
+		output.PrintInfo("Backups are stored in %s", config.GetSettingsBackupDirectory())
+		return nil
+	}
+
+	if list {
+		output.PrintHeader("Settings Backups")
+		for _, backup := range backups {
+			fmt.Println(formatBackup(backup))
+		}
+		fmt.Println()
+		output.PrintInfo("Run 'anvil config restore-settings --version N' to restore one")
+		return nil
+	}
+
+	if version < 1 || version > len(backups) {
+		return errors.NewValidationError(constants.OpConfig, "version",
+			fmt.Errorf("--version must be between 1 and %d, see 'anvil config restore-settings --list'", len(backups)))
+	}
+	backup := backups[version-1]
+
+	output.PrintHeader("Restore Settings")
+	fmt.Println(formatBackup(backup))
+	fmt.Println()
+
+	if dryRun {
+		output.PrintInfo("Dry run - would restore %s from backup %d", constants.ANVIL_CONFIG_FILE, backup.Version)
+		audit.Record("config restore-settings", "overwrite-file", config.GetAnvilConfigPath(), "from "+backup.Path)
+		return nil
+	}
+
+	if !charm.Confirm(charm.ConfirmSettings, fmt.Sprintf("Replace %s with backup %d? The current file is backed up first.", constants.ANVIL_CONFIG_FILE, backup.Version)) {
+		output.PrintInfo("Restore cancelled")
+		return nil
+	}
+	return restore(backup)
+}
+
+// OfferRestore offers to restore the newest valid backup when loadErr reports a corrupted
+// settings.yaml, and reports whether the settings were restored
+func OfferRestore(loadErr error) bool {
+	if !config.IsCorruptSettings(loadErr) {
+		return false
+	}
+	output := palantir.GetGlobalOutputHandler()
+	output.PrintWarning("%v", loadErr)
+
+	backup, ok := config.LatestValidSettingsBackup()
+	if !ok {
+		output.PrintInfo("No valid backup found in %s, fix the file by hand", config.GetSettingsBackupDirectory())
+		return false
+	}
+
+	if !charm.Confirm(charm.ConfirmSettings, fmt.Sprintf("Restore %s from the backup of %s?", constants.ANVIL_CONFIG_FILE, backup.Time.Format("2006-01-02 15:04:05"))) {
+		output.PrintInfo("Run 'anvil config restore-settings --list' to choose a backup later")
+		return false
+	}
+	if err := restore(backup); err != nil {
+		output.PrintError("Restore failed: %v", err)
+		return false
+	}
+	return true
+}
+
+// restore replaces settings.yaml with the backup and reports the result
+func restore(backup config.SettingsBackup) error {
+	if _, err := config.RestoreSettingsBackup(backup.Version); err != nil {
+		return errors.NewConfigurationError(constants.OpConfig, "restore-settings", err)
+	}
+	palantir.GetGlobalOutputHandler().PrintSuccess(fmt.Sprintf("Restored %s from the backup of %s", constants.ANVIL_CONFIG_FILE, backup.Time.Format("2006-01-02 15:04:05")))
+	return nil
+}
+
+// formatBackup renders a backup line with its version, time and size
+func formatBackup(backup config.SettingsBackup) string {
+	return fmt.Sprintf("  %3d  %s  %6d bytes", backup.Version, backup.Time.Format("2006-01-02 15:04:05"), backup.Size)
+}
+
+func init() {
+	RestoreSettingsCmd.Flags().Bool("list", false, "List the available backups, most recent first")
+	RestoreSettingsCmd.Flags().Int("version", 1, "Backup to restore, 1 is the most recent")
+	RestoreSettingsCmd.Flags().Bool("dry-run", false, "Show which backup would be restored without changing settings.yaml")
+}
@@ -0,0 +1,117 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package show
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/github"
+	"github.com/0xjuanma/palantir"
+	"github.com/spf13/cobra"
+)
+
+// showFileHistory lists the commits of the config repository that touched a file
+func showFileHistory(cmd *cobra.Command, file string, limit int) error {
+	o := palantir.GetGlobalOutputHandler()
+	client, err := newRepoClient()
+	if err != nil {
+		return err
+	}
+
+	o.PrintHeader(fmt.Sprintf("History: %s", file))
+	commits, err := client.FileHistory(cmd.Context(), file, limit)
+	if err != nil {
+		return err
+	}
+	if len(commits) == 0 {
+		o.PrintInfo("No commits touch %s in %s", file, client.LocalPath)
+		return nil
+	}
+
+	for _, commit := range commits {
+		fmt.Println(formatFileCommit(commit))
+	}
+	fmt.Println()
+	o.PrintInfo("💡 Show a revision: anvil config show %s --at %s", file, commits[0].ShortCommit())
+	return nil
+}
+
+// showFileAt prints a config repository file as it was at a revision
+func showFileAt(cmd *cobra.Command, file, ref string, raw bool) error {
+	client, err := newRepoClient()
+	if err != nil {
+		return err
+	}
+
+	content, commit, err := client.FileAtRevision(cmd.Context(), file, ref)
+	if err != nil {
+		return err
+	}
+
+	if !raw {
+		o := palantir.GetGlobalOutputHandler()
+		o.PrintHeader(fmt.Sprintf("Configuration: %s", file))
+		o.PrintInfo("Revision: %s (%s)\n", ref, github.FileCommit{Commit: commit}.ShortCommit())
+	}
+	fmt.Print(content)
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		fmt.Println()
+	}
+	return nil
+}
+
+// formatFileCommit renders a commit line with its date, hash, author and subject
+func formatFileCommit(commit github.FileCommit) string {
+	date := "unknown date"
+	if !commit.Date.IsZero() {
+		date = commit.Date.Local().Format("2006-01-02 15:04")
+	}
+	return fmt.Sprintf("  %s  %s  %-16s %s", commit.ShortCommit(), date, commit.Author, commit.Subject)
+}
+
+// newRepoClient creates a client for the local clone of the config repository
+func newRepoClient() (*github.GitHubClient, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, errors.NewConfigurationError(constants.OpShow, "load-config", err)
+	}
+	if cfg.GitHub.ConfigRepo == "" {
+		return nil, errors.NewConfigurationError(constants.OpShow, "missing-repo",
+			fmt.Errorf("GitHub repository not configured. Please set 'github.config_repo' in your %s", constants.ANVIL_CONFIG_FILE))
+	}
+
+	var token string
+	if cfg.GitHub.TokenEnvVar != "" {
+		token = os.Getenv(cfg.GitHub.TokenEnvVar)
+	}
+	client := github.NewGitHubClient(
+		cfg.GitHub.ConfigRepo,
+		cfg.GitHub.Branch,
+		cfg.GitHub.LocalPath,
+		token,
+		cfg.Git.SSHKeyPath,
+		cfg.Git.Username,
+		cfg.Git.Email,
+	)
+	client.CloneDepth = cfg.GitHub.CloneDepth
+	return client, nil
+}
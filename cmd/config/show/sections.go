@@ -81,7 +81,7 @@ func showGroupsSection() error {
 	// Use the shared tree view renderer
 	content := utils.RenderTreeView(groups, builtInGroupNames, customGroupNames, installedApps)
 
-	fmt.Println(charm.RenderBox("Groups", content, "#E0C867", false))
+	fmt.Println(charm.RenderBox("Groups", content, charm.ActiveTheme().Highlight, false))
 
 	return nil
 }
@@ -99,7 +99,7 @@ func showConfigsSection(anvilConfig *config.AnvilConfig) error {
 		}
 	}
 
-	fmt.Println(charm.RenderBox("Config Sources", boxContent.String(), "#E0C867", false))
+	fmt.Println(charm.RenderBox("Config Sources", boxContent.String(), charm.ActiveTheme().Highlight, false))
 	fmt.Println()
 	fmt.Println("  💡 Use 'anvil config push <app-name>' to push source directories")
 	fmt.Println("  💡 Use 'anvil config pull <app-name>' to pull configurations")
@@ -118,7 +118,7 @@ func showGitSection(anvilConfig *config.AnvilConfig) error {
 		boxContent.WriteString(fmt.Sprintf("    SSH Key Path: %s\n", utils.BoldText(anvilConfig.Git.SSHKeyPath, "")))
 	}
 
-	fmt.Println(charm.RenderBox("Git Configuration", boxContent.String(), "#CC78EB", false))
+	fmt.Println(charm.RenderBox("Git Configuration", boxContent.String(), charm.ActiveTheme().Special, false))
 	fmt.Println()
 	fmt.Println("  💡 Git configuration is auto-populated from your local git settings")
 	fmt.Println()
@@ -140,7 +140,7 @@ func showGitHubSection(anvilConfig *config.AnvilConfig) error {
 		boxContent.WriteString(fmt.Sprintf("    Token Environment Variable: %s\n", utils.BoldText(anvilConfig.GitHub.TokenEnvVar, "")))
 	}
 
-	fmt.Println(charm.RenderBox("GitHub Configuration", boxContent.String(), "#CC78EB", false))
+	fmt.Println(charm.RenderBox("GitHub Configuration", boxContent.String(), charm.ActiveTheme().Special, false))
 
 	return nil
 }
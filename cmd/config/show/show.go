@@ -21,6 +21,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/0xjuanma/anvil/internal/config"
 	"github.com/0xjuanma/anvil/internal/constants"
@@ -31,10 +32,11 @@ import (
 )
 
 var ShowCmd = &cobra.Command{
-	Use:   "show [directory]",
-	Short: "Show configuration files from anvil settings or pulled directories",
-	Long:  constants.SHOW_COMMAND_LONG_DESCRIPTION,
-	Args:  cobra.MaximumNArgs(1), // Accept 0 or 1 argument
+	Use:         "show [directory|file]",
+	Short:       "Show configuration files from anvil settings or pulled directories",
+	Long:        constants.SHOW_COMMAND_LONG_DESCRIPTION,
+	Annotations: map[string]string{constants.SafeModeAnnotation: "true"},
+	Args:        cobra.MaximumNArgs(1), // Accept 0 or 1 argument
 	Run: func(cmd *cobra.Command, args []string) {
 		if err := runShowCommand(cmd, args); err != nil {
 			palantir.GetGlobalOutputHandler().PrintError("Show failed: %v", err)
@@ -46,7 +48,9 @@ var ShowCmd = &cobra.Command{
   anvil config show --configs         # Show only config sources
   anvil config show --git             # Show only git configuration
   anvil config show --github          # Show only GitHub configuration
-  anvil config show myapp             # Show pulled configuration for 'myapp'`,
+  anvil config show myapp             # Show pulled configuration for 'myapp'
+  anvil config show obsidian/.obsidian/app.json --history   # Commits touching a repo file
+  anvil config show obsidian/.obsidian/app.json --at a1b2c3d # The file at a revision`,
 }
 
 func init() {
@@ -55,6 +59,10 @@ func init() {
 	ShowCmd.Flags().BoolP("configs", "c", false, "Show only config source directories (only applicable for anvil settings)")
 	ShowCmd.Flags().Bool("git", false, "Show only git configuration (only applicable for anvil settings)")
 	ShowCmd.Flags().Bool("github", false, "Show only GitHub configuration (only applicable for anvil settings)")
+	ShowCmd.Flags().Bool("history", false, "List the commits of the config repository that touched the given file")
+	ShowCmd.Flags().IntP("limit", "n", 10, "Number of commits listed by --history (0 for all)")
+	ShowCmd.Flags().String("sort", SortByName, "Order of the pulled directory tree: name, size or mtime")
+	ShowCmd.Flags().String("at", "", "Print the given file at a commit, branch, tag or YYYY-MM-DD date of the config repository")
 }
 
 // runShowCommand executes the configuration show process
@@ -64,6 +72,24 @@ func runShowCommand(cmd *cobra.Command, args []string) error {
 	configs, _ := cmd.Flags().GetBool("configs")
 	git, _ := cmd.Flags().GetBool("git")
 	github, _ := cmd.Flags().GetBool("github")
+	history, _ := cmd.Flags().GetBool("history")
+	at, _ := cmd.Flags().GetString("at")
+	sortBy, _ := cmd.Flags().GetString("sort")
+
+	// History and revisions read a file of the local clone of the config repository
+	if history || at != "" {
+		if len(args) == 0 {
+			return fmt.Errorf("--history and --at need a file path inside the config repository, e.g. obsidian/.obsidian/app.json")
+		}
+		if history && at != "" {
+			return fmt.Errorf("--history and --at cannot be combined")
+		}
+		if history {
+			limit, _ := cmd.Flags().GetInt("limit")
+			return showFileHistory(cmd, args[0], limit)
+		}
+		return showFileAt(cmd, args[0], at, raw)
+	}
 
 	// If no arguments provided, show the anvil config file
 	if len(args) == 0 {
@@ -75,8 +101,11 @@ func runShowCommand(cmd *cobra.Command, args []string) error {
 	}
 
 	// Show specific pulled configuration directory
+	if err := validateSortOrder(sortBy); err != nil {
+		return errors.NewValidationError(constants.OpShow, "sort", err)
+	}
 	targetDir := args[0]
-	return showPulledConfig(targetDir)
+	return showPulledConfig(targetDir, sortBy)
 }
 
 func checkSettingsFileExists(o palantir.OutputHandler, configPath string) error {
@@ -132,7 +161,7 @@ func showAnvilSettings(raw bool) error {
 	boxContent.WriteString("\n")
 
 	// Display in box
-	fmt.Println(charm.RenderBox(fmt.Sprintf("anvil %s", constants.ANVIL_CONFIG_FILE), boxContent.String(), "#00FF87", false))
+	fmt.Println(charm.RenderBox(fmt.Sprintf("anvil %s", constants.ANVIL_CONFIG_FILE), boxContent.String(), charm.ActiveTheme().Success, false))
 
 	// Footer with helpful info
 	fmt.Println()
@@ -144,7 +173,7 @@ func showAnvilSettings(raw bool) error {
 }
 
 // showPulledConfig displays configuration files from a pulled directory
-func showPulledConfig(targetDir string) error {
+func showPulledConfig(targetDir, sortBy string) error {
 	o := palantir.GetGlobalOutputHandler()
 	o.PrintHeader(fmt.Sprintf("Configuration Directory: %s", targetDir))
 
@@ -170,7 +199,7 @@ func showPulledConfig(targetDir string) error {
 		if entries, err := os.ReadDir(tempBasePath); err == nil && len(entries) > 0 {
 			o.PrintInfo("Available pulled configurations:")
 			for _, entry := range entries {
-				if entry.IsDir() {
+				if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
 					o.PrintInfo("  • %s", entry.Name())
 				}
 			}
@@ -182,11 +211,13 @@ func showPulledConfig(targetDir string) error {
 		return fmt.Errorf("configuration directory not found")
 	}
 	o.PrintSuccess("Configuration directory located")
-	o.PrintInfo("Directory: %s\n", tempDir)
+	o.PrintInfo("Directory: %s", tempDir)
+	printPullInfo(targetDir)
+	fmt.Println("")
 
 	// Stage 3: Display directory contents
 	o.PrintStage("Reading configuration files...")
-	err := showDirectoryTree(tempDir, targetDir)
+	err := showDirectoryTree(tempDir, targetDir, sortBy)
 	if err != nil {
 		return err
 	}
@@ -195,6 +226,25 @@ func showPulledConfig(targetDir string) error {
 	return nil
 }
 
+// printPullInfo shows when a pulled directory was copied and from which commit, flagging stale copies
+func printPullInfo(targetDir string) {
+	entry, found, err := config.GetTempEntry(targetDir)
+	if err != nil || !found {
+		return
+	}
+
+	o := palantir.GetGlobalOutputHandler()
+	now := time.Now()
+	if entry.Recorded {
+		o.PrintInfo("Pulled: %s ago from %s@%s (commit %s)", config.FormatAge(entry.Age(now)), entry.Repo, entry.Branch, entry.ShortCommit())
+	} else {
+		o.PrintInfo("Pulled: %s ago", config.FormatAge(entry.Age(now)))
+	}
+	if entry.IsStale(config.GetTempMaxAge(), now) {
+		o.PrintWarning("This copy is older than %s, run 'anvil config pull %s' to refresh it", config.FormatAge(config.GetTempMaxAge()), targetDir)
+	}
+}
+
 // showSingleFile displays the content of a single configuration file
 func showSingleFile(filePath, targetDir string) error {
 	o := palantir.GetGlobalOutputHandler()
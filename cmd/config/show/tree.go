@@ -22,22 +22,43 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/0xjuanma/anvil/internal/constants"
 	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/utils"
 	"github.com/0xjuanma/palantir"
 )
 
+// Orders accepted by --sort
+const (
+	SortByName  = "name"  // Directories first, then files, both alphabetically
+	SortBySize  = "size"  // Largest first
+	SortByMtime = "mtime" // Most recently modified first
+)
+
 // TreeNode represents a node in the file tree
 type TreeNode struct {
 	Name     string
 	Path     string
 	IsDir    bool
+	Size     int64     // File size, or the total size of the files below a directory
+	Files    int       // Files below a directory, 1 for a file
+	ModTime  time.Time // Modification time, for directories the newest file below it
 	Children []*TreeNode
 }
 
+// validateSortOrder rejects --sort values other than name, size and mtime
+func validateSortOrder(sortBy string) error {
+	switch sortBy {
+	case SortByName, SortBySize, SortByMtime:
+		return nil
+	}
+	return fmt.Errorf("invalid --sort '%s', use %s, %s or %s", sortBy, SortByName, SortBySize, SortByMtime)
+}
+
 // showDirectoryTree displays a tree structure of files/directories
-func showDirectoryTree(basePath, targetDir string) error {
+func showDirectoryTree(basePath, targetDir, sortBy string) error {
 	// Build the tree structure
 	root, err := buildTree(basePath)
 	if err != nil {
@@ -50,13 +71,14 @@ func showDirectoryTree(basePath, targetDir string) error {
 	}
 	o := palantir.GetGlobalOutputHandler()
 	o.PrintHeader(fmt.Sprintf("Configuration Directory: %s", targetDir))
-	o.PrintInfo("Path: %s\n", basePath)
+	o.PrintInfo("Path: %s", basePath)
+	o.PrintInfo("Total: %s in %d files, last modified %s\n", utils.FormatSize(root.Size), root.Files, formatModTime(root.ModTime))
 
 	// Display the tree structure
 	o.PrintInfo("Directory structure:\n")
 
 	// Sort children for consistent display
-	sortChildren(root)
+	sortChildren(root, sortBy)
 
 	// Print the tree starting from root
 	printTreeNode(root, "", true, true)
@@ -128,33 +150,84 @@ func buildTree(dirPath string) (*TreeNode, error) {
 		}
 		if info.IsDir() {
 			finalNode.Children = []*TreeNode{}
+		} else {
+			finalNode.Size, finalNode.Files, finalNode.ModTime = info.Size(), 1, info.ModTime()
 		}
 		current.Children = append(current.Children, finalNode)
 
 		return nil
 	})
+	summarizeTree(root)
 
 	return root, err
 }
 
-// sortChildren recursively sorts all children in the tree (directories first, then files, both alphabetically)
-func sortChildren(node *TreeNode) {
+// summarizeTree fills in the size, file count and newest modification time of every directory
+func summarizeTree(node *TreeNode) {
+	if !node.IsDir {
+		return
+	}
+	node.Size, node.Files, node.ModTime = 0, 0, time.Time{}
+	for _, child := range node.Children {
+		summarizeTree(child)
+		node.Size += child.Size
+		node.Files += child.Files
+		if child.ModTime.After(node.ModTime) {
+			node.ModTime = child.ModTime
+		}
+	}
+}
+
+// sortChildren recursively sorts all children in the tree. By name, directories come first and
+// both are sorted alphabetically; by size or mtime, the largest or newest entries come first.
+func sortChildren(node *TreeNode, sortBy string) {
 	if node.Children == nil {
 		return
 	}
 
-	// Sort children: directories first, then files, both alphabetically
-	sort.Slice(node.Children, func(i, j int) bool {
-		if node.Children[i].IsDir != node.Children[j].IsDir {
-			return node.Children[i].IsDir // directories come first
+	sort.SliceStable(node.Children, func(i, j int) bool {
+		a, b := node.Children[i], node.Children[j]
+		switch sortBy {
+		case SortBySize:
+			if a.Size != b.Size {
+				return a.Size > b.Size
+			}
+		case SortByMtime:
+			if !a.ModTime.Equal(b.ModTime) {
+				return a.ModTime.After(b.ModTime)
+			}
+		default:
+			if a.IsDir != b.IsDir {
+				return a.IsDir // directories come first
+			}
 		}
-		return node.Children[i].Name < node.Children[j].Name
+		return a.Name < b.Name
 	})
 
 	// Recursively sort children
 	for _, child := range node.Children {
-		sortChildren(child)
+		sortChildren(child, sortBy)
+	}
+}
+
+// formatModTime renders a modification date, empty directories have none
+func formatModTime(modTime time.Time) string {
+	if modTime.IsZero() {
+		return "never"
+	}
+	return modTime.Format("2006-01-02 15:04")
+}
+
+// nodeDetails describes a node's size and modification date, with the file count of directories
+func nodeDetails(node *TreeNode) string {
+	if node.IsDir {
+		noun := "files"
+		if node.Files == 1 {
+			noun = "file"
+		}
+		return fmt.Sprintf("%s in %d %s, %s", utils.FormatSize(node.Size), node.Files, noun, formatModTime(node.ModTime))
 	}
+	return fmt.Sprintf("%s, %s", utils.FormatSize(node.Size), formatModTime(node.ModTime))
 }
 
 // printTreeNode prints a tree node with ASCII art and colors
@@ -188,7 +261,7 @@ func printTreeNode(node *TreeNode, prefix string, isLast bool, isRoot bool) {
 		}
 
 		// Print the current node
-		fmt.Printf("%s%s%s\n", prefix, treeChar, coloredName)
+		fmt.Printf("%s%s%s  (%s)\n", prefix, treeChar, coloredName, nodeDetails(node))
 	}
 
 	// Print children
@@ -0,0 +1,85 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package show
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBuildTreeTotals(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"init.lua":          "-- 12 bytes",
+		"lua/plugins.lua":   "return { 'a', 'b' }",
+		"lua/keymaps.lua":   "x",
+		"after/ftplugin/go": "set noet",
+	}
+	older := time.Now().Add(-48 * time.Hour)
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if name != "lua/keymaps.lua" {
+			os.Chtimes(path, older, older)
+		}
+	}
+
+	root, err := buildTree(dir)
+	if err != nil {
+		t.Fatalf("buildTree() error = %v", err)
+	}
+	if root.Files != 4 || root.Size != 39 {
+		t.Errorf("root = %d files, %d bytes, want 4 files, 39 bytes", root.Files, root.Size)
+	}
+
+	names := func(node *TreeNode) []string {
+		var got []string
+		for _, child := range node.Children {
+			got = append(got, child.Name)
+		}
+		return got
+	}
+
+	sortChildren(root, SortByName)
+	if got := names(root); got[0] != "after" || got[1] != "lua" || got[2] != "init.lua" {
+		t.Errorf("by name = %v, want directories first", got)
+	}
+	if lua := root.Children[1]; lua.Files != 2 || lua.Size != 20 {
+		t.Errorf("lua = %d files, %d bytes, want 2 files, 20 bytes", lua.Files, lua.Size)
+	}
+
+	sortChildren(root, SortBySize)
+	if got := names(root); got[0] != "lua" || got[1] != "init.lua" || got[2] != "after" {
+		t.Errorf("by size = %v, want [lua init.lua after]", got)
+	}
+
+	sortChildren(root, SortByMtime)
+	if got := names(root); got[0] != "lua" {
+		t.Errorf("by mtime = %v, want lua first with the newest file", got)
+	}
+
+	if err := validateSortOrder("largest"); err == nil {
+		t.Error("validateSortOrder(largest) should fail")
+	}
+}
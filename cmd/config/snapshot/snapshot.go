@@ -0,0 +1,396 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/github"
+	"github.com/0xjuanma/anvil/internal/system"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+	"github.com/0xjuanma/anvil/internal/utils"
+	"github.com/0xjuanma/palantir"
+	"github.com/spf13/cobra"
+)
+
+// localSnapshot names the configs currently on this machine
+const localSnapshot = "local"
+
+// maxDiffLines caps the diff shown for a single file
+const maxDiffLines = 200
+
+// archiveTimestamp matches the suffix sync adds to archive directory names
+var archiveTimestamp = regexp.MustCompile(`-\d{4}-\d{2}-\d{2}-\d{2}-\d{2}-\d{2}$`)
+
+var SnapshotDiffCmd = &cobra.Command{
+	Use:   "snapshot-diff <from> <to>",
+	Short: "Show what changed in your configuration between two points in time",
+	Long:  constants.SNAPSHOT_DIFF_COMMAND_LONG_DESCRIPTION,
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runSnapshotDiffCommand(cmd, args); err != nil {
+			palantir.GetGlobalOutputHandler().PrintError("Snapshot diff failed: %v", err)
+			return
+		}
+	},
+}
+
+// snapshot is a set of app configs at one point in time, keyed by app name
+type snapshot struct {
+	label string
+	apps  map[string]string // App name to its config file or directory
+}
+
+// fileDiff is a file that differs between two snapshots
+type fileDiff struct {
+	app     string
+	relPath string
+	status  string // added, removed or modified
+	oldPath string
+	newPath string
+}
+
+// runSnapshotDiffCommand resolves both snapshots and prints their differences
+func runSnapshotDiffCommand(cmd *cobra.Command, args []string) error {
+	output := palantir.GetGlobalOutputHandler()
+	apps, _ := cmd.Flags().GetStringSlice("app")
+	patterns, _ := cmd.Flags().GetStringSlice("pattern")
+	statOnly, _ := cmd.Flags().GetBool("stat")
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return errors.NewConfigurationError(constants.OpConfig, "load-config", err)
+	}
+
+	workDir, err := os.MkdirTemp("", "anvil-snapshot-diff-")
+	if err != nil {
+		return errors.NewFileSystemError(constants.OpConfig, "snapshot-temp", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	resolver := &snapshotResolver{cfg: cfg, workDir: workDir, ctx: cmd.Context()}
+	from, err := resolver.resolve(args[0])
+	if err != nil {
+		return err
+	}
+	to, err := resolver.resolve(args[1])
+	if err != nil {
+		return err
+	}
+
+	output.PrintHeader(fmt.Sprintf("Config Changes: %s → %s", from.label, to.label))
+	diffs, err := diffSnapshots(from, to, apps, patterns)
+	if err != nil {
+		return err
+	}
+	if len(diffs) == 0 {
+		output.PrintSuccess("No differences found")
+		return nil
+	}
+
+	changedApps := 0
+	for i, diff := range diffs {
+		if i == 0 || diffs[i-1].app != diff.app {
+			changedApps++
+			fmt.Println()
+			output.PrintStage(diff.app)
+		}
+		output.PrintInfo("  %s %s (%s)", statusMarker(diff.status), diff.relPath, diff.status)
+		if !statOnly {
+			showDiff(diff)
+		}
+	}
+	fmt.Println()
+	output.PrintInfo("%d file(s) changed in %d app(s)", len(diffs), changedApps)
+	return nil
+}
+
+// snapshotResolver turns command arguments into snapshots
+type snapshotResolver struct {
+	cfg     *config.AnvilConfig
+	workDir string
+	ctx     context.Context
+	client  *github.GitHubClient
+}
+
+// resolve accepts "local", an archive name or path, or a revision or date of the config repository
+func (r *snapshotResolver) resolve(arg string) (*snapshot, error) {
+	if arg == localSnapshot {
+		return localConfigs(r.cfg), nil
+	}
+
+	archiveDir := filepath.Join(config.GetAnvilConfigDirectory(), "archive", arg)
+	if info, err := os.Stat(archiveDir); err == nil && info.IsDir() {
+		return archiveSnapshot(archiveDir), nil
+	}
+	if info, err := os.Stat(arg); err == nil && info.IsDir() && filepath.Base(filepath.Dir(filepath.Clean(arg))) == "archive" {
+		return archiveSnapshot(arg), nil
+	}
+
+	if r.client == nil {
+		r.client = newGitHubClient(r.cfg)
+	}
+	commit, err := r.client.ResolveRevision(r.ctx, arg)
+	if err != nil {
+		return nil, err
+	}
+	dest := filepath.Join(r.workDir, commit)
+	if err := r.client.ExportRevision(r.ctx, commit, dest); err != nil {
+		return nil, err
+	}
+	return repoSnapshot(fmt.Sprintf("%s (%.8s)", arg, commit), dest)
+}
+
+// localConfigs returns the configs and settings currently on this machine
+func localConfigs(cfg *config.AnvilConfig) *snapshot {
+	apps := map[string]string{constants.ANVIL: config.GetAnvilConfigPath()}
+	for app, path := range cfg.Configs {
+		apps[app] = path
+	}
+	return &snapshot{label: localSnapshot, apps: apps}
+}
+
+// archiveSnapshot returns the single app a sync archive holds, named from the archive directory
+func archiveSnapshot(dir string) *snapshot {
+	name := archiveTimestamp.ReplaceAllString(filepath.Base(dir), "")
+	app := strings.TrimSuffix(name, "-configs")
+	if name == "anvil-settings" {
+		app = constants.ANVIL
+	}
+	return &snapshot{label: filepath.Base(dir), apps: map[string]string{app: dir}}
+}
+
+// repoSnapshot returns the app directories of an exported repository revision
+func repoSnapshot(label, dir string) (*snapshot, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	apps := make(map[string]string)
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			apps[entry.Name()] = filepath.Join(dir, entry.Name())
+		}
+	}
+	return &snapshot{label: label, apps: apps}, nil
+}
+
+// diffSnapshots compares the apps of two snapshots, limited to the given apps and file patterns
+func diffSnapshots(from, to *snapshot, apps, patterns []string) ([]fileDiff, error) {
+	names := make(map[string]bool)
+	for app := range from.apps {
+		names[app] = true
+	}
+	for app := range to.apps {
+		names[app] = true
+	}
+
+	var sorted []string
+	for app := range names {
+		if len(apps) == 0 || containsFold(apps, app) {
+			sorted = append(sorted, app)
+		}
+	}
+	sort.Strings(sorted)
+
+	var diffs []fileDiff
+	for _, app := range sorted {
+		oldFiles, err := listSnapshotFiles(from.apps[app])
+		if err != nil {
+			return nil, fmt.Errorf("%s in %s: %w", app, from.label, err)
+		}
+		newFiles, err := listSnapshotFiles(to.apps[app])
+		if err != nil {
+			return nil, fmt.Errorf("%s in %s: %w", app, to.label, err)
+		}
+
+		appDiffs, err := compareFiles(app, oldFiles, newFiles, patterns)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, appDiffs...)
+	}
+	return diffs, nil
+}
+
+// compareFiles compares two file listings of an app by content
+func compareFiles(app string, oldFiles, newFiles map[string]string, patterns []string) ([]fileDiff, error) {
+	var rels []string
+	seen := make(map[string]bool)
+	for _, files := range []map[string]string{oldFiles, newFiles} {
+		for rel := range files {
+			if !seen[rel] && (len(patterns) == 0 || utils.MatchesExclude(rel, patterns)) {
+				seen[rel] = true
+				rels = append(rels, rel)
+			}
+		}
+	}
+	sort.Strings(rels)
+
+	var diffs []fileDiff
+	for _, rel := range rels {
+		diff := fileDiff{app: app, relPath: rel, oldPath: oldFiles[rel], newPath: newFiles[rel]}
+		switch {
+		case diff.oldPath == "":
+			diff.status = "added"
+		case diff.newPath == "":
+			diff.status = "removed"
+		default:
+			oldData, err := os.ReadFile(diff.oldPath)
+			if err != nil {
+				return nil, err
+			}
+			newData, err := os.ReadFile(diff.newPath)
+			if err != nil {
+				return nil, err
+			}
+			if bytes.Equal(oldData, newData) {
+				continue
+			}
+			diff.status = "modified"
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs, nil
+}
+
+// listSnapshotFiles maps relative paths to files for a config file or directory. A single
+// file is listed by its name, matching how it is stored in the repository.
+func listSnapshotFiles(path string) (map[string]string, error) {
+	files := make(map[string]string)
+	if path == "" {
+		return files, nil
+	}
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return files, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		files[filepath.Base(path)] = path
+		return files, nil
+	}
+
+	err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if entry.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(path, file)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = file
+		return nil
+	})
+	return files, err
+}
+
+// showDiff prints the content diff of a changed file
+func showDiff(diff fileDiff) {
+	oldPath, newPath := diff.oldPath, diff.newPath
+	if oldPath == "" {
+		oldPath = os.DevNull
+	}
+	if newPath == "" {
+		newPath = os.DevNull
+	}
+
+	color := "--color=always"
+	if charm.ColorDisabled() {
+		color = "--color=never"
+	}
+	// git diff --no-index exits 1 when files differ, so only the output matters here
+	result, _ := system.RunCommand(constants.GitCommand, "diff", "--no-index", color, "--", oldPath, newPath)
+	text := strings.TrimRight(result.Output, "\n")
+	if text == "" {
+		fmt.Printf("    (diff unavailable, file mode or binary content changed)\n")
+		return
+	}
+
+	lines := strings.Split(text, "\n")
+	if len(lines) > maxDiffLines {
+		fmt.Println(strings.Join(lines[:maxDiffLines], "\n"))
+		fmt.Printf("    ... %d more line(s)\n", len(lines)-maxDiffLines)
+		return
+	}
+	fmt.Println(text)
+}
+
+// statusMarker returns the +/-/~ marker used for a change
+func statusMarker(status string) string {
+	switch status {
+	case "added":
+		return "+"
+	case "removed":
+		return "-"
+	}
+	return "~"
+}
+
+// containsFold reports whether list contains value, ignoring case
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// newGitHubClient creates a client for the local clone of the config repository
+func newGitHubClient(cfg *config.AnvilConfig) *github.GitHubClient {
+	var token string
+	if cfg.GitHub.TokenEnvVar != "" {
+		token = os.Getenv(cfg.GitHub.TokenEnvVar)
+	}
+	client := github.NewGitHubClient(
+		cfg.GitHub.ConfigRepo,
+		cfg.GitHub.Branch,
+		cfg.GitHub.LocalPath,
+		token,
+		cfg.Git.SSHKeyPath,
+		cfg.Git.Username,
+		cfg.Git.Email,
+	)
+	client.CloneDepth = cfg.GitHub.CloneDepth
+	return client
+}
+
+func init() {
+	SnapshotDiffCmd.Flags().StringSlice("app", nil, "Only compare these apps (repeatable, 'anvil' for settings.yaml)")
+	SnapshotDiffCmd.Flags().StringSlice("pattern", nil, "Only compare files matching these globs, e.g. '*.json'")
+	SnapshotDiffCmd.Flags().Bool("stat", false, "List changed files without their diffs")
+}
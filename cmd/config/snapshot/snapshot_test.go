@@ -0,0 +1,101 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		path := filepath.Join(root, rel)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	old, current := t.TempDir(), t.TempDir()
+	writeFiles(t, old, map[string]string{
+		"zsh/.zshrc":           "export A=1",
+		"zsh/plugins/git.zsh":  "old",
+		"nvim/init.lua":        "same",
+		"anvil/settings.yaml":  "version: 1",
+		"cursor/settings.json": "{}",
+	})
+	writeFiles(t, current, map[string]string{
+		"zsh/.zshrc":          "export A=2",
+		"zsh/plugins/new.zsh": "new",
+		"nvim/init.lua":       "same",
+		"nvim/.git/HEAD":      "ignored",
+	})
+	// Local file configs are compared by name, like they are stored in the repository
+	settings := filepath.Join(current, "settings.yaml")
+	os.WriteFile(settings, []byte("version: 2"), 0644)
+
+	from, err := repoSnapshot("old", old)
+	if err != nil {
+		t.Fatalf("repoSnapshot failed: %v", err)
+	}
+	to := &snapshot{label: "local", apps: map[string]string{
+		"zsh":   filepath.Join(current, "zsh"),
+		"nvim":  filepath.Join(current, "nvim"),
+		"anvil": settings,
+	}}
+
+	diffs, err := diffSnapshots(from, to, nil, nil)
+	if err != nil {
+		t.Fatalf("diffSnapshots failed: %v", err)
+	}
+	want := []string{
+		"anvil/settings.yaml modified",
+		"cursor/settings.json removed",
+		"zsh/.zshrc modified",
+		"zsh/plugins/git.zsh removed",
+		"zsh/plugins/new.zsh added",
+	}
+	if len(diffs) != len(want) {
+		t.Fatalf("got %d diffs, want %d: %+v", len(diffs), len(want), diffs)
+	}
+	for i, diff := range diffs {
+		if got := diff.app + "/" + diff.relPath + " " + diff.status; got != want[i] {
+			t.Errorf("diff %d = %s, want %s", i, got, want[i])
+		}
+	}
+
+	filtered, _ := diffSnapshots(from, to, []string{"ZSH"}, []string{"*.zsh"})
+	if len(filtered) != 2 || filtered[0].relPath != "plugins/git.zsh" || filtered[1].relPath != "plugins/new.zsh" {
+		t.Errorf("filtered diffs = %+v", filtered)
+	}
+}
+
+func TestArchiveSnapshot(t *testing.T) {
+	tests := map[string]string{
+		"cursor-configs-2025-06-01-10-00-00": "cursor",
+		"anvil-settings-2025-06-01-10-00-00": "anvil",
+		"my-app-configs-2025-06-01-10-00-00": "my-app",
+	}
+	for dir, app := range tests {
+		s := archiveSnapshot(filepath.Join("/archive", dir))
+		if _, ok := s.apps[app]; !ok || len(s.apps) != 1 {
+			t.Errorf("archiveSnapshot(%s) apps = %v, want %s", dir, s.apps, app)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+
+	"github.com/0xjuanma/anvil/internal/audit"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/gitsettings"
+	"github.com/0xjuanma/palantir"
+)
+
+// ApplyGitSettings sets git.extra_config and git.global_ignore from settings.yaml in the global
+// git config, leaving other keys alone. It reports whether any value changed, or would change
+// in a dry run.
+func ApplyGitSettings(dryRun bool) (bool, error) {
+	git, err := config.GetGitConfig()
+	if err != nil {
+		return false, errors.NewConfigurationError(constants.OpSync, "load-settings", err)
+	}
+	if len(git.ExtraConfig) == 0 && git.GlobalIgnore == "" {
+		return false, nil
+	}
+
+	o := palantir.GetGlobalOutputHandler()
+	settings, err := gitsettings.Plan(git)
+	if err != nil {
+		return false, errors.NewConfigurationError(constants.OpSync, "git-settings", err)
+	}
+	drifted := gitsettings.Drifted(settings)
+	if len(drifted) == 0 {
+		o.PrintAlreadyAvailable("Git settings are already applied")
+		return false, nil
+	}
+
+	if dryRun {
+		for _, setting := range drifted {
+			o.PrintInfo("Dry run - would set git %s to '%s'", setting.Key, setting.Want)
+			audit.Record("sync", "set-git-config", setting.Key, setting.Want)
+		}
+		return true, nil
+	}
+
+	applied, err := gitsettings.Apply(git)
+	if err != nil {
+		return false, errors.NewConfigurationError(constants.OpSync, "git-settings", err)
+	}
+	for _, setting := range applied {
+		o.PrintInfo("Set git %s to '%s'", setting.Key, setting.Want)
+	}
+	o.PrintSuccess(fmt.Sprintf("Applied %d git settings", len(applied)))
+	if gitsettings.IgnoreFileMissing(git) {
+		o.PrintWarning("Global ignore file %s does not exist yet, add it to configs to sync it from your config repo", git.GlobalIgnore)
+	}
+	return true, nil
+}
@@ -0,0 +1,304 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/events"
+	"github.com/0xjuanma/anvil/internal/system"
+	"github.com/0xjuanma/anvil/internal/utils"
+	"github.com/0xjuanma/palantir"
+)
+
+// maxDiffLines caps the diff shown for a single file during interactive sync
+const maxDiffLines = 200
+
+// promptInput is where interactive sync reads per-file decisions from
+var promptInput io.Reader = os.Stdin
+
+// fileChange is a file whose pulled version differs from the local one
+type fileChange struct {
+	relPath string
+	source  string
+	dest    string
+	isNew   bool
+}
+
+// syncDecision is the user's choice for a changed file
+type syncDecision int
+
+const (
+	decisionApply syncDecision = iota
+	decisionSkip
+	decisionAbort
+)
+
+// collectChanges returns the files under sourcePath that are new or differ from destPath.
+// Files only present locally are left alone, matching a regular sync.
+func collectChanges(sourcePath, destPath string, filter config.ConfigFilter) ([]fileChange, error) {
+	sourceInfo, err := os.Stat(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if !sourceInfo.IsDir() {
+		change, changed, err := compareFile(sourcePath, destPath, filepath.Base(destPath))
+		if err != nil || !changed {
+			return nil, err
+		}
+		return []fileChange{change}, nil
+	}
+
+	var changes []fileChange
+	err = filepath.WalkDir(sourcePath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(sourcePath, path)
+		if err != nil || relPath == "." {
+			return err
+		}
+		if filter.Skips(relPath, entry.IsDir()) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		change, changed, err := compareFile(path, filepath.Join(destPath, relPath), relPath)
+		if err != nil {
+			return err
+		}
+		if changed {
+			changes = append(changes, change)
+		}
+		return nil
+	})
+	return changes, err
+}
+
+// compareFile reports whether the pulled file differs from the local one
+func compareFile(source, dest, relPath string) (fileChange, bool, error) {
+	change := fileChange{relPath: relPath, source: source, dest: dest}
+
+	destInfo, err := os.Lstat(dest)
+	if os.IsNotExist(err) {
+		change.isNew = true
+		return change, true, nil
+	}
+	if err != nil {
+		return change, false, err
+	}
+
+	sourceInfo, err := os.Lstat(source)
+	if err != nil {
+		return change, false, err
+	}
+
+	if utils.IsSymlink(sourceInfo) || utils.IsSymlink(destInfo) {
+		sourceTarget, _ := os.Readlink(source)
+		destTarget, _ := os.Readlink(dest)
+		return change, sourceTarget != destTarget || utils.IsSymlink(sourceInfo) != utils.IsSymlink(destInfo), nil
+	}
+
+	if sourceInfo.Size() != destInfo.Size() || sourceInfo.Mode().Perm() != destInfo.Mode().Perm() {
+		return change, true, nil
+	}
+
+	sourceData, err := os.ReadFile(source)
+	if err != nil {
+		return change, false, err
+	}
+	destData, err := os.ReadFile(dest)
+	if err != nil {
+		return change, false, err
+	}
+	return change, !bytes.Equal(sourceData, destData), nil
+}
+
+// performInteractiveSync walks through each changed file, showing its diff and asking whether to apply it.
+// Only files that get overwritten are archived.
+func performInteractiveSync(archivePrefix, sourcePath, destPath string, filter config.ConfigFilter) error {
+	output := palantir.GetGlobalOutputHandler()
+
+	changes, err := collectChanges(sourcePath, destPath, filter)
+	if err != nil {
+		return fmt.Errorf("failed to compare configs: %w", err)
+	}
+	if len(changes) == 0 {
+		output.PrintSuccess("Already in sync, no changes to apply")
+		return nil
+	}
+
+	output.PrintInfo("%d file(s) differ from the local copy\n", len(changes))
+
+	reader := bufio.NewReader(promptInput)
+	options := syncCopyOptions()
+	var applied, skipped []string
+	var archivePath string
+	aborted := false
+
+	for i, change := range changes {
+		status := "modified"
+		if change.isNew {
+			status = "new"
+		}
+		output.PrintStage(fmt.Sprintf("[%d/%d] %s (%s)", i+1, len(changes), change.relPath, status))
+		showFileDiff(change)
+
+		decision := promptDecision(reader)
+		if decision == decisionAbort {
+			aborted = true
+			break
+		}
+		if decision == decisionSkip {
+			skipped = append(skipped, change.relPath)
+			continue
+		}
+
+		if !change.isNew {
+			if archivePath == "" {
+				if archivePath, err = createArchiveDirectory(archivePrefix); err != nil {
+					return fmt.Errorf("failed to create archive directory: %w", err)
+				}
+			}
+			if err := archiveFile(change.dest, filepath.Join(archivePath, change.relPath)); err != nil {
+				return fmt.Errorf("failed to archive %s: %w", change.relPath, err)
+			}
+		}
+
+		if err := applyFile(change, options); err != nil {
+			return fmt.Errorf("failed to apply %s: %w", change.relPath, err)
+		}
+		applied = append(applied, change.relPath)
+	}
+
+	printSyncSummary(applied, skipped, len(changes), aborted, archivePath)
+	if len(applied) > 0 {
+		events.Publish(events.Synced, destPath, "")
+		recordSync(rollbackName(archivePrefix))
+	}
+	return nil
+}
+
+// showFileDiff prints a diff between the local file and the pulled one
+func showFileDiff(change fileChange) {
+	oldPath := change.dest
+	if change.isNew {
+		oldPath = os.DevNull
+	}
+
+	// git diff --no-index exits 1 when files differ, so only the output matters here
+	result, _ := system.RunCommand(constants.GitCommand, "diff", "--no-index", "--color=always", "--", oldPath, change.source)
+	diff := strings.TrimRight(result.Output, "\n")
+	if diff == "" {
+		fmt.Printf("  (diff unavailable, file mode or binary content changed)\n\n")
+		return
+	}
+
+	lines := strings.Split(diff, "\n")
+	if len(lines) > maxDiffLines {
+		fmt.Println(strings.Join(lines[:maxDiffLines], "\n"))
+		fmt.Printf("  ... %d more line(s)\n\n", len(lines)-maxDiffLines)
+		return
+	}
+	fmt.Println(diff)
+	fmt.Println()
+}
+
+// promptDecision asks whether to apply, skip or abort, re-prompting on unknown input
+func promptDecision(reader *bufio.Reader) syncDecision {
+	for {
+		fmt.Print("Apply this change? [a]pply / [s]kip / [q]uit: ")
+		answer, err := reader.ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "a", "apply", "y", "yes":
+			return decisionApply
+		case "s", "skip", "n", "no":
+			return decisionSkip
+		case "q", "quit", "abort":
+			return decisionAbort
+		}
+		if err != nil {
+			// Input closed, treat as abort so nothing is applied unattended
+			fmt.Println()
+			return decisionAbort
+		}
+	}
+}
+
+// archiveFile copies a local file into the archive before it is overwritten
+func archiveFile(source, archiveDest string) error {
+	if err := utils.EnsureDirectory(filepath.Dir(archiveDest)); err != nil {
+		return err
+	}
+	if info, err := os.Lstat(source); err == nil && utils.IsSymlink(info) {
+		return utils.CopySymlink(source, archiveDest, true)
+	}
+	return utils.CopyFileSimple(source, archiveDest)
+}
+
+// applyFile copies the pulled file over the local one
+func applyFile(change fileChange, options utils.CopyOptions) error {
+	if err := utils.EnsureDirectory(filepath.Dir(change.dest)); err != nil {
+		return err
+	}
+	if info, err := os.Lstat(change.source); err == nil && utils.IsSymlink(info) && options.PreserveSymlinks {
+		return utils.CopySymlink(change.source, change.dest, true)
+	}
+	return utils.CopyFile(change.source, change.dest, options)
+}
+
+// printSyncSummary reports what an interactive sync applied and skipped
+func printSyncSummary(applied, skipped []string, total int, aborted bool, archivePath string) {
+	output := palantir.GetGlobalOutputHandler()
+	fmt.Println()
+
+	if aborted {
+		output.PrintWarning("Sync aborted after reviewing %d of %d file(s)", len(applied)+len(skipped), total)
+	}
+
+	if len(applied) == 0 {
+		output.PrintInfo("No changes applied")
+	} else {
+		output.PrintSuccess(fmt.Sprintf("Applied %d change(s):", len(applied)))
+		for _, path := range applied {
+			output.PrintInfo("  • %s", path)
+		}
+	}
+
+	if len(skipped) > 0 {
+		output.PrintInfo("Skipped %d file(s): %s", len(skipped), strings.Join(skipped, ", "))
+	}
+
+	if archivePath != "" {
+		output.PrintInfo("Overwritten files archived to: %s", archivePath)
+	}
+}
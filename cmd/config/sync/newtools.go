@@ -0,0 +1,147 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/0xjuanma/anvil/cmd/install"
+	"github.com/0xjuanma/anvil/internal/audit"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/i18n"
+	"github.com/0xjuanma/anvil/internal/pkgmanager"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+	"github.com/0xjuanma/palantir"
+	"gopkg.in/yaml.v2"
+)
+
+// installNewGroupTools looks for tools the synced settings added to groups installed on this
+// machine and, depending on sync.new_group_tools, installs them after a prompt, right away,
+// or only lists them. A dry run compares the pulled settings, which were not applied.
+func installNewGroupTools(dryRun bool, pulledSettingsPath string) {
+	o := palantir.GetGlobalOutputHandler()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		o.PrintWarning(i18n.T("sync.new_tools.skipped"), err)
+		return
+	}
+	groups := cfg.Groups
+	if dryRun {
+		pulled, err := loadPulledGroups(pulledSettingsPath)
+		if err != nil {
+			o.PrintWarning(i18n.T("sync.new_tools.skipped"), err)
+			return
+		}
+		groups = pulled
+	}
+
+	lock, err := config.LoadGroupsLock()
+	if err != nil {
+		o.PrintWarning(i18n.T("sync.new_tools.skipped"), err)
+		return
+	}
+
+	added := config.NewGroupTools(groups, lock)
+	pending := pendingGroupTools(added, pkgmanager.IsAvailable)
+	for groupName := range added {
+		// Nothing left to install, e.g. the new tools were installed by hand
+		if _, found := pending[groupName]; !found && !dryRun {
+			_ = config.RecordInstalledGroup(groupName, groups[groupName])
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(pending))
+	count := 0
+	for groupName, tools := range pending {
+		names = append(names, groupName)
+		count += len(tools)
+	}
+	sort.Strings(names)
+
+	o.PrintStage(i18n.T("sync.new_tools.title"))
+	for _, groupName := range names {
+		o.PrintInfo("  %s: %s", groupName, strings.Join(pending[groupName], ", "))
+	}
+
+	mode := cfg.NewGroupToolsMode()
+	switch {
+	case dryRun:
+		o.PrintInfo(i18n.T("sync.new_tools.dry_run"), count, mode)
+		for _, groupName := range names {
+			audit.Record("sync", "install-new-group-tools", groupName, strings.Join(pending[groupName], ", "))
+		}
+		return
+	case mode == config.NewGroupToolsOff:
+		o.PrintInfo(i18n.T("sync.new_tools.hint"))
+		return
+	case mode == config.NewGroupToolsPrompt && !charm.Confirm(charm.ConfirmInstall, i18n.T("sync.new_tools.confirm", count)):
+		o.PrintInfo(i18n.T("sync.new_tools.hint_later"))
+		return
+	}
+
+	for _, groupName := range names {
+		failed := 0
+		for _, tool := range pending[groupName] {
+			if err := install.InstallTarget(tool, false); err != nil {
+				o.PrintWarning(i18n.T("sync.new_tools.install_failed"), tool, err)
+				failed++
+			}
+		}
+		// Failed tools stay pending, the next sync offers them again
+		if failed == 0 {
+			if err := config.RecordInstalledGroup(groupName, groups[groupName]); err != nil {
+				o.PrintWarning(i18n.T("install.group.record_failed"), groupName, constants.GROUPS_LOCK_FILE, err)
+			}
+		}
+	}
+}
+
+// pendingGroupTools drops the new tools there is nothing to do for: tools already available,
+// managed by another tool or tagged for other platforms
+func pendingGroupTools(added map[string][]string, isAvailable func(string) bool) map[string][]string {
+	pending := make(map[string][]string)
+	for groupName, tools := range added {
+		for _, tool := range tools {
+			if config.IsManagedExternally(tool) || !config.IsToolSupported(tool) || isAvailable(tool) {
+				continue
+			}
+			pending[groupName] = append(pending[groupName], tool)
+		}
+	}
+	return pending
+}
+
+// loadPulledGroups reads the groups of pulled settings that were not synced yet
+func loadPulledGroups(path string) (config.AnvilGroups, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pulled settings: %w", err)
+	}
+	var pulled config.AnvilConfig
+	if err := yaml.Unmarshal(data, &pulled); err != nil {
+		return nil, fmt.Errorf("failed to parse pulled settings: %w", err)
+	}
+	return pulled.Groups, nil
+}
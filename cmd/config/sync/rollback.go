@@ -0,0 +1,434 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/audit"
+	"github.com/0xjuanma/anvil/internal/automation"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/events"
+	"github.com/0xjuanma/anvil/internal/i18n"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+	"github.com/0xjuanma/anvil/internal/timeline"
+	"github.com/0xjuanma/palantir"
+	"github.com/spf13/cobra"
+)
+
+// archiveTimeLayout is the timestamp createArchiveDirectory appends to archive names
+const archiveTimeLayout = "2006-01-02-15-04-05"
+
+// archiveSuffix matches the timestamp suffix of an archive directory name
+var archiveSuffix = regexp.MustCompile(`-(\d{4}-\d{2}-\d{2}-\d{2}-\d{2}-\d{2})$`)
+
+var RollbackCmd = &cobra.Command{
+	Use:   "rollback [app-name]",
+	Short: "Restore an app's configs from an archive made by 'config sync'",
+	Long:  constants.ROLLBACK_COMMAND_LONG_DESCRIPTION,
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		reader := bufio.NewReader(promptInput)
+		appName := ""
+		if len(args) > 0 {
+			appName = args[0]
+		} else {
+			var ok bool
+			if appName, ok = pickArchivedApp(reader); !ok {
+				return
+			}
+		}
+
+		if err := runRollbackCommand(cmd, appName, reader); err != nil {
+			palantir.GetGlobalOutputHandler().PrintError(i18n.T("sync.rollback.failed"), err)
+			return
+		}
+	},
+}
+
+// syncArchive is an archive directory sync created before overwriting an app's configs
+type syncArchive struct {
+	name    string
+	path    string
+	time    time.Time
+	files   int
+	changes []fileChange // Files restoring the archive would write
+}
+
+// archivedApp is an app with at least one non-empty sync archive
+type archivedApp struct {
+	name   string
+	count  int
+	latest time.Time
+}
+
+// runRollbackCommand lists an app's archives, previews the chosen one and restores it
+func runRollbackCommand(cmd *cobra.Command, appName string, reader *bufio.Reader) error {
+	output := palantir.GetGlobalOutputHandler()
+	list, _ := cmd.Flags().GetBool("list")
+	number, _ := cmd.Flags().GetInt("archive")
+	latest, _ := cmd.Flags().GetBool("latest")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	if latest {
+		if number != 0 {
+			return errors.NewValidationError(constants.OpSync, "archive",
+				fmt.Errorf("--latest and --archive can't be used together"))
+		}
+		number = 1
+	}
+
+	prefix, destPath, err := rollbackTarget(appName)
+	if err != nil {
+		return err
+	}
+
+	archives, err := listSyncArchives(prefix, destPath)
+	if err != nil {
+		return errors.NewFileSystemError(constants.OpSync, "list-archives", err)
+	}
+	if len(archives) == 0 {
+		output.PrintInfo(i18n.T("sync.rollback.no_archives_app"), appName, appName)
+		output.PrintInfo(i18n.T("sync.rollback.stored_in"), archiveRoot())
+		return nil
+	}
+
+	output.PrintHeader(i18n.T("sync.rollback.title", appName))
+	output.PrintInfo(i18n.T("sync.destination"), destPath)
+	for i, archive := range archives {
+		fmt.Println(formatArchive(i+1, archive))
+	}
+	fmt.Println()
+	if list {
+		output.PrintInfo(i18n.T("sync.rollback.list_hint"), appName)
+		return nil
+	}
+
+	if number == 0 {
+		var ok bool
+		if number, ok = promptArchive(reader, len(archives)); !ok {
+			output.PrintInfo(i18n.T("sync.rollback.cancelled"))
+			return nil
+		}
+	}
+	if number < 1 || number > len(archives) {
+		return errors.NewValidationError(constants.OpSync, "archive",
+			fmt.Errorf("--archive must be between 1 and %d, see 'anvil config rollback %s --list'", len(archives), appName))
+	}
+	archive := archives[number-1]
+
+	output.PrintStage(i18n.T("sync.rollback.changes", archive.name))
+	if len(archive.changes) == 0 {
+		output.PrintSuccess(i18n.T("sync.rollback.matches_current"))
+		return nil
+	}
+	for _, change := range archive.changes {
+		status := i18n.T("sync.rollback.status_modified")
+		if change.isNew {
+			status = i18n.T("sync.rollback.status_restored")
+		}
+		output.PrintInfo("  %s (%s)", change.relPath, status)
+		showFileDiff(change)
+	}
+
+	if dryRun {
+		output.PrintInfo(i18n.T("sync.rollback.dry_run"), len(archive.changes), appName, archive.name)
+		audit.Record("config rollback", "overwrite-config", destPath, "from "+archive.path)
+		return nil
+	}
+
+	if os.Getenv("ANVIL_TEST_MODE") != "true" {
+		if !charm.Confirm(charm.ConfirmSync, i18n.T("sync.rollback.confirm", len(archive.changes), appName, archive.name)) {
+			output.PrintInfo(i18n.T("sync.rollback.cancelled"))
+			return nil
+		}
+	}
+
+	backupPath, err := restoreArchive(prefix, archive)
+	if err != nil {
+		return errors.NewFileSystemError(constants.OpSync, "rollback", err)
+	}
+	if appName == constants.ANVIL {
+		config.InvalidateConfigCache()
+	}
+	events.Publish(events.Synced, destPath, "")
+	timeline.Record(timeline.Entry{Action: timeline.RolledBack, App: appName, Details: archive.name})
+
+	output.PrintSuccess(i18n.T("sync.rollback.restored", len(archive.changes), appName, archive.name))
+	if backupPath != "" {
+		output.PrintInfo(i18n.T("sync.rollback.archived_to"), backupPath)
+	}
+	if appName == automation.AppName {
+		// Like sync, the restored copy is staged and still has to be loaded
+		return importAutomation(destPath, false)
+	}
+	return nil
+}
+
+// pickArchivedApp lists the apps that have sync archives and asks which one to roll back
+func pickArchivedApp(reader *bufio.Reader) (string, bool) {
+	output := palantir.GetGlobalOutputHandler()
+	apps, err := listArchivedApps()
+	if err != nil {
+		output.PrintError(i18n.T("sync.rollback.failed"), errors.NewFileSystemError(constants.OpSync, "list-archives", err))
+		return "", false
+	}
+	if len(apps) == 0 {
+		output.PrintInfo(i18n.T("sync.rollback.no_archives"))
+		output.PrintInfo(i18n.T("sync.rollback.stored_in"), archiveRoot())
+		return "", false
+	}
+
+	output.PrintHeader(i18n.T("sync.rollback.apps_title"))
+	for i, app := range apps {
+		fmt.Print(i18n.T("sync.rollback.app_line", i+1, app.name, app.count,
+			app.latest.Format("2006-01-02 15:04:05"), formatAge(time.Since(app.latest))))
+	}
+	fmt.Println()
+
+	number, ok := promptChoice(reader, i18n.T("sync.rollback.pick_app", len(apps)), len(apps))
+	if !ok {
+		output.PrintInfo(i18n.T("sync.rollback.cancelled"))
+		return "", false
+	}
+	return apps[number-1].name, true
+}
+
+// listArchivedApps returns the apps with non-empty sync archives, sorted by name
+func listArchivedApps() ([]archivedApp, error) {
+	entries, err := os.ReadDir(archiveRoot())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*archivedApp)
+	for _, entry := range entries {
+		match := archiveSuffix.FindStringSubmatch(entry.Name())
+		if !entry.IsDir() || match == nil {
+			continue
+		}
+		archiveTime, err := time.ParseInLocation(archiveTimeLayout, match[1], time.Local)
+		if err != nil || countFiles(filepath.Join(archiveRoot(), entry.Name())) == 0 {
+			continue
+		}
+
+		prefix := strings.TrimSuffix(entry.Name(), "-"+match[1])
+		if prefix != "anvil-settings" && !strings.HasSuffix(prefix, "-configs") {
+			continue
+		}
+		appName := rollbackName(prefix)
+		app := byName[appName]
+		if app == nil {
+			app = &archivedApp{name: appName}
+			byName[appName] = app
+		}
+		app.count++
+		if archiveTime.After(app.latest) {
+			app.latest = archiveTime
+		}
+	}
+
+	apps := make([]archivedApp, 0, len(byName))
+	for _, app := range byName {
+		apps = append(apps, *app)
+	}
+	sort.Slice(apps, func(i, j int) bool { return apps[i].name < apps[j].name })
+	return apps, nil
+}
+
+// rollbackTarget returns the archive prefix sync uses for an app and the path it restores to
+func rollbackTarget(appName string) (prefix, destPath string, err error) {
+	if appName == constants.ANVIL {
+		return "anvil-settings", config.GetAnvilConfigPath(), nil
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return "", "", errors.NewConfigurationError(constants.OpSync, "load-config", err)
+	}
+	destPath, exists := cfg.Configs[appName]
+	if !exists && appName == automation.AppName {
+		destPath, exists = automation.Dir(), true
+	}
+	if !exists {
+		return "", "", errors.NewConfigurationError(constants.OpSync, appName,
+			fmt.Errorf("app '%s' has no local config path in the 'configs' section of %s", appName, constants.ANVIL_CONFIG_FILE))
+	}
+	return fmt.Sprintf("%s-configs", appName), destPath, nil
+}
+
+// archiveRoot returns the directory sync archives are created in
+func archiveRoot() string {
+	return filepath.Join(config.GetAnvilConfigDirectory(), "archive")
+}
+
+// listSyncArchives returns the non-empty archives with prefix, newest first, with the files
+// each would restore over destPath
+func listSyncArchives(prefix, destPath string) ([]syncArchive, error) {
+	entries, err := os.ReadDir(archiveRoot())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var archives []syncArchive
+	for _, entry := range entries {
+		match := archiveSuffix.FindStringSubmatch(entry.Name())
+		if !entry.IsDir() || match == nil || strings.TrimSuffix(entry.Name(), "-"+match[1]) != prefix {
+			continue
+		}
+		archiveTime, err := time.ParseInLocation(archiveTimeLayout, match[1], time.Local)
+		if err != nil {
+			continue
+		}
+
+		archive := syncArchive{name: entry.Name(), path: filepath.Join(archiveRoot(), entry.Name()), time: archiveTime}
+		source := archiveSource(archive.path, destPath)
+		if archive.files = countFiles(source); archive.files == 0 {
+			// Sync creates the archive before asking, cancelled syncs leave it empty
+			continue
+		}
+		if archive.changes, err = collectChanges(source, destPath, config.ConfigFilter{}); err != nil {
+			return nil, fmt.Errorf("failed to compare %s: %w", entry.Name(), err)
+		}
+		archives = append(archives, archive)
+	}
+
+	sort.Slice(archives, func(i, j int) bool { return archives[i].time.After(archives[j].time) })
+	return archives, nil
+}
+
+// archiveSource returns what an archive holds for destPath: the archive directory for a
+// config directory, or the archived copy of a single config file
+func archiveSource(archivePath, destPath string) string {
+	if info, err := os.Stat(destPath); err == nil && !info.IsDir() {
+		return filepath.Join(archivePath, filepath.Base(destPath))
+	}
+	if filepath.Base(destPath) == constants.ANVIL_CONFIG_FILE {
+		return filepath.Join(archivePath, constants.ANVIL_CONFIG_FILE)
+	}
+	return archivePath
+}
+
+// countFiles returns the number of files under path, 1 for a file and 0 when it is missing
+func countFiles(path string) int {
+	count := 0
+	_ = filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			count++
+		}
+		return nil
+	})
+	return count
+}
+
+// restoreArchive copies the archive's changed files over the current ones. Files that get
+// overwritten are archived first, so a rollback can itself be rolled back.
+func restoreArchive(prefix string, archive syncArchive) (string, error) {
+	var backupPath string
+	options := syncCopyOptions()
+	for _, change := range archive.changes {
+		if !change.isNew {
+			if backupPath == "" {
+				var err error
+				if backupPath, err = createArchiveDirectory(prefix); err != nil {
+					return "", fmt.Errorf("failed to create archive directory: %w", err)
+				}
+			}
+			if err := archiveFile(change.dest, filepath.Join(backupPath, change.relPath)); err != nil {
+				return backupPath, fmt.Errorf("failed to archive %s: %w", change.relPath, err)
+			}
+		}
+		if err := applyFile(change, options); err != nil {
+			return backupPath, fmt.Errorf("failed to restore %s: %w", change.relPath, err)
+		}
+	}
+	return backupPath, nil
+}
+
+// formatArchive renders an archive line with its number, date and change summary
+func formatArchive(number int, archive syncArchive) string {
+	summary := i18n.T("sync.rollback.summary_match")
+	if len(archive.changes) > 0 {
+		summary = i18n.T("sync.rollback.summary_differ", len(archive.changes))
+	}
+	return i18n.T("sync.rollback.archive_line", number, archive.time.Format("2006-01-02 15:04:05"),
+		archive.files, summary, formatAge(time.Since(archive.time)))
+}
+
+// formatAge renders a duration in the largest whole unit
+func formatAge(age time.Duration) string {
+	switch {
+	case age < time.Hour:
+		return fmt.Sprintf("%dm", int(age.Minutes()))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(age.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(age.Hours()/24))
+	}
+}
+
+// promptArchive asks which archive to restore, false means the user quit or input closed
+func promptArchive(reader *bufio.Reader, count int) (int, bool) {
+	return promptChoice(reader, i18n.T("sync.rollback.pick_archive", count), count)
+}
+
+// promptChoice asks for a number between 1 and count until one is given, false means the
+// user quit or input closed
+func promptChoice(reader *bufio.Reader, prompt string, count int) (int, bool) {
+	for {
+		fmt.Print(prompt)
+		answer, err := reader.ReadString('\n')
+		answer = strings.TrimSpace(answer)
+		if answer == "" {
+			if err != nil {
+				fmt.Println()
+			}
+			return 0, false
+		}
+		if number, convErr := strconv.Atoi(answer); convErr == nil && number >= 1 && number <= count {
+			return number, true
+		}
+		if err != nil {
+			fmt.Println()
+			return 0, false
+		}
+		fmt.Print(i18n.T("sync.rollback.invalid_choice", count))
+	}
+}
+
+func init() {
+	RollbackCmd.Flags().Bool("list", false, "List the app's archives, most recent first")
+	RollbackCmd.Flags().Int("archive", 0, "Archive to restore, 1 is the most recent (prompts when not set)")
+	RollbackCmd.Flags().Bool("latest", false, "Restore the most recent archive without prompting for one")
+	RollbackCmd.Flags().Bool("dry-run", false, "Preview the restore without changing any files")
+}
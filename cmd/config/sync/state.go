@@ -0,0 +1,288 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/0xjuanma/anvil/internal/automation"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+)
+
+// InSync reports whether syncing the pulled copy of an app's configs, or of the anvil
+// settings, would leave the local files unchanged. Provisioning uses it to skip syncs
+// that are already satisfied.
+func InSync(appName string) (bool, error) {
+	tempPath := filepath.Join(config.GetAnvilConfigDirectory(), "temp", appName)
+	if appName == constants.ANVIL {
+		return settingsInSync(filepath.Join(tempPath, constants.ANVIL_CONFIG_FILE), config.GetAnvilConfigPath())
+	}
+	if _, err := os.Stat(tempPath); err != nil {
+		return false, fmt.Errorf("config not pulled yet: %w", err)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return false, err
+	}
+	localConfigPath, exists := cfg.Configs[appName]
+	if !exists && appName == automation.AppName {
+		localConfigPath, exists = automation.Dir(), true
+	}
+	if !exists {
+		return false, fmt.Errorf("app '%s' has no config path in %s", appName, constants.ANVIL_CONFIG_FILE)
+	}
+
+	same, err := treesMatch(tempPath, localConfigPath, appSyncFilter(cfg, appName))
+	if err != nil || !same || appName != automation.AppName {
+		return same, err
+	}
+
+	// The staged copy matching is not enough, agents and the crontab must be in place too
+	changes, err := automation.Plan(localConfigPath)
+	return len(changes) == 0, err
+}
+
+// settingsInSync reports whether the pulled settings, with this machine's excluded keys
+// kept from the local file, are identical to the local settings
+func settingsInSync(pulledPath, currentPath string) (bool, error) {
+	pulled, err := os.ReadFile(pulledPath)
+	if err != nil {
+		return false, fmt.Errorf("config not pulled yet: %w", err)
+	}
+	current, err := os.ReadFile(currentPath)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	excludes, err := pulledSettingsExcludes(pulledPath)
+	if err != nil {
+		return false, err
+	}
+	if len(excludes) > 0 {
+		if pulled, err = config.ApplySettingsExcludes(pulled, current, excludes); err != nil {
+			return false, err
+		}
+	}
+	return bytes.Equal(pulled, current), nil
+}
+
+// treesMatch reports whether dest holds exactly the files of source, ignoring excluded
+// paths, which sync neither copies nor removes
+func treesMatch(source, dest string, filter config.ConfigFilter) (bool, error) {
+	if _, err := os.Lstat(dest); os.IsNotExist(err) {
+		return false, nil
+	}
+	preserveSymlinks := syncCopyOptions().PreserveSymlinks
+	want, err := hashTree(source, filter, preserveSymlinks)
+	if err != nil {
+		return false, err
+	}
+	have, err := hashTree(dest, filter, preserveSymlinks)
+	if err != nil {
+		return false, err
+	}
+	return maps.Equal(want, have), nil
+}
+
+// hashTree maps each file below root to the SHA-256 of its content, or of its target for
+// symlinks that sync preserves. A root that is a single file is keyed by ".".
+func hashTree(root string, filter config.ConfigFilter, preserveSymlinks bool) (map[string]string, error) {
+	// A config directory that is itself a symlink is compared by its contents
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+
+	hashes := make(map[string]string)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if filter.Skips(relPath, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		hash := sha256.New()
+		if d.Type()&fs.ModeSymlink != 0 && preserveSymlinks {
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			hash.Write([]byte("symlink:" + target))
+		} else {
+			file, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(hash, file)
+			file.Close()
+			if err != nil {
+				return err
+			}
+		}
+		hashes[filepath.ToSlash(relPath)] = hex.EncodeToString(hash.Sum(nil))
+		return nil
+	})
+	return hashes, err
+}
+
+// SyncPlan summarizes what syncing an app's configs, or the anvil settings, would change
+type SyncPlan struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	Pulled      bool   `json:"pulled"` // False when counted from the local clone, the pull may still change it
+	Files       int    `json:"files"`
+	New         int    `json:"new"`
+	Changed     int    `json:"changed"`
+}
+
+// PlanSync counts the files a pull and sync of appName would add or change without pulling,
+// comparing the last pulled copy, or the local clone when nothing was pulled, with the local files
+func PlanSync(appName string) (SyncPlan, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return SyncPlan{}, err
+	}
+
+	var plan SyncPlan
+	plan.Source, plan.Pulled, err = syncSource(cfg, appName)
+	if err != nil {
+		return plan, err
+	}
+
+	if appName == constants.ANVIL {
+		plan.Source = filepath.Join(plan.Source, constants.ANVIL_CONFIG_FILE)
+		plan.Destination = config.GetAnvilConfigPath()
+		plan.Files = 1
+		if _, err := os.Stat(plan.Destination); os.IsNotExist(err) {
+			plan.New = 1
+			return plan, nil
+		}
+		same, err := settingsInSync(plan.Source, plan.Destination)
+		if err == nil && !same {
+			plan.Changed = 1
+		}
+		return plan, err
+	}
+
+	localConfigPath, exists := cfg.Configs[appName]
+	if !exists && appName == automation.AppName {
+		localConfigPath, exists = automation.Dir(), true
+	}
+	if !exists {
+		return plan, fmt.Errorf("app '%s' has no config path in %s", appName, constants.ANVIL_CONFIG_FILE)
+	}
+	plan.Destination = localConfigPath
+
+	filter := appSyncFilter(cfg, appName)
+	preserveSymlinks := syncCopyOptions().PreserveSymlinks
+	want, err := hashTree(plan.Source, filter, preserveSymlinks)
+	if err != nil {
+		return plan, err
+	}
+	have := map[string]string{}
+	if _, err := os.Lstat(localConfigPath); err == nil {
+		if have, err = hashTree(localConfigPath, filter, preserveSymlinks); err != nil {
+			return plan, err
+		}
+	}
+
+	plan.Files = len(want)
+	for file, hash := range want {
+		switch current, ok := have[file]; {
+		case !ok:
+			plan.New++
+		case current != hash:
+			plan.Changed++
+		}
+	}
+	return plan, nil
+}
+
+// syncSource returns the last pulled copy of appName, or its directory in the local clone when
+// nothing was pulled, and whether it was pulled
+func syncSource(cfg *config.AnvilConfig, appName string) (string, bool, error) {
+	source := filepath.Join(config.GetAnvilConfigDirectory(), "temp", appName)
+	if _, err := os.Stat(source); err == nil {
+		return source, true, nil
+	}
+	source = filepath.Join(cfg.GitHub.LocalPath, appName)
+	if _, err := os.Stat(source); err != nil {
+		return source, false, fmt.Errorf("'%s' is neither pulled nor in the local clone, its files are known after a pull", appName)
+	}
+	return source, false, nil
+}
+
+// SourceHash hashes the files a pull and sync of appName would apply, read from the same
+// source as PlanSync, together with the sync excludes of this machine
+func SourceHash(appName string) (string, []string, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return "", nil, err
+	}
+	source, _, err := syncSource(cfg, appName)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var excludes []string
+	var filter config.ConfigFilter
+	if appName == constants.ANVIL {
+		source = filepath.Join(source, constants.ANVIL_CONFIG_FILE)
+	} else {
+		excludes = config.SyncExcludesFor(cfg.Sync.Rules, appName, config.CurrentMachineID())
+		filter = appSyncFilter(cfg, appName)
+	}
+	hashes, err := hashTree(source, filter, syncCopyOptions().PreserveSymlinks)
+	if err != nil {
+		return "", nil, err
+	}
+
+	files := make([]string, 0, len(hashes))
+	for file := range hashes {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	hash := sha256.New()
+	for _, file := range files {
+		fmt.Fprintf(hash, "%s %s\n", hashes[file], file)
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16], excludes, nil
+}
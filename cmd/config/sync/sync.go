@@ -17,18 +17,32 @@ limitations under the License.
 package sync
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/0xjuanma/anvil/cmd/config/components"
+	"github.com/0xjuanma/anvil/cmd/hosts"
+	"github.com/0xjuanma/anvil/internal/audit"
+	"github.com/0xjuanma/anvil/internal/automation"
 	"github.com/0xjuanma/anvil/internal/config"
 	"github.com/0xjuanma/anvil/internal/constants"
 	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/events"
+	hostsblock "github.com/0xjuanma/anvil/internal/hosts"
+	"github.com/0xjuanma/anvil/internal/i18n"
+	"github.com/0xjuanma/anvil/internal/pkgmanager"
 	"github.com/0xjuanma/anvil/internal/terminal/charm"
+	"github.com/0xjuanma/anvil/internal/timeline"
 	"github.com/0xjuanma/anvil/internal/utils"
 	"github.com/0xjuanma/palantir"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
 )
 
 var SyncCmd = &cobra.Command{
@@ -38,7 +52,7 @@ var SyncCmd = &cobra.Command{
 	Args:  cobra.MaximumNArgs(1), // Accept 0 or 1 argument
 	Run: func(cmd *cobra.Command, args []string) {
 		if err := runSyncCommand(cmd, args); err != nil {
-			palantir.GetGlobalOutputHandler().PrintError("Sync failed: %v", err)
+			palantir.GetGlobalOutputHandler().PrintError(i18n.T("sync.failed"), err)
 			return
 		}
 	},
@@ -46,115 +60,376 @@ var SyncCmd = &cobra.Command{
 
 // runSyncCommand executes the configuration sync process
 func runSyncCommand(cmd *cobra.Command, args []string) error {
-	// Check for dry-run flag
+	// Check for dry-run and interactive flags
 	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	interactive, _ := cmd.Flags().GetBool("interactive")
+	skipComponents, _ := cmd.Flags().GetBool("skip-components")
+	ignoreAppVersion, _ := cmd.Flags().GetBool("ignore-app-version")
+	remote, _ := cmd.Flags().GetString("remote")
+	if err := config.UseRemote(remote); err != nil {
+		return errors.NewConfigurationError(constants.OpSync, "remote", err)
+	}
 
 	// If no arguments provided, sync the anvil settings
 	if len(args) == 0 {
-		return syncAnvilSettings(dryRun)
+		if err := syncAnvilSettings(dryRun, interactive); err != nil {
+			return err
+		}
+		installNewGroupTools(dryRun, filepath.Join(config.GetAnvilConfigDirectory(), "temp", constants.ANVIL, constants.ANVIL_CONFIG_FILE))
+		return nil
 	}
 
 	// Sync specific app config
 	appName := args[0]
-	return syncAppConfig(appName, dryRun)
+	return syncAppConfig(appName, dryRun, interactive, skipComponents, ignoreAppVersion)
+}
+
+// SyncConfig syncs pulled configs for an app, or anvil settings for "anvil", used by provisioning
+func SyncConfig(appName string, dryRun bool) error {
+	if appName == constants.ANVIL {
+		return syncAnvilSettings(dryRun, false)
+	}
+	return syncAppConfig(appName, dryRun, false, false, false)
 }
 
 // syncAnvilSettings syncs the main anvil settings.yaml file
-func syncAnvilSettings(dryRun bool) error {
+func syncAnvilSettings(dryRun, interactive bool) error {
 	o := palantir.GetGlobalOutputHandler()
-	o.PrintHeader("Configuration Sync: Anvil settings")
+	o.PrintHeader(i18n.T("sync.title_anvil"))
 
 	tempSettingsPath := filepath.Join(config.GetAnvilConfigDirectory(), "temp", constants.ANVIL, constants.ANVIL_CONFIG_FILE)
 	if _, err := os.Stat(tempSettingsPath); os.IsNotExist(err) {
-		o.PrintError("Pulled anvil settings not found\n")
-		o.PrintInfo("💡 No pulled settings found at: %s", tempSettingsPath)
-		o.PrintInfo("🔧 To fix this:")
-		o.PrintInfo("   • Run 'anvil config pull anvil' to download settings")
-		o.PrintInfo("   • Ensure your repository has an 'anvil' directory with settings.yaml")
+		o.PrintError(i18n.T("sync.anvil_not_pulled"))
+		o.PrintInfo(i18n.T("sync.anvil_not_pulled.path"), tempSettingsPath)
+		o.PrintInfo(i18n.T("sync.to_fix"))
+		o.PrintInfo(i18n.T("sync.anvil_not_pulled.pull"))
+		o.PrintInfo(i18n.T("sync.anvil_not_pulled.directory"))
 		return fmt.Errorf("config not pulled yet")
 	}
 
+	if err := checkPulledFromRemote(constants.ANVIL); err != nil {
+		return err
+	}
+
 	currentSettingsPath := config.GetAnvilConfigPath()
 
-	o.PrintInfo("Source: %s", tempSettingsPath)
-	o.PrintInfo("Destination: %s\n", currentSettingsPath)
+	o.PrintInfo(i18n.T("sync.source"), tempSettingsPath)
+	o.PrintInfo(i18n.T("sync.destination"), currentSettingsPath)
+	warnStalePull(constants.ANVIL)
+
+	// Rules come from the pulled settings so one synced file drives every machine
+	excludes, err := pulledSettingsExcludes(tempSettingsPath)
+	if err != nil {
+		return errors.NewConfigurationError(constants.OpSync, "sync-rules", err)
+	}
+	printExcludes(excludes)
 
 	if dryRun {
-		o.PrintInfo("Dry run - would sync anvil settings")
+		o.PrintInfo(i18n.T("sync.dry_run_anvil"))
+		audit.Record("sync", "overwrite-file", currentSettingsPath, fmt.Sprintf("from %s, archiving the old copy", tempSettingsPath))
+		return nil
+	}
+
+	sourcePath := tempSettingsPath
+	if len(excludes) > 0 {
+		filteredPath, err := writeFilteredSettings(tempSettingsPath, currentSettingsPath, excludes)
+		if err != nil {
+			return errors.NewConfigurationError(constants.OpSync, "apply-sync-rules", err)
+		}
+		defer os.Remove(filteredPath)
+		sourcePath = filteredPath
+	}
+
+	// Settings are replaced on disk, later reads in this run must not use the cached copy
+	defer config.InvalidateConfigCache()
+
+	if interactive {
+		err = performInteractiveSync("anvil-settings", sourcePath, currentSettingsPath, config.ConfigFilter{})
+	} else {
+		err = performSync(
+			"anvil-settings",
+			sourcePath,
+			currentSettingsPath,
+			config.ConfigFilter{},
+			i18n.T("sync.confirm_anvil", constants.ANVIL_CONFIG_FILE),
+			i18n.T("sync.syncing_anvil"),
+			i18n.T("sync.synced_anvil"),
+			i18n.T("sync.done"),
+		)
+	}
+	if err != nil {
+		return err
+	}
+	if err := applySyncedHosts(); err != nil {
+		return err
+	}
+	_, err = ApplyGitSettings(false)
+	return err
+}
+
+// warnStalePull warns when the pulled copy about to be synced is older than temp.max_age_days,
+// it may no longer match the repository
+func warnStalePull(appName string) {
+	entry, found, err := config.GetTempEntry(appName)
+	if err != nil || !found {
+		return
+	}
+	now := time.Now()
+	if !entry.IsStale(config.GetTempMaxAge(), now) {
+		return
+	}
+
+	output := palantir.GetGlobalOutputHandler()
+	output.PrintWarning(i18n.T("sync.stale"), appName, config.FormatAge(entry.Age(now)), entry.ShortCommit())
+	output.PrintInfo(i18n.T("sync.stale_hint"), appName)
+}
+
+// checkPulledFromRemote refuses to sync a pulled copy that came from another repository than the
+// active one, the github section or the remote selected with --remote. The pulled directory is
+// shared by every remote, so a pull with --remote followed by a plain sync must not mix them.
+func checkPulledFromRemote(appName string) error {
+	entry, found, err := config.GetTempEntry(appName)
+	if err != nil || !found || !entry.Recorded || entry.Repo == "" {
+		return nil
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return errors.NewConfigurationError(constants.OpSync, "load-config", err)
+	}
+	if entry.Repo == cfg.GitHub.ConfigRepo {
 		return nil
 	}
 
-	return performSync(
-		"anvil-settings",
-		tempSettingsPath,
-		currentSettingsPath,
-		fmt.Sprintf("Sync local %s? Old copy will be archived.", constants.ANVIL_CONFIG_FILE),
-		"Syncing anvil settings",
-		"[Anvil] settings synced successfully",
-		"Sync done!",
-	)
+	output := palantir.GetGlobalOutputHandler()
+	pullCommand := fmt.Sprintf("anvil config pull %s", appName)
+	if remote := config.ActiveRemote(); remote != "" {
+		output.PrintError(i18n.T("sync.other_remote"), appName, entry.Repo, remote, cfg.GitHub.ConfigRepo)
+		pullCommand += " --remote " + remote
+	} else {
+		output.PrintError(i18n.T("sync.other_repo"), appName, entry.Repo, cfg.GitHub.ConfigRepo)
+	}
+	output.PrintInfo(i18n.T("sync.other_repo.pull"), pullCommand)
+	for _, name := range cfg.GitHub.RemoteNames() {
+		if cfg.GitHub.Remotes[name].ConfigRepo == entry.Repo {
+			output.PrintInfo(i18n.T("sync.other_repo.remote"), appName, name)
+		}
+	}
+	return fmt.Errorf("pulled config does not match %s", cfg.GitHub.ConfigRepo)
 }
 
-// syncAppConfig syncs configuration files for a specific app
-func syncAppConfig(appName string, dryRun bool) error {
+// checkAppVersion compares the installed app with the oldest version its pulled configs work
+// with, as recorded by the machine that pushed them. A mismatch is a warning unless the
+// requirement blocks it and ignore is false. Unknown installed versions are only reported.
+func checkAppVersion(appName string, ignore bool) error {
+	entry, found, err := config.GetTempEntry(appName)
+	if err != nil || !found || entry.AppVersion.MinAppVersion == "" {
+		return nil
+	}
+	requirement := entry.AppVersion
+
 	output := palantir.GetGlobalOutputHandler()
-	output.PrintHeader(fmt.Sprintf("Configuration Sync: %s", appName))
+	installed := pkgmanager.InstalledVersion(appName)
+	if installed == "" {
+		output.PrintWarning(i18n.T("sync.app_version.unknown"), appName, requirement.MinAppVersion)
+		return nil
+	}
+	ok, err := config.SatisfiesAppVersion(installed, requirement.MinAppVersion)
+	if err != nil {
+		output.PrintWarning(i18n.T("sync.app_version.compare_failed"), appName, installed, requirement.MinAppVersion, err)
+		return nil
+	}
+	if ok {
+		return nil
+	}
+
+	if !requirement.Blocks() || ignore {
+		output.PrintWarning(i18n.T("sync.app_version.warn"), appName, requirement.MinAppVersion, installed)
+		return nil
+	}
+	output.PrintError(i18n.T("sync.app_version.blocked"), appName, requirement.MinAppVersion, installed)
+	output.PrintInfo(i18n.T("sync.app_version.upgrade"), appName, appName)
+	output.PrintInfo(i18n.T("sync.app_version.ignore"), appName)
+	return errors.NewValidationError(constants.OpSync, "app-version",
+		fmt.Errorf("%s %s is older than %s", appName, installed, requirement.MinAppVersion))
+}
+
+// applySyncedHosts writes the hosts entries of the synced settings into /etc/hosts
+func applySyncedHosts() error {
+	config.InvalidateConfigCache()
+	entries, err := config.GetHostsConfig()
+	if err != nil {
+		return nil
+	}
+	if inSync, err := hostsblock.InSync(entries); err == nil && inSync {
+		return nil
+	}
+	_, err = hosts.ApplyHosts(false)
+	return err
+}
+
+// syncAppConfig syncs configuration files for a specific app, then installs the components its manifests list
+func syncAppConfig(appName string, dryRun, interactive, skipComponents, ignoreAppVersion bool) error {
+	output := palantir.GetGlobalOutputHandler()
+	output.PrintHeader(i18n.T("sync.title_app", appName))
 
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		return errors.NewConfigurationError(constants.OpSync, "load-config", err)
 	}
+	if err := cfg.CheckAppMode(appName, constants.OpSync); err != nil {
+		return errors.NewValidationError(constants.OpSync, appName, err)
+	}
 
 	tempAppPath := filepath.Join(config.GetAnvilConfigDirectory(), "temp", appName)
 	if _, err := os.Stat(tempAppPath); os.IsNotExist(err) {
-		output.PrintError("Pulled %s configuration not found\n", appName)
-		output.PrintInfo("💡 No pulled config found at: %s", tempAppPath)
-		output.PrintInfo("🔧 To fix this:")
-		output.PrintInfo("   • Run 'anvil config pull %s' to download configuration", appName)
-		output.PrintInfo("   • Ensure your repository has a '%s' directory", appName)
+		output.PrintError(i18n.T("sync.app_not_pulled"), appName)
+		output.PrintInfo(i18n.T("sync.app_not_pulled.path"), tempAppPath)
+		output.PrintInfo(i18n.T("sync.to_fix"))
+		output.PrintInfo(i18n.T("sync.app_not_pulled.pull"), appName)
+		output.PrintInfo(i18n.T("sync.app_not_pulled.directory"), appName)
 		return fmt.Errorf("config not pulled yet")
 	}
 
-	if cfg.Configs == nil {
-		return fmt.Errorf("no configs section found in %s", constants.ANVIL_CONFIG_FILE)
+	if err := checkPulledFromRemote(appName); err != nil {
+		return err
+	}
+	if err := checkAppVersion(appName, ignoreAppVersion); err != nil {
+		return err
 	}
 
 	localConfigPath, exists := cfg.Configs[appName]
+	if !exists && appName == automation.AppName {
+		// Built in, staged under ~/.anvil and imported into place after the copy
+		localConfigPath, exists = automation.Dir(), true
+	}
+	if cfg.Configs == nil && !exists {
+		return fmt.Errorf("no configs section found in %s", constants.ANVIL_CONFIG_FILE)
+	}
 	if !exists {
-		output.PrintError("App config path not configured\n")
-		output.PrintInfo("💡 The app '%s' doesn't have a local config path defined", appName)
-		output.PrintInfo("🔧 To fix this:")
-		output.PrintInfo("   • Edit your %s file", constants.ANVIL_CONFIG_FILE)
-		output.PrintInfo("   • Add the following to the 'configs' section:\n")
+		output.PrintError(i18n.T("sync.no_path"))
+		output.PrintInfo(i18n.T("sync.no_path.detail"), appName)
+		output.PrintInfo(i18n.T("sync.to_fix"))
+		output.PrintInfo(i18n.T("sync.no_path.edit"), constants.ANVIL_CONFIG_FILE)
+		output.PrintInfo(i18n.T("sync.no_path.add"))
 		output.PrintInfo("configs:")
 		output.PrintInfo("  %s: \"/path/to/%s/config\"\n", appName, appName)
-		output.PrintInfo("Example paths:")
+		output.PrintInfo(i18n.T("sync.no_path.examples"))
 		output.PrintInfo("  • ~/.config/%s", appName)
 		output.PrintInfo("  • ~/Library/Application Support/%s", strings.Title(appName))
 		return fmt.Errorf("app config path not defined")
 	}
 
-	output.PrintInfo("Source: %s", tempAppPath)
-	output.PrintInfo("Destination: %s\n", localConfigPath)
+	output.PrintInfo(i18n.T("sync.source"), tempAppPath)
+	output.PrintInfo(i18n.T("sync.destination"), localConfigPath)
+	warnStalePull(appName)
+
+	printExcludes(config.SyncExcludesFor(cfg.Sync.Rules, appName, config.CurrentMachineID()))
+	filter := appSyncFilter(cfg, appName)
+
+	if dryRun {
+		output.PrintInfo(i18n.T("sync.dry_run_app"), appName)
+		audit.Record("sync", "overwrite-config", localConfigPath, fmt.Sprintf("from %s, archiving the old copy", tempAppPath))
+		if appName == automation.AppName {
+			return importAutomation(tempAppPath, true)
+		}
+		return nil
+	}
+
+	// Sync from a private copy, a pull finishing mid-sync replaces the pulled directory
+	sourcePath, cleanup, err := snapshotPulledConfig(tempAppPath)
+	if err != nil {
+		return errors.NewFileSystemError(constants.OpSync, appName, err)
+	}
+	defer cleanup()
 
+	if interactive {
+		err = performInteractiveSync(fmt.Sprintf("%s-configs", appName), sourcePath, localConfigPath, filter)
+	} else {
+		err = performSync(
+			fmt.Sprintf("%s-configs", appName),
+			sourcePath,
+			localConfigPath,
+			filter,
+			i18n.T("sync.confirm_app", appName),
+			i18n.T("sync.syncing_app", appName),
+			i18n.T("sync.synced_app", strings.Title(appName)),
+			i18n.T("sync.done"),
+		)
+	}
+	if err != nil {
+		return err
+	}
+	if appName == automation.AppName {
+		return importAutomation(localConfigPath, false)
+	}
+	if skipComponents {
+		return nil
+	}
+
+	// Plugins and extensions listed by the synced config are not files, install the missing ones
+	if err := components.Reconcile(appName, localConfigPath, true, false); err != nil {
+		output.PrintWarning(i18n.T("sync.components_failed"), appName, err)
+	}
+	return nil
+}
+
+// snapshotPulledConfig copies a pulled directory into a staging directory of this sync
+func snapshotPulledConfig(tempAppPath string) (string, func(), error) {
+	stagingDir, err := utils.NewStagingDir(filepath.Dir(tempAppPath), filepath.Base(tempAppPath)+"-sync")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(stagingDir) }
+
+	snapshot := filepath.Join(stagingDir, filepath.Base(tempAppPath))
+	if err := utils.CopyDirectorySimple(tempAppPath, snapshot); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to read the pulled config, it may have been replaced by a pull, try again: %w", err)
+	}
+	return snapshot, cleanup, nil
+}
+
+// importAutomation loads synced LaunchAgents through launchctl and installs the synced crontab
+func importAutomation(dir string, dryRun bool) error {
+	output := palantir.GetGlobalOutputHandler()
+
+	changes, err := automation.Plan(dir)
+	if err != nil {
+		return errors.NewConfigurationError(constants.OpSync, automation.AppName, err)
+	}
+	if len(changes) == 0 {
+		output.PrintSuccess(i18n.T("sync.automation.match"))
+		return nil
+	}
+
+	for _, change := range changes {
+		action := "install"
+		if change.Replace {
+			action = "replace"
+		}
+		output.PrintInfo("  • %s %s %s", action, change.Kind, change.Name)
+		audit.Record("sync", action+"-"+change.Kind, change.Name, fmt.Sprintf("from %s", change.Source))
+	}
 	if dryRun {
-		output.PrintInfo("Dry run - would sync %s configuration", appName)
 		return nil
 	}
 
-	return performSync(
-		fmt.Sprintf("%s-configs", appName),
-		tempAppPath,
-		localConfigPath,
-		fmt.Sprintf("Sync %s configs? Old copy will be archived.", appName),
-		fmt.Sprintf("Syncing %s configuration", appName),
-		fmt.Sprintf("[%s] configuration synced successfully", strings.Title(appName)),
-		"Sync done!",
-	)
+	if os.Getenv("ANVIL_TEST_MODE") != "true" {
+		if !charm.Confirm(charm.ConfirmSync, i18n.T("sync.automation.confirm", len(changes))) {
+			output.PrintInfo(i18n.T("sync.automation.unchanged"))
+			return nil
+		}
+	}
+
+	if err := automation.Apply(changes); err != nil {
+		return errors.NewConfigurationError(constants.OpSync, automation.AppName, err)
+	}
+	output.PrintSuccess(i18n.T("sync.automation.applied", len(changes)))
+	return nil
 }
 
 // performSync executes the core sync operation for any config type
-func performSync(archivePrefix, sourcePath, destPath, confirmMsg, spinnerMsg, spinnerSuccess, successMsg string) error {
+func performSync(archivePrefix, sourcePath, destPath string, filter config.ConfigFilter, confirmMsg, spinnerMsg, spinnerSuccess, successMsg string) error {
 	output := palantir.GetGlobalOutputHandler()
 
 	archivePath, err := createArchiveDirectory(archivePrefix)
@@ -162,11 +437,11 @@ func performSync(archivePrefix, sourcePath, destPath, confirmMsg, spinnerMsg, sp
 		return fmt.Errorf("failed to create archive directory: %w", err)
 	}
 
-	output.PrintInfo("Archive: %s\n", archivePath)
+	output.PrintInfo(i18n.T("sync.archive"), archivePath)
 
 	if os.Getenv("ANVIL_TEST_MODE") != "true" {
-		if !output.Confirm(confirmMsg) {
-			output.PrintInfo("Sync cancelled")
+		if !charm.Confirm(charm.ConfirmSync, confirmMsg) {
+			output.PrintInfo(i18n.T("sync.cancelled"))
 			return nil
 		}
 	}
@@ -177,36 +452,136 @@ func performSync(archivePrefix, sourcePath, destPath, confirmMsg, spinnerMsg, sp
 	spinner.Start()
 
 	if err := archiveExistingConfig(archivePrefix, destPath, archivePath); err != nil {
-		spinner.Error("Failed to archive existing config")
+		spinner.Error(i18n.T("sync.archive_failed"))
 		return fmt.Errorf("failed to archive existing config: %w", err)
 	}
 
 	sourceInfo, err := os.Stat(sourcePath)
 	if err != nil {
-		spinner.Error("Failed to read source")
+		spinner.Error(i18n.T("sync.read_failed"))
 		return fmt.Errorf("failed to read source: %w", err)
 	}
 
+	// Ctrl-C stops the copy between chunks, no file is left half-written and the archive holds the old configs
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	if sourceInfo.IsDir() {
-		err = utils.CopyDirectorySimple(sourcePath, destPath)
+		options := syncCopyOptions()
+		options.Include, options.Exclude = filter.Include, filter.Exclude
+		err = utils.CopyDirectoryContext(ctx, sourcePath, destPath, options)
 	} else {
-		err = utils.CopyFileSimple(sourcePath, destPath)
+		err = utils.CopyFileContext(ctx, sourcePath, destPath, utils.ConfigCopyOptions())
 	}
 
+	if ctx.Err() != nil {
+		spinner.Error(i18n.T("sync.interrupted"))
+		output.PrintInfo(i18n.T("sync.interrupted_detail"), archivePath)
+		return fmt.Errorf("sync interrupted")
+	}
 	if err != nil {
-		spinner.Error("Failed to copy new config")
+		spinner.Error(i18n.T("sync.copy_failed"))
 		return fmt.Errorf("failed to copy new config: %w", err)
 	}
 
 	spinner.Success(spinnerSuccess)
+	events.Publish(events.Synced, destPath, "")
+	recordSync(rollbackName(archivePrefix))
 
 	output.PrintSuccess(successMsg)
-	output.PrintInfo("Old configs archived to: %s", archivePath)
-	output.PrintInfo("Manual recovery possible from archive directory (no auto-recover yet)")
+	output.PrintInfo(i18n.T("sync.archived_to"), archivePath)
+	output.PrintInfo(i18n.T("sync.undo_hint"), rollbackName(archivePrefix))
 
 	return nil
 }
 
+// recordSync adds a sync to the timeline with the repository commit the pulled copy came from
+func recordSync(appName string) {
+	entry := timeline.Entry{Action: timeline.Synced, App: appName}
+	if pulled, found, err := config.GetTempEntry(appName); err == nil && found {
+		entry.Repo, entry.Branch, entry.Commit = pulled.Repo, pulled.Branch, pulled.Commit
+	}
+	timeline.Record(entry)
+}
+
+// rollbackName returns the app name 'config rollback' takes for an archive prefix
+func rollbackName(archivePrefix string) string {
+	if archivePrefix == "anvil-settings" {
+		return constants.ANVIL
+	}
+	return strings.TrimSuffix(archivePrefix, "-configs")
+}
+
+// pulledSettingsExcludes returns the settings keys excluded on this machine by the pulled sync rules
+func pulledSettingsExcludes(pulledSettingsPath string) ([]string, error) {
+	data, err := os.ReadFile(pulledSettingsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var pulled config.AnvilConfig
+	if err := yaml.Unmarshal(data, &pulled); err != nil {
+		return nil, fmt.Errorf("failed to parse pulled settings: %w", err)
+	}
+
+	return config.SyncExcludesFor(pulled.Sync.Rules, constants.ANVIL, config.CurrentMachineID()), nil
+}
+
+// writeFilteredSettings writes the pulled settings with excluded keys kept from the local file
+func writeFilteredSettings(pulledPath, currentPath string, excludes []string) (string, error) {
+	pulled, err := os.ReadFile(pulledPath)
+	if err != nil {
+		return "", err
+	}
+
+	// Without local settings there is nothing to keep, excluded keys are dropped
+	current, err := os.ReadFile(currentPath)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	filtered, err := config.ApplySettingsExcludes(pulled, current, excludes)
+	if err != nil {
+		return "", err
+	}
+
+	filteredPath := filepath.Join(filepath.Dir(pulledPath), "."+constants.ANVIL_CONFIG_FILE+".filtered")
+	if err := os.WriteFile(filteredPath, filtered, 0644); err != nil {
+		return "", err
+	}
+	return filteredPath, nil
+}
+
+// appSyncFilter returns the files of an app that sync leaves alone on this machine: those left
+// out by its configs entry and those excluded by matching sync rules
+func appSyncFilter(cfg *config.AnvilConfig, appName string) config.ConfigFilter {
+	filter := cfg.ConfigFilters[appName]
+	filter.Exclude = append(append([]string{}, filter.Exclude...), config.SyncExcludesFor(cfg.Sync.Rules, appName, config.CurrentMachineID())...)
+	return filter
+}
+
+// printExcludes shows which entries keep their local values on this machine
+func printExcludes(excludes []string) {
+	if len(excludes) == 0 {
+		return
+	}
+	palantir.GetGlobalOutputHandler().PrintInfo(i18n.T("sync.keeping_local"),
+		config.CurrentMachineID(), strings.Join(excludes, ", "))
+}
+
+// syncCopyOptions returns the copy options for syncing, honoring the symlink materialization setting
+func syncCopyOptions() utils.CopyOptions {
+	options := utils.ConfigCopyOptions()
+	if cfg, err := config.LoadConfig(); err == nil {
+		options.PreserveSymlinks = !cfg.GitHub.MaterializeSymlinks
+	}
+	return options
+}
+
 func init() {
 	SyncCmd.Flags().Bool("dry-run", false, "Show what would be synced without making changes")
+	SyncCmd.Flags().BoolP("interactive", "i", false, "Review the diff of each changed file and choose to apply, skip or abort")
+	SyncCmd.Flags().Bool("skip-components", false, "Do not install plugins or extensions listed by the synced config")
+	SyncCmd.Flags().Bool("ignore-app-version", false, "Sync even when the installed app is older than the pulled configs require")
+	SyncCmd.Flags().String("remote", "", "Sync configs pulled from a named repository from github.remotes")
 }
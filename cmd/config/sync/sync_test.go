@@ -17,9 +17,14 @@ limitations under the License.
 package sync
 
 import (
+	"bufio"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
 )
 
 func setupTestEnv(t *testing.T) (anvilDir, archiveDir string, cleanup func()) {
@@ -65,6 +70,7 @@ func TestPerformSync_SingleFile(t *testing.T) {
 		"test-sync",
 		sourceFile,
 		destFile,
+		config.ConfigFilter{},
 		"Confirm sync?",
 		"Syncing...",
 		"Synced",
@@ -115,6 +121,7 @@ func TestPerformSync_Directory_PreservesLocalFiles(t *testing.T) {
 		"test-dir-sync",
 		sourceDir,
 		destDir,
+		config.ConfigFilter{},
 		"Confirm sync?",
 		"Syncing...",
 		"Synced",
@@ -172,6 +179,7 @@ func TestPerformSync_CreatesArchive(t *testing.T) {
 		"archive-test",
 		sourceDir,
 		destDir,
+		config.ConfigFilter{},
 		"Confirm?",
 		"Syncing...",
 		"Done",
@@ -218,6 +226,7 @@ func TestPerformSync_NestedDirectories(t *testing.T) {
 		"nested-test",
 		sourceDir,
 		destDir,
+		config.ConfigFilter{},
 		"Confirm?",
 		"Syncing...",
 		"Done",
@@ -247,6 +256,7 @@ func TestPerformSync_SourceNotExists(t *testing.T) {
 		"error-test",
 		sourceFile,
 		destFile,
+		config.ConfigFilter{},
 		"Confirm?",
 		"Syncing...",
 		"Done",
@@ -282,6 +292,7 @@ func TestPerformSync_WithExistingDestination(t *testing.T) {
 		"overwrite-test",
 		sourceDir,
 		destDir,
+		config.ConfigFilter{},
 		"Confirm?",
 		"Syncing...",
 		"Done",
@@ -380,3 +391,303 @@ func TestCreateArchiveDirectory(t *testing.T) {
 		t.Error("Archive path is not absolute")
 	}
 }
+
+func TestCollectChanges(t *testing.T) {
+	tempDir := t.TempDir()
+	source := filepath.Join(tempDir, "source")
+	dest := filepath.Join(tempDir, "dest")
+
+	files := map[string]string{
+		filepath.Join(source, "same.txt"):          "same",
+		filepath.Join(source, "changed.txt"):       "new",
+		filepath.Join(source, "added.txt"):         "added",
+		filepath.Join(source, "cache", "skip.txt"): "excluded",
+		filepath.Join(dest, "same.txt"):            "same",
+		filepath.Join(dest, "changed.txt"):         "old",
+		filepath.Join(dest, "local-only.txt"):      "local",
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	changes, err := collectChanges(source, dest, config.ConfigFilter{Exclude: []string{"cache"}})
+	if err != nil {
+		t.Fatalf("collectChanges failed: %v", err)
+	}
+
+	got := make(map[string]bool)
+	for _, change := range changes {
+		got[change.relPath] = change.isNew
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 changes, got %v", got)
+	}
+	if isNew, ok := got["changed.txt"]; !ok || isNew {
+		t.Errorf("expected changed.txt to be a modified file, got %v", got)
+	}
+	if isNew, ok := got["added.txt"]; !ok || !isNew {
+		t.Errorf("expected added.txt to be a new file, got %v", got)
+	}
+}
+
+func TestPerformInteractiveSync(t *testing.T) {
+	anvilDir, _, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	// Archives are created under the home directory, keep them in the test's temp dir
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	archiveDir := filepath.Join(home, ".anvil", "archive")
+
+	source := filepath.Join(anvilDir, "source")
+	dest := filepath.Join(anvilDir, "dest")
+	for _, dir := range []string{source, dest} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.WriteFile(filepath.Join(source, "a.txt"), []byte("new a"), 0644)
+	os.WriteFile(filepath.Join(source, "b.txt"), []byte("new b"), 0644)
+	os.WriteFile(filepath.Join(dest, "a.txt"), []byte("old a"), 0644)
+	os.WriteFile(filepath.Join(dest, "b.txt"), []byte("old b"), 0644)
+
+	// Apply a.txt, skip b.txt
+	originalInput := promptInput
+	promptInput = strings.NewReader("a\ns\n")
+	defer func() { promptInput = originalInput }()
+
+	if err := performInteractiveSync("test-interactive", source, dest, config.ConfigFilter{}); err != nil {
+		t.Fatalf("performInteractiveSync failed: %v", err)
+	}
+
+	if content, _ := os.ReadFile(filepath.Join(dest, "a.txt")); string(content) != "new a" {
+		t.Errorf("a.txt = %q, want applied content", content)
+	}
+	if content, _ := os.ReadFile(filepath.Join(dest, "b.txt")); string(content) != "old b" {
+		t.Errorf("b.txt = %q, want skipped file left untouched", content)
+	}
+
+	// Only the overwritten file is archived
+	archives, _ := os.ReadDir(archiveDir)
+	if len(archives) != 1 {
+		t.Fatalf("expected 1 archive, got %d", len(archives))
+	}
+	archived := filepath.Join(archiveDir, archives[0].Name())
+	if content, _ := os.ReadFile(filepath.Join(archived, "a.txt")); string(content) != "old a" {
+		t.Errorf("archived a.txt = %q, want old content", content)
+	}
+	if _, err := os.Stat(filepath.Join(archived, "b.txt")); !os.IsNotExist(err) {
+		t.Error("skipped file should not be archived")
+	}
+}
+
+func TestTreesMatch(t *testing.T) {
+	source := t.TempDir()
+	dest := t.TempDir()
+	for _, dir := range []string{source, dest} {
+		os.MkdirAll(filepath.Join(dir, "nested"), 0755)
+		os.WriteFile(filepath.Join(dir, "init.lua"), []byte("set number"), 0644)
+		os.WriteFile(filepath.Join(dir, "nested", "keys.lua"), []byte("map"), 0644)
+	}
+
+	match := func(excludes ...string) bool {
+		t.Helper()
+		same, err := treesMatch(source, dest, config.ConfigFilter{Exclude: excludes})
+		if err != nil {
+			t.Fatalf("treesMatch failed: %v", err)
+		}
+		return same
+	}
+
+	if !match() {
+		t.Error("identical trees should match")
+	}
+
+	// Excluded files are neither copied nor removed by sync, so they never count
+	os.WriteFile(filepath.Join(dest, "secrets.local"), []byte("token"), 0644)
+	if match() {
+		t.Error("an extra local file would be removed by sync, trees should differ")
+	}
+	if !match("*.local") {
+		t.Error("an excluded extra file should be ignored")
+	}
+
+	os.WriteFile(filepath.Join(dest, "nested", "keys.lua"), []byte("changed"), 0644)
+	if match("*.local") {
+		t.Error("changed content should make the trees differ")
+	}
+
+	// Files outside the include patterns of the configs entry don't count either
+	if same, _ := treesMatch(source, dest, config.ConfigFilter{Include: []string{"init.lua"}}); !same {
+		t.Error("files outside the include patterns should be ignored")
+	}
+
+	if same, _ := treesMatch(source, filepath.Join(dest, "missing"), config.ConfigFilter{}); same {
+		t.Error("a missing destination should not match")
+	}
+}
+
+func TestSettingsInSync(t *testing.T) {
+	dir := t.TempDir()
+	pulled := filepath.Join(dir, "pulled.yaml")
+	current := filepath.Join(dir, "current.yaml")
+
+	os.WriteFile(pulled, []byte("version: 1.0.0\n"), 0644)
+	if same, err := settingsInSync(pulled, current); err != nil || same {
+		t.Errorf("settingsInSync without local settings = %v, %v, want false", same, err)
+	}
+
+	os.WriteFile(current, []byte("version: 1.0.0\n"), 0644)
+	if same, err := settingsInSync(pulled, current); err != nil || !same {
+		t.Errorf("settingsInSync of identical files = %v, %v, want true", same, err)
+	}
+
+	os.WriteFile(current, []byte("version: 0.9.0\n"), 0644)
+	if same, _ := settingsInSync(pulled, current); same {
+		t.Error("settingsInSync of different files should be false")
+	}
+}
+
+func TestListSyncArchivesAndRestore(t *testing.T) {
+	_, _, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	root := archiveRoot()
+
+	dest := filepath.Join(home, "nvim")
+	os.MkdirAll(dest, 0755)
+	os.WriteFile(filepath.Join(dest, "init.lua"), []byte("synced"), 0644)
+	os.WriteFile(filepath.Join(dest, "extra.lua"), []byte("added by sync"), 0644)
+
+	older := filepath.Join(root, "nvim-configs-2026-01-02-10-00-00")
+	newer := filepath.Join(root, "nvim-configs-2026-03-04-10-00-00")
+	for _, dir := range []string{older, newer, filepath.Join(root, "nvim-configs-2026-05-06-10-00-00"), filepath.Join(root, "zsh-configs-2026-03-04-10-00-00")} {
+		os.MkdirAll(dir, 0755)
+	}
+	os.WriteFile(filepath.Join(older, "init.lua"), []byte("synced"), 0644)
+	os.WriteFile(filepath.Join(newer, "init.lua"), []byte("before sync"), 0644)
+	os.MkdirAll(filepath.Join(newer, "lua"), 0755)
+	os.WriteFile(filepath.Join(newer, "lua", "plugins.lua"), []byte("plugins"), 0644)
+	os.WriteFile(filepath.Join(root, "zsh-configs-2026-03-04-10-00-00", ".zshrc"), []byte("zsh"), 0644)
+
+	archives, err := listSyncArchives("nvim-configs", dest)
+	if err != nil {
+		t.Fatalf("listSyncArchives failed: %v", err)
+	}
+	// The empty archive and other apps' archives are left out, newest first
+	if len(archives) != 2 || archives[0].name != filepath.Base(newer) || archives[1].name != filepath.Base(older) {
+		t.Fatalf("unexpected archives: %+v", archives)
+	}
+	if archives[0].files != 2 || len(archives[0].changes) != 2 || len(archives[1].changes) != 0 {
+		t.Errorf("unexpected change summary: %d files, %d and %d changes", archives[0].files, len(archives[0].changes), len(archives[1].changes))
+	}
+
+	backup, err := restoreArchive("nvim-configs", archives[0])
+	if err != nil {
+		t.Fatalf("restoreArchive failed: %v", err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(dest, "init.lua")); string(content) != "before sync" {
+		t.Errorf("init.lua = %q, want the archived content", content)
+	}
+	if content, _ := os.ReadFile(filepath.Join(dest, "lua", "plugins.lua")); string(content) != "plugins" {
+		t.Errorf("plugins.lua = %q, want the archived file restored", content)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "extra.lua")); err != nil {
+		t.Error("files the archive doesn't hold should be left in place")
+	}
+	if content, _ := os.ReadFile(filepath.Join(backup, "init.lua")); string(content) != "synced" {
+		t.Errorf("backup init.lua = %q, want the replaced content", content)
+	}
+}
+
+func TestPromptArchive(t *testing.T) {
+	if number, ok := promptArchive(bufio.NewReader(strings.NewReader("9\nx\n2\n")), 3); !ok || number != 2 {
+		t.Errorf("promptArchive() = %d, %v; want 2 after re-prompting", number, ok)
+	}
+	if _, ok := promptArchive(bufio.NewReader(strings.NewReader("\n")), 3); ok {
+		t.Error("enter should quit")
+	}
+	if _, ok := promptArchive(bufio.NewReader(strings.NewReader("")), 3); ok {
+		t.Error("closed input should quit")
+	}
+}
+
+func TestListArchivedApps(t *testing.T) {
+	_, _, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	t.Setenv("HOME", t.TempDir())
+	root := archiveRoot()
+
+	files := map[string]string{
+		"zsh-configs-2026-01-02-10-00-00/.zshrc":           "old",
+		"zsh-configs-2026-03-04-10-00-00/.zshrc":           "newer",
+		"anvil-settings-2026-02-03-10-00-00/settings.yaml": "settings",
+		"unrelated-2026-02-03-10-00-00/file":               "not a sync archive",
+	}
+	for path, content := range files {
+		os.MkdirAll(filepath.Dir(filepath.Join(root, path)), 0755)
+		os.WriteFile(filepath.Join(root, path), []byte(content), 0644)
+	}
+	// Cancelled syncs leave empty archives behind
+	os.MkdirAll(filepath.Join(root, "nvim-configs-2026-03-04-10-00-00"), 0755)
+
+	apps, err := listArchivedApps()
+	if err != nil {
+		t.Fatalf("listArchivedApps failed: %v", err)
+	}
+	if len(apps) != 2 || apps[0].name != constants.ANVIL || apps[1].name != "zsh" {
+		t.Fatalf("unexpected apps: %+v", apps)
+	}
+	if apps[1].count != 2 || apps[1].latest.Format(archiveTimeLayout) != "2026-03-04-10-00-00" {
+		t.Errorf("zsh = %d archives, latest %s; want 2, 2026-03-04-10-00-00", apps[1].count, apps[1].latest)
+	}
+}
+
+func TestCheckPulledFromRemote(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	settings := `version: "1"
+github:
+  config_repo: me/dotfiles
+  branch: main
+  remotes:
+    work:
+      config_repo: company/dotfiles
+`
+	os.MkdirAll(filepath.Join(home, ".anvil"), 0755)
+	if err := os.WriteFile(filepath.Join(home, ".anvil", "settings.yaml"), []byte(settings), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config.InvalidateConfigCache()
+	t.Cleanup(func() {
+		config.UseRemote("")
+		config.InvalidateConfigCache()
+	})
+
+	os.MkdirAll(filepath.Join(config.GetTempDirectory(), "zsh"), 0755)
+	if err := config.RecordTempPull(config.TempEntry{Name: "zsh", Repo: "company/dotfiles"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Pulled with --remote work, then synced without it
+	if err := checkPulledFromRemote("zsh"); err == nil {
+		t.Error("expected a plain sync of a copy pulled from a remote to be refused")
+	}
+	if err := config.UseRemote("work"); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkPulledFromRemote("zsh"); err != nil {
+		t.Errorf("sync with the matching remote failed: %v", err)
+	}
+	if err := checkPulledFromRemote("nvim"); err != nil {
+		t.Errorf("apps never pulled should be left to the pulled-copy check: %v", err)
+	}
+}
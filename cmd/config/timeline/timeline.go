@@ -0,0 +1,240 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package timeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/github"
+	"github.com/0xjuanma/anvil/internal/timeline"
+	"github.com/0xjuanma/palantir"
+	"github.com/spf13/cobra"
+)
+
+var TimelineCmd = &cobra.Command{
+	Use:   "timeline [app-name]",
+	Short: "Show when configs were pushed, pulled and synced, here and in the repository",
+	Long:  constants.TIMELINE_COMMAND_LONG_DESCRIPTION,
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runTimelineCommand(cmd, args); err != nil {
+			palantir.GetGlobalOutputHandler().PrintError("Timeline failed: %v", err)
+			return
+		}
+	},
+}
+
+// runTimelineCommand merges the local timeline with the repository history and prints it
+func runTimelineCommand(cmd *cobra.Command, args []string) error {
+	output := palantir.GetGlobalOutputHandler()
+	limit, _ := cmd.Flags().GetInt("limit")
+	localOnly, _ := cmd.Flags().GetBool("local")
+	asJSON, _ := cmd.Flags().GetBool("json")
+
+	app := ""
+	if len(args) > 0 {
+		app = args[0]
+	}
+
+	local, err := timeline.Load(app)
+	if err != nil {
+		return errors.NewFileSystemError(constants.OpConfig, "read-timeline", err)
+	}
+
+	var repository []timeline.Entry
+	if !localOnly {
+		repository, err = repositoryEntries(cmd.Context(), app, limit)
+		if err != nil && !asJSON {
+			output.PrintWarning("Repository history left out: %v", err)
+		}
+	}
+	entries := timeline.Merge(limit, local, repository)
+
+	if asJSON {
+		if entries == nil {
+			entries = []timeline.Entry{}
+		}
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return errors.NewValidationError(constants.OpConfig, "encode-json", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if app != "" {
+		output.PrintHeader(fmt.Sprintf("Config Timeline: %s", app))
+	} else {
+		output.PrintHeader("Config Timeline")
+	}
+	if len(entries) == 0 {
+		output.PrintInfo("No pushes, pulls or syncs recorded yet")
+		return nil
+	}
+
+	printTimeline(entries, app == "")
+	fmt.Println()
+	if app != "" {
+		if last, ok := timeline.LastChanges(entries)[app]; ok {
+			output.PrintInfo("Last change: %s", describeChange(last))
+		}
+	}
+	return nil
+}
+
+// repositoryEntries turns the commits of the local clone that changed app directories into entries
+func repositoryEntries(ctx context.Context, app string, limit int) ([]timeline.Entry, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.GitHub.ConfigRepo == "" {
+		return nil, fmt.Errorf("github.config_repo is not set")
+	}
+
+	commits, err := newGitHubClient(cfg).AppHistory(ctx, app, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []timeline.Entry
+	for _, commit := range commits {
+		for _, name := range commit.Apps {
+			if app != "" && name != app {
+				continue
+			}
+			entries = append(entries, timeline.Entry{
+				Time:    commit.Date,
+				Action:  timeline.Committed,
+				App:     name,
+				Source:  timeline.SourceRepository,
+				Repo:    cfg.GitHub.ConfigRepo,
+				Branch:  cfg.GitHub.Branch,
+				Commit:  commit.Commit,
+				Author:  commit.Author,
+				Details: commit.Subject,
+			})
+		}
+	}
+	return entries, nil
+}
+
+// newGitHubClient builds a client for the configured repository
+func newGitHubClient(anvilConfig *config.AnvilConfig) *github.GitHubClient {
+	var token string
+	if anvilConfig.GitHub.TokenEnvVar != "" {
+		token = os.Getenv(anvilConfig.GitHub.TokenEnvVar)
+	}
+
+	githubClient := github.NewGitHubClient(
+		anvilConfig.GitHub.ConfigRepo,
+		anvilConfig.GitHub.Branch,
+		anvilConfig.GitHub.LocalPath,
+		token,
+		anvilConfig.Git.SSHKeyPath,
+		anvilConfig.Git.Username,
+		anvilConfig.Git.Email,
+	)
+	githubClient.CloneDepth = anvilConfig.GitHub.CloneDepth
+	return githubClient
+}
+
+// printTimeline prints entries newest first under a heading per day
+func printTimeline(entries []timeline.Entry, showApp bool) {
+	appWidth := 0
+	if showApp {
+		for _, entry := range entries {
+			appWidth = max(appWidth, len(entry.App))
+		}
+	}
+
+	day := ""
+	for _, entry := range entries {
+		local := entry.Time.Local()
+		if current := local.Format("2006-01-02 Mon"); current != day {
+			day = current
+			fmt.Printf("\n  %s\n", day)
+		}
+		fmt.Println(formatEntry(entry, appWidth))
+	}
+}
+
+// actionIcons mark each kind of entry in the timeline
+var actionIcons = map[timeline.Action]string{
+	timeline.Pushed:     "⬆",
+	timeline.Pulled:     "⬇",
+	timeline.Synced:     "✓",
+	timeline.RolledBack: "↺",
+	timeline.Committed:  "●",
+}
+
+// formatEntry renders one timeline line, with an app column appWidth wide when it is not 0
+func formatEntry(entry timeline.Entry, appWidth int) string {
+	line := fmt.Sprintf("    %s  %s %-11s", entry.Time.Local().Format("15:04"), actionIcons[entry.Action], entry.Action)
+	if appWidth > 0 {
+		line += fmt.Sprintf(" %-*s", appWidth, entry.App)
+	}
+	return strings.TrimRight(line+" "+entryDetails(entry), " ")
+}
+
+// entryDetails describes where an entry came from and what it touched
+func entryDetails(entry timeline.Entry) string {
+	switch entry.Action {
+	case timeline.Committed:
+		return fmt.Sprintf("%s by %s: %s", shortCommit(entry.Commit), entry.Author, entry.Details)
+	case timeline.Pushed:
+		return fmt.Sprintf("on %s → %s", entry.Machine, entry.Branch)
+	case timeline.RolledBack:
+		return fmt.Sprintf("on %s from %s", entry.Machine, entry.Details)
+	}
+	details := "on " + entry.Machine
+	if entry.Commit != "" {
+		details += fmt.Sprintf(" at %s", shortCommit(entry.Commit))
+	}
+	return details
+}
+
+// describeChange summarizes the last change of an app, e.g. "3 days ago, pushed on macbook"
+func describeChange(entry timeline.Entry) string {
+	age := config.FormatAge(time.Since(entry.Time))
+	if entry.Source == timeline.SourceRepository {
+		return fmt.Sprintf("%s ago, committed by %s (%s)", age, entry.Author, shortCommit(entry.Commit))
+	}
+	return fmt.Sprintf("%s ago, %s on %s", age, entry.Action, entry.Machine)
+}
+
+// shortCommit abbreviates a commit hash
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
+
+func init() {
+	TimelineCmd.Flags().IntP("limit", "n", 20, "Number of entries to show (0 for all)")
+	TimelineCmd.Flags().Bool("local", false, "Only show pushes, pulls and syncs made on this machine")
+	TimelineCmd.Flags().Bool("json", false, "Print the entries as a JSON array")
+}
@@ -0,0 +1,91 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validate
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/palantir"
+	"github.com/spf13/cobra"
+)
+
+var ValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check settings.yaml section by section and report what needs fixing",
+	Long:  constants.VALIDATE_COMMAND_LONG_DESCRIPTION,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runValidateCommand(); err != nil {
+			palantir.GetGlobalOutputHandler().PrintError("Validate failed: %v", err)
+			os.Exit(errors.ExitCode(err))
+		}
+	},
+}
+
+// runValidateCommand parses settings.yaml one section at a time, then validates its content
+func runValidateCommand() error {
+	o := palantir.GetGlobalOutputHandler()
+	configPath := config.GetAnvilConfigPath()
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return errors.NewFileSystemError(constants.OpConfig, "read-settings", err)
+	}
+
+	o.PrintHeader(fmt.Sprintf("Validating %s", constants.ANVIL_CONFIG_FILE))
+	o.PrintInfo("File: %s", configPath)
+
+	parsed, problems := config.ParseSettingsBySection(data)
+	corrupt := config.ValidSettings(data) != nil
+	for _, problem := range problems {
+		if problem.Err == nil {
+			o.PrintWarning("%s", problem)
+		} else {
+			o.PrintError("%s", problem)
+		}
+	}
+	if corrupt {
+		printRepairHints()
+		return fmt.Errorf("%d section(s) of %s could not be parsed", len(problems), constants.ANVIL_CONFIG_FILE)
+	}
+
+	if err := config.NewConfigValidator(parsed).ValidateConfig(parsed); err != nil {
+		o.PrintError("%v", err)
+		return fmt.Errorf("%s parses but has invalid values", constants.ANVIL_CONFIG_FILE)
+	}
+
+	if len(problems) > 0 {
+		o.PrintWarning("%s is valid, missing sections use defaults", constants.ANVIL_CONFIG_FILE)
+		return nil
+	}
+	o.PrintSuccess(fmt.Sprintf("%s is valid", constants.ANVIL_CONFIG_FILE))
+	return nil
+}
+
+// printRepairHints points at the ways to get a working settings file back
+func printRepairHints() {
+	o := palantir.GetGlobalOutputHandler()
+	fmt.Println()
+	o.PrintInfo("💡 Fix the sections above in %s, or restore a backup:", config.GetAnvilConfigPath())
+	o.PrintInfo("   anvil config restore-settings --list")
+	o.PrintInfo("   anvil config restore-settings --version <n>")
+	o.PrintInfo("💡 Read-only commands such as 'anvil config show', 'anvil install --list' and 'anvil doctor' run in safe mode meanwhile")
+}
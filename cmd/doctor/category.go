@@ -18,8 +18,8 @@ package doctor
 
 import (
 	"fmt"
-	"strings"
 
+	"github.com/0xjuanma/anvil/internal/i18n"
 	"github.com/0xjuanma/anvil/internal/terminal/charm"
 	"github.com/0xjuanma/anvil/internal/validators"
 	"github.com/0xjuanma/palantir"
@@ -76,11 +76,11 @@ func getStatusEmoji(status validators.ValidationStatus) string {
 
 func getCategoryStatus(passed, warned, failed, skipped int) string {
 	if failed > 0 {
-		return "[FAIL]"
+		return i18n.T("doctor.status.fail")
 	} else if warned > 0 {
-		return "[WARN]"
+		return i18n.T("doctor.status.warn")
 	} else {
-		return "[PASS]"
+		return i18n.T("doctor.status.pass")
 	}
 }
 
@@ -104,7 +104,7 @@ func displayCategory(category string, results []*validators.ValidationResult, ve
 	// Choose category status
 	categoryStatus := getCategoryStatus(passed, warned, failed, skipped)
 	o := palantir.GetGlobalOutputHandler()
-	o.PrintStage(fmt.Sprintf("%s %s", categoryStatus, strings.Title(category)))
+	o.PrintStage(fmt.Sprintf("%s %s", categoryStatus, categoryTitle(category)))
 
 	for _, result := range results {
 		statusEmoji := getStatusEmoji(result.Status)
@@ -142,10 +142,8 @@ func printCategoryResults(category string, checkNames []string, statuses map[str
 	}
 
 	categoryStatus := getCategoryStatus(passed, warned, failed, 0)
-	categoryTitle := strings.Title(category)
-
 	// Print category header with emoji
-	fmt.Printf("  %s %s\n", categoryStatus, charm.RenderHighlight(categoryTitle, "#00D9FF"))
+	fmt.Printf("  %s %s\n", categoryStatus, charm.RenderHighlight(categoryTitle(category), charm.ActiveTheme().Accent))
 
 	// Print each check result
 	for _, name := range checkNames {
@@ -179,3 +177,8 @@ func printCategoryResults(category string, checkNames []string, statuses map[str
 
 	fmt.Println()
 }
+
+// categoryTitle returns the display name of a check category in the active language
+func categoryTitle(category string) string {
+	return i18n.T("doctor.category." + category)
+}
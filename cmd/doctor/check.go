@@ -24,6 +24,7 @@ import (
 
 	"github.com/0xjuanma/anvil/internal/constants"
 	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/i18n"
 	"github.com/0xjuanma/anvil/internal/terminal/charm"
 	"github.com/0xjuanma/anvil/internal/validators"
 	"github.com/0xjuanma/palantir"
@@ -32,35 +33,36 @@ import (
 // showAvailableChecks displays all available checks organized by category
 func showAvailableChecks(engine *validators.DoctorEngine) error {
 	o := palantir.GetGlobalOutputHandler()
-	o.PrintHeader("Available Health Checks")
+	o.PrintHeader(i18n.T("doctor.list.title"))
 
-	o.PrintInfo("🏷️  CATEGORIES (run all checks in a group):\n")
+	o.PrintInfo(i18n.T("doctor.list.categories"))
 
 	checks := engine.ListChecks()
 
 	categoryDescriptions := map[string]string{
-		"environment":   "Verify anvil initialization and directory structure",
-		"dependencies":  "Check required tools and Homebrew installation",
-		"configuration": "Validate git and GitHub settings",
-		"connectivity":  "Test GitHub access and repository connections",
+		"environment":   i18n.T("doctor.list.about.environment"),
+		"dependencies":  i18n.T("doctor.list.about.dependencies"),
+		"configuration": i18n.T("doctor.list.about.configuration"),
+		"connectivity":  i18n.T("doctor.list.about.connectivity"),
+		"apps":          i18n.T("doctor.list.about.apps"),
 	}
 
 	totalChecks := 0
-	for _, category := range []string{"environment", "dependencies", "configuration", "connectivity"} {
+	for _, category := range doctorCategories {
 		if checkNames, exists := checks[category]; exists {
 			o.PrintStage(fmt.Sprintf("anvil doctor %s", category))
 			o.PrintInfo("    %s", categoryDescriptions[category])
-			o.PrintInfo("    Includes: %s", strings.Join(checkNames, ", "))
-			o.PrintInfo("    (%d checks)\n", len(checkNames))
+			o.PrintInfo(i18n.T("doctor.list.includes"), strings.Join(checkNames, ", "))
+			o.PrintInfo(i18n.T("doctor.list.count"), len(checkNames))
 			totalChecks += len(checkNames)
 		}
 	}
 
-	o.PrintInfo("🔍 SPECIFIC CHECKS (run individual validators):\n")
+	o.PrintInfo(i18n.T("doctor.list.specific"))
 
-	for _, category := range []string{"environment", "dependencies", "configuration", "connectivity"} {
+	for _, category := range doctorCategories {
 		if checkNames, exists := checks[category]; exists {
-			o.PrintStage(fmt.Sprintf("%s checks:", strings.Title(category)))
+			o.PrintStage(i18n.T("doctor.list.category_checks", categoryTitle(category)))
 			for _, checkName := range checkNames {
 				o.PrintInfo("  anvil doctor %s", checkName)
 			}
@@ -68,12 +70,12 @@ func showAvailableChecks(engine *validators.DoctorEngine) error {
 		}
 	}
 
-	o.PrintInfo("💡 USAGE EXAMPLES:\n")
-	o.PrintInfo("  anvil doctor                    # Run all %d checks", totalChecks)
-	o.PrintInfo("  anvil doctor environment        # Run 3 environment checks")
-	o.PrintInfo("  anvil doctor git-config         # Run only git configuration check")
-	o.PrintInfo("  anvil doctor --fix              # Auto-fix detected issues")
-	o.PrintInfo("  anvil doctor dependencies --fix # Auto-fix dependency issues")
+	o.PrintInfo(i18n.T("doctor.list.examples"))
+	o.PrintInfo(i18n.T("doctor.list.example_all"), totalChecks)
+	o.PrintInfo(i18n.T("doctor.list.example_category"), len(checks["environment"]))
+	o.PrintInfo(i18n.T("doctor.list.example_check"))
+	o.PrintInfo(i18n.T("doctor.list.example_fix"))
+	o.PrintInfo(i18n.T("doctor.list.example_category_fix"))
 
 	return nil
 }
@@ -81,25 +83,26 @@ func showAvailableChecks(engine *validators.DoctorEngine) error {
 // runSingleCheck executes a specific health check
 func runSingleCheck(engine *validators.DoctorEngine, checkName string, verbose bool) error {
 	o := palantir.GetGlobalOutputHandler()
-	o.PrintHeader(fmt.Sprintf("Running Check: %s", checkName))
+	o.PrintHeader(i18n.T("doctor.check.title", checkName))
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	spinner := charm.NewLineSpinner(fmt.Sprintf("Executing %s check", checkName))
+	spinner := charm.NewLineSpinner(i18n.T("doctor.check.running", checkName))
 	spinner.Start()
 
 	result := engine.RunCheckWithProgress(ctx, checkName, verbose)
 
 	if result.Status == validators.PASS {
-		spinner.Success(fmt.Sprintf("%s check passed", checkName))
+		spinner.Success(i18n.T("doctor.check.passed", checkName))
 	} else if result.Status == validators.WARN {
-		spinner.Warning(fmt.Sprintf("%s check completed with warnings", checkName))
+		spinner.Warning(i18n.T("doctor.check.warned", checkName))
 	} else {
-		spinner.Error(fmt.Sprintf("%s check failed", checkName))
+		spinner.Error(i18n.T("doctor.check.failed", checkName))
 	}
 
 	displayResults([]*validators.ValidationResult{result}, verbose)
+	recordWarnings([]*validators.ValidationResult{result})
 
 	if result.Status == validators.FAIL {
 		return errors.NewValidationError(constants.OpDoctor, checkName, fmt.Errorf(result.Message))
@@ -111,7 +114,7 @@ func runSingleCheck(engine *validators.DoctorEngine, checkName string, verbose b
 // runCategoryChecks executes all checks in a specific category
 func runCategoryChecks(engine *validators.DoctorEngine, category string, verbose bool) error {
 	o := palantir.GetGlobalOutputHandler()
-	o.PrintHeader(fmt.Sprintf("Running %s Health Checks", strings.Title(category)))
+	o.PrintHeader(i18n.T("doctor.category.title", categoryTitle(category)))
 
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
@@ -119,11 +122,11 @@ func runCategoryChecks(engine *validators.DoctorEngine, category string, verbose
 	// Get validators for this category to show count
 	categoryValidators := engine.GetValidatorsByCategory(category)
 	if len(categoryValidators) == 0 {
-		o.PrintError("Category '%s' not found", category)
+		o.PrintError(i18n.T("doctor.category.not_found"), category)
 		return errors.NewValidationError(constants.OpDoctor, category, fmt.Errorf("category not found"))
 	}
 
-	spinner := charm.NewLineSpinner(fmt.Sprintf("Executing %d checks in %s category", len(categoryValidators), category))
+	spinner := charm.NewLineSpinner(i18n.T("doctor.category.running", len(categoryValidators), categoryTitle(category)))
 	spinner.Start()
 
 	results := engine.RunCategoryWithProgress(ctx, category, verbose)
@@ -141,15 +144,16 @@ func runCategoryChecks(engine *validators.DoctorEngine, category string, verbose
 	}
 
 	if failed > 0 {
-		spinner.Error(fmt.Sprintf("%s checks completed: %d failed", category, failed))
+		spinner.Error(i18n.T("doctor.category.failed", categoryTitle(category), failed))
 	} else if warned > 0 {
-		spinner.Warning(fmt.Sprintf("%s checks completed: %d warnings", category, warned))
+		spinner.Warning(i18n.T("doctor.category.warned", categoryTitle(category), warned))
 	} else {
-		spinner.Success(fmt.Sprintf("All %s checks passed", category))
+		spinner.Success(i18n.T("doctor.category.passed", categoryTitle(category)))
 	}
 
 	displayResults(results, verbose)
 	printSummary(results)
+	recordWarnings(results)
 
 	// Check if any critical failures
 	for _, result := range results {
@@ -172,7 +176,7 @@ type checkStatus struct {
 // runAllChecks executes all available health checks
 func runAllChecks(engine *validators.DoctorEngine, verbose bool) error {
 	o := palantir.GetGlobalOutputHandler()
-	o.PrintHeader("Running Anvil Health Check")
+	o.PrintHeader(i18n.T("doctor.all.title"))
 
 	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
 	defer cancel()
@@ -187,6 +191,7 @@ func runAllChecks(engine *validators.DoctorEngine, verbose bool) error {
 		"dependencies":  {},
 		"configuration": {},
 		"connectivity":  {},
+		"apps":          {},
 	}
 
 	for _, v := range allValidators {
@@ -204,10 +209,11 @@ func runAllChecks(engine *validators.DoctorEngine, verbose bool) error {
 	}
 
 	// Run checks with a spinner
-	spinner := charm.NewLineSpinner(fmt.Sprintf("Running %d health checks", totalChecks))
+	spinner := charm.NewLineSpinner(i18n.T("doctor.all.running", totalChecks))
 	spinner.Start()
 
 	results := engine.RunAll(ctx)
+	validators.SaveCachedResults(validators.ResultsCacheDir(), results)
 
 	// Count results for spinner message
 	passed, warned, failed := 0, 0, 0
@@ -224,11 +230,11 @@ func runAllChecks(engine *validators.DoctorEngine, verbose bool) error {
 
 	// Update spinner based on results
 	if failed > 0 {
-		spinner.Error(fmt.Sprintf("Completed: %d passed, %d failed", passed, failed))
+		spinner.Error(i18n.T("doctor.all.failed", passed, failed))
 	} else if warned > 0 {
-		spinner.Warning(fmt.Sprintf("Completed: %d passed, %d warnings", passed, warned))
+		spinner.Warning(i18n.T("doctor.all.warned", passed, warned))
 	} else {
-		spinner.Success(fmt.Sprintf("All %d checks passed!", totalChecks))
+		spinner.Success(i18n.T("doctor.all.passed", totalChecks))
 	}
 
 	// Update statuses based on results
@@ -254,13 +260,14 @@ func runAllChecks(engine *validators.DoctorEngine, verbose bool) error {
 
 	// Print organized results by category
 	fmt.Println()
-	for _, category := range []string{"environment", "dependencies", "configuration", "connectivity"} {
+	for _, category := range doctorCategories {
 		if checkNames, exists := categories[category]; exists && len(checkNames) > 0 {
 			printCategoryResults(category, checkNames, checkStatuses, results, verbose)
 		}
 	}
 
 	printSummary(results)
+	recordWarnings(results)
 
 	return nil
 }
@@ -17,23 +17,44 @@ limitations under the License.
 package doctor
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
 	"fmt"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/i18n"
 	"github.com/0xjuanma/anvil/internal/terminal/charm"
 	"github.com/0xjuanma/anvil/internal/validators"
 	"github.com/0xjuanma/palantir"
 	"github.com/spf13/cobra"
 )
 
+// doctorCategories lists the check categories in display order
+var doctorCategories = []string{"environment", "dependencies", "configuration", "connectivity", "apps"}
+
+// Output formats of doctor results
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
 var DoctorCmd = &cobra.Command{
-	Use:   "doctor [category|check]",
-	Short: "Run health checks and validate anvil environment",
-	Long:  constants.DOCTOR_COMMAND_LONG_DESCRIPTION,
+	Use:         "doctor [category|check]",
+	Short:       "Run health checks and validate anvil environment",
+	Long:        constants.DOCTOR_COMMAND_LONG_DESCRIPTION,
+	Annotations: map[string]string{constants.SafeModeAnnotation: "true"},
 	Run: func(cmd *cobra.Command, args []string) {
 		if err := runDoctorCommand(cmd, args); err != nil {
-			palantir.GetGlobalOutputHandler().PrintError("Doctor failed: %v", err)
+			palantir.GetGlobalOutputHandler().PrintError(i18n.T("doctor.failed"), err)
+			// Machine-readable runs reserve exit code 1 for doctor itself failing
+			if output, _ := cmd.Flags().GetString("output"); output != outputText {
+				os.Exit(1)
+			}
 			return
 		}
 	},
@@ -44,11 +65,39 @@ func runDoctorCommand(cmd *cobra.Command, args []string) error {
 	// Get command flags
 	listChecks, _ := cmd.Flags().GetBool("list")
 	fix, _ := cmd.Flags().GetBool("fix")
+	fixAll, _ := cmd.Flags().GetBool("fix-all")
 	verbose, _ := cmd.Flags().GetBool("verbose")
+	output, _ := cmd.Flags().GetString("output")
 
 	// Create doctor engine with terminal output
 	engine := validators.NewDoctorEngine(palantir.GetGlobalOutputHandler())
 
+	switch output {
+	case outputText:
+	case outputJSON:
+		if listChecks || fix || fixAll {
+			return errors.NewValidationError(constants.OpDoctor, "output", fmt.Errorf("%s", i18n.T("doctor.output.combined")))
+		}
+		return runJSONReport(engine, args)
+	default:
+		return errors.NewValidationError(constants.OpDoctor, "output", fmt.Errorf(i18n.T("doctor.output.unknown"), output))
+	}
+
+	// Handle bulk fixes picked from a checklist
+	if fixAll {
+		if listChecks || fix {
+			return errors.NewValidationError(constants.OpDoctor, "fix-all", fmt.Errorf("%s", i18n.T("doctor.fix_all.combined")))
+		}
+		category := ""
+		if len(args) > 0 {
+			if !isDoctorCategory(args[0]) {
+				return errors.NewValidationError(constants.OpDoctor, "fix-all", fmt.Errorf(i18n.T("doctor.fix_all.category_only"), args[0]))
+			}
+			category = args[0]
+		}
+		return runFixAllSelect(engine, category, bufio.NewReader(os.Stdin))
+	}
+
 	// Handle list command
 	if listChecks {
 		return showAvailableChecks(engine)
@@ -56,7 +105,9 @@ func runDoctorCommand(cmd *cobra.Command, args []string) error {
 
 	// Handle fix command
 	if fix {
-		if len(args) > 0 {
+		if len(args) > 0 && isDoctorCategory(args[0]) {
+			return runFixAll(engine, args[0])
+		} else if len(args) > 0 {
 			return runFixCheck(engine, args[0])
 		} else {
 			return runFixAll(engine, "")
@@ -71,29 +122,76 @@ func runDoctorCommand(cmd *cobra.Command, args []string) error {
 
 	target := args[0]
 
-	// Check if it's a category first
-	categories := []string{"environment", "dependencies", "configuration", "connectivity"}
-	for _, category := range categories {
+	// Check if it's a category first, otherwise treat it as a specific check
+	if isDoctorCategory(target) {
+		return runCategoryChecks(engine, target, verbose)
+	}
+	return runSingleCheck(engine, target, verbose)
+}
+
+// isDoctorCategory reports whether a doctor argument names a category rather than a check
+func isDoctorCategory(target string) bool {
+	for _, category := range doctorCategories {
 		if target == category {
-			return runCategoryChecks(engine, category, verbose)
+			return true
 		}
 	}
+	return false
+}
 
-	// Otherwise treat it as a specific check
-	return runSingleCheck(engine, target, verbose)
+// runJSONReport runs the requested checks without progress output and prints one JSON report
+// grouped by category. The exit code tells CI whether there were warnings or failures.
+func runJSONReport(engine *validators.DoctorEngine, args []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	defer cancel()
+
+	var results []*validators.ValidationResult
+	switch {
+	case len(args) == 0:
+		results = engine.RunAll(ctx)
+	case isDoctorCategory(args[0]):
+		results = engine.RunCategory(ctx, args[0])
+	default:
+		results = []*validators.ValidationResult{engine.RunCheck(ctx, args[0])}
+	}
+
+	if len(args) == 0 {
+		validators.SaveCachedResults(validators.ResultsCacheDir(), results)
+	}
+
+	report := validators.NewReport(results, doctorCategories)
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return errors.NewValidationError(constants.OpDoctor, "encode-json", err)
+	}
+	fmt.Println(string(data))
+
+	if report.Summary.ExitCode != validators.ExitHealthy {
+		os.Exit(report.Summary.ExitCode)
+	}
+	return nil
 }
 
 // displayResults shows validation results in a formatted table
 func displayResults(results []*validators.ValidationResult, verbose bool) {
 	categories := validators.FormatResultsTable(results)
 
-	for _, category := range []string{"environment", "dependencies", "configuration", "connectivity"} {
+	for _, category := range doctorCategories {
 		if categoryResults, exists := categories[category]; exists {
 			displayCategory(category, categoryResults, verbose)
 		}
 	}
 }
 
+// recordWarnings notes each WARN result so strict mode can fail the run and name the check
+func recordWarnings(results []*validators.ValidationResult) {
+	for _, result := range results {
+		if result.Status == validators.WARN {
+			charm.RecordWarning(fmt.Sprintf("doctor %s: %s", result.Name, result.Message))
+		}
+	}
+}
+
 // printSummary shows overall health check summary
 func printSummary(results []*validators.ValidationResult) {
 	passed, warned, failed, _ := validators.GetSummary(results)
@@ -107,7 +205,7 @@ func printSummary(results []*validators.ValidationResult) {
 	dashboardContent.WriteString("\n")
 
 	// Category status bars
-	for _, category := range []string{"environment", "dependencies", "configuration", "connectivity"} {
+	for _, category := range doctorCategories {
 		if stats, exists := categoryStats[category]; exists {
 			status := getCategoryStatus(stats.passed, stats.warned, stats.failed, stats.skipped)
 
@@ -122,18 +220,18 @@ func printSummary(results []*validators.ValidationResult) {
 			filled := (percentage * barWidth) / 100
 			bar := strings.Repeat("━", filled) + strings.Repeat("━", barWidth-filled)
 
-			categoryTitle := strings.Title(category)
-			dashboardContent.WriteString(fmt.Sprintf("  %-6s %-15s %s  %d/%d passing\n",
+			categoryTitle := categoryTitle(category)
+			dashboardContent.WriteString(i18n.T("doctor.summary.category_line",
 				status, categoryTitle, bar, stats.passed, stats.total))
 		}
 	}
 
 	dashboardContent.WriteString("\n")
-	dashboardContent.WriteString(fmt.Sprintf("  Overall: %d/%d checks passing\n", passed, total))
+	dashboardContent.WriteString(i18n.T("doctor.summary.overall", passed, total))
 	dashboardContent.WriteString("\n")
 
 	// Render dashboard box
-	fmt.Println(charm.RenderBox("Summary", dashboardContent.String(), "#00D9FF", true))
+	fmt.Println(charm.RenderBox(i18n.T("doctor.summary.title"), dashboardContent.String(), charm.ActiveTheme().Accent, true))
 
 	// Show fixable issues in a separate box
 	fixableIssues := validators.GetFixableIssues(results)
@@ -143,19 +241,19 @@ func printSummary(results []*validators.ValidationResult) {
 			fixContent.WriteString(fmt.Sprintf("  • %s\n", issue.Name))
 		}
 		fixContent.WriteString("\n")
-		fixContent.WriteString("  Run 'anvil doctor --fix' to automatically fix them\n")
+		fixContent.WriteString(i18n.T("doctor.summary.fix_hint"))
 
-		fmt.Println(charm.RenderBox("🔧 Auto-fixable Issues", fixContent.String(), "#FFD700", true))
+		fmt.Println(charm.RenderBox(i18n.T("doctor.summary.fixable_title"), fixContent.String(), charm.ActiveTheme().Warning, true))
 	}
 
 	// Overall status badge
 	fmt.Println()
 	if failed > 0 {
-		fmt.Println("  " + charm.RenderBadge("ISSUES FOUND", "#FF5F87"))
+		fmt.Println("  " + charm.RenderBadge(i18n.T("doctor.badge.issues"), charm.ActiveTheme().Error))
 	} else if warned > 0 {
-		fmt.Println("  " + charm.RenderBadge("MINOR ISSUES", "#FFD700"))
+		fmt.Println("  " + charm.RenderBadge(i18n.T("doctor.badge.minor"), charm.ActiveTheme().Warning))
 	} else {
-		fmt.Println("  " + charm.RenderBadge("HEALTHY", "#00FF87"))
+		fmt.Println("  " + charm.RenderBadge(i18n.T("doctor.badge.healthy"), charm.ActiveTheme().Success))
 	}
 	fmt.Println()
 }
@@ -164,5 +262,10 @@ func init() {
 	// Add flags for enhanced doctor functionality
 	DoctorCmd.Flags().Bool("list", false, "List all available health checks")
 	DoctorCmd.Flags().Bool("fix", false, "Attempt to automatically fix issues")
+	DoctorCmd.Flags().Bool("fix-all", false, "Run all checks and pick which fixable issues to repair, all of them with --yes")
 	DoctorCmd.Flags().Bool("verbose", false, "Show detailed output")
+	DoctorCmd.Flags().StringP("output", "o", outputText, "Output format: text or json (json exits 0 healthy, 2 warnings, 3 failures)")
+
+	DoctorCmd.AddCommand(summaryCmd)
+	summaryCmd.Flags().Duration("max-age", time.Hour, "Use cached results up to this old before running the local checks again")
 }
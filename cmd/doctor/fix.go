@@ -21,6 +21,8 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/0xjuanma/anvil/internal/audit"
+	"github.com/0xjuanma/anvil/internal/i18n"
 	"github.com/0xjuanma/anvil/internal/terminal/charm"
 	"github.com/0xjuanma/anvil/internal/validators"
 	"github.com/0xjuanma/palantir"
@@ -29,7 +31,7 @@ import (
 // runFixCheck attempts to fix a specific check
 func runFixCheck(engine *validators.DoctorEngine, checkName string) error {
 	o := palantir.GetGlobalOutputHandler()
-	o.PrintHeader(fmt.Sprintf("Fixing Check: %s", checkName))
+	o.PrintHeader(i18n.T("doctor.fix.title", checkName))
 
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
@@ -39,44 +41,70 @@ func runFixCheck(engine *validators.DoctorEngine, checkName string) error {
 	displayResults([]*validators.ValidationResult{result}, false)
 
 	if result.Status == validators.PASS && checkName != "git-config" {
-		o.PrintSuccess("Check is already passing, no fix needed")
+		o.PrintSuccess(i18n.T("doctor.fix.already_passing"))
 		return nil
 	}
 
 	if !result.AutoFix {
-		o.PrintWarning("This check cannot be automatically fixed")
-		o.PrintInfo("Manual fix required: %s", result.FixHint)
+		o.PrintWarning(i18n.T("doctor.fix.not_fixable"))
+		o.PrintInfo(i18n.T("doctor.fix.manual"), result.FixHint)
 		return nil
 	}
 
+	if audit.IsEnabled() {
+		o.PrintInfo(i18n.T("doctor.fix.audit_one"), checkName)
+		audit.Record("doctor", "fix", checkName, result.Message)
+		return nil
+	}
+
+	// Fix failing dependencies first, e.g. Homebrew before required tools
+	prerequisites := failingPrerequisites(ctx, engine, checkName)
+
 	// Confirm with user
-	if !o.Confirm(fmt.Sprintf("Attempt to fix '%s'?", checkName)) {
-		o.PrintInfo("Fix cancelled by user")
+	confirmMessage := i18n.T("doctor.fix.confirm_one", checkName)
+	if len(prerequisites) > 0 {
+		o.PrintInfo(i18n.T("doctor.fix.prerequisites"), checkName)
+		for _, prerequisite := range prerequisites {
+			o.PrintInfo("  • %s: %s", prerequisite.Name, prerequisite.Message)
+		}
+		confirmMessage = i18n.T("doctor.fix.confirm_prerequisites", checkName, len(prerequisites))
+	}
+	if !charm.Confirm(charm.ConfirmFix, confirmMessage) {
+		o.PrintInfo(i18n.T("doctor.fix.cancelled"))
 		return nil
 	}
 
+	for i, prerequisite := range prerequisites {
+		o.PrintInfo(i18n.T("doctor.fix.fixing_prerequisite"), i+1, len(prerequisites), prerequisite.Name)
+		if err := engine.FixCheck(ctx, prerequisite.Name); err != nil {
+			o.PrintError(i18n.T("doctor.fix.failed_check"), prerequisite.Name, err)
+			return fmt.Errorf("prerequisite '%s' could not be fixed: %w", prerequisite.Name, err)
+		}
+		o.PrintSuccess(i18n.T("doctor.fix.fixed", prerequisite.Name))
+	}
+
 	// Attempt fix
-	spinner := charm.NewDotsSpinner(fmt.Sprintf("Attempting to fix %s", checkName))
+	spinner := charm.NewDotsSpinner(i18n.T("doctor.fix.attempting", checkName))
 	spinner.Start()
 	if err := engine.FixCheck(ctx, checkName); err != nil {
-		spinner.Error("Fix failed")
-		o.PrintError("Fix failed: %v", err)
+		spinner.Error(i18n.T("doctor.fix.spinner_failed"))
+		o.PrintError(i18n.T("doctor.fix.failed"), err)
 		return err
 	}
 
-	spinner.Success("Fix completed!")
+	spinner.Success(i18n.T("doctor.fix.completed"))
 
 	// Verify fix
-	spinner = charm.NewLineSpinner("Verifying fix")
+	spinner = charm.NewLineSpinner(i18n.T("doctor.fix.verifying"))
 	spinner.Start()
 	newResult := engine.RunCheck(ctx, checkName)
-	spinner.Success("Verification complete")
+	spinner.Success(i18n.T("doctor.fix.verified"))
 	displayResults([]*validators.ValidationResult{newResult}, false)
 
 	if newResult.Status == validators.PASS {
-		o.PrintSuccess("✅ Check is now passing!")
+		o.PrintSuccess(i18n.T("doctor.fix.now_passing"))
 	} else {
-		o.PrintWarning("⚠️  Check still has issues after fix attempt")
+		o.PrintWarning(i18n.T("doctor.fix.still_failing"))
 	}
 
 	return nil
@@ -85,7 +113,7 @@ func runFixCheck(engine *validators.DoctorEngine, checkName string) error {
 // runFixAll attempts to fix all auto-fixable issues
 func runFixAll(engine *validators.DoctorEngine, category string) error {
 	o := palantir.GetGlobalOutputHandler()
-	o.PrintHeader("Auto-fixing Issues")
+	o.PrintHeader(i18n.T("doctor.fix_all.title"))
 
 	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
 	defer cancel()
@@ -101,44 +129,126 @@ func runFixAll(engine *validators.DoctorEngine, category string) error {
 	fixableIssues := validators.GetFixableIssues(results)
 	if len(fixableIssues) == 0 {
 		if category != "" {
-			o.PrintSuccess(fmt.Sprintf("No auto-fixable issues found in %s category!", category))
+			o.PrintSuccess(i18n.T("doctor.fix_all.none_in_category", categoryTitle(category)))
 		} else {
-			o.PrintSuccess("No auto-fixable issues found!")
+			o.PrintSuccess(i18n.T("doctor.fix_all.none"))
 		}
 		return nil
 	}
 
 	if category != "" {
-		o.PrintInfo("Found %d auto-fixable issues in %s category:", len(fixableIssues), category)
+		o.PrintInfo(i18n.T("doctor.fix_all.found_in_category"), len(fixableIssues), categoryTitle(category))
 	} else {
-		o.PrintInfo("Found %d auto-fixable issues:", len(fixableIssues))
+		o.PrintInfo(i18n.T("doctor.fix_all.found"), len(fixableIssues))
 	}
 	for _, issue := range fixableIssues {
 		o.PrintInfo("  • %s: %s", issue.Name, issue.Message)
 	}
 
-	confirmMessage := "Attempt to fix all auto-fixable issues?"
+	confirmMessage := i18n.T("doctor.fix_all.confirm")
 	if category != "" {
-		confirmMessage = fmt.Sprintf("Attempt to fix all auto-fixable issues in %s category?", category)
+		confirmMessage = i18n.T("doctor.fix_all.confirm_category", categoryTitle(category))
 	}
 
-	if !o.Confirm(confirmMessage) {
-		o.PrintInfo("Fix cancelled by user")
+	if audit.IsEnabled() {
+		for _, issue := range fixableIssues {
+			audit.Record("doctor", "fix", issue.Name, issue.Message)
+		}
+		o.PrintInfo(i18n.T("doctor.fix_all.audit"), len(fixableIssues))
 		return nil
 	}
 
-	var fixedCount, failedCount int
-	for _, issue := range fixableIssues {
-		o.PrintInfo("Fixing %s...", issue.Name)
+	if !charm.Confirm(charm.ConfirmFix, confirmMessage) {
+		o.PrintInfo(i18n.T("doctor.fix.cancelled"))
+		return nil
+	}
+
+	outcomes, err := applyFixes(ctx, engine, fixableIssues)
+	if err != nil {
+		return err
+	}
+
+	fixedCount, failedCount, skippedCount := countOutcomes(outcomes)
+	if skippedCount > 0 {
+		o.PrintInfo(i18n.T("doctor.fix_all.done_skipped"), fixedCount, failedCount, skippedCount)
+	} else {
+		o.PrintInfo(i18n.T("doctor.fix_all.done"), fixedCount, failedCount)
+	}
+	return nil
+}
+
+// fixOutcome is what happened to one fix applied by applyFixes
+type fixOutcome struct {
+	name    string
+	err     error  // Set when the fix failed
+	blocker string // Set when the fix was skipped, the dependency that could not be fixed
+}
+
+// applyFixes repairs issues in dependency order, skipping any whose prerequisites failed to fix
+func applyFixes(ctx context.Context, engine *validators.DoctorEngine, issues []*validators.ValidationResult) ([]fixOutcome, error) {
+	o := palantir.GetGlobalOutputHandler()
+	orderedIssues, err := engine.OrderFixes(issues)
+	if err != nil {
+		return nil, err
+	}
+
+	var outcomes []fixOutcome
+	failed := make(map[string]bool)
+	for i, issue := range orderedIssues {
+		progress := fmt.Sprintf("[%d/%d]", i+1, len(orderedIssues))
+		if blocker := failedDependency(engine, issue.Name, failed); blocker != "" {
+			o.PrintWarning(i18n.T("doctor.fix_all.skipping"), progress, issue.Name, blocker)
+			failed[issue.Name] = true
+			outcomes = append(outcomes, fixOutcome{name: issue.Name, blocker: blocker})
+			continue
+		}
+
+		o.PrintInfo(i18n.T("doctor.fix_all.fixing"), progress, issue.Name)
 		if err := engine.FixCheck(ctx, issue.Name); err != nil {
-			o.PrintError("Failed to fix %s: %v", issue.Name, err)
-			failedCount++
+			o.PrintError(i18n.T("doctor.fix.failed_check"), issue.Name, err)
+			failed[issue.Name] = true
+			outcomes = append(outcomes, fixOutcome{name: issue.Name, err: err})
 		} else {
-			o.PrintSuccess(fmt.Sprintf("Fixed %s", issue.Name))
-			fixedCount++
+			o.PrintSuccess(i18n.T("doctor.fix.fixed", issue.Name))
+			outcomes = append(outcomes, fixOutcome{name: issue.Name})
 		}
 	}
+	return outcomes, nil
+}
 
-	o.PrintInfo("Fix complete: %d succeeded, %d failed", fixedCount, failedCount)
-	return nil
+// countOutcomes counts fixes that succeeded, failed and were skipped
+func countOutcomes(outcomes []fixOutcome) (fixed, failed, skipped int) {
+	for _, outcome := range outcomes {
+		switch {
+		case outcome.blocker != "":
+			skipped++
+		case outcome.err != nil:
+			failed++
+		default:
+			fixed++
+		}
+	}
+	return fixed, failed, skipped
+}
+
+// failingPrerequisites returns the auto-fixable dependencies of a check that are currently failing, in fix order
+func failingPrerequisites(ctx context.Context, engine *validators.DoctorEngine, checkName string) []*validators.ValidationResult {
+	var failing []*validators.ValidationResult
+	for _, dep := range engine.Dependencies(checkName) {
+		result := engine.RunCheck(ctx, dep)
+		if result.Status != validators.PASS && result.AutoFix {
+			failing = append(failing, result)
+		}
+	}
+	return failing
+}
+
+// failedDependency returns the first dependency of a check whose fix failed or was skipped
+func failedDependency(engine *validators.DoctorEngine, checkName string, failed map[string]bool) string {
+	for _, dep := range engine.Dependencies(checkName) {
+		if failed[dep] {
+			return dep
+		}
+	}
+	return ""
 }
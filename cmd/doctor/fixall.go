@@ -0,0 +1,205 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package doctor
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/audit"
+	"github.com/0xjuanma/anvil/internal/i18n"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+	"github.com/0xjuanma/anvil/internal/utils"
+	"github.com/0xjuanma/anvil/internal/validators"
+	"github.com/0xjuanma/palantir"
+)
+
+// runFixAllSelect runs every check, lets the user pick which fixable issues to repair from a
+// numbered checklist, or takes them all with --yes, then fixes them and reports each outcome
+func runFixAllSelect(engine *validators.DoctorEngine, category string, reader *bufio.Reader) error {
+	o := palantir.GetGlobalOutputHandler()
+	o.PrintHeader(i18n.T("doctor.fix_all.title"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
+	defer cancel()
+
+	var results []*validators.ValidationResult
+	if category != "" {
+		results = engine.RunCategoryWithProgress(ctx, category, false)
+	} else {
+		results = engine.RunAllWithProgress(ctx, false)
+	}
+
+	fixableIssues := validators.GetFixableIssues(results)
+	if len(fixableIssues) == 0 {
+		if category != "" {
+			o.PrintSuccess(i18n.T("doctor.fix_all.none_in_category", categoryTitle(category)))
+		} else {
+			o.PrintSuccess(i18n.T("doctor.fix_all.none"))
+		}
+		return nil
+	}
+
+	if category != "" {
+		o.PrintInfo(i18n.T("doctor.fix_all.found_in_category"), len(fixableIssues), categoryTitle(category))
+	} else {
+		o.PrintInfo(i18n.T("doctor.fix_all.found"), len(fixableIssues))
+	}
+	for i, issue := range fixableIssues {
+		o.PrintInfo("  %2d. %s %s: %s", i+1, getStatusEmoji(issue.Status), issue.Name, issue.Message)
+	}
+
+	if audit.IsEnabled() {
+		for _, issue := range fixableIssues {
+			audit.Record("doctor", "fix", issue.Name, issue.Message)
+		}
+		o.PrintInfo(i18n.T("doctor.fix_all.audit"), len(fixableIssues))
+		return nil
+	}
+
+	var selected []*validators.ValidationResult
+	if charm.AssumeYes() {
+		selected = fixableIssues
+		if !charm.Confirm(charm.ConfirmFix, i18n.T("doctor.fix_all.confirm")) {
+			o.PrintInfo(i18n.T("doctor.fix.cancelled"))
+			return nil
+		}
+	} else {
+		var ok bool
+		selected, ok = promptFixSelection(reader, fixableIssues)
+		if !ok || len(selected) == 0 {
+			o.PrintInfo(i18n.T("doctor.fix_all.none_selected"))
+			return nil
+		}
+	}
+
+	for _, added := range addPrerequisites(engine, selected, fixableIssues) {
+		o.PrintInfo(i18n.T("doctor.fix_all.adding_prerequisite"), added.issue.Name, added.requiredBy)
+		selected = append(selected, added.issue)
+	}
+
+	outcomes, err := applyFixes(ctx, engine, selected)
+	if err != nil {
+		return err
+	}
+	printFixReport(ctx, engine, outcomes)
+	return nil
+}
+
+// promptFixSelection asks which issues to fix, false means the input was closed or left empty
+func promptFixSelection(reader *bufio.Reader, issues []*validators.ValidationResult) ([]*validators.ValidationResult, bool) {
+	for {
+		fmt.Print(i18n.T("doctor.fix_all.select"))
+		answer, err := reader.ReadString('\n')
+		answer = strings.TrimSpace(answer)
+		switch strings.ToLower(answer) {
+		case "":
+			if err != nil {
+				fmt.Println()
+			}
+			return nil, false
+		case "a", "all":
+			return issues, true
+		}
+
+		indexes, parseErr := utils.ParseSelection(answer, len(issues))
+		if parseErr == nil {
+			picked := make([]*validators.ValidationResult, 0, len(indexes))
+			for _, index := range indexes {
+				picked = append(picked, issues[index])
+			}
+			return picked, true
+		}
+		if err != nil {
+			fmt.Println()
+			return nil, false
+		}
+		fmt.Printf("  %v\n", parseErr)
+	}
+}
+
+// prerequisite is a fixable issue added to a selection because a selected fix depends on it
+type prerequisite struct {
+	issue      *validators.ValidationResult
+	requiredBy string
+}
+
+// addPrerequisites returns the fixable issues that selected fixes depend on but were not picked,
+// following dependencies of dependencies
+func addPrerequisites(engine *validators.DoctorEngine, selected, fixable []*validators.ValidationResult) []prerequisite {
+	picked := make(map[string]bool, len(selected))
+	for _, issue := range selected {
+		picked[issue.Name] = true
+	}
+
+	var added []prerequisite
+	queue := slices.Clone(selected)
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dep := range engine.Dependencies(current.Name) {
+			index := slices.IndexFunc(fixable, func(issue *validators.ValidationResult) bool { return issue.Name == dep })
+			if index < 0 || picked[dep] {
+				continue
+			}
+			picked[dep] = true
+			added = append(added, prerequisite{issue: fixable[index], requiredBy: current.Name})
+			queue = append(queue, fixable[index])
+		}
+	}
+	return added
+}
+
+// printFixReport re-runs the fixed checks and shows what happened to every selected fix
+func printFixReport(ctx context.Context, engine *validators.DoctorEngine, outcomes []fixOutcome) {
+	var content strings.Builder
+	content.WriteString("\n")
+
+	var fixed, stillFailing, failed, skipped int
+	for _, outcome := range outcomes {
+		switch {
+		case outcome.blocker != "":
+			skipped++
+			content.WriteString(i18n.T("doctor.fix_all.report_skipped", outcome.name, outcome.blocker))
+		case outcome.err != nil:
+			failed++
+			content.WriteString(i18n.T("doctor.fix_all.report_failed", outcome.name, outcome.err))
+		default:
+			if result := engine.RunCheck(ctx, outcome.name); result.Status != validators.PASS {
+				stillFailing++
+				content.WriteString(i18n.T("doctor.fix_all.report_still_failing", outcome.name, result.Message))
+			} else {
+				fixed++
+				content.WriteString(i18n.T("doctor.fix_all.report_fixed", outcome.name))
+			}
+		}
+		content.WriteString("\n")
+	}
+	content.WriteString("\n")
+	content.WriteString(i18n.T("doctor.fix_all.report_totals", fixed, stillFailing, failed, skipped))
+	content.WriteString("\n")
+
+	color := charm.ActiveTheme().Success
+	if stillFailing > 0 || failed > 0 || skipped > 0 {
+		color = charm.ActiveTheme().Warning
+	}
+	fmt.Println(charm.RenderBox(i18n.T("doctor.fix_all.report_title"), content.String(), color, false))
+}
@@ -0,0 +1,74 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package doctor
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/0xjuanma/anvil/internal/validators"
+	"github.com/0xjuanma/palantir"
+)
+
+func TestPromptFixSelection(t *testing.T) {
+	issues := []*validators.ValidationResult{{Name: "homebrew"}, {Name: "git-config"}, {Name: "hosts-block"}}
+
+	tests := []struct {
+		input string
+		want  string
+		ok    bool
+	}{
+		{"1,3\n", "homebrew,hosts-block", true},
+		{"9\n2-3\n", "git-config,hosts-block", true},
+		{"all\n", "homebrew,git-config,hosts-block", true},
+		{"\n", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		picked, ok := promptFixSelection(bufio.NewReader(strings.NewReader(tt.input)), issues)
+		var names []string
+		for _, issue := range picked {
+			names = append(names, issue.Name)
+		}
+		if got := strings.Join(names, ","); got != tt.want || ok != tt.ok {
+			t.Errorf("promptFixSelection(%q) = %s, %v; want %s, %v", tt.input, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestAddPrerequisites(t *testing.T) {
+	engine := validators.NewDoctorEngine(palantir.GetGlobalOutputHandler())
+	fixable := []*validators.ValidationResult{{Name: "homebrew"}, {Name: "required-tools"}, {Name: "git-config"}, {Name: "directory-structure"}}
+
+	added := addPrerequisites(engine, []*validators.ValidationResult{fixable[2]}, fixable)
+	var names []string
+	for _, prerequisite := range added {
+		names = append(names, prerequisite.issue.Name)
+		if prerequisite.requiredBy == "" {
+			t.Errorf("%s added without the fix that needs it", prerequisite.issue.Name)
+		}
+	}
+	if got := strings.Join(names, ","); !strings.Contains(got, "required-tools") || !strings.Contains(got, "homebrew") || strings.Contains(got, "directory-structure") {
+		t.Errorf("addPrerequisites(git-config) = %s, want homebrew and required-tools only", got)
+	}
+
+	if added := addPrerequisites(engine, fixable[:2], fixable); len(added) != 0 {
+		t.Errorf("addPrerequisites added %d issues already selected", len(added))
+	}
+}
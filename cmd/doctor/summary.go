@@ -0,0 +1,61 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/validators"
+	"github.com/0xjuanma/palantir"
+	"github.com/spf13/cobra"
+)
+
+var summaryCmd = &cobra.Command{
+	Use:         "summary",
+	Short:       "Print a one-line cached health status for shell prompts",
+	Long:        constants.DOCTOR_SUMMARY_LONG_DESCRIPTION,
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{constants.SafeModeAnnotation: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		maxAge, _ := cmd.Flags().GetDuration("max-age")
+		engine := validators.NewDoctorEngine(palantir.GetGlobalOutputHandler())
+		fmt.Println(doctorSummary(engine, validators.ResultsCacheDir(), maxAge))
+	},
+}
+
+// doctorSummary returns the summary line of the cached results when they are at most maxAge
+// old. Otherwise the local checks run again, keeping the cached network results, and the
+// refreshed results replace the cache.
+func doctorSummary(engine *validators.DoctorEngine, cacheDir string, maxAge time.Duration) string {
+	cached, found := validators.LoadCachedResults(cacheDir)
+	if found && time.Since(cached.Time) <= maxAge {
+		return validators.SummaryLine(cached.Results)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	results := engine.RunOffline(ctx)
+	if found {
+		results = validators.WithNetworkResults(results, cached.Results)
+	}
+	validators.SaveCachedResults(cacheDir, results)
+	return validators.SummaryLine(results)
+}
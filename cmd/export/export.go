@@ -0,0 +1,102 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package export
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/0xjuanma/anvil/internal/brew"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/palantir"
+	"github.com/spf13/cobra"
+)
+
+// formatBrewfile is the only export format for now
+const formatBrewfile = "brewfile"
+
+var ExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export tracked apps as a Brewfile",
+	Long:  constants.EXPORT_COMMAND_LONG_DESCRIPTION,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runExportCommand(cmd); err != nil {
+			palantir.GetGlobalOutputHandler().PrintError("Export failed: %v", err)
+			os.Exit(errors.ExitCode(err))
+		}
+	},
+}
+
+// runExportCommand writes the tracked apps in the requested format
+func runExportCommand(cmd *cobra.Command) error {
+	format, _ := cmd.Flags().GetString("format")
+	outputPath, _ := cmd.Flags().GetString("output")
+	force, _ := cmd.Flags().GetBool("force")
+
+	if format != formatBrewfile {
+		return errors.NewValidationError(constants.OpExport, "format",
+			fmt.Errorf("unsupported format '%s', supported: %s", format, formatBrewfile))
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return errors.NewConfigurationError(constants.OpExport, "load-config", err)
+	}
+
+	data := brew.GenerateBrewfile(brewfileSections(cfg))
+	if outputPath == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+
+	if _, err := os.Stat(outputPath); err == nil && !force {
+		return errors.NewFileSystemError(constants.OpExport, "write",
+			fmt.Errorf("%s already exists, use --force to replace it", outputPath))
+	}
+	if err := os.WriteFile(outputPath, data, constants.FilePerm); err != nil {
+		return errors.NewFileSystemError(constants.OpExport, "write", err)
+	}
+
+	palantir.GetGlobalOutputHandler().PrintSuccess(fmt.Sprintf("Brewfile written to %s", outputPath))
+	return nil
+}
+
+// brewfileSections lists required_tools, each group by name and installed_apps as Brewfile sections
+func brewfileSections(cfg *config.AnvilConfig) []brew.BrewfileSection {
+	sections := []brew.BrewfileSection{{Title: "required_tools", Entries: cfg.Tools.RequiredTools}}
+
+	groupNames := make([]string, 0, len(cfg.Groups))
+	for name := range cfg.Groups {
+		groupNames = append(groupNames, name)
+	}
+	sort.Strings(groupNames)
+	for _, name := range groupNames {
+		sections = append(sections, brew.BrewfileSection{Title: "group: " + name, Entries: cfg.Groups[name]})
+	}
+
+	return append(sections, brew.BrewfileSection{Title: "installed_apps", Entries: cfg.Tools.InstalledApps})
+}
+
+func init() {
+	ExportCmd.Flags().String("format", formatBrewfile, "Export format (brewfile)")
+	ExportCmd.Flags().StringP("output", "o", "", "Write to this file instead of stdout")
+	ExportCmd.Flags().Bool("force", false, "Replace the output file if it exists")
+}
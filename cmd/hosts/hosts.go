@@ -0,0 +1,178 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hosts
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/0xjuanma/anvil/internal/audit"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/hosts"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+	"github.com/0xjuanma/palantir"
+	"github.com/spf13/cobra"
+)
+
+var HostsCmd = &cobra.Command{
+	Use:   "hosts",
+	Short: "Manage the anvil block of /etc/hosts entries",
+	Long:  constants.HOSTS_COMMAND_LONG_DESCRIPTION,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Write the hosts entries from settings.yaml into /etc/hosts",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		if _, err := ApplyHosts(dryRun); err != nil {
+			palantir.GetGlobalOutputHandler().PrintError("Hosts failed: %v", err)
+			os.Exit(errors.ExitCode(err))
+		}
+	},
+}
+
+var removeCmd = &cobra.Command{
+	Use:   "remove",
+	Short: "Remove the anvil block from /etc/hosts",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		if err := runRemoveCommand(dryRun); err != nil {
+			palantir.GetGlobalOutputHandler().PrintError("Hosts failed: %v", err)
+			os.Exit(errors.ExitCode(err))
+		}
+	},
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the anvil block and whether it matches settings.yaml",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runStatusCommand(); err != nil {
+			palantir.GetGlobalOutputHandler().PrintError("Hosts failed: %v", err)
+			os.Exit(errors.ExitCode(err))
+		}
+	},
+}
+
+// ApplyHosts writes the hosts entries from settings.yaml into the managed block, removing
+// the block when none are defined. It reports whether /etc/hosts changed, or would change
+// in a dry run.
+func ApplyHosts(dryRun bool) (bool, error) {
+	entries, err := config.GetHostsConfig()
+	if err != nil {
+		return false, errors.NewConfigurationError(constants.OpHosts, "load-settings", err)
+	}
+	return update(entries, dryRun)
+}
+
+// runRemoveCommand removes the managed block, leaving the hosts entries in settings.yaml
+func runRemoveCommand(dryRun bool) error {
+	changed, err := update(nil, dryRun)
+	if err == nil && changed && !dryRun {
+		palantir.GetGlobalOutputHandler().PrintInfo("The entries stay in %s, 'anvil hosts apply' restores them", constants.ANVIL_CONFIG_FILE)
+	}
+	return err
+}
+
+// update brings the managed block in line with entries after confirming the privileged write
+func update(entries []config.HostEntry, dryRun bool) (bool, error) {
+	o := palantir.GetGlobalOutputHandler()
+	path := hosts.Path()
+
+	current, next, err := hosts.Plan(entries)
+	if err != nil {
+		return false, errors.NewConfigurationError(constants.OpHosts, path, err)
+	}
+	if current == next {
+		o.PrintAlreadyAvailable("%s is already up to date", path)
+		return false, nil
+	}
+
+	action := "Remove the anvil block from"
+	if len(entries) > 0 {
+		action = "Write the hosts entries to"
+		for _, line := range strings.Split(strings.TrimSpace(hosts.Render(entries)), "\n") {
+			o.PrintInfo("  %s", line)
+		}
+	}
+	if dryRun {
+		o.PrintInfo("Dry run - would %s %s", strings.ToLower(action[:1])+action[1:], path)
+		audit.Record("hosts", "update-file", path, fmt.Sprintf("%d entries in the anvil block", len(entries)))
+		return true, nil
+	}
+
+	if !charm.Confirm(charm.ConfirmPrivileged, fmt.Sprintf("%s %s? This may ask for your password.", action, path)) {
+		o.PrintInfo("%s left unchanged", path)
+		return false, nil
+	}
+	if _, err := hosts.Apply(entries); err != nil {
+		return false, errors.NewFileSystemError(constants.OpHosts, path, err)
+	}
+	o.PrintSuccess(fmt.Sprintf("Updated %s", path))
+	return true, nil
+}
+
+// runStatusCommand prints the managed block and whether it matches settings.yaml
+func runStatusCommand() error {
+	o := palantir.GetGlobalOutputHandler()
+	entries, err := config.GetHostsConfig()
+	if err != nil {
+		return errors.NewConfigurationError(constants.OpHosts, "load-settings", err)
+	}
+	current, next, err := hosts.Plan(entries)
+	if err != nil {
+		return errors.NewConfigurationError(constants.OpHosts, hosts.Path(), err)
+	}
+
+	o.PrintHeader(fmt.Sprintf("Hosts: %s", hosts.Path()))
+	if block := hosts.Block(current); block != "" {
+		for _, line := range strings.Split(strings.TrimSpace(block), "\n") {
+			o.PrintInfo("  %s", line)
+		}
+	} else {
+		o.PrintInfo("No anvil block")
+	}
+	fmt.Println()
+
+	switch {
+	case current == next && len(entries) == 0:
+		o.PrintInfo("No hosts entries in %s", constants.ANVIL_CONFIG_FILE)
+	case current == next:
+		o.PrintSuccess(fmt.Sprintf("Matches the %d entries in %s", len(entries), constants.ANVIL_CONFIG_FILE))
+	default:
+		o.PrintWarning("Out of date with %s, run 'anvil hosts apply'", constants.ANVIL_CONFIG_FILE)
+	}
+	return nil
+}
+
+func init() {
+	HostsCmd.AddCommand(applyCmd)
+	HostsCmd.AddCommand(removeCmd)
+	HostsCmd.AddCommand(statusCmd)
+	applyCmd.Flags().Bool("dry-run", false, "Show the entries that would be written without changing /etc/hosts")
+	removeCmd.Flags().Bool("dry-run", false, "Show what would be removed without changing /etc/hosts")
+}
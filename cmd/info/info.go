@@ -0,0 +1,140 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package info
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/0xjuanma/anvil/internal/appinfo"
+	"github.com/0xjuanma/anvil/internal/brew"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/utils"
+	"github.com/0xjuanma/palantir"
+	"github.com/spf13/cobra"
+)
+
+// InfoCmd represents the info command
+var InfoCmd = &cobra.Command{
+	Use:         "info <app> [app...]",
+	Short:       "Show install status, groups and config mapping for apps",
+	Long:        constants.INFO_COMMAND_LONG_DESCRIPTION,
+	Annotations: map[string]string{constants.SafeModeAnnotation: "true"},
+	Args:        cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runInfoCommand(cmd, args); err != nil {
+			palantir.GetGlobalOutputHandler().PrintError("Info failed: %v", err)
+			os.Exit(errors.ExitCode(err))
+		}
+	},
+}
+
+// runInfoCommand looks up every requested app in one pass and prints the result
+func runInfoCommand(cmd *cobra.Command, args []string) error {
+	asJSON, _ := cmd.Flags().GetBool("json")
+	maxWorkers, _ := cmd.Flags().GetInt("workers")
+	metadata, _ := cmd.Flags().GetBool("metadata")
+	sizes, _ := cmd.Flags().GetBool("sizes")
+
+	anvilConfig, err := config.LoadConfig()
+	if err != nil {
+		return errors.NewConfigurationError(constants.OpInfo, "load-config", err)
+	}
+
+	results := appinfo.Collect(cmd.Context(), anvilConfig, args, maxWorkers)
+	if metadata || sizes {
+		appinfo.Enrich(results, brew.MetadataOptions{
+			CacheDir: filepath.Join(config.GetAnvilConfigDirectory(), "cache", "metadata"),
+			Workers:  maxWorkers,
+			Sizes:    sizes,
+		})
+	}
+
+	if asJSON {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return errors.NewValidationError(constants.OpInfo, "encode-json", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	printInfo(results)
+	return nil
+}
+
+// printInfo renders the results for humans
+func printInfo(results []appinfo.AppInfo) {
+	o := palantir.GetGlobalOutputHandler()
+	for _, info := range results {
+		status := "❌ not installed"
+		if info.Available {
+			status = "✅ installed"
+			if info.Version != "" {
+				status += " (" + info.Version + ")"
+			}
+		}
+
+		if info.Managed {
+			status += ", managed externally"
+		}
+
+		o.PrintHeader(info.Name)
+		o.PrintInfo("Status:   %s", status)
+		o.PrintInfo("Package:  %s [%s]", info.Package, info.Type)
+		if info.Description != "" {
+			o.PrintInfo("About:    %s", info.Description)
+		}
+		if info.License != "" {
+			o.PrintInfo("License:  %s", info.License)
+		}
+		if info.Homepage != "" {
+			o.PrintInfo("Homepage: %s", info.Homepage)
+		}
+		if info.Size > 0 {
+			o.PrintInfo("Download: %s", utils.FormatSize(info.Size))
+		}
+		o.PrintInfo("Groups:   %s", listOrNone(info.Groups))
+		o.PrintInfo("Tracked:  %s", listOrNone(info.TrackedIn))
+		if info.ConfigPath != "" {
+			o.PrintInfo("Config:   %s", info.ConfigPath)
+		}
+		if info.Source != "" {
+			o.PrintInfo("Source:   %s", info.Source)
+		}
+	}
+}
+
+// listOrNone joins values or returns "none" for an empty list
+func listOrNone(values []string) string {
+	if len(values) == 0 {
+		return "none"
+	}
+	return strings.Join(values, ", ")
+}
+
+func init() {
+	InfoCmd.Flags().Bool("json", false, "Print results as a JSON array")
+	InfoCmd.Flags().Int("workers", 0, "Number of concurrent lookups (default: number of CPU cores)")
+	InfoCmd.Flags().Bool("metadata", false, "Add descriptions, licenses and homepages from Homebrew, cached by package revision")
+	InfoCmd.Flags().Bool("sizes", false, "Add download sizes from the download servers (implies --metadata)")
+}
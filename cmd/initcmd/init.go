@@ -21,6 +21,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/0xjuanma/anvil/internal/audit"
 	"github.com/0xjuanma/anvil/internal/config"
 	"github.com/0xjuanma/anvil/internal/constants"
 	"github.com/0xjuanma/anvil/internal/errors"
@@ -36,21 +37,35 @@ var InitCmd = &cobra.Command{
 	Short: "Initialize Anvil CLI environment for macOS",
 	Long:  constants.INIT_COMMAND_LONG_DESCRIPTION,
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := runInitCommand(); err != nil {
+		if err := runInitCommand(cmd); err != nil {
 			palantir.GetGlobalOutputHandler().PrintError("Initialization failed: %v", err)
-			os.Exit(1)
+			os.Exit(errors.ExitCode(err))
 		}
 	},
 }
 
 // runInitCommand executes the complete initialization process for Anvil CLI on macOS
-func runInitCommand() error {
+func runInitCommand(cmd *cobra.Command) error {
+	configRepo, _ := cmd.Flags().GetString("config-repo")
+	branch, _ := cmd.Flags().GetString("branch")
+
 	// Display initialization banner
-	fmt.Println(charm.RenderBox("🔨 ANVIL INITIALIZATION", "", "#00D9FF", true))
+	fmt.Println(charm.RenderBox("🔨 ANVIL INITIALIZATION", "", charm.ActiveTheme().Accent, true))
 	fmt.Println()
 
 	o := palantir.GetGlobalOutputHandler()
 
+	if audit.IsEnabled() {
+		o.PrintInfo("Audit mode - would install required tools, create %s and generate %s", config.GetAnvilConfigDirectory(), constants.ANVIL_CONFIG_FILE)
+		audit.Record("init", "install-required-tools", "git", "")
+		audit.Record("init", "create-directories", config.GetAnvilConfigDirectory(), "")
+		audit.Record("init", "generate-settings", config.GetAnvilConfigPath(), "")
+		if configRepo != "" {
+			audit.Record("init", "set-config-repo", config.GetAnvilConfigPath(), configRepo)
+		}
+		return nil
+	}
+
 	// Stage 1: Tool validation and installation
 	o.PrintStage("Stage 1: Tool Validation")
 	spinner := charm.NewCircleSpinner("Validating and installing required tools")
@@ -81,6 +96,14 @@ func runInitCommand() error {
 	}
 	spinner.Success(fmt.Sprintf("Default %s generated", constants.ANVIL_CONFIG_FILE))
 
+	// Configure the config repository up front for unattended setups
+	if configRepo != "" {
+		if err := config.SetConfigRepository(configRepo, branch); err != nil {
+			return errors.NewConfigurationError(constants.OpInit, "set-config-repo", err)
+		}
+		o.PrintSuccess(fmt.Sprintf("Config repository set to %s", configRepo))
+	}
+
 	// Stage 4: Check local environment configurations
 	o.PrintStage("Stage 4: Environment Check")
 	spinner = charm.NewLineSpinner("Checking local environment configurations")
@@ -119,11 +142,13 @@ func runInitCommand() error {
 	o.PrintInfo("  • Edit %s/%s to customize your configuration", config.GetAnvilConfigDirectory(), constants.ANVIL_CONFIG_FILE)
 
 	// GitHub configuration warning
-	o.PrintWarning("Configuration Management Setup Required:")
-	o.PrintInfo("  • Edit the 'github.config_repo' field in %s to enable config pull/push", constants.ANVIL_CONFIG_FILE)
-	o.PrintInfo("  • Example: 'github.config_repo: username/dotfiles'")
-	o.PrintInfo("  • Set GITHUB_TOKEN environment variable for authentication")
-	o.PrintInfo("  • Run 'anvil doctor' once added to validate configuration")
+	if configRepo == "" {
+		o.PrintWarning("Configuration Management Setup Required:")
+		o.PrintInfo("  • Edit the 'github.config_repo' field in %s to enable config pull/push", constants.ANVIL_CONFIG_FILE)
+		o.PrintInfo("  • Example: 'github.config_repo: username/dotfiles'")
+		o.PrintInfo("  • Set GITHUB_TOKEN environment variable for authentication")
+		o.PrintInfo("  • Run 'anvil doctor' once added to validate configuration")
+	}
 
 	// Show available groups dynamically
 	if groups, err := config.GetAvailableGroups(); err == nil {
@@ -144,4 +169,6 @@ func runInitCommand() error {
 func init() {
 	// Add flags for additional functionality
 	InitCmd.Flags().Bool("skip-tools", false, "Skip tool validation and installation")
+	InitCmd.Flags().String("config-repo", "", "GitHub repository for config pull/push (e.g. username/dotfiles)")
+	InitCmd.Flags().String("branch", "", "Branch of the config repository (default: main)")
 }
@@ -0,0 +1,78 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package install
+
+import (
+	"fmt"
+
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/i18n"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+	"github.com/0xjuanma/palantir"
+)
+
+// groupStop records why a group install stopped before every tool was attempted
+type groupStop struct {
+	tool         string // Tool whose failure stopped the group
+	reason       string
+	notAttempted int
+}
+
+// resolveFailurePolicy returns the --on-failure policy when given, otherwise the group's configured policy
+func resolveFailurePolicy(groupName, flagPolicy string) (string, error) {
+	if flagPolicy == "" {
+		return config.GetFailurePolicy(groupName), nil
+	}
+	if err := config.ValidateFailurePolicy(flagPolicy); err != nil {
+		return "", fmt.Errorf("--on-failure: %w", err)
+	}
+	return flagPolicy, nil
+}
+
+// stopAfterFailure decides whether a group install stops after a tool failed. A critical tag
+// on the tool wins over the group policy, critical: false never stops the group.
+func stopAfterFailure(groupName, policy, tool string) (stop bool, reason string) {
+	if critical, set := config.GetToolCritical(groupName, tool); set {
+		if critical {
+			return true, i18n.T("install.failure.critical", tool)
+		}
+		return false, ""
+	}
+
+	switch policy {
+	case config.FailurePolicyFailFast:
+		return true, i18n.T("install.failure.fail_fast", groupName)
+	case config.FailurePolicyPrompt:
+		if !charm.Confirm(charm.ConfirmInstall, i18n.T("install.failure.prompt", tool)) {
+			return true, i18n.T("install.failure.declined")
+		}
+	}
+	return false, ""
+}
+
+// reportGroupStop prints why a group install stopped and how many tools were left out
+func reportGroupStop(stop *groupStop) {
+	if stop == nil {
+		return
+	}
+
+	o := palantir.GetGlobalOutputHandler()
+	o.PrintWarning(i18n.T("install.summary.stopped"), stop.tool, stop.reason)
+	if stop.notAttempted > 0 {
+		o.PrintWarning(i18n.T("install.summary.not_attempted"), stop.notAttempted)
+	}
+}
@@ -0,0 +1,60 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package install
+
+import (
+	"github.com/0xjuanma/anvil/internal/i18n"
+	"github.com/0xjuanma/anvil/internal/installer"
+	"github.com/0xjuanma/palantir"
+)
+
+// runFirstRuns runs the configured first-run steps for newly installed tools
+func runFirstRuns(tools []string, dryRun bool) []installer.FirstRunResult {
+	o := palantir.GetGlobalOutputHandler()
+
+	var results []installer.FirstRunResult
+	for _, tool := range tools {
+		steps, ok := installer.FirstRunSteps(tool)
+		if !ok {
+			continue
+		}
+		if dryRun {
+			o.PrintInfo(i18n.T("install.first_run.would_run"), tool)
+			continue
+		}
+		o.PrintStage(i18n.T("install.first_run.running", tool))
+		results = append(results, installer.RunFirstRun(tool, steps))
+	}
+	return results
+}
+
+// reportFirstRuns prints the outcome of first-run steps as part of the install summary
+func reportFirstRuns(results []installer.FirstRunResult) {
+	if len(results) == 0 {
+		return
+	}
+
+	o := palantir.GetGlobalOutputHandler()
+	o.PrintInfo(i18n.T("install.first_run.title"))
+	for _, result := range results {
+		if result.OK() {
+			o.PrintSuccess(i18n.T("install.first_run.verified", result.App, result.Details()))
+		} else {
+			o.PrintWarning(i18n.T("install.first_run.problems"), result.App, result.Details())
+		}
+	}
+}
@@ -0,0 +1,315 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package install
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/0xjuanma/anvil/internal/audit"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/pkgmanager"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+	"github.com/0xjuanma/palantir"
+	"github.com/spf13/cobra"
+)
+
+// fileEntry is an app read from a --from-file list, with its optional group column
+type fileEntry struct {
+	Name  string
+	Group string
+}
+
+// fileHeaderNames are first-row values treated as a CSV header rather than an app
+var fileHeaderNames = []string{"name", "app", "tool", "package"}
+
+// parseAppListFile reads app names from a plain text file (one per line) or a CSV file
+// with an optional second group column. Blank lines and '#' comments are ignored and
+// duplicates, compared case-insensitively, are dropped.
+func parseAppListFile(path string) ([]fileEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return parseAppList(file, strings.EqualFold(filepath.Ext(path), ".csv"))
+}
+
+// parseAppList parses an app list from r, as CSV when isCSV is set
+func parseAppList(r io.Reader, isCSV bool) ([]fileEntry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	if isCSV {
+		reader := csv.NewReader(strings.NewReader(string(data)))
+		reader.FieldsPerRecord = -1
+		reader.Comment = '#'
+		reader.TrimLeadingSpace = true
+		if rows, err = reader.ReadAll(); err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+	} else {
+		for _, line := range strings.Split(string(data), "\n") {
+			if before, _, found := strings.Cut(line, "#"); found {
+				line = before
+			}
+			rows = append(rows, []string{line})
+		}
+	}
+
+	seen := make(map[string]bool)
+	var entries []fileEntry
+	for i, row := range rows {
+		name := strings.TrimSpace(row[0])
+		if name == "" {
+			continue
+		}
+		if i == 0 && isCSV && containsFold(fileHeaderNames, name) {
+			continue
+		}
+
+		key := config.NormalizeAppName(name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		entry := fileEntry{Name: name}
+		if len(row) > 1 {
+			entry.Group = strings.TrimSpace(row[1])
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// containsFold reports whether values holds s, ignoring case
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// filePlan is the validated result of an app list
+type filePlan struct {
+	toInstall []fileEntry
+	installed []fileEntry
+	invalid   []string
+}
+
+// buildFilePlan validates names and checks the package manager and the system concurrently
+func buildFilePlan(entries []fileEntry, manager pkgmanager.Manager) *filePlan {
+	validator := config.NewConfigValidator(nil)
+
+	type check struct {
+		valid     bool
+		available bool
+		reason    string
+	}
+	checks := make([]check, len(entries))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, runtime.NumCPU())
+	for i, entry := range entries {
+		if err := validator.ValidateAppName(entry.Name); err != nil {
+			checks[i] = check{reason: err.Error()}
+			continue
+		}
+		if config.IsManagedExternally(entry.Name) {
+			checks[i] = check{reason: "managed externally"}
+			continue
+		}
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			if pkgmanager.IsAvailable(name) {
+				checks[i] = check{valid: true, available: true}
+				return
+			}
+			if manager.Exists(name) {
+				checks[i] = check{valid: true}
+				return
+			}
+			checks[i] = check{reason: fmt.Sprintf("not found in %s, try '%s'", manager.Name(), manager.SearchCommand(name))}
+		}(i, entry.Name)
+	}
+	wg.Wait()
+
+	plan := &filePlan{}
+	for i, entry := range entries {
+		switch {
+		case !checks[i].valid:
+			plan.invalid = append(plan.invalid, fmt.Sprintf("%s: %s", entry.Name, checks[i].reason))
+		case checks[i].available:
+			plan.installed = append(plan.installed, entry)
+		default:
+			plan.toInstall = append(plan.toInstall, entry)
+		}
+	}
+	return plan
+}
+
+// describeEntries renders entries with their target group for the preview
+func describeEntries(entries []fileEntry, defaultGroup string) []string {
+	lines := make([]string, len(entries))
+	for i, entry := range entries {
+		lines[i] = entry.Name
+		if group := entryGroup(entry, defaultGroup); group != "" {
+			lines[i] += fmt.Sprintf(" → %s", group)
+		}
+	}
+	return lines
+}
+
+// entryGroup returns the group an entry is added to: --group-name wins over the file column
+func entryGroup(entry fileEntry, defaultGroup string) string {
+	if defaultGroup != "" {
+		return defaultGroup
+	}
+	return entry.Group
+}
+
+// installFromFile installs every app listed in a text or CSV file after previewing the plan
+func installFromFile(cmd *cobra.Command, path string, dryRun bool) error {
+	o := palantir.GetGlobalOutputHandler()
+	groupName, _ := cmd.Flags().GetString("group-name")
+
+	manager, err := pkgmanager.Current()
+	if err != nil {
+		return errors.NewConfigurationError(constants.OpInstall, "package-manager", err)
+	}
+	if err := manager.Ensure(); err != nil {
+		return fmt.Errorf("install: %w", err)
+	}
+	if manager.Name() == pkgmanager.Brew {
+		update, _ := cmd.Flags().GetBool("update")
+		updateBrewBeforeInstall(update, dryRun)
+	}
+
+	entries, err := parseAppListFile(path)
+	if err != nil {
+		return errors.NewFileSystemError(constants.OpInstall, "read-app-list", err)
+	}
+	if len(entries) == 0 {
+		return errors.NewValidationError(constants.OpInstall, "read-app-list", fmt.Errorf("no app names found in %s", path))
+	}
+
+	if groupName != "" {
+		if err := config.NewConfigValidator(nil).ValidateGroupName(groupName); err != nil {
+			return errors.NewValidationError(constants.OpInstall, "group-name", err)
+		}
+	}
+
+	spinner := charm.NewDotsSpinner(fmt.Sprintf("Checking %d apps against %s", len(entries), manager.Name()))
+	spinner.Start()
+	plan := buildFilePlan(entries, manager)
+	spinner.Success(fmt.Sprintf("Checked %d apps from %s", len(entries), filepath.Base(path)))
+
+	var preview strings.Builder
+	writeSection := func(title string, lines []string) {
+		if len(lines) == 0 {
+			return
+		}
+		preview.WriteString(fmt.Sprintf("%s (%d)\n", title, len(lines)))
+		for _, line := range lines {
+			preview.WriteString("  • " + line + "\n")
+		}
+		preview.WriteString("\n")
+	}
+	writeSection("To install", describeEntries(plan.toInstall, groupName))
+	writeSection("Already installed", describeEntries(plan.installed, groupName))
+	writeSection("Skipped", plan.invalid)
+	fmt.Println(charm.RenderBox(fmt.Sprintf("Install plan: %s", filepath.Base(path)), strings.TrimRight(preview.String(), "\n"), charm.ActiveTheme().Accent, false))
+
+	if len(plan.toInstall) == 0 && len(plan.installed) == 0 {
+		return errors.NewValidationError(constants.OpInstall, "read-app-list", fmt.Errorf("none of the %d names in %s are installable", len(entries), path))
+	}
+
+	if dryRun {
+		for _, entry := range plan.toInstall {
+			audit.Record("install", "install-package", entry.Name, entryGroup(entry, groupName))
+		}
+		o.PrintInfo("Dry run mode - nothing was installed")
+		return nil
+	}
+
+	if len(plan.toInstall) > 0 && !charm.Confirm(charm.ConfirmInstall, fmt.Sprintf("Install %d apps?", len(plan.toInstall))) {
+		o.PrintInfo("Installation cancelled by user")
+		return nil
+	}
+
+	defer migrateRenamedPackages()
+
+	var failed []string
+	for i, entry := range plan.toInstall {
+		o.PrintStage(fmt.Sprintf("[%d/%d] Installing %s", i+1, len(plan.toInstall), entry.Name))
+		if _, err := installSingleToolUnified(entry.Name, false); err != nil {
+			o.PrintError("%s: %v", entry.Name, err)
+			failed = append(failed, entry.Name)
+			continue
+		}
+		trackFileEntry(entry, groupName)
+	}
+
+	// Apps that were already present still join the group so it reflects the whole file
+	for _, entry := range plan.installed {
+		if entryGroup(entry, groupName) != "" {
+			trackFileEntry(entry, groupName)
+		}
+	}
+
+	if len(failed) > 0 {
+		return errors.NewInstallationError(constants.OpInstall, filepath.Base(path),
+			fmt.Errorf("%d of %d apps failed: %s", len(failed), len(plan.toInstall), strings.Join(failed, ", ")))
+	}
+
+	o.PrintSuccess(fmt.Sprintf("Installed %d apps from %s", len(plan.toInstall), filepath.Base(path)))
+	return nil
+}
+
+// trackFileEntry records an app in its group, or in installed_apps when it has none
+func trackFileEntry(entry fileEntry, defaultGroup string) {
+	group := entryGroup(entry, defaultGroup)
+	if group == "" {
+		trackAppInSettings(entry.Name)
+		return
+	}
+
+	if err := config.AddAppToGroup(group, entry.Name); err != nil {
+		palantir.GetGlobalOutputHandler().PrintWarning("Failed to add %s to group '%s': %v", entry.Name, group, err)
+		trackAppInSettings(entry.Name)
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package install
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/0xjuanma/anvil/internal/audit"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/events"
+	"github.com/0xjuanma/anvil/internal/i18n"
+	"github.com/0xjuanma/anvil/internal/installer"
+	"github.com/0xjuanma/anvil/internal/pkgmanager"
+	"github.com/0xjuanma/anvil/internal/session"
+	"github.com/0xjuanma/palantir"
+	"github.com/spf13/cobra"
+)
+
+// installFromURL installs an app that isn't available from the package manager by
+// downloading it from sourceURL. The URL is saved under sources, so later installs and
+// provisioning download it again, and the app is tracked like any other install.
+func installFromURL(cmd *cobra.Command, appName, sourceURL string, dryRun bool) error {
+	o := palantir.GetGlobalOutputHandler()
+
+	appName = strings.TrimSpace(appName)
+	sourceURL = strings.TrimSpace(sourceURL)
+	if appName == "" {
+		return errors.NewValidationError(constants.OpInstall, "from-url", fmt.Errorf("%s", i18n.T("install.app.empty")))
+	}
+	if err := config.ValidateSourceURL(sourceURL); err != nil {
+		return errors.NewValidationError(constants.OpInstall, "from-url", err)
+	}
+	if _, err := config.GetGroupTools(appName); err == nil {
+		return errors.NewValidationError(constants.OpInstall, "from-url",
+			fmt.Errorf("'%s' is a group, --from-url installs a single app", appName))
+	}
+
+	o.PrintHeader(i18n.T("install.app.title", appName))
+
+	// Apps managed by another tool, such as an MDM, are left alone
+	if config.IsManagedExternally(appName) {
+		o.PrintInfo(i18n.T("install.managed.skipped"), appName)
+		return nil
+	}
+	if pkgmanager.IsAvailable(appName) {
+		o.PrintAlreadyAvailable(i18n.T("install.tool.available"), appName)
+		return nil
+	}
+
+	if dryRun {
+		o.PrintInfo(i18n.T("install.url.would_download"), appName, sourceURL)
+		o.PrintInfo(i18n.T("install.url.would_save"), constants.ANVIL_CONFIG_FILE)
+		audit.Record("install", "install-source", appName, sourceURL)
+		return nil
+	}
+
+	// Recorded sessions keep the duration and error like any other install
+	err := session.Track(appName, func() error {
+		return installer.InstallWithHooks(appName, func(phase, line string) {
+			o.PrintInfo("  %s │ %s", phase, line)
+		}, func(hookErr error) {
+			o.PrintWarning(i18n.T("install.hooks.failed"), hookErr)
+		}, func() error {
+			return installer.InstallFromSource(appName, sourceURL)
+		})
+	})
+	if err != nil {
+		events.Publish(events.Failed, appName, "")
+		return errors.NewInstallationError(constants.OpInstall, appName, err)
+	}
+	o.PrintSuccess(i18n.T("install.tool.installed", appName))
+	events.Publish(events.Installed, appName, "")
+
+	if err := config.SetAppSource(appName, sourceURL); err != nil {
+		o.PrintWarning(i18n.T("install.url.save_failed"), appName, constants.ANVIL_CONFIG_FILE, err)
+	} else {
+		o.PrintInfo(i18n.T("install.url.saved"), appName, constants.ANVIL_CONFIG_FILE)
+	}
+
+	reportFirstRuns(runFirstRuns([]string{appName}, false))
+	return trackInstalledApp(cmd, appName)
+}
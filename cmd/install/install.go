@@ -18,25 +18,37 @@ package install
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/0xjuanma/anvil/internal/audit"
 	"github.com/0xjuanma/anvil/internal/brew"
 	"github.com/0xjuanma/anvil/internal/config"
 	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/ecosystem"
 	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/events"
+	"github.com/0xjuanma/anvil/internal/i18n"
 	"github.com/0xjuanma/anvil/internal/installer"
+	"github.com/0xjuanma/anvil/internal/pkgmanager"
+	"github.com/0xjuanma/anvil/internal/session"
+	"github.com/0xjuanma/anvil/internal/system"
 	"github.com/0xjuanma/anvil/internal/terminal/charm"
 	"github.com/0xjuanma/anvil/internal/tools"
 	"github.com/0xjuanma/anvil/internal/utils"
 	"github.com/0xjuanma/palantir"
+	"github.com/charmbracelet/x/ansi"
 	"github.com/spf13/cobra"
 )
 
 // InstallCmd represents the install command
 var InstallCmd = &cobra.Command{
-	Use:   "install [group-name|app-name] [--group-name group]",
+	Use:   "install [group-name|app-name] [--group-name group] [--from-file path] [--from-url url]",
 	Short: "Install development tools and applications dynamically via Homebrew",
 	Long:  constants.INSTALL_COMMAND_LONG_DESCRIPTION,
 	Args: func(cmd *cobra.Command, args []string) error {
@@ -46,6 +58,10 @@ var InstallCmd = &cobra.Command{
 		if listFlag || treeFlag {
 			return nil
 		}
+		// The app list comes from the file instead of an argument
+		if fromFile, _ := cmd.Flags().GetString("from-file"); fromFile != "" {
+			return cobra.NoArgs(cmd, args)
+		}
 		// Otherwise, require exactly one argument
 		return cobra.ExactArgs(1)(cmd, args)
 	},
@@ -58,33 +74,68 @@ var InstallCmd = &cobra.Command{
 			// Load and prepare data once
 			groups, builtInGroupNames, customGroupNames, installedApps, err := tools.LoadAndPrepareAppData()
 			if err != nil {
-				palantir.GetGlobalOutputHandler().PrintError("Failed to load application data: %v", err)
+				palantir.GetGlobalOutputHandler().PrintError(i18n.T("install.load_failed"), err)
 				return
 			}
 
 			// Choose rendering based on flag
 			var content string
-			var title = "Available Applications"
+			var title = i18n.T("install.view.title")
 			if treeFlag {
 				content = renderTreeView(groups, builtInGroupNames, customGroupNames, installedApps)
-				title = fmt.Sprintf("%s (Tree View)", title)
+				title = i18n.T("install.view.tree", title)
 			} else {
 				content = renderListView(groups, builtInGroupNames, customGroupNames, installedApps)
-				title = fmt.Sprintf("%s (List View)", title)
+				title = i18n.T("install.view.list", title)
 			}
 
 			// Display in box
-			fmt.Println(charm.RenderBox(title, content, "#00D9FF", false))
+			fmt.Println(charm.RenderBox(title, content, charm.ActiveTheme().Accent, false))
 			return
 		}
 
-		if err := runInstallCommand(cmd, args[0]); err != nil {
-			palantir.GetGlobalOutputHandler().PrintError("Install failed: %v", err)
-			return
+		if record, _ := cmd.Flags().GetBool("record"); record {
+			session.Start(RecordedInvocation())
+		}
+
+		var err error
+		if fromFile, _ := cmd.Flags().GetString("from-file"); fromFile != "" {
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			err = installFromFile(cmd, fromFile, dryRun)
+		} else if fromURL, _ := cmd.Flags().GetString("from-url"); fromURL != "" {
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			err = installFromURL(cmd, args[0], fromURL, dryRun)
+		} else {
+			err = runInstallCommand(cmd, args[0])
+		}
+		if err != nil {
+			palantir.GetGlobalOutputHandler().PrintError(i18n.T("install.failed"), err)
 		}
+		FinishRecording(err)
 	},
 }
 
+// RecordedInvocation returns the command line stored with a recorded session
+func RecordedInvocation() string {
+	return strings.TrimSpace(constants.ANVIL + " " + strings.Join(os.Args[1:], " "))
+}
+
+// FinishRecording writes the session transcript when --record was used and prints where it is
+func FinishRecording(runErr error) {
+	if !session.IsRecording() {
+		return
+	}
+
+	o := palantir.GetGlobalOutputHandler()
+	path, err := session.Finish(runErr)
+	if err != nil {
+		o.PrintWarning(i18n.T("install.session.save_failed"), err)
+		return
+	}
+	o.PrintInfo(i18n.T("install.session.recorded"), path)
+	o.PrintInfo(i18n.T("install.session.review"), strings.TrimSuffix(filepath.Base(path), ".json"))
+}
+
 // runInstallCommand executes the dynamic install process
 func runInstallCommand(cmd *cobra.Command, target string) error {
 	o := palantir.GetGlobalOutputHandler()
@@ -92,7 +143,7 @@ func runInstallCommand(cmd *cobra.Command, target string) error {
 	// Check for dry-run flag
 	dryRun, _ := cmd.Flags().GetBool("dry-run")
 	if dryRun {
-		o.PrintInfo("Dry run mode - no actual installations will be performed")
+		o.PrintInfo(i18n.T("install.dry_run"))
 	}
 
 	// Check for concurrent flag
@@ -100,14 +151,44 @@ func runInstallCommand(cmd *cobra.Command, target string) error {
 	maxWorkers, _ := cmd.Flags().GetInt("workers")
 	timeout, _ := cmd.Flags().GetDuration("timeout")
 
-	// Ensure Homebrew is installed
-	if err := brew.EnsureBrewIsInstalled(); err != nil {
+	return installTarget(cmd, target, dryRun, concurrent, maxWorkers, timeout)
+}
+
+// InstallTarget installs a group or an individual app, used by provisioning
+func InstallTarget(target string, dryRun bool) error {
+	return installTarget(InstallCmd, target, dryRun, false, 0, 0)
+}
+
+// installTarget installs a group if the target names one, otherwise an individual app
+func installTarget(cmd *cobra.Command, target string, dryRun, concurrent bool, maxWorkers int, timeout time.Duration) error {
+	// Ensure the package manager is ready, Homebrew is installed when missing
+	manager, err := pkgmanager.Current()
+	if err != nil {
+		return errors.NewConfigurationError(constants.OpInstall, "package-manager", err)
+	}
+	if err := manager.Ensure(); err != nil {
 		return fmt.Errorf("install: %w", err)
 	}
+	if manager.Name() == pkgmanager.Brew {
+		update, _ := cmd.Flags().GetBool("update")
+		updateBrewBeforeInstall(update, dryRun)
+	}
+
+	// Offer to migrate entries Homebrew reported as renamed once installs are done
+	defer migrateRenamedPackages()
 
 	// Try to get group tools first
 	if tools, err := config.GetGroupTools(target); err == nil {
-		return installGroup(target, tools, dryRun, concurrent, maxWorkers, timeout)
+		onFailure, _ := cmd.Flags().GetString("on-failure")
+		policy, err := resolveFailurePolicy(target, onFailure)
+		if err != nil {
+			return errors.NewValidationError(constants.OpInstall, target, err)
+		}
+		installErr := installGroup(target, tools, dryRun, concurrent, maxWorkers, timeout, policy)
+		if noCleanup, _ := cmd.Flags().GetBool("no-cleanup"); !noCleanup && manager.Name() == pkgmanager.Brew {
+			runBrewCleanup(dryRun)
+		}
+		return installErr
 	}
 
 	// If not a group, treat as individual application
@@ -115,30 +196,142 @@ func runInstallCommand(cmd *cobra.Command, target string) error {
 }
 
 // installGroup installs all tools in a group
-func installGroup(groupName string, tools []string, dryRun bool, concurrent bool, maxWorkers int, timeout time.Duration) error {
+func installGroup(groupName string, tools []string, dryRun bool, concurrent bool, maxWorkers int, timeout time.Duration, policy string) error {
 	o := palantir.GetGlobalOutputHandler()
-	o.PrintHeader(fmt.Sprintf("Installing '%s' group", groupName))
+	o.PrintHeader(i18n.T("install.group.title", groupName))
 
 	if len(tools) == 0 {
 		return errors.NewInstallationError(constants.OpInstall, groupName,
-			fmt.Errorf("group '%s' has no tools defined", groupName))
+			fmt.Errorf(i18n.T("install.group.empty"), groupName))
 	}
 
 	// Deduplicate tools within the group and update settings if needed
 	deduplicatedTools, err := deduplicateGroupTools(groupName, tools)
 	if err != nil {
-		o.PrintWarning("Failed to deduplicate group tools: %v", err)
+		o.PrintWarning(i18n.T("install.group.dedupe_failed"), err)
 	} else {
 		tools = deduplicatedTools
 	}
 
-	o.PrintInfo("Installing %d tools: %s", len(tools), strings.Join(tools, ", "))
+	// Order and after tags decide which tools install first
+	order, err := config.GetGroupOrder(groupName, tools)
+	if err != nil {
+		return errors.NewValidationError(constants.OpInstall, groupName, err)
+	}
+	tools = order.Tools
+
+	o.PrintInfo(i18n.T("install.group.installing"), len(tools), strings.Join(tools, ", "))
 
+	var installErr error
 	if concurrent {
-		return installGroupConcurrent(groupName, tools, dryRun, maxWorkers, timeout)
+		installErr = installGroupConcurrent(groupName, tools, order, dryRun, maxWorkers, timeout, policy)
+	} else {
+		installErr = installGroupSerial(groupName, tools, order.After, dryRun, policy)
 	}
 
-	return installGroupSerial(groupName, tools, dryRun)
+	// Later syncs offer the tools added to the group after this install
+	if !dryRun {
+		if err := config.RecordInstalledGroup(groupName, tools); err != nil {
+			o.PrintWarning(i18n.T("install.group.record_failed"), groupName, constants.GROUPS_LOCK_FILE, err)
+		}
+	}
+	return installErr
+}
+
+// brewUpdateChecked makes runs that install several targets, such as provisioning, decide once
+var brewUpdateChecked sync.Once
+
+// updateBrewBeforeInstall runs 'brew update' once before anything installs, when --update is
+// set or 'brew.auto_update' is on and the last update is over a day old. A failed update is
+// reported and the install goes on with the current formulae.
+func updateBrewBeforeInstall(requested, dryRun bool) {
+	brewUpdateChecked.Do(func() { updateBrew(requested, dryRun) })
+}
+
+// updateOnce runs the shared Homebrew update, replaced in tests
+var updateOnce = brew.UpdateOnce
+
+// updateBrew runs the update decided by updateBrewBeforeInstall
+func updateBrew(requested, dryRun bool) {
+	if !requested {
+		brewConfig, err := config.GetBrewConfig()
+		if err != nil || !brewConfig.AutoUpdate || time.Since(config.LastBrewUpdate()) < config.BrewAutoUpdateInterval {
+			return
+		}
+	}
+
+	o := palantir.GetGlobalOutputHandler()
+	if dryRun {
+		o.PrintInfo(i18n.T("install.update.dry_run"))
+		audit.Record("install", "brew-update", constants.BrewCommand, "")
+		return
+	}
+	if err := updateOnce(); err != nil {
+		o.PrintWarning(i18n.T("install.update.failed"), err)
+		return
+	}
+	if err := config.RecordBrewUpdate(time.Now()); err != nil {
+		o.PrintWarning(i18n.T("install.update.record_failed"), err)
+	}
+}
+
+// runBrewCleanup reclaims Homebrew cache space after a group install when 'brew.cleanup' is enabled.
+// Cleanup failures are reported but never fail the install.
+func runBrewCleanup(dryRun bool) {
+	brewConfig, err := config.GetBrewConfig()
+	if err != nil || !brewConfig.Cleanup {
+		return
+	}
+
+	o := palantir.GetGlobalOutputHandler()
+	if dryRun {
+		o.PrintInfo(i18n.T("install.cleanup.dry_run"))
+		audit.Record("install", "brew-cleanup", constants.BrewCommand, fmt.Sprintf("prune=%d", brewConfig.CleanupPruneDays))
+		return
+	}
+
+	spinner := charm.NewDotsSpinner(i18n.T("install.cleanup.running"))
+	spinner.Start()
+	freed, err := brew.Cleanup(brewConfig.CleanupPruneDays)
+	if err != nil {
+		spinner.Warning(i18n.T("install.cleanup.failed", err))
+		return
+	}
+	if freed == "" {
+		spinner.Success(i18n.T("install.cleanup.clean"))
+		return
+	}
+	spinner.Success(i18n.T("install.cleanup.reclaimed", freed))
+}
+
+// migrateRenamedPackages reports packages brew warned about during installs and, with consent,
+// updates settings.yaml entries that still use a renamed package's old name
+func migrateRenamedPackages() {
+	notices := brew.TakeInstallNotices()
+	if len(notices) == 0 {
+		return
+	}
+
+	o := palantir.GetGlobalOutputHandler()
+	for _, notice := range notices {
+		if notice.Deprecated || notice.Disabled {
+			o.PrintWarning(i18n.T("install.renames.notice"), notice.Describe())
+		}
+		if notice.RenamedTo == "" {
+			continue
+		}
+
+		if !charm.Confirm(charm.ConfirmSettings, i18n.T("install.renames.confirm", notice.Name, notice.RenamedTo, constants.ANVIL_CONFIG_FILE)) {
+			o.PrintInfo(i18n.T("install.renames.kept"), notice.Name)
+			continue
+		}
+		count, err := config.RenameAppEntries(notice.Name, notice.RenamedTo)
+		if err != nil {
+			o.PrintWarning(i18n.T("install.renames.failed"), notice.Name, constants.ANVIL_CONFIG_FILE, err)
+			continue
+		}
+		o.PrintSuccess(i18n.T("install.renames.done", count, notice.Name, notice.RenamedTo))
+	}
 }
 
 // deduplicateGroupTools removes duplicate tools within a group and updates the settings file
@@ -163,58 +356,113 @@ func deduplicateGroupTools(groupName string, tools []string) ([]string, error) {
 	}
 
 	o := palantir.GetGlobalOutputHandler()
-	o.PrintWarning("Found duplicates in group '%s': %s", groupName, strings.Join(duplicatesFound, ", "))
-	o.PrintInfo("Removing duplicates from settings file...")
+	o.PrintWarning(i18n.T("install.duplicates.found"), groupName, strings.Join(duplicatesFound, ", "))
+	o.PrintInfo(i18n.T("install.duplicates.removing"))
 
 	// Update the configuration with deduplicated tools
 	if err := config.UpdateGroupTools(groupName, deduplicatedTools); err != nil {
 		return tools, fmt.Errorf("failed to update group with deduplicated tools: %w", err)
 	}
 
-	o.PrintSuccess(fmt.Sprintf("Successfully removed %d duplicate(s) from group '%s'", len(duplicatesFound), groupName))
+	o.PrintSuccess(i18n.T("install.duplicates.removed", len(duplicatesFound), groupName))
 	return deduplicatedTools, nil
 }
 
-// installGroupConcurrent installs tools concurrently
-func installGroupConcurrent(groupName string, tools []string, dryRun bool, maxWorkers int, timeout time.Duration) error {
+// installGroupConcurrent installs tools concurrently, starting each once the tools it is ordered after finished
+func installGroupConcurrent(groupName string, tools []string, order *config.GroupOrder, dryRun bool, maxWorkers int, timeout time.Duration, policy string) error {
 	o := palantir.GetGlobalOutputHandler()
 
 	// Create new output handler to send into concurrent installer
 	outputHandler := palantir.NewDefaultOutputHandler()
 	concurrentInstaller := installer.NewConcurrentInstaller(maxWorkers, outputHandler, dryRun)
+	concurrentInstaller.SetOrder(order.WaitFor, order.After)
 
 	if timeout > 0 {
 		concurrentInstaller.SetTimeout(timeout)
 	}
 
+	// The installer calls the handler one failure at a time, so prompts never overlap
+	var stop *groupStop
+	concurrentInstaller.SetFailureHandler(func(tool string, err error) bool {
+		if halt, reason := stopAfterFailure(groupName, policy, tool); halt {
+			stop = &groupStop{tool: tool, reason: reason}
+			return true
+		}
+		return false
+	})
+
+	// Tools tagged for other platforms or managed externally never reach the workers
+	var supported []string
+	for _, tool := range tools {
+		if config.IsManagedExternally(tool) {
+			o.PrintInfo(i18n.T("install.managed.skipped"), tool)
+		} else if config.IsToolSupported(tool) {
+			supported = append(supported, tool)
+		} else {
+			o.PrintInfo(i18n.T("install.platform.skipped"), tool, strings.Join(config.GetToolPlatforms(tool), ", "))
+		}
+	}
+
 	// Create context with potential cancellation
 	ctx := context.Background()
-	stats, err := concurrentInstaller.InstallTools(ctx, tools)
+	stats, err := concurrentInstaller.InstallTools(ctx, supported)
+
+	if stats != nil {
+		// First-run steps and tracking only follow a fresh install
+		newlyInstalled := stats.NewlyInstalled()
+		reportFirstRuns(runFirstRuns(newlyInstalled, dryRun))
 
-	// Track successfully installed apps
-	if !dryRun && stats != nil && stats.SuccessfulTools > 0 {
-		o.PrintInfo("Updating settings to track installed apps...")
-		o.PrintInfo("Group installation tracking not implemented yet")
+		if stop != nil {
+			stop.notAttempted = stats.AbortedTools
+			reportGroupStop(stop)
+		}
+
+		if !dryRun {
+			trackInstalledApps(newlyInstalled)
+		}
 	}
 
 	return err
 }
 
+// trackInstalledApps adds newly installed apps that settings don't track yet, asking for each like individual installs
+func trackInstalledApps(apps []string) {
+	var untracked []string
+	for _, app := range apps {
+		if tracked, err := config.IsAppTracked(app); err != nil || !tracked {
+			untracked = append(untracked, app)
+		}
+	}
+	if len(untracked) == 0 {
+		return
+	}
+
+	palantir.GetGlobalOutputHandler().PrintInfo(i18n.T("install.group.tracking"))
+	for _, app := range untracked {
+		_ = trackAppInSettings(app)
+	}
+}
+
 // toolStatus represents the status of a tool installation
 type toolStatus struct {
 	name   string
-	status string // "pending", "installing", "done", "failed"
+	status string // "pending", "installing", "done", "failed", "skipped"
 	emoji  string
 }
 
-// installGroupSerial installs tools serially using unified installation logic
-func installGroupSerial(groupName string, tools []string, dryRun bool) error {
+// installGroupSerial installs tools serially using unified installation logic. Tools are left out
+// when a tool they are tagged to install after did not install.
+func installGroupSerial(groupName string, tools []string, after map[string][]string, dryRun bool, policy string) error {
 	o := palantir.GetGlobalOutputHandler()
 
 	successCount := 0
+	skippedCount := 0
 	var installErrors []string
+	var newlyInstalled []string
+	var stop *groupStop
+	notInstalled := make(map[string]bool)
 
-	// Initialize tool statuses
+	// Initialize tool statuses, tools tagged for other platforms or managed externally are skipped up front
 	toolStatuses := make([]toolStatus, len(tools))
 	for i, tool := range tools {
 		toolStatuses[i] = toolStatus{
@@ -222,22 +470,64 @@ func installGroupSerial(groupName string, tools []string, dryRun bool) error {
 			status: "pending",
 			emoji:  "⋯",
 		}
+		if config.IsManagedExternally(tool) || !config.IsToolSupported(tool) {
+			toolStatuses[i].status = "skipped"
+			toolStatuses[i].emoji = "⊘"
+			skippedCount++
+		}
 	}
 
 	for i, tool := range tools {
+		if toolStatuses[i].status == "skipped" {
+			if charm.IsPlain() {
+				charm.PlainEvent("skip", tool, time.Time{})
+			}
+			continue
+		}
+
+		// Tools after a stopping failure are left out and counted in the report
+		if stop != nil {
+			toolStatuses[i].status = "skipped"
+			toolStatuses[i].emoji = "⊘"
+			stop.notAttempted++
+			if charm.IsPlain() {
+				charm.PlainEvent("skip", tool, time.Time{})
+			}
+			continue
+		}
+
+		if dep, blocked := failedAfter(tool, after, notInstalled); blocked {
+			toolStatuses[i].status = "skipped"
+			toolStatuses[i].emoji = "⊘"
+			notInstalled[tool] = true
+			errorMsg := fmt.Sprintf(i18n.T("install.order.blocked"), tool, dep)
+			installErrors = append(installErrors, errorMsg)
+			o.PrintWarning("%s", errorMsg)
+			if charm.IsPlain() {
+				charm.PlainEvent("skip", tool, time.Time{})
+			}
+			continue
+		}
+
 		// Update status to installing
 		toolStatuses[i].status = "installing"
 		toolStatuses[i].emoji = "⠋"
 
-		// Print dashboard
-		printInstallDashboard(groupName, toolStatuses, i+1, len(tools))
+		// Print dashboard, plain output gets a line per tool instead
+		if charm.IsPlain() {
+			charm.PlainEvent("start", fmt.Sprintf("%s [%d/%d]", tool, i+1, len(tools)), time.Time{})
+		} else {
+			printInstallDashboard(groupName, toolStatuses, i+1, len(tools))
+		}
+		started := time.Now()
 
 		// Use unified installation logic
-		_, err := installSingleToolUnified(tool, dryRun)
+		wasNewlyInstalled, err := installSingleToolUnified(tool, dryRun)
 
 		if err != nil {
 			toolStatuses[i].status = "failed"
 			toolStatuses[i].emoji = "✗"
+			notInstalled[tool] = true
 			errorMsg := fmt.Sprintf("%s: %v", tool, err)
 			installErrors = append(installErrors, errorMsg)
 			o.PrintError("%s: %v", tool, err)
@@ -245,165 +535,301 @@ func installGroupSerial(groupName string, tools []string, dryRun bool) error {
 			toolStatuses[i].status = "done"
 			toolStatuses[i].emoji = "✓"
 			successCount++
+			if wasNewlyInstalled {
+				newlyInstalled = append(newlyInstalled, tool)
+			}
 		}
 
 		// Print final dashboard state
-		printInstallDashboard(groupName, toolStatuses, i+1, len(tools))
+		if charm.IsPlain() {
+			event := "done"
+			if err != nil {
+				event = "fail"
+			}
+			charm.PlainEvent(event, tool, started)
+		} else {
+			printInstallDashboard(groupName, toolStatuses, i+1, len(tools))
+		}
+
+		// Apply critical tags and the failure policy while tools are still pending
+		if err != nil && hasPendingTools(toolStatuses[i+1:]) {
+			if halt, reason := stopAfterFailure(groupName, policy, tool); halt {
+				stop = &groupStop{tool: tool, reason: reason}
+			}
+		}
+	}
+
+	firstRuns := runFirstRuns(newlyInstalled, dryRun)
+	if !dryRun {
+		trackInstalledApps(newlyInstalled)
+	}
+	return reportGroupInstallationResults(groupName, successCount, len(tools)-skippedCount, skippedCount, installErrors, firstRuns, stop)
+}
+
+// failedAfter returns the first tool a tool is tagged to install after that did not install
+func failedAfter(tool string, after map[string][]string, notInstalled map[string]bool) (string, bool) {
+	for _, dep := range after[tool] {
+		if notInstalled[dep] {
+			return dep, true
+		}
 	}
+	return "", false
+}
 
-	return reportGroupInstallationResults(groupName, successCount, len(tools), installErrors)
+// hasPendingTools reports whether any of the statuses is still waiting to install
+func hasPendingTools(statuses []toolStatus) bool {
+	for _, status := range statuses {
+		if status.status == "pending" {
+			return true
+		}
+	}
+	return false
 }
 
-// printInstallDashboard displays the current installation progress
+// printInstallDashboard clears the screen and prints the group installation dashboard
 func printInstallDashboard(groupName string, statuses []toolStatus, current, total int) {
+	content := renderInstallDashboard(statuses, current, total, charm.BoxContentWidth())
+
+	// Clear previous output and print new dashboard
+	fmt.Print("\033[2J\033[H") // Clear screen and move cursor to top
+	fmt.Println(charm.RenderBox(i18n.T("install.dashboard.title", groupName, total), content, charm.ActiveTheme().Accent, false))
+}
+
+// renderInstallDashboard renders tool statuses and a progress bar that fit in width.
+// Long tool names are truncated and the progress bar shrinks on narrow terminals.
+func renderInstallDashboard(statuses []toolStatus, current, total int, width int) string {
 	var content strings.Builder
 	content.WriteString("\n")
 
+	// "  [i/n] " + name + " " + emoji + " " + status
+	counterWidth := len(fmt.Sprintf("  [%d/%d] ", total, total))
+	nameWidth := min(20, max(width-counterWidth-len(" ✓ Installing..."), 8))
+
 	// Show each tool with its status
 	for i, status := range statuses {
-		var statusText string
+		var label string
 		switch status.status {
 		case "done":
-			statusText = fmt.Sprintf("%-20s %s %-15s", status.name, status.emoji, "Installed")
+			label = i18n.T("install.dashboard.installed")
 		case "failed":
-			statusText = fmt.Sprintf("%-20s %s %-15s", status.name, status.emoji, "Failed")
+			label = i18n.T("install.dashboard.failed")
 		case "installing":
-			statusText = fmt.Sprintf("%-20s %s %-15s", status.name, status.emoji, "Installing...")
+			label = i18n.T("install.dashboard.installing")
+		case "skipped":
+			label = i18n.T("install.dashboard.skipped")
 		default:
-			statusText = fmt.Sprintf("%-20s %s %-15s", status.name, status.emoji, "Pending")
+			label = i18n.T("install.dashboard.pending")
 		}
 
-		content.WriteString(fmt.Sprintf("  [%d/%d] %s\n", i+1, total, statusText))
+		name := charm.Truncate(status.name, nameWidth)
+		statusText := fmt.Sprintf("%s%s %s %-15s", name, strings.Repeat(" ", nameWidth-ansi.StringWidth(name)), status.emoji, label)
+		content.WriteString(strings.TrimRight(fmt.Sprintf("  [%d/%d] %s", i+1, total, statusText), " ") + "\n")
 	}
 
 	content.WriteString("\n")
 
 	// Calculate progress
 	percentage := (current * 100) / total
-	barWidth := 30
+	barWidth := min(30, max(width-len("  Progress: 100% "), 10))
 	filled := (percentage * barWidth) / 100
 	bar := strings.Repeat("█", filled) + strings.Repeat("░", barWidth-filled)
 
-	content.WriteString(fmt.Sprintf("  Progress: %d%% %s\n", percentage, bar))
-
-	// Clear previous output and print new dashboard
-	fmt.Print("\033[2J\033[H") // Clear screen and move cursor to top
-	fmt.Println(charm.RenderBox(fmt.Sprintf("Installing '%s' group (%d tools)", groupName, total), content.String(), "#00D9FF", false))
+	content.WriteString(i18n.T("install.dashboard.progress", percentage, bar))
+	return content.String()
 }
 
 // installIndividualApp installs a single application using unified installation logic
 func installIndividualApp(appName string, dryRun bool, cmd *cobra.Command) error {
 	o := palantir.GetGlobalOutputHandler()
-	o.PrintHeader(fmt.Sprintf("Installing '%s'", appName))
+	o.PrintHeader(i18n.T("install.app.title", appName))
 
 	// Validate app name is not empty
 	if appName == "" {
 		return errors.NewInstallationError(constants.OpInstall, appName,
-			fmt.Errorf("application name cannot be empty"))
+			fmt.Errorf("%s", i18n.T("install.app.empty")))
+	}
+
+	// Apps managed by another tool, such as an MDM, are left alone
+	if config.IsManagedExternally(appName) {
+		o.PrintInfo(i18n.T("install.managed.skipped"), appName)
+		return nil
+	}
+
+	// Respect platform tags from groups instead of failing on an inapplicable tool
+	if !config.IsToolSupported(appName) {
+		o.PrintInfo(i18n.T("install.platform.skipped_here"), appName, strings.Join(config.GetToolPlatforms(appName), ", "), system.Platform())
+		return nil
 	}
 
 	wasNewlyInstalled, err := installSingleToolUnified(appName, dryRun)
 	if err != nil {
 		return errors.NewInstallationError(constants.OpInstall, appName,
-			fmt.Errorf("failed to install '%s'. Please verify the name is correct. You can search for packages using 'brew search %s'", appName, appName))
+			fmt.Errorf(i18n.T("install.app.failed"), appName, searchCommand(appName)))
+	}
+
+	if wasNewlyInstalled {
+		reportFirstRuns(runFirstRuns([]string{appName}, dryRun))
 	}
 
 	// Only track the app in settings if it was newly installed and not dry-run
 	if !dryRun && wasNewlyInstalled {
-		// Check if --group-name flag is provided
-		groupName, _ := cmd.Flags().GetString("group-name")
-		if groupName != "" {
-			// Add app to the specified group
-			if err := config.AddAppToGroup(groupName, appName); err != nil {
-				o.PrintWarning("Failed to add %s to group '%s': %v", appName, groupName, err)
-				// Continue with normal tracking as fallback
-				return trackAppInSettings(appName)
-			}
-			o.PrintSuccess(fmt.Sprintf("Added %s to group '%s'", appName, groupName))
-			return nil
-		} else {
-			// Normal tracking in installed_apps
+		return trackInstalledApp(cmd, appName)
+	}
+
+	return nil
+}
+
+// trackInstalledApp adds a newly installed app to the group given with --group-name, or to
+// installed_apps when there is none
+func trackInstalledApp(cmd *cobra.Command, appName string) error {
+	o := palantir.GetGlobalOutputHandler()
+
+	// Check if --group-name flag is provided
+	groupName, _ := cmd.Flags().GetString("group-name")
+	if groupName != "" {
+		// Add app to the specified group
+		if err := config.AddAppToGroup(groupName, appName); err != nil {
+			o.PrintWarning(i18n.T("install.app.group_failed"), appName, groupName, err)
+			// Continue with normal tracking as fallback
 			return trackAppInSettings(appName)
 		}
+		o.PrintSuccess(i18n.T("install.app.grouped", appName, groupName))
+		return nil
 	}
 
-	return nil
+	// Normal tracking in installed_apps
+	return trackAppInSettings(appName)
 }
 
+// installTool installs a tool between its hooks, replaced in tests
+var installTool = installSingleTool
+
 // installSingleTool installs a single tool, handling special cases dynamically
 func installSingleTool(toolName string) error {
 	o := palantir.GetGlobalOutputHandler()
 
+	// Strip any cask:/formula: annotation, brew still receives the annotated entry
+	name, _ := brew.ParsePackageName(toolName)
+
+	// Tools from language ecosystems are installed with go, cargo, npm or pipx
+	if tool, ok := ecosystem.Lookup(name); ok {
+		return ecosystem.Install(name, tool)
+	}
+
 	// Check if source is configured for this app (user explicitly configured it)
-	sourceURL, exists, sourceErr := installer.GetSourceURL(toolName)
+	sourceURL, exists, sourceErr := installer.GetSourceURL(name)
 	if sourceErr != nil {
-		o.PrintWarning("Failed to check source URL for %s: %v", toolName, sourceErr)
-		// Fall back to brew if we can't check source
-		return brew.InstallPackageDirectly(toolName)
+		o.PrintWarning(i18n.T("install.source.check_failed"), toolName, sourceErr)
+		// Fall back to the package manager if we can't check source
+		return installWithManager(toolName)
 	}
 
 	// If source exists, try it first (user explicitly configured it)
 	if exists && sourceURL != "" {
-		o.PrintInfo("Installing %s from configured source", toolName)
-		if err := installer.InstallFromSource(toolName, sourceURL); err != nil {
-			// Source installation failed, fall back to brew
-			o.PrintInfo("Source installation failed, falling back to brew for %s", toolName)
-			return brew.InstallPackageDirectly(toolName)
+		o.PrintInfo(i18n.T("install.source.installing"), toolName)
+		if err := installer.InstallFromSource(name, sourceURL); err != nil {
+			// Source installation failed, fall back to the package manager
+			o.PrintInfo(i18n.T("install.source.fallback"), toolName)
+			return installWithManager(toolName)
 		}
 		// Source installation succeeded, continue with post-install steps
 	} else {
-		// No source configured, use brew (default for majority of apps)
-		if err := brew.InstallPackageDirectly(toolName); err != nil {
+		// No source configured, use the package manager (default for majority of apps)
+		if err := installWithManager(toolName); err != nil {
 			return err
 		}
 	}
 
-	// Handle special cases for specific tools
-	if toolName == "zsh" {
-		spinner := charm.NewLineSpinner("Installing Oh My Zsh")
-		spinner.Start()
-		ohMyZshScript := `sh -c "$(curl -fsSL https://raw.github.com/ohmyzsh/ohmyzsh/master/tools/install.sh)" "" --unattended`
-		if err := runPostInstallScript(ohMyZshScript); err != nil {
-			spinner.Warning("Oh My Zsh setup skipped")
-			o.PrintWarning("Post-install script failed for %s: %v", toolName, err)
-		} else {
-			spinner.Success("Oh My Zsh installed successfully")
-		}
-	}
-
 	// Handle config check for git
-	if toolName == "git" {
-		if err := checkToolConfiguration(toolName); err != nil {
-			o.PrintWarning("Configuration check failed for %s: %v", toolName, err)
+	if name == "git" {
+		if err := checkToolConfiguration(name); err != nil {
+			o.PrintWarning(i18n.T("install.config_check.failed"), toolName, err)
 		}
 	}
 
 	return nil
 }
 
+// installWithManager installs a tool with the machine's package manager. Homebrew installs
+// offer recovery steps when they fail.
+func installWithManager(toolName string) error {
+	manager, err := pkgmanager.Current()
+	if err != nil {
+		return err
+	}
+	if manager.Name() != pkgmanager.Brew {
+		return manager.Install(toolName)
+	}
+	if err := brew.InstallPackageDirectly(toolName); err != nil {
+		return brew.RecoverFromInstallFailure(toolName, err)
+	}
+	return nil
+}
+
+// searchCommand returns the command that searches the package manager for an entry's package
+func searchCommand(entry string) string {
+	name, _ := brew.ParsePackageName(entry)
+	manager, err := pkgmanager.Current()
+	if err != nil {
+		return "brew search " + name
+	}
+	return manager.SearchCommand(name)
+}
+
 // installSingleToolUnified provides unified installation logic for all installation modes
 // This is the core function that ensures consistent behavior across individual, serial, and concurrent installations
 func installSingleToolUnified(toolName string, dryRun bool) (wasNewlyInstalled bool, err error) {
+	// Recorded sessions keep each tool's duration, brew output and error
+	err = session.Track(toolName, func() error {
+		var installErr error
+		wasNewlyInstalled, installErr = installAndReportTool(toolName, dryRun)
+		return installErr
+	})
+	return wasNewlyInstalled, err
+}
+
+// installAndReportTool installs a tool unless it is already available and reports the outcome
+func installAndReportTool(toolName string, dryRun bool) (wasNewlyInstalled bool, err error) {
 	o := palantir.GetGlobalOutputHandler()
 
-	// ALWAYS check availability first using the latest IsApplicationAvailable logic
-	if brew.IsApplicationAvailable(toolName) {
-		o.PrintAlreadyAvailable("%s is already available on the system", toolName)
+	// ALWAYS check availability first, including packages the package manager installed
+	if pkgmanager.IsAvailable(toolName) {
+		o.PrintAlreadyAvailable(i18n.T("install.tool.available"), toolName)
 		return false, nil
 	}
 
 	// Handle installation based on mode
 	if dryRun {
-		o.PrintInfo("Would install: %s", toolName)
+		o.PrintInfo(i18n.T("install.tool.would_install"), toolName)
+		if hook, ok := installer.HookFor(toolName); ok && len(installer.HookPhases(hook)) > 0 {
+			o.PrintInfo(i18n.T("install.hooks.would_run"), strings.Join(installer.HookPhases(hook), " and "), toolName)
+		}
+		audit.Record("install", "install-package", toolName, "")
 		return true, nil
 	}
 
-	// Perform real installation using existing logic
-	if err := installSingleTool(toolName); err != nil {
+	// Perform real installation using existing logic, between the tool's install hooks
+	err = installer.InstallWithHooks(toolName, func(phase, line string) {
+		o.PrintInfo("  %s │ %s", phase, line)
+	}, func(hookErr error) {
+		o.PrintWarning(i18n.T("install.hooks.failed"), hookErr)
+	}, func() error {
+		return installTool(toolName)
+	})
+	var substituted *brew.SubstitutedError
+	if stderrors.As(err, &substituted) {
+		// The entry is still unknown to Homebrew, tracking it would fail the same way next run
+		o.PrintSuccess(i18n.T("install.tool.substituted", substituted.Substitute, toolName, toolName))
+		events.Publish(events.Installed, substituted.Substitute, "")
+		return false, nil
+	}
+	if err != nil {
+		events.Publish(events.Failed, toolName, "")
 		return false, err
 	}
 
-	o.PrintSuccess(fmt.Sprintf("%s installed successfully", toolName))
+	o.PrintSuccess(i18n.T("install.tool.installed", toolName))
+	events.Publish(events.Installed, toolName, "")
 	return true, nil
 }
 
@@ -412,53 +838,54 @@ func trackAppInSettings(appName string) error {
 	o := palantir.GetGlobalOutputHandler()
 	// Check if already tracked to avoid duplicates
 	if isTracked, err := config.IsAppTracked(appName); err != nil {
-		o.PrintWarning("Failed to check if %s is already tracked: %v", appName, err)
+		o.PrintWarning(i18n.T("install.track.check_failed"), appName, err)
 		return nil // Don't fail installation for tracking issues
 	} else if isTracked {
 		return nil
 	}
 
-	spinner := charm.NewDotsSpinner(fmt.Sprintf("Tracking %s in settings", appName))
+	if !charm.Confirm(charm.ConfirmTracking, i18n.T("install.track.confirm", appName, constants.ANVIL_CONFIG_FILE)) {
+		o.PrintInfo(i18n.T("install.track.skipped"), appName, constants.ANVIL_CONFIG_FILE)
+		return nil
+	}
+
+	spinner := charm.NewDotsSpinner(i18n.T("install.track.tracking", appName))
 	spinner.Start()
 
 	if err := config.AddInstalledApp(appName); err != nil {
-		spinner.Warning("Failed to update settings")
-		o.PrintWarning("Failed to update settings file: %v", err)
+		spinner.Warning(i18n.T("install.track.update_failed"))
+		o.PrintWarning(i18n.T("install.track.save_failed"), err)
 		return nil // Don't fail installation for tracking issues
 	}
 
-	spinner.Success(fmt.Sprintf("%s tracked in settings", appName))
+	spinner.Success(i18n.T("install.track.tracked", appName))
 	return nil
 }
 
 // reportGroupInstallationResults provides unified error reporting for group installations
-func reportGroupInstallationResults(groupName string, successCount, totalCount int, installErrors []string) error {
+func reportGroupInstallationResults(groupName string, successCount, totalCount, skippedCount int, installErrors []string, firstRuns []installer.FirstRunResult, stop *groupStop) error {
 	// Print summary
 	o := palantir.GetGlobalOutputHandler()
-	o.PrintHeader("Group Installation Complete")
-	o.PrintInfo("Successfully installed %d of %d tools", successCount, totalCount)
+	o.PrintHeader(i18n.T("install.summary.title"))
+	o.PrintInfo(i18n.T("install.summary.installed"), successCount, totalCount)
+	if skippedCount > 0 {
+		o.PrintInfo(i18n.T("install.summary.skipped"), skippedCount, system.Platform())
+	}
+	reportFirstRuns(firstRuns)
 
 	if len(installErrors) > 0 {
-		o.PrintWarning("Some installations failed:")
+		o.PrintWarning(i18n.T("install.summary.failures"))
 		for _, err := range installErrors {
 			o.PrintError("  • %s", err)
 		}
+		reportGroupStop(stop)
 		return errors.NewInstallationError(constants.OpInstall, groupName,
-			fmt.Errorf("failed to install %d tools", len(installErrors)))
+			fmt.Errorf(i18n.T("install.summary.failed"), len(installErrors)))
 	}
 
 	return nil
 }
 
-// runPostInstallScript runs a post-install script for a tool
-func runPostInstallScript(script string) error {
-	// For now, just provide instructions to the user
-	o := palantir.GetGlobalOutputHandler()
-	o.PrintInfo("To complete installation, run:")
-	o.PrintInfo("  %s", script)
-	return nil
-}
-
 // checkToolConfiguration checks if a tool is properly configured
 func checkToolConfiguration(toolName string) error {
 	switch toolName {
@@ -474,8 +901,8 @@ func checkGitConfiguration() error {
 	config, err := config.LoadConfig()
 	if err == nil && (config.Git.Username == "" || config.Git.Email == "") {
 		o := palantir.GetGlobalOutputHandler()
-		o.PrintInfo("Git installed successfully")
-		o.PrintWarning("Consider configuring git with:")
+		o.PrintInfo(i18n.T("install.git.installed"))
+		o.PrintWarning(i18n.T("install.git.configure"))
 		o.PrintInfo("  git config --global user.name 'Your Name'")
 		o.PrintInfo("  git config --global user.email 'your.email@example.com'")
 	}
@@ -497,8 +924,13 @@ func init() {
 	InstallCmd.Flags().Bool("dry-run", false, "Show what would be installed without installing")
 	InstallCmd.Flags().Bool("list", false, "List all available groups")
 	InstallCmd.Flags().Bool("tree", false, "Display all applications in a tree format")
-	InstallCmd.Flags().Bool("update", false, "Update Homebrew before installation")
+	InstallCmd.Flags().Bool("update", false, "Run 'brew update' once before installing")
+	InstallCmd.Flags().Bool("no-cleanup", false, "Skip 'brew cleanup' after group installs even when brew.cleanup is enabled")
 	InstallCmd.Flags().String("group-name", "", "Add the installed app to a group (creates group if it doesn't exist)")
+	InstallCmd.Flags().Bool("record", false, "Record the run (tool order, durations, brew output, errors) under ~/.anvil/sessions")
+	InstallCmd.Flags().String("on-failure", "", "Failure policy for group installs: continue, fail-fast or prompt (overrides failure_policies)")
+	InstallCmd.Flags().String("from-file", "", "Install apps listed in a text file (one per line) or CSV file (name,group)")
+	InstallCmd.Flags().String("from-url", "", "Install the app from a DMG, PKG, ZIP, DEB, RPM, AppImage or tarball https URL and save it under sources")
 
 	// Add concurrent installation flags
 	InstallCmd.Flags().Bool("concurrent", false, "Enable concurrent installation for improved performance")
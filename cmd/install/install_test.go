@@ -0,0 +1,202 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package install
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/brew"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/charmbracelet/x/ansi"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files")
+
+func TestRenderInstallDashboardWidths(t *testing.T) {
+	statuses := []toolStatus{
+		{name: "git", status: "done", emoji: "✓"},
+		{name: "visual-studio-code-insiders", status: "installing", emoji: "⠋"},
+		{name: "slack", status: "failed", emoji: "✗"},
+		{name: "docker", status: "pending", emoji: "⋯"},
+	}
+
+	for _, tc := range []struct {
+		name  string
+		width int
+	}{
+		{"dashboard_wide", 96},
+		{"dashboard_narrow", 36},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := renderInstallDashboard(statuses, 2, len(statuses), tc.width)
+
+			path := filepath.Join("testdata", tc.name+".golden")
+			if *updateGolden {
+				if err := os.MkdirAll("testdata", 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+			}
+			if got != string(want) {
+				t.Errorf("dashboard mismatch\n--- got ---\n%s\n--- want ---\n%s", got, want)
+			}
+
+			for _, line := range strings.Split(got, "\n") {
+				if w := ansi.StringWidth(line); w > tc.width {
+					t.Errorf("line is %d cells wide, limit is %d: %q", w, tc.width, line)
+				}
+			}
+		})
+	}
+}
+
+func TestParseAppList(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		input string
+		isCSV bool
+		want  []fileEntry
+	}{
+		{
+			name:  "text",
+			input: "# onboarding\ngit\n\n  slack  \ncask:docker-desktop # containers\nGit\n",
+			want:  []fileEntry{{Name: "git"}, {Name: "slack"}, {Name: "cask:docker-desktop"}},
+		},
+		{
+			name:  "csv with header and groups",
+			input: "name,group\ngit,dev\nslack, comms\nobsidian\n# notes\n",
+			isCSV: true,
+			want:  []fileEntry{{Name: "git", Group: "dev"}, {Name: "slack", Group: "comms"}, {Name: "obsidian"}},
+		},
+		{
+			name:  "csv without header",
+			input: "git,dev\n",
+			isCSV: true,
+			want:  []fileEntry{{Name: "git", Group: "dev"}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseAppList(strings.NewReader(tc.input), tc.isCSV)
+			if err != nil {
+				t.Fatalf("parseAppList() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("parseAppList() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestUpdateBrew(t *testing.T) {
+	original := updateOnce
+	t.Cleanup(func() { updateOnce = original })
+
+	for _, tc := range []struct {
+		name       string
+		autoUpdate bool
+		lastUpdate time.Duration // Age of the recorded update, 0 when none was recorded
+		requested  bool
+		dryRun     bool
+		updateErr  error
+		wantUpdate bool
+		wantRecord bool
+	}{
+		{name: "auto update off", lastUpdate: 48 * time.Hour},
+		{name: "never updated", autoUpdate: true, wantUpdate: true, wantRecord: true},
+		{name: "updated within a day", autoUpdate: true, lastUpdate: time.Hour},
+		{name: "updated over a day ago", autoUpdate: true, lastUpdate: 25 * time.Hour, wantUpdate: true, wantRecord: true},
+		{name: "--update ignores the last update", lastUpdate: time.Hour, requested: true, wantUpdate: true, wantRecord: true},
+		{name: "dry run only reports", autoUpdate: true, dryRun: true},
+		{name: "failed update is not recorded", autoUpdate: true, updateErr: fmt.Errorf("offline"), wantUpdate: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			anvilDir := filepath.Join(home, ".anvil")
+			if err := os.MkdirAll(anvilDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			settings := fmt.Sprintf("version: \"1\"\nbrew:\n  auto_update: %t\n", tc.autoUpdate)
+			if err := os.WriteFile(filepath.Join(anvilDir, "settings.yaml"), []byte(settings), 0644); err != nil {
+				t.Fatal(err)
+			}
+			config.InvalidateConfigCache()
+			t.Cleanup(config.InvalidateConfigCache)
+
+			var before time.Time
+			if tc.lastUpdate > 0 {
+				before = time.Now().Add(-tc.lastUpdate).Truncate(time.Second)
+				if err := config.RecordBrewUpdate(before); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			updated := false
+			updateOnce = func() error {
+				updated = true
+				return tc.updateErr
+			}
+			updateBrew(tc.requested, tc.dryRun)
+
+			if updated != tc.wantUpdate {
+				t.Errorf("brew update ran = %t, want %t", updated, tc.wantUpdate)
+			}
+			if recorded := !config.LastBrewUpdate().Equal(before); recorded != tc.wantRecord {
+				t.Errorf("last update = %v (was %v), want recorded = %t", config.LastBrewUpdate(), before, tc.wantRecord)
+			}
+		})
+	}
+}
+
+func TestInstallAndReportToolSubstitute(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	config.InvalidateConfigCache()
+	t.Cleanup(config.InvalidateConfigCache)
+
+	original := installTool
+	t.Cleanup(func() { installTool = original })
+
+	entry := "anvil-test-missing-package"
+	installTool = func(toolName string) error {
+		return &brew.SubstitutedError{Entry: toolName, Substitute: "anvil-test-similar-package"}
+	}
+	wasNewlyInstalled, err := installAndReportTool(entry, false)
+	if err != nil {
+		t.Fatalf("installAndReportTool() error = %v, want the substitute reported as installed", err)
+	}
+	if wasNewlyInstalled {
+		t.Error("a substituted install must not report the entry as newly installed, it would be tracked")
+	}
+
+	installTool = func(string) error { return fmt.Errorf("install failed") }
+	if _, err := installAndReportTool(entry, false); err == nil {
+		t.Error("other install errors must still fail")
+	}
+}
@@ -0,0 +1,220 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package project
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/0xjuanma/anvil/cmd/install"
+	"github.com/0xjuanma/anvil/internal/brew"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/project"
+	"github.com/0xjuanma/palantir"
+	"github.com/spf13/cobra"
+)
+
+var ProjectCmd = &cobra.Command{
+	Use:   "project",
+	Short: "Check and install the tools a project's .anvil.yaml requires",
+	Long:  constants.PROJECT_COMMAND_LONG_DESCRIPTION,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var checkCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Report whether this machine meets the project's requirements",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runCheckCommand(cmd); err != nil {
+			palantir.GetGlobalOutputHandler().PrintError("Project check failed: %v", err)
+			os.Exit(errors.ExitCode(err))
+		}
+	},
+}
+
+var installCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install the project's missing tools and report what is left",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runInstallCommand(cmd); err != nil {
+			palantir.GetGlobalOutputHandler().PrintError("Project install failed: %v", err)
+			os.Exit(errors.ExitCode(err))
+		}
+	},
+}
+
+// checker looks tools, groups and environment variables up on this machine
+var checker = project.Checker{
+	Available:  brew.IsApplicationAvailable,
+	Supported:  config.IsToolSupported,
+	Managed:    config.IsManagedExternally,
+	GroupTools: config.GetGroupTools,
+	LookupEnv:  os.LookupEnv,
+}
+
+// loadProject reads the file given with --file, or the closest .anvil.yaml from the working directory up
+func loadProject(cmd *cobra.Command) (*project.Project, error) {
+	path, _ := cmd.Flags().GetString("file")
+	if path == "" {
+		found, err := project.Find(".")
+		if err != nil {
+			return nil, errors.NewConfigurationError(constants.OpProject, "find", err)
+		}
+		path = found
+	}
+
+	p, err := project.Load(path)
+	if err != nil {
+		return nil, errors.NewConfigurationError(constants.OpProject, "load", err)
+	}
+	return p, nil
+}
+
+// runCheckCommand prints the project's requirements and fails when any is not met
+func runCheckCommand(cmd *cobra.Command) error {
+	p, err := loadProject(cmd)
+	if err != nil {
+		return err
+	}
+
+	report := checker.Check(p)
+	printReport(p, report)
+	return complianceError(report)
+}
+
+// runInstallCommand installs missing tools one at a time, then checks the project again
+func runInstallCommand(cmd *cobra.Command) error {
+	o := palantir.GetGlobalOutputHandler()
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	p, err := loadProject(cmd)
+	if err != nil {
+		return err
+	}
+
+	missing := checker.Check(p).MissingTools()
+	if len(missing) == 0 {
+		o.PrintAlreadyAvailable("All tools for %s are installed", p.DisplayName())
+	} else {
+		o.PrintHeader(fmt.Sprintf("Installing %d tool(s) for %s", len(missing), p.DisplayName()))
+		if dryRun {
+			o.PrintInfo("Dry run mode - no changes will be made")
+		}
+		for _, tool := range missing {
+			if err := install.InstallTarget(tool, dryRun); err != nil {
+				o.PrintError("%s: %v", tool, err)
+			}
+		}
+		fmt.Println()
+	}
+
+	report := checker.Check(p)
+	printReport(p, report)
+	if dryRun {
+		return nil
+	}
+	return complianceError(report)
+}
+
+// printReport lists each requirement with its state and a compliance summary
+func printReport(p *project.Project, report *project.Report) {
+	o := palantir.GetGlobalOutputHandler()
+	o.PrintHeader(fmt.Sprintf("Project: %s", p.DisplayName()))
+	o.PrintInfo("File: %s", p.Path)
+	if p.Description != "" {
+		o.PrintInfo("%s", p.Description)
+	}
+	fmt.Println()
+
+	if len(report.Tools) > 0 || len(report.UnknownGroups) > 0 {
+		o.PrintInfo("Tools:")
+		for _, tool := range report.Tools {
+			switch tool.State {
+			case project.ToolInstalled:
+				fmt.Printf("  ✓ %-28s %s\n", tool.Name, tool.Source)
+			case project.ToolSkipped:
+				fmt.Printf("  ⊘ %-28s %s, not for this platform\n", tool.Name, tool.Source)
+			case project.ToolManaged:
+				fmt.Printf("  ⊘ %-28s %s, managed externally\n", tool.Name, tool.Source)
+			default:
+				fmt.Printf("  ✗ %-28s %s, missing\n", tool.Name, tool.Source)
+			}
+		}
+		for _, group := range report.UnknownGroups {
+			fmt.Printf("  ✗ %-28s group not found in %s\n", group, constants.ANVIL_CONFIG_FILE)
+		}
+		fmt.Println()
+	}
+
+	if len(report.SetEnv) > 0 || len(report.MissingEnv) > 0 {
+		o.PrintInfo("Environment:")
+		for _, name := range report.SetEnv {
+			fmt.Printf("  ✓ %s\n", name)
+		}
+		for _, name := range report.MissingEnv {
+			fmt.Printf("  ✗ %s not set\n", name)
+		}
+		fmt.Println()
+	}
+
+	if report.Compliant() {
+		o.PrintSuccess(fmt.Sprintf("This machine meets every requirement of %s", p.DisplayName()))
+		return
+	}
+	if missing := report.MissingTools(); len(missing) > 0 {
+		o.PrintInfo("💡 Install missing tools with: anvil project install")
+	}
+	if len(report.UnknownGroups) > 0 {
+		o.PrintInfo("💡 Pull the team settings or add the groups to %s: %s", constants.ANVIL_CONFIG_FILE, strings.Join(report.UnknownGroups, ", "))
+	}
+	if len(report.MissingEnv) > 0 {
+		o.PrintInfo("💡 Set these variables in your shell profile: %s", strings.Join(report.MissingEnv, ", "))
+	}
+}
+
+// complianceError summarizes the unmet requirements, nil when there are none
+func complianceError(report *project.Report) error {
+	if report.Compliant() {
+		return nil
+	}
+
+	var problems []string
+	if n := len(report.MissingTools()); n > 0 {
+		problems = append(problems, fmt.Sprintf("%d tool(s) missing", n))
+	}
+	if n := len(report.UnknownGroups); n > 0 {
+		problems = append(problems, fmt.Sprintf("%d unknown group(s)", n))
+	}
+	if n := len(report.MissingEnv); n > 0 {
+		problems = append(problems, fmt.Sprintf("%d environment variable(s) not set", n))
+	}
+	return fmt.Errorf("not compliant: %s", strings.Join(problems, ", "))
+}
+
+func init() {
+	ProjectCmd.AddCommand(checkCmd)
+	ProjectCmd.AddCommand(installCmd)
+	ProjectCmd.PersistentFlags().StringP("file", "f", "", "Project file to use instead of the closest "+project.FileName)
+	installCmd.Flags().Bool("dry-run", false, "Show which tools would be installed without installing them")
+}
@@ -0,0 +1,160 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provision
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/0xjuanma/anvil/cmd/config/sync"
+	"github.com/0xjuanma/anvil/internal/brew"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/gitsettings"
+	"github.com/0xjuanma/anvil/internal/provision"
+	"github.com/0xjuanma/anvil/internal/version"
+	"github.com/0xjuanma/palantir"
+)
+
+// runDiff prints the steps a re-run of the profile would act on differently than its last run
+func runDiff(profileName string, steps []provision.Step, asJSON bool) error {
+	last, err := provision.LoadLastRun(profileName)
+	if err != nil {
+		return errors.NewFileSystemError(constants.OpProvision, "load-last-run", err)
+	}
+	o := palantir.GetGlobalOutputHandler()
+	if last == nil {
+		o.PrintInfo("No previous run of '%s' recorded on this machine, every step would run", profileName)
+		o.PrintInfo("Run 'anvil provision %s --plan' to see the full plan", profileName)
+		return nil
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return errors.NewConfigurationError(constants.OpProvision, "load-config", err)
+	}
+	current := make([]provision.StepRecord, len(steps))
+	for i, step := range steps {
+		current[i] = provision.NewStepRecord(step, "", stepInputs(cfg, step))
+	}
+	diff := provision.DiffRun(*last, current)
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(diff)
+	}
+
+	o.PrintHeader(fmt.Sprintf("Changes since the last run of '%s'", profileName))
+	o.PrintInfo("Last run: %s, %d steps", last.FinishedAt.Local().Format("2006-01-02 15:04"), len(last.Steps))
+	fmt.Println()
+	if len(diff.Changed) == 0 && len(diff.Removed) == 0 {
+		o.PrintSuccess(fmt.Sprintf("Nothing changed, a re-run of '%s' would repeat the last run", profileName))
+		return nil
+	}
+
+	for _, changed := range diff.Changed {
+		fmt.Printf("  🔄 %s\n", changed.Step)
+		for _, reason := range changed.Reasons {
+			fmt.Printf("       %s\n", reason)
+		}
+	}
+	for _, removed := range diff.Removed {
+		fmt.Printf("  ➖ %s\n       no longer in the profile, nothing is undone\n", removed)
+	}
+	fmt.Println()
+	o.PrintInfo("%d step(s) changed, %d unchanged since the last run", len(diff.Changed), diff.Unchanged)
+	for _, step := range steps {
+		if step.Kind == provision.StepSyncSettings || step.Kind == provision.StepSyncConfig {
+			o.PrintInfo("Config hashes use the last pulled copy or the local clone, a pull may change them")
+			break
+		}
+	}
+	return nil
+}
+
+// recordRun saves the outcomes and inputs of an applied run for the next 'provision --diff'
+func recordRun(profileName string, results []stepResult) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return
+	}
+	record := provision.RunRecord{
+		Profile:      profileName,
+		Machine:      config.CurrentMachineID(),
+		AnvilVersion: version.GetVersion(),
+		FinishedAt:   time.Now().UTC(),
+	}
+	for _, result := range results {
+		record.Steps = append(record.Steps, provision.NewStepRecord(result.step, result.outcome, stepInputs(cfg, result.step)))
+	}
+	if err := provision.SaveRun(record); err != nil {
+		palantir.GetGlobalOutputHandler().PrintWarning("Could not record this run for 'provision --diff': %v", err)
+	}
+}
+
+// stepInputs collects the values that decide what a step does: the tools of a group, an app's
+// install source, the hash of the configs a sync applies, hosts entries and command defaults
+func stepInputs(cfg *config.AnvilConfig, step provision.Step) provision.StepInputs {
+	inputs := provision.StepInputs{}
+	unknown := func(err error) provision.StepInputs {
+		inputs[provision.InputUnknown] = err.Error()
+		return inputs
+	}
+
+	switch step.Kind {
+	case provision.StepInstallGroup:
+		tools, err := config.GetGroupTools(step.Target)
+		if err != nil {
+			return unknown(err)
+		}
+		inputs[provision.InputTools] = strings.Join(tools, ",")
+		inputs[provision.InputDefaults] = provision.HashValues(cfg.Defaults.For("install"))
+	case provision.StepInstallApp:
+		name, _ := brew.ParsePackageName(step.Target)
+		inputs[provision.InputSource] = cfg.Sources[name]
+		inputs[provision.InputDefaults] = provision.HashValues(cfg.Defaults.For("install"))
+	case provision.StepSyncSettings, provision.StepSyncConfig:
+		hash, excludes, err := sync.SourceHash(step.Target)
+		if err != nil {
+			return unknown(err)
+		}
+		inputs[provision.InputConfig] = hash
+		inputs[provision.InputExcludes] = strings.Join(excludes, ",")
+		inputs[provision.InputDefaults] = provision.HashValues(cfg.Defaults.For("config sync"))
+	case provision.StepApplyHosts:
+		hosts := make(map[string]string, len(cfg.Hosts))
+		for i, entry := range cfg.Hosts {
+			hosts[strconv.Itoa(i)] = entry.IP + " " + strings.Join(entry.Names, " ")
+		}
+		inputs[provision.InputHosts] = provision.HashValues(hosts)
+	case provision.StepApplyGit:
+		wanted, err := gitsettings.Wanted(cfg.Git)
+		if err != nil {
+			return unknown(err)
+		}
+		inputs[provision.InputGit] = provision.HashValues(wanted)
+	case provision.StepApplyServices:
+		inputs[provision.InputServices] = provision.HashValues(cfg.Services)
+	}
+	return inputs
+}
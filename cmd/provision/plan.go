@@ -0,0 +1,229 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provision
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/0xjuanma/anvil/cmd/config/sync"
+	"github.com/0xjuanma/anvil/internal/brew"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/gitsettings"
+	hostsblock "github.com/0xjuanma/anvil/internal/hosts"
+	"github.com/0xjuanma/anvil/internal/provision"
+	"github.com/0xjuanma/anvil/internal/services"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+	"github.com/0xjuanma/anvil/internal/version"
+	"github.com/0xjuanma/palantir"
+)
+
+// runPlan prints the full plan of a profile as a tree or JSON without changing anything
+func runPlan(profileName string, profile config.ProvisionProfile, steps []provision.Step, asJSON, sizes bool) error {
+	var spinner *charm.Spinner
+	if !asJSON {
+		spinner = charm.NewDotsSpinner(fmt.Sprintf("Planning profile '%s'", profileName))
+		spinner.Start()
+	}
+	document := buildPlanDocument(profileName, profile, steps, sizes)
+	if spinner != nil {
+		spinner.Success(fmt.Sprintf("Planned %d steps", len(document.Steps)))
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(document)
+	}
+
+	fmt.Println()
+	fmt.Print(document.RenderTree())
+	fmt.Println()
+	palantir.GetGlobalOutputHandler().PrintInfo("Run 'anvil provision %s' to apply it, or add --json to export the plan", profileName)
+	return nil
+}
+
+// buildPlanDocument details every step of a profile from the current state of the machine
+func buildPlanDocument(profileName string, profile config.ProvisionProfile, steps []provision.Step, sizes bool) *provision.Document {
+	document := &provision.Document{
+		Profile:      profileName,
+		Description:  profile.Description,
+		Machine:      config.CurrentMachineID(),
+		AnvilVersion: version.GetVersion(),
+		GeneratedAt:  time.Now().UTC(),
+	}
+	for _, step := range steps {
+		document.Steps = append(document.Steps, planStep(step, sizes))
+	}
+	document.Finish(brew.GetTappedRepositories())
+	return document
+}
+
+// planStep details a single step, marking it satisfied when it would change nothing
+func planStep(step provision.Step, sizes bool) provision.PlannedStep {
+	planned := provision.PlannedStep{
+		Kind:        step.Kind,
+		Target:      step.Target,
+		Description: step.String(),
+		Outcome:     provision.OutcomeChanged,
+	}
+	unknown := func(err error) provision.PlannedStep {
+		planned.Outcome = provision.OutcomeFailed
+		planned.Note = err.Error()
+		return planned
+	}
+
+	switch step.Kind {
+	case provision.StepInstallGroup, provision.StepInstallApp:
+		tools := []string{step.Target}
+		if step.Kind == provision.StepInstallGroup {
+			groupTools, err := config.GetGroupTools(step.Target)
+			if err != nil {
+				return unknown(err)
+			}
+			// Packages are listed in the order the group installs them
+			order, err := config.GetGroupOrder(step.Target, groupTools)
+			if err != nil {
+				return unknown(err)
+			}
+			tools = order.Tools
+		}
+		planned.Packages = planPackages(tools, sizes)
+		pending := 0
+		for _, pkg := range planned.Packages {
+			if pkg.Pending() {
+				pending++
+			}
+		}
+		if len(tools) > 0 && pending == 0 {
+			planned.Outcome = provision.OutcomeSatisfied
+		}
+	case provision.StepSyncSettings, provision.StepSyncConfig:
+		files, err := sync.PlanSync(step.Target)
+		if err != nil {
+			return unknown(err)
+		}
+		planned.Files = &provision.PlannedFiles{
+			Source:      files.Source,
+			Destination: files.Destination,
+			Pulled:      files.Pulled,
+			Files:       files.Files,
+			New:         files.New,
+			Changed:     files.Changed,
+		}
+		if files.New+files.Changed == 0 {
+			planned.Outcome = provision.OutcomeSatisfied
+		}
+	case provision.StepApplyHosts:
+		entries, err := config.GetHostsConfig()
+		if err != nil {
+			return unknown(err)
+		}
+		for _, entry := range entries {
+			planned.Hosts = append(planned.Hosts, fmt.Sprintf("%s %s", entry.IP, strings.Join(entry.Names, " ")))
+		}
+		inSync, err := hostsblock.InSync(entries)
+		if err != nil {
+			return unknown(err)
+		}
+		if inSync {
+			planned.Outcome = provision.OutcomeSatisfied
+		}
+	case provision.StepApplyGit:
+		git, err := config.GetGitConfig()
+		if err != nil {
+			return unknown(err)
+		}
+		settings, err := gitsettings.Plan(git)
+		if err != nil {
+			return unknown(err)
+		}
+		for _, setting := range settings {
+			marker := " "
+			if !setting.InSync() {
+				marker = "+"
+			}
+			planned.GitSettings = append(planned.GitSettings, fmt.Sprintf("%s %s = %s", marker, setting.Key, setting.Want))
+		}
+		if len(gitsettings.Drifted(settings)) == 0 {
+			planned.Outcome = provision.OutcomeSatisfied
+		}
+	case provision.StepApplyServices:
+		wanted, err := config.GetServicesConfig()
+		if err != nil {
+			return unknown(err)
+		}
+		states, err := services.Plan(wanted)
+		if err != nil {
+			return unknown(err)
+		}
+		for _, state := range states {
+			marker, status := " ", state.Status
+			if !state.InSync() {
+				marker = "+"
+			}
+			if !state.Installed {
+				status = "not installed"
+			}
+			planned.Services = append(planned.Services, fmt.Sprintf("%s %s: %s (%s)", marker, state.Name, state.Want, status))
+		}
+		if len(services.Drifted(states)) == 0 {
+			planned.Outcome = provision.OutcomeSatisfied
+		}
+	default:
+		return unknown(fmt.Errorf("unknown provisioning step '%s'", step.Kind))
+	}
+	return planned
+}
+
+// planPackages describes the tools of an install step: skipped on this platform or managed externally, installed
+// from a configured source or from Homebrew with version, size and dependencies
+func planPackages(tools []string, sizes bool) []provision.PlannedPackage {
+	cfg, _ := config.LoadConfig()
+	packages := make([]provision.PlannedPackage, len(tools))
+	var brewTools []string
+	var brewIndexes []int
+	for i, tool := range tools {
+		name, _ := brew.ParsePackageName(tool)
+		packages[i].Entry, packages[i].Name = tool, name
+		switch {
+		case cfg != nil && cfg.IsManagedExternally(tool):
+			packages[i].Skipped = "managed externally"
+		case !config.IsToolSupported(tool):
+			packages[i].Skipped = "platform: only for " + strings.Join(config.GetToolPlatforms(tool), ", ")
+		case cfg != nil && cfg.Sources[name] != "":
+			packages[i].Source = cfg.Sources[name]
+			packages[i].Present = brew.IsApplicationAvailable(tool)
+		default:
+			brewTools = append(brewTools, tool)
+			brewIndexes = append(brewIndexes, i)
+		}
+	}
+
+	for j, details := range brew.DescribePackages(brewTools, sizes) {
+		pkg := &packages[brewIndexes[j]]
+		pkg.PackageDetails = details
+		// Apps installed outside Homebrew satisfy the step just like provisioning checks them
+		if details.InstalledVersion == "" {
+			pkg.Present = brew.IsApplicationAvailable(details.Entry)
+		}
+	}
+	return packages
+}
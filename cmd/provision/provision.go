@@ -0,0 +1,295 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provision
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/0xjuanma/anvil/cmd/config/pull"
+	"github.com/0xjuanma/anvil/cmd/config/sync"
+	"github.com/0xjuanma/anvil/cmd/hosts"
+	"github.com/0xjuanma/anvil/cmd/install"
+	servicescmd "github.com/0xjuanma/anvil/cmd/services"
+	"github.com/0xjuanma/anvil/internal/audit"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	hostsblock "github.com/0xjuanma/anvil/internal/hosts"
+	"github.com/0xjuanma/anvil/internal/pkgmanager"
+	"github.com/0xjuanma/anvil/internal/provision"
+	"github.com/0xjuanma/anvil/internal/session"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+	"github.com/0xjuanma/palantir"
+	"github.com/spf13/cobra"
+)
+
+// ProvisionCmd represents the provision command
+var ProvisionCmd = &cobra.Command{
+	Use:   "provision [profile]",
+	Short: "Set up this machine from a profile defined in settings.yaml",
+	Long:  constants.PROVISION_COMMAND_LONG_DESCRIPTION,
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		plan, _ := cmd.Flags().GetBool("plan")
+		diff, _ := cmd.Flags().GetBool("diff")
+		if record, _ := cmd.Flags().GetBool("record"); record && len(args) > 0 && !plan && !diff {
+			session.Start(install.RecordedInvocation())
+		}
+
+		err := runProvisionCommand(cmd, args)
+		if err != nil {
+			palantir.GetGlobalOutputHandler().PrintError("Provision failed: %v", err)
+		}
+		install.FinishRecording(err)
+		if err != nil {
+			os.Exit(errors.ExitCode(err))
+		}
+	},
+}
+
+// stepResult is the outcome of a provisioning step
+type stepResult struct {
+	step     provision.Step
+	outcome  provision.Outcome
+	err      error
+	duration time.Duration
+}
+
+// runProvisionCommand applies a provisioning profile, or lists profiles when none is given
+func runProvisionCommand(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return listProfiles()
+	}
+
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	plan, _ := cmd.Flags().GetBool("plan")
+	diff, _ := cmd.Flags().GetBool("diff")
+	asJSON, _ := cmd.Flags().GetBool("json")
+	noSizes, _ := cmd.Flags().GetBool("no-sizes")
+	profileName := args[0]
+	if asJSON && !plan && !diff {
+		return errors.NewValidationError(constants.OpProvision, "json", fmt.Errorf("--json is only supported with --plan or --diff"))
+	}
+	if plan && diff {
+		return errors.NewValidationError(constants.OpProvision, "diff", fmt.Errorf("--plan and --diff cannot be combined"))
+	}
+
+	profile, err := config.GetProvisionProfile(profileName)
+	if err != nil {
+		return errors.NewConfigurationError(constants.OpProvision, profileName, err)
+	}
+
+	steps := provision.BuildPlan(profile)
+	if len(steps) == 0 {
+		return errors.NewConfigurationError(constants.OpProvision, profileName,
+			fmt.Errorf("profile '%s' has no groups, apps, configs, hosts, git settings or services", profileName))
+	}
+
+	if plan {
+		return runPlan(profileName, profile, steps, asJSON, !noSizes)
+	}
+	if diff {
+		return runDiff(profileName, steps, asJSON)
+	}
+
+	o := palantir.GetGlobalOutputHandler()
+	fmt.Println(charm.RenderBox(fmt.Sprintf("🔨 PROVISIONING: %s", profileName), profile.Description, charm.ActiveTheme().Accent, true))
+	for i, step := range steps {
+		o.PrintInfo("  %d. %s", i+1, step)
+	}
+	fmt.Println()
+
+	if dryRun {
+		o.PrintInfo("Dry run mode - no changes will be made")
+	}
+
+	var results []stepResult
+	for i, step := range steps {
+		o.PrintStage(fmt.Sprintf("[%d/%d] %s", i+1, len(steps), step))
+		start := time.Now()
+		outcome := provision.OutcomeChanged
+		err := session.Track(step.String(), func() error {
+			var stepErr error
+			outcome, stepErr = runStep(step, dryRun)
+			return stepErr
+		})
+		if err != nil {
+			outcome = provision.OutcomeFailed
+			o.PrintError("%s failed: %v", step, err)
+		} else if outcome == provision.OutcomeSatisfied {
+			o.PrintAlreadyAvailable("%s: already satisfied", step)
+		}
+		results = append(results, stepResult{step: step, outcome: outcome, err: err, duration: time.Since(start)})
+	}
+	if !dryRun {
+		recordRun(profileName, results)
+	}
+
+	return printProvisionSummary(profileName, results, dryRun)
+}
+
+// runStep executes a single provisioning step, skipping it when the machine already satisfies it
+func runStep(step provision.Step, dryRun bool) (provision.Outcome, error) {
+	switch step.Kind {
+	case provision.StepInstallGroup, provision.StepInstallApp:
+		tools := []string{step.Target}
+		if step.Kind == provision.StepInstallGroup {
+			groupTools, err := config.GetGroupTools(step.Target)
+			if err != nil {
+				return provision.OutcomeFailed, err
+			}
+			tools = groupTools
+		}
+		if len(tools) > 0 && len(provision.Pending(tools, toolAvailable)) == 0 {
+			return provision.OutcomeSatisfied, nil
+		}
+		return provision.OutcomeChanged, install.InstallTarget(step.Target, dryRun)
+	case provision.StepSyncSettings, provision.StepSyncConfig:
+		if dryRun {
+			// Without pulling, only an earlier pull can show the step is satisfied
+			if inSync, err := sync.InSync(step.Target); err == nil && inSync {
+				return provision.OutcomeSatisfied, nil
+			}
+			palantir.GetGlobalOutputHandler().PrintInfo("Dry run - would pull and sync '%s'", step.Target)
+			audit.Record("provision", "pull-and-sync", step.Target, "")
+			return provision.OutcomeChanged, nil
+		}
+		if err := pull.PullConfig(step.Target); err != nil {
+			return provision.OutcomeFailed, err
+		}
+		if inSync, err := sync.InSync(step.Target); err == nil && inSync {
+			return provision.OutcomeSatisfied, nil
+		}
+		return provision.OutcomeChanged, sync.SyncConfig(step.Target, false)
+	case provision.StepApplyHosts:
+		entries, err := config.GetHostsConfig()
+		if err != nil {
+			return provision.OutcomeFailed, err
+		}
+		if inSync, err := hostsblock.InSync(entries); err == nil && inSync {
+			return provision.OutcomeSatisfied, nil
+		}
+		_, err = hosts.ApplyHosts(dryRun)
+		return provision.OutcomeChanged, err
+	case provision.StepApplyGit:
+		changed, err := sync.ApplyGitSettings(dryRun)
+		if err != nil {
+			return provision.OutcomeFailed, err
+		}
+		if !changed {
+			return provision.OutcomeSatisfied, nil
+		}
+		return provision.OutcomeChanged, nil
+	case provision.StepApplyServices:
+		changed, err := servicescmd.ApplyServices(dryRun)
+		if err != nil {
+			return provision.OutcomeFailed, err
+		}
+		if !changed {
+			return provision.OutcomeSatisfied, nil
+		}
+		return provision.OutcomeChanged, nil
+	default:
+		return provision.OutcomeFailed, fmt.Errorf("unknown provisioning step '%s'", step.Kind)
+	}
+}
+
+// toolAvailable reports whether a tool needs no install, either present, managed externally
+// or not meant for this platform
+func toolAvailable(tool string) bool {
+	return config.IsManagedExternally(tool) || !config.IsToolSupported(tool) || pkgmanager.IsAvailable(tool)
+}
+
+// printProvisionSummary reports each step's outcome and fails if any step failed
+func printProvisionSummary(profileName string, results []stepResult, dryRun bool) error {
+	o := palantir.GetGlobalOutputHandler()
+
+	changedLabel := string(provision.OutcomeChanged)
+	if dryRun {
+		changedLabel = "would change"
+	}
+
+	var summary strings.Builder
+	var failed []string
+	counts := make(map[provision.Outcome]int)
+	for _, result := range results {
+		counts[result.outcome]++
+		status, label := "🔄", changedLabel
+		switch result.outcome {
+		case provision.OutcomeSatisfied:
+			status, label = "✅", string(provision.OutcomeSatisfied)
+		case provision.OutcomeFailed:
+			status, label = "❌", string(provision.OutcomeFailed)
+			failed = append(failed, result.step.String())
+		}
+		summary.WriteString(fmt.Sprintf("  %s %-40s %-12s %s\n", status, result.step, label, result.duration.Round(time.Second)))
+	}
+	summary.WriteString(fmt.Sprintf("\n  %d satisfied, %d %s, %d failed\n",
+		counts[provision.OutcomeSatisfied], counts[provision.OutcomeChanged], changedLabel, counts[provision.OutcomeFailed]))
+	fmt.Println(charm.RenderBox(fmt.Sprintf("Provision '%s' Summary", profileName), summary.String(), charm.ActiveTheme().Accent, false))
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d steps failed: %s", len(failed), len(results), strings.Join(failed, ", "))
+	}
+
+	if counts[provision.OutcomeChanged] == 0 {
+		o.PrintSuccess(fmt.Sprintf("Profile '%s' already satisfied, nothing to change", profileName))
+		return nil
+	}
+	o.PrintSuccess(fmt.Sprintf("Profile '%s' provisioned successfully", profileName))
+	return nil
+}
+
+// listProfiles shows the provisioning profiles defined in settings.yaml
+func listProfiles() error {
+	o := palantir.GetGlobalOutputHandler()
+	names, err := config.GetProvisionProfileNames()
+	if err != nil {
+		return errors.NewConfigurationError(constants.OpProvision, "load-profiles", err)
+	}
+
+	if len(names) == 0 {
+		o.PrintInfo("No provisioning profiles defined")
+		o.PrintInfo("Add a 'provision.profiles' section to %s, see 'anvil provision --help'", constants.ANVIL_CONFIG_FILE)
+		return nil
+	}
+
+	o.PrintHeader("Provisioning Profiles")
+	for _, name := range names {
+		profile, _ := config.GetProvisionProfile(name)
+		if profile.Description != "" {
+			o.PrintInfo("  • %s - %s", name, profile.Description)
+		} else {
+			o.PrintInfo("  • %s", name)
+		}
+	}
+	fmt.Println()
+	o.PrintInfo("Run 'anvil provision <profile>' to apply one")
+	return nil
+}
+
+func init() {
+	ProvisionCmd.Flags().Bool("dry-run", false, "Show what would be provisioned without making changes")
+	ProvisionCmd.Flags().Bool("plan", false, "Print the full plan of the profile as a tree without making changes")
+	ProvisionCmd.Flags().Bool("diff", false, "Show only the steps whose inputs changed since the last run of the profile, without making changes")
+	ProvisionCmd.Flags().Bool("json", false, "With --plan or --diff, export the result as JSON for review")
+	ProvisionCmd.Flags().Bool("no-sizes", false, "With --plan, skip looking up download sizes")
+	ProvisionCmd.Flags().Bool("record", false, "Record the run (steps, durations, brew output, errors) under ~/.anvil/sessions")
+}
@@ -0,0 +1,235 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/audit"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/release"
+	"github.com/0xjuanma/anvil/internal/system"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+	"github.com/0xjuanma/anvil/internal/utils"
+	"github.com/0xjuanma/palantir"
+	"github.com/spf13/cobra"
+)
+
+// ReleaseCmd is a maintainer tool, hidden from help so users don't stumble on it
+var ReleaseCmd = &cobra.Command{
+	Use:    "release <version>",
+	Short:  "Build, sign and package release binaries (maintainers only)",
+	Long:   constants.RELEASE_COMMAND_LONG_DESCRIPTION,
+	Args:   cobra.ExactArgs(1),
+	Hidden: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runReleaseCommand(cmd, args[0]); err != nil {
+			palantir.GetGlobalOutputHandler().PrintError("Release failed: %v", err)
+			os.Exit(errors.ExitCode(err))
+		}
+	},
+}
+
+// releaseOptions are the flags of a release run
+type releaseOptions struct {
+	version       string
+	sourceDir     string
+	outDir        string
+	targets       []release.Target
+	signIdentity  string
+	notaryProfile string
+	dryRun        bool
+}
+
+// runReleaseCommand checks the release can be built here, then builds every target
+func runReleaseCommand(cmd *cobra.Command, version string) error {
+	opts, err := parseOptions(cmd, version)
+	if err != nil {
+		return errors.NewValidationError(constants.OpRelease, version, err)
+	}
+
+	o := palantir.GetGlobalOutputHandler()
+	o.PrintHeader(fmt.Sprintf("Release %s", opts.version))
+	o.PrintInfo("Source: %s", opts.sourceDir)
+	o.PrintInfo("Output: %s", opts.outDir)
+	for _, target := range opts.targets {
+		o.PrintInfo("  • %s → %s, %s", target, release.BinaryFile(target), release.ArchiveFile(opts.version, target))
+	}
+	if opts.signIdentity == "" {
+		o.PrintInfo("Signing: skipped, pass --sign-identity to codesign")
+	} else if opts.notaryProfile == "" {
+		o.PrintInfo("Signing: %s, notarization skipped", opts.signIdentity)
+	} else {
+		o.PrintInfo("Signing: %s, notarized with profile %s", opts.signIdentity, opts.notaryProfile)
+	}
+	warnIfUntagged(opts)
+	fmt.Println()
+
+	if opts.dryRun {
+		o.PrintInfo("Dry run - no binaries will be built")
+		for _, target := range opts.targets {
+			audit.Record("release", "build-binary", filepath.Join(opts.outDir, release.BinaryFile(target)), release.LDFlags(opts.version))
+		}
+		return nil
+	}
+
+	if err := utils.EnsureDirectory(opts.outDir); err != nil {
+		return errors.NewFileSystemError(constants.OpRelease, opts.outDir, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	var artifacts []string
+	for i, target := range opts.targets {
+		files, err := buildTarget(ctx, opts, target, i+1)
+		if err != nil {
+			return errors.NewInstallationError(constants.OpRelease, target.String(), err)
+		}
+		artifacts = append(artifacts, files...)
+	}
+
+	checksums, err := release.WriteChecksums(opts.outDir, artifacts)
+	if err != nil {
+		return errors.NewFileSystemError(constants.OpRelease, release.ChecksumsFile, err)
+	}
+
+	var summary strings.Builder
+	for _, artifact := range append(artifacts, checksums) {
+		summary.WriteString(fmt.Sprintf("  %s\n", filepath.Base(artifact)))
+	}
+	fmt.Println(charm.RenderBox(fmt.Sprintf("Release %s", opts.version), summary.String(), charm.ActiveTheme().Success, false))
+	o.PrintSuccess(fmt.Sprintf("Artifacts written to %s", opts.outDir))
+	return nil
+}
+
+// parseOptions reads the flags and refuses to run outside the anvil source tree
+func parseOptions(cmd *cobra.Command, version string) (releaseOptions, error) {
+	opts := releaseOptions{version: version}
+	if err := release.ValidateVersion(version); err != nil {
+		return opts, err
+	}
+
+	sourceDir, err := os.Getwd()
+	if err != nil {
+		return opts, err
+	}
+	if !release.IsSourceTree(sourceDir) {
+		return opts, fmt.Errorf("run from the root of the anvil source tree (module %s)", release.ModulePath)
+	}
+	opts.sourceDir = sourceDir
+
+	outDir, _ := cmd.Flags().GetString("out")
+	if !filepath.IsAbs(outDir) {
+		outDir = filepath.Join(sourceDir, outDir)
+	}
+	opts.outDir = outDir
+
+	targetValues, _ := cmd.Flags().GetStringSlice("target")
+	if opts.targets, err = release.ParseTargets(targetValues); err != nil {
+		return opts, err
+	}
+
+	opts.signIdentity, _ = cmd.Flags().GetString("sign-identity")
+	opts.notaryProfile, _ = cmd.Flags().GetString("notary-profile")
+	opts.dryRun, _ = cmd.Flags().GetBool("dry-run")
+
+	if opts.notaryProfile != "" && opts.signIdentity == "" {
+		return opts, fmt.Errorf("--notary-profile requires --sign-identity, Apple only notarizes signed binaries")
+	}
+	if opts.signIdentity != "" && !opts.dryRun {
+		if !system.IsMacOS() || !system.CommandExists("codesign") {
+			return opts, fmt.Errorf("signing requires macOS with the Xcode command line tools")
+		}
+		if opts.notaryProfile != "" && !system.CommandExists("xcrun") {
+			return opts, fmt.Errorf("notarization requires xcrun from the Xcode command line tools")
+		}
+	}
+	return opts, nil
+}
+
+// warnIfUntagged warns when HEAD is not the tagged commit, the binaries would not match the release
+func warnIfUntagged(opts releaseOptions) {
+	result, err := system.RunCommandInDirectory(opts.sourceDir, constants.GitCommand, "tag", "--points-at", "HEAD")
+	if err != nil || !result.Success {
+		return
+	}
+	for _, tag := range strings.Fields(result.Output) {
+		if tag == opts.version {
+			return
+		}
+	}
+	palantir.GetGlobalOutputHandler().PrintWarning("HEAD is not tagged %s, tag the commit before publishing these binaries", opts.version)
+}
+
+// buildTarget builds, signs and packages one target, returning the binary and archive paths
+func buildTarget(ctx context.Context, opts releaseOptions, target release.Target, index int) ([]string, error) {
+	spinner := charm.NewDotsSpinner(fmt.Sprintf("Building %s", target))
+	spinner.SetProgress(index, len(opts.targets))
+	spinner.Start()
+
+	binary, err := release.Build(ctx, opts.sourceDir, opts.outDir, opts.version, target)
+	if err != nil {
+		spinner.Error(fmt.Sprintf("Build of %s failed", target))
+		return nil, err
+	}
+
+	// macOS binaries are signed before archiving so the archive carries the signature
+	if opts.signIdentity != "" && target.OS == "darwin" {
+		spinner.SetDetail("Signing")
+		if err := release.Sign(ctx, opts.signIdentity, binary); err != nil {
+			spinner.Error(fmt.Sprintf("Signing %s failed", target))
+			return nil, err
+		}
+		if opts.notaryProfile != "" {
+			spinner.SetDetail("Waiting for notarization")
+			if err := release.Notarize(ctx, opts.notaryProfile, binary); err != nil {
+				spinner.Error(fmt.Sprintf("Notarizing %s failed", target))
+				return nil, err
+			}
+		}
+	}
+
+	spinner.SetDetail("Archiving")
+	archive := filepath.Join(opts.outDir, release.ArchiveFile(opts.version, target))
+	if err := release.Archive(binary, archive); err != nil {
+		spinner.Error(fmt.Sprintf("Archiving %s failed", target))
+		return nil, err
+	}
+
+	spinner.Success(fmt.Sprintf("Built %s", target))
+	return []string{binary, archive}, nil
+}
+
+func init() {
+	defaultTargets := make([]string, 0, len(release.DefaultTargets))
+	for _, target := range release.DefaultTargets {
+		defaultTargets = append(defaultTargets, target.String())
+	}
+
+	ReleaseCmd.Flags().String("out", "dist", "Directory the artifacts are written to, relative to the source tree")
+	ReleaseCmd.Flags().StringSlice("target", defaultTargets, "GOOS/GOARCH targets to build")
+	ReleaseCmd.Flags().String("sign-identity", "", "codesign identity, e.g. 'Developer ID Application: Name (TEAMID)'")
+	ReleaseCmd.Flags().String("notary-profile", "", "notarytool keychain profile used to notarize signed binaries")
+	ReleaseCmd.Flags().Bool("dry-run", false, "Show what would be built without building")
+}
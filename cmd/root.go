@@ -19,19 +19,50 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/0xjuanma/anvil/cmd/alias"
+	"github.com/0xjuanma/anvil/cmd/bootstrap"
 	"github.com/0xjuanma/anvil/cmd/clean"
 	"github.com/0xjuanma/anvil/cmd/config"
+	"github.com/0xjuanma/anvil/cmd/config/defaults"
+	"github.com/0xjuanma/anvil/cmd/config/restore"
+	"github.com/0xjuanma/anvil/cmd/config/validate"
 	"github.com/0xjuanma/anvil/cmd/doctor"
+	"github.com/0xjuanma/anvil/cmd/export"
+	"github.com/0xjuanma/anvil/cmd/hosts"
+	"github.com/0xjuanma/anvil/cmd/info"
 	"github.com/0xjuanma/anvil/cmd/initcmd"
 	"github.com/0xjuanma/anvil/cmd/install"
+	"github.com/0xjuanma/anvil/cmd/project"
+	"github.com/0xjuanma/anvil/cmd/provision"
+	"github.com/0xjuanma/anvil/cmd/release"
+	"github.com/0xjuanma/anvil/cmd/search"
+	"github.com/0xjuanma/anvil/cmd/self"
+	"github.com/0xjuanma/anvil/cmd/services"
+	"github.com/0xjuanma/anvil/cmd/sessions"
+	"github.com/0xjuanma/anvil/cmd/tour"
+	"github.com/0xjuanma/anvil/cmd/undo"
+	"github.com/0xjuanma/anvil/cmd/uninstall"
 	"github.com/0xjuanma/anvil/cmd/update"
+	"github.com/0xjuanma/anvil/internal/audit"
+	"github.com/0xjuanma/anvil/internal/brew"
+	anvilconfig "github.com/0xjuanma/anvil/internal/config"
 	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/i18n"
+	"github.com/0xjuanma/anvil/internal/reminder"
+	"github.com/0xjuanma/anvil/internal/summary"
+	"github.com/0xjuanma/anvil/internal/system"
 	"github.com/0xjuanma/anvil/internal/terminal/charm"
 	"github.com/0xjuanma/anvil/internal/version"
+	"github.com/0xjuanma/palantir"
 	"github.com/spf13/cobra"
 )
 
+// recorder collects the events of the running command for the summary footer
+var recorder *summary.Recorder
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   constants.ANVIL,
@@ -46,22 +77,267 @@ var rootCmd = &cobra.Command{
 
 		showWelcomeBanner()
 	},
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		recorder = summary.Start(time.Now())
+		// Settings changes made by this run are logged with the invocation, see 'anvil undo'
+		anvilconfig.SetTransactionCommand(strings.TrimSpace(cmd.CommandPath() + " " + strings.Join(args, " ")))
+		// Defaults from settings.yaml come first so a default such as 'yes: true' takes effect below
+		for _, err := range defaults.Apply(cmd) {
+			palantir.GetGlobalOutputHandler().PrintWarning("Ignoring command default: %v", err)
+		}
+		if yesFlag, _ := cmd.Flags().GetBool("yes"); yesFlag || os.Getenv(constants.AssumeYesEnvVar) == "true" {
+			charm.SetAssumeYes(true)
+		}
+		if strictFlag, _ := cmd.Flags().GetBool("strict"); strictFlag || os.Getenv(constants.StrictEnvVar) == "true" {
+			charm.SetStrict(true)
+		}
+		// Spinners and dashboards redraw the terminal, logs and pipes get one line per event
+		if plainFlag, _ := cmd.Flags().GetBool("plain"); plainFlag || os.Getenv(constants.PlainEnvVar) == "true" || !charm.StdoutIsTerminal() {
+			charm.SetPlain(true)
+		}
+		applyConfirmationPolicy()
+		// A corrupted settings.yaml breaks the command, offer a backup once --yes is known
+		if _, err := anvilconfig.LoadConfig(); err != nil && cmd != restore.RestoreSettingsCmd {
+			restore.OfferRestore(err)
+		}
+		applySafeMode(cmd)
+		enforceMinimumVersion(cmd)
+		if auditFlag, _ := cmd.Flags().GetBool("audit"); auditFlag {
+			enableAuditMode(cmd, args)
+		}
+		applyNetwork()
+		applyBrewPolicy()
+	},
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		if audit.IsEnabled() {
+			writeAuditReport()
+			return
+		}
+		// Written to stderr so piped output such as 'anvil info --json' stays clean. The footer
+		// already suggests pushing changed configs, so the reminder only follows commands without one.
+		if !printSummaryFooter(cmd) {
+			reminder.MaybeRemindPush(cmd.CommandPath(), time.Now(), os.Stderr)
+		}
+		exitOnStrictWarnings()
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
+	// Expand user-defined aliases from settings.yaml before cobra resolves the command
+	if args, expanded := alias.ExpandUserAlias(rootCmd, os.Args[1:]); expanded {
+		rootCmd.SetArgs(args)
+	}
+
+	// Theme and language settings apply before anything renders, including help output
+	applyTheme()
+	applyLanguage()
+
 	err := rootCmd.Execute()
 	if err != nil {
-		os.Exit(1)
+		os.Exit(errors.ExitCode(err))
+	}
+}
+
+// applySafeMode lets read-only commands run on what could be parsed when settings.yaml is still
+// corrupt, and tells every other command how to get a working file back
+func applySafeMode(cmd *cobra.Command) {
+	if _, err := anvilconfig.LoadConfig(); !anvilconfig.IsCorruptSettings(err) || cmd == restore.RestoreSettingsCmd || cmd == validate.ValidateCmd {
+		return
+	}
+	o := palantir.GetGlobalOutputHandler()
+	if !allowsSafeMode(cmd) {
+		o.PrintInfo("💡 Run 'anvil config validate' to see which sections of %s need fixing", constants.ANVIL_CONFIG_FILE)
+		o.PrintInfo("💡 Read-only commands such as 'anvil config show', 'anvil install --list' and 'anvil doctor' still run in safe mode")
+		return
+	}
+
+	anvilconfig.EnableSafeMode()
+	o.PrintWarning("Safe mode: %s is corrupted, broken sections fall back to defaults and nothing will be saved", constants.ANVIL_CONFIG_FILE)
+	if problems, err := anvilconfig.SettingsProblems(); err == nil {
+		for _, problem := range problems {
+			o.PrintWarning("  %s", problem)
+		}
+	}
+	o.PrintInfo("💡 Run 'anvil config validate' for details, or 'anvil config restore-settings' to restore a backup")
+	fmt.Println()
+}
+
+// allowsSafeMode reports whether the command only reads settings, so it can run in safe mode
+func allowsSafeMode(cmd *cobra.Command) bool {
+	if cmd.Annotations[constants.SafeModeAnnotation] == "true" {
+		return true
+	}
+	if cmd == install.InstallCmd {
+		listFlag, _ := cmd.Flags().GetBool("list")
+		treeFlag, _ := cmd.Flags().GetBool("tree")
+		return listFlag || treeFlag
+	}
+	return false
+}
+
+// applyTheme applies the output theme and color settings from settings.yaml. NO_COLOR,
+// handled when output is initialized, always wins over a configured theme.
+func applyTheme() {
+	uiConfig, err := anvilconfig.GetUIConfig()
+	if err != nil {
+		return
+	}
+	if !uiConfig.ColorEnabled() {
+		charm.DisableColor()
+	}
+	if uiConfig.Theme != "" {
+		if err := charm.SetTheme(uiConfig.Theme); err != nil {
+			palantir.GetGlobalOutputHandler().PrintWarning("Ignoring ui.theme in %s: %v", constants.ANVIL_CONFIG_FILE, err)
+		}
+	}
+}
+
+// applyLanguage picks the message language from ui.language, or from LANG when unset
+func applyLanguage() {
+	language := ""
+	if uiConfig, err := anvilconfig.GetUIConfig(); err == nil {
+		language = uiConfig.Language
+	}
+	if err := i18n.SetLanguage(language); err != nil {
+		palantir.GetGlobalOutputHandler().PrintWarning("Ignoring ui.language in %s: %v", constants.ANVIL_CONFIG_FILE, err)
+	}
+}
+
+// applyConfirmationPolicy applies the confirmations section of settings.yaml to every prompt
+func applyConfirmationPolicy() {
+	confirmations, err := anvilconfig.GetConfirmationsConfig()
+	if err != nil {
+		return
+	}
+	policy := charm.ConfirmPolicy{Modes: confirmations.Policies, YesAllowed: confirmations.YesAllowed}
+	if err := charm.SetConfirmPolicy(policy); err != nil {
+		palantir.GetGlobalOutputHandler().PrintWarning("Ignoring invalid confirmations in %s: %v", constants.ANVIL_CONFIG_FILE, err)
+	}
+}
+
+// applyNetwork routes downloads, git and brew through the proxy and CA bundle from settings.yaml
+func applyNetwork() {
+	networkConfig, err := anvilconfig.GetNetworkConfig()
+	if err != nil {
+		return
+	}
+	if err := system.SetNetwork(networkConfig.Settings()); err != nil {
+		palantir.GetGlobalOutputHandler().PrintWarning("Ignoring network settings in %s: %v", constants.ANVIL_CONFIG_FILE, err)
+	}
+}
+
+// applyBrewPolicy injects the Homebrew policy from settings.yaml into every brew invocation.
+// Before 'anvil init' there are no settings and brew runs unchanged.
+func applyBrewPolicy() {
+	if brewConfig, err := anvilconfig.GetBrewConfig(); err == nil {
+		brew.SetPolicyEnv(brewConfig.Env())
+	}
+}
+
+// enableAuditMode starts recording intended actions and forces dry-run on commands that support it
+func enableAuditMode(cmd *cobra.Command, args []string) {
+	audit.Enable(strings.TrimSpace(cmd.CommandPath() + " " + strings.Join(args, " ")))
+	if cmd.Flags().Lookup("dry-run") != nil {
+		_ = cmd.Flags().Set("dry-run", "true")
+	}
+
+	o := palantir.GetGlobalOutputHandler()
+	o.PrintWarning("Audit mode: no changes will be made, intended actions are recorded to a signed report")
+}
+
+// printSummaryFooter writes what the command changed, how long it took and what to do next
+// to stderr, unless --quiet is set or nothing changed. It reports whether a footer was printed.
+func printSummaryFooter(cmd *cobra.Command) bool {
+	recorded, elapsed := recorder.Stop(time.Now())
+	if quietFlag, _ := cmd.Flags().GetBool("quiet"); quietFlag || os.Getenv(constants.QuietEnvVar) == "true" {
+		return false
+	}
+	if len(summary.Changes(recorded)) == 0 {
+		return false
+	}
+
+	var unpushed []string
+	if cfg, err := anvilconfig.LoadConfig(); err == nil && cfg.GitHub.ConfigRepo != "" && cfg.GitHub.LocalPath != "" {
+		unpushed = reminder.FindUnpushedApps(cfg)
+	}
+	fmt.Fprint(os.Stderr, "\n"+summary.Render(recorded, elapsed, summary.Suggestions(recorded, unpushed)))
+	return true
+}
+
+// writeAuditReport writes the signed audit report and prints its location
+func writeAuditReport() {
+	o := palantir.GetGlobalOutputHandler()
+	path, err := audit.WriteReport()
+	if err != nil {
+		o.PrintError("Failed to write audit report: %v", err)
+		return
+	}
+	o.PrintSuccess(fmt.Sprintf("Audit report written to %s", path))
+}
+
+// exitOnStrictWarnings fails the run when --strict is set and any warning was printed,
+// listing the warnings so a CI log shows what to fix without scrolling back
+func exitOnStrictWarnings() {
+	warnings := charm.StrictWarnings()
+	if !charm.IsStrict() || len(warnings) == 0 {
+		return
+	}
+
+	var content strings.Builder
+	content.WriteString(fmt.Sprintf("  %d warning(s) treated as errors:\n", len(warnings)))
+	for _, warning := range warnings {
+		content.WriteString(fmt.Sprintf("  • %s\n", warning))
 	}
+	fmt.Fprintln(os.Stderr, charm.RenderBox("Strict Mode", content.String(), charm.ActiveTheme().Error, false))
+	os.Exit(1)
+}
+
+// versionExemptCommands still run when settings require a newer anvil, they upgrade anvil,
+// diagnose the requirement or roll back the settings that raised it
+var versionExemptCommands = map[string]bool{
+	"update":                  true,
+	"self":                    true,
+	"doctor":                  true,
+	"help":                    true,
+	"completion":              true,
+	"config restore-settings": true,
+}
+
+// enforceMinimumVersion blocks commands when the team layer or settings.yaml require a newer anvil
+func enforceMinimumVersion(cmd *cobra.Command) {
+	err := anvilconfig.CheckVersionRequirement()
+	if err == nil {
+		return
+	}
+
+	output := palantir.GetGlobalOutputHandler()
+	tooOld, ok := err.(*anvilconfig.VersionTooOldError)
+	if !ok {
+		output.PrintWarning("Ignoring min_anvil_version: %v", err)
+		return
+	}
+
+	path := strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" ")
+	topLevel, _, _ := strings.Cut(path, " ")
+	if !cmd.HasParent() || versionExemptCommands[path] || versionExemptCommands[topLevel] {
+		output.PrintWarning("%v. Upgrade with 'anvil update'", tooOld)
+		return
+	}
+
+	content := fmt.Sprintf("  %s requires anvil %s or newer, this is %s.\n\n", tooOld.Requirement.Source, tooOld.Requirement.Minimum, tooOld.Current)
+	content += "  Upgrade with 'anvil update' (or 'brew upgrade anvil'), then run the command again.\n"
+	content += "  'anvil doctor anvil-version' shows where the requirement comes from."
+	fmt.Fprintln(os.Stderr, charm.RenderBox("Upgrade Required", content, charm.ActiveTheme().Error, false))
+	os.Exit(1)
 }
 
 // showWelcomeBanner displays the enhanced welcome banner
 func showWelcomeBanner() {
 	// Main banner
 	bannerContent := fmt.Sprintf("%s\n🔥 One CLI to rule them all 🔥\n\tversion: %s\n\n", constants.AnvilLogo, version.GetVersion())
-	fmt.Println(charm.RenderBox("", bannerContent, "#FF6B9D", true))
+	fmt.Println(charm.RenderBox("", bannerContent, charm.ActiveTheme().Primary, true))
 
 	quickStart := `
   anvil init              					Initialize your environment
@@ -71,8 +347,9 @@ func showWelcomeBanner() {
   anvil config push [app-name]				Push your app configurations to GitHub
   anvil config pull [app-name]				Pull your app configurations from GitHub
   anvil config sync [app-name]				Sync your app configurations to your local machine
+  anvil pull/push/sync [app-name]			Shortcuts for the config subcommands above
 `
-	fmt.Println(charm.RenderBox("Quick Start", quickStart, "#00D9FF", false))
+	fmt.Println(charm.RenderBox("Quick Start", quickStart, charm.ActiveTheme().Accent, false))
 
 	// Footer
 	fmt.Println()
@@ -81,20 +358,43 @@ func showWelcomeBanner() {
 
 // showVersionInfo displays the version information with branding
 func showVersionInfo() {
-	fmt.Println(charm.RenderBox("ANVIL CLI", version.GetVersion(), "#FF6B9D", true))
+	fmt.Println(charm.RenderBox("ANVIL CLI", version.GetVersion(), charm.ActiveTheme().Primary, true))
 }
 
 func init() {
 	rootCmd.AddCommand(initcmd.InitCmd)
 	rootCmd.AddCommand(install.InstallCmd)
+	rootCmd.AddCommand(uninstall.UninstallCmd)
 	rootCmd.AddCommand(config.ConfigCmd)
 	rootCmd.AddCommand(doctor.DoctorCmd)
 	rootCmd.AddCommand(clean.CleanCmd)
 	rootCmd.AddCommand(update.UpdateCmd)
+	rootCmd.AddCommand(self.SelfCmd)
+	rootCmd.AddCommand(info.InfoCmd)
+	rootCmd.AddCommand(provision.ProvisionCmd)
+	rootCmd.AddCommand(project.ProjectCmd)
+	rootCmd.AddCommand(bootstrap.BootstrapCmd)
+	rootCmd.AddCommand(sessions.SessionsCmd)
+	rootCmd.AddCommand(hosts.HostsCmd)
+	rootCmd.AddCommand(services.ServicesCmd)
+	rootCmd.AddCommand(release.ReleaseCmd)
+	rootCmd.AddCommand(search.SearchCmd)
+	rootCmd.AddCommand(export.ExportCmd)
+	rootCmd.AddCommand(tour.TourCmd)
+	rootCmd.AddCommand(undo.UndoCmd)
+	rootCmd.AddCommand(alias.PullCmd)
+	rootCmd.AddCommand(alias.PushCmd)
+	rootCmd.AddCommand(alias.SyncCmd)
+	rootCmd.AddCommand(alias.ImportCmd)
 
 	// Add version flag
 	rootCmd.Flags().BoolP("version", "v", false, "Show version information")
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	rootCmd.PersistentFlags().BoolP("yes", "y", false, "Answer yes to confirmation prompts for unattended runs")
+	rootCmd.PersistentFlags().Bool("strict", false, "Treat warnings as errors and exit non-zero, for CI runs")
+	rootCmd.PersistentFlags().Bool("plain", false, "Print progress as timestamped single lines, the default when output is not a terminal")
+	rootCmd.PersistentFlags().BoolP("quiet", "q", false, "Leave out the summary footer of changes and next steps printed after a command")
+	rootCmd.PersistentFlags().Bool("audit", false, "Read-only mode: force dry-run and write a signed JSON report of intended actions")
 
 	// Set custom help template
 	rootCmd.SetHelpFunc(customHelpFunc)
@@ -129,18 +429,18 @@ func customHelpFunc(cmd *cobra.Command, args []string) {
 		}
 		formattedDesc.WriteString("\n")
 
-		fmt.Println(charm.RenderBox("About", formattedDesc.String(), "#FF6B9D", false))
+		fmt.Println(charm.RenderBox("About", formattedDesc.String(), charm.ActiveTheme().Primary, false))
 	} else if cmd.Short != "" {
-		fmt.Println(charm.RenderBox("", "\n  "+cmd.Short+"\n", "#FF6B9D", false))
+		fmt.Println(charm.RenderBox("", "\n  "+cmd.Short+"\n", charm.ActiveTheme().Primary, false))
 	}
 
 	// Usage section
 	if cmd.HasAvailableSubCommands() {
 		usageContent := fmt.Sprintf("\n  %s [command] [flags]\n", cmd.Name())
-		fmt.Println(charm.RenderBox("Usage", usageContent, "#00D9FF", false))
+		fmt.Println(charm.RenderBox("Usage", usageContent, charm.ActiveTheme().Accent, false))
 	} else {
 		usageContent := fmt.Sprintf("\n  %s\n", cmd.UseLine())
-		fmt.Println(charm.RenderBox("Usage", usageContent, "#00D9FF", false))
+		fmt.Println(charm.RenderBox("Usage", usageContent, charm.ActiveTheme().Accent, false))
 	}
 
 	// Available Commands
@@ -155,7 +455,7 @@ func customHelpFunc(cmd *cobra.Command, args []string) {
 		}
 		commandsContent.WriteString("\n")
 
-		fmt.Println(charm.RenderBox("Available Commands", commandsContent.String(), "#00FF87", false))
+		fmt.Println(charm.RenderBox("Available Commands", commandsContent.String(), charm.ActiveTheme().Success, false))
 	}
 
 	// Flags
@@ -164,7 +464,7 @@ func customHelpFunc(cmd *cobra.Command, args []string) {
 		flagsContent.WriteString("\n")
 		flagsContent.WriteString(cmd.Flags().FlagUsages())
 
-		fmt.Println(charm.RenderBox("Flags", flagsContent.String(), "#FFD700", false))
+		fmt.Println(charm.RenderBox("Flags", flagsContent.String(), charm.ActiveTheme().Warning, false))
 	}
 
 	// Footer
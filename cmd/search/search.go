@@ -0,0 +1,259 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package search
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/0xjuanma/anvil/cmd/install"
+	"github.com/0xjuanma/anvil/internal/audit"
+	"github.com/0xjuanma/anvil/internal/brew"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+	"github.com/0xjuanma/anvil/internal/utils"
+	"github.com/0xjuanma/palantir"
+	"github.com/charmbracelet/x/term"
+	"github.com/spf13/cobra"
+)
+
+var SearchCmd = &cobra.Command{
+	Use:   "search <term>",
+	Short: "Search Homebrew and install or group the packages you pick",
+	Long:  constants.SEARCH_COMMAND_LONG_DESCRIPTION,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runSearchCommand(cmd, args[0]); err != nil {
+			palantir.GetGlobalOutputHandler().PrintError("Search failed: %v", err)
+			os.Exit(errors.ExitCode(err))
+		}
+	},
+}
+
+// runSearchCommand lists matching packages and acts on the ones picked
+func runSearchCommand(cmd *cobra.Command, searchTerm string) error {
+	o := palantir.GetGlobalOutputHandler()
+	limit, _ := cmd.Flags().GetInt("limit")
+	refresh, _ := cmd.Flags().GetBool("refresh")
+	listOnly, _ := cmd.Flags().GetBool("list")
+	groupName, _ := cmd.Flags().GetString("group")
+
+	spinner := charm.NewDotsSpinner(fmt.Sprintf("Searching Homebrew for '%s'", searchTerm))
+	spinner.Start()
+	results, err := brew.Search(searchTerm, brew.SearchOptions{
+		Limit:    limit,
+		CacheDir: filepath.Join(config.GetAnvilConfigDirectory(), "cache", "search"),
+		Refresh:  refresh,
+	})
+	if err != nil {
+		spinner.Error("Search failed")
+		return errors.NewInstallationError(constants.OpSearch, searchTerm, err)
+	}
+	spinner.Success(fmt.Sprintf("Found %d packages", len(results)))
+
+	if len(results) == 0 {
+		o.PrintInfo("No formulae or casks match '%s'", searchTerm)
+		return nil
+	}
+	printResults(results)
+
+	// Picking needs someone at the keyboard
+	if listOnly || !term.IsTerminal(os.Stdin.Fd()) {
+		return nil
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	picked, ok := promptSelection(reader, results)
+	if !ok || len(picked) == 0 {
+		o.PrintInfo("Nothing selected")
+		return nil
+	}
+
+	if groupName == "" {
+		var install bool
+		if install, groupName, ok = promptAction(reader); !ok {
+			o.PrintInfo("Cancelled")
+			return nil
+		}
+		if install {
+			if audit.IsEnabled() {
+				return auditPicked("install-package", "", picked)
+			}
+			return installPicked(picked)
+		}
+	}
+	if audit.IsEnabled() {
+		return auditPicked("add-to-group", groupName, picked)
+	}
+	return addPickedToGroup(groupName, picked)
+}
+
+// auditPicked records the action audit mode skips for each picked package
+func auditPicked(action, groupName string, picked []brew.SearchResult) error {
+	for _, result := range picked {
+		audit.Record("search", action, result.Entry(), groupName)
+	}
+	if groupName != "" {
+		palantir.GetGlobalOutputHandler().PrintInfo("Audit mode - would add %s to group '%s'", joinEntries(picked), groupName)
+	} else {
+		palantir.GetGlobalOutputHandler().PrintInfo("Audit mode - would install %s", joinEntries(picked))
+	}
+	return nil
+}
+
+// printResults lists results numbered for picking, marking installed packages
+func printResults(results []brew.SearchResult) {
+	fmt.Println()
+	for i, result := range results {
+		mark := " "
+		if result.Installed {
+			mark = "✓"
+		}
+		line := fmt.Sprintf("%3d. %s %-30s %-7s", i+1, mark, result.Entry(), result.Type)
+		if result.Description != "" {
+			line += " " + result.Description
+		}
+		fmt.Println(strings.TrimRight(line, " "))
+	}
+	fmt.Println()
+}
+
+// promptSelection asks which results to act on, false means the input was closed
+func promptSelection(reader *bufio.Reader, results []brew.SearchResult) ([]brew.SearchResult, bool) {
+	for {
+		fmt.Print("Pick packages by number (e.g. 1,3-5), or press enter to quit: ")
+		answer, err := reader.ReadString('\n')
+		answer = strings.TrimSpace(answer)
+		if answer == "" {
+			if err != nil {
+				fmt.Println()
+			}
+			return nil, err == nil
+		}
+
+		indexes, parseErr := utils.ParseSelection(answer, len(results))
+		if parseErr == nil {
+			picked := make([]brew.SearchResult, 0, len(indexes))
+			for _, index := range indexes {
+				picked = append(picked, results[index])
+			}
+			return picked, true
+		}
+		if err != nil {
+			fmt.Println()
+			return nil, false
+		}
+		fmt.Printf("  %v\n", parseErr)
+	}
+}
+
+// promptAction asks whether to install the picked packages now or add them to a group
+func promptAction(reader *bufio.Reader) (install bool, group string, ok bool) {
+	for {
+		fmt.Print("Install now or add to a group? [i]nstall / [g]roup / [c]ancel: ")
+		answer, err := reader.ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "i", "install":
+			return true, "", true
+		case "g", "group":
+			group, ok := promptGroup(reader)
+			return false, group, ok
+		case "c", "cancel", "q", "quit":
+			return false, "", false
+		}
+		if err != nil {
+			// Input closed, treat as cancel so nothing changes unattended
+			fmt.Println()
+			return false, "", false
+		}
+	}
+}
+
+// promptGroup asks for the group to add packages to, listing the existing ones
+func promptGroup(reader *bufio.Reader) (string, bool) {
+	if groups, err := config.GetAvailableGroups(); err == nil && len(groups) > 0 {
+		names := make([]string, 0, len(groups))
+		for name := range groups {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Printf("Groups: %s\n", strings.Join(names, ", "))
+	}
+	for {
+		fmt.Print("Group name (new groups are created): ")
+		answer, err := reader.ReadString('\n')
+		if group := strings.TrimSpace(answer); group != "" {
+			return group, true
+		}
+		if err != nil {
+			fmt.Println()
+			return "", false
+		}
+	}
+}
+
+// installPicked installs each picked package, continuing past failures
+func installPicked(picked []brew.SearchResult) error {
+	o := palantir.GetGlobalOutputHandler()
+	var failed []string
+	for _, result := range picked {
+		if err := install.InstallTarget(result.Entry(), false); err != nil {
+			o.PrintError("%v", err)
+			failed = append(failed, result.Entry())
+		}
+	}
+	if len(failed) > 0 {
+		return errors.NewInstallationError(constants.OpSearch, strings.Join(failed, ", "),
+			fmt.Errorf("%d of %d packages failed to install", len(failed), len(picked)))
+	}
+	return nil
+}
+
+// addPickedToGroup adds each picked package to a group in settings.yaml without installing it
+func addPickedToGroup(groupName string, picked []brew.SearchResult) error {
+	o := palantir.GetGlobalOutputHandler()
+	for _, result := range picked {
+		if err := config.AddAppToGroup(groupName, result.Entry()); err != nil {
+			return errors.NewConfigurationError(constants.OpSearch, groupName, err)
+		}
+	}
+	o.PrintSuccess(fmt.Sprintf("Added %s to group '%s'", joinEntries(picked), groupName))
+	o.PrintInfo("Install them with 'anvil install %s'", groupName)
+	return nil
+}
+
+// joinEntries lists the settings entries of results
+func joinEntries(results []brew.SearchResult) string {
+	entries := make([]string, len(results))
+	for i, result := range results {
+		entries[i] = result.Entry()
+	}
+	return strings.Join(entries, ", ")
+}
+
+func init() {
+	SearchCmd.Flags().Int("limit", 20, "Maximum number of results to show")
+	SearchCmd.Flags().Bool("refresh", false, "Ignore cached results and search Homebrew again")
+	SearchCmd.Flags().Bool("list", false, "Only list results, without prompting to pick")
+	SearchCmd.Flags().String("group", "", "Add picked packages to this group instead of asking")
+}
@@ -0,0 +1,290 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package self
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/0xjuanma/anvil/internal/audit"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/hosts"
+	"github.com/0xjuanma/anvil/internal/system"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+	"github.com/0xjuanma/palantir"
+	"github.com/spf13/cobra"
+)
+
+// confirmationWord must be typed to confirm a self destruct
+const confirmationWord = "destruct"
+
+var DestructCmd = &cobra.Command{
+	Use:   "destruct",
+	Short: "Remove all anvil data and completions",
+	Long:  constants.SELF_DESTRUCT_COMMAND_LONG_DESCRIPTION,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runDestructCommand(cmd); err != nil {
+			palantir.GetGlobalOutputHandler().PrintError("Self destruct failed: %v", err)
+			return
+		}
+	},
+}
+
+// selfArtifacts lists everything anvil has created outside of its binary
+type selfArtifacts struct {
+	anvilDir    string
+	completions []string
+	clonePath   string // Config repository clone at github.local_path outside ~/.anvil, left for manual removal
+	hostsBlock  bool   // The managed block anvil wrote to the hosts file
+}
+
+// hostsFile is the hosts file checked for the managed block, replaced in tests
+var hostsFile = hosts.Path
+
+// isEmpty reports whether nothing was found to remove or report
+func (a *selfArtifacts) isEmpty() bool {
+	return a.anvilDir == "" && len(a.completions) == 0 && a.clonePath == "" && !a.hostsBlock
+}
+
+// runDestructCommand removes everything anvil created after a typed confirmation
+func runDestructCommand(cmd *cobra.Command) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	backup, _ := cmd.Flags().GetBool("backup")
+	output := palantir.GetGlobalOutputHandler()
+	output.PrintHeader("Anvil Self Destruct")
+
+	homeDir, err := system.GetHomeDir()
+	if err != nil {
+		return errors.NewFileSystemError(constants.OpSelf, "home-dir", err)
+	}
+
+	localPath := ""
+	if cfg, err := config.LoadConfig(); err == nil {
+		localPath = cfg.GitHub.LocalPath
+	}
+
+	artifacts := collectArtifacts(homeDir, localPath)
+	if artifacts.isEmpty() {
+		output.PrintSuccess("Nothing to remove, anvil has left no files on this machine")
+		printBinaryHint(output)
+		return nil
+	}
+
+	displayArtifacts(output, artifacts)
+
+	if dryRun {
+		output.PrintInfo("DRY RUN: Nothing was removed")
+		recordArtifacts(artifacts, backup)
+		return nil
+	}
+
+	if !confirmDestruct(output) {
+		output.PrintInfo("Self destruct cancelled")
+		return nil
+	}
+
+	// Export the backup first so a failure leaves everything in place
+	if backup && artifacts.anvilDir != "" {
+		backupPath, err := backupAnvilDirectory(artifacts.anvilDir, homeDir)
+		if err != nil {
+			return errors.NewFileSystemError(constants.OpSelf, "backup", err)
+		}
+		output.PrintSuccess(fmt.Sprintf("Backup written to %s", backupPath))
+	}
+
+	failures := 0
+	if artifacts.hostsBlock {
+		failures += removeHostsBlock(output)
+	}
+
+	for _, completion := range artifacts.completions {
+		failures += removeArtifact(output, completion)
+	}
+
+	if artifacts.anvilDir != "" {
+		failures += removeArtifact(output, artifacts.anvilDir)
+	}
+
+	if failures > 0 {
+		output.PrintWarning("Self destruct finished with %d items that could not be removed", failures)
+	} else {
+		output.PrintSuccess("Anvil has been removed from this machine")
+	}
+	printBinaryHint(output)
+
+	return nil
+}
+
+// collectArtifacts finds every file and directory anvil may have created, given the
+// github.local_path of the config repository clone
+func collectArtifacts(homeDir, localPath string) *selfArtifacts {
+	artifacts := &selfArtifacts{}
+
+	anvilDir := filepath.Join(homeDir, constants.ANVIL_CONFIG_DIR)
+	if _, err := os.Stat(anvilDir); err == nil {
+		artifacts.anvilDir = anvilDir
+	}
+
+	// A clone outside ~/.anvil may be a repository the user cloned and works in, so it is only listed
+	if localPath != "" && !isInside(anvilDir, localPath) {
+		if _, err := os.Stat(filepath.Join(localPath, ".git")); err == nil {
+			artifacts.clonePath = localPath
+		}
+	}
+
+	if data, err := os.ReadFile(hostsFile()); err == nil && hosts.Block(string(data)) != "" {
+		artifacts.hostsBlock = true
+	}
+
+	for _, completion := range completionFiles(homeDir) {
+		if _, err := os.Lstat(completion); err == nil {
+			artifacts.completions = append(artifacts.completions, completion)
+		}
+	}
+
+	return artifacts
+}
+
+// isInside reports whether path is dir or located below it
+func isInside(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// completionFiles returns the usual locations for 'anvil completion' output under the home
+// directory. Completions in Homebrew's directories belong to the formula and go with 'brew uninstall anvil'.
+func completionFiles(homeDir string) []string {
+	name := constants.CompletionFileName
+	return []string{
+		filepath.Join(homeDir, ".zsh", "completions", "_"+name),
+		filepath.Join(homeDir, ".zfunc", "_"+name),
+		filepath.Join(homeDir, ".oh-my-zsh", "completions", "_"+name),
+		filepath.Join(homeDir, ".local", "share", "bash-completion", "completions", name),
+		filepath.Join(homeDir, ".config", "fish", "completions", name+".fish"),
+	}
+}
+
+// displayArtifacts lists what will be removed
+func displayArtifacts(output palantir.OutputHandler, artifacts *selfArtifacts) {
+	output.PrintWarning("The following will be permanently removed:")
+	if artifacts.anvilDir != "" {
+		output.PrintInfo("  📁 %s", artifacts.anvilDir)
+	}
+	for _, completion := range artifacts.completions {
+		output.PrintInfo("  ⌨️  %s", completion)
+	}
+	if artifacts.hostsBlock {
+		output.PrintInfo("  🌐 anvil block in %s", hostsFile())
+	}
+	fmt.Println()
+	printManualSteps(output, artifacts)
+}
+
+// printManualSteps lists what anvil created but leaves for the user to remove
+func printManualSteps(output palantir.OutputHandler, artifacts *selfArtifacts) {
+	if artifacts.clonePath == "" {
+		return
+	}
+	output.PrintInfo("💡 Left in place, remove it yourself if anvil created it:")
+	output.PrintInfo("   rm -rf %s   (config repository clone at github.local_path)", artifacts.clonePath)
+}
+
+// recordArtifacts adds the intended removals to the audit report
+func recordArtifacts(artifacts *selfArtifacts, backup bool) {
+	if backup && artifacts.anvilDir != "" {
+		audit.Record("self destruct", "backup-directory", artifacts.anvilDir, "")
+	}
+	if artifacts.hostsBlock {
+		audit.Record("self destruct", "remove-hosts-block", hostsFile(), "")
+	}
+	for _, completion := range artifacts.completions {
+		audit.Record("self destruct", "remove-file", completion, "")
+	}
+	if artifacts.anvilDir != "" {
+		audit.Record("self destruct", "remove-directory", artifacts.anvilDir, "")
+	}
+}
+
+// confirmDestruct asks for a yes/no confirmation followed by the typed confirmation word
+func confirmDestruct(output palantir.OutputHandler) bool {
+	if !charm.Confirm(charm.ConfirmDelete, "This permanently removes anvil's data from this machine. Continue?") {
+		return false
+	}
+
+	fmt.Printf("Type '%s' to confirm: ", confirmationWord)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(answer) == confirmationWord
+}
+
+// removeHostsBlock takes the managed block out of the hosts file after confirming the
+// privileged write, returning 1 on failure
+func removeHostsBlock(output palantir.OutputHandler) int {
+	path := hostsFile()
+	if !charm.Confirm(charm.ConfirmPrivileged, fmt.Sprintf("Remove the anvil block from %s? This may ask for your password.", path)) {
+		output.PrintInfo("Left the anvil block in %s, remove the lines between its markers yourself", path)
+		return 0
+	}
+	if _, err := hosts.Remove(); err != nil {
+		output.PrintWarning("Failed to remove the anvil block from %s: %v", path, err)
+		return 1
+	}
+	output.PrintSuccess(fmt.Sprintf("Removed the anvil block from %s", path))
+	return 0
+}
+
+// removeArtifact deletes a file or directory, returning 1 on failure
+func removeArtifact(output palantir.OutputHandler, path string) int {
+	if err := os.RemoveAll(path); err != nil {
+		output.PrintWarning("Failed to remove %s: %v", path, err)
+		return 1
+	}
+	output.PrintSuccess(fmt.Sprintf("Removed %s", path))
+	return 0
+}
+
+// printBinaryHint explains how to remove the anvil binary itself
+func printBinaryHint(output palantir.OutputHandler) {
+	executable, err := os.Executable()
+	if err != nil {
+		executable = "/usr/local/bin/anvil"
+	}
+	if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+		executable = resolved
+	}
+
+	output.PrintInfo("💡 To remove the anvil binary itself:")
+	if strings.Contains(executable, "/Cellar/") {
+		// The formula also owns the completions in Homebrew's share and etc directories
+		output.PrintInfo("   brew uninstall anvil   (also removes the completions Homebrew installed)")
+	} else {
+		output.PrintInfo("   sudo rm %s", executable)
+	}
+}
+
+func init() {
+	DestructCmd.Flags().Bool("backup", false, "Export ~/.anvil to a tar.gz archive in your home directory before removing it")
+	DestructCmd.Flags().Bool("dry-run", false, "Show what would be removed without removing anything")
+}
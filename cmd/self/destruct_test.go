@@ -0,0 +1,97 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package self
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/hosts"
+)
+
+func TestCollectArtifacts(t *testing.T) {
+	homeDir := t.TempDir()
+	if artifacts := collectArtifacts(homeDir, ""); !artifacts.isEmpty() {
+		t.Fatalf("Expected no artifacts in an empty home, got %+v", artifacts)
+	}
+
+	anvilDir := filepath.Join(homeDir, ".anvil")
+	outsideClone := filepath.Join(homeDir, "src", "dotfiles")
+	for _, dir := range []string{filepath.Join(anvilDir, "dotfiles", ".git"), filepath.Join(outsideClone, ".git"), filepath.Join(homeDir, ".zfunc")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(homeDir, ".zfunc", "_anvil"), []byte("#compdef anvil\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	artifacts := collectArtifacts(homeDir, filepath.Join(anvilDir, "dotfiles"))
+	if artifacts.anvilDir != anvilDir {
+		t.Errorf("Expected anvil directory to be found, got %q", artifacts.anvilDir)
+	}
+	if len(artifacts.completions) != 1 || !strings.HasSuffix(artifacts.completions[0], "_anvil") {
+		t.Errorf("Expected only the home completion to be listed, got %v", artifacts.completions)
+	}
+	if artifacts.clonePath != "" {
+		t.Errorf("A clone inside ~/.anvil goes with it, got clonePath %q", artifacts.clonePath)
+	}
+
+	if artifacts := collectArtifacts(homeDir, outsideClone); artifacts.clonePath != outsideClone {
+		t.Errorf("Expected the clone outside ~/.anvil to be listed, got %q", artifacts.clonePath)
+	}
+}
+
+func TestCollectArtifactsHostsBlock(t *testing.T) {
+	original := hostsFile
+	t.Cleanup(func() { hostsFile = original })
+	path := filepath.Join(t.TempDir(), "hosts")
+	hostsFile = func() string { return path }
+
+	os.WriteFile(path, []byte("127.0.0.1\tlocalhost\n"), 0644)
+	if artifacts := collectArtifacts(t.TempDir(), ""); artifacts.hostsBlock {
+		t.Error("Expected no hosts block in a hosts file anvil never wrote")
+	}
+
+	block := hosts.Render([]config.HostEntry{{IP: "127.0.0.1", Names: []string{"api.local"}}})
+	os.WriteFile(path, []byte("127.0.0.1\tlocalhost\n\n"+block), 0644)
+	if artifacts := collectArtifacts(t.TempDir(), ""); !artifacts.hostsBlock || artifacts.isEmpty() {
+		t.Errorf("Expected the managed hosts block to be found, got %+v", artifacts)
+	}
+}
+
+func TestBackupAnvilDirectory(t *testing.T) {
+	homeDir := t.TempDir()
+	anvilDir := filepath.Join(homeDir, ".anvil")
+	if err := os.MkdirAll(filepath.Join(anvilDir, "temp"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(anvilDir, "settings.yaml"), []byte("version: 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	backupPath, err := backupAnvilDirectory(anvilDir, homeDir)
+	if err != nil {
+		t.Fatalf("backupAnvilDirectory() error = %v", err)
+	}
+	if info, err := os.Stat(backupPath); err != nil || info.Size() == 0 {
+		t.Errorf("Expected a non-empty backup archive at %s", backupPath)
+	}
+}
@@ -0,0 +1,35 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package self
+
+import (
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/spf13/cobra"
+)
+
+var SelfCmd = &cobra.Command{
+	Use:   "self",
+	Short: "Manage the anvil installation itself",
+	Long:  constants.SELF_COMMAND_LONG_DESCRIPTION,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+func init() {
+	SelfCmd.AddCommand(DestructCmd)
+}
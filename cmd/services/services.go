@@ -0,0 +1,242 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package services
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/0xjuanma/anvil/internal/audit"
+	"github.com/0xjuanma/anvil/internal/brew"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/services"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+	"github.com/0xjuanma/palantir"
+	"github.com/spf13/cobra"
+)
+
+var ServicesCmd = &cobra.Command{
+	Use:   "services",
+	Short: "List, start and stop Homebrew services",
+	Long:  constants.SERVICES_COMMAND_LONG_DESCRIPTION,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List Homebrew services with their status and wanted state",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runListCommand(); err != nil {
+			palantir.GetGlobalOutputHandler().PrintError("Services failed: %v", err)
+			os.Exit(errors.ExitCode(err))
+		}
+	},
+}
+
+var startCmd = &cobra.Command{
+	Use:   "start <name>",
+	Short: "Start a service now and at login",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runActionCommand(args[0], config.ServiceStarted); err != nil {
+			palantir.GetGlobalOutputHandler().PrintError("Services failed: %v", err)
+			os.Exit(errors.ExitCode(err))
+		}
+	},
+}
+
+var stopCmd = &cobra.Command{
+	Use:   "stop <name>",
+	Short: "Stop a service and keep it from starting at login",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runActionCommand(args[0], config.ServiceStopped); err != nil {
+			palantir.GetGlobalOutputHandler().PrintError("Services failed: %v", err)
+			os.Exit(errors.ExitCode(err))
+		}
+	},
+}
+
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Start and stop services as the services section of settings.yaml asks",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		if _, err := ApplyServices(dryRun); err != nil {
+			palantir.GetGlobalOutputHandler().PrintError("Services failed: %v", err)
+			os.Exit(errors.ExitCode(err))
+		}
+	},
+}
+
+// ApplyServices starts and stops Homebrew services until they match the services section of
+// settings.yaml. It reports whether any service changed, or would change in a dry run.
+func ApplyServices(dryRun bool) (bool, error) {
+	wanted, err := config.GetServicesConfig()
+	if err != nil {
+		return false, errors.NewConfigurationError(constants.OpServices, "load-settings", err)
+	}
+	if len(wanted) == 0 {
+		return false, nil
+	}
+	if !brew.IsBrewInstalled() {
+		return false, errors.NewInstallationError(constants.OpServices, "brew", fmt.Errorf("Homebrew is not installed"))
+	}
+
+	o := palantir.GetGlobalOutputHandler()
+	states, err := services.Plan(wanted)
+	if err != nil {
+		return false, errors.NewConfigurationError(constants.OpServices, "plan", err)
+	}
+	drifted := services.Drifted(states)
+	if len(drifted) == 0 {
+		o.PrintAlreadyAvailable("Services are already in their wanted state")
+		return false, nil
+	}
+
+	if dryRun {
+		for _, state := range drifted {
+			o.PrintInfo("Dry run - would %s %s (%s)", verb(state.Want), state.Name, describeStatus(state))
+			audit.Record("services", verb(state.Want)+"-service", state.Name, state.Want)
+		}
+		return true, nil
+	}
+
+	changed, err := services.Apply(wanted)
+	for _, state := range changed {
+		o.PrintInfo("%s %s", capitalizedPast(state.Want), state.Name)
+	}
+	if err != nil {
+		return len(changed) > 0, errors.NewInstallationError(constants.OpServices, "apply", err)
+	}
+	o.PrintSuccess(fmt.Sprintf("Applied %d service changes", len(changed)))
+	return true, nil
+}
+
+// runListCommand prints every Homebrew service with its status and the state settings.yaml wants
+func runListCommand() error {
+	o := palantir.GetGlobalOutputHandler()
+	if !brew.IsBrewInstalled() {
+		return errors.NewInstallationError(constants.OpServices, "brew", fmt.Errorf("Homebrew is not installed"))
+	}
+	wanted, err := config.GetServicesConfig()
+	if err != nil {
+		return errors.NewConfigurationError(constants.OpServices, "load-settings", err)
+	}
+	listed, err := services.List()
+	if err != nil {
+		return errors.NewInstallationError(constants.OpServices, "list", err)
+	}
+
+	o.PrintHeader("Homebrew Services")
+	rows := make([][]string, 0, len(listed))
+	seen := make(map[string]bool, len(listed))
+	drifted := 0
+	for _, service := range listed {
+		seen[service.Name] = true
+		want := wanted[service.Name]
+		state := services.State{Name: service.Name, Want: want, Status: service.Status, Installed: true}
+		if want != "" && !state.InSync() {
+			drifted++
+			want += " ⚠️"
+		}
+		rows = append(rows, []string{service.Name, service.Status, service.User, want})
+	}
+	for name, want := range wanted {
+		if !seen[name] {
+			state := services.State{Name: name, Want: want}
+			if !state.InSync() {
+				drifted++
+				want += " ⚠️"
+			}
+			rows = append(rows, []string{name, "not installed", "", want})
+		}
+	}
+
+	if len(rows) == 0 {
+		o.PrintInfo("No formulas with services are installed")
+		return nil
+	}
+	fmt.Println(charm.RenderTable([]string{"Service", "Status", "User", "Wanted"}, rows))
+
+	if drifted > 0 {
+		o.PrintWarning("%d service(s) are not in the state %s wants, run 'anvil services apply'", drifted, constants.ANVIL_CONFIG_FILE)
+	}
+	return nil
+}
+
+// runActionCommand starts or stops a single service
+func runActionCommand(name, want string) error {
+	o := palantir.GetGlobalOutputHandler()
+	if !brew.IsBrewInstalled() {
+		return errors.NewInstallationError(constants.OpServices, "brew", fmt.Errorf("Homebrew is not installed"))
+	}
+
+	action := services.Stop
+	if want == config.ServiceStarted {
+		action = services.Start
+	}
+	if err := action(name); err != nil {
+		return errors.NewInstallationError(constants.OpServices, name, err)
+	}
+	o.PrintSuccess(fmt.Sprintf("%s %s", capitalizedPast(want), name))
+
+	if configured, err := config.GetServicesConfig(); err == nil {
+		if configured[name] != "" && configured[name] != want {
+			o.PrintWarning("%s wants %s %s, 'anvil provision' and 'anvil services apply' will change it back", constants.ANVIL_CONFIG_FILE, name, configured[name])
+		}
+	}
+	return nil
+}
+
+// verb returns the action that brings a service to the wanted state
+func verb(want string) string {
+	if want == config.ServiceStarted {
+		return "start"
+	}
+	return "stop"
+}
+
+// capitalizedPast describes a completed action for the wanted state
+func capitalizedPast(want string) string {
+	if want == config.ServiceStarted {
+		return "Started"
+	}
+	return "Stopped"
+}
+
+// describeStatus renders the current status of a service for dry-run output
+func describeStatus(state services.State) string {
+	if !state.Installed {
+		return "not installed"
+	}
+	return "currently " + state.Status
+}
+
+func init() {
+	ServicesCmd.AddCommand(listCmd)
+	ServicesCmd.AddCommand(startCmd)
+	ServicesCmd.AddCommand(stopCmd)
+	ServicesCmd.AddCommand(applyCmd)
+	applyCmd.Flags().Bool("dry-run", false, "Show which services would be started or stopped without changing them")
+}
@@ -0,0 +1,222 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sessions
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/session"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+	"github.com/0xjuanma/palantir"
+	"github.com/spf13/cobra"
+)
+
+// failedOutputLines is how much brew output is shown for failed commands without --verbose
+const failedOutputLines = 15
+
+var SessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "Review recorded install and provision runs",
+	Long:  constants.SESSIONS_COMMAND_LONG_DESCRIPTION,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List recorded sessions, newest first",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runListCommand(); err != nil {
+			palantir.GetGlobalOutputHandler().PrintError("Sessions failed: %v", err)
+		}
+	},
+}
+
+var showCmd = &cobra.Command{
+	Use:   "show [id]",
+	Short: "Show a recorded session, the latest one by default",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runShowCommand(cmd, args); err != nil {
+			palantir.GetGlobalOutputHandler().PrintError("Sessions failed: %v", err)
+		}
+	},
+}
+
+// runListCommand prints one line per recorded session
+func runListCommand() error {
+	o := palantir.GetGlobalOutputHandler()
+	ids, err := session.List()
+	if err != nil {
+		return errors.NewFileSystemError(constants.OpSessions, "list", err)
+	}
+	if len(ids) == 0 {
+		o.PrintInfo("No sessions recorded yet. Use --record with 'anvil install' or 'anvil provision'")
+		return nil
+	}
+
+	o.PrintHeader("Recorded Sessions")
+	for _, id := range ids {
+		recorded, err := session.Load(id)
+		if err != nil {
+			o.PrintWarning("%s: %v", id, err)
+			continue
+		}
+		status := "✅"
+		if failedSteps(recorded.Steps) > 0 || recorded.Error != "" {
+			status = "❌"
+		}
+		fmt.Printf("  %s %s  %-40s %s\n", status, id, recorded.Invocation, formatDuration(recorded.Duration()))
+	}
+	return nil
+}
+
+// runShowCommand re-renders a recorded session
+func runShowCommand(cmd *cobra.Command, args []string) error {
+	verbose, _ := cmd.Flags().GetBool("verbose")
+
+	var id string
+	if len(args) > 0 {
+		id = args[0]
+	} else {
+		ids, err := session.List()
+		if err != nil {
+			return errors.NewFileSystemError(constants.OpSessions, "list", err)
+		}
+		if len(ids) == 0 {
+			return errors.NewValidationError(constants.OpSessions, "show",
+				fmt.Errorf("no sessions recorded yet, use --record with 'anvil install' or 'anvil provision'"))
+		}
+		id = ids[0]
+	}
+
+	recorded, err := session.Load(id)
+	if err != nil {
+		return errors.NewFileSystemError(constants.OpSessions, "show", err)
+	}
+
+	fmt.Println(charm.RenderBox(fmt.Sprintf("Session %s", recorded.ID), renderSession(recorded, verbose), charm.ActiveTheme().Accent, false))
+	return nil
+}
+
+// renderSession renders the session header, each step in order and the output of failed
+// commands, or of every command when verbose
+func renderSession(recorded *session.Session, verbose bool) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Command:  %s\n", recorded.Invocation))
+	b.WriteString(fmt.Sprintf("Host:     %s (anvil %s)\n", recorded.Host, recorded.AnvilVersion))
+	b.WriteString(fmt.Sprintf("Started:  %s\n", recorded.StartedAt.Format("2006-01-02 15:04:05")))
+	b.WriteString(fmt.Sprintf("Duration: %s\n", formatDuration(recorded.Duration())))
+
+	failed := failedSteps(recorded.Steps)
+	b.WriteString(fmt.Sprintf("Steps:    %d, %d failed\n", countSteps(recorded.Steps), failed))
+	if recorded.Error != "" {
+		b.WriteString(fmt.Sprintf("Error:    %s\n", recorded.Error))
+	}
+
+	b.WriteString("\n")
+	for i, step := range recorded.Steps {
+		renderStep(&b, step, fmt.Sprintf("%d.", i+1), "", verbose)
+	}
+	for _, command := range recorded.Commands {
+		renderCommand(&b, command, "", verbose)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// renderStep writes a step line, its commands and its nested steps
+func renderStep(b *strings.Builder, step *session.Step, label, indent string, verbose bool) {
+	status := "✓"
+	if step.Failed() {
+		status = "✗"
+	}
+	b.WriteString(fmt.Sprintf("%s%s %s %s (%s)\n", indent, label, status, step.Name, formatDuration(step.Duration)))
+	if step.Failed() {
+		b.WriteString(fmt.Sprintf("%s     error: %s\n", indent, step.Error))
+	}
+
+	for _, command := range step.Commands {
+		renderCommand(b, command, indent+"     ", verbose)
+	}
+	for _, child := range step.Steps {
+		renderStep(b, child, "•", indent+"   ", verbose)
+	}
+}
+
+// renderCommand writes a command line and, for failures or when verbose, its output
+func renderCommand(b *strings.Builder, command session.Command, indent string, verbose bool) {
+	status := "$"
+	if !command.Success {
+		status = fmt.Sprintf("$ [exit %d]", command.ExitCode)
+	}
+	b.WriteString(fmt.Sprintf("%s%s %s (%s)\n", indent, status, command.Command, formatDuration(command.Duration)))
+
+	if command.Success && !verbose {
+		return
+	}
+	lines := strings.Split(strings.TrimRight(command.Output, "\n"), "\n")
+	if !verbose && len(lines) > failedOutputLines {
+		b.WriteString(fmt.Sprintf("%s  ... %d earlier lines, use --verbose to see all\n", indent, len(lines)-failedOutputLines))
+		lines = lines[len(lines)-failedOutputLines:]
+	}
+	for _, line := range lines {
+		if line != "" {
+			b.WriteString(fmt.Sprintf("%s  │ %s\n", indent, line))
+		}
+	}
+}
+
+// countSteps counts steps including nested ones
+func countSteps(steps []*session.Step) int {
+	count := len(steps)
+	for _, step := range steps {
+		count += countSteps(step.Steps)
+	}
+	return count
+}
+
+// failedSteps counts failed steps including nested ones
+func failedSteps(steps []*session.Step) int {
+	count := 0
+	for _, step := range steps {
+		if step.Failed() {
+			count++
+		}
+		count += failedSteps(step.Steps)
+	}
+	return count
+}
+
+// formatDuration rounds durations for display
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
+
+func init() {
+	SessionsCmd.AddCommand(listCmd)
+	SessionsCmd.AddCommand(showCmd)
+	showCmd.Flags().BoolP("verbose", "v", false, "Show the output of every command, not only failed ones")
+}
@@ -0,0 +1,236 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tour
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/system"
+	"github.com/0xjuanma/anvil/internal/version"
+	"gopkg.in/yaml.v2"
+)
+
+// sandboxSettings is the settings.yaml the tour starts from, pointing at the sandbox repository
+const sandboxSettings = `version: "%s"
+tools:
+  required_tools: [git]
+  installed_apps: []
+groups: {}
+configs: {}
+github:
+  config_repo: "file://%s"
+  branch: main
+  local_path: "%s"
+`
+
+// tourGitEnv is the commit identity used in the sandbox, which has no global git config
+var tourGitEnv = []string{
+	"GIT_AUTHOR_NAME=anvil tour",
+	"GIT_AUTHOR_EMAIL=tour@anvil.local",
+	"GIT_COMMITTER_NAME=anvil tour",
+	"GIT_COMMITTER_EMAIL=tour@anvil.local",
+}
+
+// sandbox is the throwaway home directory and config repository the tour runs commands against
+type sandbox struct {
+	dir    string // ~/.anvil/tour
+	home   string // HOME of the commands the tour runs
+	remote string // Bare git repository standing in for the GitHub config repository
+}
+
+// tourProgress records when each step was completed
+type tourProgress struct {
+	Completed map[string]time.Time `yaml:"completed"`
+}
+
+// newSandbox returns the sandbox under the anvil config directory
+func newSandbox() *sandbox {
+	dir := filepath.Join(config.GetAnvilConfigDirectory(), "tour")
+	return &sandbox{dir: dir, home: filepath.Join(dir, "home"), remote: filepath.Join(dir, "remote.git")}
+}
+
+// settingsPath returns the sandbox settings.yaml
+func (s *sandbox) settingsPath() string {
+	return filepath.Join(s.home, constants.ANVIL_CONFIG_DIR, constants.ANVIL_CONFIG_FILE)
+}
+
+// progressPath returns the file step completion is recorded in
+func (s *sandbox) progressPath() string {
+	return filepath.Join(s.dir, "progress.yaml")
+}
+
+// ensure creates the sandbox settings and config repository when they are missing
+func (s *sandbox) ensure() error {
+	if _, err := os.Stat(s.settingsPath()); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(s.settingsPath()), constants.DirPerm); err != nil {
+			return err
+		}
+		settings := fmt.Sprintf(sandboxSettings, version.GetVersion(), s.remote,
+			filepath.Join(s.home, constants.ANVIL_CONFIG_DIR, "dotfiles"))
+		if err := os.WriteFile(s.settingsPath(), []byte(settings), constants.FilePerm); err != nil {
+			return err
+		}
+	}
+
+	if _, err := os.Stat(s.remote); err == nil {
+		return nil
+	}
+	return s.createRemote()
+}
+
+// createRemote creates the sandbox repository with an initial commit on main, which push branches from
+func (s *sandbox) createRemote() error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	seed := filepath.Join(s.dir, "seed")
+	defer os.RemoveAll(seed)
+
+	steps := [][]string{
+		{"init", "--bare", "--initial-branch=main", s.remote},
+		{"init", "--initial-branch=main", seed},
+	}
+	for _, args := range steps {
+		if err := s.git(ctx, "", args...); err != nil {
+			return err
+		}
+	}
+
+	readme := "# anvil tour\n\nA local stand-in for your config repository, created by 'anvil tour'.\n"
+	if err := os.WriteFile(filepath.Join(seed, "README.md"), []byte(readme), constants.FilePerm); err != nil {
+		return err
+	}
+	for _, args := range [][]string{
+		{"add", "README.md"},
+		{"commit", "-m", "Initial commit"},
+		{"push", s.remote, "main"},
+	} {
+		if err := s.git(ctx, seed, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// git runs a git command in dir with the sandbox identity
+func (s *sandbox) git(ctx context.Context, dir string, args ...string) error {
+	result, err := system.RunCommandWithEnv(ctx, dir, tourGitEnv, "git", args...)
+	if err != nil {
+		return err
+	}
+	if !result.Success {
+		return fmt.Errorf("git %s: %s", args[0], result.Output)
+	}
+	return nil
+}
+
+// setSetting sets key under a top-level section of the sandbox settings.yaml, keeping the rest as it is
+func (s *sandbox) setSetting(section, key string, value interface{}) error {
+	data, err := os.ReadFile(s.settingsPath())
+	if err != nil {
+		return err
+	}
+
+	var settings yaml.MapSlice
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return err
+	}
+
+	found := false
+	for i, item := range settings {
+		if item.Key != section {
+			continue
+		}
+		entries, _ := item.Value.(yaml.MapSlice)
+		settings[i].Value = setMapSliceKey(entries, key, value)
+		found = true
+	}
+	if !found {
+		settings = append(settings, yaml.MapItem{Key: section, Value: yaml.MapSlice{{Key: key, Value: value}}})
+	}
+
+	data, err = yaml.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.settingsPath(), data, constants.FilePerm)
+}
+
+// setMapSliceKey replaces key's value, or appends it when missing
+func setMapSliceKey(entries yaml.MapSlice, key string, value interface{}) yaml.MapSlice {
+	for i, item := range entries {
+		if item.Key == key {
+			entries[i].Value = value
+			return entries
+		}
+	}
+	return append(entries, yaml.MapItem{Key: key, Value: value})
+}
+
+// run runs anvil with the sandbox as HOME, connected to the terminal so its prompts can be answered
+func (s *sandbox) run(args ...string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(executable, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), "HOME="+s.home)
+	cmd.Env = append(cmd.Env, tourGitEnv...)
+	return cmd.Run()
+}
+
+// loadProgress reads the completed steps, an unstarted tour has none
+func (s *sandbox) loadProgress() (*tourProgress, error) {
+	progress := &tourProgress{Completed: make(map[string]time.Time)}
+	data, err := os.ReadFile(s.progressPath())
+	if os.IsNotExist(err) {
+		return progress, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, progress); err != nil {
+		return nil, err
+	}
+	if progress.Completed == nil {
+		progress.Completed = make(map[string]time.Time)
+	}
+	return progress, nil
+}
+
+// saveProgress writes the completed steps
+func (s *sandbox) saveProgress(progress *tourProgress) error {
+	data, err := yaml.Marshal(progress)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, constants.DirPerm); err != nil {
+		return err
+	}
+	return os.WriteFile(s.progressPath(), data, constants.FilePerm)
+}
@@ -0,0 +1,219 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tour
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+	"github.com/0xjuanma/palantir"
+	"github.com/spf13/cobra"
+)
+
+// Names the tour uses in the sandbox settings
+const (
+	tourGroup = "tour"
+	tourApp   = "tour-demo"
+)
+
+// tourGroupTools are the apps of the group the tour creates
+var tourGroupTools = []string{"git", "jq"}
+
+var TourCmd = &cobra.Command{
+	Use:   "tour",
+	Short: "Take a guided tour of groups, installs and the config push/pull/sync lifecycle",
+	Long:  constants.TOUR_COMMAND_LONG_DESCRIPTION,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runTourCommand(cmd); err != nil {
+			palantir.GetGlobalOutputHandler().PrintError("Tour failed: %v", err)
+			os.Exit(errors.ExitCode(err))
+		}
+	},
+}
+
+// tourStep is one lesson of the tour, run against the sandbox
+type tourStep struct {
+	id      string
+	title   string
+	explain string
+	command string // The anvil command the step runs, empty when it only edits settings.yaml
+	run     func(s *sandbox) error
+}
+
+// tourSteps are the lessons in order, each building on the previous one
+var tourSteps = []tourStep{
+	{
+		id:    "create-group",
+		title: "Create a group",
+		explain: "Groups are named lists of apps in settings.yaml that install together, e.g. 'anvil install dev'.\n" +
+			fmt.Sprintf("The tour adds a '%s' group with %s to the sandbox settings.yaml.", tourGroup, strings.Join(tourGroupTools, " and ")),
+		run: func(s *sandbox) error {
+			if err := s.setSetting("groups", tourGroup, tourGroupTools); err != nil {
+				return err
+			}
+			palantir.GetGlobalOutputHandler().PrintSuccess(fmt.Sprintf("Added to %s:\n\ngroups:\n  %s: [%s]\n",
+				s.settingsPath(), tourGroup, strings.Join(tourGroupTools, ", ")))
+			return nil
+		},
+	},
+	{
+		id:    "dry-run-install",
+		title: "Dry-run install the group",
+		explain: "--dry-run shows what an install would do without installing anything.\n" +
+			"Apps already on this machine are reported as available and skipped.",
+		command: fmt.Sprintf("anvil install %s --dry-run", tourGroup),
+		run: func(s *sandbox) error {
+			return s.run("install", tourGroup, "--dry-run")
+		},
+	},
+	{
+		id:    "register-config",
+		title: "Register an app config",
+		explain: "The configs section maps an app name to the file or directory anvil pushes, pulls and syncs.\n" +
+			fmt.Sprintf("The tour creates a small '%s' config in the sandbox and registers it.", tourApp),
+		run: func(s *sandbox) error {
+			configDir := filepath.Join(s.home, ".config", tourApp)
+			if err := os.MkdirAll(configDir, constants.DirPerm); err != nil {
+				return err
+			}
+			content := fmt.Sprintf("# Written by anvil tour on %s\ntheme = \"dark\"\n", time.Now().Format("2006-01-02"))
+			if err := os.WriteFile(filepath.Join(configDir, "demo.conf"), []byte(content), constants.FilePerm); err != nil {
+				return err
+			}
+			if err := s.setSetting("configs", tourApp, configDir); err != nil {
+				return err
+			}
+			palantir.GetGlobalOutputHandler().PrintSuccess(fmt.Sprintf("Added to %s:\n\nconfigs:\n  %s: %s\n",
+				s.settingsPath(), tourApp, configDir))
+			return nil
+		},
+	},
+	{
+		id:    "push",
+		title: "Push the config",
+		explain: "Push copies an app's configs to a new branch of your config repository, ready to merge.\n" +
+			"In the tour the repository is a local git repository in the sandbox, nothing is sent to GitHub.",
+		command: fmt.Sprintf("anvil config push %s", tourApp),
+		run: func(s *sandbox) error {
+			return s.run("config", "push", tourApp)
+		},
+	},
+}
+
+// runTourCommand walks through the steps not completed yet, recording each one as it finishes
+func runTourCommand(cmd *cobra.Command) error {
+	output := palantir.GetGlobalOutputHandler()
+	s := newSandbox()
+
+	if reset, _ := cmd.Flags().GetBool("reset"); reset {
+		if err := os.RemoveAll(s.dir); err != nil {
+			return errors.NewFileSystemError(constants.OpTour, "reset", err)
+		}
+		output.PrintSuccess("Tour progress and sandbox removed, run 'anvil tour' to start again")
+		return nil
+	}
+
+	progress, err := s.loadProgress()
+	if err != nil {
+		return errors.NewFileSystemError(constants.OpTour, "load-progress", err)
+	}
+
+	fmt.Println(charm.RenderBox("Welcome to anvil", tourIntro(s), charm.ActiveTheme().Accent, false))
+	printProgress(progress)
+
+	if err := s.ensure(); err != nil {
+		return errors.NewFileSystemError(constants.OpTour, "sandbox", err)
+	}
+
+	for i, step := range tourSteps {
+		if _, done := progress.Completed[step.id]; done {
+			continue
+		}
+
+		output.PrintHeader(fmt.Sprintf("Step %d/%d: %s", i+1, len(tourSteps), step.title))
+		fmt.Println(step.explain)
+		if step.command != "" {
+			output.PrintInfo("Command: %s", step.command)
+		}
+		fmt.Println()
+
+		// Every step only changes the sandbox settings.yaml or runs against it
+		if !charm.Confirm(charm.ConfirmSettings, fmt.Sprintf("Run step %d?", i+1)) {
+			output.PrintInfo("Tour paused, run 'anvil tour' to continue from step %d", i+1)
+			return nil
+		}
+		if err := step.run(s); err != nil {
+			output.PrintInfo("Fix the problem and run 'anvil tour' to retry step %d", i+1)
+			return errors.NewInstallationError(constants.OpTour, step.id, err)
+		}
+
+		progress.Completed[step.id] = time.Now()
+		if err := s.saveProgress(progress); err != nil {
+			return errors.NewFileSystemError(constants.OpTour, "save-progress", err)
+		}
+		output.PrintSuccess(fmt.Sprintf("Step %d/%d complete", i+1, len(tourSteps)))
+	}
+
+	fmt.Println(charm.RenderBox("Tour complete", tourOutro(), charm.ActiveTheme().Success, false))
+	return nil
+}
+
+// tourIntro explains the config lifecycle and where the tour runs
+func tourIntro(s *sandbox) string {
+	return "anvil keeps your apps and configs in ~/.anvil/settings.yaml and your config\n" +
+		"files in a private GitHub repository:\n\n" +
+		"  push  copies configs from this machine to the repository\n" +
+		"  pull  downloads them on another machine\n" +
+		"  sync  applies pulled configs, archiving the ones they replace\n\n" +
+		fmt.Sprintf("Everything in this tour runs in a sandbox at %s.\n", s.dir) +
+		"Your own settings.yaml, configs and repository are never touched."
+}
+
+// tourOutro suggests what to do after the tour
+func tourOutro() string {
+	return "Once the pushed branch is merged, your other machines get the configs back with:\n\n" +
+		fmt.Sprintf("  anvil config pull %s\n", tourApp) +
+		fmt.Sprintf("  anvil config sync %s\n\n", tourApp) +
+		"To set up your own machine:\n\n" +
+		"  anvil init               Create settings.yaml\n" +
+		"  github.config_repo       Point it at your private config repository\n" +
+		"  anvil install <group>    Install a group\n\n" +
+		"Run 'anvil tour --reset' to remove the sandbox and start over."
+}
+
+// printProgress lists the steps with a mark for the completed ones
+func printProgress(progress *tourProgress) {
+	for i, step := range tourSteps {
+		mark := "○"
+		if completed, done := progress.Completed[step.id]; done {
+			mark = fmt.Sprintf("✓ (%s)", completed.Format("2006-01-02"))
+		}
+		fmt.Printf("  %d. %-28s %s\n", i+1, step.title, mark)
+	}
+	fmt.Println()
+}
+
+func init() {
+	TourCmd.Flags().Bool("reset", false, "Remove the tour sandbox and progress to start over")
+}
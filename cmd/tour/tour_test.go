@@ -0,0 +1,88 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tour
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestSandbox(t *testing.T) *sandbox {
+	t.Helper()
+	dir := t.TempDir()
+	s := &sandbox{dir: dir, home: filepath.Join(dir, "home"), remote: filepath.Join(dir, "remote.git")}
+	os.MkdirAll(filepath.Dir(s.settingsPath()), 0755)
+	return s
+}
+
+func TestSetSetting(t *testing.T) {
+	s := newTestSandbox(t)
+	os.WriteFile(s.settingsPath(), []byte("tools:\n  required_tools: [git]\ngroups:\n  dev: [zsh]\nconfigs: {}\n"), 0644)
+
+	if err := s.setSetting("groups", tourGroup, tourGroupTools); err != nil {
+		t.Fatalf("setSetting failed: %v", err)
+	}
+	if err := s.setSetting("configs", tourApp, "/tmp/demo"); err != nil {
+		t.Fatalf("setSetting failed: %v", err)
+	}
+	// Setting a key again replaces it rather than adding a duplicate
+	if err := s.setSetting("configs", tourApp, "/tmp/other"); err != nil {
+		t.Fatalf("setSetting failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(s.settingsPath())
+	expected := "tools:\n  required_tools:\n  - git\ngroups:\n  dev:\n  - zsh\n  tour:\n  - git\n  - jq\nconfigs:\n  tour-demo: /tmp/other\n"
+	if string(data) != expected {
+		t.Errorf("settings =\n%s\nwant\n%s", data, expected)
+	}
+}
+
+func TestProgressRoundTrip(t *testing.T) {
+	s := newTestSandbox(t)
+
+	progress, err := s.loadProgress()
+	if err != nil || len(progress.Completed) != 0 {
+		t.Fatalf("loadProgress() = %+v, %v; want no completed steps", progress, err)
+	}
+
+	completed := time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+	progress.Completed[tourSteps[0].id] = completed
+	if err := s.saveProgress(progress); err != nil {
+		t.Fatalf("saveProgress failed: %v", err)
+	}
+
+	loaded, err := s.loadProgress()
+	if err != nil {
+		t.Fatalf("loadProgress failed: %v", err)
+	}
+	if got := loaded.Completed[tourSteps[0].id]; !got.Equal(completed) || len(loaded.Completed) != 1 {
+		t.Errorf("loaded progress = %+v, want only %s completed at %s", loaded.Completed, tourSteps[0].id, completed)
+	}
+}
+
+func TestTourStepsAreUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, step := range tourSteps {
+		if seen[step.id] || strings.TrimSpace(step.id) == "" {
+			t.Errorf("step id %q is empty or duplicated", step.id)
+		}
+		seen[step.id] = true
+	}
+}
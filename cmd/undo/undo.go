@@ -0,0 +1,150 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package undo
+
+import (
+	"fmt"
+
+	"github.com/0xjuanma/anvil/internal/audit"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+	"github.com/0xjuanma/palantir"
+	"github.com/spf13/cobra"
+)
+
+var UndoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "Revert the most recent changes anvil made to settings.yaml",
+	Long:  constants.UNDO_COMMAND_LONG_DESCRIPTION,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runUndoCommand(cmd); err != nil {
+			palantir.GetGlobalOutputHandler().PrintError("Undo failed: %v", err)
+			return
+		}
+	},
+}
+
+// runUndoCommand lists the settings transactions or reverts the most recent ones
+func runUndoCommand(cmd *cobra.Command) error {
+	output := palantir.GetGlobalOutputHandler()
+	list, _ := cmd.Flags().GetBool("list")
+	steps, _ := cmd.Flags().GetInt("steps")
+	force, _ := cmd.Flags().GetBool("force")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	if list {
+		return listTransactions()
+	}
+
+	undoable, err := config.UndoableTransactions()
+	if err != nil {
+		return errors.NewFileSystemError(constants.OpUndo, "list-transactions", err)
+	}
+	if len(undoable) == 0 {
+		output.PrintInfo("No changes to %s to undo", constants.ANVIL_CONFIG_FILE)
+		return nil
+	}
+	if steps < 1 || steps > len(undoable) {
+		return errors.NewValidationError(constants.OpUndo, "steps",
+			fmt.Errorf("--steps must be between 1 and %d, see 'anvil undo --list'", len(undoable)))
+	}
+
+	output.PrintHeader("Undo Settings Changes")
+	for _, transaction := range undoable[:steps] {
+		fmt.Println(formatTransaction(transaction))
+	}
+	fmt.Println()
+
+	if dryRun {
+		output.PrintInfo("Dry run - would revert %d change(s) to %s", steps, constants.ANVIL_CONFIG_FILE)
+		for _, transaction := range undoable[:steps] {
+			audit.Record("undo", "overwrite-file", config.GetAnvilConfigPath(), "revert transaction "+transaction.ID)
+		}
+		return nil
+	}
+
+	if !charm.Confirm(charm.ConfirmSettings, fmt.Sprintf("Revert %d change(s) to %s?", steps, constants.ANVIL_CONFIG_FILE)) {
+		output.PrintInfo("Undo cancelled")
+		return nil
+	}
+
+	undone, err := config.UndoTransactions(steps, force)
+	for _, transaction := range undone {
+		output.PrintSuccess(fmt.Sprintf("Reverted %s", describeTransaction(transaction)))
+	}
+	if err != nil {
+		return errors.NewConfigurationError(constants.OpUndo, "revert", err)
+	}
+	output.PrintInfo("Run 'anvil undo' again to keep walking back, or 'anvil undo --list' to review history")
+	return nil
+}
+
+// listTransactions prints the transaction log, most recent first
+func listTransactions() error {
+	output := palantir.GetGlobalOutputHandler()
+	transactions, err := config.ListTransactions()
+	if err != nil {
+		return errors.NewFileSystemError(constants.OpUndo, "list-transactions", err)
+	}
+	if len(transactions) == 0 {
+		output.PrintInfo("No changes to %s recorded yet", constants.ANVIL_CONFIG_FILE)
+		output.PrintInfo("Changes are recorded in %s", config.GetTransactionDirectory())
+		return nil
+	}
+
+	output.PrintHeader("Settings History")
+	for _, transaction := range transactions {
+		fmt.Println(formatTransaction(transaction))
+	}
+	fmt.Println()
+	output.PrintInfo("Run 'anvil undo --steps N' to revert the N most recent changes not undone yet")
+	return nil
+}
+
+// formatTransaction renders a transaction line with its time, command, changed keys and undo state
+func formatTransaction(transaction config.SettingsTransaction) string {
+	line := fmt.Sprintf("  %s  %s", transaction.Time.Format("2006-01-02 15:04:05"), describeTransaction(transaction))
+	switch {
+	case transaction.Undoes != "":
+		line += "  (undo)"
+	case transaction.UndoneBy != "":
+		line += "  (undone)"
+	}
+	return line
+}
+
+// describeTransaction names the command behind a transaction and what it changed
+func describeTransaction(transaction config.SettingsTransaction) string {
+	command := transaction.Command
+	if command == "" {
+		command = "unknown command"
+	}
+	if transaction.Summary == "" {
+		return command
+	}
+	return fmt.Sprintf("%s: %s", command, transaction.Summary)
+}
+
+func init() {
+	UndoCmd.Flags().Bool("list", false, "List recorded settings changes, most recent first")
+	UndoCmd.Flags().Int("steps", 1, "Number of recent changes to revert")
+	UndoCmd.Flags().Bool("force", false, "Revert even if settings.yaml was edited since the change")
+	UndoCmd.Flags().Bool("dry-run", false, "Show which changes would be reverted without changing settings.yaml")
+}
@@ -0,0 +1,200 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package uninstall
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/0xjuanma/anvil/internal/audit"
+	"github.com/0xjuanma/anvil/internal/brew"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/events"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+	"github.com/0xjuanma/palantir"
+	"github.com/spf13/cobra"
+)
+
+// UninstallCmd removes apps installed with Homebrew and stops tracking them
+var UninstallCmd = &cobra.Command{
+	Use:   "uninstall [group-name|app-name]",
+	Short: "Uninstall an app or a group with Homebrew and remove it from settings.yaml",
+	Long:  constants.UNINSTALL_COMMAND_LONG_DESCRIPTION,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runUninstallCommand(cmd, args[0]); err != nil {
+			palantir.GetGlobalOutputHandler().PrintError("Uninstall failed: %v", err)
+			os.Exit(errors.ExitCode(err))
+		}
+	},
+}
+
+// uninstallPlan is what uninstalling a target removes and what it leaves alone
+type uninstallPlan struct {
+	group   string   // Set when the target names a group
+	remove  []string // Entries to uninstall, as written in settings.yaml
+	skipped []string // "<app>: <reason>" for entries left installed
+}
+
+// buildPlan decides which apps of the target are removed. Apps managed externally are always
+// kept; without force, so are required tools and apps of a group that another group lists.
+func buildPlan(cfg *config.AnvilConfig, target string, force bool) (*uninstallPlan, error) {
+	plan := &uninstallPlan{}
+	apps := []string{target}
+	if tools, ok := cfg.Groups[target]; ok {
+		if len(tools) == 0 {
+			return nil, fmt.Errorf("group '%s' has no apps", target)
+		}
+		plan.group, apps = target, tools
+	}
+
+	for _, app := range apps {
+		switch {
+		case cfg.IsManagedExternally(app):
+			plan.skipped = append(plan.skipped, fmt.Sprintf("%s: managed externally", app))
+		case force:
+			plan.remove = append(plan.remove, app)
+		case slices.Contains(cfg.AppListings(app), config.SectionRequiredTools):
+			plan.skipped = append(plan.skipped, fmt.Sprintf("%s: required tool, use --force to remove it", app))
+		case plan.group != "" && len(otherGroups(cfg, app, plan.group)) > 0:
+			plan.skipped = append(plan.skipped, fmt.Sprintf("%s: also in %s, use --force to remove it", app, strings.Join(otherGroups(cfg, app, plan.group), ", ")))
+		default:
+			plan.remove = append(plan.remove, app)
+		}
+	}
+	return plan, nil
+}
+
+// otherGroups returns the groups listing the app other than the given one
+func otherGroups(cfg *config.AnvilConfig, app, group string) []string {
+	var others []string
+	for _, name := range cfg.GroupsListing(app) {
+		if name != group {
+			others = append(others, name)
+		}
+	}
+	return others
+}
+
+// runUninstallCommand previews the plan, asks for confirmation and removes each app
+func runUninstallCommand(cmd *cobra.Command, target string) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	force, _ := cmd.Flags().GetBool("force")
+	o := palantir.GetGlobalOutputHandler()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return errors.NewConfigurationError(constants.OpUninstall, "load-config", err)
+	}
+
+	plan, err := buildPlan(cfg, target, force)
+	if err != nil {
+		return errors.NewValidationError(constants.OpUninstall, target, err)
+	}
+
+	if plan.group != "" {
+		o.PrintHeader(fmt.Sprintf("Uninstall group '%s'", plan.group))
+	} else {
+		o.PrintHeader(fmt.Sprintf("Uninstall %s", target))
+		if len(plan.remove) == 1 && len(cfg.AppListings(target)) == 0 && !brew.IsPackageInstalled(target) {
+			return errors.NewValidationError(constants.OpUninstall, target,
+				fmt.Errorf("'%s' is neither a group, installed with Homebrew nor tracked in %s", target, constants.ANVIL_CONFIG_FILE))
+		}
+	}
+
+	for _, skipped := range plan.skipped {
+		o.PrintInfo("Keeping %s", skipped)
+	}
+	if len(plan.remove) == 0 {
+		o.PrintInfo("Nothing to uninstall")
+		return nil
+	}
+	o.PrintInfo("To uninstall: %s", strings.Join(plan.remove, ", "))
+
+	if dryRun {
+		for _, app := range plan.remove {
+			o.PrintInfo("Dry run - would uninstall %s and stop tracking it", app)
+			audit.Record("uninstall", "uninstall-package", app, plan.group)
+		}
+		return nil
+	}
+
+	if !force && !charm.Confirm(charm.ConfirmDelete, fmt.Sprintf("Uninstall %d app(s)?", len(plan.remove))) {
+		o.PrintInfo("Uninstall cancelled by user")
+		return nil
+	}
+
+	if err := brew.EnsureBrewIsInstalled(); err != nil {
+		return errors.NewInstallationError(constants.OpUninstall, "brew", err)
+	}
+
+	var failed []string
+	for _, app := range plan.remove {
+		if err := uninstallApp(app, plan.group == ""); err != nil {
+			o.PrintError("%s: %v", app, err)
+			failed = append(failed, app)
+		}
+	}
+
+	removed := len(plan.remove) - len(failed)
+	if len(failed) > 0 {
+		return errors.NewInstallationError(constants.OpUninstall, target,
+			fmt.Errorf("%d of %d apps failed to uninstall: %s", len(failed), len(plan.remove), strings.Join(failed, ", ")))
+	}
+	o.PrintSuccess(fmt.Sprintf("Uninstalled %d app(s)", removed))
+	if plan.group != "" {
+		o.PrintInfo("Group '%s' is kept in %s, reinstall it with 'anvil install %s'", plan.group, constants.ANVIL_CONFIG_FILE, plan.group)
+	}
+	return nil
+}
+
+// uninstallApp removes one app with Homebrew and from settings.yaml. Apps that are no longer
+// installed are only untracked. fromGroups also removes the app from the groups listing it.
+func uninstallApp(app string, fromGroups bool) error {
+	o := palantir.GetGlobalOutputHandler()
+	name, _ := brew.ParsePackageName(app)
+
+	if brew.IsPackageInstalled(name) {
+		if err := brew.UninstallPackage(app); err != nil {
+			return err
+		}
+		events.Publish(events.Uninstalled, name, "")
+		if _, found, _ := brew.LoadCaskManifest(name); found {
+			o.PrintInfo("Run 'anvil clean remnants %s' to remove what the cask left behind", name)
+		}
+	} else {
+		o.PrintInfo("%s is not installed with Homebrew, removing it from %s only", name, constants.ANVIL_CONFIG_FILE)
+	}
+
+	untracked, err := config.UntrackApp(app, fromGroups)
+	if err != nil {
+		return fmt.Errorf("uninstalled but failed to update %s: %w", constants.ANVIL_CONFIG_FILE, err)
+	}
+	if len(untracked) > 0 {
+		o.PrintInfo("Removed %s from %s", name, strings.Join(untracked, ", "))
+	}
+	return nil
+}
+
+func init() {
+	UninstallCmd.Flags().Bool("dry-run", false, "Show what would be uninstalled without removing anything")
+	UninstallCmd.Flags().Bool("force", false, "Skip the confirmation and also remove required tools and apps other groups list")
+}
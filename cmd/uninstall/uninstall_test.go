@@ -0,0 +1,88 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package uninstall
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/0xjuanma/anvil/internal/config"
+)
+
+func TestBuildPlan(t *testing.T) {
+	cfg := &config.AnvilConfig{
+		Tools: config.AnvilTools{RequiredTools: []string{"git"}, InstalledApps: []string{"htop"}},
+		Groups: config.AnvilGroups{
+			"dev":   {"git", "cask:docker", "jq", "slack"},
+			"data":  {"jq"},
+			"empty": {},
+		},
+		ManagedExternally: []string{"slack"},
+	}
+
+	tests := []struct {
+		name        string
+		target      string
+		force       bool
+		wantRemove  []string
+		wantSkipped []string
+	}{
+		{
+			name:       "group keeps required, shared and managed apps",
+			target:     "dev",
+			wantRemove: []string{"cask:docker"},
+			wantSkipped: []string{
+				"git: required tool, use --force to remove it",
+				"jq: also in data, use --force to remove it",
+				"slack: managed externally",
+			},
+		},
+		{
+			name:        "force removes everything but managed apps",
+			target:      "dev",
+			force:       true,
+			wantRemove:  []string{"git", "cask:docker", "jq"},
+			wantSkipped: []string{"slack: managed externally"},
+		},
+		{
+			name:       "single app",
+			target:     "htop",
+			wantRemove: []string{"htop"},
+		},
+		{
+			name:        "required app without force",
+			target:      "git",
+			wantSkipped: []string{"git: required tool, use --force to remove it"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := buildPlan(cfg, tt.target, tt.force)
+			if err != nil {
+				t.Fatalf("buildPlan() error = %v", err)
+			}
+			if !reflect.DeepEqual(plan.remove, tt.wantRemove) || !reflect.DeepEqual(plan.skipped, tt.wantSkipped) {
+				t.Errorf("buildPlan() remove = %q, skipped = %q, want %q and %q", plan.remove, plan.skipped, tt.wantRemove, tt.wantSkipped)
+			}
+		})
+	}
+
+	if _, err := buildPlan(cfg, "empty", false); err == nil {
+		t.Error("buildPlan() should fail for a group without apps")
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/0xjuanma/anvil/internal/audit"
 	"github.com/0xjuanma/anvil/internal/constants"
 	"github.com/0xjuanma/anvil/internal/errors"
 	"github.com/0xjuanma/anvil/internal/system"
@@ -79,6 +80,7 @@ func updateAnvil(ctx context.Context, dryRun bool) (*system.CommandResult, error
 		o.PrintInfo("Dry run mode - would update Anvil to the latest version")
 		o.PrintInfo("Command that would be executed:")
 		o.PrintInfo("curl -sSL https://github.com/0xjuanma/anvil/releases/latest/download/install.sh | bash")
+		audit.Record("update", "run-install-script", constants.ANVIL, "https://github.com/0xjuanma/anvil/releases/latest/download/install.sh")
 		return nil, nil
 	}
 
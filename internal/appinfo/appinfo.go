@@ -0,0 +1,234 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appinfo
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/0xjuanma/anvil/internal/brew"
+	"github.com/0xjuanma/anvil/internal/config"
+)
+
+// AppInfo is the structured status of a single app
+type AppInfo struct {
+	Name       string   `json:"name"`
+	Package    string   `json:"package"`           // Homebrew formula/cask name or App Store id
+	Type       string   `json:"type"`              // cask, formula or mas
+	Available  bool     `json:"available"`         // Found on this machine by any means
+	Version    string   `json:"version,omitempty"` // Installed Homebrew version, if any
+	Groups     []string `json:"groups"`
+	TrackedIn  []string `json:"tracked_in"`            // Sections of settings.yaml listing the app
+	ConfigPath string   `json:"config_path,omitempty"` // Local path mapped under configs
+	Source     string   `json:"source,omitempty"`      // Custom download URL or command under sources
+	Managed    bool     `json:"managed_externally"`    // Listed under managed_externally, never changed by anvil
+
+	// Filled in by Enrich
+	Description string `json:"description,omitempty"`
+	License     string `json:"license,omitempty"`
+	Homepage    string `json:"homepage,omitempty"`
+	Size        int64  `json:"size,omitempty"` // Download size in bytes
+}
+
+// checker holds the lookups used to build AppInfo, swappable in tests
+type checker struct {
+	isAvailable func(entry string) bool
+	packageType func(entry string) brew.PackageType
+	versions    func() map[string]string
+	metadata    func(entries []string, options brew.MetadataOptions) map[string]brew.PackageMetadata
+}
+
+var defaultChecker = checker{
+	isAvailable: brew.IsApplicationAvailable,
+	packageType: brew.ResolvePackageType,
+	versions:    brew.GetInstalledVersions,
+	metadata:    brew.FetchMetadata,
+}
+
+// Collect gathers info for each app concurrently, returning results in the order requested.
+// maxWorkers <= 0 uses the number of CPU cores.
+func Collect(ctx context.Context, cfg *config.AnvilConfig, apps []string, maxWorkers int) []AppInfo {
+	return defaultChecker.collect(ctx, cfg, apps, maxWorkers)
+}
+
+func (c checker) collect(ctx context.Context, cfg *config.AnvilConfig, apps []string, maxWorkers int) []AppInfo {
+	if maxWorkers <= 0 {
+		maxWorkers = runtime.NumCPU()
+	}
+
+	// Load the shared caches once before fanning out
+	versions := c.versions()
+
+	results := make([]AppInfo, len(apps))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < maxWorkers && w < len(apps); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = c.lookup(cfg, apps[i], versions)
+			}
+		}()
+	}
+
+	for i := range apps {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// Enrich adds descriptions, licenses, homepages and, with options.Sizes, download sizes to
+// collected results. All Homebrew packages are fetched together so brew runs once per package type.
+func Enrich(results []AppInfo, options brew.MetadataOptions) {
+	defaultChecker.enrich(results, options)
+}
+
+func (c checker) enrich(results []AppInfo, options brew.MetadataOptions) {
+	entries := make([]string, 0, len(results))
+	for _, info := range results {
+		if info.Type == string(brew.PackageTypeFormula) || info.Type == string(brew.PackageTypeCask) {
+			entries = append(entries, info.Type+":"+info.Package)
+		}
+	}
+	if len(entries) == 0 {
+		return
+	}
+
+	metadata := c.metadata(entries, options)
+	for i := range results {
+		found, ok := metadata[results[i].Type+":"+results[i].Package]
+		if !ok {
+			continue
+		}
+		results[i].Description = found.Description
+		results[i].License = found.License
+		results[i].Homepage = found.Homepage
+		results[i].Size = found.Size
+	}
+}
+
+// lookup builds the info for a single app
+func (c checker) lookup(cfg *config.AnvilConfig, app string, versions map[string]string) AppInfo {
+	entry := findEntry(cfg, app)
+	name, _ := brew.ParsePackageName(entry)
+
+	info := AppInfo{
+		Name:      app,
+		Package:   name,
+		Type:      string(c.packageType(entry)),
+		Available: c.isAvailable(entry),
+		Version:   versions[name],
+		Groups:    []string{},
+		TrackedIn: []string{},
+	}
+
+	key := config.NormalizeAppName(app)
+	for _, group := range sortedKeys(cfg.Groups) {
+		if containsApp(cfg.Groups[group], key) {
+			info.Groups = append(info.Groups, group)
+		}
+	}
+
+	if containsApp(cfg.Tools.RequiredTools, key) {
+		info.TrackedIn = append(info.TrackedIn, config.SectionRequiredTools)
+	}
+	if containsApp(cfg.Tools.InstalledApps, key) {
+		info.TrackedIn = append(info.TrackedIn, config.SectionInstalledApps)
+	}
+
+	info.ConfigPath = lookupByName(cfg.Configs, app)
+	info.Source = lookupByName(cfg.Sources, app)
+	info.Managed = cfg.IsManagedExternally(name)
+	return info
+}
+
+// findEntry returns the settings entry for an app as written, preferring one with a type annotation.
+// Apps not listed anywhere are looked up by the name given.
+func findEntry(cfg *config.AnvilConfig, app string) string {
+	key := config.NormalizeAppName(app)
+	lists := [][]string{cfg.Tools.RequiredTools, cfg.Tools.InstalledApps}
+	for _, group := range sortedKeys(cfg.Groups) {
+		lists = append(lists, cfg.Groups[group])
+	}
+
+	found := ""
+	for _, list := range lists {
+		for _, entry := range list {
+			name, packageType := brew.ParsePackageName(entry)
+			if config.NormalizeAppName(name) != key {
+				continue
+			}
+			if packageType != brew.PackageTypeAuto {
+				return entry
+			}
+			if found == "" {
+				found = entry
+			}
+		}
+	}
+	if found != "" {
+		return found
+	}
+	return app
+}
+
+// lookupByName returns the value for an app in a name-keyed map, ignoring case
+func lookupByName(m map[string]string, app string) string {
+	if value, ok := m[app]; ok {
+		return value
+	}
+	key := config.NormalizeAppName(app)
+	for name, value := range m {
+		if config.NormalizeAppName(name) == key {
+			return value
+		}
+	}
+	return ""
+}
+
+// sortedKeys returns the group names in a stable order
+func sortedKeys(groups config.AnvilGroups) []string {
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// containsApp reports whether list holds the normalized app name, with or without a type annotation
+func containsApp(list []string, key string) bool {
+	for _, entry := range list {
+		name, _ := brew.ParsePackageName(entry)
+		if config.NormalizeAppName(name) == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package appinfo
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/0xjuanma/anvil/internal/brew"
+	"github.com/0xjuanma/anvil/internal/config"
+)
+
+func TestCollect(t *testing.T) {
+	cfg := &config.AnvilConfig{
+		Tools: config.AnvilTools{
+			RequiredTools: []string{"git"},
+			InstalledApps: []string{"cask:obsidian"},
+		},
+		Groups: config.AnvilGroups{
+			"essentials": {"Slack", "git"},
+			"dev":        {"git"},
+		},
+		Configs:           map[string]string{"obsidian": "/Users/me/Library/obsidian"},
+		ManagedExternally: []string{"slack"},
+	}
+
+	stub := checker{
+		isAvailable: func(entry string) bool { return entry == "git" },
+		packageType: func(entry string) brew.PackageType {
+			if name, packageType := brew.ParsePackageName(entry); packageType != brew.PackageTypeAuto || name == "Slack" {
+				return brew.PackageTypeCask
+			}
+			return brew.PackageTypeFormula
+		},
+		versions: func() map[string]string { return map[string]string{"git": "2.45.0"} },
+	}
+
+	results := stub.collect(context.Background(), cfg, []string{"git", "slack", "obsidian", "unknown"}, 2)
+
+	want := []AppInfo{
+		{Name: "git", Package: "git", Type: "formula", Available: true, Version: "2.45.0",
+			Groups: []string{"dev", "essentials"}, TrackedIn: []string{config.SectionRequiredTools}},
+		{Name: "slack", Package: "Slack", Type: "cask",
+			Groups: []string{"essentials"}, TrackedIn: []string{}, Managed: true},
+		{Name: "obsidian", Package: "obsidian", Type: "cask",
+			Groups: []string{}, TrackedIn: []string{config.SectionInstalledApps}, ConfigPath: "/Users/me/Library/obsidian"},
+		{Name: "unknown", Package: "unknown", Type: "formula",
+			Groups: []string{}, TrackedIn: []string{}},
+	}
+
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("collect() =\n%+v\nwant\n%+v", results, want)
+	}
+}
+
+func TestEnrich(t *testing.T) {
+	var requested []string
+	stub := checker{
+		metadata: func(entries []string, options brew.MetadataOptions) map[string]brew.PackageMetadata {
+			requested = entries
+			return map[string]brew.PackageMetadata{
+				"formula:git": {Description: "Distributed revision control system", License: "GPL-2.0-only", Size: 2048},
+			}
+		},
+	}
+
+	results := []AppInfo{
+		{Name: "git", Package: "git", Type: "formula"},
+		{Name: "slack", Package: "slack", Type: "cask"},
+		{Name: "xcode", Package: "497799835", Type: "mas"},
+	}
+	stub.enrich(results, brew.MetadataOptions{})
+
+	if !reflect.DeepEqual(requested, []string{"formula:git", "cask:slack"}) {
+		t.Errorf("requested %v, want one batch of the Homebrew packages", requested)
+	}
+	if results[0].Description == "" || results[0].License != "GPL-2.0-only" || results[0].Size != 2048 {
+		t.Errorf("git = %+v, want its metadata", results[0])
+	}
+	if results[1].Description != "" || results[2].Description != "" {
+		t.Errorf("packages without metadata should be left unchanged, got %+v", results[1:])
+	}
+}
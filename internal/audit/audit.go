@@ -0,0 +1,203 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/version"
+)
+
+const (
+	// SignatureAlgorithm identifies how audit reports are signed
+	SignatureAlgorithm = "HMAC-SHA256"
+	// KeyEnvVar overrides the locally generated signing key
+	KeyEnvVar = "ANVIL_AUDIT_KEY"
+
+	// DirName is the directory under ~/.anvil where reports are kept
+	DirName = "audit"
+
+	keyFileName = ".signing-key"
+)
+
+// Action is a single change anvil would have made outside of audit mode
+type Action struct {
+	Command   string    `json:"command"`
+	Action    string    `json:"action"`
+	Target    string    `json:"target"`
+	Details   string    `json:"details,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Report is the signed JSON document written at the end of an audited run
+type Report struct {
+	AnvilVersion       string    `json:"anvil_version"`
+	Invocation         string    `json:"invocation"`
+	Host               string    `json:"host"`
+	User               string    `json:"user"`
+	StartedAt          time.Time `json:"started_at"`
+	FinishedAt         time.Time `json:"finished_at"`
+	Actions            []Action  `json:"actions"`
+	SignatureAlgorithm string    `json:"signature_algorithm,omitempty"`
+	Signature          string    `json:"signature,omitempty"`
+}
+
+var (
+	mu      sync.Mutex
+	enabled bool
+	current *Report
+)
+
+// Enable turns on audit mode for the current process
+func Enable(invocation string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	host, _ := os.Hostname()
+	enabled = true
+	current = &Report{
+		AnvilVersion: version.GetVersion(),
+		Invocation:   invocation,
+		Host:         host,
+		User:         os.Getenv("USER"),
+		StartedAt:    time.Now().UTC(),
+		Actions:      []Action{},
+	}
+}
+
+// IsEnabled reports whether audit mode is active
+func IsEnabled() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return enabled
+}
+
+// Record adds an intended action to the report; it is a no-op outside audit mode
+func Record(command, action, target, details string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if !enabled {
+		return
+	}
+
+	current.Actions = append(current.Actions, Action{
+		Command:   command,
+		Action:    action,
+		Target:    target,
+		Details:   details,
+		Timestamp: time.Now().UTC(),
+	})
+}
+
+// WriteReport signs the collected report and writes it to the anvil audit directory
+func WriteReport() (string, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if !enabled {
+		return "", fmt.Errorf("audit mode is not enabled")
+	}
+
+	dir := filepath.Join(config.GetAnvilConfigDirectory(), DirName)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create audit directory: %w", err)
+	}
+
+	key, err := signingKey(dir)
+	if err != nil {
+		return "", err
+	}
+
+	current.FinishedAt = time.Now().UTC()
+	data, err := Sign(current, key)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("audit-%s.json", current.StartedAt.Format("20060102-150405")))
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write audit report: %w", err)
+	}
+
+	return path, nil
+}
+
+// Sign computes the report signature over its unsigned JSON form and returns the signed document
+func Sign(report *Report, key []byte) ([]byte, error) {
+	report.SignatureAlgorithm = ""
+	report.Signature = ""
+
+	payload, err := json.Marshal(report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit report: %w", err)
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	report.SignatureAlgorithm = SignatureAlgorithm
+	report.Signature = hex.EncodeToString(mac.Sum(nil))
+
+	return json.MarshalIndent(report, "", "  ")
+}
+
+// Verify checks that a signed report was produced with the given key and has not been modified
+func Verify(data, key []byte) (bool, error) {
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return false, fmt.Errorf("failed to decode audit report: %w", err)
+	}
+
+	signature := report.Signature
+	if _, err := Sign(&report, key); err != nil {
+		return false, err
+	}
+
+	return hmac.Equal([]byte(signature), []byte(report.Signature)), nil
+}
+
+// signingKey returns the key from the environment, or a per-machine key stored alongside the reports
+func signingKey(dir string) ([]byte, error) {
+	if key := strings.TrimSpace(os.Getenv(KeyEnvVar)); key != "" {
+		return []byte(key), nil
+	}
+
+	keyPath := filepath.Join(dir, keyFileName)
+	if data, err := os.ReadFile(keyPath); err == nil && len(data) > 0 {
+		return data, nil
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate audit signing key: %w", err)
+	}
+	encoded := []byte(hex.EncodeToString(key))
+	if err := os.WriteFile(keyPath, encoded, 0600); err != nil {
+		return nil, fmt.Errorf("failed to store audit signing key: %w", err)
+	}
+
+	return encoded, nil
+}
@@ -0,0 +1,65 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	key := []byte("test-key")
+	report := &Report{
+		Invocation: "anvil install dev --audit",
+		StartedAt:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Actions: []Action{
+			{Command: "install", Action: "install-package", Target: "git"},
+		},
+	}
+
+	data, err := Sign(report, key)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	ok, err := Verify(data, key)
+	if err != nil || !ok {
+		t.Fatalf("Verify() = %v, %v; want true, nil", ok, err)
+	}
+
+	ok, _ = Verify(data, []byte("other-key"))
+	if ok {
+		t.Error("Verify() accepted a report signed with a different key")
+	}
+
+	tampered := []byte(strings.Replace(string(data), `"git"`, `"curl"`, 1))
+	ok, _ = Verify(tampered, key)
+	if ok {
+		t.Error("Verify() accepted a tampered report")
+	}
+}
+
+func TestRecordOutsideAuditMode(t *testing.T) {
+	Record("install", "install-package", "git", "")
+	if IsEnabled() {
+		t.Fatal("audit mode should be disabled by default")
+	}
+	if current != nil {
+		t.Error("Record() should not collect actions when audit mode is disabled")
+	}
+}
@@ -0,0 +1,262 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package automation manages the "automation" pseudo-app, the user's LaunchAgents and
+// crontab. Neither lives in a config directory, so they are exported into a staging
+// directory under ~/.anvil before a push and imported back into place after a sync.
+package automation
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/system"
+	"github.com/0xjuanma/anvil/internal/utils"
+)
+
+// AppName is the app name used to push, pull and sync automation
+const AppName = "automation"
+
+const (
+	agentsDir   = "LaunchAgents"
+	crontabFile = "crontab"
+)
+
+// Change kinds returned by Plan
+const (
+	KindAgent   = "launch-agent"
+	KindCrontab = "crontab"
+)
+
+// Overridden in tests
+var (
+	homeDir    = system.GetHomeDir
+	isMacOS    = system.IsMacOS
+	runCommand = system.RunCommand
+	hasCrontab = func() bool { return system.CommandExists("crontab") }
+)
+
+// Change is one agent or crontab that differs from the synced copy
+type Change struct {
+	Kind    string
+	Name    string
+	Source  string
+	Dest    string
+	Replace bool // Dest exists and is overwritten
+}
+
+// Dir returns the staging directory holding the exported automation
+func Dir() string {
+	return filepath.Join(config.GetAnvilConfigDirectory(), AppName)
+}
+
+// Export writes the user's LaunchAgents and crontab into dir, replacing what was there
+func Export(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := exportAgents(filepath.Join(dir, agentsDir)); err != nil {
+		return fmt.Errorf("launch agents: %w", err)
+	}
+	if err := exportCrontab(filepath.Join(dir, crontabFile)); err != nil {
+		return fmt.Errorf("crontab: %w", err)
+	}
+	return nil
+}
+
+// Plan compares the automation in dir with this machine and returns what Apply would change
+func Plan(dir string) ([]Change, error) {
+	var changes []Change
+
+	if isMacOS() {
+		live, err := liveAgentsDir()
+		if err != nil {
+			return nil, err
+		}
+		for _, source := range agentFiles(filepath.Join(dir, agentsDir)) {
+			name := filepath.Base(source)
+			dest := filepath.Join(live, name)
+			same, exists, err := sameContent(source, dest)
+			if err != nil {
+				return nil, err
+			}
+			if !same {
+				changes = append(changes, Change{Kind: KindAgent, Name: strings.TrimSuffix(name, ".plist"), Source: source, Dest: dest, Replace: exists})
+			}
+		}
+	}
+
+	source := filepath.Join(dir, crontabFile)
+	if _, err := os.Stat(source); err == nil && hasCrontab() {
+		wanted, err := os.ReadFile(source)
+		if err != nil {
+			return nil, err
+		}
+		current, err := readCrontab()
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(bytes.TrimSpace(wanted), bytes.TrimSpace(current)) {
+			changes = append(changes, Change{Kind: KindCrontab, Name: crontabFile, Source: source, Replace: len(current) > 0})
+		}
+	}
+
+	return changes, nil
+}
+
+// Apply puts each change in place, reloading replaced agents through launchctl
+func Apply(changes []Change) error {
+	var failed []string
+	for _, change := range changes {
+		if err := apply(change); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", change.Name, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// apply puts a single change in place
+func apply(change Change) error {
+	switch change.Kind {
+	case KindAgent:
+		// Unloading fails harmlessly when the agent is not loaded
+		if change.Replace {
+			runCommand("launchctl", "unload", change.Dest)
+		}
+		if err := os.MkdirAll(filepath.Dir(change.Dest), 0755); err != nil {
+			return err
+		}
+		if err := utils.CopyFileSimple(change.Source, change.Dest); err != nil {
+			return err
+		}
+		result, _ := runCommand("launchctl", "load", change.Dest)
+		if !result.Success {
+			return fmt.Errorf("launchctl load failed: %s", strings.TrimSpace(result.Output))
+		}
+	case KindCrontab:
+		result, _ := runCommand("crontab", change.Source)
+		if !result.Success {
+			return fmt.Errorf("crontab install failed: %s", strings.TrimSpace(result.Output))
+		}
+	default:
+		return fmt.Errorf("unknown change kind %q", change.Kind)
+	}
+	return nil
+}
+
+// exportAgents copies the user's agents into dest, leaving out the ones anvil creates itself
+func exportAgents(dest string) error {
+	if err := os.RemoveAll(dest); err != nil {
+		return err
+	}
+	if !isMacOS() {
+		return nil
+	}
+	live, err := liveAgentsDir()
+	if err != nil {
+		return err
+	}
+	agents := agentFiles(live)
+	if len(agents) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return err
+	}
+	for _, agent := range agents {
+		if err := utils.CopyFileSimple(agent, filepath.Join(dest, filepath.Base(agent))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportCrontab writes the user crontab to dest, or removes dest when there is none
+func exportCrontab(dest string) error {
+	if !hasCrontab() {
+		return nil
+	}
+	current, err := readCrontab()
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(current)) == 0 {
+		if err := os.Remove(dest); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return os.WriteFile(dest, current, 0644)
+}
+
+// readCrontab returns the user crontab, empty when none is installed
+func readCrontab() ([]byte, error) {
+	result, _ := runCommand("crontab", "-l")
+	if result.Success {
+		return []byte(result.Output), nil
+	}
+	if strings.Contains(result.Output, "no crontab") {
+		return nil, nil
+	}
+	return nil, fmt.Errorf("crontab -l failed: %s", strings.TrimSpace(result.Output))
+}
+
+// liveAgentsDir returns ~/Library/LaunchAgents
+func liveAgentsDir() (string, error) {
+	home, err := homeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "Library", agentsDir), nil
+}
+
+// agentFiles returns the plists in dir, sorted, skipping anvil's own agents
+func agentFiles(dir string) []string {
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.plist"))
+	var agents []string
+	for _, match := range matches {
+		if !strings.HasPrefix(filepath.Base(match), constants.LaunchAgentPrefix) {
+			agents = append(agents, match)
+		}
+	}
+	sort.Strings(agents)
+	return agents
+}
+
+// sameContent reports whether two files are identical and whether b exists
+func sameContent(a, b string) (bool, bool, error) {
+	left, err := os.ReadFile(a)
+	if err != nil {
+		return false, false, err
+	}
+	right, err := os.ReadFile(b)
+	if os.IsNotExist(err) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+	return bytes.Equal(left, right), true, nil
+}
@@ -0,0 +1,159 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package automation
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/system"
+)
+
+// fakeMachine stubs the home directory, launchctl and crontab for one test
+type fakeMachine struct {
+	home     string
+	crontab  string
+	commands []string
+}
+
+func newFakeMachine(t *testing.T) *fakeMachine {
+	t.Helper()
+	m := &fakeMachine{home: t.TempDir()}
+	os.MkdirAll(filepath.Join(m.home, "Library", agentsDir), 0755)
+
+	oldHome, oldMac, oldRun, oldCrontab := homeDir, isMacOS, runCommand, hasCrontab
+	t.Cleanup(func() { homeDir, isMacOS, runCommand, hasCrontab = oldHome, oldMac, oldRun, oldCrontab })
+
+	homeDir = func() (string, error) { return m.home, nil }
+	isMacOS = func() bool { return true }
+	hasCrontab = func() bool { return true }
+	runCommand = func(command string, args ...string) (*system.CommandResult, error) {
+		m.commands = append(m.commands, strings.Join(append([]string{command}, args...), " "))
+		if command == "crontab" && args[0] == "-l" {
+			if m.crontab == "" {
+				return &system.CommandResult{Output: "crontab: no crontab for user\n", ExitCode: 1}, nil
+			}
+			return &system.CommandResult{Output: m.crontab, Success: true}, nil
+		}
+		if command == "crontab" {
+			data, _ := os.ReadFile(args[0])
+			m.crontab = string(data)
+		}
+		return &system.CommandResult{Success: true}, nil
+	}
+	return m
+}
+
+func (m *fakeMachine) writeAgent(name, content string) {
+	os.WriteFile(filepath.Join(m.home, "Library", agentsDir, name), []byte(content), 0644)
+}
+
+func TestExport(t *testing.T) {
+	m := newFakeMachine(t)
+	m.writeAgent("com.me.backup.plist", "backup")
+	m.writeAgent(constants.LaunchAgentPrefix+".reminder.plist", "anvil")
+	m.crontab = "0 * * * * echo hi\n"
+
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, agentsDir), 0755)
+	os.WriteFile(filepath.Join(dir, agentsDir, "com.me.removed.plist"), []byte("old"), 0644)
+
+	if err := Export(dir); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	exported, _ := filepath.Glob(filepath.Join(dir, agentsDir, "*.plist"))
+	if len(exported) != 1 || filepath.Base(exported[0]) != "com.me.backup.plist" {
+		t.Errorf("exported agents = %v, want only com.me.backup.plist", exported)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, crontabFile)); string(data) != m.crontab {
+		t.Errorf("exported crontab = %q, want %q", data, m.crontab)
+	}
+
+	// A removed crontab removes the exported copy
+	m.crontab = ""
+	if err := Export(dir); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, crontabFile)); !os.IsNotExist(err) {
+		t.Errorf("crontab file should be removed when the user has no crontab")
+	}
+}
+
+func TestPlanAndApply(t *testing.T) {
+	m := newFakeMachine(t)
+	m.writeAgent("com.me.same.plist", "same")
+	m.writeAgent("com.me.changed.plist", "old")
+
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, agentsDir), 0755)
+	os.WriteFile(filepath.Join(dir, agentsDir, "com.me.same.plist"), []byte("same"), 0644)
+	os.WriteFile(filepath.Join(dir, agentsDir, "com.me.changed.plist"), []byte("new"), 0644)
+	os.WriteFile(filepath.Join(dir, agentsDir, "com.me.added.plist"), []byte("added"), 0644)
+	os.WriteFile(filepath.Join(dir, crontabFile), []byte("0 * * * * echo hi\n"), 0644)
+
+	changes, err := Plan(dir)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	var names []string
+	for _, change := range changes {
+		names = append(names, change.Name)
+	}
+	want := []string{"com.me.added", "com.me.changed", crontabFile}
+	if !slices.Equal(names, want) {
+		t.Fatalf("Plan = %v, want %v", names, want)
+	}
+	if changes[0].Replace || !changes[1].Replace {
+		t.Errorf("Replace should be set only for agents that exist")
+	}
+
+	if err := Apply(changes); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	live := filepath.Join(m.home, "Library", agentsDir)
+	if data, _ := os.ReadFile(filepath.Join(live, "com.me.changed.plist")); string(data) != "new" {
+		t.Errorf("changed agent = %q, want new", data)
+	}
+	for _, command := range []string{
+		"launchctl unload " + filepath.Join(live, "com.me.changed.plist"),
+		"launchctl load " + filepath.Join(live, "com.me.changed.plist"),
+		"launchctl load " + filepath.Join(live, "com.me.added.plist"),
+	} {
+		if !slices.Contains(m.commands, command) {
+			t.Errorf("expected %q in %v", command, m.commands)
+		}
+	}
+	if slices.Contains(m.commands, "launchctl unload "+filepath.Join(live, "com.me.added.plist")) {
+		t.Errorf("new agents should not be unloaded")
+	}
+
+	// Once applied the machine matches the synced copy
+	changes, err = Plan(dir)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("Plan after Apply = %v, want no changes", changes)
+	}
+}
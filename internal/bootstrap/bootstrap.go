@@ -0,0 +1,95 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bootstrap
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+const (
+	// InstallScriptURL is the release asset that installs the anvil binary
+	InstallScriptURL = "https://github.com/0xjuanma/anvil/releases/latest/download/install.sh"
+	// ScriptURL is the release asset of the generic bootstrap script
+	ScriptURL = "https://github.com/0xjuanma/anvil/releases/latest/download/bootstrap.sh"
+	// DefaultBranch is used when no config repository branch is given
+	DefaultBranch = "main"
+)
+
+//go:embed bootstrap.sh.tmpl
+var scriptTemplate string
+
+// Options are the values baked into a generated bootstrap script
+// Empty values are read from the environment when the script runs
+type Options struct {
+	Repo    string
+	Branch  string
+	Profile string
+}
+
+// templateData holds everything the script template renders
+type templateData struct {
+	Options
+	InstallURL string
+	ScriptURL  string
+}
+
+// GenerateScript renders the bootstrap script for the given options
+func GenerateScript(opts Options) (string, error) {
+	for name, value := range map[string]string{"repo": opts.Repo, "branch": opts.Branch, "profile": opts.Profile} {
+		if strings.ContainsAny(value, "\"'$`\\ \n") {
+			return "", fmt.Errorf("invalid %s '%s': must not contain quotes, spaces or shell characters", name, value)
+		}
+	}
+	if opts.Branch == "" {
+		opts.Branch = DefaultBranch
+	}
+
+	tmpl, err := template.New("bootstrap").Parse(scriptTemplate)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse bootstrap template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	data := templateData{Options: opts, InstallURL: InstallScriptURL, ScriptURL: ScriptURL}
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render bootstrap script: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+// OneLiner returns the curl | bash command that runs the published bootstrap script
+func OneLiner(opts Options) string {
+	var env []string
+	if opts.Repo != "" {
+		env = append(env, "ANVIL_CONFIG_REPO="+opts.Repo)
+	} else {
+		env = append(env, "ANVIL_CONFIG_REPO=username/dotfiles")
+	}
+	if opts.Branch != "" && opts.Branch != DefaultBranch {
+		env = append(env, "ANVIL_CONFIG_BRANCH="+opts.Branch)
+	}
+	if opts.Profile != "" {
+		env = append(env, "ANVIL_PROFILE="+opts.Profile)
+	}
+
+	return fmt.Sprintf("curl -fsSL %s | %s bash", ScriptURL, strings.Join(env, " "))
+}
@@ -0,0 +1,62 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bootstrap
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGenerateScript(t *testing.T) {
+	script, err := GenerateScript(Options{Repo: "user/dotfiles", Profile: "work"})
+	if err != nil {
+		t.Fatalf("GenerateScript() error = %v", err)
+	}
+
+	for _, want := range []string{
+		`ANVIL_CONFIG_REPO="${ANVIL_CONFIG_REPO:-user/dotfiles}"`,
+		`ANVIL_CONFIG_BRANCH="${ANVIL_CONFIG_BRANCH:-main}"`,
+		`ANVIL_PROFILE="${ANVIL_PROFILE:-work}"`,
+		"export ANVIL_ASSUME_YES=true",
+		InstallScriptURL,
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("GenerateScript() missing %q", want)
+		}
+	}
+
+	if _, err := GenerateScript(Options{Repo: "user/dotfiles; rm -rf ~"}); err == nil {
+		t.Error("GenerateScript() should reject shell characters")
+	}
+}
+
+func TestPublishedScriptIsCurrent(t *testing.T) {
+	script, err := GenerateScript(Options{})
+	if err != nil {
+		t.Fatalf("GenerateScript() error = %v", err)
+	}
+
+	published, err := os.ReadFile("../../bootstrap.sh")
+	if err != nil {
+		t.Fatalf("failed to read bootstrap.sh: %v", err)
+	}
+
+	if string(published) != script {
+		t.Error("bootstrap.sh is out of date, regenerate it with 'anvil bootstrap --output bootstrap.sh'")
+	}
+}
@@ -0,0 +1,90 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package brew
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/0xjuanma/anvil/internal/system"
+)
+
+// PackageType is an explicit install type annotated on a settings entry
+type PackageType string
+
+// Supported package type annotations, e.g. "cask:docker-desktop", "formula:docker", "mas:497799835"
+const (
+	PackageTypeAuto     PackageType = ""
+	PackageTypeCask     PackageType = "cask"
+	PackageTypeFormula  PackageType = "formula"
+	PackageTypeAppStore PackageType = "mas"
+)
+
+// ParsePackageName splits an annotated entry into its package name and explicit type.
+// Entries without a known annotation are returned unchanged with PackageTypeAuto.
+func ParsePackageName(entry string) (string, PackageType) {
+	prefix, name, found := strings.Cut(entry, ":")
+	if !found || name == "" {
+		return entry, PackageTypeAuto
+	}
+
+	switch PackageType(prefix) {
+	case PackageTypeCask, PackageTypeFormula, PackageTypeAppStore:
+		return name, PackageType(prefix)
+	default:
+		return entry, PackageTypeAuto
+	}
+}
+
+// isAppStoreAppInstalled checks if an App Store app id is installed using the mas CLI
+func isAppStoreAppInstalled(appID string) bool {
+	if !system.CommandExists("mas") {
+		return false
+	}
+
+	result, err := system.RunCommand("mas", "list")
+	if err != nil || !result.Success {
+		return false
+	}
+
+	for _, line := range strings.Split(result.Output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && fields[0] == appID {
+			return true
+		}
+	}
+	return false
+}
+
+// installFromAppStore installs an App Store app by id using the mas CLI
+func installFromAppStore(appID string) error {
+	if !system.IsMacOS() {
+		return fmt.Errorf("App Store installs are only supported on macOS")
+	}
+
+	if !system.CommandExists("mas") {
+		if err := InstallPackage("mas"); err != nil {
+			return fmt.Errorf("mas CLI is required for App Store installs: %w", err)
+		}
+	}
+
+	result, err := system.RunCommand("mas", "install", appID)
+	if err != nil || !result.Success {
+		return fmt.Errorf("mas install %s failed: %s", appID, strings.TrimSpace(result.Output))
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package brew
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/system"
+)
+
+// caskArtifacts is the subset of 'brew info --json=v2 --cask' listing what a cask installs
+type caskArtifacts struct {
+	Casks []struct {
+		Token     string                       `json:"token"`
+		Version   string                       `json:"version"`
+		Artifacts []map[string]json.RawMessage `json:"artifacts"`
+	} `json:"casks"`
+}
+
+// CaskAppBundles returns the paths of the .app bundles an installed cask put in place,
+// looked up in /Applications and ~/Applications
+func CaskAppBundles(name string) ([]string, error) {
+	result, _ := system.RunCommand(constants.BrewCommand, constants.BrewInfo, "--json=v2", "--cask", name)
+	if !result.Success {
+		return nil, fmt.Errorf("brew info failed for %s: %s", name, strings.TrimSpace(result.Error))
+	}
+
+	homeDir, _ := system.GetHomeDir()
+	var bundles []string
+	for _, app := range parseCaskApps([]byte(result.Output)) {
+		for _, dir := range []string{"/Applications", filepath.Join(homeDir, "Applications")} {
+			bundle := filepath.Join(dir, app)
+			if _, err := os.Stat(bundle); err == nil {
+				bundles = append(bundles, bundle)
+				break
+			}
+		}
+	}
+	if len(bundles) == 0 {
+		return nil, fmt.Errorf("no app bundle of %s found in /Applications", name)
+	}
+	return bundles, nil
+}
+
+// parseCaskApps returns the app bundle names in brew info JSON. An app artifact lists the
+// bundle name, optionally followed by options whose target renames it on install.
+func parseCaskApps(data []byte) []string {
+	var info caskArtifacts
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil
+	}
+
+	var apps []string
+	for _, cask := range info.Casks {
+		for _, artifact := range cask.Artifacts {
+			raw, ok := artifact["app"]
+			if !ok {
+				continue
+			}
+			var values []interface{}
+			if err := json.Unmarshal(raw, &values); err != nil {
+				continue
+			}
+
+			name := ""
+			for _, value := range values {
+				switch v := value.(type) {
+				case string:
+					if name == "" {
+						name = filepath.Base(v)
+					}
+				case map[string]interface{}:
+					if target, ok := v["target"].(string); ok && target != "" {
+						name = filepath.Base(target)
+					}
+				}
+			}
+			if name != "" {
+				apps = append(apps, name)
+			}
+		}
+	}
+	return apps
+}
@@ -0,0 +1,157 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package brew
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/system"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+)
+
+// Default Homebrew prefixes on macOS
+const (
+	AppleSiliconPrefix = "/opt/homebrew"
+	IntelPrefix        = "/usr/local"
+)
+
+// Setup describes the Homebrew on PATH and the Mac it runs on
+type Setup struct {
+	Prefix       string // Prefix of the brew found on PATH
+	AppleSilicon bool   // The Mac has an Apple Silicon CPU
+	Translated   bool   // Commands run under Rosetta 2, e.g. from a terminal set to open with Rosetta
+	NativeBrew   bool   // An arm64 Homebrew exists under /opt/homebrew, even if not first on PATH
+}
+
+// DetectSetup inspects the Homebrew install on PATH. It reports false off macOS or without Homebrew.
+func DetectSetup() (Setup, bool) {
+	if !system.IsMacOS() || !IsBrewInstalled() {
+		return Setup{}, false
+	}
+
+	result, err := system.RunCommand(constants.BrewCommand, "--prefix")
+	if err != nil || !result.Success {
+		return Setup{}, false
+	}
+
+	setup := Setup{
+		Prefix:       filepath.Clean(strings.TrimSpace(result.Output)),
+		AppleSilicon: sysctlFlag("hw.optional.arm64"),
+		Translated:   sysctlFlag("sysctl.proc_translated"),
+	}
+	if _, err := os.Stat(filepath.Join(AppleSiliconPrefix, "bin", "brew")); err == nil {
+		setup.NativeBrew = true
+	}
+	return setup, true
+}
+
+// sysctlFlag reports whether a boolean sysctl is set, treating missing keys as unset
+func sysctlFlag(name string) bool {
+	result, err := system.RunCommand("sysctl", "-n", name)
+	return err == nil && result.Success && strings.TrimSpace(result.Output) == "1"
+}
+
+// NeedsMigration reports whether the Intel Homebrew is in use on an Apple Silicon Mac,
+// the state Homebrew's documented migration to /opt/homebrew resolves
+func (s Setup) NeedsMigration() bool {
+	return s.AppleSilicon && s.Prefix == IntelPrefix
+}
+
+// Issues describes each mismatch between the Homebrew prefix and the Mac's architecture
+func (s Setup) Issues() []string {
+	var issues []string
+	switch {
+	case s.NeedsMigration():
+		issues = append(issues, "Homebrew at /usr/local is the Intel install, so its packages run under Rosetta 2 on this Apple Silicon Mac")
+		if s.NativeBrew {
+			issues = append(issues, "An arm64 Homebrew exists at /opt/homebrew, but /usr/local/bin comes first on PATH")
+		}
+	case !s.AppleSilicon && s.Prefix == AppleSiliconPrefix:
+		issues = append(issues, "Homebrew at /opt/homebrew is meant for Apple Silicon, on an Intel Mac it belongs in /usr/local")
+	case s.Prefix != AppleSiliconPrefix && s.Prefix != IntelPrefix:
+		issues = append(issues, fmt.Sprintf("Homebrew uses the non-default prefix %s, so bottles can't be used and packages build from source", s.Prefix))
+	}
+	if s.AppleSilicon && s.Translated {
+		issues = append(issues, "The shell runs under Rosetta 2, so brew installs for Intel; turn off 'Open using Rosetta' for your terminal app")
+	}
+	return issues
+}
+
+// MigrateToNative follows Homebrew's migration from an Intel install: it installs the arm64
+// Homebrew under /opt/homebrew when missing, then reinstalls each entry with it. The Intel
+// install is left in place to be removed once the new one works. Entries that fail to
+// reinstall are returned.
+func MigrateToNative(entries []string) ([]string, error) {
+	nativeBrew := filepath.Join(AppleSiliconPrefix, "bin", "brew")
+	if _, err := os.Stat(nativeBrew); err != nil {
+		if err := system.RunInteractiveCommand("arch", "-arm64", "/bin/bash", "-c", brewInstallScript()); err != nil {
+			return nil, fmt.Errorf("failed to install the arm64 Homebrew: %w", err)
+		}
+		if _, err := os.Stat(nativeBrew); err != nil {
+			return nil, fmt.Errorf("Homebrew install finished but %s was not found", nativeBrew)
+		}
+	}
+
+	var failed []string
+	for _, entry := range entries {
+		name, packageType := ParsePackageName(entry)
+		if packageType == PackageTypeAppStore {
+			continue
+		}
+
+		args := []string{"-arm64", nativeBrew, constants.BrewInstall, name}
+		if ResolvePackageType(entry) == PackageTypeCask {
+			// The app is already in /Applications from the Intel install, let the new brew take it over
+			args = []string{"-arm64", nativeBrew, constants.BrewInstall, "--cask", "--force", name}
+		}
+
+		spinner := charm.NewDotsSpinner(fmt.Sprintf("Reinstalling %s for arm64", name))
+		spinner.Start()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		result, err := system.RunCommandWithEnv(ctx, "", system.GetCommandEnv(constants.BrewCommand), "arch", args...)
+		cancel()
+		if err != nil || !result.Success {
+			spinner.Error(fmt.Sprintf("Failed to reinstall %s", name))
+			failed = append(failed, entry)
+			continue
+		}
+		spinner.Success(fmt.Sprintf("%s reinstalled", name))
+	}
+	return failed, nil
+}
+
+// InstalledEntries returns the settings entries installed by the Homebrew on PATH, given
+// its installed versions. App Store entries are never included.
+func InstalledEntries(entries []string, versions map[string]string) []string {
+	var installed []string
+	for _, entry := range entries {
+		name, packageType := ParsePackageName(entry)
+		if packageType == PackageTypeAppStore {
+			continue
+		}
+		if _, ok := versions[name[strings.LastIndex(name, "/")+1:]]; ok {
+			installed = append(installed, entry)
+		}
+	}
+	return installed
+}
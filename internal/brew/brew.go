@@ -17,7 +17,9 @@ limitations under the License.
 package brew
 
 import (
+	"context"
 	"fmt"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
@@ -32,6 +34,10 @@ var (
 	// Cache brew installation status to avoid repeated checks
 	brewInstalledCache *bool
 	brewCacheMutex     sync.RWMutex
+
+	// Cache installed package versions so batch lookups run brew list once
+	installedVersionsCache map[string]string
+	installedVersionsOnce  sync.Once
 )
 
 // BrewPackage represents a brew package
@@ -140,6 +146,10 @@ func InstallBrew() error {
 	palantir.GetGlobalOutputHandler().PrintInfo("You may be prompted for your password to complete the installation")
 	fmt.Println()
 
+	if !charm.Confirm(charm.ConfirmPrivileged, "Run the Homebrew install script with your account's privileges?") {
+		return fmt.Errorf("Homebrew installation declined")
+	}
+
 	spinner := charm.NewDotsSpinner("Preparing Homebrew installation")
 	spinner.Start()
 	time.Sleep(200 * time.Millisecond)
@@ -147,11 +157,9 @@ func InstallBrew() error {
 
 	fmt.Print("\r\033[K→ Enter password when prompted: ")
 
-	installScript := `echo | /bin/bash -c "$(curl -fsSL https://raw.githubusercontent.com/Homebrew/install/HEAD/install.sh)"`
-
 	spinner = charm.NewDotsSpinner("Installing")
 	spinner.Start()
-	err := system.RunInteractiveCommand("/bin/bash", "-c", installScript)
+	err := system.RunInteractiveCommand("/bin/bash", "-c", brewInstallScript())
 	spinner.Stop()
 	fmt.Println()
 
@@ -172,6 +180,15 @@ func InstallBrew() error {
 	return nil
 }
 
+// brewInstallScript returns the shell command running Homebrew's install script
+func brewInstallScript() string {
+	if charm.AssumeYes() {
+		// Unattended runs (e.g. bootstrap) must not wait on the installer's prompts
+		return `echo | NONINTERACTIVE=1 /bin/bash -c "$(curl -fsSL https://raw.githubusercontent.com/Homebrew/install/HEAD/install.sh)"`
+	}
+	return `echo | /bin/bash -c "$(curl -fsSL https://raw.githubusercontent.com/Homebrew/install/HEAD/install.sh)"`
+}
+
 // UpdateBrew updates Homebrew and its formulae
 func UpdateBrew() error {
 	if !IsBrewInstalled() {
@@ -196,6 +213,70 @@ func UpdateBrew() error {
 	return nil
 }
 
+// runUpdate is the update UpdateOnce shares, replaced in tests
+var runUpdate = UpdateBrew
+
+// updateState coordinates 'brew update' across the goroutines of one run
+var updateState struct {
+	sync.Mutex
+	done bool
+	err  error
+}
+
+// UpdateOnce runs 'brew update' the first time it is called in a run. Concurrent and later
+// callers wait for that update and share its result, so workers never start competing
+// updates. After a successful update, brew's own auto-update is turned off for the rest of
+// the run, since every install would otherwise check for updates again.
+func UpdateOnce() error {
+	updateState.Lock()
+	defer updateState.Unlock()
+	if updateState.done {
+		return updateState.err
+	}
+
+	updateState.err = runUpdate()
+	updateState.done = true
+	if updateState.err == nil {
+		system.SetCommandEnv(constants.BrewCommand, append(system.GetCommandEnv(constants.BrewCommand), constants.BrewNoAutoUpdateEnvVar+"=1"))
+	}
+	return updateState.err
+}
+
+// cleanupFreedPattern matches the summary line printed by 'brew cleanup'
+var cleanupFreedPattern = regexp.MustCompile(`freed approximately ([0-9.]+\s*[KMGT]?B)`)
+
+// Cleanup removes stale downloads and old versions with 'brew cleanup', pruning cached
+// downloads older than pruneDays when it is positive. It returns the disk space brew reports
+// as freed, or an empty string when nothing was reclaimed.
+func Cleanup(pruneDays int) (string, error) {
+	if !IsBrewInstalled() {
+		return "", fmt.Errorf("Homebrew is not installed")
+	}
+
+	args := []string{constants.BrewCleanup}
+	if pruneDays > 0 {
+		args = append(args, fmt.Sprintf("--prune=%d", pruneDays))
+	}
+
+	result, err := system.RunCommand(constants.BrewCommand, args...)
+	if err != nil {
+		return "", fmt.Errorf("failed to run brew cleanup: %w", err)
+	}
+	if !result.Success {
+		return "", fmt.Errorf("brew cleanup failed: %s", strings.TrimSpace(result.Output))
+	}
+
+	return parseCleanupFreed(result.Output), nil
+}
+
+// parseCleanupFreed extracts the reclaimed disk space from 'brew cleanup' output
+func parseCleanupFreed(output string) string {
+	if match := cleanupFreedPattern.FindStringSubmatch(output); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
 // InstallPackage installs a package using Homebrew
 func InstallPackage(packageName string) error {
 	if !IsBrewInstalled() {
@@ -228,6 +309,7 @@ func IsPackageInstalled(packageName string) bool {
 	if !IsBrewInstalled() {
 		return false
 	}
+	packageName, _ = ParsePackageName(packageName)
 
 	// Use single brew list command to check both formulas and casks
 	result, err := system.RunCommand(constants.BrewCommand, constants.BrewList, packageName)
@@ -328,9 +410,94 @@ func GetPackageInfo(packageName string) (*BrewPackage, error) {
 	return pkg, nil
 }
 
+// GetInstalledVersions returns the versions of all Homebrew formulas and casks, keyed by name.
+// The result is loaded once per run.
+func GetInstalledVersions() map[string]string {
+	installedVersionsOnce.Do(func() {
+		installedVersionsCache = make(map[string]string)
+		if !IsBrewInstalled() {
+			return
+		}
+
+		for _, args := range [][]string{{constants.BrewList, "--formula", "--versions"}, {constants.BrewList, "--cask", "--versions"}} {
+			result, err := system.RunCommand(constants.BrewCommand, args...)
+			if err != nil || !result.Success {
+				continue
+			}
+			for name, version := range parseVersionsOutput(result.Output) {
+				installedVersionsCache[name] = version
+			}
+		}
+	})
+	return installedVersionsCache
+}
+
+// parseVersionsOutput parses 'brew list --versions' lines such as "git 2.39.0", keeping the newest version listed
+func parseVersionsOutput(output string) map[string]string {
+	versions := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		versions[fields[0]] = fields[len(fields)-1]
+	}
+	return versions
+}
+
+// SetPolicyEnv applies Homebrew policy environment variables, such as HOMEBREW_NO_ANALYTICS
+// or a bottle mirror, to every brew invocation made by anvil
+func SetPolicyEnv(env []string) {
+	system.SetCommandEnv(constants.BrewCommand, env)
+}
+
+// PackageExists reports whether Homebrew knows a formula or cask for the entry, honoring
+// type annotations. App Store ids cannot be verified without signing in and are accepted.
+func PackageExists(entry string) bool {
+	name, packageType := ParsePackageName(entry)
+	switch packageType {
+	case PackageTypeAppStore:
+		return name != ""
+	case PackageTypeAuto:
+		if _, known := knownBrewPackages[name]; known {
+			return true
+		}
+	}
+
+	args := []string{constants.BrewInfo}
+	switch packageType {
+	case PackageTypeCask:
+		args = append(args, "--cask")
+	case PackageTypeFormula:
+		args = append(args, "--formula")
+	}
+	args = append(args, name)
+
+	result, err := system.RunCommand(constants.BrewCommand, args...)
+	return err == nil && result.Success
+}
+
+// ResolvePackageType returns the install type for an entry, honoring annotations
+// and falling back to the cached cask detection
+func ResolvePackageType(entry string) PackageType {
+	name, packageType := ParsePackageName(entry)
+	if packageType != PackageTypeAuto {
+		return packageType
+	}
+	if isCaskPackage(name) {
+		return PackageTypeCask
+	}
+	return PackageTypeFormula
+}
+
 // IsApplicationAvailable checks if an application is available on the system
 // Optimized approach: Fastest operations first, slowest operations last
 func IsApplicationAvailable(packageName string) bool {
+	// Explicitly annotated entries skip heuristic detection
+	if name, packageType := ParsePackageName(packageName); packageType != PackageTypeAuto {
+		return isAnnotatedPackageAvailable(name, packageType)
+	}
+
 	// Step 1: For known casks, check if app exists in /Applications (fastest - no system calls) - macOS only
 	if system.IsMacOS() && isKnownCask(packageName) {
 		if checkKnownCaskInApplications(packageName) {
@@ -372,6 +539,25 @@ func IsApplicationAvailable(packageName string) bool {
 	return false
 }
 
+// isAnnotatedPackageAvailable checks availability for entries with an explicit type annotation
+func isAnnotatedPackageAvailable(name string, packageType PackageType) bool {
+	switch packageType {
+	case PackageTypeAppStore:
+		return isAppStoreAppInstalled(name)
+	case PackageTypeCask:
+		if system.IsMacOS() && (checkKnownCaskInApplications(name) || searchApplication(fmt.Sprintf("%s.app", name))) {
+			return true
+		}
+		return IsPackageInstalled(name)
+	default:
+		result, err := system.RunCommand("which", name)
+		if err == nil && result.Success {
+			return true
+		}
+		return IsPackageInstalled(name)
+	}
+}
+
 // checkKnownCaskInApplications checks if a known cask app exists in /Applications
 func checkKnownCaskInApplications(packageName string) bool {
 	// Use optimized app name generation for known casks
@@ -560,29 +746,59 @@ func InstallPackageWithCheck(packageName string) error {
 }
 
 // InstallPackageDirectly installs a package without checking availability first
-// Used when availability has already been verified by the caller
-func InstallPackageDirectly(packageName string) error {
+// Used when availability has already been verified by the caller. Entries may carry
+// a cask:, formula: or mas: annotation to force the install type.
+func InstallPackageDirectly(entry string) error {
+	packageName, packageType := ParsePackageName(entry)
+	if packageType == PackageTypeAppStore {
+		spinner := charm.NewDotsSpinner(fmt.Sprintf("Installing App Store app %s", packageName))
+		spinner.Start()
+		if err := installFromAppStore(packageName); err != nil {
+			spinner.Error(fmt.Sprintf("Failed to install App Store app %s", packageName))
+			return err
+		}
+		spinner.Success(fmt.Sprintf("App Store app %s installed successfully", packageName))
+		return nil
+	}
+
 	if !IsBrewInstalled() {
 		return fmt.Errorf("Homebrew is not installed")
 	}
 
-	isCask := isCaskPackage(packageName)
+	// Explicit cask:/formula: annotations override heuristic detection
+	var isCask bool
+	switch packageType {
+	case PackageTypeCask:
+		isCask = true
+	case PackageTypeFormula:
+		isCask = false
+	default:
+		isCask = isCaskPackage(packageName)
+	}
 	spinner := charm.NewDotsSpinner(fmt.Sprintf("Installing %s", packageName))
 	spinner.Start()
 
-	var result *system.CommandResult
-	var err error
-
+	args := []string{constants.BrewInstall, packageName}
 	if isCask {
-		result, err = system.RunCommand(constants.BrewCommand, constants.BrewInstall, "--cask", packageName)
-	} else {
-		result, err = system.RunCommand(constants.BrewCommand, constants.BrewInstall, packageName)
+		args = []string{constants.BrewInstall, "--cask", packageName}
 	}
 
+	// Show downloads, dependencies and pouring on the spinner as brew reports them
+	progress := &installProgress{}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	result, err := system.RunCommandWithProgress(ctx, func(line string) {
+		if detail, ok := progress.update(line); ok {
+			spinner.SetProgress(progress.current, progress.total)
+			spinner.SetDetail(detail)
+		}
+	}, constants.BrewCommand, args...)
+
 	if err != nil {
 		spinner.Error(fmt.Sprintf("Failed to install %s", packageName))
 		return fmt.Errorf("failed to run brew install: %w", err)
 	}
+	recordInstallWarnings(packageName, result.Output)
 
 	if !result.Success {
 		if strings.Contains(result.Error, "already an App at") {
@@ -599,5 +815,11 @@ func InstallPackageDirectly(packageName string) error {
 	}
 
 	spinner.Success(fmt.Sprintf("%s installed successfully", packageName))
+
+	// Keep what the cask installed so 'anvil clean remnants' can find leftovers after it is
+	// removed. Without a manifest removal falls back to brew's own uninstall, so failures are ignored.
+	if isCask {
+		_ = RecordCaskManifest(packageName)
+	}
 	return nil
 }
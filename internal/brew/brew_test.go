@@ -17,8 +17,19 @@ limitations under the License.
 package brew
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
 	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"testing"
+
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/system"
 )
 
 func TestBrewPackageStruct(t *testing.T) {
@@ -107,6 +118,78 @@ func TestUpdateBrewWhenNotInstalled(t *testing.T) {
 	}
 }
 
+// resetUpdateState forgets the shared update and the env it set, restoring both after the test
+func resetUpdateState(t *testing.T, update func() error) {
+	t.Helper()
+	originalUpdate, originalEnv := runUpdate, system.GetCommandEnv(constants.BrewCommand)
+	t.Cleanup(func() {
+		runUpdate = originalUpdate
+		system.SetCommandEnv(constants.BrewCommand, originalEnv)
+		updateState.done, updateState.err = false, nil
+	})
+	runUpdate = update
+	updateState.done, updateState.err = false, nil
+}
+
+func TestUpdateOnce(t *testing.T) {
+	noAutoUpdate := constants.BrewNoAutoUpdateEnvVar + "=1"
+
+	t.Run("concurrent callers share one update", func(t *testing.T) {
+		var calls atomic.Int32
+		release := make(chan struct{})
+		resetUpdateState(t, func() error {
+			calls.Add(1)
+			<-release
+			return nil
+		})
+
+		var wg sync.WaitGroup
+		errs := make([]error, 8)
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				errs[i] = UpdateOnce()
+			}(i)
+		}
+		close(release)
+		wg.Wait()
+
+		if got := calls.Load(); got != 1 {
+			t.Errorf("brew update ran %d times, want 1", got)
+		}
+		for i, err := range errs {
+			if err != nil {
+				t.Errorf("caller %d got %v, want nil", i, err)
+			}
+		}
+		if !slices.Contains(system.GetCommandEnv(constants.BrewCommand), noAutoUpdate) {
+			t.Errorf("brew env = %v, want %s after a successful update", system.GetCommandEnv(constants.BrewCommand), noAutoUpdate)
+		}
+	})
+
+	t.Run("later callers get the failed result", func(t *testing.T) {
+		var calls int
+		updateErr := fmt.Errorf("brew update failed: offline")
+		resetUpdateState(t, func() error {
+			calls++
+			return updateErr
+		})
+
+		for i := 0; i < 3; i++ {
+			if err := UpdateOnce(); err != updateErr {
+				t.Errorf("call %d got %v, want %v", i, err, updateErr)
+			}
+		}
+		if calls != 1 {
+			t.Errorf("brew update ran %d times, want 1", calls)
+		}
+		if slices.Contains(system.GetCommandEnv(constants.BrewCommand), noAutoUpdate) {
+			t.Errorf("brew auto-update was turned off after a failed update")
+		}
+	})
+}
+
 func TestInstallPackageWhenNotInstalled(t *testing.T) {
 	// If brew is not installed, InstallPackage should return an error
 	// This test assumes brew is not installed - skip if it is
@@ -379,3 +462,510 @@ func BenchmarkIsPackageInstalled(b *testing.B) {
 		IsPackageInstalled("git")
 	}
 }
+
+func TestParsePackageName(t *testing.T) {
+	tests := []struct {
+		entry        string
+		expectedName string
+		expectedType PackageType
+	}{
+		{"docker", "docker", PackageTypeAuto},
+		{"cask:docker-desktop", "docker-desktop", PackageTypeCask},
+		{"formula:docker", "docker", PackageTypeFormula},
+		{"mas:497799835", "497799835", PackageTypeAppStore},
+		{"unknown:docker", "unknown:docker", PackageTypeAuto},
+		{"cask:", "cask:", PackageTypeAuto},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.entry, func(t *testing.T) {
+			name, packageType := ParsePackageName(tt.entry)
+			if name != tt.expectedName {
+				t.Errorf("Expected name %q, got %q", tt.expectedName, name)
+			}
+			if packageType != tt.expectedType {
+				t.Errorf("Expected type %q, got %q", tt.expectedType, packageType)
+			}
+		})
+	}
+}
+
+func TestDiagnoseInstallFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected FailureKind
+	}{
+		{"checksum mismatch", "Error: SHA256 mismatch\nExpected: abc", FailureChecksum},
+		{"rosetta required", "Error: wine-stable requires Rosetta 2", FailureRosetta},
+		{"newer macOS", "Error: This cask requires macOS >= 14", FailureMacOSVersion},
+		{"existing app", "Error: It seems there is already an App at '/Applications/Slack.app'.", FailureAppConflict},
+		{"network", "curl: (6) Could not resolve host: github.com", FailureNetwork},
+		{"unknown", "Error: something unexpected", FailureUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diagnosis := DiagnoseInstallFailure("pkg", tt.output)
+			if diagnosis.Kind != tt.expected {
+				t.Errorf("Expected kind %s, got %s", tt.expected, diagnosis.Kind)
+			}
+			if len(diagnosis.Suggestions) == 0 {
+				t.Error("Expected at least one suggestion")
+			}
+		})
+	}
+}
+
+func TestRecoverFromInstallFailureSubstitute(t *testing.T) {
+	originalFind, originalConfirm, originalInstall := findCandidates, confirm, installDirectly
+	t.Cleanup(func() { findCandidates, confirm, installDirectly = originalFind, originalConfirm, originalInstall })
+
+	findCandidates = func(string) []string { return []string{"visual-studio-code", "vscodium"} }
+	confirm = func(string, string) bool { return true }
+	var installed []string
+	installDirectly = func(entry string) error {
+		installed = append(installed, entry)
+		return nil
+	}
+
+	installErr := fmt.Errorf("Error: No available formula with the name \"vscode\"")
+	err := RecoverFromInstallFailure("vscode", installErr)
+
+	var substituted *SubstitutedError
+	if !errors.As(err, &substituted) {
+		t.Fatalf("RecoverFromInstallFailure() = %v, want a *SubstitutedError", err)
+	}
+	if substituted.Entry != "vscode" || substituted.Substitute != "visual-studio-code" {
+		t.Errorf("substituted = %+v, want vscode replaced by visual-studio-code", substituted)
+	}
+	if !reflect.DeepEqual(installed, []string{"visual-studio-code"}) {
+		t.Errorf("installed %v, want only the substitute", installed)
+	}
+
+	// Declining the substitute keeps the original failure
+	confirm = func(string, string) bool { return false }
+	if err := RecoverFromInstallFailure("vscode", installErr); err != installErr {
+		t.Errorf("declined substitute returned %v, want the install error", err)
+	}
+}
+
+func TestParseVersionsOutput(t *testing.T) {
+	output := "git 2.39.0 2.45.0\nslack 4.38.125\n\nmalformed\n"
+	versions := parseVersionsOutput(output)
+
+	if versions["git"] != "2.45.0" {
+		t.Errorf("git version = %q, want newest listed version 2.45.0", versions["git"])
+	}
+	if versions["slack"] != "4.38.125" {
+		t.Errorf("slack version = %q, want 4.38.125", versions["slack"])
+	}
+	if _, ok := versions["malformed"]; ok {
+		t.Error("lines without a version should be ignored")
+	}
+}
+
+func TestParseCleanupFreed(t *testing.T) {
+	output := "Removing: /Users/me/Library/Caches/Homebrew/git--2.39.0.bottle.tar.gz... (4.5MB)\n==> This operation has freed approximately 1.2GB of disk space.\n"
+	if got := parseCleanupFreed(output); got != "1.2GB" {
+		t.Errorf("parseCleanupFreed() = %q, want 1.2GB", got)
+	}
+	if got := parseCleanupFreed(""); got != "" {
+		t.Errorf("parseCleanupFreed() of empty output = %q, want empty", got)
+	}
+}
+
+func TestParseInstallWarnings(t *testing.T) {
+	output := `Warning: Formula docker was renamed to docker-cli.
+Warning: docker-cli has been deprecated because it is not supported upstream! It will be disabled on 2026-01-01.
+==> Pouring docker-cli--27.0.bottle.tar.gz`
+
+	notices := ParseInstallWarnings("docker", output)
+	if len(notices) != 1 {
+		t.Fatalf("ParseInstallWarnings() = %+v, want one notice", notices)
+	}
+	got := notices[0]
+	if got.Name != "docker" || got.RenamedTo != "docker-cli" || !got.Deprecated || got.Reason != "is not supported upstream" {
+		t.Errorf("notice = %+v, want docker renamed to docker-cli and deprecated", got)
+	}
+
+	if notices := ParseInstallWarnings("git", "==> Pouring git--2.45.bottle.tar.gz"); len(notices) != 0 {
+		t.Errorf("clean output produced notices: %+v", notices)
+	}
+}
+
+func TestFindPackageNotices(t *testing.T) {
+	data := []byte(`{
+  "formulae": [
+    {"name": "docker-cli", "oldnames": ["docker"], "deprecated": false},
+    {"name": "python@3.8", "oldnames": [], "deprecated": true, "deprecation_reason": "unsupported"},
+    {"name": "git", "oldnames": []}
+  ],
+  "casks": [
+    {"token": "docker-desktop", "old_tokens": ["docker"], "disabled": true, "disable_reason": "no_longer_available"}
+  ]
+}`)
+
+	notices, err := findPackageNotices([]string{"git", "formula:docker", "python@3.8", "mas:497799835", "git"}, data)
+	if err != nil {
+		t.Fatalf("findPackageNotices() error = %v", err)
+	}
+	if len(notices) != 2 {
+		t.Fatalf("findPackageNotices() = %+v, want two notices", notices)
+	}
+	if notices[0].Name != "formula:docker" || notices[0].RenamedTo == "" {
+		t.Errorf("notices[0] = %+v, want docker renamed", notices[0])
+	}
+	if notices[1].Name != "python@3.8" || !notices[1].Deprecated || notices[1].Reason != "unsupported" {
+		t.Errorf("notices[1] = %+v, want python@3.8 deprecated", notices[1])
+	}
+}
+
+func TestInstallProgress(t *testing.T) {
+	progress := &installProgress{}
+	steps := []struct {
+		line    string
+		detail  string
+		ok      bool
+		current int
+	}{
+		{"==> Downloading https://ghcr.io/v2/homebrew/core/wget/blobs/sha256:abc", "Downloading sha256:abc", true, 0},
+		{"######################################################################## 100.0%", "", false, 0},
+		{"==> Installing dependencies for wget: libunistring, gettext and openssl@3", "Installing dependencies for wget: libunistring, gettext and openssl@3", true, 0},
+		{"==> Installing wget dependency: libunistring", "Installing libunistring", true, 1},
+		{"==> Pouring libunistring--1.2.arm64_sonoma.bottle.tar.gz", "Pouring libunistring--1.2.arm64_sonoma.bottle.tar.gz", true, 1},
+		{"==> Installing wget dependency: gettext", "Installing gettext", true, 2},
+		{"==> Installing wget dependency: openssl@3", "Installing openssl@3", true, 3},
+		{"==> Installing wget", "Installing wget", true, 4},
+	}
+
+	for _, step := range steps {
+		detail, ok := progress.update(step.line)
+		if detail != step.detail || ok != step.ok || progress.current != step.current {
+			t.Errorf("update(%q) = %q, %v at %d, want %q, %v at %d", step.line, detail, ok, progress.current, step.detail, step.ok, step.current)
+		}
+	}
+	if progress.total != 4 {
+		t.Errorf("total = %d, want 4", progress.total)
+	}
+}
+
+func TestParseSearchOutput(t *testing.T) {
+	output := "==> Formulae\npostgresql@16\npostgrest\n\n==> Casks\npostgres-unofficial\nIf you meant \"postgres\" specifically:\n"
+	names := parseSearchOutput(output)
+	want := []string{"postgresql@16", "postgrest", "postgres-unofficial"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("parseSearchOutput() = %v, want %v", names, want)
+	}
+}
+
+func TestRankResults(t *testing.T) {
+	results := []SearchResult{
+		{Name: "libpq", Type: PackageTypeFormula},
+		{Name: "docker-compose", Type: PackageTypeFormula},
+		{Name: "docker", Type: PackageTypeCask, Ambiguous: true},
+		{Name: "docker", Type: PackageTypeFormula, Ambiguous: true},
+		{Name: "homebrew/cask/docker-desktop", Type: PackageTypeCask},
+	}
+	rankResults("Docker", results)
+
+	var got []string
+	for _, result := range results {
+		got = append(got, result.Entry())
+	}
+	want := []string{"formula:docker", "cask:docker", "docker-compose", "homebrew/cask/docker-desktop", "libpq"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rankResults() order = %v, want %v", got, want)
+	}
+}
+
+func TestApplyDescriptions(t *testing.T) {
+	results := []SearchResult{
+		{Name: "wget", Type: PackageTypeFormula},
+		{Name: "homebrew/core/jq", Type: PackageTypeFormula},
+		{Name: "wget", Type: PackageTypeCask},
+	}
+	data := `{"formulae":[{"name":"wget","full_name":"wget","desc":"Internet file retriever"},{"name":"jq","full_name":"homebrew/core/jq","desc":"JSON processor"}],"casks":[]}`
+	applyDescriptions(results, PackageTypeFormula, []byte(data))
+
+	if results[0].Description != "Internet file retriever" || results[1].Description != "JSON processor" {
+		t.Errorf("formula descriptions = %q, %q", results[0].Description, results[1].Description)
+	}
+	if results[2].Description != "" {
+		t.Errorf("cask description = %q, want it untouched", results[2].Description)
+	}
+}
+
+func TestSearchCache(t *testing.T) {
+	options := SearchOptions{Limit: 20, CacheDir: t.TempDir()}
+	results := []SearchResult{{Name: "wget", Type: PackageTypeFormula, Description: "Internet file retriever", Installed: true}}
+	saveSearchCache(options, "wget", results)
+
+	cached, ok := loadSearchCache(options, "WGET")
+	if !ok || len(cached) != 1 || cached[0].Description != "Internet file retriever" || cached[0].Installed {
+		t.Errorf("loadSearchCache() = %+v, %v, want the saved result without install state", cached, ok)
+	}
+	if _, ok := loadSearchCache(SearchOptions{Limit: 50, CacheDir: options.CacheDir}, "wget"); ok {
+		t.Error("loadSearchCache() hit for a different limit")
+	}
+	if _, ok := loadSearchCache(SearchOptions{Limit: 20, CacheDir: options.CacheDir, Refresh: true}, "wget"); ok {
+		t.Error("loadSearchCache() hit with Refresh set")
+	}
+}
+
+func TestAddPackageInfo(t *testing.T) {
+	data := `{
+		"formulae": [{"name": "aerospace", "full_name": "nikitabobko/tap/aerospace", "tap": "nikitabobko/tap",
+			"versions": {"stable": "0.15.2"}, "dependencies": [],
+			"bottle": {"stable": {"files": {"all": {"url": "https://example.com/aerospace.tar.gz"}}}}},
+			{"name": "git", "full_name": "git", "tap": "homebrew/core", "versions": {"stable": "2.45.0"},
+			"dependencies": ["gettext", "pcre2"], "bottle": {"stable": {"files": {}}}}],
+		"casks": [{"token": "iterm2", "full_token": "iterm2", "tap": "homebrew/cask", "version": "3.5.0",
+			"url": "https://example.com/iTerm2.zip"}]
+	}`
+
+	found := make(map[string]PackageDetails)
+	addPackageInfo(found, PackageTypeFormula, []byte(data))
+
+	aerospace, ok := found["formula:nikitabobko/tap/aerospace"]
+	if !ok || found["formula:aerospace"].Version != "0.15.2" {
+		t.Fatalf("aerospace should be found by name and full name, got %v", found)
+	}
+	if aerospace.Tap != "nikitabobko/tap" || aerospace.downloadURL != "https://example.com/aerospace.tar.gz" {
+		t.Errorf("aerospace = %+v, want its tap and the 'all' bottle URL", aerospace)
+	}
+	git := found["formula:git"]
+	if git.Tap != "" || len(git.Dependencies) != 2 || git.downloadURL != "" {
+		t.Errorf("git = %+v, want no tap, two dependencies and no bottle URL", git)
+	}
+	iterm := found["formula:iterm2"]
+	if iterm.Type != PackageTypeCask || iterm.Version != "3.5.0" || iterm.Tap != "" {
+		t.Errorf("iterm2 = %+v, want a cask at 3.5.0 with no tap", iterm)
+	}
+
+	addPackageInfo(found, PackageTypeFormula, []byte("not json"))
+	if len(found) != 4 {
+		t.Errorf("invalid JSON should add nothing, got %d entries", len(found))
+	}
+}
+
+func TestAddMetadataInfo(t *testing.T) {
+	data := `{
+		"formulae": [{"name": "git", "full_name": "git", "desc": "Distributed revision control system",
+			"license": "GPL-2.0-only", "homepage": "https://git-scm.com", "revision": 1,
+			"versions": {"stable": "2.45.0"}, "bottle": {"stable": {"files": {"all": {"url": "https://example.com/git.tar.gz"}}}}}],
+		"casks": [{"token": "iterm2", "full_token": "iterm2", "desc": "Terminal emulator", "homepage": "https://iterm2.com",
+			"version": "3.5.0", "url": "https://example.com/iTerm2.zip"}]
+	}`
+
+	found := make(map[string]PackageMetadata)
+	addMetadataInfo(found, PackageTypeCask, []byte(data))
+
+	git := found["cask:git"]
+	if git.Description != "Distributed revision control system" || git.License != "GPL-2.0-only" || git.Revision != "2.45.0_1" {
+		t.Errorf("git = %+v, want description, license and a revision with the formula revision", git)
+	}
+	if git.downloadURL != "https://example.com/git.tar.gz" {
+		t.Errorf("git download URL = %q, want the 'all' bottle", git.downloadURL)
+	}
+	iterm := found["cask:iterm2"]
+	if iterm.Revision != "3.5.0" || iterm.License != "" || iterm.Homepage != "https://iterm2.com" {
+		t.Errorf("iterm2 = %+v, want the cask version as revision", iterm)
+	}
+}
+
+func TestMetadataCache(t *testing.T) {
+	dir := t.TempDir()
+	if cache := loadMetadataCache(dir); len(cache) != 0 {
+		t.Fatalf("missing cache should load empty, got %v", cache)
+	}
+
+	cache := map[string]cachedMetadata{
+		"formula:git": {Metadata: PackageMetadata{Description: "vcs", Revision: "2.45.0_1", Size: 1024}, SizeChecked: true},
+	}
+	saveMetadataCache(dir, cache)
+	if got := loadMetadataCache(dir); !reflect.DeepEqual(got, cache) {
+		t.Errorf("loadMetadataCache() = %+v, want %+v", got, cache)
+	}
+
+	saveMetadataCache("", cache)
+	if got := loadMetadataCache(""); len(got) != 0 {
+		t.Errorf("disabled cache should load empty, got %v", got)
+	}
+}
+
+func TestTapOf(t *testing.T) {
+	for name, want := range map[string]string{
+		"nikitabobko/tap/aerospace": "nikitabobko/tap",
+		"git":                       "",
+		"homebrew/core":             "",
+	} {
+		if got := TapOf(name); got != want {
+			t.Errorf("TapOf(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestSetupIssues(t *testing.T) {
+	tests := []struct {
+		name      string
+		setup     Setup
+		issues    int
+		migration bool
+	}{
+		{"native apple silicon", Setup{Prefix: AppleSiliconPrefix, AppleSilicon: true}, 0, false},
+		{"native intel", Setup{Prefix: IntelPrefix}, 0, false},
+		{"intel brew on apple silicon", Setup{Prefix: IntelPrefix, AppleSilicon: true}, 1, true},
+		{"both installs, intel first on path", Setup{Prefix: IntelPrefix, AppleSilicon: true, NativeBrew: true}, 2, true},
+		{"shell under rosetta", Setup{Prefix: AppleSiliconPrefix, AppleSilicon: true, Translated: true}, 1, false},
+		{"apple silicon prefix on intel", Setup{Prefix: AppleSiliconPrefix}, 1, false},
+		{"custom prefix", Setup{Prefix: "/Users/me/homebrew", AppleSilicon: true}, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if issues := tt.setup.Issues(); len(issues) != tt.issues {
+				t.Errorf("Issues() = %v, want %d issues", issues, tt.issues)
+			}
+			if got := tt.setup.NeedsMigration(); got != tt.migration {
+				t.Errorf("NeedsMigration() = %v, want %v", got, tt.migration)
+			}
+		})
+	}
+}
+
+func TestInstalledEntries(t *testing.T) {
+	versions := map[string]string{"git": "2.45.0", "iterm2": "3.5.0", "aerospace": "0.15.2"}
+	entries := []string{"git", "cask:iterm2", "nikitabobko/tap/aerospace", "slack", "mas:497799835"}
+
+	got := InstalledEntries(entries, versions)
+	want := []string{"git", "cask:iterm2", "nikitabobko/tap/aerospace"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("InstalledEntries() = %v, want %v", got, want)
+	}
+}
+
+func TestParseCaskApps(t *testing.T) {
+	data := []byte(`{"formulae":[],"casks":[
+		{"token":"visual-studio-code","artifacts":[{"app":["Visual Studio Code.app"]},{"binary":["code"]}]},
+		{"token":"renamed","artifacts":[{"uninstall":[{"quit":"x"}]},{"app":["Thing.app",{"target":"Other Thing.app"}]}]}
+	]}`)
+
+	got := parseCaskApps(data)
+	want := []string{"Visual Studio Code.app", "Other Thing.app"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseCaskApps() = %v, want %v", got, want)
+	}
+
+	if got := parseCaskApps([]byte("not json")); got != nil {
+		t.Errorf("parseCaskApps(invalid) = %v, want nil", got)
+	}
+}
+
+func TestCaskManifest(t *testing.T) {
+	data := []byte(`{"casks":[{"token":"zoom","version":"6.1.0","artifacts":[
+		{"uninstall":[{"launchctl":"us.zoom.ZoomDaemon","pkgutil":["us.zoom.pkg.videomeeting"],"delete":"/Applications/zoom.us.app"}]},
+		{"pkg":["zoomusInstallerFull.pkg"]},
+		{"zap":[{"trash":["~/Library/Preferences/us.zoom.xos.plist","~/Library/Caches/us.zoom.*"],"rmdir":"~/Library/Application Support/zoom.us"}]}
+	]}]}`)
+
+	manifest, err := parseCaskManifest(data)
+	if err != nil {
+		t.Fatalf("parseCaskManifest() failed: %v", err)
+	}
+	if manifest.Token != "zoom" || manifest.Version != "6.1.0" {
+		t.Errorf("parseCaskManifest() token %s version %s, want zoom 6.1.0", manifest.Token, manifest.Version)
+	}
+	if !reflect.DeepEqual(manifest.LaunchAgents, []string{"us.zoom.ZoomDaemon"}) || !reflect.DeepEqual(manifest.PkgReceipts, []string{"us.zoom.pkg.videomeeting"}) {
+		t.Errorf("parseCaskManifest() launch agents %v, receipts %v", manifest.LaunchAgents, manifest.PkgReceipts)
+	}
+	wantRemnants := []string{"/Applications/zoom.us.app", "~/Library/Preferences/us.zoom.xos.plist", "~/Library/Caches/us.zoom.*", "~/Library/Application Support/zoom.us"}
+	if !reflect.DeepEqual(manifest.Remnants, wantRemnants) {
+		t.Errorf("parseCaskManifest() remnants = %v, want %v", manifest.Remnants, wantRemnants)
+	}
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	manifest.PkgReceipts = nil
+	manifest.Remnants = manifest.Remnants[1:]
+	for _, path := range []string{"Library/Preferences/us.zoom.xos.plist", "Library/Caches/us.zoom.xos/Cache.db", "Library/LaunchAgents/us.zoom.ZoomDaemon.plist"} {
+		path = filepath.Join(home, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	leftovers := manifest.Leftovers()
+	want := []Leftover{
+		{Kind: "launch agent", Path: filepath.Join(home, "Library/LaunchAgents/us.zoom.ZoomDaemon.plist")},
+		{Kind: "file", Path: filepath.Join(home, "Library/Preferences/us.zoom.xos.plist")},
+		{Kind: "file", Path: filepath.Join(home, "Library/Caches/us.zoom.xos")},
+	}
+	if !reflect.DeepEqual(leftovers, want) {
+		t.Errorf("Leftovers() = %v, want %v", leftovers, want)
+	}
+
+	if err := SaveCaskManifest(manifest); err != nil {
+		t.Fatalf("SaveCaskManifest() failed: %v", err)
+	}
+	manifests, err := ListCaskManifests()
+	if err != nil || len(manifests) != 1 || manifests[0].Token != "zoom" {
+		t.Errorf("ListCaskManifests() = %v, %v, want the zoom manifest", manifests, err)
+	}
+}
+
+func TestGenerateBrewfile(t *testing.T) {
+	data := GenerateBrewfile([]BrewfileSection{
+		{Title: "required_tools", Entries: []string{"git", "cask:docker"}},
+		{Title: "group: dev", Entries: []string{"git", "firefox", "mas:497799835"}},
+		{Title: "installed_apps", Entries: []string{"git"}},
+	})
+
+	expected := `# Generated by anvil export from settings.yaml
+
+# required_tools
+brew "git"
+cask "docker"
+
+# group: dev
+cask "firefox"
+mas "497799835", id: 497799835
+`
+	if string(data) != expected {
+		t.Errorf("GenerateBrewfile() =\n%s\nwant\n%s", data, expected)
+	}
+}
+
+func TestParseBrewfile(t *testing.T) {
+	brewfile := `# Essentials
+tap "homebrew/bundle"
+brew "git"
+brew "mysql", restart_service: true
+brew "example-formula"
+cask 'firefox', args: { appdir: "~/Applications" }
+cask "docker"
+mas "Xcode", id: 497799835
+brew "git"
+vscode "golang.go"
+`
+	parsed, err := ParseBrewfile([]byte(brewfile))
+	if err != nil {
+		t.Fatalf("ParseBrewfile() error = %v", err)
+	}
+
+	expectedEntries := []string{"git", "mysql", "formula:example-formula", "firefox", "cask:docker", "mas:497799835"}
+	if !reflect.DeepEqual(parsed.Entries, expectedEntries) {
+		t.Errorf("Entries = %v, want %v", parsed.Entries, expectedEntries)
+	}
+	expectedSkipped := []string{`tap "homebrew/bundle"`, `vscode "golang.go"`}
+	if !reflect.DeepEqual(parsed.Skipped, expectedSkipped) {
+		t.Errorf("Skipped = %v, want %v", parsed.Skipped, expectedSkipped)
+	}
+
+	if _, err := ParseBrewfile([]byte(`mas "Xcode"`)); err == nil {
+		t.Error("Expected an error for a mas line without an id")
+	}
+}
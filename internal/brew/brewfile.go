@@ -0,0 +1,167 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package brew
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Brewfile keywords understood by 'brew bundle'
+const (
+	brewfileBrew = "brew"
+	brewfileCask = "cask"
+	brewfileMas  = "mas"
+)
+
+// brewfileMasID matches the App Store id of a mas line, e.g. mas "Xcode", id: 497799835
+var brewfileMasID = regexp.MustCompile(`id:\s*(\d+)`)
+
+// BrewfileSection is a commented block of entries written to a Brewfile
+type BrewfileSection struct {
+	Title   string
+	Entries []string
+}
+
+// GenerateBrewfile renders settings entries as a Brewfile, one commented block per section.
+// Entries are resolved to brew, cask or mas lines through their annotation or the known
+// packages table, and entries already written by an earlier section are skipped.
+func GenerateBrewfile(sections []BrewfileSection) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("# Generated by anvil export from settings.yaml\n")
+
+	written := make(map[string]bool)
+	for _, section := range sections {
+		var lines []string
+		for _, entry := range section.Entries {
+			line := BrewfileLine(entry)
+			if line == "" || written[line] {
+				continue
+			}
+			written[line] = true
+			lines = append(lines, line)
+		}
+		if len(lines) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(&buf, "\n# %s\n", section.Title)
+		for _, line := range lines {
+			buf.WriteString(line + "\n")
+		}
+	}
+	return buf.Bytes()
+}
+
+// BrewfileLine formats a settings entry as a Brewfile line
+func BrewfileLine(entry string) string {
+	entry = strings.TrimSpace(entry)
+	if entry == "" {
+		return ""
+	}
+
+	name, _ := ParsePackageName(entry)
+	switch ResolvePackageType(entry) {
+	case PackageTypeAppStore:
+		return fmt.Sprintf("%s %q, id: %s", brewfileMas, name, name)
+	case PackageTypeCask:
+		return fmt.Sprintf("%s %q", brewfileCask, name)
+	default:
+		return fmt.Sprintf("%s %q", brewfileBrew, name)
+	}
+}
+
+// BrewfileImport holds the settings entries read from a Brewfile
+type BrewfileImport struct {
+	Entries []string
+	Skipped []string // Lines anvil has no equivalent for, such as tap, vscode or cask_args
+}
+
+// ParseBrewfile reads the brew, cask and mas lines of a Brewfile as settings entries.
+// Entries keep their plain name when the known packages table agrees with the line's
+// type and carry a cask:, formula: or mas: annotation otherwise.
+func ParseBrewfile(data []byte) (*BrewfileImport, error) {
+	result := &BrewfileImport{}
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		keyword, rest, _ := strings.Cut(line, " ")
+		var entry string
+		switch keyword {
+		case brewfileBrew, brewfileCask:
+			name := brewfileName(rest)
+			if name == "" {
+				return nil, fmt.Errorf("line %d: missing package name in '%s'", lineNumber, line)
+			}
+			packageType := PackageTypeFormula
+			if keyword == brewfileCask {
+				packageType = PackageTypeCask
+			}
+			entry = brewfileEntry(name, packageType)
+		case brewfileMas:
+			match := brewfileMasID.FindStringSubmatch(rest)
+			if match == nil {
+				return nil, fmt.Errorf("line %d: missing App Store id in '%s'", lineNumber, line)
+			}
+			entry = string(PackageTypeAppStore) + ":" + match[1]
+		default:
+			result.Skipped = append(result.Skipped, line)
+			continue
+		}
+
+		if !seen[entry] {
+			seen[entry] = true
+			result.Entries = append(result.Entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// brewfileName returns the quoted package name at the start of a Brewfile line's arguments
+func brewfileName(args string) string {
+	args = strings.TrimSpace(args)
+	if len(args) < 2 || (args[0] != '"' && args[0] != '\'') {
+		return ""
+	}
+	end := strings.IndexByte(args[1:], args[0])
+	if end < 0 {
+		return ""
+	}
+	return strings.TrimSpace(args[1 : end+1])
+}
+
+// brewfileEntry annotates a package with its type unless the known packages table already implies it
+func brewfileEntry(name string, packageType PackageType) string {
+	if isCask, known := knownBrewPackages[name]; known && isCask == (packageType == PackageTypeCask) {
+		return name
+	}
+	return string(packageType) + ":" + name
+}
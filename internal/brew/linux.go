@@ -35,6 +35,10 @@ func InstallBrewLinux() error {
 	palantir.GetGlobalOutputHandler().PrintInfo("You may be prompted for your password to complete the installation")
 	fmt.Println()
 
+	if !charm.Confirm(charm.ConfirmPrivileged, "Run the Homebrew install script with your account's privileges?") {
+		return fmt.Errorf("Homebrew installation declined")
+	}
+
 	spinner := charm.NewDotsSpinner("Preparing Homebrew installation for Linux")
 	spinner.Start()
 	time.Sleep(200 * time.Millisecond)
@@ -0,0 +1,268 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package brew
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/system"
+	"gopkg.in/yaml.v2"
+)
+
+// ManifestDirName is the directory under ~/.anvil holding the manifests of installed casks
+const ManifestDirName = "casks"
+
+// launchdDirs are where launchctl labels from cask metadata have their plists
+var launchdDirs = []string{"~/Library/LaunchAgents", "/Library/LaunchAgents", "/Library/LaunchDaemons"}
+
+// CaskManifest records what a cask put on the system, captured when anvil installed it so
+// leftovers can be found after the cask is removed, by anvil or with brew directly
+type CaskManifest struct {
+	Token        string    `yaml:"token"`
+	Version      string    `yaml:"version,omitempty"`
+	RecordedAt   time.Time `yaml:"recorded_at"`
+	Apps         []string  `yaml:"apps,omitempty"`          // App bundle names in /Applications
+	LaunchAgents []string  `yaml:"launch_agents,omitempty"` // launchctl labels of agents and daemons
+	PkgReceipts  []string  `yaml:"pkg_receipts,omitempty"`  // pkgutil ids of installer packages
+	Remnants     []string  `yaml:"remnants,omitempty"`      // Preferences, caches and support files, may hold globs
+}
+
+// Leftover is something of a removed cask that is still on disk
+type Leftover struct {
+	Kind string // "app", "launch agent", "pkg receipt" or "file"
+	Path string // File path, or the pkgutil id for receipts
+}
+
+// GetManifestDirectory returns the directory holding cask manifests
+func GetManifestDirectory() string {
+	homeDir, _ := system.GetHomeDir()
+	return filepath.Join(homeDir, constants.ANVIL_CONFIG_DIR, ManifestDirName)
+}
+
+// RecordCaskManifest reads a cask's artifacts from brew and stores them in its manifest
+func RecordCaskManifest(name string) error {
+	result, _ := system.RunCommand(constants.BrewCommand, constants.BrewInfo, "--json=v2", "--cask", name)
+	if !result.Success {
+		return fmt.Errorf("brew info failed for %s: %s", name, strings.TrimSpace(result.Error))
+	}
+
+	manifest, err := parseCaskManifest([]byte(result.Output))
+	if err != nil {
+		return err
+	}
+	manifest.RecordedAt = time.Now()
+	return SaveCaskManifest(manifest)
+}
+
+// SaveCaskManifest writes a manifest to the manifest directory
+func SaveCaskManifest(manifest CaskManifest) error {
+	data, err := yaml.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(GetManifestDirectory(), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(manifestPath(manifest.Token), data, 0644)
+}
+
+// LoadCaskManifest returns the manifest of a cask, found is false when none was recorded
+func LoadCaskManifest(token string) (CaskManifest, bool, error) {
+	data, err := os.ReadFile(manifestPath(token))
+	if os.IsNotExist(err) {
+		return CaskManifest{}, false, nil
+	}
+	if err != nil {
+		return CaskManifest{}, false, err
+	}
+
+	var manifest CaskManifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return CaskManifest{}, true, fmt.Errorf("invalid manifest for %s: %w", token, err)
+	}
+	return manifest, true, nil
+}
+
+// ListCaskManifests returns every recorded manifest sorted by token
+func ListCaskManifests() ([]CaskManifest, error) {
+	entries, err := os.ReadDir(GetManifestDirectory())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var manifests []CaskManifest
+	for _, entry := range entries {
+		token, ok := strings.CutSuffix(entry.Name(), ".yaml")
+		if entry.IsDir() || !ok {
+			continue
+		}
+		manifest, _, err := LoadCaskManifest(token)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, manifest)
+	}
+	sort.Slice(manifests, func(i, j int) bool { return manifests[i].Token < manifests[j].Token })
+	return manifests, nil
+}
+
+// RemoveCaskManifest deletes the manifest of a cask once nothing of it is left
+func RemoveCaskManifest(token string) error {
+	if err := os.Remove(manifestPath(token)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// manifestPath returns where the manifest of a cask is stored
+func manifestPath(token string) string {
+	return filepath.Join(GetManifestDirectory(), token+".yaml")
+}
+
+// Leftovers returns what the manifest lists that is still on disk. Receipts of installer
+// packages are looked up with pkgutil.
+func (m CaskManifest) Leftovers() []Leftover {
+	homeDir, _ := system.GetHomeDir()
+	var leftovers []Leftover
+	seen := make(map[string]bool)
+	add := func(kind, path string) {
+		if !seen[path] {
+			seen[path] = true
+			leftovers = append(leftovers, Leftover{Kind: kind, Path: path})
+		}
+	}
+
+	for _, app := range m.Apps {
+		for _, dir := range []string{"/Applications", filepath.Join(homeDir, "Applications")} {
+			if path := filepath.Join(dir, app); exists(path) {
+				add("app", path)
+			}
+		}
+	}
+	for _, label := range m.LaunchAgents {
+		for _, dir := range launchdDirs {
+			if path := filepath.Join(expandHome(dir, homeDir), label+".plist"); exists(path) {
+				add("launch agent", path)
+			}
+		}
+	}
+	for _, pattern := range m.Remnants {
+		matches, _ := filepath.Glob(expandHome(pattern, homeDir))
+		for _, path := range matches {
+			add("file", path)
+		}
+	}
+	for _, id := range m.PkgReceipts {
+		if result, _ := system.RunCommand("pkgutil", "--pkg-info", id); result.Success {
+			add("pkg receipt", id)
+		}
+	}
+	return leftovers
+}
+
+// parseCaskManifest builds a manifest from 'brew info --json=v2 --cask' output, taking apps
+// from app artifacts and the rest from the uninstall and zap stanzas
+func parseCaskManifest(data []byte) (CaskManifest, error) {
+	var info caskArtifacts
+	if err := json.Unmarshal(data, &info); err != nil {
+		return CaskManifest{}, fmt.Errorf("invalid brew info output: %w", err)
+	}
+	if len(info.Casks) == 0 {
+		return CaskManifest{}, fmt.Errorf("brew info returned no cask")
+	}
+
+	cask := info.Casks[0]
+	manifest := CaskManifest{Token: cask.Token, Version: cask.Version, Apps: parseCaskApps(data)}
+	for _, artifact := range cask.Artifacts {
+		for _, stanza := range []string{"uninstall", "zap"} {
+			raw, ok := artifact[stanza]
+			if !ok {
+				continue
+			}
+			var directives []map[string]interface{}
+			if err := json.Unmarshal(raw, &directives); err != nil {
+				continue
+			}
+			for _, directive := range directives {
+				manifest.LaunchAgents = appendUnique(manifest.LaunchAgents, stringValues(directive["launchctl"])...)
+				manifest.PkgReceipts = appendUnique(manifest.PkgReceipts, stringValues(directive["pkgutil"])...)
+				for _, key := range []string{"delete", "trash", "rmdir"} {
+					manifest.Remnants = appendUnique(manifest.Remnants, stringValues(directive[key])...)
+				}
+			}
+		}
+	}
+	return manifest, nil
+}
+
+// stringValues returns a directive value that is either a string or a list of strings
+func stringValues(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var values []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// appendUnique appends the values not already in list
+func appendUnique(list []string, values ...string) []string {
+	for _, value := range values {
+		found := false
+		for _, existing := range list {
+			if existing == value {
+				found = true
+				break
+			}
+		}
+		if !found && value != "" {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+// expandHome replaces a leading ~ with the home directory
+func expandHome(path, homeDir string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		return filepath.Join(homeDir, strings.TrimPrefix(path, "~"))
+	}
+	return path
+}
+
+// exists reports whether a path exists, without following a final symlink
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
@@ -0,0 +1,281 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package brew
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/system"
+)
+
+// metadataCacheFile is the cache file inside MetadataOptions.CacheDir
+const metadataCacheFile = "metadata.json"
+
+// PackageMetadata is descriptive information about a formula or cask
+type PackageMetadata struct {
+	Description string `json:"description,omitempty"`
+	License     string `json:"license,omitempty"`
+	Homepage    string `json:"homepage,omitempty"`
+	Version     string `json:"version,omitempty"`
+	Revision    string `json:"revision"`       // Version plus formula revision, changes whenever the package does
+	Size        int64  `json:"size,omitempty"` // Download size in bytes, 0 when unknown or not requested
+
+	downloadURL string
+}
+
+// MetadataOptions controls how metadata is fetched
+type MetadataOptions struct {
+	CacheDir string // Directory of the metadata cache, empty disables it
+	Workers  int    // Concurrent lookups for names a batch could not answer and for sizes, <= 0 uses sizeLookupWorkers
+	Sizes    bool   // Also request download sizes from download servers
+}
+
+// cachedMetadata is a cache entry, valid while the package revision is unchanged
+type cachedMetadata struct {
+	Metadata    PackageMetadata `json:"metadata"`
+	SizeChecked bool            `json:"size_checked,omitempty"`
+}
+
+// metadataInfo is the subset of 'brew info --json=v2' used for package metadata
+type metadataInfo struct {
+	Formulae []struct {
+		Name     string `json:"name"`
+		FullName string `json:"full_name"`
+		Desc     string `json:"desc"`
+		License  string `json:"license"`
+		Homepage string `json:"homepage"`
+		Revision int    `json:"revision"`
+		Versions struct {
+			Stable string `json:"stable"`
+		} `json:"versions"`
+		Bottle struct {
+			Stable struct {
+				Files map[string]struct {
+					URL string `json:"url"`
+				} `json:"files"`
+			} `json:"stable"`
+		} `json:"bottle"`
+	} `json:"formulae"`
+	Casks []struct {
+		Token     string `json:"token"`
+		FullToken string `json:"full_token"`
+		Desc      string `json:"desc"`
+		Homepage  string `json:"homepage"`
+		Version   string `json:"version"`
+		URL       string `json:"url"`
+	} `json:"casks"`
+}
+
+// FetchMetadata looks up metadata for settings entries, keyed by entry. Names are batched into one
+// 'brew info --json=v2' call per package type, names a failed batch could not answer and download
+// sizes are looked up concurrently, and results are cached by package revision so unchanged
+// packages skip the size requests next time. App Store entries and unknown packages are left out.
+func FetchMetadata(entries []string, options MetadataOptions) map[string]PackageMetadata {
+	results := make(map[string]PackageMetadata)
+	if len(entries) == 0 || !IsBrewInstalled() {
+		return results
+	}
+	if options.Workers <= 0 {
+		options.Workers = sizeLookupWorkers
+	}
+
+	byType := make(map[PackageType][]string)
+	for _, entry := range entries {
+		name, packageType := ParsePackageName(entry)
+		if packageType != PackageTypeAppStore {
+			byType[packageType] = append(byType[packageType], name)
+		}
+	}
+
+	found := make(map[string]PackageMetadata)
+	for packageType, names := range byType {
+		for key, metadata := range lookupMetadata(packageType, names, options.Workers) {
+			found[key] = metadata
+		}
+	}
+
+	cache := loadMetadataCache(options.CacheDir)
+	var pending []string
+	for _, entry := range entries {
+		name, packageType := ParsePackageName(entry)
+		key := string(packageType) + ":" + name
+		metadata, ok := found[key]
+		if !ok {
+			continue
+		}
+		if cached, ok := cache[key]; ok && cached.Metadata.Revision == metadata.Revision && (cached.SizeChecked || !options.Sizes) {
+			metadata.Size = cached.Metadata.Size
+		} else {
+			pending = append(pending, key)
+		}
+		found[key] = metadata
+	}
+
+	if options.Sizes {
+		lookupMetadataSizes(found, pending, options.Workers)
+	}
+	for _, key := range pending {
+		cache[key] = cachedMetadata{Metadata: found[key], SizeChecked: options.Sizes}
+	}
+	for _, entry := range entries {
+		name, packageType := ParsePackageName(entry)
+		if metadata, ok := found[string(packageType)+":"+name]; ok {
+			results[entry] = metadata
+		}
+	}
+	if len(pending) > 0 {
+		saveMetadataCache(options.CacheDir, cache)
+	}
+	return results
+}
+
+// lookupMetadata runs one brew info call for names of a type. When an unknown name fails the
+// batch, the names are looked up one at a time by up to workers concurrent calls.
+func lookupMetadata(packageType PackageType, names []string, workers int) map[string]PackageMetadata {
+	args := []string{constants.BrewInfo, "--json=v2"}
+	if packageType == PackageTypeFormula || packageType == PackageTypeCask {
+		args = append(args, "--"+string(packageType))
+	}
+
+	found := make(map[string]PackageMetadata)
+	result, err := system.RunCommand(constants.BrewCommand, append(args, names...)...)
+	if err == nil && result.Success {
+		addMetadataInfo(found, packageType, []byte(result.Output))
+		return found
+	}
+	if len(names) == 1 {
+		return found
+	}
+
+	var mu sync.Mutex
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			single := lookupMetadata(packageType, []string{name}, 1)
+			mu.Lock()
+			defer mu.Unlock()
+			for key, metadata := range single {
+				found[key] = metadata
+			}
+		}(name)
+	}
+	wg.Wait()
+	return found
+}
+
+// addMetadataInfo parses brew info JSON into metadata keyed by requested type and every name a package answers to
+func addMetadataInfo(found map[string]PackageMetadata, requested PackageType, data []byte) {
+	var info metadataInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return
+	}
+
+	tag := bottleTag()
+	for _, formula := range info.Formulae {
+		metadata := PackageMetadata{
+			Description: formula.Desc,
+			License:     formula.License,
+			Homepage:    formula.Homepage,
+			Version:     formula.Versions.Stable,
+			Revision:    formula.Versions.Stable,
+		}
+		if formula.Revision > 0 {
+			metadata.Revision = fmt.Sprintf("%s_%d", formula.Versions.Stable, formula.Revision)
+		}
+		if file, ok := formula.Bottle.Stable.Files[tag]; ok {
+			metadata.downloadURL = file.URL
+		} else if file, ok := formula.Bottle.Stable.Files["all"]; ok {
+			metadata.downloadURL = file.URL
+		}
+		for _, name := range []string{formula.Name, formula.FullName} {
+			found[string(requested)+":"+name] = metadata
+		}
+	}
+	for _, cask := range info.Casks {
+		metadata := PackageMetadata{
+			Description: cask.Desc,
+			Homepage:    cask.Homepage,
+			Version:     cask.Version,
+			Revision:    cask.Version,
+			downloadURL: cask.URL,
+		}
+		for _, name := range []string{cask.Token, cask.FullToken} {
+			found[string(requested)+":"+name] = metadata
+		}
+	}
+}
+
+// lookupMetadataSizes requests the download sizes of the given packages concurrently
+func lookupMetadataSizes(found map[string]PackageMetadata, keys []string, workers int) {
+	sizes := make([]int64, len(keys))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		url := found[key].downloadURL
+		if url == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, url string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			sizes[i] = downloadSize(url)
+		}(i, url)
+	}
+	wg.Wait()
+
+	for i, key := range keys {
+		metadata := found[key]
+		metadata.Size = sizes[i]
+		found[key] = metadata
+	}
+}
+
+// loadMetadataCache reads the metadata cache, a missing or unreadable cache is empty
+func loadMetadataCache(dir string) map[string]cachedMetadata {
+	cache := make(map[string]cachedMetadata)
+	if dir == "" {
+		return cache
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, metadataCacheFile)); err == nil {
+		_ = json.Unmarshal(data, &cache)
+	}
+	return cache
+}
+
+// saveMetadataCache stores the metadata cache, failures only cost the next lookup its size requests
+func saveMetadataCache(dir string, cache map[string]cachedMetadata) {
+	if dir == "" {
+		return
+	}
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil || os.MkdirAll(dir, 0755) != nil {
+		return
+	}
+	_ = os.WriteFile(filepath.Join(dir, metadataCacheFile), data, 0644)
+}
@@ -0,0 +1,306 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package brew
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/system"
+)
+
+// sizeLookupTimeout bounds each download size request
+const sizeLookupTimeout = 10 * time.Second
+
+// sizeLookupWorkers bounds concurrent download size requests
+const sizeLookupWorkers = 4
+
+// ghcrAnonymousToken is the token Homebrew itself uses to read public bottles from ghcr.io
+const ghcrAnonymousToken = "Bearer QQ=="
+
+// macOSBottleNames maps macOS major versions to the names used in bottle tags
+var macOSBottleNames = map[string]string{
+	"26": "tahoe",
+	"15": "sequoia",
+	"14": "sonoma",
+	"13": "ventura",
+	"12": "monterey",
+	"11": "big_sur",
+}
+
+// PackageDetails describes a settings entry before it is installed, for provisioning plans
+type PackageDetails struct {
+	Entry            string      `json:"entry"`
+	Name             string      `json:"name"`
+	Type             PackageType `json:"type,omitempty"`
+	Tap              string      `json:"tap,omitempty"`               // Set for third-party taps only
+	Version          string      `json:"version,omitempty"`           // Version Homebrew would install
+	InstalledVersion string      `json:"installed_version,omitempty"` // Empty when not installed
+	Dependencies     []string    `json:"dependencies,omitempty"`      // Direct formula dependencies
+	Size             int64       `json:"size,omitempty"`              // Download size in bytes, 0 when unknown
+	Unknown          bool        `json:"unknown,omitempty"`           // Homebrew knows no such formula or cask
+
+	downloadURL string
+}
+
+// packageInfo is the subset of 'brew info --json=v2' used for provisioning plans
+type packageInfo struct {
+	Formulae []struct {
+		Name     string `json:"name"`
+		FullName string `json:"full_name"`
+		Tap      string `json:"tap"`
+		Versions struct {
+			Stable string `json:"stable"`
+		} `json:"versions"`
+		Dependencies []string `json:"dependencies"`
+		Bottle       struct {
+			Stable struct {
+				Files map[string]struct {
+					URL string `json:"url"`
+				} `json:"files"`
+			} `json:"stable"`
+		} `json:"bottle"`
+	} `json:"formulae"`
+	Casks []struct {
+		Token     string `json:"token"`
+		FullToken string `json:"full_token"`
+		Tap       string `json:"tap"`
+		Version   string `json:"version"`
+		URL       string `json:"url"`
+	} `json:"casks"`
+}
+
+// DescribePackages looks up version, tap, dependencies and install state for settings entries
+// with one brew call per package type. With sizes, download sizes of packages that are not
+// installed yet are requested from their download servers.
+func DescribePackages(entries []string, sizes bool) []PackageDetails {
+	details := make([]PackageDetails, len(entries))
+	byType := make(map[PackageType][]string)
+	tapped := GetTappedRepositories()
+	for i, entry := range entries {
+		name, packageType := ParsePackageName(entry)
+		details[i] = PackageDetails{Entry: entry, Name: name, Type: packageType, Tap: TapOf(name)}
+		// brew info adds missing taps, a plan must leave the machine untouched
+		if packageType != PackageTypeAppStore && (details[i].Tap == "" || tapped[details[i].Tap]) {
+			byType[packageType] = append(byType[packageType], name)
+		}
+	}
+
+	found := make(map[string]PackageDetails)
+	brewInstalled := IsBrewInstalled()
+	if brewInstalled {
+		for packageType, names := range byType {
+			for key, detail := range lookupPackages(packageType, names) {
+				found[key] = detail
+			}
+		}
+	}
+
+	installed := GetInstalledVersions()
+	for i := range details {
+		detail := &details[i]
+		if detail.Type == PackageTypeAppStore {
+			continue
+		}
+		info, ok := found[string(detail.Type)+":"+detail.Name]
+		switch {
+		case !ok && (!brewInstalled || detail.Tap != "" && !tapped[detail.Tap]):
+			// Details are only known once Homebrew and the tap are installed
+		case !ok:
+			detail.Unknown = true
+		default:
+			info.Entry, info.Name = detail.Entry, detail.Name
+			*detail = info
+		}
+		detail.InstalledVersion = installed[path.Base(detail.Name)]
+	}
+
+	if sizes {
+		lookupSizes(details)
+	}
+	return details
+}
+
+// lookupPackages runs brew info for names of one type, falling back to one call per name when
+// a single unknown name fails the batch. Results are keyed by requested type and name.
+func lookupPackages(packageType PackageType, names []string) map[string]PackageDetails {
+	args := []string{constants.BrewInfo, "--json=v2"}
+	if packageType == PackageTypeFormula || packageType == PackageTypeCask {
+		args = append(args, "--"+string(packageType))
+	}
+
+	found := make(map[string]PackageDetails)
+	result, err := system.RunCommand(constants.BrewCommand, append(args, names...)...)
+	if err == nil && result.Success {
+		addPackageInfo(found, packageType, []byte(result.Output))
+		return found
+	}
+	if len(names) == 1 {
+		return found
+	}
+	for _, name := range names {
+		for key, detail := range lookupPackages(packageType, []string{name}) {
+			found[key] = detail
+		}
+	}
+	return found
+}
+
+// addPackageInfo parses brew info JSON into details keyed by requested type and every name a package answers to
+func addPackageInfo(found map[string]PackageDetails, requested PackageType, data []byte) {
+	var info packageInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return
+	}
+
+	tag := bottleTag()
+	for _, formula := range info.Formulae {
+		detail := PackageDetails{
+			Type:         PackageTypeFormula,
+			Tap:          thirdPartyTap(formula.Tap),
+			Version:      formula.Versions.Stable,
+			Dependencies: formula.Dependencies,
+		}
+		if file, ok := formula.Bottle.Stable.Files[tag]; ok {
+			detail.downloadURL = file.URL
+		} else if file, ok := formula.Bottle.Stable.Files["all"]; ok {
+			detail.downloadURL = file.URL
+		}
+		for _, name := range []string{formula.Name, formula.FullName} {
+			found[string(requested)+":"+name] = detail
+		}
+	}
+	for _, cask := range info.Casks {
+		detail := PackageDetails{
+			Type:        PackageTypeCask,
+			Tap:         thirdPartyTap(cask.Tap),
+			Version:     cask.Version,
+			downloadURL: cask.URL,
+		}
+		for _, name := range []string{cask.Token, cask.FullToken} {
+			found[string(requested)+":"+name] = detail
+		}
+	}
+}
+
+// thirdPartyTap returns the tap unless it is one of Homebrew's own
+func thirdPartyTap(tap string) string {
+	if tap == "" || tap == "homebrew/core" || tap == "homebrew/cask" {
+		return ""
+	}
+	return tap
+}
+
+// TapOf returns the tap of a tap-qualified name such as "nikitabobko/tap/aerospace"
+func TapOf(name string) string {
+	parts := strings.Split(name, "/")
+	if len(parts) != 3 {
+		return ""
+	}
+	return parts[0] + "/" + parts[1]
+}
+
+// GetTappedRepositories returns the taps already added, keyed by name
+func GetTappedRepositories() map[string]bool {
+	taps := make(map[string]bool)
+	if !IsBrewInstalled() {
+		return taps
+	}
+	result, err := system.RunCommand(constants.BrewCommand, "tap")
+	if err != nil || !result.Success {
+		return taps
+	}
+	for _, line := range strings.Split(result.Output, "\n") {
+		if tap := strings.TrimSpace(line); tap != "" {
+			taps[tap] = true
+		}
+	}
+	return taps
+}
+
+// bottleTag returns the bottle tag Homebrew picks on this machine, such as "arm64_sonoma"
+func bottleTag() string {
+	if runtime.GOOS == "linux" {
+		if runtime.GOARCH == "arm64" {
+			return "arm64_linux"
+		}
+		return "x86_64_linux"
+	}
+
+	result, err := system.RunCommand("sw_vers", "-productVersion")
+	if err != nil || !result.Success {
+		return ""
+	}
+	major, _, _ := strings.Cut(strings.TrimSpace(result.Output), ".")
+	name, ok := macOSBottleNames[major]
+	if !ok {
+		return ""
+	}
+	if runtime.GOARCH == "arm64" {
+		return "arm64_" + name
+	}
+	return name
+}
+
+// lookupSizes fills in download sizes of packages that are not installed yet
+func lookupSizes(details []PackageDetails) {
+	sem := make(chan struct{}, sizeLookupWorkers)
+	var wg sync.WaitGroup
+	for i := range details {
+		if details[i].InstalledVersion != "" || details[i].downloadURL == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(detail *PackageDetails) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			detail.Size = downloadSize(detail.downloadURL)
+		}(&details[i])
+	}
+	wg.Wait()
+}
+
+// downloadSize asks a download server for the size of a file without downloading it, 0 when unknown
+func downloadSize(url string) int64 {
+	ctx, cancel := context.WithTimeout(context.Background(), sizeLookupTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return 0
+	}
+	if strings.HasPrefix(url, "https://ghcr.io/") {
+		req.Header.Set("Authorization", ghcrAnonymousToken)
+	}
+	resp, err := system.HTTPClient().Do(req)
+	if err != nil {
+		return 0
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.ContentLength < 0 {
+		return 0
+	}
+	return resp.ContentLength
+}
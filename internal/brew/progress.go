@@ -0,0 +1,68 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package brew
+
+import (
+	"path"
+	"strings"
+)
+
+// installProgress follows 'brew install' output to describe the step in progress.
+// Dependencies are counted as sub-steps once brew announces them.
+type installProgress struct {
+	current int
+	total   int
+}
+
+// update reads one line of brew output and returns the detail to show for it,
+// ok is false for lines that are not brew step headers
+func (p *installProgress) update(line string) (detail string, ok bool) {
+	step, found := strings.CutPrefix(line, "==> ")
+	if !found {
+		return "", false
+	}
+	step = strings.TrimSpace(step)
+
+	switch {
+	case strings.HasPrefix(step, "Installing dependencies for "):
+		if _, deps, found := strings.Cut(step, ": "); found {
+			// The package itself is the last step after its dependencies
+			p.total = len(splitBrewList(deps)) + 1
+		}
+	case strings.HasPrefix(step, "Installing ") && p.total > 0:
+		if p.current < p.total {
+			p.current++
+		}
+		if _, dep, found := strings.Cut(step, " dependency: "); found {
+			return "Installing " + dep, true
+		}
+	case strings.HasPrefix(step, "Downloading "):
+		return "Downloading " + path.Base(strings.TrimPrefix(step, "Downloading ")), true
+	}
+	return step, true
+}
+
+// splitBrewList splits a brew list such as "a, b and c" into its names
+func splitBrewList(list string) []string {
+	var names []string
+	for _, part := range strings.Split(strings.ReplaceAll(list, " and ", ", "), ",") {
+		if name := strings.TrimSpace(part); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
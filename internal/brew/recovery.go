@@ -0,0 +1,229 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package brew
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/system"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+	"github.com/0xjuanma/palantir"
+)
+
+// FailureKind identifies a known brew install failure signature
+type FailureKind string
+
+const (
+	FailureUnknown       FailureKind = "unknown"
+	FailureNotFound      FailureKind = "not-found"
+	FailureChecksum      FailureKind = "checksum-mismatch"
+	FailureRosetta       FailureKind = "needs-rosetta"
+	FailureMacOSVersion  FailureKind = "requires-newer-macos"
+	FailureAppConflict   FailureKind = "app-conflict"
+	FailureWrongCaskType FailureKind = "wrong-package-type"
+	FailureNetwork       FailureKind = "network"
+	FailureBrewLocked    FailureKind = "brew-locked"
+)
+
+// InstallDiagnosis describes a failed install and how to recover from it
+type InstallDiagnosis struct {
+	Kind        FailureKind
+	Summary     string
+	Suggestions []string
+	Candidates  []string // Similarly named packages from brew search
+}
+
+// failureSignatures maps brew output fragments to failure kinds, checked in order
+var failureSignatures = []struct {
+	kind      FailureKind
+	fragments []string
+}{
+	{FailureChecksum, []string{"SHA256 mismatch", "Checksum mismatch", "sha256 mismatch"}},
+	{FailureRosetta, []string{"Rosetta", "Bad CPU type in executable"}},
+	{FailureMacOSVersion, []string{"requires macOS", "is not supported on this macOS", "does not run on macOS versions older"}},
+	{FailureAppConflict, []string{"already an App at", "already a Binary at", "It seems there is already"}},
+	{FailureBrewLocked, []string{"has already locked", "Another active Homebrew"}},
+	{FailureNetwork, []string{"Could not resolve host", "Failed to connect", "Connection timed out", "curl: (6)", "curl: (7)"}},
+	{FailureNotFound, []string{"No available formula", "No available cask", "No formulae or casks found", "No cask with this name"}},
+}
+
+// DiagnoseInstallFailure matches brew output against known failure signatures
+func DiagnoseInstallFailure(packageName, output string) *InstallDiagnosis {
+	for _, signature := range failureSignatures {
+		for _, fragment := range signature.fragments {
+			if strings.Contains(output, fragment) {
+				return buildDiagnosis(signature.kind, packageName)
+			}
+		}
+	}
+	return buildDiagnosis(FailureUnknown, packageName)
+}
+
+// buildDiagnosis fills in the summary and next steps for a failure kind
+func buildDiagnosis(kind FailureKind, packageName string) *InstallDiagnosis {
+	diagnosis := &InstallDiagnosis{Kind: kind}
+
+	switch kind {
+	case FailureNotFound:
+		diagnosis.Summary = fmt.Sprintf("Homebrew has no package named '%s'", packageName)
+		diagnosis.Candidates = findCandidates(packageName)
+		for _, candidate := range diagnosis.Candidates {
+			if candidate == packageName {
+				// Exact match exists under the other type, e.g. a cask installed as a formula
+				diagnosis.Kind = FailureWrongCaskType
+				diagnosis.Summary = fmt.Sprintf("'%s' exists but with a different package type", packageName)
+			}
+		}
+		diagnosis.Suggestions = []string{
+			"Run 'brew update' to refresh package definitions",
+			fmt.Sprintf("Search for the correct name with 'brew search %s'", packageName),
+			"Force the package type with a cask: or formula: prefix in settings.yaml",
+		}
+	case FailureChecksum:
+		diagnosis.Summary = "Downloaded file does not match the expected checksum"
+		diagnosis.Suggestions = []string{
+			fmt.Sprintf("Clear the cached download with 'brew cleanup %s'", packageName),
+			"Run 'brew update' and retry, the upstream release may have changed",
+		}
+	case FailureRosetta:
+		diagnosis.Summary = "Package requires Rosetta 2 on Apple Silicon"
+		diagnosis.Suggestions = []string{"Install Rosetta with 'softwareupdate --install-rosetta --agree-to-license'"}
+	case FailureMacOSVersion:
+		diagnosis.Summary = "Package requires a newer macOS version"
+		diagnosis.Suggestions = []string{
+			"Update macOS from System Settings → General → Software Update",
+			fmt.Sprintf("Look for an older version with 'brew search %s@'", packageName),
+		}
+	case FailureAppConflict:
+		diagnosis.Summary = "An app with the same name already exists outside of Homebrew"
+		diagnosis.Suggestions = []string{
+			fmt.Sprintf("Let Homebrew take over the existing app with 'brew install --cask --adopt %s'", packageName),
+			"Or remove the existing app and retry",
+		}
+	case FailureBrewLocked:
+		diagnosis.Summary = "Another Homebrew process is running"
+		diagnosis.Suggestions = []string{"Wait for the other brew process to finish and retry"}
+	case FailureNetwork:
+		diagnosis.Summary = "Network error while downloading"
+		diagnosis.Suggestions = []string{"Check your internet connection or proxy settings and retry"}
+	default:
+		diagnosis.Summary = "Unrecognized brew failure"
+		diagnosis.Suggestions = []string{
+			"Run 'brew doctor' to check your Homebrew installation",
+			fmt.Sprintf("Retry manually with 'brew install %s' for the full output", packageName),
+		}
+	}
+
+	return diagnosis
+}
+
+// Replaced in tests, recovery searches, prompts and reinstalls through these
+var (
+	findCandidates  = searchCandidates
+	confirm         = charm.Confirm
+	installDirectly = InstallPackageDirectly
+)
+
+// SubstitutedError reports that a similarly named package was installed in place of an entry
+// Homebrew doesn't know. The entry itself is still not installed, so it must not be tracked.
+type SubstitutedError struct {
+	Entry      string // The entry that could not be installed
+	Substitute string // The package installed instead
+}
+
+func (e *SubstitutedError) Error() string {
+	return fmt.Sprintf("'%s' was installed instead of '%s'", e.Substitute, e.Entry)
+}
+
+// searchCandidates returns similarly named formulae and casks from brew search
+func searchCandidates(packageName string) []string {
+	result, err := system.RunCommand(constants.BrewCommand, constants.BrewSearch, packageName)
+	if err != nil || !result.Success {
+		return nil
+	}
+
+	var candidates []string
+	for _, line := range strings.Split(result.Output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "==>") || strings.Contains(line, "Error:") || strings.Contains(line, "Warning:") {
+			continue
+		}
+		candidates = append(candidates, strings.Fields(line)...)
+		if len(candidates) >= 5 {
+			return candidates[:5]
+		}
+	}
+	return candidates
+}
+
+// RecoverFromInstallFailure explains a failed install and offers automatic remediation when one is known.
+// It returns nil only when a remediation was accepted and the retry installed the entry, and a
+// *SubstitutedError when a similarly named package was installed instead.
+func RecoverFromInstallFailure(entry string, installErr error) error {
+	o := palantir.GetGlobalOutputHandler()
+	packageName, packageType := ParsePackageName(entry)
+	diagnosis := DiagnoseInstallFailure(packageName, installErr.Error())
+
+	o.PrintWarning("Install failed: %s", diagnosis.Summary)
+	for _, suggestion := range diagnosis.Suggestions {
+		o.PrintInfo("  • %s", suggestion)
+	}
+
+	switch diagnosis.Kind {
+	case FailureWrongCaskType:
+		retryEntry := string(PackageTypeCask) + ":" + packageName
+		if packageType == PackageTypeCask || isCaskPackage(packageName) {
+			retryEntry = string(PackageTypeFormula) + ":" + packageName
+		}
+		if confirm(charm.ConfirmInstall, fmt.Sprintf("Retry as '%s'?", retryEntry)) {
+			return retryInstall(retryEntry, installErr)
+		}
+	case FailureNotFound:
+		if len(diagnosis.Candidates) > 0 {
+			o.PrintInfo("Similar packages: %s", strings.Join(diagnosis.Candidates, ", "))
+			if confirm(charm.ConfirmInstall, fmt.Sprintf("Install '%s' instead?", diagnosis.Candidates[0])) {
+				if err := retryInstall(diagnosis.Candidates[0], installErr); err != nil {
+					return err
+				}
+				o.PrintInfo("Update settings.yaml to use '%s' instead of '%s'", diagnosis.Candidates[0], entry)
+				return &SubstitutedError{Entry: entry, Substitute: diagnosis.Candidates[0]}
+			}
+		} else if confirm(charm.ConfirmInstall, "Run 'brew update' and retry?") {
+			if err := UpdateOnce(); err != nil {
+				return installErr
+			}
+			return retryInstall(entry, installErr)
+		}
+	case FailureChecksum:
+		if confirm(charm.ConfirmInstall, fmt.Sprintf("Clear cached downloads for %s and retry?", packageName)) {
+			system.RunCommand(constants.BrewCommand, "cleanup", "--prune=all", packageName)
+			return retryInstall(entry, installErr)
+		}
+	}
+
+	return installErr
+}
+
+// retryInstall reinstalls an entry, keeping the original error when the retry also fails
+func retryInstall(entry string, originalErr error) error {
+	if err := installDirectly(entry); err != nil {
+		return fmt.Errorf("%w (retry failed: %v)", originalErr, err)
+	}
+	return nil
+}
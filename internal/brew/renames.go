@@ -0,0 +1,210 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package brew
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/system"
+)
+
+// PackageNotice reports that Homebrew renamed, deprecated or disabled a package
+type PackageNotice struct {
+	Name       string // Name as requested or written in settings
+	RenamedTo  string // New name when the package was renamed
+	Deprecated bool
+	Disabled   bool
+	Reason     string // Deprecation or disable reason given by Homebrew
+}
+
+// Describe returns a one-line description of the notice
+func (n PackageNotice) Describe() string {
+	var parts []string
+	if n.RenamedTo != "" {
+		parts = append(parts, fmt.Sprintf("renamed to %s", n.RenamedTo))
+	}
+	switch {
+	case n.Disabled:
+		parts = append(parts, "disabled")
+	case n.Deprecated:
+		parts = append(parts, "deprecated")
+	}
+	description := fmt.Sprintf("%s: %s", n.Name, strings.Join(parts, ", "))
+	if n.Reason != "" {
+		description += fmt.Sprintf(" (%s)", n.Reason)
+	}
+	return description
+}
+
+var (
+	// renameWarningPattern matches e.g. "Warning: Formula docker was renamed to docker-cli."
+	renameWarningPattern = regexp.MustCompile(`(?m)(?:Formula|Cask) '?([\w@.+/-]+?)'? (?:was|has been) renamed to '?([\w@.+/-]+?)'?\.?\s*$`)
+	// deprecationWarningPattern matches e.g. "Warning: python@3.8 has been deprecated because it is not supported upstream!"
+	deprecationWarningPattern = regexp.MustCompile(`(?m)(?:Warning|Error): ([\w@.+/-]+) has been (deprecated|disabled) because (?:it )?([^!\n]+)`)
+
+	installNotices      []PackageNotice
+	installNoticesMutex sync.Mutex
+)
+
+// ParseInstallWarnings extracts rename and deprecation warnings from brew install output
+func ParseInstallWarnings(requested, output string) []PackageNotice {
+	notices := make(map[string]*PackageNotice)
+	notice := func(name string) *PackageNotice {
+		if notices[name] == nil {
+			notices[name] = &PackageNotice{Name: name}
+		}
+		return notices[name]
+	}
+
+	for _, match := range renameWarningPattern.FindAllStringSubmatch(output, -1) {
+		notice(match[1]).RenamedTo = match[2]
+	}
+	for _, match := range deprecationWarningPattern.FindAllStringSubmatch(output, -1) {
+		name := match[1]
+		// Warnings name the package after the rename, report them against the requested name
+		if n, ok := notices[requested]; ok && n.RenamedTo == name {
+			name = requested
+		}
+		n := notice(name)
+		n.Deprecated = match[2] == "deprecated"
+		n.Disabled = match[2] == "disabled"
+		n.Reason = strings.TrimSpace(match[3])
+	}
+
+	result := make([]PackageNotice, 0, len(notices))
+	for _, n := range notices {
+		result = append(result, *n)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// recordInstallWarnings keeps notices found in install output until TakeInstallNotices is called
+func recordInstallWarnings(requested, output string) {
+	notices := ParseInstallWarnings(requested, output)
+	if len(notices) == 0 {
+		return
+	}
+	installNoticesMutex.Lock()
+	defer installNoticesMutex.Unlock()
+	installNotices = append(installNotices, notices...)
+}
+
+// TakeInstallNotices returns the rename and deprecation notices seen by installs so far and clears them
+func TakeInstallNotices() []PackageNotice {
+	installNoticesMutex.Lock()
+	defer installNoticesMutex.Unlock()
+	notices := installNotices
+	installNotices = nil
+	return notices
+}
+
+// installedInfo is the subset of 'brew info --json=v2' used to find renames and deprecations
+type installedInfo struct {
+	Formulae []struct {
+		Name              string   `json:"name"`
+		OldNames          []string `json:"oldnames"`
+		OldName           string   `json:"oldname"`
+		Deprecated        bool     `json:"deprecated"`
+		DeprecationReason string   `json:"deprecation_reason"`
+		Disabled          bool     `json:"disabled"`
+		DisableReason     string   `json:"disable_reason"`
+	} `json:"formulae"`
+	Casks []struct {
+		Token             string   `json:"token"`
+		OldTokens         []string `json:"old_tokens"`
+		Deprecated        bool     `json:"deprecated"`
+		DeprecationReason string   `json:"deprecation_reason"`
+		Disabled          bool     `json:"disabled"`
+		DisableReason     string   `json:"disable_reason"`
+	} `json:"casks"`
+}
+
+// FindPackageNotices checks settings entries against installed packages in a single brew call,
+// reporting entries that still use an old name and entries that are deprecated or disabled
+func FindPackageNotices(entries []string) ([]PackageNotice, error) {
+	result, _ := system.RunCommand(constants.BrewCommand, constants.BrewInfo, "--json=v2", "--installed")
+	if !result.Success {
+		return nil, fmt.Errorf("brew info failed: %s", strings.TrimSpace(result.Output))
+	}
+	return findPackageNotices(entries, []byte(result.Output))
+}
+
+// findPackageNotices matches entries against 'brew info --json=v2' output
+func findPackageNotices(entries []string, data []byte) ([]PackageNotice, error) {
+	var info installedInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse brew info: %w", err)
+	}
+
+	renames := make(map[string]string)
+	status := make(map[string]PackageNotice)
+	addStatus := func(name string, deprecated, disabled bool, deprecationReason, disableReason string) {
+		if !deprecated && !disabled {
+			return
+		}
+		reason := deprecationReason
+		if disabled && disableReason != "" {
+			reason = disableReason
+		}
+		status[name] = PackageNotice{Deprecated: deprecated && !disabled, Disabled: disabled, Reason: strings.ReplaceAll(reason, "_", " ")}
+	}
+	for _, f := range info.Formulae {
+		for _, old := range append(f.OldNames, f.OldName) {
+			if old != "" {
+				renames[old] = f.Name
+			}
+		}
+		addStatus(f.Name, f.Deprecated, f.Disabled, f.DeprecationReason, f.DisableReason)
+	}
+	for _, c := range info.Casks {
+		for _, old := range c.OldTokens {
+			renames[old] = c.Token
+		}
+		addStatus(c.Token, c.Deprecated, c.Disabled, c.DeprecationReason, c.DisableReason)
+	}
+
+	var notices []PackageNotice
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		name, packageType := ParsePackageName(entry)
+		if packageType == PackageTypeAppStore || seen[entry] {
+			continue
+		}
+		seen[entry] = true
+
+		current := name
+		notice := PackageNotice{Name: entry}
+		if renamed, ok := renames[name]; ok && renamed != name {
+			notice.RenamedTo = renamed
+			current = renamed
+		}
+		if s, ok := status[current]; ok {
+			notice.Deprecated, notice.Disabled, notice.Reason = s.Deprecated, s.Disabled, s.Reason
+		}
+		if notice.RenamedTo != "" || notice.Deprecated || notice.Disabled {
+			notices = append(notices, notice)
+		}
+	}
+	return notices, nil
+}
@@ -0,0 +1,246 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package brew
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/system"
+)
+
+// searchCacheTTL is how long search results are reused before brew is asked again
+const searchCacheTTL = 6 * time.Hour
+
+// SearchOptions controls a package search
+type SearchOptions struct {
+	Limit    int    // Results kept and described, 0 keeps all
+	CacheDir string // Directory caching results, empty disables the cache
+	Refresh  bool   // Ignore cached results
+}
+
+// SearchResult is a formula or cask matching a search
+type SearchResult struct {
+	Name        string      `json:"name"`
+	Type        PackageType `json:"type"`
+	Description string      `json:"description,omitempty"`
+	Ambiguous   bool        `json:"ambiguous,omitempty"` // A formula and a cask share the name
+	Installed   bool        `json:"-"`                   // Checked on every search, never cached
+}
+
+// Entry returns the name to install or add to a group, annotated with its type only
+// when a formula and a cask share the name
+func (r SearchResult) Entry() string {
+	if r.Ambiguous {
+		return fmt.Sprintf("%s:%s", r.Type, r.Name)
+	}
+	return r.Name
+}
+
+// searchCache is the on-disk form of cached results
+type searchCache struct {
+	Term    string         `json:"term"`
+	Limit   int            `json:"limit"`
+	Time    time.Time      `json:"time"`
+	Results []SearchResult `json:"results"`
+}
+
+// Search finds formulae and casks matching term, best matches first, with descriptions
+// and install state. Results are cached in options.CacheDir for repeated queries.
+func Search(term string, options SearchOptions) ([]SearchResult, error) {
+	if !IsBrewInstalled() {
+		return nil, fmt.Errorf("Homebrew is not installed")
+	}
+
+	results, cached := loadSearchCache(options, term)
+	if !cached {
+		results = searchPackages(term)
+		rankResults(term, results)
+		if options.Limit > 0 && len(results) > options.Limit {
+			results = results[:options.Limit]
+		}
+		describeResults(results)
+		saveSearchCache(options, term, results)
+	}
+
+	installed := GetInstalledVersions()
+	for i := range results {
+		_, results[i].Installed = installed[path.Base(results[i].Name)]
+	}
+	return results, nil
+}
+
+// searchPackages runs brew search for formulae and casks, marking names found as both
+func searchPackages(term string) []SearchResult {
+	var results []SearchResult
+	seen := make(map[string]int)
+	for _, packageType := range []PackageType{PackageTypeFormula, PackageTypeCask} {
+		result, err := system.RunCommand(constants.BrewCommand, constants.BrewSearch, "--"+string(packageType), term)
+		// brew search exits non-zero when nothing matches
+		if err != nil || !result.Success {
+			continue
+		}
+		for _, name := range parseSearchOutput(result.Output) {
+			if i, exists := seen[name]; exists {
+				results[i].Ambiguous = true
+				results = append(results, SearchResult{Name: name, Type: packageType, Ambiguous: true})
+				continue
+			}
+			seen[name] = len(results)
+			results = append(results, SearchResult{Name: name, Type: packageType})
+		}
+	}
+	return results
+}
+
+// parseSearchOutput returns the package names listed by brew search, skipping headers and notes
+func parseSearchOutput(output string) []string {
+	var names []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "==>") || strings.ContainsAny(line, " :") {
+			continue
+		}
+		names = append(names, line)
+	}
+	return names
+}
+
+// rankResults orders results by exact match, then prefix match, then name, formulae before casks
+func rankResults(term string, results []SearchResult) {
+	term = strings.ToLower(term)
+	rank := func(result SearchResult) int {
+		name := strings.ToLower(path.Base(result.Name))
+		switch {
+		case name == term:
+			return 0
+		case strings.HasPrefix(name, term):
+			return 1
+		default:
+			return 2
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if ri, rj := rank(results[i]), rank(results[j]); ri != rj {
+			return ri < rj
+		}
+		if results[i].Name != results[j].Name {
+			return results[i].Name < results[j].Name
+		}
+		return results[i].Type == PackageTypeFormula && results[j].Type == PackageTypeCask
+	})
+}
+
+// searchInfo is the subset of 'brew info --json=v2' used for descriptions
+type searchInfo struct {
+	Formulae []struct {
+		Name     string `json:"name"`
+		FullName string `json:"full_name"`
+		Desc     string `json:"desc"`
+	} `json:"formulae"`
+	Casks []struct {
+		Token     string `json:"token"`
+		FullToken string `json:"full_token"`
+		Desc      string `json:"desc"`
+	} `json:"casks"`
+}
+
+// describeResults fills in descriptions with one brew info call per package type.
+// Descriptions are best effort, a failed lookup leaves them empty.
+func describeResults(results []SearchResult) {
+	for _, packageType := range []PackageType{PackageTypeFormula, PackageTypeCask} {
+		var names []string
+		for _, result := range results {
+			if result.Type == packageType {
+				names = append(names, result.Name)
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+
+		args := append([]string{constants.BrewInfo, "--json=v2", "--" + string(packageType)}, names...)
+		result, err := system.RunCommand(constants.BrewCommand, args...)
+		if err != nil || !result.Success {
+			continue
+		}
+		applyDescriptions(results, packageType, []byte(result.Output))
+	}
+}
+
+// applyDescriptions copies descriptions from brew info JSON onto results of packageType
+func applyDescriptions(results []SearchResult, packageType PackageType, data []byte) {
+	var info searchInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return
+	}
+
+	descriptions := make(map[string]string)
+	for _, formula := range info.Formulae {
+		descriptions[formula.Name], descriptions[formula.FullName] = formula.Desc, formula.Desc
+	}
+	for _, cask := range info.Casks {
+		descriptions[cask.Token], descriptions[cask.FullToken] = cask.Desc, cask.Desc
+	}
+	for i := range results {
+		if results[i].Type == packageType {
+			results[i].Description = descriptions[results[i].Name]
+		}
+	}
+}
+
+// searchCachePath returns the cache file for a term and limit
+func searchCachePath(options SearchOptions, term string) string {
+	key := fmt.Sprintf("%s\x00%d", strings.ToLower(term), options.Limit)
+	return filepath.Join(options.CacheDir, fmt.Sprintf("%x.json", sha256.Sum256([]byte(key))))
+}
+
+// loadSearchCache returns cached results that are still fresh
+func loadSearchCache(options SearchOptions, term string) ([]SearchResult, bool) {
+	if options.CacheDir == "" || options.Refresh {
+		return nil, false
+	}
+	data, err := os.ReadFile(searchCachePath(options, term))
+	if err != nil {
+		return nil, false
+	}
+	var cache searchCache
+	if err := json.Unmarshal(data, &cache); err != nil || time.Since(cache.Time) > searchCacheTTL {
+		return nil, false
+	}
+	return cache.Results, true
+}
+
+// saveSearchCache stores results, failures only cost the next search a brew call
+func saveSearchCache(options SearchOptions, term string, results []SearchResult) {
+	if options.CacheDir == "" {
+		return
+	}
+	data, err := json.MarshalIndent(searchCache{Term: term, Limit: options.Limit, Time: time.Now(), Results: results}, "", "  ")
+	if err != nil || os.MkdirAll(options.CacheDir, 0755) != nil {
+		return
+	}
+	_ = os.WriteFile(searchCachePath(options, term), data, 0644)
+}
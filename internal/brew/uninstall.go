@@ -0,0 +1,77 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package brew
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/system"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+)
+
+// InstalledPackageType reports whether Homebrew has the package installed as a cask or a formula
+func InstalledPackageType(name string) (PackageType, bool) {
+	for _, packageType := range []PackageType{PackageTypeCask, PackageTypeFormula} {
+		result, err := system.RunCommand(constants.BrewCommand, constants.BrewList, "--"+string(packageType), name)
+		if err == nil && result.Success {
+			return packageType, true
+		}
+	}
+	return PackageTypeAuto, false
+}
+
+// UninstallPackage removes a formula or cask with 'brew uninstall'. A cask: or formula:
+// annotation picks the type, otherwise it is taken from what Homebrew has installed.
+// Cask manifests are kept so 'anvil clean remnants' can find what the cask left behind.
+func UninstallPackage(entry string) error {
+	name, packageType := ParsePackageName(entry)
+	if packageType == PackageTypeAppStore {
+		return fmt.Errorf("App Store app %s can't be removed with Homebrew, delete it from /Applications", name)
+	}
+	if !IsBrewInstalled() {
+		return fmt.Errorf("Homebrew is not installed")
+	}
+
+	if packageType == PackageTypeAuto {
+		installed, ok := InstalledPackageType(name)
+		if !ok {
+			return fmt.Errorf("%s is not installed with Homebrew", name)
+		}
+		packageType = installed
+	}
+
+	spinner := charm.NewDotsSpinner(fmt.Sprintf("Uninstalling %s", name))
+	spinner.Start()
+
+	result, err := system.RunCommand(constants.BrewCommand, constants.BrewUninstall, "--"+string(packageType), name)
+	if err != nil {
+		spinner.Error(fmt.Sprintf("Failed to uninstall %s", name))
+		return fmt.Errorf("failed to run brew uninstall: %w", err)
+	}
+	if !result.Success {
+		spinner.Error(fmt.Sprintf("Failed to uninstall %s", name))
+		if output := strings.TrimSpace(result.Output); output != "" {
+			return fmt.Errorf("brew: %s", output)
+		}
+		return fmt.Errorf("uninstall failed: %s", result.Error)
+	}
+
+	spinner.Success(fmt.Sprintf("%s uninstalled", name))
+	return nil
+}
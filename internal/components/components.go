@@ -0,0 +1,119 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package components tracks the plugins and extensions an app config depends on. Some
+// apps keep part of their setup as a list of installable components rather than files,
+// so copying the config is not enough to reproduce it on another machine.
+package components
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Manifest is a list of components kept inside an app's config directory
+type Manifest interface {
+	// Name identifies the manifest kind, e.g. "obsidian-plugins"
+	Name() string
+	// Detect reports whether the manifest applies to the app's config
+	Detect(appName, configPath string) bool
+	// Wanted returns the components listed by the manifest
+	Wanted(configPath string) ([]string, error)
+	// Installed returns the components present on this machine
+	Installed(configPath string) ([]string, error)
+	// Install installs a single component
+	Install(configPath, id string) error
+}
+
+// Capturer is implemented by manifests that are written from the machine's state before a push
+type Capturer interface {
+	Capture(configPath string) error
+}
+
+// manifests lists every supported manifest kind
+var manifests = []Manifest{
+	obsidianPlugins{},
+	editorExtensions{cli: "code", apps: []string{"vscode", "code", "visual-studio-code"}},
+	editorExtensions{cli: "cursor", apps: []string{"cursor"}},
+}
+
+// Status is the result of verifying one manifest against the machine
+type Status struct {
+	Manifest Manifest
+	Wanted   []string
+	Missing  []string
+}
+
+// For returns the manifests that apply to an app config
+func For(appName, configPath string) []Manifest {
+	info, err := os.Stat(configPath)
+	if err != nil || !info.IsDir() {
+		return nil
+	}
+	var found []Manifest
+	for _, m := range manifests {
+		if m.Detect(appName, configPath) {
+			found = append(found, m)
+		}
+	}
+	return found
+}
+
+// Verify compares each applicable manifest with the components installed on this machine
+func Verify(appName, configPath string) ([]Status, error) {
+	var statuses []Status
+	for _, m := range For(appName, configPath) {
+		wanted, err := m.Wanted(configPath)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", m.Name(), err)
+		}
+		installed, err := m.Installed(configPath)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", m.Name(), err)
+		}
+		statuses = append(statuses, Status{Manifest: m, Wanted: wanted, Missing: missing(wanted, installed)})
+	}
+	return statuses, nil
+}
+
+// Capture refreshes the manifests that are generated from the machine's state
+func Capture(appName, configPath string) error {
+	for _, m := range For(appName, configPath) {
+		if c, ok := m.(Capturer); ok {
+			if err := c.Capture(configPath); err != nil {
+				return fmt.Errorf("%s: %w", m.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// missing returns the wanted ids that are not installed, sorted
+func missing(wanted, installed []string) []string {
+	have := make(map[string]bool, len(installed))
+	for _, id := range installed {
+		have[id] = true
+	}
+	var out []string
+	for _, id := range wanted {
+		if !have[id] {
+			out = append(out, id)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
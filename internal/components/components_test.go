@@ -0,0 +1,113 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func writeVault(t *testing.T, plugins string, installed ...string) string {
+	t.Helper()
+	vault := t.TempDir()
+	dir := filepath.Join(vault, obsidianDir)
+	os.MkdirAll(filepath.Join(dir, obsidianPluginsDir), 0755)
+	os.WriteFile(filepath.Join(dir, obsidianPluginsList), []byte(plugins), 0644)
+	for _, id := range installed {
+		os.MkdirAll(filepath.Join(dir, obsidianPluginsDir, id), 0755)
+		os.WriteFile(filepath.Join(dir, obsidianPluginsDir, id, obsidianPluginManifest), []byte("{}"), 0644)
+	}
+	return vault
+}
+
+func TestVerifyObsidianPlugins(t *testing.T) {
+	vault := writeVault(t, `["dataview", "calendar", "templater-obsidian"]`, "dataview")
+	// A plugin directory without a manifest is a broken install
+	os.MkdirAll(filepath.Join(vault, obsidianDir, obsidianPluginsDir, "calendar"), 0755)
+
+	for _, path := range []string{vault, filepath.Join(vault, obsidianDir)} {
+		statuses, err := Verify("obsidian", path)
+		if err != nil {
+			t.Fatalf("Verify(%s) failed: %v", path, err)
+		}
+		if len(statuses) != 1 || statuses[0].Manifest.Name() != "obsidian-plugins" {
+			t.Fatalf("expected one obsidian manifest for %s, got %+v", path, statuses)
+		}
+		if want := []string{"calendar", "templater-obsidian"}; !slices.Equal(statuses[0].Missing, want) {
+			t.Errorf("Missing = %v, want %v", statuses[0].Missing, want)
+		}
+	}
+
+	if statuses, _ := Verify("obsidian", t.TempDir()); len(statuses) != 0 {
+		t.Errorf("expected no manifests without community-plugins.json, got %d", len(statuses))
+	}
+}
+
+func TestInstallObsidianPlugin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index":
+			w.Write([]byte(`[{"id": "dataview", "repo": "blacksmithgu/obsidian-dataview"}]`))
+		case "/blacksmithgu/obsidian-dataview/main.js", "/blacksmithgu/obsidian-dataview/manifest.json":
+			w.Write([]byte("content"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	origIndex, origRelease := obsidianPluginIndexURL, obsidianReleaseURL
+	obsidianPluginIndexURL, obsidianReleaseURL = server.URL+"/index", server.URL+"/%s/%s"
+	obsidianIndexOnce, obsidianIndex, obsidianIndexErr = sync.Once{}, nil, nil
+	defer func() {
+		obsidianPluginIndexURL, obsidianReleaseURL = origIndex, origRelease
+		obsidianIndexOnce, obsidianIndex, obsidianIndexErr = sync.Once{}, nil, nil
+	}()
+
+	vault := writeVault(t, `["dataview", "unknown"]`)
+	if err := (obsidianPlugins{}).Install(vault, "dataview"); err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+	installed, _ := (obsidianPlugins{}).Installed(vault)
+	if !slices.Equal(installed, []string{"dataview"}) {
+		t.Errorf("Installed = %v, want [dataview]", installed)
+	}
+	if _, err := os.Stat(filepath.Join(vault, obsidianDir, obsidianPluginsDir, "dataview", "styles.css")); !os.IsNotExist(err) {
+		t.Error("optional styles.css should be skipped when the release has none")
+	}
+	if err := (obsidianPlugins{}).Install(vault, "unknown"); err == nil {
+		t.Error("expected an error for a plugin missing from the index")
+	}
+}
+
+func TestParseExtensionList(t *testing.T) {
+	input := "# header\nms-python.python\n\nGolang.Go@0.41.0\n  esbenp.prettier-vscode  \n"
+	ids, err := parseExtensionList("test", bufio.NewScanner(strings.NewReader(input)))
+	if err != nil {
+		t.Fatalf("parseExtensionList failed: %v", err)
+	}
+	if want := []string{"ms-python.python", "golang.go", "esbenp.prettier-vscode"}; !slices.Equal(ids, want) {
+		t.Errorf("ids = %v, want %v", ids, want)
+	}
+}
@@ -0,0 +1,108 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/0xjuanma/anvil/internal/system"
+)
+
+// extensionsFile lists one extension id per line in the editor's config directory
+const extensionsFile = "extensions.txt"
+
+// editorExtensions handles VS Code style editors that manage extensions through their CLI
+type editorExtensions struct {
+	cli  string   // Editor CLI, e.g. "code"
+	apps []string // App names in settings.yaml that use this editor
+}
+
+func (e editorExtensions) Name() string {
+	return e.cli + "-extensions"
+}
+
+func (e editorExtensions) Detect(appName, configPath string) bool {
+	return slices.Contains(e.apps, strings.ToLower(appName))
+}
+
+// Wanted reads extensions.txt, an absent file means no extensions are tracked yet
+func (e editorExtensions) Wanted(configPath string) ([]string, error) {
+	file, err := os.Open(filepath.Join(configPath, extensionsFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return parseExtensionList(file.Name(), bufio.NewScanner(file))
+}
+
+func (e editorExtensions) Installed(configPath string) ([]string, error) {
+	if !system.CommandExists(e.cli) {
+		return nil, fmt.Errorf("'%s' command not found, install the shell command from the editor", e.cli)
+	}
+	result, _ := system.RunCommand(e.cli, "--list-extensions")
+	if !result.Success {
+		return nil, fmt.Errorf("failed to list extensions: %s", strings.TrimSpace(result.Error))
+	}
+	return parseExtensionList(e.cli, bufio.NewScanner(strings.NewReader(result.Output)))
+}
+
+func (e editorExtensions) Install(configPath, id string) error {
+	result, _ := system.RunCommand(e.cli, "--install-extension", id)
+	if !result.Success {
+		return fmt.Errorf("failed to install %s: %s", id, strings.TrimSpace(result.Error))
+	}
+	return nil
+}
+
+// Capture writes the editor's current extensions to extensions.txt so they are pushed with the config
+func (e editorExtensions) Capture(configPath string) error {
+	if !system.CommandExists(e.cli) {
+		return nil
+	}
+	installed, err := e.Installed(configPath)
+	if err != nil {
+		return err
+	}
+	slices.Sort(installed)
+	content := fmt.Sprintf("# Extensions installed by anvil on sync (%s --install-extension)\n%s\n", e.cli, strings.Join(installed, "\n"))
+	return os.WriteFile(filepath.Join(configPath, extensionsFile), []byte(content), 0644)
+}
+
+// parseExtensionList reads extension ids, ignoring blank lines, comments and version suffixes
+func parseExtensionList(source string, scanner *bufio.Scanner) ([]string, error) {
+	var ids []string
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		id, _, _ := strings.Cut(line, "@")
+		ids = append(ids, strings.ToLower(id))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", source, err)
+	}
+	return ids, nil
+}
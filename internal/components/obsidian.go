@@ -0,0 +1,195 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/system"
+)
+
+const (
+	obsidianDir            = ".obsidian"
+	obsidianPluginsList    = "community-plugins.json"
+	obsidianPluginsDir     = "plugins"
+	obsidianPluginManifest = "manifest.json"
+)
+
+// Release locations for community plugins, variables so tests can point them elsewhere
+var (
+	obsidianPluginIndexURL = "https://raw.githubusercontent.com/obsidianmd/obsidian-releases/master/community-plugins.json"
+	obsidianReleaseURL     = "https://github.com/%s/releases/latest/download/%s"
+)
+
+// obsidianPlugins handles the community plugins enabled in an Obsidian vault
+type obsidianPlugins struct{}
+
+var (
+	obsidianIndexOnce sync.Once
+	obsidianIndex     map[string]string // Plugin id to GitHub repository
+	obsidianIndexErr  error
+)
+
+func (obsidianPlugins) Name() string {
+	return "obsidian-plugins"
+}
+
+// Detect accepts either the vault root or its .obsidian directory as the config path
+func (obsidianPlugins) Detect(appName, configPath string) bool {
+	_, err := os.Stat(filepath.Join(obsidianConfigDir(configPath), obsidianPluginsList))
+	return err == nil
+}
+
+func (obsidianPlugins) Wanted(configPath string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(obsidianConfigDir(configPath), obsidianPluginsList))
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	if err := json.Unmarshal(data, &ids); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", obsidianPluginsList, err)
+	}
+	return ids, nil
+}
+
+// Installed lists plugin directories that contain a manifest
+func (obsidianPlugins) Installed(configPath string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(obsidianConfigDir(configPath), obsidianPluginsDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		manifest := filepath.Join(obsidianConfigDir(configPath), obsidianPluginsDir, entry.Name(), obsidianPluginManifest)
+		if _, err := os.Stat(manifest); err == nil {
+			ids = append(ids, entry.Name())
+		}
+	}
+	return ids, nil
+}
+
+// Install downloads the latest release of a community plugin into the vault
+func (obsidianPlugins) Install(configPath, id string) error {
+	repo, err := obsidianPluginRepo(id)
+	if err != nil {
+		return err
+	}
+
+	pluginDir := filepath.Join(obsidianConfigDir(configPath), obsidianPluginsDir, id)
+	if err := os.MkdirAll(pluginDir, 0755); err != nil {
+		return err
+	}
+
+	// styles.css is optional, the other two files make up every plugin release
+	for _, file := range []string{"main.js", obsidianPluginManifest, "styles.css"} {
+		data, status, err := download(fmt.Sprintf(obsidianReleaseURL, repo, file))
+		if err != nil {
+			return fmt.Errorf("failed to download %s for %s: %w", file, id, err)
+		}
+		if status == http.StatusNotFound && file == "styles.css" {
+			continue
+		}
+		if status != http.StatusOK {
+			return fmt.Errorf("failed to download %s for %s: HTTP %d", file, id, status)
+		}
+		if err := os.WriteFile(filepath.Join(pluginDir, file), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// obsidianConfigDir returns the .obsidian directory for a vault root or the directory itself
+func obsidianConfigDir(configPath string) string {
+	if filepath.Base(configPath) == obsidianDir {
+		return configPath
+	}
+	if info, err := os.Stat(filepath.Join(configPath, obsidianDir)); err == nil && info.IsDir() {
+		return filepath.Join(configPath, obsidianDir)
+	}
+	return configPath
+}
+
+// obsidianPluginRepo resolves a plugin id to its repository using the community plugin index
+func obsidianPluginRepo(id string) (string, error) {
+	obsidianIndexOnce.Do(func() {
+		data, status, err := download(obsidianPluginIndexURL)
+		if err == nil && status != http.StatusOK {
+			err = fmt.Errorf("HTTP %d", status)
+		}
+		if err != nil {
+			obsidianIndexErr = fmt.Errorf("failed to fetch the community plugin index: %w", err)
+			return
+		}
+		var plugins []struct {
+			ID   string `json:"id"`
+			Repo string `json:"repo"`
+		}
+		if err := json.Unmarshal(data, &plugins); err != nil {
+			obsidianIndexErr = fmt.Errorf("invalid community plugin index: %w", err)
+			return
+		}
+		obsidianIndex = make(map[string]string, len(plugins))
+		for _, p := range plugins {
+			obsidianIndex[p.ID] = p.Repo
+		}
+	})
+	if obsidianIndexErr != nil {
+		return "", obsidianIndexErr
+	}
+	repo, ok := obsidianIndex[id]
+	if !ok || strings.Count(repo, "/") != 1 {
+		return "", fmt.Errorf("'%s' is not in the community plugin index", id)
+	}
+	return repo, nil
+}
+
+// download fetches a URL, returning the body and status code
+func download(url string) ([]byte, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("User-Agent", "anvil-cli/1.0")
+
+	resp, err := system.HTTPClient().Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	return data, resp.StatusCode, err
+}
@@ -18,9 +18,11 @@ package config
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/0xjuanma/anvil/internal/constants"
 	"github.com/0xjuanma/anvil/internal/system"
@@ -29,15 +31,69 @@ import (
 
 // AnvilConfig represents the main anvil configuration
 type AnvilConfig struct {
-	Version string            `yaml:"version"`
-	Tools   AnvilTools        `yaml:"tools"`
-	Groups  AnvilGroups       `yaml:"groups"`
-	Configs map[string]string `yaml:"configs"` // Maps app names to their local config paths
-	Sources map[string]string `yaml:"sources"` // Maps app names to their download URLs
-	Git     GitConfig         `yaml:"git"`
-	GitHub  GitHubConfig      `yaml:"github"`
+	Version           string                           `yaml:"version"`
+	Tools             AnvilTools                       `yaml:"tools"`
+	Groups            AnvilGroups                      `yaml:"groups"`
+	Configs           AnvilConfigs                     `yaml:"configs"` // Maps app names to their local config paths
+	Sources           map[string]string                `yaml:"sources"` // Maps app names to their download URLs
+	Git               GitConfig                        `yaml:"git"`
+	GitHub            GitHubConfig                     `yaml:"github"`
+	Aliases           map[string]string                `yaml:"aliases,omitempty"`            // Maps alias names to full anvil invocations
+	LocalOnly         []string                         `yaml:"local_only,omitempty"`         // Apps whose configs are tracked locally but never pushed, pulled or synced
+	RepoOnly          []string                         `yaml:"repo_only,omitempty"`          // Apps kept in the config repository for restores but never pushed
+	ManagedExternally []string                         `yaml:"managed_externally,omitempty"` // Apps installed and updated by another tool, e.g. an MDM
+	Sync              SyncConfig                       `yaml:"sync,omitempty"`               // Selective sync rules, optionally scoped to machines
+	Provision         ProvisionConfig                  `yaml:"provision,omitempty"`          // Machine profiles applied by 'anvil provision'
+	Reminders         RemindersConfig                  `yaml:"reminders,omitempty"`          // Periodic reminders shown after commands
+	Brew              BrewConfig                       `yaml:"brew,omitempty"`               // Homebrew maintenance options
+	UI                UIConfig                         `yaml:"ui,omitempty"`                 // Output theme and color settings
+	Network           NetworkConfig                    `yaml:"network,omitempty"`            // Proxy and CA bundle for downloads, git and brew
+	Hosts             []HostEntry                      `yaml:"hosts,omitempty"`              // Entries kept in a managed block of /etc/hosts
+	Services          map[string]string                `yaml:"services,omitempty"`           // Homebrew services by name, "started" or "stopped"
+	Temp              TempConfig                       `yaml:"temp,omitempty"`               // How long pulled copies are trusted
+	FirstRun          map[string]FirstRunConfig        `yaml:"first_run,omitempty"`          // Steps run once after a cask is installed
+	Hooks             map[string]HookConfig            `yaml:"hooks,omitempty"`              // Scripts run before and after an app is installed
+	EcosystemTools    map[string]EcosystemTool         `yaml:"ecosystem_tools,omitempty"`    // Tools installed with go, cargo, npm or pipx instead of the package manager
+	AppVersions       map[string]AppVersionRequirement `yaml:"app_versions,omitempty"`       // Oldest app versions pushed configs work with, checked by sync
+	Defaults          CommandDefaults                  `yaml:"defaults,omitempty"`           // Default flag values per command, e.g. install: {concurrent: true}
+
+	Confirmations   ConfirmationsConfig `yaml:"confirmations,omitempty"`     // Which prompts are shown and which --yes may approve
+	SettingsBackups int                 `yaml:"settings_backups,omitempty"`  // Versions of settings.yaml kept on each write (0 = default 10, -1 = disabled)
+	MinAnvilVersion string              `yaml:"min_anvil_version,omitempty"` // Oldest anvil release these settings work with
+	FailurePolicies map[string]string   `yaml:"failure_policies,omitempty"`  // Per group: fail-fast, continue or prompt when a tool fails
+	PackageManager  string              `yaml:"package_manager,omitempty"`   // auto (default), brew, apt or dnf
+
+	// ToolPlatforms holds platform tags from group entries such as "- iterm2: {platforms: [darwin]}".
+	// It is read from and written back into groups, see platforms.go
+	ToolPlatforms map[string][]string `yaml:"-"`
+
+	// ToolCritical holds critical tags from group entries such as "- git: {critical: true}",
+	// by group and then app. Like platform tags they are written back into groups.
+	ToolCritical map[string]map[string]bool `yaml:"-"`
+
+	// ToolOrdering holds order and after tags from group entries such as "- docker-compose: {after: [docker]}",
+	// by group and then app, see ordering.go
+	ToolOrdering map[string]map[string]OrderTags `yaml:"-"`
+
+	// ConfigFilters holds include and exclude patterns of configs entries such as
+	// "obsidian: {path: ..., exclude: [.DS_Store]}". They are written back into configs, see configfilters.go
+	ConfigFilters map[string]ConfigFilter `yaml:"-"`
+
+	// writtenGitHub is the github section as written in settings.yaml while --remote swaps
+	// another repository in, see remotes.go
+	writtenGitHub *GitHubConfig
+
+	// local is what settings.local.yaml merged over settings.yaml, kept out of it on save
+	local *localOverlay
 }
 
+// protectedWarning makes sure the protected settings warning is only shown once per run
+var protectedWarning sync.Once
+
+// warningOutput receives warnings raised while loading or saving settings. It is stderr so
+// machine-readable output such as 'anvil info --json' stays clean
+var warningOutput io.Writer = os.Stderr
+
 // GetAnvilConfigDirectory returns the path to the anvil config directory
 func GetAnvilConfigDirectory() string {
 	homeDir, _ := system.GetHomeDir()
@@ -49,7 +105,7 @@ func GetAnvilConfigPath() string {
 	return fmt.Sprintf("%s/%s", GetAnvilConfigDirectory(), constants.ANVIL_CONFIG_FILE)
 }
 
-// LoadConfig loads the anvil configuration from settings.yaml
+// LoadConfig loads the anvil configuration from settings.yaml, with settings.local.yaml merged over it
 func LoadConfig() (*AnvilConfig, error) {
 	configPath := GetAnvilConfigPath()
 
@@ -58,10 +114,38 @@ func LoadConfig() (*AnvilConfig, error) {
 		return nil, fmt.Errorf("failed to read %s: %w", constants.ANVIL_CONFIG_FILE, err)
 	}
 
+	// Checked first so broken YAML is reported as corruption, which can be restored from a backup
+	if err := ValidSettings(data); err != nil {
+		// Read-only commands keep working on what could be parsed, see safemode.go
+		if SafeModeEnabled() {
+			config, _ := ParseSettingsBySection(data)
+			return config, nil
+		}
+		return nil, &CorruptSettingsError{Err: err}
+	}
+
+	// Machine-local values win over settings.yaml, see overlay.go
+	data, local, err := applyLocalOverlay(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Protected team settings always win over local edits
+	data, conflicts, err := EnforceProtectedSettings(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(conflicts) > 0 {
+		protectedWarning.Do(func() {
+			fmt.Fprintf(warningOutput, "Warning: %d local setting(s) conflict with protected team settings and were overridden. Run 'anvil doctor protected-settings' for details\n", len(conflicts))
+		})
+	}
+
 	var config AnvilConfig
 	if err := yaml.Unmarshal(data, &config); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal %s: %w", constants.ANVIL_CONFIG_FILE, err)
 	}
+	config.local = local
 
 	// Validate and auto-correct GitHub configuration
 	if ValidateAndFixGitHubConfig(&config) {
@@ -72,6 +156,10 @@ func LoadConfig() (*AnvilConfig, error) {
 		}
 	}
 
+	if err := applyActiveRemote(&config); err != nil {
+		return nil, err
+	}
+
 	return &config, nil
 }
 
@@ -104,19 +192,32 @@ func LoadSampleConfigWithVersion(version string) (*AnvilConfig, error) {
 
 // SaveConfig saves the anvil configuration to settings.yaml
 func SaveConfig(config *AnvilConfig) error {
-	configPath := GetAnvilConfigPath()
+	if SafeModeEnabled() {
+		return fmt.Errorf("%s is corrupted and loaded in safe mode, run 'anvil config validate' and fix it before changes can be saved", constants.ANVIL_CONFIG_FILE)
+	}
+
+	if config.writtenGitHub != nil {
+		written := *config
+		written.GitHub = *config.writtenGitHub
+		config = &written
+	}
 
 	data, err := yaml.Marshal(config)
 	if err != nil {
 		return fmt.Errorf("failed to marshal config to YAML: %w", err)
 	}
 
-	if err := os.WriteFile(configPath, data, constants.FilePerm); err != nil {
-		return fmt.Errorf("failed to write %s: %w", constants.ANVIL_CONFIG_FILE, err)
+	// Values from settings.local.yaml stay out of the synced settings.yaml
+	if config.local != nil {
+		var saved yaml.MapSlice
+		if err := yaml.Unmarshal(data, &saved); err != nil {
+			return fmt.Errorf("failed to marshal config to YAML: %w", err)
+		}
+		if data, err = yaml.Marshal(config.local.withoutLocalValues(saved)); err != nil {
+			return fmt.Errorf("failed to marshal config to YAML: %w", err)
+		}
 	}
 
-	// Invalidate cache after saving
-	invalidateCache()
-
-	return nil
+	// The replaced version is kept in the backup ring, see backups.go
+	return writeSettings(data, config.SettingsBackups)
 }
@@ -0,0 +1,84 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/0xjuanma/anvil/internal/version"
+)
+
+// Policies applied by sync when the installed app is older than its configs require
+const (
+	AppVersionWarn  = "warn"  // Report the mismatch and sync anyway
+	AppVersionBlock = "block" // Refuse to sync until the app is upgraded
+)
+
+// AppVersionRequirement is the oldest version of an app its configs work with. It is recorded
+// in the repository manifest when the app is pushed and checked by sync on other machines.
+type AppVersionRequirement struct {
+	MinAppVersion string `yaml:"min_app_version"`       // e.g. 1.5.0
+	OnMismatch    string `yaml:"on_mismatch,omitempty"` // warn (default) or block
+}
+
+// Blocks reports whether a mismatch stops the sync
+func (r AppVersionRequirement) Blocks() bool {
+	return r.OnMismatch == AppVersionBlock
+}
+
+// ValidateAppVersions checks the minimum versions and mismatch policies of app_versions
+func ValidateAppVersions(requirements map[string]AppVersionRequirement) error {
+	names := make([]string, 0, len(requirements))
+	for name := range requirements {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		requirement := requirements[name]
+		if err := version.Validate(requirement.MinAppVersion); err != nil {
+			return fmt.Errorf("min_app_version for '%s': %w", name, err)
+		}
+		switch requirement.OnMismatch {
+		case "", AppVersionWarn, AppVersionBlock:
+		default:
+			return fmt.Errorf("unknown app version policy '%s' for '%s' (use %s)", requirement.OnMismatch, name,
+				strings.Join([]string{AppVersionWarn, AppVersionBlock}, ", "))
+		}
+	}
+	return nil
+}
+
+// SatisfiesAppVersion reports whether an installed app version is minimum or newer. Package
+// manager versions such as "1.5.12_1", "2024.1,241.14" or "1.2.3.4" are compared by their
+// first three numbers.
+func SatisfiesAppVersion(installed, minimum string) (bool, error) {
+	cmp, err := version.Compare(normalizeAppVersion(installed), minimum)
+	return cmp >= 0, err
+}
+
+// normalizeAppVersion strips build suffixes and extra components from a package version
+func normalizeAppVersion(v string) string {
+	v, _, _ = strings.Cut(strings.TrimSpace(v), ",")
+	v, _, _ = strings.Cut(v, "_")
+	if parts := strings.Split(v, "."); len(parts) > 3 {
+		v = strings.Join(parts[:3], ".")
+	}
+	return v
+}
@@ -0,0 +1,243 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/constants"
+	"gopkg.in/yaml.v2"
+)
+
+// settingsBackupTimeFormat names backups so they sort oldest to newest
+const settingsBackupTimeFormat = "20060102-150405.000000000"
+
+// SettingsBackup is a previous version of settings.yaml kept in the backup ring
+type SettingsBackup struct {
+	Version int // 1 is the most recent backup
+	Path    string
+	Time    time.Time
+	Size    int64
+}
+
+// CorruptSettingsError is returned by LoadConfig when settings.yaml is not valid YAML
+type CorruptSettingsError struct {
+	Err error
+}
+
+func (e *CorruptSettingsError) Error() string {
+	return fmt.Sprintf("%s is corrupted: %v", constants.ANVIL_CONFIG_FILE, e.Err)
+}
+
+func (e *CorruptSettingsError) Unwrap() error {
+	return e.Err
+}
+
+// IsCorruptSettings reports whether err, or an error it wraps, is a CorruptSettingsError
+func IsCorruptSettings(err error) bool {
+	var corrupt *CorruptSettingsError
+	return errors.As(err, &corrupt)
+}
+
+// GetSettingsBackupDirectory returns the directory holding the settings backup ring
+func GetSettingsBackupDirectory() string {
+	return filepath.Join(GetAnvilConfigDirectory(), "backups", "settings")
+}
+
+// settingsBackupLimit returns how many backups to keep, 0 when backups are disabled
+func settingsBackupLimit(configured int) int {
+	switch {
+	case configured < 0:
+		return 0
+	case configured == 0:
+		return constants.DefaultSettingsBackups
+	default:
+		return configured
+	}
+}
+
+// writeSettings writes settings.yaml, first keeping the version it replaces in the backup ring
+func writeSettings(data []byte, backups int) error {
+	_, err := commitSettings(data, backups, "")
+	return err
+}
+
+// commitSettings writes settings.yaml like writeSettings and logs the change in the transaction
+// log, as the revert of transaction undoes when set. It returns the ID of the new transaction.
+func commitSettings(data []byte, backups int, undoes string) (string, error) {
+	configPath := GetAnvilConfigPath()
+	current, readErr := os.ReadFile(configPath)
+
+	// A failed backup never blocks the write, settings changes matter more than history
+	if keep := settingsBackupLimit(backups); keep > 0 {
+		if err := backupSettings(configPath, data, keep); err != nil {
+			fmt.Fprintf(warningOutput, "Warning: Could not back up %s: %v\n", constants.ANVIL_CONFIG_FILE, err)
+		}
+	}
+
+	if err := os.WriteFile(configPath, data, constants.FilePerm); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", constants.ANVIL_CONFIG_FILE, err)
+	}
+	invalidateCache()
+
+	// Creating the file is not logged, undoing 'anvil init' would leave no settings behind
+	if readErr != nil || (undoes == "" && bytes.Equal(current, data)) {
+		return "", nil
+	}
+	id, err := recordTransaction(current, data, undoes)
+	if err != nil {
+		fmt.Fprintf(warningOutput, "Warning: Could not record the %s change for 'anvil undo': %v\n", constants.ANVIL_CONFIG_FILE, err)
+	}
+	return id, nil
+}
+
+// backupSettings copies the settings file into the ring before it is replaced by next,
+// unless nothing changes or the newest backup already holds the same content, then drops
+// the oldest backups beyond keep
+func backupSettings(configPath string, next []byte, keep int) error {
+	current, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(current, next) {
+		return nil
+	}
+
+	backups, err := ListSettingsBackups()
+	if err != nil {
+		return err
+	}
+	if len(backups) > 0 {
+		if newest, err := os.ReadFile(backups[0].Path); err == nil && bytes.Equal(newest, current) {
+			return nil
+		}
+	}
+
+	dir := GetSettingsBackupDirectory()
+	if err := os.MkdirAll(dir, constants.DirPerm); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("settings-%s.yaml", time.Now().Format(settingsBackupTimeFormat))
+	if err := os.WriteFile(filepath.Join(dir, name), current, constants.FilePerm); err != nil {
+		return err
+	}
+
+	backups, err = ListSettingsBackups()
+	if err != nil {
+		return err
+	}
+	for _, old := range backups[min(keep, len(backups)):] {
+		if err := os.Remove(old.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListSettingsBackups returns the settings backups, most recent first
+func ListSettingsBackups() ([]SettingsBackup, error) {
+	entries, err := os.ReadDir(GetSettingsBackupDirectory())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []SettingsBackup
+	for _, entry := range entries {
+		name := entry.Name()
+		stamp, ok := strings.CutPrefix(strings.TrimSuffix(name, ".yaml"), "settings-")
+		if entry.IsDir() || !ok || !strings.HasSuffix(name, ".yaml") {
+			continue
+		}
+		created, err := time.ParseInLocation(settingsBackupTimeFormat, stamp, time.Local)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, SettingsBackup{Path: filepath.Join(GetSettingsBackupDirectory(), name), Time: created, Size: info.Size()})
+	}
+
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Time.After(backups[j].Time) })
+	for i := range backups {
+		backups[i].Version = i + 1
+	}
+	return backups, nil
+}
+
+// ValidSettings reports whether data parses as settings.yaml
+func ValidSettings(data []byte) error {
+	var config AnvilConfig
+	return yaml.Unmarshal(data, &config)
+}
+
+// RestoreSettingsBackup replaces settings.yaml with a backup. The replaced settings are
+// backed up first, so a restore can itself be undone.
+func RestoreSettingsBackup(version int) (SettingsBackup, error) {
+	backups, err := ListSettingsBackups()
+	if err != nil {
+		return SettingsBackup{}, err
+	}
+	if version < 1 || version > len(backups) {
+		return SettingsBackup{}, fmt.Errorf("no settings backup version %d, %d available", version, len(backups))
+	}
+	backup := backups[version-1]
+
+	data, err := os.ReadFile(backup.Path)
+	if err != nil {
+		return SettingsBackup{}, err
+	}
+	if err := ValidSettings(data); err != nil {
+		return SettingsBackup{}, fmt.Errorf("backup %d is not valid YAML: %w", version, err)
+	}
+
+	var restored AnvilConfig
+	_ = yaml.Unmarshal(data, &restored)
+	if err := writeSettings(data, restored.SettingsBackups); err != nil {
+		return SettingsBackup{}, err
+	}
+	return backup, nil
+}
+
+// LatestValidSettingsBackup returns the most recent backup that parses, used to recover
+// from a corrupted settings.yaml
+func LatestValidSettingsBackup() (SettingsBackup, bool) {
+	backups, err := ListSettingsBackups()
+	if err != nil {
+		return SettingsBackup{}, false
+	}
+	for _, backup := range backups {
+		if data, err := os.ReadFile(backup.Path); err == nil && ValidSettings(data) == nil {
+			return backup, true
+		}
+	}
+	return SettingsBackup{}, false
+}
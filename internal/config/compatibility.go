@@ -0,0 +1,88 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/version"
+)
+
+// VersionRequirement is the oldest anvil release the loaded settings work with
+type VersionRequirement struct {
+	Minimum string // Empty when nothing requires a minimum
+	Source  string // File that set the minimum
+}
+
+// VersionTooOldError is returned when the running anvil is older than the settings require
+type VersionTooOldError struct {
+	Current     string
+	Requirement VersionRequirement
+}
+
+func (e *VersionTooOldError) Error() string {
+	return fmt.Sprintf("anvil %s is older than %s, required by %s", e.Current, e.Requirement.Minimum, e.Requirement.Source)
+}
+
+// GetVersionRequirement returns the highest min_anvil_version set by the team layer or settings.yaml
+func GetVersionRequirement() (VersionRequirement, error) {
+	var requirement VersionRequirement
+
+	layer, err := LoadTeamLayer()
+	if err != nil {
+		return requirement, err
+	}
+	if layer != nil && layer.MinAnvilVersion != "" {
+		if err := version.Validate(layer.MinAnvilVersion); err != nil {
+			return requirement, fmt.Errorf("min_anvil_version in %s: %w", GetTeamSettingsPath(), err)
+		}
+		requirement = VersionRequirement{Minimum: layer.MinAnvilVersion, Source: GetTeamSettingsPath()}
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil || cfg.MinAnvilVersion == "" {
+		// A missing settings.yaml requires nothing, broken ones are reported elsewhere
+		return requirement, nil
+	}
+	if err := version.Validate(cfg.MinAnvilVersion); err != nil {
+		return requirement, fmt.Errorf("min_anvil_version in %s: %w", constants.ANVIL_CONFIG_FILE, err)
+	}
+	if requirement.Minimum != "" {
+		if cmp, _ := version.Compare(cfg.MinAnvilVersion, requirement.Minimum); cmp <= 0 {
+			return requirement, nil
+		}
+	}
+	return VersionRequirement{Minimum: cfg.MinAnvilVersion, Source: GetAnvilConfigPath()}, nil
+}
+
+// CheckVersionRequirement returns a *VersionTooOldError when the running anvil is older
+// than the team layer or settings.yaml require. Builds without a release version pass.
+func CheckVersionRequirement() error {
+	requirement, err := GetVersionRequirement()
+	if err != nil || requirement.Minimum == "" {
+		return err
+	}
+	ok, err := version.AtLeast(requirement.Minimum)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return &VersionTooOldError{Current: version.GetVersion(), Requirement: requirement}
+	}
+	return nil
+}
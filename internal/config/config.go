@@ -25,6 +25,7 @@ import (
 	"sync"
 
 	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/events"
 	"github.com/0xjuanma/anvil/internal/system"
 	"github.com/0xjuanma/anvil/internal/utils"
 	"github.com/0xjuanma/anvil/internal/version"
@@ -45,11 +46,17 @@ var builtInGroups = []string{"dev", "essentials"}
 // AnvilGroups represents grouped tool configurations
 type AnvilGroups map[string][]string
 
+// AnvilConfigs maps app names to their local config paths
+type AnvilConfigs map[string]string
+
 // GitConfig represents git configuration
 type GitConfig struct {
 	Username   string `yaml:"username"`
 	Email      string `yaml:"email"`
 	SSHKeyPath string `yaml:"ssh_key_path,omitempty"` // Reference to SSH private key
+
+	ExtraConfig  map[string]string `yaml:"extra_config,omitempty"`  // Global git config values, e.g. alias.co: checkout or core.editor: nvim
+	GlobalIgnore string            `yaml:"global_ignore,omitempty"` // Global ignore file set as core.excludesfile, e.g. ~/.gitignore_global
 }
 
 // GitHubConfig represents GitHub repository configuration for config sync
@@ -59,6 +66,104 @@ type GitHubConfig struct {
 	LocalPath   string `yaml:"local_path"`              // Local path where configs are stored/synced
 	Token       string `yaml:"token,omitempty"`         // GitHub token (use env var reference)
 	TokenEnvVar string `yaml:"token_env_var,omitempty"` // Environment variable name for token
+	CloneDepth  int    `yaml:"clone_depth,omitempty"`   // Clone depth (0 = default shallow clone, -1 = full history)
+
+	MaterializeSymlinks bool `yaml:"materialize_symlinks,omitempty"` // Copy symlink targets instead of preserving links on push/sync
+
+	BranchTimestampFormat string `yaml:"branch_timestamp_format,omitempty"` // Push branch timestamp: iso (default), legacy, us, or a Go time layout
+	BranchIncludeHost     bool   `yaml:"branch_include_host,omitempty"`     // Append the hostname to push branch names
+	AppBranches           bool   `yaml:"app_branches,omitempty"`            // Push each app to a long-lived configs/<app> branch instead of a new timestamped one
+
+	MaxPushSizeMB int `yaml:"max_push_size_mb,omitempty"` // Push size limit in MB (0 = default 100, -1 = unlimited)
+	MaxPushFiles  int `yaml:"max_push_files,omitempty"`   // Push file count limit (0 = default 5000, -1 = unlimited)
+
+	GenerateReadme bool `yaml:"generate_readme,omitempty"` // Regenerate a README index of app directories on each push
+
+	Remotes map[string]GitHubRemote `yaml:"remotes,omitempty"` // Named config repositories selected with --remote, see remotes.go
+}
+
+// ConfirmationsConfig sets a policy (ask, always or never) per confirmation action,
+// e.g. "sync: always", and which actions --yes may approve
+type ConfirmationsConfig struct {
+	Policies   map[string]string `yaml:",inline"`
+	YesAllowed []string          `yaml:"yes_allowed,omitempty"` // Actions --yes may approve, empty allows all
+}
+
+// UIConfig controls how anvil renders its output
+type UIConfig struct {
+	Theme    string `yaml:"theme,omitempty"`    // default, light, high-contrast or monochrome
+	Color    *bool  `yaml:"color,omitempty"`    // false disables colors, like setting NO_COLOR
+	Language string `yaml:"language,omitempty"` // Message language, e.g. es; empty detects it from LANG
+}
+
+// ColorEnabled reports whether colored output is allowed by settings
+func (u UIConfig) ColorEnabled() bool {
+	return u.Color == nil || *u.Color
+}
+
+// NetworkConfig holds proxy and TLS settings for machines behind a corporate proxy
+type NetworkConfig struct {
+	HTTPProxy  string `yaml:"http_proxy,omitempty"`  // Proxy for http:// requests, e.g. http://proxy.corp.com:8080
+	HTTPSProxy string `yaml:"https_proxy,omitempty"` // Proxy for https:// requests
+	NoProxy    string `yaml:"no_proxy,omitempty"`    // Comma separated hosts and domains reached directly
+	CABundle   string `yaml:"ca_bundle,omitempty"`   // PEM file with extra trusted certificates, e.g. the proxy's CA
+}
+
+// Wanted states of a Homebrew service in the services section
+const (
+	ServiceStarted = "started"
+	ServiceStopped = "stopped"
+)
+
+// HostEntry is one line of the managed /etc/hosts block, e.g. 127.0.0.1 api.local web.local
+type HostEntry struct {
+	IP    string   `yaml:"ip"`
+	Names []string `yaml:"names"`
+}
+
+// Settings returns the network settings to apply, with "~" in ca_bundle expanded
+func (n NetworkConfig) Settings() system.Network {
+	caBundle := n.CABundle
+	if strings.HasPrefix(caBundle, "~/") {
+		homeDir, _ := system.GetHomeDir()
+		caBundle = filepath.Join(homeDir, caBundle[2:])
+	}
+	return system.Network{HTTPProxy: n.HTTPProxy, HTTPSProxy: n.HTTPSProxy, NoProxy: n.NoProxy, CABundle: caBundle}
+}
+
+// BrewConfig represents Homebrew maintenance and policy options
+type BrewConfig struct {
+	Cleanup          bool   `yaml:"cleanup,omitempty"`            // Run 'brew cleanup' after group installs
+	CleanupPruneDays int    `yaml:"cleanup_prune_days,omitempty"` // Remove cached downloads older than this many days (0 = brew default)
+	Analytics        *bool  `yaml:"analytics,omitempty"`          // false disables Homebrew analytics for every brew call
+	BottleDomain     string `yaml:"bottle_domain,omitempty"`      // Mirror for bottles, sets HOMEBREW_BOTTLE_DOMAIN
+	APIDomain        string `yaml:"api_domain,omitempty"`         // Mirror for the formula and cask API, sets HOMEBREW_API_DOMAIN
+	AutoUpdate       bool   `yaml:"auto_update,omitempty"`        // Run 'brew update' before installs, at most once a day
+}
+
+// AnalyticsDisabled reports whether the policy turns Homebrew analytics off
+func (b BrewConfig) AnalyticsDisabled() bool {
+	return b.Analytics != nil && !*b.Analytics
+}
+
+// HasPolicy reports whether any Homebrew policy setting is configured
+func (b BrewConfig) HasPolicy() bool {
+	return b.AnalyticsDisabled() || b.BottleDomain != "" || b.APIDomain != ""
+}
+
+// Env returns the environment variables that apply the policy to brew invocations
+func (b BrewConfig) Env() []string {
+	var env []string
+	if b.AnalyticsDisabled() {
+		env = append(env, constants.BrewNoAnalyticsEnvVar+"=1")
+	}
+	if b.BottleDomain != "" {
+		env = append(env, constants.BrewBottleDomainEnvVar+"="+b.BottleDomain)
+	}
+	if b.APIDomain != "" {
+		env = append(env, constants.BrewAPIDomainEnvVar+"="+b.APIDomain)
+	}
+	return env
 }
 
 // AnvilTools represents tool configurations
@@ -121,6 +226,10 @@ func ensureMap(m interface{}) {
 		if *v == nil {
 			*v = make(map[string]string)
 		}
+	case *AnvilConfigs:
+		if *v == nil {
+			*v = make(AnvilConfigs)
+		}
 	}
 }
 
@@ -166,6 +275,11 @@ func PopulateGitConfigFromSystem(gitConfig *GitConfig) error {
 	return nil
 }
 
+// InvalidateConfigCache forces the next read to reload settings.yaml, e.g. after a sync replaced it
+func InvalidateConfigCache() {
+	invalidateCache()
+}
+
 // invalidateCache clears the configuration cache
 func invalidateCache() {
 	configCacheMutex.Lock()
@@ -224,6 +338,17 @@ func GenerateDefaultSettingsWithVersion(version string) error {
 	return nil
 }
 
+// SetConfigRepository sets the GitHub repository (and optionally the branch) used for config pull/push
+func SetConfigRepository(repo, branch string) error {
+	return withConfigAndSave(func(config *AnvilConfig) error {
+		config.GitHub.ConfigRepo = normalizeGitHubRepo(repo)
+		if branch != "" {
+			config.GitHub.Branch = branch
+		}
+		return nil
+	})
+}
+
 // GetGroupTools returns the tools for a specific group
 func GetGroupTools(groupName string) ([]string, error) {
 	var result []string
@@ -291,7 +416,8 @@ func UpdateGroupTools(groupName string, tools []string) error {
 
 // AddAppToGroup adds an app to a group, creating the group if it doesn't exist
 func AddAppToGroup(groupName string, appName string) error {
-	return withConfigAndSave(func(config *AnvilConfig) error {
+	added := false
+	err := withConfigAndSave(func(config *AnvilConfig) error {
 		ensureMap(&config.Groups)
 
 		if tools, exists := config.Groups[groupName]; exists {
@@ -304,8 +430,13 @@ func AddAppToGroup(groupName string, appName string) error {
 		} else {
 			config.Groups[groupName] = []string{appName}
 		}
+		added = true
 		return nil
 	})
+	if err == nil && added {
+		events.Publish(events.Tracked, appName, groupName)
+	}
+	return err
 }
 
 // CheckEnvironmentConfigurations checks local environment configurations
@@ -354,15 +485,21 @@ func CheckEnvironmentConfigurations() []string {
 
 // AddInstalledApp adds an app to the installed apps list if it's not already there
 func AddInstalledApp(appName string) error {
-	return withConfigAndSave(func(config *AnvilConfig) error {
+	added := false
+	err := withConfigAndSave(func(config *AnvilConfig) error {
 		// Check if already tracked anywhere
 		if tracked, _ := IsAppTracked(appName); tracked {
 			return nil
 		}
 
 		config.Tools.InstalledApps = append(config.Tools.InstalledApps, appName)
+		added = true
 		return nil
 	})
+	if err == nil && added {
+		events.Publish(events.Tracked, appName, SectionInstalledApps)
+	}
+	return err
 }
 
 // GetInstalledApps returns the list of individually installed applications
@@ -375,13 +512,14 @@ func GetInstalledApps() ([]string, error) {
 	return apps, err
 }
 
-// IsAppTracked checks if an app is being tracked in any category
+// IsAppTracked checks if an app is being tracked in any category, ignoring case
 func IsAppTracked(appName string) (bool, error) {
 	var found bool
+	name := NormalizeAppName(appName)
 	err := withConfig(func(config *AnvilConfig) error {
 		// Check in all tool lists
 		for _, tool := range append(config.Tools.RequiredTools, config.Tools.InstalledApps...) {
-			if tool == appName {
+			if NormalizeAppName(tool) == name {
 				found = true
 				return nil
 			}
@@ -390,7 +528,7 @@ func IsAppTracked(appName string) (bool, error) {
 		// Check in groups
 		for _, tools := range config.Groups {
 			for _, tool := range tools {
-				if tool == appName {
+				if NormalizeAppName(tool) == name {
 					found = true
 					return nil
 				}
@@ -401,11 +539,11 @@ func IsAppTracked(appName string) (bool, error) {
 	return found, err
 }
 
-// RemoveInstalledApp removes an app from the installed apps list
+// RemoveInstalledApp removes an app from the installed apps list, ignoring case
 func RemoveInstalledApp(appName string) error {
 	return withConfigAndSave(func(config *AnvilConfig) error {
 		for i, app := range config.Tools.InstalledApps {
-			if app == appName {
+			if NormalizeAppName(app) == NormalizeAppName(appName) {
 				config.Tools.InstalledApps = append(config.Tools.InstalledApps[:i], config.Tools.InstalledApps[i+1:]...)
 				break
 			}
@@ -460,7 +598,7 @@ func GetAppConfigPath(appName string) (string, bool, error) {
 
 // GetTempAppPath checks if an app directory exists in the temp directory (from previous pull)
 func GetTempAppPath(appName string) (string, bool, error) {
-	tempPath := filepath.Join(GetAnvilConfigDirectory(), "temp", appName)
+	tempPath := filepath.Join(GetTempDirectory(), appName)
 	if _, err := os.Stat(tempPath); os.IsNotExist(err) {
 		return "", false, nil
 	}
@@ -508,3 +646,83 @@ func GetConfiguredApps() ([]string, error) {
 	})
 	return apps, err
 }
+
+// GetAliases returns the user-defined command aliases
+func GetAliases() (map[string]string, error) {
+	var aliases map[string]string
+	err := withConfig(func(config *AnvilConfig) error {
+		aliases = config.Aliases
+		return nil
+	})
+	return aliases, err
+}
+
+// GetConfirmationsConfig returns the confirmation policy
+func GetConfirmationsConfig() (ConfirmationsConfig, error) {
+	var confirmations ConfirmationsConfig
+	err := withConfig(func(config *AnvilConfig) error {
+		confirmations = config.Confirmations
+		return nil
+	})
+	return confirmations, err
+}
+
+// GetUIConfig returns the output theme and color settings
+func GetUIConfig() (UIConfig, error) {
+	var uiConfig UIConfig
+	err := withConfig(func(config *AnvilConfig) error {
+		uiConfig = config.UI
+		return nil
+	})
+	return uiConfig, err
+}
+
+// GetNetworkConfig returns the proxy and TLS settings
+func GetNetworkConfig() (NetworkConfig, error) {
+	var networkConfig NetworkConfig
+	err := withConfig(func(config *AnvilConfig) error {
+		networkConfig = config.Network
+		return nil
+	})
+	return networkConfig, err
+}
+
+// GetHostsConfig returns the entries of the managed /etc/hosts block
+func GetHostsConfig() ([]HostEntry, error) {
+	var entries []HostEntry
+	err := withConfig(func(config *AnvilConfig) error {
+		entries = config.Hosts
+		return nil
+	})
+	return entries, err
+}
+
+// GetServicesConfig returns the wanted state of each Homebrew service in the services section
+func GetServicesConfig() (map[string]string, error) {
+	var services map[string]string
+	err := withConfig(func(config *AnvilConfig) error {
+		services = config.Services
+		return nil
+	})
+	return services, err
+}
+
+// GetGitConfig returns the git section of settings.yaml
+func GetGitConfig() (GitConfig, error) {
+	var git GitConfig
+	err := withConfig(func(config *AnvilConfig) error {
+		git = config.Git
+		return nil
+	})
+	return git, err
+}
+
+// GetBrewConfig returns the Homebrew maintenance options
+func GetBrewConfig() (BrewConfig, error) {
+	var brewConfig BrewConfig
+	err := withConfig(func(config *AnvilConfig) error {
+		brewConfig = config.Brew
+		return nil
+	})
+	return brewConfig, err
+}
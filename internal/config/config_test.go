@@ -17,11 +17,22 @@ limitations under the License.
 package config
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/system"
+	"github.com/0xjuanma/anvil/internal/version"
+	"gopkg.in/yaml.v2"
 )
 
 // setupTestConfig creates a test configuration with temporary directories
@@ -516,3 +527,1291 @@ func TestGroupManagementFunctions(t *testing.T) {
 		t.Errorf("Expected at least 3 groups, got %d", len(groups))
 	}
 }
+
+func TestSyncExcludesFor(t *testing.T) {
+	rules := []SyncRule{
+		{Hosts: []string{"work-*"}, Apps: []string{"anvil"}, Exclude: []string{"git"}},
+		{Apps: []string{"zsh"}, Exclude: []string{"*.local"}},
+		{Hosts: []string{"home-mac"}, Exclude: []string{"github.local_path"}},
+	}
+
+	tests := []struct {
+		name      string
+		app       string
+		machineID string
+		expected  []string
+	}{
+		{name: "work machine settings", app: "anvil", machineID: "work-laptop", expected: []string{"git"}},
+		{name: "home machine settings", app: "anvil", machineID: "home-mac", expected: []string{"github.local_path"}},
+		{name: "app rule on any machine", app: "zsh", machineID: "work-laptop", expected: []string{"*.local"}},
+		{name: "no matching rules", app: "nvim", machineID: "other", expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SyncExcludesFor(rules, tt.app, tt.machineID)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("SyncExcludesFor() = %v, want %v", got, tt.expected)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("SyncExcludesFor() = %v, want %v", got, tt.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestApplySettingsExcludes(t *testing.T) {
+	pulled := []byte("git:\n  username: shared\n  email: shared@example.com\ngithub:\n  branch: main\n  local_path: /shared\nconfigs:\n  zsh: /a\n")
+	current := []byte("git:\n  username: work\n  email: me@work.com\ngithub:\n  branch: main\n  local_path: /Users/me/dotfiles\n")
+
+	filtered, err := ApplySettingsExcludes(pulled, current, []string{"git", "github.local_path", "configs"})
+	if err != nil {
+		t.Fatalf("ApplySettingsExcludes() error = %v", err)
+	}
+
+	var result AnvilConfig
+	if err := yaml.Unmarshal(filtered, &result); err != nil {
+		t.Fatalf("failed to parse filtered settings: %v", err)
+	}
+	if result.Git.Username != "work" || result.Git.Email != "me@work.com" {
+		t.Errorf("Expected git section to keep local values, got %+v", result.Git)
+	}
+	if result.GitHub.LocalPath != "/Users/me/dotfiles" || result.GitHub.Branch != "main" {
+		t.Errorf("Expected only github.local_path to keep its local value, got %+v", result.GitHub)
+	}
+	if len(result.Configs) != 0 {
+		t.Errorf("Expected configs missing locally to be dropped, got %v", result.Configs)
+	}
+}
+
+func TestFindProtectedConflicts(t *testing.T) {
+	var local, protected yaml.MapSlice
+	if err := yaml.Unmarshal([]byte(`
+tools:
+  required_tools: [git, brew-extra]
+github:
+  token_env_var: MY_TOKEN
+`), &local); err != nil {
+		t.Fatal(err)
+	}
+	if err := yaml.Unmarshal([]byte(`
+tools:
+  required_tools: [git, curl]
+github:
+  token_env_var: GITHUB_TOKEN
+git:
+  email: dev@example.com
+`), &protected); err != nil {
+		t.Fatal(err)
+	}
+
+	conflicts := FindProtectedConflicts(local, protected)
+	got := make(map[string]string)
+	for _, conflict := range conflicts {
+		got[conflict.Key] = conflict.Message
+	}
+
+	want := map[string]string{
+		"tools.required_tools": "missing curl",
+		"github.token_env_var": "is MY_TOKEN, must be GITHUB_TOKEN",
+		"git.email":            "missing, must be dev@example.com",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("FindProtectedConflicts() = %v, want %v", got, want)
+	}
+	for key, message := range want {
+		if got[key] != message {
+			t.Errorf("conflict %s = %q, want %q", key, got[key], message)
+		}
+	}
+
+	enforced := ApplyProtected(local, protected)
+	if remaining := FindProtectedConflicts(enforced, protected); len(remaining) != 0 {
+		t.Errorf("conflicts remain after ApplyProtected: %v", remaining)
+	}
+	tools, _ := lookupMapSlice(enforced, []string{"tools", "required_tools"})
+	if fmt.Sprint(tools) != "[git brew-extra curl]" {
+		t.Errorf("required_tools = %v, want local additions kept", tools)
+	}
+}
+
+func TestLoadConfigEnforcesProtectedSettings(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	teamPath := filepath.Join(GetAnvilConfigDirectory(), constants.TEAM_CONFIG_FILE)
+	teamData := "protected:\n  tools:\n    required_tools: [git, 1password-cli]\n"
+	if err := os.WriteFile(teamPath, []byte(teamData), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var warnings bytes.Buffer
+	warningOutput = &warnings
+	protectedWarning = sync.Once{}
+	t.Cleanup(func() {
+		warningOutput = os.Stderr
+		protectedWarning = sync.Once{}
+	})
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if got := fmt.Sprint(config.Tools.RequiredTools); got != "[git curl 1password-cli]" {
+		t.Errorf("RequiredTools = %s, want protected tool added", got)
+	}
+	if !strings.Contains(warnings.String(), "1 local setting(s) conflict") {
+		t.Errorf("warning output = %q, want the conflict warning", warnings.String())
+	}
+
+	if err := ValidateProtectedSettings(); err == nil {
+		t.Error("expected a validation error for the local settings conflict")
+	}
+	if err := RestoreProtectedSettings(); err != nil {
+		t.Fatalf("RestoreProtectedSettings failed: %v", err)
+	}
+	if err := ValidateProtectedSettings(); err != nil {
+		t.Errorf("expected no conflicts after restore, got %v", err)
+	}
+}
+
+func TestFindAppConflicts(t *testing.T) {
+	cfg := &AnvilConfig{
+		Tools: AnvilTools{
+			RequiredTools: []string{"git", "curl"},
+			InstalledApps: []string{"Slack", "git", "zoom", "zoom"},
+		},
+		Groups: AnvilGroups{
+			"dev":        {"slack", "docker"},
+			"essentials": {"docker", "firefox"},
+		},
+	}
+
+	conflicts := FindAppConflicts(cfg)
+	var names []string
+	for _, conflict := range conflicts {
+		names = append(names, conflict.Name)
+	}
+	if want := []string{"git", "slack", "zoom"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("FindAppConflicts() = %v, want %v (docker in two groups is allowed)", names, want)
+	}
+
+	slackIssues := strings.Join(conflicts[1].Issues(), "; ")
+	if !strings.Contains(slackIssues, `"Slack", "slack"`) || !strings.Contains(slackIssues, "groups.dev") {
+		t.Errorf("slack issues = %q, want case variants and group coverage", slackIssues)
+	}
+
+	changes := CleanupAppConflicts(cfg)
+	if changes != 4 {
+		t.Errorf("CleanupAppConflicts() changes = %d, want 4", changes)
+	}
+	if want := []string{"zoom"}; !reflect.DeepEqual(cfg.Tools.InstalledApps, want) {
+		t.Errorf("installed apps after cleanup = %v, want %v", cfg.Tools.InstalledApps, want)
+	}
+	if remaining := FindAppConflicts(cfg); len(remaining) != 0 {
+		t.Errorf("conflicts after cleanup = %v, want none", remaining)
+	}
+}
+
+func TestGroupPlatformTags(t *testing.T) {
+	input := "groups:\n  dev:\n    - git\n    - iterm2: {platforms: [macos]}\n  linux:\n    - xclip: {platforms: [linux]}\n"
+
+	var cfg AnvilConfig
+	if err := yaml.Unmarshal([]byte(input), &cfg); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if got := fmt.Sprint(cfg.Groups["dev"]); got != "[git iterm2]" {
+		t.Errorf("Groups[dev] = %s, want tagged entries read by name", got)
+	}
+	if !SupportsPlatform(cfg.ToolPlatforms["iterm2"], "darwin") || SupportsPlatform(cfg.ToolPlatforms["iterm2"], "linux") {
+		t.Errorf("iterm2 platforms = %v, want darwin only", cfg.ToolPlatforms["iterm2"])
+	}
+	if !SupportsPlatform(cfg.ToolPlatforms["git"], "linux") {
+		t.Error("untagged tools should support every platform")
+	}
+
+	data, err := yaml.Marshal(&cfg)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var roundTrip AnvilConfig
+	if err := yaml.Unmarshal(data, &roundTrip); err != nil {
+		t.Fatalf("Unmarshal of marshalled config failed: %v\n%s", err, data)
+	}
+	if !reflect.DeepEqual(roundTrip.Groups, cfg.Groups) || !reflect.DeepEqual(roundTrip.ToolPlatforms, cfg.ToolPlatforms) {
+		t.Errorf("round trip lost platform tags:\n%s", data)
+	}
+
+	if err := yaml.Unmarshal([]byte("groups:\n  dev:\n    - [git]\n"), &cfg); err == nil {
+		t.Error("expected an error for a malformed group entry")
+	}
+}
+
+func TestGroupCriticalTags(t *testing.T) {
+	input := "groups:\n  dev:\n    - git: {critical: true}\n    - fzf: {critical: false, platforms: [macos]}\n    - jq\nfailure_policies:\n  dev: fail-fast\n"
+
+	var cfg AnvilConfig
+	if err := yaml.Unmarshal([]byte(input), &cfg); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	want := map[string]map[string]bool{"dev": {"git": true, "fzf": false}}
+	if !reflect.DeepEqual(cfg.ToolCritical, want) {
+		t.Errorf("ToolCritical = %v, want %v", cfg.ToolCritical, want)
+	}
+
+	data, err := yaml.Marshal(&cfg)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var roundTrip AnvilConfig
+	if err := yaml.Unmarshal(data, &roundTrip); err != nil {
+		t.Fatalf("Unmarshal of marshalled config failed: %v\n%s", err, data)
+	}
+	if !reflect.DeepEqual(roundTrip.ToolCritical, want) || roundTrip.FailurePolicies["dev"] != FailurePolicyFailFast {
+		t.Errorf("round trip lost critical tags or failure policies:\n%s", data)
+	}
+
+	if err := ValidateFailurePolicy("retry"); err == nil {
+		t.Error("expected an error for an unknown failure policy")
+	}
+}
+
+func TestGroupOrderTags(t *testing.T) {
+	input := "groups:\n  dev:\n    - docker-compose: {after: [docker]}\n    - oh-my-zsh: {order: 1}\n    - docker\n    - zsh: {order: -1}\n    - jq\n"
+
+	var cfg AnvilConfig
+	if err := yaml.Unmarshal([]byte(input), &cfg); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	plan, err := cfg.PlanGroupOrder("dev", cfg.Groups["dev"])
+	if err != nil {
+		t.Fatalf("PlanGroupOrder failed: %v", err)
+	}
+	if got := fmt.Sprint(plan.Tools); got != "[zsh docker docker-compose jq oh-my-zsh]" {
+		t.Errorf("Tools = %s, want [zsh docker docker-compose jq oh-my-zsh]", got)
+	}
+	if got := fmt.Sprint(plan.After["docker-compose"]); got != "[docker]" {
+		t.Errorf("After[docker-compose] = %s, want [docker]", got)
+	}
+	if got := fmt.Sprint(plan.WaitFor["jq"]); got != "[zsh]" {
+		t.Errorf("WaitFor[jq] = %s, want [zsh]", got)
+	}
+
+	data, err := yaml.Marshal(&cfg)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var roundTrip AnvilConfig
+	if err := yaml.Unmarshal(data, &roundTrip); err != nil {
+		t.Fatalf("Unmarshal of marshalled config failed: %v\n%s", err, data)
+	}
+	if !reflect.DeepEqual(roundTrip.ToolOrdering, cfg.ToolOrdering) {
+		t.Errorf("round trip lost order tags:\n%s", data)
+	}
+
+	cycle := "groups:\n  dev:\n    - docker: {after: [docker-compose]}\n    - docker-compose: {after: [docker]}\n    - kubectl: {after: [helm]}\n"
+	var cyclic AnvilConfig
+	if err := yaml.Unmarshal([]byte(cycle), &cyclic); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if _, err := cyclic.PlanGroupOrder("dev", cyclic.Groups["dev"]); err == nil {
+		t.Error("expected an error for after tags forming a cycle")
+	}
+	if got := fmt.Sprint(cyclic.UnknownAfterTools("dev")); got != "[helm]" {
+		t.Errorf("UnknownAfterTools = %s, want [helm]", got)
+	}
+}
+
+func TestBrewPolicyEnv(t *testing.T) {
+	disabled, enabled := false, true
+
+	if env := (BrewConfig{Analytics: &enabled}).Env(); len(env) != 0 {
+		t.Errorf("Env() with analytics enabled = %v, want none", env)
+	}
+
+	policy := BrewConfig{Analytics: &disabled, BottleDomain: "https://mirror.example.com/bottles"}
+	want := []string{"HOMEBREW_NO_ANALYTICS=1", "HOMEBREW_BOTTLE_DOMAIN=https://mirror.example.com/bottles"}
+	if got := policy.Env(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Env() = %v, want %v", got, want)
+	}
+}
+
+func TestRenameAppEntries(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	if err := AddAppToGroup("dev", "cask:docker"); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddInstalledApp("Docker"); err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := RenameAppEntries("docker", "docker-desktop")
+	if err != nil {
+		t.Fatalf("RenameAppEntries failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("RenameAppEntries() = %d, want 2", count)
+	}
+
+	InvalidateConfigCache()
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(cfg.Groups["dev"], "cask:docker-desktop") {
+		t.Errorf("groups.dev = %v, want the annotation kept", cfg.Groups["dev"])
+	}
+	if !slices.Contains(cfg.Tools.InstalledApps, "docker-desktop") {
+		t.Errorf("installed_apps = %v, want docker-desktop", cfg.Tools.InstalledApps)
+	}
+}
+
+func TestUntrackApp(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	if err := AddAppToGroup("tools", "cask:Docker"); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddAppToGroup("dev", "docker"); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddInstalledApp("htop"); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, _ := LoadConfig()
+	if got := cfg.GroupsListing("docker"); !slices.Equal(got, []string{"dev", "tools"}) {
+		t.Errorf("GroupsListing(docker) = %v, want [dev tools]", got)
+	}
+
+	untracked, err := UntrackApp("docker", true)
+	if err != nil {
+		t.Fatalf("UntrackApp failed: %v", err)
+	}
+	if want := []string{"groups.dev", "groups.tools"}; !slices.Equal(untracked, want) {
+		t.Errorf("UntrackApp(docker) = %v, want %v", untracked, want)
+	}
+
+	// Without groups only installed_apps changes, required tools are never touched
+	if untracked, _ := UntrackApp("git", false); len(untracked) != 0 {
+		t.Errorf("UntrackApp(git) = %v, want nothing removed", untracked)
+	}
+	if untracked, _ := UntrackApp("htop", false); !slices.Equal(untracked, []string{SectionInstalledApps}) {
+		t.Errorf("UntrackApp(htop) = %v, want [%s]", untracked, SectionInstalledApps)
+	}
+
+	InvalidateConfigCache()
+	cfg, err = LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.AppListings("docker")) != 0 || len(cfg.AppListings("htop")) != 0 {
+		t.Errorf("apps still listed: docker %v, htop %v", cfg.AppListings("docker"), cfg.AppListings("htop"))
+	}
+	if !slices.Contains(cfg.Groups["dev"], constants.PkgGit) || !slices.Contains(cfg.Tools.RequiredTools, constants.PkgGit) {
+		t.Error("git should still be listed in groups.dev and required_tools")
+	}
+}
+
+func TestCheckAppMode(t *testing.T) {
+	cfg := &AnvilConfig{LocalOnly: []string{"work-vpn", "both"}, RepoOnly: []string{"old-laptop", "both"}}
+
+	tests := []struct {
+		app     string
+		op      string
+		blocked bool
+	}{
+		{"zsh", constants.OpPush, false},
+		{"work-vpn", constants.OpPush, true},
+		{"work-vpn", constants.OpPull, true},
+		{"work-vpn", constants.OpSync, true},
+		{"old-laptop", constants.OpPush, true},
+		{"old-laptop", constants.OpPull, false},
+		{"old-laptop", constants.OpSync, false},
+		{"both", constants.OpPull, true},
+	}
+	for _, tt := range tests {
+		if err := cfg.CheckAppMode(tt.app, tt.op); (err != nil) != tt.blocked {
+			t.Errorf("CheckAppMode(%s, %s) = %v, want blocked %v", tt.app, tt.op, err, tt.blocked)
+		}
+	}
+
+	if got := cfg.AppsInBothModes(); !slices.Equal(got, []string{"both"}) {
+		t.Errorf("AppsInBothModes() = %v, want [both]", got)
+	}
+}
+
+func TestIsManagedExternally(t *testing.T) {
+	cfg := &AnvilConfig{ManagedExternally: []string{"Slack", "google-chrome"}}
+
+	tests := []struct {
+		app     string
+		managed bool
+	}{
+		{"slack", true},
+		{"homebrew/cask/slack", true},
+		{"google-chrome", true},
+		{"git", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := cfg.IsManagedExternally(tt.app); got != tt.managed {
+			t.Errorf("IsManagedExternally(%q) = %v, want %v", tt.app, got, tt.managed)
+		}
+	}
+
+	if got := cfg.ExcludeManagedExternally([]string{"git", "slack", "jq"}); !slices.Equal(got, []string{"git", "jq"}) {
+		t.Errorf("ExcludeManagedExternally() = %v, want [git jq]", got)
+	}
+}
+
+func TestSettingsBackupRing(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.SettingsBackups = 3
+	for _, version := range []string{"1.0.0", "1.1.0", "1.2.0", "1.3.0", "1.4.0"} {
+		cfg.Version = version
+		if err := SaveConfig(cfg); err != nil {
+			t.Fatalf("SaveConfig failed: %v", err)
+		}
+	}
+
+	backups, err := ListSettingsBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 3 {
+		t.Fatalf("kept %d backups, want 3", len(backups))
+	}
+	if data, _ := os.ReadFile(backups[0].Path); !strings.Contains(string(data), "version: 1.3.0") {
+		t.Errorf("newest backup should hold the version replaced last, got:\n%s", data)
+	}
+
+	// Saving unchanged settings adds no backup
+	if err := SaveConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := ListSettingsBackups(); len(again) != 3 || again[0].Path != backups[0].Path {
+		t.Errorf("unchanged saves should not add backups, got %v", again)
+	}
+
+	// A corrupted file is reported as such and restored from the newest valid backup
+	os.WriteFile(GetAnvilConfigPath(), []byte("version: [unclosed\n"), 0644)
+	if _, err := LoadConfig(); !IsCorruptSettings(err) {
+		t.Fatalf("LoadConfig of broken YAML = %v, want a CorruptSettingsError", err)
+	}
+	backup, ok := LatestValidSettingsBackup()
+	if !ok {
+		t.Fatal("expected a valid backup")
+	}
+	if _, err := RestoreSettingsBackup(backup.Version); err != nil {
+		t.Fatalf("RestoreSettingsBackup failed: %v", err)
+	}
+	restored, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig after restore failed: %v", err)
+	}
+	if restored.Version != "1.3.0" {
+		t.Errorf("restored version = %s, want 1.3.0", restored.Version)
+	}
+
+	// The corrupted file was backed up before being replaced
+	if backups, _ = ListSettingsBackups(); len(backups) == 0 {
+		t.Fatal("expected backups after restore")
+	} else if data, _ := os.ReadFile(backups[0].Path); !strings.Contains(string(data), "unclosed") {
+		t.Errorf("newest backup should be the replaced corrupted file, got:\n%s", data)
+	}
+}
+
+func TestSettingsBackupFailureWarns(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	var warnings bytes.Buffer
+	warningOutput = &warnings
+	t.Cleanup(func() { warningOutput = os.Stderr })
+
+	// A file where the backup directory belongs makes every backup fail
+	backupDir := GetSettingsBackupDirectory()
+	os.MkdirAll(filepath.Dir(backupDir), 0755)
+	if err := os.WriteFile(backupDir, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.SettingsBackups = 3
+	cfg.Version = "2.0.0"
+	if err := SaveConfig(cfg); err != nil {
+		t.Fatalf("a failed backup should not block the write: %v", err)
+	}
+	if !strings.Contains(warnings.String(), "Could not back up") {
+		t.Errorf("warning output = %q, want the backup warning", warnings.String())
+	}
+}
+
+func TestVersionRequirement(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+	defer version.SetVersion(version.GetVersion())
+
+	if requirement, err := GetVersionRequirement(); err != nil || requirement.Minimum != "" {
+		t.Fatalf("GetVersionRequirement() = %+v, %v, want no requirement", requirement, err)
+	}
+
+	teamPath := filepath.Join(GetAnvilConfigDirectory(), constants.TEAM_CONFIG_FILE)
+	if err := os.WriteFile(teamPath, []byte("min_anvil_version: 1.4.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := createTestConfig()
+	config.MinAnvilVersion = "v1.5.2"
+	if err := SaveConfig(config); err != nil {
+		t.Fatal(err)
+	}
+
+	requirement, err := GetVersionRequirement()
+	if err != nil || requirement.Minimum != "v1.5.2" || requirement.Source != GetAnvilConfigPath() {
+		t.Fatalf("GetVersionRequirement() = %+v, %v, want the higher settings.yaml minimum", requirement, err)
+	}
+
+	version.SetVersion("v1.5.1")
+	var tooOld *VersionTooOldError
+	if err := CheckVersionRequirement(); !errors.As(err, &tooOld) || tooOld.Current != "v1.5.1" {
+		t.Errorf("CheckVersionRequirement() = %v, want a VersionTooOldError", err)
+	}
+	version.SetVersion("1.6.0")
+	if err := CheckVersionRequirement(); err != nil {
+		t.Errorf("CheckVersionRequirement() = %v, want nil for a newer release", err)
+	}
+	version.SetVersion("dev-local")
+	if err := CheckVersionRequirement(); err != nil {
+		t.Errorf("CheckVersionRequirement() = %v, want nil for a development build", err)
+	}
+
+	config.MinAnvilVersion = "latest"
+	if err := SaveConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetVersionRequirement(); err == nil {
+		t.Error("GetVersionRequirement() accepted an invalid min_anvil_version")
+	}
+}
+
+func TestCategoryOf(t *testing.T) {
+	tests := map[string]string{
+		"git":                            "dev-tools",
+		"Slack":                          "communication",
+		"cask:firefox@developer-edition": "browsers",
+		"nikitabobko/tap/aerospace":      "productivity",
+		"python@3.12":                    "dev-tools",
+		"mas:497799835":                  "",
+		"some-internal-tool":             "",
+	}
+	for app, want := range tests {
+		if got, _ := CategoryOf(app); got != want {
+			t.Errorf("CategoryOf(%q) = %q, want %q", app, got, want)
+		}
+	}
+}
+
+func TestSuggestGroups(t *testing.T) {
+	config := &AnvilConfig{
+		Groups: AnvilGroups{"browsers": {"firefox"}},
+		Tools: AnvilTools{
+			InstalledApps: []string{"slack", "google-chrome", "Firefox", "git", "some-internal-tool", "zoom", "spotify"},
+		},
+	}
+
+	suggestions, uncategorized := SuggestGroups(config)
+	want := []GroupSuggestion{
+		{Group: "dev-tools", Title: "Developer tools", Apps: []string{"git"}},
+		{Group: "browsers", Title: "Browsers", Apps: []string{"google-chrome"}, Existing: true},
+		{Group: "communication", Title: "Communication", Apps: []string{"slack", "zoom"}},
+		{Group: "media", Title: "Media & design", Apps: []string{"spotify"}},
+	}
+	if !reflect.DeepEqual(suggestions, want) {
+		t.Errorf("SuggestGroups() = %+v, want %+v", suggestions, want)
+	}
+	if !reflect.DeepEqual(uncategorized, []string{"some-internal-tool"}) {
+		t.Errorf("uncategorized = %v, want [some-internal-tool]", uncategorized)
+	}
+
+	// Accepting a renamed suggestion and an existing group moves the apps out of installed_apps
+	suggestions[2].Group = "chat"
+	ApplyGroupSuggestions(config, []GroupSuggestion{suggestions[1], suggestions[2]})
+
+	if !reflect.DeepEqual(config.Groups["browsers"], []string{"firefox", "google-chrome"}) {
+		t.Errorf("browsers = %v, want [firefox google-chrome]", config.Groups["browsers"])
+	}
+	if !reflect.DeepEqual(config.Groups["chat"], []string{"slack", "zoom"}) {
+		t.Errorf("chat = %v, want [slack zoom]", config.Groups["chat"])
+	}
+	wantApps := []string{"Firefox", "git", "some-internal-tool", "spotify"}
+	if !reflect.DeepEqual(config.Tools.InstalledApps, wantApps) {
+		t.Errorf("installed_apps = %v, want %v", config.Tools.InstalledApps, wantApps)
+	}
+}
+
+func TestTempEntries(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	tempDir := GetTempDirectory()
+	for _, name := range []string{"zsh", "nvim", PreviousPullDir, ".staging-nvim-1"} {
+		if err := os.MkdirAll(filepath.Join(tempDir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(tempDir, PreviousPullDir, "nvim"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	if err := RecordTempPull(TempEntry{Name: "nvim", PulledAt: now.Add(-45 * 24 * time.Hour), Repo: "user/dotfiles", Branch: "main", Commit: "0123456789abcdef"}); err != nil {
+		t.Fatalf("RecordTempPull failed: %v", err)
+	}
+
+	entries, err := ListTempEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Name != "nvim" || entries[1].Name != "zsh" {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+	if !entries[0].Recorded || entries[0].ShortCommit() != "0123456" || entries[0].Branch != "main" {
+		t.Errorf("metadata not read back: %+v", entries[0])
+	}
+	if entries[1].Recorded || entries[1].ShortCommit() != "unknown" {
+		t.Errorf("copies without metadata should fall back to the directory time: %+v", entries[1])
+	}
+
+	stale, err := StaleTempEntries(TempConfig{}.MaxAge(), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stale) != 1 || stale[0].Name != "nvim" {
+		t.Fatalf("expected only nvim to be stale, got %+v", stale)
+	}
+
+	if err := RemoveTempEntry("nvim"); err != nil {
+		t.Fatalf("RemoveTempEntry failed: %v", err)
+	}
+	for _, path := range []string{filepath.Join(tempDir, "nvim"), filepath.Join(tempDir, PreviousPullDir, "nvim"), getTempMetadataPath("nvim")} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s should have been removed", path)
+		}
+	}
+	if _, found, _ := GetTempEntry("zsh"); !found {
+		t.Error("zsh should be kept")
+	}
+
+	if got := (TempConfig{MaxAgeDays: 7}).MaxAge(); got != 7*24*time.Hour {
+		t.Errorf("MaxAge() = %v, want 7 days", got)
+	}
+}
+
+func TestSettingsTransactions(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+	SetTransactionCommand("anvil install git")
+	defer SetTransactionCommand("")
+
+	original, err := os.ReadFile(GetAnvilConfigPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := AddInstalledApp("htop"); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddAppToGroup("dev", "jq"); err != nil {
+		t.Fatal(err)
+	}
+
+	transactions, err := ListTransactions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transactions) != 2 {
+		t.Fatalf("recorded %d transactions, want 2", len(transactions))
+	}
+	if transactions[0].Command != "anvil install git" || transactions[0].Summary != "groups.dev" {
+		t.Errorf("unexpected newest transaction: %q %q", transactions[0].Command, transactions[0].Summary)
+	}
+	if transactions[1].Summary != "tools.installed_apps" {
+		t.Errorf("unexpected summary %q", transactions[1].Summary)
+	}
+
+	undone, err := UndoTransactions(1, false)
+	if err != nil || len(undone) != 1 {
+		t.Fatalf("UndoTransactions failed: %v", err)
+	}
+	if current, _ := os.ReadFile(GetAnvilConfigPath()); string(current) != transactions[0].Before {
+		t.Error("undo should restore the settings before the change")
+	}
+
+	// Undos are not undone, the next undo walks back to the original settings
+	if _, err := UndoTransactions(1, false); err != nil {
+		t.Fatalf("second undo failed: %v", err)
+	}
+	if current, _ := os.ReadFile(GetAnvilConfigPath()); !bytes.Equal(current, original) {
+		t.Error("two undos should restore the original settings")
+	}
+	if undoable, _ := UndoableTransactions(); len(undoable) != 0 {
+		t.Errorf("expected nothing left to undo, got %d", len(undoable))
+	}
+
+	// Edits made outside anvil block an undo unless forced
+	if err := AddInstalledApp("zoom"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(GetAnvilConfigPath(), append(original, []byte("\n# edited by hand\n")...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UndoTransactions(1, false); err == nil {
+		t.Error("expected undo to refuse after a manual edit")
+	}
+	if _, err := UndoTransactions(1, true); err != nil {
+		t.Errorf("forced undo failed: %v", err)
+	}
+}
+
+func TestParseSettingsBySection(t *testing.T) {
+	data := []byte(`version: 1.2.0
+tools:
+  required_tools: [git]
+  installed_apps: [htop
+git:
+  username: octocat
+`)
+	cfg, problems := ParseSettingsBySection(data)
+	if cfg.Version != "1.2.0" || cfg.Git.Username != "octocat" {
+		t.Errorf("sections that parse should be kept, got version %q and git user %q", cfg.Version, cfg.Git.Username)
+	}
+	if len(cfg.Groups) == 0 {
+		t.Error("missing groups should come from the sample settings")
+	}
+
+	found := make(map[string]SectionProblem)
+	for _, problem := range problems {
+		found[problem.Section] = problem
+	}
+	if problem, ok := found["tools"]; !ok || problem.Err == nil || !strings.Contains(problem.Err.Error(), "line 4") {
+		t.Errorf("expected a syntax error for tools with its line in the file, got %+v", problem)
+	}
+	if problem, ok := found["groups"]; !ok || problem.Err != nil {
+		t.Errorf("expected groups reported as missing, got %+v", problem)
+	}
+
+	// A well-formed document with a wrongly typed section still keeps the rest
+	_, problems = ParseSettingsBySection([]byte("version: 1.2.0\ntools: [git]\ngroups:\n  dev: [git]\n"))
+	if len(problems) != 1 || problems[0].Section != "tools" || problems[0].Err == nil {
+		t.Errorf("expected a type error for tools only, got %+v", problems)
+	}
+}
+
+func TestSafeModeLoadConfig(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+	defer func() {
+		safeMode.Lock()
+		safeMode.enabled = false
+		safeMode.Unlock()
+		invalidateCache()
+	}()
+
+	corrupt := []byte("version: 1.2.0\ngroups:\n  dev: [git\n")
+	if err := os.WriteFile(GetAnvilConfigPath(), corrupt, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(); !IsCorruptSettings(err) {
+		t.Fatalf("expected corrupt settings error, got %v", err)
+	}
+
+	EnableSafeMode()
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig in safe mode failed: %v", err)
+	}
+	if cfg.Version != "1.2.0" || len(cfg.Groups) == 0 {
+		t.Errorf("safe mode should keep version and fall back to default groups, got %+v", cfg.Groups)
+	}
+	if err := SaveConfig(cfg); err == nil {
+		t.Error("SaveConfig should refuse in safe mode")
+	}
+	if current, _ := os.ReadFile(GetAnvilConfigPath()); !bytes.Equal(current, corrupt) {
+		t.Error("the corrupt file must not be overwritten in safe mode")
+	}
+}
+
+func TestConfigRemotes(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+	defer UseRemote("")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	cfg.GitHub.ConfigRepo = "me/dotfiles"
+	cfg.GitHub.Remotes = map[string]GitHubRemote{
+		"work":     {ConfigRepo: "https://github.com/company/dotfiles.git", Branch: "master"},
+		"personal": {ConfigRepo: "me/other", LocalPath: "/tmp/personal"},
+	}
+	if err := SaveConfig(cfg); err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+
+	if err := UseRemote("missing"); err == nil || !strings.Contains(err.Error(), "personal, work") {
+		t.Errorf("UseRemote(missing) = %v, want error listing the remotes", err)
+	}
+	if err := UseRemote("work"); err != nil {
+		t.Fatalf("UseRemote failed: %v", err)
+	}
+
+	cfg, err = LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	wantPath := filepath.Join(GetAnvilConfigDirectory(), "dotfiles-work")
+	if cfg.GitHub.ConfigRepo != "company/dotfiles" || cfg.GitHub.Branch != "master" || cfg.GitHub.LocalPath != wantPath {
+		t.Errorf("github = %+v, want the work remote", cfg.GitHub)
+	}
+	if cfg.GitHub.TokenEnvVar != "GITHUB_TOKEN" {
+		t.Errorf("TokenEnvVar = %q, want fallback to the github section", cfg.GitHub.TokenEnvVar)
+	}
+
+	// Saving while a remote is active keeps the github section as written
+	if err := SaveConfig(cfg); err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+	if err := UseRemote(""); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.GitHub.ConfigRepo != "me/dotfiles" || cfg.GitHub.Branch != "main" || len(cfg.GitHub.Remotes) != 2 {
+		t.Errorf("github = %+v, want the section as written", cfg.GitHub)
+	}
+}
+
+func TestValidateHooks(t *testing.T) {
+	valid := map[string]HookConfig{
+		"docker": {PostInstall: "docker --version", Timeout: "2m", OnFailure: HookFailureAbort},
+		"jq":     {PreInstall: "true"},
+	}
+	if err := ValidateHooks(valid); err != nil {
+		t.Errorf("ValidateHooks failed: %v", err)
+	}
+	if got := valid["jq"].TimeoutDuration(); got != DefaultHookTimeout {
+		t.Errorf("TimeoutDuration = %v, want default %v", got, DefaultHookTimeout)
+	}
+
+	if err := ValidateHooks(map[string]HookConfig{"jq": {Timeout: "soon"}}); err == nil {
+		t.Error("expected an error for an invalid timeout")
+	}
+	if err := ValidateHooks(map[string]HookConfig{"jq": {OnFailure: "retry"}}); err == nil {
+		t.Error("expected an error for an unknown failure policy")
+	}
+}
+
+func TestValidateEcosystemTools(t *testing.T) {
+	valid := map[string]EcosystemTool{
+		"golangci-lint": {Installer: EcosystemGo, Package: "github.com/golangci/golangci-lint/cmd/golangci-lint", Version: "v1.59.1"},
+		"tldr":          {Installer: EcosystemNpm},
+	}
+	if err := ValidateEcosystemTools(valid); err != nil {
+		t.Errorf("ValidateEcosystemTools failed: %v", err)
+	}
+	if got := valid["tldr"].PackageName("tldr"); got != "tldr" {
+		t.Errorf("PackageName = %q, want the tool name", got)
+	}
+
+	if err := ValidateEcosystemTools(map[string]EcosystemTool{"rg": {Installer: "brew"}}); err == nil {
+		t.Error("expected an error for an unknown installer")
+	}
+	if err := ValidateEcosystemTools(map[string]EcosystemTool{"gopls": {Installer: EcosystemGo}}); err == nil {
+		t.Error("expected an error for a go tool without a package path")
+	}
+}
+
+func TestAppVersionRequirements(t *testing.T) {
+	valid := map[string]AppVersionRequirement{
+		"obsidian": {MinAppVersion: "1.5.0", OnMismatch: AppVersionBlock},
+		"cursor":   {MinAppVersion: "0.40"},
+	}
+	if err := ValidateAppVersions(valid); err != nil {
+		t.Errorf("ValidateAppVersions failed: %v", err)
+	}
+	if !valid["obsidian"].Blocks() || valid["cursor"].Blocks() {
+		t.Error("only the block policy should stop a sync")
+	}
+	if err := ValidateAppVersions(map[string]AppVersionRequirement{"obsidian": {MinAppVersion: "latest"}}); err == nil {
+		t.Error("expected an error for an invalid minimum version")
+	}
+	if err := ValidateAppVersions(map[string]AppVersionRequirement{"obsidian": {MinAppVersion: "1.5", OnMismatch: "ask"}}); err == nil {
+		t.Error("expected an error for an unknown mismatch policy")
+	}
+
+	tests := []struct {
+		installed, minimum string
+		want               bool
+	}{
+		{"1.5.12_1", "1.5.0", true},
+		{"1.3.7", "1.5.0", false},
+		{"2024.1,241.14", "2023.3", true},
+		{"1.5.0.4", "1.5.0", true},
+	}
+	for _, tt := range tests {
+		got, err := SatisfiesAppVersion(tt.installed, tt.minimum)
+		if err != nil || got != tt.want {
+			t.Errorf("SatisfiesAppVersion(%q, %q) = %v, %v, want %v", tt.installed, tt.minimum, got, err, tt.want)
+		}
+	}
+}
+
+func TestReplaceConfigsSectionKeepsComments(t *testing.T) {
+	settings := `# My anvil settings
+version: "2"
+# Apps whose configs are synced
+configs:
+  # Editor
+  nvim: /home/me/.config/nvim # lua config
+  zsh: /home/me/.zshrc
+  old: /home/me/old
+git:
+  username: me # set by init
+`
+	data, err := replaceConfigsSection([]byte(settings), map[string]string{
+		"nvim":    "/home/me/.config/nvim",
+		"zsh":     "/home/me/.config/zsh/.zshrc",
+		"ghostty": "/home/me/.config/ghostty",
+	})
+	if err != nil {
+		t.Fatalf("replaceConfigsSection failed: %v", err)
+	}
+	out := string(data)
+	for _, want := range []string{"# My anvil settings", "# Apps whose configs are synced", "# Editor",
+		"nvim: /home/me/.config/nvim # lua config", "zsh: /home/me/.config/zsh/.zshrc",
+		"ghostty: /home/me/.config/ghostty", "username: me # set by init"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "old") {
+		t.Errorf("removed entry still present:\n%s", out)
+	}
+
+	var cfg AnvilConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil || len(cfg.Configs) != 3 || cfg.Git.Username != "me" {
+		t.Errorf("rewritten settings load as %+v, %v", cfg.Configs, err)
+	}
+
+	data, err = replaceConfigsSection([]byte("version: \"2\"\n"), map[string]string{})
+	if err != nil || !strings.Contains(string(data), "configs: {}") {
+		t.Errorf("empty configs section = %q, %v", data, err)
+	}
+}
+
+func TestConfigPathHelpers(t *testing.T) {
+	homeDir, _ := system.GetHomeDir()
+	if got := ExpandConfigPath(" ~/.config/nvim/ "); got != filepath.Join(homeDir, ".config", "nvim") {
+		t.Errorf("ExpandConfigPath = %q", got)
+	}
+	if problem := CheckConfigPath(t.TempDir()); problem != "" {
+		t.Errorf("existing directory reported as %q", problem)
+	}
+	if problem := CheckConfigPath("relative/path"); problem == "" {
+		t.Error("relative paths should be rejected")
+	}
+	for _, name := range []string{"", "my app", "a/b", "anvil"} {
+		if ValidateConfigAppName(name) == nil {
+			t.Errorf("ValidateConfigAppName(%q) should fail", name)
+		}
+	}
+}
+
+func TestMergeSettings(t *testing.T) {
+	var base, overlay yaml.MapSlice
+	if err := yaml.Unmarshal([]byte(`
+git:
+  username: me
+  email: me@example.com
+tools:
+  required_tools: [git, curl]
+configs:
+  nvim: /home/me/.config/nvim
+`), &base); err != nil {
+		t.Fatal(err)
+	}
+	if err := yaml.Unmarshal([]byte(`
+git:
+  email: me@work.example.com
+tools:
+  required_tools: [git]
+configs:
+  vpn: /etc/vpn
+settings_backups: 3
+`), &overlay); err != nil {
+		t.Fatal(err)
+	}
+
+	merged := MergeSettings(base, overlay)
+	for key, want := range map[string]string{
+		"git.username":         "me",
+		"git.email":            "me@work.example.com",
+		"tools.required_tools": "[git]",
+		"configs.nvim":         "/home/me/.config/nvim",
+		"configs.vpn":          "/etc/vpn",
+		"settings_backups":     "3",
+	} {
+		got, found := lookupMapSlice(merged, strings.Split(key, "."))
+		if !found || fmt.Sprint(got) != want {
+			t.Errorf("%s = %v, want %s", key, got, want)
+		}
+	}
+	if email, _ := lookupMapSlice(base, []string{"git", "email"}); email != "me@example.com" {
+		t.Errorf("base was modified, git.email = %v", email)
+	}
+}
+
+func TestLoadConfigMergesLocalOverlay(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	overlay := "git:\n  email: me@work.example.com\nconfigs:\n  vpn: /etc/vpn\n"
+	if err := os.WriteFile(GetLocalOverlayPath(), []byte(overlay), 0644); err != nil {
+		t.Fatal(err)
+	}
+	teamPath := filepath.Join(GetAnvilConfigDirectory(), constants.TEAM_CONFIG_FILE)
+	if err := os.WriteFile(teamPath, []byte("protected:\n  git:\n    email: me@team.example.com\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.Git.Email != "me@team.example.com" {
+		t.Errorf("Git.Email = %s, protected settings should win over the overlay", cfg.Git.Email)
+	}
+	if cfg.Git.Username != "Test User" || cfg.Configs["vpn"] != "/etc/vpn" {
+		t.Errorf("overlay not merged: username %q, configs %v", cfg.Git.Username, cfg.Configs)
+	}
+
+	if err := os.Remove(teamPath); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.Git.Email != "me@work.example.com" {
+		t.Errorf("Git.Email = %s, want the overlay value", cfg.Git.Email)
+	}
+
+	// Saving keeps overlay values out of settings.yaml, other changes are written
+	cfg.Configs["nvim"] = "/home/me/.config/nvim"
+	if err := SaveConfig(cfg); err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+	data, err := os.ReadFile(GetAnvilConfigPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved AnvilConfig
+	if err := yaml.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved.Git.Email != "test@example.com" {
+		t.Errorf("settings.yaml git.email = %s, want the value from before the overlay", saved.Git.Email)
+	}
+	if _, found := saved.Configs["vpn"]; found {
+		t.Error("settings.yaml picked up the overlay configs entry")
+	}
+	if saved.Configs["nvim"] != "/home/me/.config/nvim" {
+		t.Errorf("settings.yaml configs = %v, want the new nvim entry", saved.Configs)
+	}
+}
+
+func TestAppSources(t *testing.T) {
+	for _, tt := range []struct {
+		url   string
+		valid bool
+	}{
+		{"https://example.com/App.dmg", true},
+		{"http://downloads.example.com/tool.tar.gz", false},
+		{"ftp://example.com/App.dmg", false},
+		{"example.com/App.dmg", false},
+		{"https:///App.dmg", false},
+		{"curl -fsSL https://example.com/install.sh | sh", false},
+	} {
+		if err := ValidateSourceURL(tt.url); (err == nil) != tt.valid {
+			t.Errorf("ValidateSourceURL(%q) = %v, want valid %v", tt.url, err, tt.valid)
+		}
+	}
+
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	if err := SetAppSource("obscure-app", " https://example.com/Obscure.dmg "); err != nil {
+		t.Fatalf("SetAppSource failed: %v", err)
+	}
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if got := cfg.Sources["obscure-app"]; got != "https://example.com/Obscure.dmg" {
+		t.Errorf("Sources[obscure-app] = %q", got)
+	}
+}
+
+func TestNewGroupTools(t *testing.T) {
+	for _, mode := range []string{"", NewGroupToolsPrompt, NewGroupToolsAuto, NewGroupToolsOff} {
+		if err := ValidateNewGroupTools(mode); err != nil {
+			t.Errorf("ValidateNewGroupTools(%q) = %v", mode, err)
+		}
+	}
+	if err := ValidateNewGroupTools("always"); err == nil {
+		t.Error("ValidateNewGroupTools(\"always\") should fail")
+	}
+
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	if err := RecordInstalledGroup("dev", []string{"git", "Visual-Studio-Code"}); err != nil {
+		t.Fatalf("RecordInstalledGroup failed: %v", err)
+	}
+	lock, err := LoadGroupsLock()
+	if err != nil {
+		t.Fatalf("LoadGroupsLock failed: %v", err)
+	}
+	if got := lock.Groups["dev"].Tools; !reflect.DeepEqual(got, []string{"git", "Visual-Studio-Code"}) {
+		t.Errorf("locked tools = %v", got)
+	}
+
+	groups := AnvilGroups{
+		"dev":        {"git", "visual-studio-code", "jq", "fzf"},
+		"essentials": {"slack"},
+	}
+	want := map[string][]string{"dev": {"jq", "fzf"}}
+	if got := NewGroupTools(groups, lock); !reflect.DeepEqual(got, want) {
+		t.Errorf("NewGroupTools() = %v, want %v", got, want)
+	}
+
+	// Groups removed from settings are not reported
+	if got := NewGroupTools(AnvilGroups{"essentials": {"slack"}}, lock); len(got) != 0 {
+		t.Errorf("NewGroupTools() = %v, want none", got)
+	}
+}
+
+func TestConfigFilters(t *testing.T) {
+	settings := `configs:
+  nvim: /home/me/.config/nvim
+  obsidian:
+    path: /home/me/Notes/.obsidian
+    include: ["*.json", "plugins/**/main.js"]
+    exclude: [.DS_Store]
+`
+	var cfg AnvilConfig
+	if err := yaml.Unmarshal([]byte(settings), &cfg); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if cfg.Configs["nvim"] != "/home/me/.config/nvim" || cfg.Configs["obsidian"] != "/home/me/Notes/.obsidian" {
+		t.Errorf("Configs = %v", cfg.Configs)
+	}
+	want := ConfigFilter{Include: []string{"*.json", "plugins/**/main.js"}, Exclude: []string{".DS_Store"}}
+	if !reflect.DeepEqual(cfg.ConfigFilters, map[string]ConfigFilter{"obsidian": want}) {
+		t.Errorf("ConfigFilters = %+v", cfg.ConfigFilters)
+	}
+
+	// Filters are written back onto their entries, plain entries stay plain
+	data, err := yaml.Marshal(&cfg)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !strings.Contains(string(data), "nvim: /home/me/.config/nvim") {
+		t.Errorf("plain entry not kept as a path:\n%s", data)
+	}
+	var reloaded AnvilConfig
+	if err := yaml.Unmarshal(data, &reloaded); err != nil {
+		t.Fatalf("Unmarshal of marshalled settings failed: %v", err)
+	}
+	if !reflect.DeepEqual(reloaded.ConfigFilters, cfg.ConfigFilters) || !reflect.DeepEqual(reloaded.Configs, cfg.Configs) {
+		t.Errorf("round trip lost entries: %v %+v", reloaded.Configs, reloaded.ConfigFilters)
+	}
+
+	// Editing the path of a filtered entry keeps its patterns
+	data, err = replaceConfigsSection([]byte(settings), map[string]string{"obsidian": "/home/me/Vault/.obsidian"})
+	if err != nil {
+		t.Fatalf("replaceConfigsSection failed: %v", err)
+	}
+	reloaded = AnvilConfig{}
+	if err := yaml.Unmarshal(data, &reloaded); err != nil {
+		t.Fatalf("Unmarshal of edited settings failed: %v", err)
+	}
+	if reloaded.Configs["obsidian"] != "/home/me/Vault/.obsidian" || !reflect.DeepEqual(reloaded.ConfigFilters["obsidian"], want) {
+		t.Errorf("edited entry = %q %+v", reloaded.Configs["obsidian"], reloaded.ConfigFilters["obsidian"])
+	}
+
+	if err := ValidateConfigFilters(map[string]ConfigFilter{"obsidian": {Exclude: []string{"[.DS_Store"}}}); err == nil {
+		t.Error("ValidateConfigFilters should reject a malformed pattern")
+	}
+	if !want.Skips(".DS_Store", false) || !want.Skips("notes.md", false) || want.Skips("plugins/git/main.js", false) || want.Skips("plugins", true) {
+		t.Error("Skips does not follow the include and exclude patterns")
+	}
+}
+
+func TestPushBases(t *testing.T) {
+	_, cleanup := setupTestConfig(t)
+	defer cleanup()
+
+	if _, found, err := GetPushBase("nvim", "me/dotfiles", "main"); err != nil || found {
+		t.Fatalf("GetPushBase() before any record = %v, %v", found, err)
+	}
+
+	pushed := PushBase{Repo: "me/dotfiles", Branch: "main", Commit: "c1", Tree: "t1", PushedTree: "t2"}
+	if err := RecordPushedBase("nvim", pushed); err != nil {
+		t.Fatalf("RecordPushedBase failed: %v", err)
+	}
+
+	// A pull moves the base but keeps the tree of the push, its pull request may be open
+	if err := RecordPulledBase("nvim", "me/dotfiles", "main", "c2", "t1"); err != nil {
+		t.Fatalf("RecordPulledBase failed: %v", err)
+	}
+	base, found, err := GetPushBase("nvim", "me/dotfiles", "main")
+	if err != nil || !found {
+		t.Fatalf("GetPushBase() = %v, %v", found, err)
+	}
+	if base.Commit != "c2" || base.Tree != "t1" || base.PushedTree != "t2" {
+		t.Errorf("base after pull = %+v", base)
+	}
+
+	// Bases recorded against another repository or branch don't apply
+	if _, found, _ := GetPushBase("nvim", "me/dotfiles", "dev"); found {
+		t.Error("a base from another branch should not be found")
+	}
+	if err := RecordPulledBase("nvim", "me/work", "main", "c3", "t3"); err != nil {
+		t.Fatalf("RecordPulledBase failed: %v", err)
+	}
+	if base, _, _ := GetPushBase("nvim", "me/work", "main"); base.PushedTree != "" {
+		t.Errorf("pushed tree of another repository was kept: %+v", base)
+	}
+}
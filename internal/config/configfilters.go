@@ -0,0 +1,169 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/0xjuanma/anvil/internal/utils"
+	"gopkg.in/yaml.v2"
+)
+
+// ConfigFilter limits the files of an app's config directory that push, pull and sync handle.
+// Patterns are globs relative to the directory, see utils.MatchesExclude.
+type ConfigFilter struct {
+	Include []string `yaml:"include,omitempty"` // When set, only matching files
+	Exclude []string `yaml:"exclude,omitempty"` // Files and directories that are never handled
+}
+
+// IsEmpty reports whether the filter lets every file through
+func (f ConfigFilter) IsEmpty() bool {
+	return len(f.Include) == 0 && len(f.Exclude) == 0
+}
+
+// Skips reports whether the filter leaves out a path relative to the config directory
+func (f ConfigFilter) Skips(relPath string, isDir bool) bool {
+	return utils.SkipsPath(relPath, isDir, f.Include, f.Exclude)
+}
+
+// configEntry is a configs entry written either as a plain path or as a mapping with filters:
+//
+//	configs:
+//	  nvim: /Users/me/.config/nvim
+//	  obsidian:
+//	    path: /Users/me/Notes/.obsidian
+//	    include: ["*.json", "plugins/**/main.js"]
+//	    exclude: [".DS_Store", "workspace.json"]
+type configEntry struct {
+	Path   string
+	Filter ConfigFilter
+}
+
+// UnmarshalYAML accepts both the plain and the filtered form of a configs entry
+func (e *configEntry) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var configPath string
+	if err := unmarshal(&configPath); err == nil {
+		e.Path = configPath
+		return nil
+	}
+
+	var filtered struct {
+		Path         string `yaml:"path"`
+		ConfigFilter `yaml:",inline"`
+	}
+	if err := unmarshal(&filtered); err != nil {
+		return fmt.Errorf("configs entries must be a path or '{path: ..., include: [...], exclude: [...]}'")
+	}
+	e.Path = filtered.Path
+	e.Filter = filtered.ConfigFilter
+	return nil
+}
+
+// MarshalYAML writes unfiltered entries back as plain paths
+func (e configEntry) MarshalYAML() (interface{}, error) {
+	if e.Filter.IsEmpty() {
+		return e.Path, nil
+	}
+	entry := yaml.MapSlice{{Key: "path", Value: e.Path}}
+	if len(e.Filter.Include) > 0 {
+		entry = append(entry, yaml.MapItem{Key: "include", Value: e.Filter.Include})
+	}
+	if len(e.Filter.Exclude) > 0 {
+		entry = append(entry, yaml.MapItem{Key: "exclude", Value: e.Filter.Exclude})
+	}
+	return entry, nil
+}
+
+// UnmarshalYAML reads configs entries by path, filters are collected separately by AnvilConfig
+func (c *AnvilConfigs) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw map[string]configEntry
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	*c = make(AnvilConfigs, len(raw))
+	for app, entry := range raw {
+		(*c)[app] = entry.Path
+	}
+	return nil
+}
+
+// collectConfigFilters returns the filters of the configs entries in a settings document
+func collectConfigFilters(unmarshal func(interface{}) error) (map[string]ConfigFilter, error) {
+	var entries struct {
+		Configs map[string]configEntry `yaml:"configs"`
+	}
+	if err := unmarshal(&entries); err != nil {
+		return nil, err
+	}
+
+	var filters map[string]ConfigFilter
+	for app, entry := range entries.Configs {
+		if entry.Filter.IsEmpty() {
+			continue
+		}
+		if filters == nil {
+			filters = make(map[string]ConfigFilter)
+		}
+		filters[app] = entry.Filter
+	}
+	return filters, nil
+}
+
+// filteredConfigs returns the configs section with filters written back onto their entries
+func (c AnvilConfig) filteredConfigs() yaml.MapSlice {
+	apps := make([]string, 0, len(c.Configs))
+	for app := range c.Configs {
+		apps = append(apps, app)
+	}
+	sort.Strings(apps)
+
+	configs := make(yaml.MapSlice, 0, len(apps))
+	for _, app := range apps {
+		configs = append(configs, yaml.MapItem{Key: app, Value: configEntry{Path: c.Configs[app], Filter: c.ConfigFilters[app]}})
+	}
+	return configs
+}
+
+// configEntryPath returns the path of a configs entry read as raw YAML
+func configEntryPath(value interface{}) string {
+	if entry, ok := value.(yaml.MapSlice); ok {
+		configPath, _ := lookupMapSlice(entry, []string{"path"})
+		return fmt.Sprint(configPath)
+	}
+	return fmt.Sprint(value)
+}
+
+// ValidateConfigFilters checks the include and exclude patterns of configs entries
+func ValidateConfigFilters(filters map[string]ConfigFilter) error {
+	for app, filter := range filters {
+		for _, pattern := range append(append([]string{}, filter.Include...), filter.Exclude...) {
+			if strings.TrimSpace(pattern) == "" {
+				return fmt.Errorf("configs.%s: empty include or exclude pattern", app)
+			}
+			for _, segment := range strings.Split(pattern, "/") {
+				if _, err := path.Match(segment, ""); err != nil {
+					return fmt.Errorf("configs.%s: invalid pattern '%s': %w", app, pattern, err)
+				}
+			}
+		}
+	}
+	return nil
+}
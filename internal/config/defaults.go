@@ -0,0 +1,58 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"sort"
+	"strings"
+)
+
+// CommandDefaults maps a command path without the "anvil" prefix, e.g. "install" or
+// "config push", to default values for its flags
+type CommandDefaults map[string]map[string]string
+
+// Commands returns the configured command paths, sorted
+func (d CommandDefaults) Commands() []string {
+	commands := make([]string, 0, len(d))
+	for command := range d {
+		commands = append(commands, command)
+	}
+	sort.Strings(commands)
+	return commands
+}
+
+// For returns the default flag values of a command path, normalizing spacing so
+// "config  push" and "config push" match
+func (d CommandDefaults) For(commandPath string) map[string]string {
+	key := strings.Join(strings.Fields(commandPath), " ")
+	for command, flags := range d {
+		if strings.Join(strings.Fields(command), " ") == key {
+			return flags
+		}
+	}
+	return nil
+}
+
+// GetCommandDefaults returns the defaults section of settings.yaml
+func GetCommandDefaults() (CommandDefaults, error) {
+	var defaults CommandDefaults
+	err := withConfig(func(config *AnvilConfig) error {
+		defaults = config.Defaults
+		return nil
+	})
+	return defaults, err
+}
@@ -0,0 +1,211 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Section names used when reporting where an app is listed
+const (
+	SectionRequiredTools = "tools.required_tools"
+	SectionInstalledApps = "tools.installed_apps"
+	sectionGroupPrefix   = "groups."
+)
+
+// AppOccurrence is a single listing of an app in settings.yaml
+type AppOccurrence struct {
+	Section string
+	Name    string // Spelling as written in settings.yaml
+}
+
+// AppConflict groups every listing of an app whose entries are duplicated, redundant or inconsistently cased
+type AppConflict struct {
+	Name        string // Normalized app name
+	Occurrences []AppOccurrence
+}
+
+// NormalizeAppName returns the canonical form used to compare app names
+func NormalizeAppName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// Variants returns the distinct spellings of the app, sorted
+func (c AppConflict) Variants() []string {
+	var variants []string
+	for _, occ := range c.Occurrences {
+		if !slices.Contains(variants, occ.Name) {
+			variants = append(variants, occ.Name)
+		}
+	}
+	sort.Strings(variants)
+	return variants
+}
+
+// Issues describes what is wrong with the app's listings
+func (c AppConflict) Issues() []string {
+	var issues []string
+
+	if variants := c.Variants(); len(variants) > 1 {
+		issues = append(issues, fmt.Sprintf("spelled differently: %s", strings.Join(quoteAll(variants), ", ")))
+	}
+
+	counts := make(map[string]int)
+	var sections []string
+	for _, occ := range c.Occurrences {
+		if counts[occ.Section] == 0 {
+			sections = append(sections, occ.Section)
+		}
+		counts[occ.Section]++
+	}
+	for _, section := range sections {
+		if counts[section] > 1 {
+			issues = append(issues, fmt.Sprintf("listed %d times in %s", counts[section], section))
+		}
+	}
+
+	if counts[SectionInstalledApps] > 0 {
+		var covering []string
+		for _, section := range sections {
+			if section != SectionInstalledApps {
+				covering = append(covering, section)
+			}
+		}
+		if len(covering) > 0 {
+			issues = append(issues, fmt.Sprintf("in %s but already tracked by %s", SectionInstalledApps, strings.Join(covering, ", ")))
+		}
+	}
+
+	return issues
+}
+
+// quoteAll wraps each value in quotes so case variants stand out
+func quoteAll(values []string) []string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = fmt.Sprintf("%q", v)
+	}
+	return quoted
+}
+
+// appSections returns every app list in settings.yaml keyed by section name, in a stable order
+func appSections(cfg *AnvilConfig) ([]string, map[string][]string) {
+	names := []string{SectionRequiredTools, SectionInstalledApps}
+	lists := map[string][]string{
+		SectionRequiredTools: cfg.Tools.RequiredTools,
+		SectionInstalledApps: cfg.Tools.InstalledApps,
+	}
+
+	groupNames := make([]string, 0, len(cfg.Groups))
+	for group := range cfg.Groups {
+		groupNames = append(groupNames, group)
+	}
+	sort.Strings(groupNames)
+	for _, group := range groupNames {
+		names = append(names, sectionGroupPrefix+group)
+		lists[sectionGroupPrefix+group] = cfg.Groups[group]
+	}
+
+	return names, lists
+}
+
+// FindAppConflicts reports apps that are duplicated within a section, spelled with different
+// casing, or tracked in installed_apps while already covered by required_tools or a group.
+// Listing the same app in several groups is allowed.
+func FindAppConflicts(cfg *AnvilConfig) []AppConflict {
+	sections, lists := appSections(cfg)
+
+	byName := make(map[string]*AppConflict)
+	var order []string
+	for _, section := range sections {
+		for _, app := range lists[section] {
+			key := NormalizeAppName(app)
+			if key == "" {
+				continue
+			}
+			if _, ok := byName[key]; !ok {
+				byName[key] = &AppConflict{Name: key}
+				order = append(order, key)
+			}
+			byName[key].Occurrences = append(byName[key].Occurrences, AppOccurrence{Section: section, Name: app})
+		}
+	}
+
+	var conflicts []AppConflict
+	for _, key := range order {
+		if len(byName[key].Issues()) > 0 {
+			conflicts = append(conflicts, *byName[key])
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].Name < conflicts[j].Name })
+	return conflicts
+}
+
+// CleanupAppConflicts normalizes app names, removes duplicates within each section and drops
+// installed_apps entries already covered by required_tools or a group. It returns the number
+// of entries that were renamed or removed.
+func CleanupAppConflicts(cfg *AnvilConfig) int {
+	changes := 0
+
+	normalize := func(apps []string) []string {
+		seen := make(map[string]bool)
+		var cleaned []string
+		for _, app := range apps {
+			key := NormalizeAppName(app)
+			if key == "" || seen[key] {
+				changes++
+				continue
+			}
+			if key != app {
+				changes++
+			}
+			seen[key] = true
+			cleaned = append(cleaned, key)
+		}
+		return cleaned
+	}
+
+	cfg.Tools.RequiredTools = normalize(cfg.Tools.RequiredTools)
+	for group, apps := range cfg.Groups {
+		cfg.Groups[group] = normalize(apps)
+	}
+
+	covered := make(map[string]bool)
+	for _, app := range cfg.Tools.RequiredTools {
+		covered[app] = true
+	}
+	for _, apps := range cfg.Groups {
+		for _, app := range apps {
+			covered[app] = true
+		}
+	}
+
+	var installed []string
+	for _, app := range normalize(cfg.Tools.InstalledApps) {
+		if covered[app] {
+			changes++
+			continue
+		}
+		installed = append(installed, app)
+	}
+	cfg.Tools.InstalledApps = installed
+
+	return changes
+}
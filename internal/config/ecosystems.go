@@ -0,0 +1,94 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Installers of language ecosystems usable for ecosystem_tools
+const (
+	EcosystemGo    = "go"
+	EcosystemCargo = "cargo"
+	EcosystemNpm   = "npm"
+	EcosystemPipx  = "pipx"
+)
+
+// Ecosystems lists the valid installers of ecosystem_tools
+var Ecosystems = []string{EcosystemGo, EcosystemCargo, EcosystemNpm, EcosystemPipx}
+
+// EcosystemTool is a tool installed with a language ecosystem's installer rather than the
+// package manager, e.g. golangci-lint with go install or tldr with npm
+type EcosystemTool struct {
+	Installer string `yaml:"installer"`         // go, cargo, npm or pipx
+	Package   string `yaml:"package,omitempty"` // Go package path, crate or package name, defaults to the tool name
+	Version   string `yaml:"version,omitempty"` // Pinned version, e.g. v1.59.1; latest when empty
+	Binary    string `yaml:"binary,omitempty"`  // Command the tool provides, defaults to the tool name
+}
+
+// PackageName returns the package to install, the tool name when none is set
+func (e EcosystemTool) PackageName(toolName string) string {
+	if e.Package != "" {
+		return e.Package
+	}
+	return toolName
+}
+
+// BinaryName returns the command the tool provides, the tool name when none is set
+func (e EcosystemTool) BinaryName(toolName string) string {
+	if e.Binary != "" {
+		return e.Binary
+	}
+	return toolName
+}
+
+// ValidateEcosystemTools checks the installer of each ecosystem tool
+func ValidateEcosystemTools(tools map[string]EcosystemTool) error {
+	names := make([]string, 0, len(tools))
+	for name := range tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		tool := tools[name]
+		valid := false
+		for _, installer := range Ecosystems {
+			valid = valid || tool.Installer == installer
+		}
+		if !valid {
+			return fmt.Errorf("unknown installer '%s' for ecosystem tool '%s' (use %s)", tool.Installer, name, strings.Join(Ecosystems, ", "))
+		}
+		if tool.Installer == EcosystemGo && !strings.Contains(tool.Package, "/") {
+			return fmt.Errorf("ecosystem tool '%s' needs the package path for go install, e.g. github.com/owner/repo/cmd/%s", name, name)
+		}
+	}
+	return nil
+}
+
+// GetEcosystemTool returns how a tool is installed when it comes from a language ecosystem
+func GetEcosystemTool(toolName string) (EcosystemTool, bool) {
+	var tool EcosystemTool
+	found := false
+	withConfig(func(config *AnvilConfig) error {
+		tool, found = config.EcosystemTools[toolName]
+		return nil
+	})
+	return tool, found
+}
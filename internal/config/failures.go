@@ -0,0 +1,63 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Failure policies decide what a group install does when a tool without a critical tag fails
+const (
+	FailurePolicyContinue = "continue"  // Install the remaining tools and report the failure
+	FailurePolicyFailFast = "fail-fast" // Stop the group at the first failure
+	FailurePolicyPrompt   = "prompt"    // Ask whether to install the remaining tools
+)
+
+// FailurePolicies lists the valid failure policies
+var FailurePolicies = []string{FailurePolicyContinue, FailurePolicyFailFast, FailurePolicyPrompt}
+
+// ValidateFailurePolicy checks a failure policy name
+func ValidateFailurePolicy(policy string) error {
+	for _, valid := range FailurePolicies {
+		if policy == valid {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown failure policy '%s' (use %s)", policy, strings.Join(FailurePolicies, ", "))
+}
+
+// GetFailurePolicy returns the failure policy configured for a group, continue by default
+func GetFailurePolicy(groupName string) string {
+	policy := FailurePolicyContinue
+	withConfig(func(config *AnvilConfig) error {
+		if configured, ok := config.FailurePolicies[groupName]; ok && ValidateFailurePolicy(configured) == nil {
+			policy = configured
+		}
+		return nil
+	})
+	return policy
+}
+
+// GetToolCritical returns the critical tag of an app in a group, set is false when the entry has none
+func GetToolCritical(groupName, appName string) (critical, set bool) {
+	withConfig(func(config *AnvilConfig) error {
+		critical, set = config.ToolCritical[groupName][appName]
+		return nil
+	})
+	return critical, set
+}
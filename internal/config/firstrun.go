@@ -0,0 +1,36 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+// FirstRunConfig lists the optional steps run once after a cask is installed. The app
+// bundle is always checked for a bundle identifier, even when no step is enabled.
+type FirstRunConfig struct {
+	RemoveQuarantine bool   `yaml:"remove_quarantine,omitempty"` // Clear the Gatekeeper quarantine flag, after asking
+	Launch           bool   `yaml:"launch,omitempty"`            // Open the app once in the background so it registers
+	BundleID         string `yaml:"bundle_id,omitempty"`         // Expected bundle identifier, checked with mdls
+}
+
+// GetFirstRunConfig returns the first-run steps configured for an app
+func GetFirstRunConfig(appName string) (FirstRunConfig, bool) {
+	var steps FirstRunConfig
+	found := false
+	withConfig(func(config *AnvilConfig) error {
+		steps, found = config.FirstRun[appName]
+		return nil
+	})
+	return steps, found
+}
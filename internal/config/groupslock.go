@@ -0,0 +1,130 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/constants"
+	"gopkg.in/yaml.v2"
+)
+
+// How tools added to installed groups are handled after 'anvil config sync', see sync.new_group_tools
+const (
+	NewGroupToolsPrompt = "prompt" // Ask before installing them (default)
+	NewGroupToolsAuto   = "auto"   // Install them without asking
+	NewGroupToolsOff    = "off"    // Only list them
+)
+
+// GroupLockEntry records a group installed on this machine and the tools it listed then
+type GroupLockEntry struct {
+	Tools       []string  `yaml:"tools"`
+	InstalledAt time.Time `yaml:"installed_at"`
+}
+
+// GroupsLock is the content of ~/.anvil/groups.lock. It stays on this machine, so synced
+// settings can be compared against the groups installed here.
+type GroupsLock struct {
+	Groups map[string]GroupLockEntry `yaml:"groups"`
+}
+
+// ValidateNewGroupTools checks the sync.new_group_tools mode
+func ValidateNewGroupTools(mode string) error {
+	switch mode {
+	case "", NewGroupToolsPrompt, NewGroupToolsAuto, NewGroupToolsOff:
+		return nil
+	}
+	return fmt.Errorf("sync.new_group_tools: '%s' is not one of %s, %s or %s", mode, NewGroupToolsPrompt, NewGroupToolsAuto, NewGroupToolsOff)
+}
+
+// NewGroupToolsMode returns the sync.new_group_tools mode, prompt when unset
+func (c *AnvilConfig) NewGroupToolsMode() string {
+	if c.Sync.NewGroupTools == "" {
+		return NewGroupToolsPrompt
+	}
+	return c.Sync.NewGroupTools
+}
+
+// GetGroupsLockPath returns the path of the groups lock file
+func GetGroupsLockPath() string {
+	return filepath.Join(GetAnvilConfigDirectory(), constants.GROUPS_LOCK_FILE)
+}
+
+// LoadGroupsLock reads the groups lock file, an empty lock when there is none yet
+func LoadGroupsLock() (GroupsLock, error) {
+	lock := GroupsLock{Groups: make(map[string]GroupLockEntry)}
+	data, err := os.ReadFile(GetGroupsLockPath())
+	if os.IsNotExist(err) {
+		return lock, nil
+	}
+	if err != nil {
+		return lock, err
+	}
+	if err := yaml.Unmarshal(data, &lock); err != nil {
+		return lock, fmt.Errorf("failed to parse %s: %w", constants.GROUPS_LOCK_FILE, err)
+	}
+	if lock.Groups == nil {
+		lock.Groups = make(map[string]GroupLockEntry)
+	}
+	return lock, nil
+}
+
+// RecordInstalledGroup stores the tools of a group installed on this machine, replacing
+// what an earlier install of the group recorded
+func RecordInstalledGroup(groupName string, tools []string) error {
+	lock, err := LoadGroupsLock()
+	if err != nil {
+		return err
+	}
+	lock.Groups[groupName] = GroupLockEntry{Tools: append([]string{}, tools...), InstalledAt: time.Now()}
+
+	data, err := yaml.Marshal(lock)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(GetGroupsLockPath()), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(GetGroupsLockPath(), data, 0644)
+}
+
+// NewGroupTools returns, for each group installed on this machine, the tools settings list
+// now that the group did not list when it was installed. Groups never installed here, or
+// since removed from settings, are left out.
+func NewGroupTools(groups AnvilGroups, lock GroupsLock) map[string][]string {
+	added := make(map[string][]string)
+	for groupName, entry := range lock.Groups {
+		tools, exists := groups[groupName]
+		if !exists {
+			continue
+		}
+
+		known := make(map[string]bool, len(entry.Tools))
+		for _, tool := range entry.Tools {
+			known[NormalizeAppName(tool)] = true
+		}
+		for _, tool := range tools {
+			if !known[NormalizeAppName(tool)] {
+				added[groupName] = append(added[groupName], tool)
+			}
+		}
+	}
+	return added
+}
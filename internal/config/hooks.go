@@ -0,0 +1,93 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Failure policies of install hooks
+const (
+	HookFailureWarn  = "warn"  // Report the failure and carry on with the install
+	HookFailureAbort = "abort" // Fail the install of the app
+)
+
+// DefaultHookTimeout is how long a hook may run when no timeout is configured
+const DefaultHookTimeout = 10 * time.Minute
+
+// HookConfig holds the scripts run around the install of one app. Scripts run with sh -c
+// and only when the app is newly installed.
+type HookConfig struct {
+	PreInstall  string            `yaml:"pre_install,omitempty"`  // Run before the package manager installs the app
+	PostInstall string            `yaml:"post_install,omitempty"` // Run after the app was installed
+	Env         map[string]string `yaml:"env,omitempty"`          // Extra environment variables, $VAR references are expanded
+	Timeout     string            `yaml:"timeout,omitempty"`      // Go duration per script, e.g. 2m (default 10m)
+	OnFailure   string            `yaml:"on_failure,omitempty"`   // warn (default) or abort
+}
+
+// TimeoutDuration returns the configured timeout, DefaultHookTimeout when unset or invalid
+func (h HookConfig) TimeoutDuration() time.Duration {
+	timeout, err := time.ParseDuration(h.Timeout)
+	if err != nil || timeout <= 0 {
+		return DefaultHookTimeout
+	}
+	return timeout
+}
+
+// Aborts reports whether a failing hook fails the install
+func (h HookConfig) Aborts() bool {
+	return h.OnFailure == HookFailureAbort
+}
+
+// ValidateHooks checks the timeouts and failure policies of install hooks
+func ValidateHooks(hooks map[string]HookConfig) error {
+	names := make([]string, 0, len(hooks))
+	for name := range hooks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		hook := hooks[name]
+		if hook.Timeout != "" {
+			if timeout, err := time.ParseDuration(hook.Timeout); err != nil || timeout <= 0 {
+				return fmt.Errorf("hook timeout for '%s' must be a positive duration such as 5m, got '%s'", name, hook.Timeout)
+			}
+		}
+		switch hook.OnFailure {
+		case "", HookFailureWarn, HookFailureAbort:
+		default:
+			return fmt.Errorf("unknown hook failure policy '%s' for '%s' (use %s)", hook.OnFailure, name,
+				strings.Join([]string{HookFailureWarn, HookFailureAbort}, ", "))
+		}
+	}
+	return nil
+}
+
+// GetHookConfig returns the install hooks configured for an app
+func GetHookConfig(appName string) (HookConfig, bool) {
+	var hook HookConfig
+	found := false
+	withConfig(func(config *AnvilConfig) error {
+		hook, found = config.Hooks[appName]
+		return nil
+	})
+	return hook, found
+}
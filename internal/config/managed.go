@@ -0,0 +1,64 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"path"
+	"strings"
+)
+
+// managedName reduces an app name to the form used when matching managed_externally entries,
+// so "Slack" and "homebrew/cask/slack" both match an entry of "slack"
+func managedName(appName string) string {
+	return strings.ToLower(path.Base(strings.TrimSpace(appName)))
+}
+
+// IsManagedExternally reports whether the app is listed under managed_externally. Such apps
+// are installed and updated by another tool, such as an MDM, so anvil never changes them.
+func (c *AnvilConfig) IsManagedExternally(appName string) bool {
+	name := managedName(appName)
+	if name == "" || name == "." {
+		return false
+	}
+	for _, app := range c.ManagedExternally {
+		if managedName(app) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ExcludeManagedExternally returns apps without the ones listed under managed_externally
+func (c *AnvilConfig) ExcludeManagedExternally(apps []string) []string {
+	var kept []string
+	for _, app := range apps {
+		if !c.IsManagedExternally(app) {
+			kept = append(kept, app)
+		}
+	}
+	return kept
+}
+
+// IsManagedExternally reports whether the app is listed under managed_externally in the current settings
+func IsManagedExternally(appName string) bool {
+	managed := false
+	withConfig(func(config *AnvilConfig) error {
+		managed = config.IsManagedExternally(appName)
+		return nil
+	})
+	return managed
+}
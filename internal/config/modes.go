@@ -0,0 +1,72 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/0xjuanma/anvil/internal/constants"
+)
+
+// App modes restrict which config operations an app takes part in. They are set by
+// listing the app under local_only or repo_only in settings.yaml.
+const (
+	ModeLocalOnly = "local_only" // Tracked in configs for documentation, never pushed, pulled or synced
+	ModeRepoOnly  = "repo_only"  // Kept in the config repository for restores, never pushed
+)
+
+// AppMode returns the mode the app is listed under, empty for apps that are pushed and pulled
+func (c *AnvilConfig) AppMode(appName string) string {
+	switch {
+	case slices.Contains(c.LocalOnly, appName):
+		return ModeLocalOnly
+	case slices.Contains(c.RepoOnly, appName):
+		return ModeRepoOnly
+	default:
+		return ""
+	}
+}
+
+// CheckAppMode returns an error explaining why the app's mode blocks an operation,
+// one of constants.OpPush, constants.OpPull or constants.OpSync
+func (c *AnvilConfig) CheckAppMode(appName, operation string) error {
+	switch c.AppMode(appName) {
+	case ModeLocalOnly:
+		if operation == constants.OpPush || operation == constants.OpPull || operation == constants.OpSync {
+			return fmt.Errorf("'%s' is marked local_only in %s, its configs stay on this machine and are never pushed, pulled or synced",
+				appName, constants.ANVIL_CONFIG_FILE)
+		}
+	case ModeRepoOnly:
+		if operation == constants.OpPush {
+			return fmt.Errorf("'%s' is marked repo_only in %s, the repository copy is only restored with 'anvil config pull %s' and 'anvil config sync %s' and never pushed",
+				appName, constants.ANVIL_CONFIG_FILE, appName, appName)
+		}
+	}
+	return nil
+}
+
+// AppsInBothModes returns the apps listed under both local_only and repo_only
+func (c *AnvilConfig) AppsInBothModes() []string {
+	var both []string
+	for _, app := range c.LocalOnly {
+		if slices.Contains(c.RepoOnly, app) && !slices.Contains(both, app) {
+			both = append(both, app)
+		}
+	}
+	return both
+}
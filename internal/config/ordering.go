@@ -0,0 +1,156 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// OrderTags are the ordering tags of a group entry
+type OrderTags struct {
+	Order int      // Lower values install first, default 0
+	After []string // Tools of the same group that must install first
+}
+
+// GroupOrder is the install order planned for a group's tools
+type GroupOrder struct {
+	Tools   []string            // Serial install order
+	WaitFor map[string][]string // Tools that must finish before each tool starts, from order values and after tags
+	After   map[string][]string // Tools each tool needs installed first, from after tags only
+}
+
+// PlanGroupOrder orders the tools of a group by their order and after tags. Untagged tools keep their
+// listed order, and after entries naming tools outside the list are ignored. Conflicting tags are an error.
+func (c *AnvilConfig) PlanGroupOrder(groupName string, tools []string) (*GroupOrder, error) {
+	tags := c.ToolOrdering[groupName]
+	plan := &GroupOrder{
+		WaitFor: make(map[string][]string),
+		After:   make(map[string][]string),
+	}
+	if len(tags) == 0 {
+		plan.Tools = append([]string(nil), tools...)
+		return plan, nil
+	}
+
+	index := make(map[string]int, len(tools))
+	for i, tool := range tools {
+		index[orderKey(tool)] = i
+	}
+
+	for _, tool := range tools {
+		order := tags[tool].Order
+		for _, other := range tools {
+			if tags[other].Order < order {
+				plan.WaitFor[tool] = append(plan.WaitFor[tool], other)
+			}
+		}
+		for _, dep := range tags[tool].After {
+			i, ok := index[orderKey(dep)]
+			if !ok {
+				continue
+			}
+			plan.After[tool] = append(plan.After[tool], tools[i])
+			if !containsEntry(plan.WaitFor[tool], tools[i]) {
+				plan.WaitFor[tool] = append(plan.WaitFor[tool], tools[i])
+			}
+		}
+	}
+
+	// Take the ready tool with the lowest order value, then the earliest listed, until none are left
+	placed := make(map[string]bool, len(tools))
+	for len(plan.Tools) < len(tools) {
+		next := -1
+		for i, tool := range tools {
+			if placed[tool] || !allPlaced(plan.WaitFor[tool], placed) {
+				continue
+			}
+			if next == -1 || tags[tool].Order < tags[tools[next]].Order {
+				next = i
+			}
+		}
+		if next == -1 {
+			var stuck []string
+			for _, tool := range tools {
+				if !placed[tool] {
+					stuck = append(stuck, tool)
+				}
+			}
+			return nil, fmt.Errorf("order and after tags form a cycle between %s", strings.Join(stuck, ", "))
+		}
+		placed[tools[next]] = true
+		plan.Tools = append(plan.Tools, tools[next])
+	}
+	return plan, nil
+}
+
+// UnknownAfterTools lists the after entries of a group that name tools the group does not list
+func (c *AnvilConfig) UnknownAfterTools(groupName string) []string {
+	listed := make(map[string]bool)
+	for _, tool := range c.Groups[groupName] {
+		listed[orderKey(tool)] = true
+	}
+
+	var unknown []string
+	for _, tags := range c.ToolOrdering[groupName] {
+		for _, dep := range tags.After {
+			if !listed[orderKey(dep)] && !containsEntry(unknown, dep) {
+				unknown = append(unknown, dep)
+			}
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// GetGroupOrder plans the install order of a group's tools from the current settings
+func GetGroupOrder(groupName string, tools []string) (*GroupOrder, error) {
+	var plan *GroupOrder
+	err := withConfig(func(config *AnvilConfig) error {
+		var err error
+		plan, err = config.PlanGroupOrder(groupName, tools)
+		return err
+	})
+	return plan, err
+}
+
+// orderKey matches after entries to group entries regardless of type annotations and case
+func orderKey(entry string) string {
+	_, name := splitTypeAnnotation(entry)
+	return NormalizeAppName(name)
+}
+
+// containsEntry reports whether list holds entry
+func containsEntry(list []string, entry string) bool {
+	for _, item := range list {
+		if item == entry {
+			return true
+		}
+	}
+	return false
+}
+
+// allPlaced reports whether every tool in deps was already placed
+func allPlaced(deps []string, placed map[string]bool) bool {
+	for _, dep := range deps {
+		if !placed[dep] {
+			return false
+		}
+	}
+	return true
+}
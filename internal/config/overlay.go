@@ -0,0 +1,174 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"github.com/0xjuanma/anvil/internal/constants"
+	"gopkg.in/yaml.v2"
+)
+
+// settings.local.yaml holds machine-specific values, such as a work email or a local_path,
+// merged over settings.yaml when it is loaded. It is never pushed, and values that come from
+// it are kept out of settings.yaml when anvil saves the settings.
+
+// localOverlay is what settings.local.yaml contributed to a loaded config
+type localOverlay struct {
+	base    yaml.MapSlice // settings.yaml before the overlay was merged
+	overlay yaml.MapSlice
+}
+
+// GetLocalOverlayPath returns the path to settings.local.yaml
+func GetLocalOverlayPath() string {
+	return filepath.Join(GetAnvilConfigDirectory(), constants.LOCAL_CONFIG_FILE)
+}
+
+// LoadLocalOverlay reads settings.local.yaml, returning nil when there is none
+func LoadLocalOverlay() (yaml.MapSlice, error) {
+	data, err := os.ReadFile(GetLocalOverlayPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", constants.LOCAL_CONFIG_FILE, err)
+	}
+
+	var overlay yaml.MapSlice
+	if err := yaml.Unmarshal(data, &overlay); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", constants.LOCAL_CONFIG_FILE, err)
+	}
+	return overlay, nil
+}
+
+// MergeSettings returns base with overlay merged over it. Maps are merged key by key,
+// scalars and lists in the overlay replace the base value. Neither input is modified.
+func MergeSettings(base, overlay yaml.MapSlice) yaml.MapSlice {
+	merged := make(yaml.MapSlice, len(base))
+	copy(merged, base)
+
+	for _, item := range overlay {
+		keys := []string{fmt.Sprint(item.Key)}
+		current, _ := lookupMapSlice(merged, keys)
+
+		nestedBase, baseIsMap := current.(yaml.MapSlice)
+		nestedOverlay, overlayIsMap := item.Value.(yaml.MapSlice)
+		if baseIsMap && overlayIsMap {
+			merged = setMapSlice(merged, keys, MergeSettings(nestedBase, nestedOverlay))
+		} else {
+			merged = setMapSlice(merged, keys, item.Value)
+		}
+	}
+	return merged
+}
+
+// applyLocalOverlay merges settings.local.yaml over the raw settings data. It returns the data
+// unchanged and a nil overlay when there is no settings.local.yaml.
+func applyLocalOverlay(data []byte) ([]byte, *localOverlay, error) {
+	overlay, err := LoadLocalOverlay()
+	if err != nil || len(overlay) == 0 {
+		return data, nil, err
+	}
+
+	var base yaml.MapSlice
+	if err := yaml.Unmarshal(data, &base); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %w", constants.ANVIL_CONFIG_FILE, err)
+	}
+
+	merged, err := yaml.Marshal(MergeSettings(base, overlay))
+	if err != nil {
+		return nil, nil, err
+	}
+	return merged, &localOverlay{base: base, overlay: overlay}, nil
+}
+
+// withoutLocalValues returns the saved document with every value that still matches the
+// overlay put back to what settings.yaml had, or removed when settings.yaml didn't have it.
+// Values changed since loading are kept, they were set on purpose.
+func (lo *localOverlay) withoutLocalValues(saved yaml.MapSlice) yaml.MapSlice {
+	return unmergeSettings(saved, lo.base, lo.overlay, nil)
+}
+
+func unmergeSettings(saved, base, overlay yaml.MapSlice, prefix []string) yaml.MapSlice {
+	for _, item := range overlay {
+		keys := append(append([]string{}, prefix...), fmt.Sprint(item.Key))
+
+		if nested, ok := item.Value.(yaml.MapSlice); ok {
+			if baseValue, _ := lookupMapSlice(base, keys); baseValue == nil || isMapSlice(baseValue) {
+				saved = unmergeSettings(saved, base, nested, keys)
+				continue
+			}
+		}
+
+		savedValue, found := lookupMapSlice(saved, keys)
+		if !found || !reflect.DeepEqual(savedValue, item.Value) {
+			continue
+		}
+		if baseValue, ok := lookupMapSlice(base, keys); ok {
+			saved = setMapSlice(saved, keys, baseValue)
+		} else {
+			saved = deleteMapSlice(saved, keys)
+		}
+	}
+	return saved
+}
+
+// isMapSlice reports whether a YAML value is a mapping
+func isMapSlice(value interface{}) bool {
+	_, ok := value.(yaml.MapSlice)
+	return ok
+}
+
+// withoutLocalConfigs returns the edited configs entries with those that still match
+// settings.local.yaml put back to their settings.yaml value, or left out when settings.yaml
+// doesn't have them
+func withoutLocalConfigs(paths map[string]string, settings []byte) (map[string]string, error) {
+	overlay, err := LoadLocalOverlay()
+	if err != nil {
+		return nil, err
+	}
+	localConfigs, _ := lookupMapSlice(overlay, []string{"configs"})
+	entries, _ := localConfigs.(yaml.MapSlice)
+	if len(entries) == 0 {
+		return paths, nil
+	}
+
+	var base AnvilConfig
+	if err := yaml.Unmarshal(settings, &base); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", constants.ANVIL_CONFIG_FILE, err)
+	}
+
+	result := make(map[string]string, len(paths))
+	for app, path := range paths {
+		result[app] = path
+	}
+	for _, item := range entries {
+		app := fmt.Sprint(item.Key)
+		if path, found := result[app]; !found || path != configEntryPath(item.Value) {
+			continue
+		}
+		if basePath, found := base.Configs[app]; found {
+			result[app] = basePath
+		} else {
+			delete(result, app)
+		}
+	}
+	return result, nil
+}
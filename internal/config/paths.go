@@ -0,0 +1,205 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/system"
+	"gopkg.in/yaml.v2"
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// ExpandConfigPath expands a leading "~" to the home directory and cleans the path, configs
+// entries are stored expanded since anvil never expands them when reading
+func ExpandConfigPath(path string) string {
+	path = strings.TrimSpace(path)
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		homeDir, _ := system.GetHomeDir()
+		path = filepath.Join(homeDir, path[1:])
+	}
+	if path == "" {
+		return ""
+	}
+	return filepath.Clean(path)
+}
+
+// CheckConfigPath returns why a configs entry path can't be used, "" when it exists
+func CheckConfigPath(path string) string {
+	switch {
+	case path == "":
+		return "empty path"
+	case !filepath.IsAbs(path):
+		return "not an absolute path"
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return "does not exist"
+	} else if err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// ValidateConfigAppName checks a name used as a configs key, which is also the app's
+// directory in the config repository
+func ValidateConfigAppName(name string) error {
+	switch {
+	case strings.TrimSpace(name) == "":
+		return fmt.Errorf("app name is empty")
+	case name != strings.TrimSpace(name) || strings.ContainsAny(name, " \t"):
+		return fmt.Errorf("app name '%s' contains spaces", name)
+	case strings.ContainsAny(name, `/\`) || name == "." || name == "..":
+		return fmt.Errorf("app name '%s' can't be a path", name)
+	case name == constants.ANVIL:
+		return fmt.Errorf("'%s' is reserved for settings.yaml", name)
+	}
+	return nil
+}
+
+// SaveConfigPaths replaces the configs section of settings.yaml with paths. Only that
+// section is rewritten: comments elsewhere in the file are kept, and so are the comments of
+// entries that remain. New entries are appended in alphabetical order.
+func SaveConfigPaths(paths map[string]string) error {
+	if SafeModeEnabled() {
+		return fmt.Errorf("%s is corrupted and loaded in safe mode, run 'anvil config validate' and fix it before changes can be saved", constants.ANVIL_CONFIG_FILE)
+	}
+
+	current, err := os.ReadFile(GetAnvilConfigPath())
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", constants.ANVIL_CONFIG_FILE, err)
+	}
+	paths, err = withoutLocalConfigs(paths, current)
+	if err != nil {
+		return err
+	}
+	data, err := replaceConfigsSection(current, paths)
+	if err != nil {
+		return err
+	}
+
+	// The rewritten file must still load before it replaces the current one
+	var check AnvilConfig
+	if err := yaml.Unmarshal(data, &check); err != nil {
+		return fmt.Errorf("edited settings do not parse: %w", err)
+	}
+
+	backups := 0
+	if cfg, err := getCachedConfig(); err == nil {
+		backups = cfg.SettingsBackups
+	}
+	return writeSettings(data, backups)
+}
+
+// replaceConfigsSection rewrites the configs mapping of a settings document
+func replaceConfigsSection(data []byte, paths map[string]string) ([]byte, error) {
+	var doc yaml3.Node
+	if err := yaml3.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", constants.ANVIL_CONFIG_FILE, err)
+	}
+	if doc.Kind == 0 {
+		doc = yaml3.Node{Kind: yaml3.DocumentNode, Content: []*yaml3.Node{{Kind: yaml3.MappingNode, Tag: "!!map"}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml3.MappingNode {
+		return nil, fmt.Errorf("%s is not a mapping", constants.ANVIL_CONFIG_FILE)
+	}
+
+	var section *yaml3.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "configs" {
+			section = root.Content[i+1]
+			break
+		}
+	}
+	if section == nil {
+		section = &yaml3.Node{Kind: yaml3.MappingNode, Tag: "!!map"}
+		root.Content = append(root.Content, scalarNode("configs"), section)
+	}
+	if section.Kind != yaml3.MappingNode {
+		// "configs:" with no entries parses as null
+		*section = yaml3.Node{Kind: yaml3.MappingNode, Tag: "!!map", HeadComment: section.HeadComment, LineComment: section.LineComment}
+	}
+
+	// Keep the existing entries in place with their comments, then add the new ones
+	seen := make(map[string]bool, len(paths))
+	var content []*yaml3.Node
+	for i := 0; i+1 < len(section.Content); i += 2 {
+		key, value := section.Content[i], section.Content[i+1]
+		path, keep := paths[key.Value]
+		if !keep {
+			continue
+		}
+		seen[key.Value] = true
+		if value.Kind == yaml3.MappingNode {
+			// Entries with include and exclude patterns keep them, only the path changes
+			setEntryPath(value, path)
+		} else if value.Kind != yaml3.ScalarNode || value.Value != path {
+			value = &yaml3.Node{Kind: yaml3.ScalarNode, Tag: "!!str", Value: path, LineComment: value.LineComment}
+		}
+		content = append(content, key, value)
+	}
+
+	added := make([]string, 0, len(paths))
+	for name := range paths {
+		if !seen[name] {
+			added = append(added, name)
+		}
+	}
+	sort.Strings(added)
+	for _, name := range added {
+		content = append(content, scalarNode(name), scalarNode(paths[name]))
+	}
+
+	section.Content = content
+	section.Style = 0
+	if len(content) == 0 {
+		section.Style = yaml3.FlowStyle
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml3.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to write YAML: %w", err)
+	}
+	encoder.Close()
+	return buf.Bytes(), nil
+}
+
+// scalarNode returns a plain string node
+func scalarNode(value string) *yaml3.Node {
+	return &yaml3.Node{Kind: yaml3.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// setEntryPath sets the path of a configs entry written as a mapping
+func setEntryPath(entry *yaml3.Node, path string) {
+	for i := 0; i+1 < len(entry.Content); i += 2 {
+		if entry.Content[i].Value == "path" {
+			if entry.Content[i+1].Value != path {
+				entry.Content[i+1] = &yaml3.Node{Kind: yaml3.ScalarNode, Tag: "!!str", Value: path, LineComment: entry.Content[i+1].LineComment}
+			}
+			return
+		}
+	}
+	entry.Content = append([]*yaml3.Node{scalarNode("path"), scalarNode(path)}, entry.Content...)
+}
@@ -0,0 +1,239 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/0xjuanma/anvil/internal/system"
+	"gopkg.in/yaml.v2"
+)
+
+// platformAliases maps friendly platform names to the names used by the Go runtime
+var platformAliases = map[string]string{
+	"macos": "darwin",
+	"mac":   "darwin",
+	"osx":   "darwin",
+}
+
+// ToolTags are the optional tags of a group entry
+type ToolTags struct {
+	Platforms []string `yaml:"platforms,omitempty"`
+	Critical  *bool    `yaml:"critical,omitempty"` // Whether a failed install stops the group, unset follows its failure policy
+	Order     int      `yaml:"order,omitempty"`    // Lower values install first within the group, default 0
+	After     []string `yaml:"after,omitempty"`    // Tools of the same group that must install first
+}
+
+// groupItem is a group entry written either as a plain name or as a single-key map with tags:
+//
+//	dev:
+//	  - git
+//	  - iterm2: {platforms: [darwin]}
+//	  - docker: {critical: false}
+//	  - docker-compose: {after: [docker]}
+type groupItem struct {
+	Name string
+	Tags ToolTags
+}
+
+// UnmarshalYAML accepts both the plain and the tagged form of a group entry
+func (g *groupItem) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var name string
+	if err := unmarshal(&name); err == nil {
+		g.Name = name
+		return nil
+	}
+
+	var tagged map[string]ToolTags
+	if err := unmarshal(&tagged); err != nil || len(tagged) != 1 {
+		return fmt.Errorf("group entries must be an app name or 'app: {platforms: [...], critical: true, order: 1, after: [...]}'")
+	}
+	for name, tags := range tagged {
+		g.Name = name
+		g.Tags = tags
+	}
+	return nil
+}
+
+// MarshalYAML writes untagged entries back as plain names
+func (g groupItem) MarshalYAML() (interface{}, error) {
+	if len(g.Tags.Platforms) == 0 && g.Tags.Critical == nil && g.Tags.Order == 0 && len(g.Tags.After) == 0 {
+		return g.Name, nil
+	}
+	return yaml.MapSlice{{Key: g.Name, Value: g.Tags}}, nil
+}
+
+// UnmarshalYAML reads group entries by name, tags are collected separately by AnvilConfig
+func (g *AnvilGroups) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw map[string][]groupItem
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	*g = make(AnvilGroups, len(raw))
+	for group, items := range raw {
+		names := make([]string, len(items))
+		for i, item := range items {
+			names[i] = item.Name
+		}
+		(*g)[group] = names
+	}
+	return nil
+}
+
+// plainConfig has the fields of AnvilConfig without its YAML methods
+type plainConfig AnvilConfig
+
+// UnmarshalYAML decodes the config and collects the platform, critical and ordering tags of group
+// entries and the filters of configs entries
+func (c *AnvilConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal((*plainConfig)(c)); err != nil {
+		return err
+	}
+
+	filters, err := collectConfigFilters(unmarshal)
+	if err != nil {
+		return err
+	}
+	c.ConfigFilters = filters
+
+	var tagged struct {
+		Groups map[string][]groupItem `yaml:"groups"`
+	}
+	if err := unmarshal(&tagged); err != nil {
+		return err
+	}
+
+	for group, items := range tagged.Groups {
+		for _, item := range items {
+			if item.Tags.Critical != nil {
+				if c.ToolCritical == nil {
+					c.ToolCritical = make(map[string]map[string]bool)
+				}
+				if c.ToolCritical[group] == nil {
+					c.ToolCritical[group] = make(map[string]bool)
+				}
+				c.ToolCritical[group][item.Name] = *item.Tags.Critical
+			}
+			if item.Tags.Order != 0 || len(item.Tags.After) > 0 {
+				if c.ToolOrdering == nil {
+					c.ToolOrdering = make(map[string]map[string]OrderTags)
+				}
+				if c.ToolOrdering[group] == nil {
+					c.ToolOrdering[group] = make(map[string]OrderTags)
+				}
+				c.ToolOrdering[group][item.Name] = OrderTags{Order: item.Tags.Order, After: item.Tags.After}
+			}
+			if len(item.Tags.Platforms) == 0 {
+				continue
+			}
+			ensureMap(&c.ToolPlatforms)
+			c.ToolPlatforms[item.Name] = item.Tags.Platforms
+		}
+	}
+	return nil
+}
+
+// MarshalYAML writes platform tags back onto every group entry of a tagged app, critical
+// and ordering tags onto the entries of the groups they were set in, and filters onto their
+// configs entries
+func (c AnvilConfig) MarshalYAML() (interface{}, error) {
+	tagged := len(c.ToolPlatforms) > 0 || len(c.ToolCritical) > 0 || len(c.ToolOrdering) > 0
+	if !tagged && len(c.ConfigFilters) == 0 {
+		return plainConfig(c), nil
+	}
+
+	data, err := yaml.Marshal(plainConfig(c))
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	groupNames := make([]string, 0, len(c.Groups))
+	for group := range c.Groups {
+		groupNames = append(groupNames, group)
+	}
+	sort.Strings(groupNames)
+
+	groups := make(yaml.MapSlice, 0, len(groupNames))
+	for _, group := range groupNames {
+		items := make([]groupItem, len(c.Groups[group]))
+		for i, name := range c.Groups[group] {
+			items[i] = groupItem{Name: name, Tags: ToolTags{Platforms: c.ToolPlatforms[name]}}
+			if critical, ok := c.ToolCritical[group][name]; ok {
+				items[i].Tags.Critical = &critical
+			}
+			if ordering, ok := c.ToolOrdering[group][name]; ok {
+				items[i].Tags.Order = ordering.Order
+				items[i].Tags.After = ordering.After
+			}
+		}
+		groups = append(groups, yaml.MapItem{Key: group, Value: items})
+	}
+
+	for i := range doc {
+		switch {
+		case doc[i].Key == "groups" && tagged:
+			doc[i].Value = groups
+		case doc[i].Key == "configs" && len(c.ConfigFilters) > 0:
+			doc[i].Value = c.filteredConfigs()
+		}
+	}
+	return doc, nil
+}
+
+// NormalizePlatform lowercases a platform tag and resolves aliases such as "macos"
+func NormalizePlatform(platform string) string {
+	platform = strings.ToLower(strings.TrimSpace(platform))
+	if alias, ok := platformAliases[platform]; ok {
+		return alias
+	}
+	return platform
+}
+
+// SupportsPlatform reports whether platforms allows the given platform; no tags means every platform
+func SupportsPlatform(platforms []string, platform string) bool {
+	if len(platforms) == 0 {
+		return true
+	}
+	for _, p := range platforms {
+		if NormalizePlatform(p) == NormalizePlatform(platform) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetToolPlatforms returns the platform tags of an app, or nil when it runs everywhere
+func GetToolPlatforms(appName string) []string {
+	var platforms []string
+	withConfig(func(config *AnvilConfig) error {
+		platforms = config.ToolPlatforms[appName]
+		return nil
+	})
+	return platforms
+}
+
+// IsToolSupported reports whether an app's platform tags allow the current machine
+func IsToolSupported(appName string) bool {
+	return SupportsPlatform(GetToolPlatforms(appName), system.Platform())
+}
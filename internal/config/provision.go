@@ -0,0 +1,67 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/0xjuanma/anvil/internal/constants"
+)
+
+// ProvisionConfig describes named machine profiles that 'anvil provision' applies
+type ProvisionConfig struct {
+	Profiles map[string]ProvisionProfile `yaml:"profiles,omitempty"`
+}
+
+// ProvisionProfile lists what a machine profile installs and which configs it pulls and syncs
+type ProvisionProfile struct {
+	Description string   `yaml:"description,omitempty"`
+	Groups      []string `yaml:"groups,omitempty"`   // Groups to install
+	Apps        []string `yaml:"apps,omitempty"`     // Individual apps to install
+	Configs     []string `yaml:"configs,omitempty"`  // Configs to pull and sync, "anvil" syncs settings.yaml
+	Hosts       bool     `yaml:"hosts,omitempty"`    // Write the hosts entries from settings.yaml into /etc/hosts
+	Git         bool     `yaml:"git,omitempty"`      // Apply git.extra_config and git.global_ignore to the global git config
+	Services    bool     `yaml:"services,omitempty"` // Start and stop Homebrew services as the services section asks
+}
+
+// GetProvisionProfile returns the named provisioning profile
+func GetProvisionProfile(name string) (ProvisionProfile, error) {
+	var profile ProvisionProfile
+	err := withConfig(func(config *AnvilConfig) error {
+		p, exists := config.Provision.Profiles[name]
+		if !exists {
+			return fmt.Errorf("provision profile '%s' not found in %s", name, constants.ANVIL_CONFIG_FILE)
+		}
+		profile = p
+		return nil
+	})
+	return profile, err
+}
+
+// GetProvisionProfileNames returns the configured provisioning profile names, sorted
+func GetProvisionProfileNames() ([]string, error) {
+	var names []string
+	err := withConfig(func(config *AnvilConfig) error {
+		for name := range config.Provision.Profiles {
+			names = append(names, name)
+		}
+		return nil
+	})
+	sort.Strings(names)
+	return names, err
+}
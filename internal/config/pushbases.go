@@ -0,0 +1,128 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/constants"
+	"gopkg.in/yaml.v2"
+)
+
+// PushBase is where an app directory stood on the configured branch when this machine last
+// pulled or pushed it. A push compares the branch against it to find changes other machines
+// pushed in the meantime.
+type PushBase struct {
+	Repo       string    `yaml:"repo"`
+	Branch     string    `yaml:"branch"`
+	Commit     string    `yaml:"commit"`                // Branch head the directory was read at
+	Tree       string    `yaml:"tree,omitempty"`        // Directory tree at that commit, empty when it did not exist
+	PushedTree string    `yaml:"pushed_tree,omitempty"` // Directory tree of the last push from here, the branch holds it once merged
+	RecordedAt time.Time `yaml:"recorded_at"`
+}
+
+// PushBases is the content of ~/.anvil/push-bases.yaml, keyed by app. It stays on this machine.
+type PushBases struct {
+	Apps map[string]PushBase `yaml:"apps"`
+}
+
+// pushBasesMutex serializes updates, pulls of several apps record their bases concurrently
+var pushBasesMutex sync.Mutex
+
+// GetPushBasesPath returns the path of the push bases file
+func GetPushBasesPath() string {
+	return filepath.Join(GetAnvilConfigDirectory(), constants.PUSH_BASES_FILE)
+}
+
+// LoadPushBases reads the push bases file, empty when there is none yet
+func LoadPushBases() (PushBases, error) {
+	bases := PushBases{Apps: make(map[string]PushBase)}
+	data, err := os.ReadFile(GetPushBasesPath())
+	if os.IsNotExist(err) {
+		return bases, nil
+	}
+	if err != nil {
+		return bases, err
+	}
+	if err := yaml.Unmarshal(data, &bases); err != nil {
+		return bases, fmt.Errorf("failed to parse %s: %w", constants.PUSH_BASES_FILE, err)
+	}
+	if bases.Apps == nil {
+		bases.Apps = make(map[string]PushBase)
+	}
+	return bases, nil
+}
+
+// GetPushBase returns the recorded base of an app in a repository and branch, found is false
+// when the app was never pulled or pushed from this machine with them
+func GetPushBase(app, repo, branch string) (PushBase, bool, error) {
+	bases, err := LoadPushBases()
+	if err != nil {
+		return PushBase{}, false, err
+	}
+	base, found := bases.Apps[app]
+	if !found || base.Repo != repo || base.Branch != branch {
+		return PushBase{}, false, nil
+	}
+	return base, true, nil
+}
+
+// RecordPulledBase records where a pulled app directory stood. The tree of an earlier push
+// from here is kept, its pull request may not be merged yet.
+func RecordPulledBase(app, repo, branch, commit, tree string) error {
+	return updatePushBase(app, func(base *PushBase) {
+		if base.Repo != repo || base.Branch != branch {
+			base.PushedTree = ""
+		}
+		base.Repo, base.Branch, base.Commit, base.Tree = repo, branch, commit, tree
+	})
+}
+
+// RecordPushedBase records the branch an app was pushed against and the tree it pushed
+func RecordPushedBase(app string, base PushBase) error {
+	return updatePushBase(app, func(current *PushBase) {
+		*current = base
+	})
+}
+
+// updatePushBase applies update to the recorded base of an app and saves the file
+func updatePushBase(app string, update func(*PushBase)) error {
+	pushBasesMutex.Lock()
+	defer pushBasesMutex.Unlock()
+
+	bases, err := LoadPushBases()
+	if err != nil {
+		return err
+	}
+	base := bases.Apps[app]
+	update(&base)
+	base.RecordedAt = time.Now()
+	bases.Apps[app] = base
+
+	data, err := yaml.Marshal(bases)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(GetPushBasesPath()), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(GetPushBasesPath(), data, 0644)
+}
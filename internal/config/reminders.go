@@ -0,0 +1,90 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/constants"
+)
+
+// DefaultPushReminderInterval is how often the unpushed changes check runs when not configured
+const DefaultPushReminderInterval = 24 * time.Hour
+
+// pushReminderStateFile records when the unpushed changes check last ran
+const pushReminderStateFile = ".push-reminder"
+
+// BrewAutoUpdateInterval is how often 'brew.auto_update' runs 'brew update' before installs
+const BrewAutoUpdateInterval = 24 * time.Hour
+
+// brewUpdateStateFile records when anvil last updated Homebrew
+const brewUpdateStateFile = ".brew-update"
+
+// RemindersConfig controls periodic reminders shown after anvil commands
+type RemindersConfig struct {
+	PushDisabled      bool `yaml:"push_disabled,omitempty"`       // Turn off the unpushed config changes reminder
+	PushIntervalHours int  `yaml:"push_interval_hours,omitempty"` // Hours between checks (0 = default 24)
+}
+
+// PushInterval returns how often the unpushed changes check may run
+func (r RemindersConfig) PushInterval() time.Duration {
+	if r.PushIntervalHours <= 0 {
+		return DefaultPushReminderInterval
+	}
+	return time.Duration(r.PushIntervalHours) * time.Hour
+}
+
+// getPushReminderStatePath returns the path of the reminder state file
+func getPushReminderStatePath() string {
+	return filepath.Join(GetAnvilConfigDirectory(), pushReminderStateFile)
+}
+
+// LastPushReminderCheck returns when the unpushed changes check last ran, or the zero time
+func LastPushReminderCheck() time.Time {
+	return readStateTime(getPushReminderStatePath())
+}
+
+// readStateTime reads the time stored in a state file, or the zero time
+func readStateTime(path string) time.Time {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return time.Time{}
+	}
+	last, err := time.Parse(time.RFC3339, strings.TrimSpace(string(data)))
+	if err != nil {
+		return time.Time{}
+	}
+	return last
+}
+
+// RecordPushReminderCheck stores the time of the latest unpushed changes check
+func RecordPushReminderCheck(now time.Time) error {
+	return os.WriteFile(getPushReminderStatePath(), []byte(now.UTC().Format(time.RFC3339)+"\n"), constants.FilePerm)
+}
+
+// LastBrewUpdate returns when anvil last updated Homebrew, or the zero time
+func LastBrewUpdate() time.Time {
+	return readStateTime(filepath.Join(GetAnvilConfigDirectory(), brewUpdateStateFile))
+}
+
+// RecordBrewUpdate stores the time of the latest Homebrew update
+func RecordBrewUpdate(now time.Time) error {
+	return os.WriteFile(filepath.Join(GetAnvilConfigDirectory(), brewUpdateStateFile), []byte(now.UTC().Format(time.RFC3339)+"\n"), constants.FilePerm)
+}
@@ -0,0 +1,114 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// GitHubRemote is a named config repository under github.remotes, e.g. work and personal
+// dotfiles kept in separate private repositories. Empty fields fall back to the github section.
+type GitHubRemote struct {
+	ConfigRepo  string `yaml:"config_repo"`             // Repository of this remote (e.g., "company/dotfiles")
+	Branch      string `yaml:"branch,omitempty"`        // Branch to use, defaults to github.branch
+	LocalPath   string `yaml:"local_path,omitempty"`    // Local clone, defaults to ~/.anvil/dotfiles-<name>
+	TokenEnvVar string `yaml:"token_env_var,omitempty"` // Environment variable name for the token, defaults to github.token_env_var
+}
+
+// activeRemote is the remote selected with --remote, empty for the github section itself
+var activeRemote string
+
+// UseRemote selects a remote from github.remotes for the rest of the run. LoadConfig then returns
+// the remote's repository, branch, local clone and token in the github section, while SaveConfig
+// keeps writing the section as it is in settings.yaml. An empty name selects the github section.
+func UseRemote(name string) error {
+	if name != "" {
+		cfg, err := LoadConfig()
+		if err != nil {
+			return err
+		}
+		if _, err := cfg.GitHub.ForRemote(name); err != nil {
+			return err
+		}
+	}
+
+	activeRemote = name
+	InvalidateConfigCache()
+	return nil
+}
+
+// ActiveRemote returns the remote selected with UseRemote, empty when none is
+func ActiveRemote() string {
+	return activeRemote
+}
+
+// ForRemote returns the github section with a named remote's settings applied
+func (g GitHubConfig) ForRemote(name string) (GitHubConfig, error) {
+	remote, exists := g.Remotes[name]
+	if !exists {
+		if len(g.Remotes) == 0 {
+			return g, fmt.Errorf("remote '%s' not found, add it under github.remotes in settings.yaml", name)
+		}
+		return g, fmt.Errorf("remote '%s' not found in github.remotes (available: %s)", name, strings.Join(g.RemoteNames(), ", "))
+	}
+	if remote.ConfigRepo == "" {
+		return g, fmt.Errorf("remote '%s' has no config_repo", name)
+	}
+
+	resolved := g
+	resolved.ConfigRepo = normalizeGitHubRepo(remote.ConfigRepo)
+	resolved.LocalPath = remote.LocalPath
+	if resolved.LocalPath == "" {
+		resolved.LocalPath = filepath.Join(GetAnvilConfigDirectory(), "dotfiles-"+name)
+	}
+	if remote.Branch != "" {
+		resolved.Branch = remote.Branch
+	}
+	if remote.TokenEnvVar != "" {
+		resolved.TokenEnvVar = remote.TokenEnvVar
+	}
+	return resolved, nil
+}
+
+// RemoteNames returns the names of github.remotes, sorted
+func (g GitHubConfig) RemoteNames() []string {
+	names := make([]string, 0, len(g.Remotes))
+	for name := range g.Remotes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyActiveRemote swaps the active remote into a loaded config, keeping the github section
+// as written so SaveConfig doesn't persist the remote over it
+func applyActiveRemote(config *AnvilConfig) error {
+	if activeRemote == "" {
+		return nil
+	}
+	resolved, err := config.GitHub.ForRemote(activeRemote)
+	if err != nil {
+		return err
+	}
+	written := config.GitHub
+	config.writtenGitHub = &written
+	config.GitHub = resolved
+	return nil
+}
@@ -0,0 +1,120 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"sort"
+	"strings"
+)
+
+// typeAnnotations are the install type prefixes an app entry may carry, e.g. "cask:docker-desktop"
+var typeAnnotations = []string{"cask:", "formula:", "mas:"}
+
+// splitTypeAnnotation splits an entry into its type prefix (including the colon) and package name
+func splitTypeAnnotation(entry string) (string, string) {
+	for _, prefix := range typeAnnotations {
+		if name, ok := strings.CutPrefix(entry, prefix); ok && name != "" {
+			return prefix, name
+		}
+	}
+	return "", entry
+}
+
+// renameEntries replaces entries for oldName with newName, keeping any type annotation
+func renameEntries(entries []string, oldName, newName string) ([]string, int) {
+	renamed := 0
+	for i, entry := range entries {
+		prefix, name := splitTypeAnnotation(entry)
+		if NormalizeAppName(name) == NormalizeAppName(oldName) {
+			entries[i] = prefix + newName
+			renamed++
+		}
+	}
+	return entries, renamed
+}
+
+// RenameAppEntries updates every groups and tools entry for a package Homebrew renamed,
+// keeping type annotations, platform, critical and ordering tags, and returns how many entries changed
+func RenameAppEntries(oldName, newName string) (int, error) {
+	total := 0
+	err := withConfigAndSave(func(config *AnvilConfig) error {
+		var n int
+		for group, apps := range config.Groups {
+			config.Groups[group], n = renameEntries(apps, oldName, newName)
+			total += n
+		}
+		config.Tools.RequiredTools, n = renameEntries(config.Tools.RequiredTools, oldName, newName)
+		total += n
+		config.Tools.InstalledApps, n = renameEntries(config.Tools.InstalledApps, oldName, newName)
+		total += n
+
+		for entry, platforms := range config.ToolPlatforms {
+			prefix, name := splitTypeAnnotation(entry)
+			if NormalizeAppName(name) == NormalizeAppName(oldName) {
+				delete(config.ToolPlatforms, entry)
+				config.ToolPlatforms[prefix+newName] = platforms
+			}
+		}
+		for _, critical := range config.ToolCritical {
+			for entry, value := range critical {
+				prefix, name := splitTypeAnnotation(entry)
+				if NormalizeAppName(name) == NormalizeAppName(oldName) {
+					delete(critical, entry)
+					critical[prefix+newName] = value
+				}
+			}
+		}
+		for _, ordering := range config.ToolOrdering {
+			for entry, tags := range ordering {
+				tags.After, _ = renameEntries(tags.After, oldName, newName)
+				ordering[entry] = tags
+				prefix, name := splitTypeAnnotation(entry)
+				if NormalizeAppName(name) == NormalizeAppName(oldName) {
+					delete(ordering, entry)
+					ordering[prefix+newName] = tags
+				}
+			}
+		}
+		return nil
+	})
+	return total, err
+}
+
+// AppEntries lists the app entries in tools and groups once each, in a stable order
+func AppEntries(config *AnvilConfig) []string {
+	seen := make(map[string]bool)
+	var entries []string
+	add := func(list []string) {
+		for _, entry := range list {
+			if !seen[entry] {
+				seen[entry] = true
+				entries = append(entries, entry)
+			}
+		}
+	}
+	add(config.Tools.RequiredTools)
+	groups := make([]string, 0, len(config.Groups))
+	for group := range config.Groups {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	for _, group := range groups {
+		add(config.Groups[group])
+	}
+	add(config.Tools.InstalledApps)
+	return entries
+}
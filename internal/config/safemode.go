@@ -0,0 +1,220 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/0xjuanma/anvil/internal/constants"
+	"gopkg.in/yaml.v2"
+)
+
+// requiredSections are taken from the sample settings when settings.yaml lacks them
+var requiredSections = []string{"version", "tools", "groups"}
+
+// SectionProblem is a top-level section of settings.yaml that could not be used as written
+type SectionProblem struct {
+	Section string // Top-level key, or the file name when the file does not parse at all
+	Err     error  // Parse error, nil for a missing section
+}
+
+// String describes the problem for listings
+func (p SectionProblem) String() string {
+	if p.Err == nil {
+		return fmt.Sprintf("%s: missing, using defaults", p.Section)
+	}
+	return fmt.Sprintf("%s: %v", p.Section, p.Err)
+}
+
+// safeMode is set when a read-only command runs with corrupt settings
+var safeMode struct {
+	sync.Mutex
+	enabled bool
+}
+
+// EnableSafeMode makes LoadConfig return settings parsed section by section, with defaults for
+// sections that could not be parsed, instead of failing. Saving settings is refused meanwhile,
+// so the corrupt file is never overwritten with defaults.
+func EnableSafeMode() {
+	safeMode.Lock()
+	safeMode.enabled = true
+	safeMode.Unlock()
+	invalidateCache()
+}
+
+// SafeModeEnabled reports whether settings are loaded in safe mode
+func SafeModeEnabled() bool {
+	safeMode.Lock()
+	defer safeMode.Unlock()
+	return safeMode.enabled
+}
+
+// SettingsProblems reads settings.yaml and returns the sections that could not be used
+func SettingsProblems() ([]SectionProblem, error) {
+	data, err := os.ReadFile(GetAnvilConfigPath())
+	if err != nil {
+		return nil, err
+	}
+	_, problems := ParseSettingsBySection(data)
+	return problems, nil
+}
+
+// ParseSettingsBySection parses settings one top-level section at a time. Sections that fail to
+// parse, and required sections that are missing, are taken from the sample settings and reported.
+// A syntax error breaks the whole document, so the text is then split at top-level keys and
+// each block is parsed on its own.
+func ParseSettingsBySection(data []byte) (*AnvilConfig, []SectionProblem) {
+	var defaults yaml.MapSlice
+	_ = yaml.Unmarshal(sampleConfigData, &defaults)
+
+	var problems []SectionProblem
+	var doc []parsedSection
+	var whole yaml.MapSlice
+	if err := yaml.Unmarshal(data, &whole); err == nil {
+		for _, item := range whole {
+			doc = append(doc, parsedSection{name: fmt.Sprint(item.Key), item: item})
+		}
+	} else {
+		doc = splitSections(data)
+		if len(doc) == 0 {
+			problems = append(problems, SectionProblem{Section: constants.ANVIL_CONFIG_FILE, Err: err})
+		}
+	}
+
+	merged := yaml.MapSlice{}
+	present := make(map[string]bool)
+	for _, parsed := range doc {
+		section, item := parsed.name, parsed.item
+		present[section] = true
+		err := parsed.err
+		if err == nil {
+			err = parseSection(item)
+		}
+		if err != nil {
+			problems = append(problems, SectionProblem{Section: section, Err: err})
+			if fallback, ok := lookupSection(defaults, section); ok {
+				merged = append(merged, fallback)
+			}
+			continue
+		}
+		merged = append(merged, item)
+	}
+
+	for _, section := range requiredSections {
+		if present[section] {
+			continue
+		}
+		if fallback, ok := lookupSection(defaults, section); ok {
+			merged = append(merged, fallback)
+		}
+		if len(doc) > 0 {
+			problems = append(problems, SectionProblem{Section: section})
+		}
+	}
+
+	var config AnvilConfig
+	out, err := yaml.Marshal(merged)
+	if err == nil {
+		err = yaml.Unmarshal(out, &config)
+	}
+	if err != nil {
+		config = AnvilConfig{}
+		_ = yaml.Unmarshal(sampleConfigData, &config)
+	}
+	return &config, problems
+}
+
+// parsedSection is a top-level section, err is set when its text does not parse
+type parsedSection struct {
+	name string
+	item yaml.MapItem
+	err  error
+}
+
+// splitSections parses each top-level block of a document that fails to parse as a whole.
+// A block starts at an unindented key and runs until the next one, and is padded to its
+// original offset so parse errors carry the line number of the file.
+func splitSections(data []byte) []parsedSection {
+	var sections []parsedSection
+	var name string
+	var start int
+	var block []string
+	flush := func() {
+		if name == "" {
+			return
+		}
+		section := parsedSection{name: name}
+		var item yaml.MapSlice
+		if err := yaml.Unmarshal([]byte(strings.Repeat("\n", start)+strings.Join(block, "\n")), &item); err != nil || len(item) != 1 {
+			if err == nil {
+				err = fmt.Errorf("not a single section")
+			}
+			section.err = err
+		} else {
+			section.item = item[0]
+		}
+		sections = append(sections, section)
+	}
+
+	for i, line := range strings.Split(string(data), "\n") {
+		if key, ok := topLevelKey(line); ok {
+			flush()
+			name, start, block = key, i, nil
+		}
+		if name != "" {
+			block = append(block, line)
+		}
+	}
+	flush()
+	return sections
+}
+
+// topLevelKey returns the key of an unindented "key:" line
+func topLevelKey(line string) (string, bool) {
+	if line == "" || line[0] == ' ' || line[0] == '\t' || line[0] == '#' || line[0] == '-' {
+		return "", false
+	}
+	key, _, found := strings.Cut(line, ":")
+	key = strings.Trim(strings.TrimSpace(key), `"'`)
+	if !found || key == "" || strings.ContainsAny(key, " {}[],") {
+		return "", false
+	}
+	return key, true
+}
+
+// parseSection checks that a single top-level section decodes into the settings
+func parseSection(item yaml.MapItem) error {
+	out, err := yaml.Marshal(yaml.MapSlice{item})
+	if err != nil {
+		return err
+	}
+	var config AnvilConfig
+	return yaml.Unmarshal(out, &config)
+}
+
+// lookupSection returns a top-level section of a parsed document
+func lookupSection(doc yaml.MapSlice, section string) (yaml.MapItem, bool) {
+	for _, item := range doc {
+		if fmt.Sprint(item.Key) == section {
+			return item, true
+		}
+	}
+	return yaml.MapItem{}, false
+}
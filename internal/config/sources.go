@@ -0,0 +1,49 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ValidateSourceURL checks that a download URL for the sources section is an absolute
+// https URL. Plain http is refused, the download is installed and run without any other check
+func ValidateSourceURL(sourceURL string) error {
+	parsed, err := url.Parse(strings.TrimSpace(sourceURL))
+	if err != nil {
+		return fmt.Errorf("invalid URL '%s': %w", sourceURL, err)
+	}
+	if parsed.Scheme != "https" {
+		return fmt.Errorf("'%s' is not an https URL", sourceURL)
+	}
+	if parsed.Host == "" {
+		return fmt.Errorf("'%s' has no host", sourceURL)
+	}
+	return nil
+}
+
+// SetAppSource records the download URL of an app in the sources section, so later installs
+// and provisioning use it instead of the package manager
+func SetAppSource(appName, sourceURL string) error {
+	return withConfigAndSave(func(config *AnvilConfig) error {
+		ensureMap(&config.Sources)
+		config.Sources[appName] = strings.TrimSpace(sourceURL)
+		return nil
+	})
+}
@@ -0,0 +1,182 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"slices"
+	"strings"
+)
+
+// AppCategory is a built-in category of well-known packages, suggested as a group
+type AppCategory struct {
+	Group string // Suggested group name
+	Title string
+}
+
+// appCategories lists the categories in the order suggestions are made
+var appCategories = []AppCategory{
+	{Group: "dev-tools", Title: "Developer tools"},
+	{Group: "browsers", Title: "Browsers"},
+	{Group: "communication", Title: "Communication"},
+	{Group: "media", Title: "Media & design"},
+	{Group: "productivity", Title: "Productivity"},
+}
+
+// knownApps maps Homebrew package names to the group of their category
+var knownApps = map[string]string{
+	// Developer tools
+	"alacritty": "dev-tools", "android-studio": "dev-tools", "awscli": "dev-tools", "azure-cli": "dev-tools",
+	"bat": "dev-tools", "bun": "dev-tools", "cmake": "dev-tools", "colima": "dev-tools", "cursor": "dev-tools",
+	"dbeaver-community": "dev-tools", "deno": "dev-tools", "docker": "dev-tools", "docker-desktop": "dev-tools",
+	"eza": "dev-tools", "fd": "dev-tools", "fnm": "dev-tools", "fork": "dev-tools", "fzf": "dev-tools",
+	"gh": "dev-tools", "ghostty": "dev-tools", "git": "dev-tools", "github": "dev-tools", "gitkraken": "dev-tools",
+	"go": "dev-tools", "goland": "dev-tools", "google-cloud-sdk": "dev-tools", "helm": "dev-tools",
+	"httpie": "dev-tools", "insomnia": "dev-tools", "intellij-idea": "dev-tools", "intellij-idea-ce": "dev-tools",
+	"iterm2": "dev-tools", "jetbrains-toolbox": "dev-tools", "jq": "dev-tools", "k9s": "dev-tools",
+	"kitty": "dev-tools", "kubernetes-cli": "dev-tools", "lazygit": "dev-tools", "mise": "dev-tools",
+	"neovim": "dev-tools", "node": "dev-tools", "nvm": "dev-tools", "orbstack": "dev-tools", "pnpm": "dev-tools",
+	"podman": "dev-tools", "postman": "dev-tools", "pycharm": "dev-tools", "pycharm-ce": "dev-tools",
+	"pyenv": "dev-tools", "python": "dev-tools", "rbenv": "dev-tools", "ripgrep": "dev-tools", "rust": "dev-tools",
+	"rustup": "dev-tools", "sourcetree": "dev-tools", "starship": "dev-tools", "sublime-text": "dev-tools",
+	"tableplus": "dev-tools", "terraform": "dev-tools", "tmux": "dev-tools", "visual-studio-code": "dev-tools",
+	"warp": "dev-tools", "webstorm": "dev-tools", "wezterm": "dev-tools", "xcodes": "dev-tools",
+	"yarn": "dev-tools", "yq": "dev-tools", "zed": "dev-tools",
+
+	// Browsers
+	"arc": "browsers", "brave-browser": "browsers", "chromium": "browsers", "firefox": "browsers",
+	"google-chrome": "browsers", "librewolf": "browsers", "microsoft-edge": "browsers", "opera": "browsers",
+	"orion": "browsers", "safari-technology-preview": "browsers", "tor-browser": "browsers",
+	"vivaldi": "browsers", "zen": "browsers", "zen-browser": "browsers",
+
+	// Communication
+	"discord": "communication", "element": "communication", "loom": "communication",
+	"microsoft-outlook": "communication", "microsoft-teams": "communication", "mimestream": "communication",
+	"signal": "communication", "skype": "communication", "slack": "communication", "spark": "communication",
+	"telegram": "communication", "thunderbird": "communication", "webex": "communication",
+	"whatsapp": "communication", "zoom": "communication",
+
+	// Media & design
+	"affinity-designer": "media", "affinity-photo": "media", "audacity": "media", "blender": "media",
+	"cleanshot": "media", "davinci-resolve": "media", "ffmpeg": "media", "figma": "media", "gimp": "media",
+	"handbrake": "media", "iina": "media", "imageoptim": "media", "infuse": "media", "inkscape": "media",
+	"kap": "media", "obs": "media", "plex": "media", "sketch": "media", "spotify": "media", "vlc": "media",
+	"yt-dlp": "media",
+
+	// Productivity
+	"1password": "productivity", "aerospace": "productivity", "alfred": "productivity", "alt-tab": "productivity",
+	"appcleaner": "productivity", "bartender": "productivity", "bitwarden": "productivity",
+	"dropbox": "productivity", "evernote": "productivity", "fantastical": "productivity",
+	"google-drive": "productivity", "karabiner-elements": "productivity", "keka": "productivity",
+	"logseq": "productivity", "maccy": "productivity", "microsoft-excel": "productivity",
+	"microsoft-powerpoint": "productivity", "microsoft-word": "productivity", "notion": "productivity",
+	"notion-calendar": "productivity", "obsidian": "productivity", "raycast": "productivity",
+	"rectangle": "productivity", "stats": "productivity", "the-unarchiver": "productivity",
+	"todoist": "productivity",
+}
+
+// GroupSuggestion proposes moving installed_apps entries of one category into a group
+type GroupSuggestion struct {
+	Group    string
+	Title    string   // Category the apps were matched by
+	Apps     []string // Entries as written in tools.installed_apps
+	Existing bool     // The group already exists and the apps are added to it
+}
+
+// CategoryOf returns the built-in category group of an app entry, ignoring type prefixes,
+// taps and versions, e.g. "cask:firefox@developer-edition" is a browser
+func CategoryOf(app string) (string, bool) {
+	name := NormalizeAppName(app)
+	if prefix, rest, found := strings.Cut(name, ":"); found {
+		if prefix == "mas" {
+			return "", false
+		}
+		name = rest
+	}
+	name = name[strings.LastIndex(name, "/")+1:]
+	name, _, _ = strings.Cut(name, "@")
+
+	group, ok := knownApps[name]
+	return group, ok
+}
+
+// SuggestGroups proposes groups for the installed_apps entries not covered by any group yet,
+// returning the suggestions in category order and the entries no category matched
+func SuggestGroups(config *AnvilConfig) ([]GroupSuggestion, []string) {
+	grouped := make(map[string]bool)
+	for _, tools := range config.Groups {
+		for _, tool := range tools {
+			grouped[NormalizeAppName(tool)] = true
+		}
+	}
+
+	byGroup := make(map[string][]string)
+	var uncategorized []string
+	for _, app := range config.Tools.InstalledApps {
+		if grouped[NormalizeAppName(app)] {
+			continue
+		}
+		if group, ok := CategoryOf(app); ok {
+			byGroup[group] = append(byGroup[group], app)
+		} else {
+			uncategorized = append(uncategorized, app)
+		}
+	}
+
+	var suggestions []GroupSuggestion
+	for _, category := range appCategories {
+		apps := byGroup[category.Group]
+		if len(apps) == 0 {
+			continue
+		}
+		_, exists := config.Groups[category.Group]
+		suggestions = append(suggestions, GroupSuggestion{
+			Group:    category.Group,
+			Title:    category.Title,
+			Apps:     apps,
+			Existing: exists,
+		})
+	}
+	return suggestions, uncategorized
+}
+
+// ApplyGroupSuggestions adds the suggested apps to their groups, creating missing groups, and
+// removes them from tools.installed_apps now that a group tracks them
+func ApplyGroupSuggestions(config *AnvilConfig, suggestions []GroupSuggestion) {
+	if config.Groups == nil {
+		config.Groups = make(AnvilGroups)
+	}
+
+	moved := make(map[string]bool)
+	for _, suggestion := range suggestions {
+		tools := config.Groups[suggestion.Group]
+		for _, app := range suggestion.Apps {
+			if !slices.Contains(tools, app) {
+				tools = append(tools, app)
+			}
+			moved[app] = true
+		}
+		config.Groups[suggestion.Group] = tools
+	}
+
+	remaining := config.Tools.InstalledApps[:0]
+	for _, app := range config.Tools.InstalledApps {
+		if !moved[app] {
+			remaining = append(remaining, app)
+		}
+	}
+	config.Tools.InstalledApps = remaining
+}
@@ -0,0 +1,175 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// MachineIDEnvVar overrides the hostname used to match machine-scoped sync rules
+const MachineIDEnvVar = "ANVIL_MACHINE_ID"
+
+// SyncConfig controls selective synchronization of pulled configs
+type SyncConfig struct {
+	Rules         []SyncRule `yaml:"rules,omitempty"`
+	NewGroupTools string     `yaml:"new_group_tools,omitempty"` // prompt (default), auto or off: installing tools added to installed groups
+}
+
+// SyncRule keeps local values for excluded settings keys or app files, optionally only on matching machines
+type SyncRule struct {
+	Hosts   []string `yaml:"hosts,omitempty"` // Hostname glob patterns, empty matches every machine
+	Apps    []string `yaml:"apps,omitempty"`  // Apps the rule applies to ("anvil" for settings.yaml), empty matches all
+	Exclude []string `yaml:"exclude"`         // Dotted settings keys for anvil, file globs for app configs
+}
+
+// CurrentMachineID returns the identifier matched against sync rule host patterns
+func CurrentMachineID() string {
+	if id := strings.TrimSpace(os.Getenv(MachineIDEnvVar)); id != "" {
+		return strings.ToLower(id)
+	}
+	host, _ := os.Hostname()
+	return strings.TrimSuffix(strings.ToLower(host), ".local")
+}
+
+// MatchesHost reports whether the rule applies to the given machine
+func (r SyncRule) MatchesHost(machineID string) bool {
+	if len(r.Hosts) == 0 {
+		return true
+	}
+	for _, pattern := range r.Hosts {
+		if matched, _ := path.Match(strings.ToLower(pattern), machineID); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchesApp reports whether the rule applies to the given app
+func (r SyncRule) MatchesApp(appName string) bool {
+	return len(r.Apps) == 0 || slices.Contains(r.Apps, appName)
+}
+
+// SyncExcludesFor collects the exclude entries of every rule matching the app and machine
+func SyncExcludesFor(rules []SyncRule, appName, machineID string) []string {
+	var excludes []string
+	for _, rule := range rules {
+		if rule.MatchesApp(appName) && rule.MatchesHost(machineID) {
+			excludes = append(excludes, rule.Exclude...)
+		}
+	}
+	return excludes
+}
+
+// ValidateSyncRules checks rule patterns and returns a description of each problem found
+func ValidateSyncRules(rules []SyncRule) []string {
+	var problems []string
+	for i, rule := range rules {
+		if len(rule.Exclude) == 0 {
+			problems = append(problems, fmt.Sprintf("rule %d has no exclude entries", i+1))
+		}
+		for _, pattern := range append(append([]string{}, rule.Hosts...), rule.Exclude...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				problems = append(problems, fmt.Sprintf("rule %d has an invalid pattern %q", i+1, pattern))
+			}
+		}
+	}
+	return problems
+}
+
+// ApplySettingsExcludes returns the pulled settings with excluded keys replaced by their current local values.
+// Keys are dotted paths into the YAML document, such as "git" or "github.local_path".
+func ApplySettingsExcludes(pulled, current []byte, excludes []string) ([]byte, error) {
+	var pulledDoc, currentDoc yaml.MapSlice
+	if err := yaml.Unmarshal(pulled, &pulledDoc); err != nil {
+		return nil, fmt.Errorf("failed to parse pulled settings: %w", err)
+	}
+	if err := yaml.Unmarshal(current, &currentDoc); err != nil {
+		return nil, fmt.Errorf("failed to parse local settings: %w", err)
+	}
+
+	for _, key := range excludes {
+		keys := strings.Split(key, ".")
+		if value, ok := lookupMapSlice(currentDoc, keys); ok {
+			pulledDoc = setMapSlice(pulledDoc, keys, value)
+		} else {
+			pulledDoc = deleteMapSlice(pulledDoc, keys)
+		}
+	}
+
+	return yaml.Marshal(pulledDoc)
+}
+
+// lookupMapSlice finds the value at a dotted key path
+func lookupMapSlice(doc yaml.MapSlice, keys []string) (interface{}, bool) {
+	for _, item := range doc {
+		if fmt.Sprint(item.Key) != keys[0] {
+			continue
+		}
+		if len(keys) == 1 {
+			return item.Value, true
+		}
+		if nested, ok := item.Value.(yaml.MapSlice); ok {
+			return lookupMapSlice(nested, keys[1:])
+		}
+		return nil, false
+	}
+	return nil, false
+}
+
+// setMapSlice sets the value at a dotted key path, creating intermediate maps as needed
+func setMapSlice(doc yaml.MapSlice, keys []string, value interface{}) yaml.MapSlice {
+	for i, item := range doc {
+		if fmt.Sprint(item.Key) != keys[0] {
+			continue
+		}
+		if len(keys) == 1 {
+			doc[i].Value = value
+			return doc
+		}
+		nested, _ := item.Value.(yaml.MapSlice)
+		doc[i].Value = setMapSlice(nested, keys[1:], value)
+		return doc
+	}
+
+	if len(keys) == 1 {
+		return append(doc, yaml.MapItem{Key: keys[0], Value: value})
+	}
+	return append(doc, yaml.MapItem{Key: keys[0], Value: setMapSlice(nil, keys[1:], value)})
+}
+
+// deleteMapSlice removes the value at a dotted key path if present
+func deleteMapSlice(doc yaml.MapSlice, keys []string) yaml.MapSlice {
+	for i, item := range doc {
+		if fmt.Sprint(item.Key) != keys[0] {
+			continue
+		}
+		if len(keys) == 1 {
+			return append(doc[:i], doc[i+1:]...)
+		}
+		if nested, ok := item.Value.(yaml.MapSlice); ok {
+			doc[i].Value = deleteMapSlice(nested, keys[1:])
+		}
+		return doc
+	}
+	return doc
+}
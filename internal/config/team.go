@@ -0,0 +1,213 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/0xjuanma/anvil/internal/constants"
+	"gopkg.in/yaml.v2"
+)
+
+// TeamSettingsEnvVar overrides the location of the team layer, e.g. for files deployed by MDM
+const TeamSettingsEnvVar = "ANVIL_TEAM_SETTINGS"
+
+// TeamLayer holds settings distributed by an organization. Values in its protected block
+// always win over local settings.yaml edits.
+type TeamLayer struct {
+	Protected       yaml.MapSlice `yaml:"protected"`
+	MinAnvilVersion string        `yaml:"min_anvil_version,omitempty"` // Oldest anvil release allowed to run with these settings
+}
+
+// ProtectedConflict describes a local setting that contradicts a protected team setting
+type ProtectedConflict struct {
+	Key     string
+	Message string
+}
+
+func (c ProtectedConflict) String() string {
+	return fmt.Sprintf("%s: %s", c.Key, c.Message)
+}
+
+// GetTeamSettingsPath returns the path to the team layer file
+func GetTeamSettingsPath() string {
+	if path := os.Getenv(TeamSettingsEnvVar); path != "" {
+		return path
+	}
+	return filepath.Join(GetAnvilConfigDirectory(), constants.TEAM_CONFIG_FILE)
+}
+
+// LoadTeamLayer loads the team layer, returning nil when no team layer is installed
+func LoadTeamLayer() (*TeamLayer, error) {
+	data, err := os.ReadFile(GetTeamSettingsPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", constants.TEAM_CONFIG_FILE, err)
+	}
+
+	var layer TeamLayer
+	if err := yaml.Unmarshal(data, &layer); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", constants.TEAM_CONFIG_FILE, err)
+	}
+	return &layer, nil
+}
+
+// FindProtectedConflicts compares local settings against the protected block. Scalars must match,
+// lists must contain every protected item (local additions are allowed) and maps are compared key by key.
+func FindProtectedConflicts(local, protected yaml.MapSlice) []ProtectedConflict {
+	return findProtectedConflicts(local, protected, "")
+}
+
+func findProtectedConflicts(local, protected yaml.MapSlice, prefix string) []ProtectedConflict {
+	var conflicts []ProtectedConflict
+	for _, item := range protected {
+		key := prefix + fmt.Sprint(item.Key)
+		localValue, exists := lookupMapSlice(local, []string{fmt.Sprint(item.Key)})
+
+		switch want := item.Value.(type) {
+		case yaml.MapSlice:
+			nested, _ := localValue.(yaml.MapSlice)
+			conflicts = append(conflicts, findProtectedConflicts(nested, want, key+".")...)
+		case []interface{}:
+			have, _ := localValue.([]interface{})
+			if missing := missingItems(have, want); len(missing) > 0 {
+				conflicts = append(conflicts, ProtectedConflict{Key: key, Message: "missing " + strings.Join(missing, ", ")})
+			}
+		default:
+			if !exists {
+				conflicts = append(conflicts, ProtectedConflict{Key: key, Message: fmt.Sprintf("missing, must be %v", want)})
+			} else if fmt.Sprint(localValue) != fmt.Sprint(want) {
+				conflicts = append(conflicts, ProtectedConflict{Key: key, Message: fmt.Sprintf("is %v, must be %v", localValue, want)})
+			}
+		}
+	}
+	return conflicts
+}
+
+// ApplyProtected returns local settings with the protected block enforced
+func ApplyProtected(local, protected yaml.MapSlice) yaml.MapSlice {
+	for _, item := range protected {
+		keys := []string{fmt.Sprint(item.Key)}
+		localValue, _ := lookupMapSlice(local, keys)
+
+		switch want := item.Value.(type) {
+		case yaml.MapSlice:
+			nested, _ := localValue.(yaml.MapSlice)
+			local = setMapSlice(local, keys, ApplyProtected(nested, want))
+		case []interface{}:
+			have, _ := localValue.([]interface{})
+			merged := append([]interface{}{}, have...)
+			for _, value := range want {
+				if !containsItem(have, value) {
+					merged = append(merged, value)
+				}
+			}
+			local = setMapSlice(local, keys, merged)
+		default:
+			local = setMapSlice(local, keys, want)
+		}
+	}
+	return local
+}
+
+// EnforceProtectedSettings applies the team layer's protected block to raw settings data.
+// It returns the data unchanged when no team layer is installed.
+func EnforceProtectedSettings(data []byte) ([]byte, []ProtectedConflict, error) {
+	layer, err := LoadTeamLayer()
+	if err != nil || layer == nil || len(layer.Protected) == 0 {
+		return data, nil, err
+	}
+
+	var local yaml.MapSlice
+	if err := yaml.Unmarshal(data, &local); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %w", constants.ANVIL_CONFIG_FILE, err)
+	}
+
+	conflicts := FindProtectedConflicts(local, layer.Protected)
+	if len(conflicts) == 0 {
+		return data, nil, nil
+	}
+
+	enforced, err := yaml.Marshal(ApplyProtected(local, layer.Protected))
+	if err != nil {
+		return nil, nil, err
+	}
+	return enforced, conflicts, nil
+}
+
+// ValidateProtectedSettings returns an error listing local settings that conflict with the team layer
+func ValidateProtectedSettings() error {
+	data, err := os.ReadFile(GetAnvilConfigPath())
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", constants.ANVIL_CONFIG_FILE, err)
+	}
+
+	_, conflicts, err := EnforceProtectedSettings(data)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		messages := make([]string, len(conflicts))
+		for i, conflict := range conflicts {
+			messages[i] = conflict.String()
+		}
+		return fmt.Errorf("%s conflicts with protected team settings: %s", constants.ANVIL_CONFIG_FILE, strings.Join(messages, "; "))
+	}
+	return nil
+}
+
+// RestoreProtectedSettings rewrites settings.yaml with the protected team settings enforced
+func RestoreProtectedSettings() error {
+	configPath := GetAnvilConfigPath()
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", constants.ANVIL_CONFIG_FILE, err)
+	}
+
+	enforced, conflicts, err := EnforceProtectedSettings(data)
+	if err != nil || len(conflicts) == 0 {
+		return err
+	}
+
+	var restored AnvilConfig
+	_ = yaml.Unmarshal(enforced, &restored)
+	return writeSettings(enforced, restored.SettingsBackups)
+}
+
+// missingItems returns the protected list items absent from the local list
+func missingItems(have, want []interface{}) []string {
+	var missing []string
+	for _, value := range want {
+		if !containsItem(have, value) {
+			missing = append(missing, fmt.Sprint(value))
+		}
+	}
+	return missing
+}
+
+// containsItem reports whether a YAML list contains a value
+func containsItem(list []interface{}, value interface{}) bool {
+	return slices.ContainsFunc(list, func(item interface{}) bool {
+		return fmt.Sprint(item) == fmt.Sprint(value)
+	})
+}
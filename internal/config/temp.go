@@ -0,0 +1,220 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/i18n"
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultTempMaxAge is how old a pulled copy gets before sync warns about it and pruning removes it
+const DefaultTempMaxAge = 30 * 24 * time.Hour
+
+// PreviousPullDir holds the last pull of each app under the temp directory, for quick diffing
+const PreviousPullDir = ".previous"
+
+// tempMetadataDir holds one metadata file per pulled copy, kept out of the copies so syncs never carry it
+const tempMetadataDir = ".meta"
+
+// TempConfig controls how long pulled copies under ~/.anvil/temp are trusted
+type TempConfig struct {
+	MaxAgeDays int `yaml:"max_age_days,omitempty"` // Days before a pulled copy counts as stale (0 = default 30)
+}
+
+// MaxAge returns the age after which a pulled copy is stale
+func (t TempConfig) MaxAge() time.Duration {
+	if t.MaxAgeDays <= 0 {
+		return DefaultTempMaxAge
+	}
+	return time.Duration(t.MaxAgeDays) * 24 * time.Hour
+}
+
+// TempEntry is a pulled copy in the temp directory and where it came from
+type TempEntry struct {
+	Name     string    `yaml:"-"`
+	Path     string    `yaml:"-"`
+	PulledAt time.Time `yaml:"pulled_at"`
+	Repo     string    `yaml:"repo,omitempty"`
+	Branch   string    `yaml:"branch,omitempty"`
+	Commit   string    `yaml:"commit,omitempty"`
+
+	// Oldest app version the configs work with, from the repository manifest when pulled
+	AppVersion AppVersionRequirement `yaml:"app_version,omitempty"`
+
+	// Recorded is false for copies pulled before metadata was kept, PulledAt is then the
+	// modification time of the directory
+	Recorded bool `yaml:"-"`
+}
+
+// Age returns how long ago the copy was pulled
+func (e TempEntry) Age(now time.Time) time.Duration {
+	return now.Sub(e.PulledAt)
+}
+
+// IsStale reports whether the copy is older than maxAge
+func (e TempEntry) IsStale(maxAge time.Duration, now time.Time) bool {
+	return e.Age(now) > maxAge
+}
+
+// ShortCommit returns the abbreviated source commit, or "unknown" when it was not recorded
+func (e TempEntry) ShortCommit() string {
+	if e.Commit == "" {
+		return "unknown"
+	}
+	if len(e.Commit) > 7 {
+		return e.Commit[:7]
+	}
+	return e.Commit
+}
+
+// GetTempDirectory returns the directory pulled configs are copied to
+func GetTempDirectory() string {
+	return filepath.Join(GetAnvilConfigDirectory(), "temp")
+}
+
+// getTempMetadataPath returns the metadata file of a pulled copy
+func getTempMetadataPath(name string) string {
+	return filepath.Join(GetTempDirectory(), tempMetadataDir, name+".yaml")
+}
+
+// RecordTempPull writes the metadata of a copy that was just pulled
+func RecordTempPull(entry TempEntry) error {
+	data, err := yaml.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	metadataPath := getTempMetadataPath(entry.Name)
+	if err := os.MkdirAll(filepath.Dir(metadataPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(metadataPath, data, 0644)
+}
+
+// GetTempEntry returns the pulled copy of name, found is false when it was never pulled
+func GetTempEntry(name string) (TempEntry, bool, error) {
+	entry := TempEntry{Name: name, Path: filepath.Join(GetTempDirectory(), name)}
+	info, err := os.Stat(entry.Path)
+	if os.IsNotExist(err) {
+		return entry, false, nil
+	}
+	if err != nil {
+		return entry, false, err
+	}
+
+	data, err := os.ReadFile(getTempMetadataPath(name))
+	if os.IsNotExist(err) {
+		entry.PulledAt = info.ModTime()
+		return entry, true, nil
+	}
+	if err != nil {
+		return entry, true, err
+	}
+	if err := yaml.Unmarshal(data, &entry); err != nil {
+		return entry, true, fmt.Errorf("invalid pull metadata for %s: %w", name, err)
+	}
+	entry.Recorded = true
+	return entry, true, nil
+}
+
+// ListTempEntries returns the pulled copies sorted by name. Hidden directories, which hold
+// previous pulls, staging copies and metadata, are skipped.
+func ListTempEntries() ([]TempEntry, error) {
+	dirEntries, err := os.ReadDir(GetTempDirectory())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []TempEntry
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() || strings.HasPrefix(dirEntry.Name(), ".") {
+			continue
+		}
+		entry, found, err := GetTempEntry(dirEntry.Name())
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries, nil
+}
+
+// StaleTempEntries returns the pulled copies older than maxAge
+func StaleTempEntries(maxAge time.Duration, now time.Time) ([]TempEntry, error) {
+	entries, err := ListTempEntries()
+	if err != nil {
+		return nil, err
+	}
+	var stale []TempEntry
+	for _, entry := range entries {
+		if entry.IsStale(maxAge, now) {
+			stale = append(stale, entry)
+		}
+	}
+	return stale, nil
+}
+
+// RemoveTempEntry deletes a pulled copy together with its metadata and previous pull
+func RemoveTempEntry(name string) error {
+	tempDir := GetTempDirectory()
+	for _, path := range []string{
+		filepath.Join(tempDir, name),
+		filepath.Join(tempDir, PreviousPullDir, name),
+		getTempMetadataPath(name),
+	} {
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FormatAge renders an age in whole days, or hours and minutes for recent copies
+func FormatAge(age time.Duration) string {
+	switch {
+	case age >= 48*time.Hour:
+		return i18n.T("age.days", int(age.Hours()/24))
+	case age >= 24*time.Hour:
+		return i18n.T("age.day")
+	case age >= time.Hour:
+		return i18n.T("age.hours", int(age.Hours()))
+	default:
+		return i18n.T("age.minutes", int(age.Minutes()))
+	}
+}
+
+// GetTempMaxAge returns the configured age after which pulled copies are stale
+func GetTempMaxAge() time.Duration {
+	maxAge := DefaultTempMaxAge
+	_ = withConfig(func(config *AnvilConfig) error {
+		maxAge = config.Temp.MaxAge()
+		return nil
+	})
+	return maxAge
+}
@@ -0,0 +1,294 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/constants"
+	"gopkg.in/yaml.v2"
+)
+
+// transactionTimeFormat names transactions so they sort oldest to newest
+const transactionTimeFormat = "20060102-150405.000000000"
+
+// maxSummaryKeys bounds how many changed keys a transaction summary lists
+const maxSummaryKeys = 4
+
+// SettingsTransaction is one change anvil made to settings.yaml, with the file before and after
+type SettingsTransaction struct {
+	ID       string    `yaml:"id"`
+	Time     time.Time `yaml:"time"`
+	Command  string    `yaml:"command,omitempty"`   // Invocation that made the change, e.g. "anvil install git"
+	Summary  string    `yaml:"summary,omitempty"`   // Settings keys that changed
+	Undoes   string    `yaml:"undoes,omitempty"`    // Transaction this one reverted
+	UndoneBy string    `yaml:"undone_by,omitempty"` // Transaction that reverted this one
+	Before   string    `yaml:"before"`
+	After    string    `yaml:"after"`
+}
+
+// Undoable reports whether 'anvil undo' may revert the transaction. Undos themselves are
+// not undone, so repeated undos keep walking back through history.
+func (t SettingsTransaction) Undoable() bool {
+	return t.Undoes == "" && t.UndoneBy == ""
+}
+
+// transactionCommand is the invocation recorded with each transaction of this run
+var (
+	transactionCommand string
+	transactionMutex   sync.Mutex
+)
+
+// SetTransactionCommand sets the invocation recorded with settings changes made by this run
+func SetTransactionCommand(command string) {
+	transactionMutex.Lock()
+	defer transactionMutex.Unlock()
+	transactionCommand = command
+}
+
+// GetTransactionDirectory returns the directory holding the settings transaction log
+func GetTransactionDirectory() string {
+	return filepath.Join(GetAnvilConfigDirectory(), "transactions")
+}
+
+// recordTransaction logs the change of settings.yaml from before to after and drops the
+// oldest transactions beyond the limit. It returns the ID of the new transaction.
+func recordTransaction(before, after []byte, undoes string) (string, error) {
+	transactionMutex.Lock()
+	defer transactionMutex.Unlock()
+
+	dir := GetTransactionDirectory()
+	if err := os.MkdirAll(dir, constants.DirPerm); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	transaction := SettingsTransaction{
+		ID:      now.Format(transactionTimeFormat),
+		Time:    now,
+		Command: transactionCommand,
+		Summary: summarizeSettingsChange(before, after),
+		Undoes:  undoes,
+		Before:  string(before),
+		After:   string(after),
+	}
+	if err := writeTransaction(transaction); err != nil {
+		return "", err
+	}
+
+	transactions, err := ListTransactions()
+	if err != nil {
+		return transaction.ID, err
+	}
+	for _, old := range transactions[min(constants.SettingsTransactions, len(transactions)):] {
+		if err := os.Remove(transactionPath(old.ID)); err != nil {
+			return transaction.ID, err
+		}
+	}
+	return transaction.ID, nil
+}
+
+// transactionPath returns the file of a transaction
+func transactionPath(id string) string {
+	return filepath.Join(GetTransactionDirectory(), id+".yaml")
+}
+
+// writeTransaction saves a transaction to the log
+func writeTransaction(transaction SettingsTransaction) error {
+	data, err := yaml.Marshal(transaction)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(transactionPath(transaction.ID), data, constants.FilePerm)
+}
+
+// ListTransactions returns the settings transactions, most recent first
+func ListTransactions() ([]SettingsTransaction, error) {
+	entries, err := os.ReadDir(GetTransactionDirectory())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var transactions []SettingsTransaction
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".yaml") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(GetTransactionDirectory(), entry.Name()))
+		if err != nil {
+			continue
+		}
+		var transaction SettingsTransaction
+		if err := yaml.Unmarshal(data, &transaction); err != nil || transaction.ID == "" {
+			continue
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	sort.Slice(transactions, func(i, j int) bool { return transactions[i].ID > transactions[j].ID })
+	return transactions, nil
+}
+
+// UndoableTransactions returns the transactions 'anvil undo' would revert next, most recent first
+func UndoableTransactions() ([]SettingsTransaction, error) {
+	transactions, err := ListTransactions()
+	if err != nil {
+		return nil, err
+	}
+	var undoable []SettingsTransaction
+	for _, transaction := range transactions {
+		if transaction.Undoable() {
+			undoable = append(undoable, transaction)
+		}
+	}
+	return undoable, nil
+}
+
+// UndoTransactions reverts the steps most recent undoable transactions, newest first, each
+// recorded as a transaction of its own. Unless force is set, it stops when settings.yaml no
+// longer matches what a transaction left behind, so edits made outside anvil are not lost.
+func UndoTransactions(steps int, force bool) ([]SettingsTransaction, error) {
+	undoable, err := UndoableTransactions()
+	if err != nil {
+		return nil, err
+	}
+	if steps < 1 || steps > len(undoable) {
+		return nil, fmt.Errorf("cannot undo %d change(s), %d available", steps, len(undoable))
+	}
+
+	var undone []SettingsTransaction
+	for _, transaction := range undoable[:steps] {
+		current, err := os.ReadFile(GetAnvilConfigPath())
+		if err != nil && !os.IsNotExist(err) {
+			return undone, err
+		}
+		if !force && !bytes.Equal(current, []byte(transaction.After)) {
+			return undone, fmt.Errorf("%s changed since %s, run with --force to revert anyway",
+				constants.ANVIL_CONFIG_FILE, transaction.Time.Format("2006-01-02 15:04:05"))
+		}
+
+		before := []byte(transaction.Before)
+		if err := ValidSettings(before); err != nil {
+			return undone, fmt.Errorf("settings before %s are not valid YAML: %w", transaction.ID, err)
+		}
+		var restored AnvilConfig
+		_ = yaml.Unmarshal(before, &restored)
+		undoID, err := commitSettings(before, restored.SettingsBackups, transaction.ID)
+		if err != nil {
+			return undone, err
+		}
+
+		transaction.UndoneBy = undoID
+		if err := writeTransaction(transaction); err != nil {
+			return undone, err
+		}
+		undone = append(undone, transaction)
+	}
+	return undone, nil
+}
+
+// summarizeSettingsChange names the settings keys that differ between two versions of
+// settings.yaml, two levels deep, e.g. "groups.dev, tools.installed_apps"
+func summarizeSettingsChange(before, after []byte) string {
+	var old, updated map[string]interface{}
+	if yaml.Unmarshal(before, &old) != nil || yaml.Unmarshal(after, &updated) != nil {
+		return ""
+	}
+
+	var changed []string
+	for _, key := range unionKeys(old, updated) {
+		if sameSetting(old[key], updated[key]) {
+			continue
+		}
+		oldMap, oldIsMap := old[key].(map[interface{}]interface{})
+		newMap, newIsMap := updated[key].(map[interface{}]interface{})
+		if !oldIsMap || !newIsMap {
+			changed = append(changed, key)
+			continue
+		}
+		oldSection, newSection := stringKeys(oldMap), stringKeys(newMap)
+		for _, subKey := range unionKeys(oldSection, newSection) {
+			if !sameSetting(oldSection[subKey], newSection[subKey]) {
+				changed = append(changed, key+"."+subKey)
+			}
+		}
+	}
+
+	if len(changed) > maxSummaryKeys {
+		return fmt.Sprintf("%s and %d more", strings.Join(changed[:maxSummaryKeys], ", "), len(changed)-maxSummaryKeys)
+	}
+	return strings.Join(changed, ", ")
+}
+
+// sameSetting reports whether two settings values are equal. Empty values equal missing ones,
+// omitempty drops them when anvil rewrites a hand-written file.
+func sameSetting(a, b interface{}) bool {
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+	return isEmptySetting(a) && isEmptySetting(b)
+}
+
+// isEmptySetting reports whether a YAML value is missing or the zero value of its kind
+func isEmptySetting(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	default:
+		return v.IsZero()
+	}
+}
+
+// stringKeys converts a YAML section to a map keyed by strings, keys are compared as text
+func stringKeys(section map[interface{}]interface{}) map[string]interface{} {
+	converted := make(map[string]interface{}, len(section))
+	for key, value := range section {
+		converted[fmt.Sprint(key)] = value
+	}
+	return converted
+}
+
+// unionKeys returns the keys of both maps, sorted
+func unionKeys(a, b map[string]interface{}) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	var keys []string
+	for _, m := range []map[string]interface{}{a, b} {
+		for key := range m {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,117 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import "strings"
+
+// matchesApp reports whether a settings entry names the app, ignoring case and type annotations
+func matchesApp(entry, appName string) bool {
+	_, name := splitTypeAnnotation(entry)
+	_, app := splitTypeAnnotation(appName)
+	return NormalizeAppName(name) == NormalizeAppName(app)
+}
+
+// AppListings returns the sections of settings.yaml that list the app, in a stable order,
+// e.g. ["tools.installed_apps", "groups.dev"]
+func (c *AnvilConfig) AppListings(appName string) []string {
+	var listed []string
+	sections, lists := appSections(c)
+	for _, section := range sections {
+		for _, entry := range lists[section] {
+			if matchesApp(entry, appName) {
+				listed = append(listed, section)
+				break
+			}
+		}
+	}
+	return listed
+}
+
+// GroupsListing returns the names of the groups that list the app, sorted
+func (c *AnvilConfig) GroupsListing(appName string) []string {
+	var groups []string
+	for _, section := range c.AppListings(appName) {
+		if group, ok := strings.CutPrefix(section, sectionGroupPrefix); ok {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// removeEntries drops the entries naming the app and reports whether any were removed
+func removeEntries(entries []string, appName string) ([]string, bool) {
+	var kept []string
+	removed := false
+	for _, entry := range entries {
+		if matchesApp(entry, appName) {
+			removed = true
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	return kept, removed
+}
+
+// UntrackApp removes the app from installed_apps and, with fromGroups, from every group along
+// with its critical and ordering tags there. required_tools entries are kept. Platform tags are dropped once
+// the app is no longer listed anywhere. It returns the sections the app was removed from.
+func UntrackApp(appName string, fromGroups bool) ([]string, error) {
+	var untracked []string
+	err := withConfigAndSave(func(config *AnvilConfig) error {
+		var removed bool
+		if config.Tools.InstalledApps, removed = removeEntries(config.Tools.InstalledApps, appName); removed {
+			untracked = append(untracked, SectionInstalledApps)
+		}
+
+		if fromGroups {
+			sections, _ := appSections(config)
+			for _, section := range sections {
+				group, isGroup := strings.CutPrefix(section, sectionGroupPrefix)
+				if !isGroup {
+					continue
+				}
+				if config.Groups[group], removed = removeEntries(config.Groups[group], appName); !removed {
+					continue
+				}
+				untracked = append(untracked, section)
+				for entry := range config.ToolCritical[group] {
+					if matchesApp(entry, appName) {
+						delete(config.ToolCritical[group], entry)
+					}
+				}
+				for entry, tags := range config.ToolOrdering[group] {
+					if matchesApp(entry, appName) {
+						delete(config.ToolOrdering[group], entry)
+						continue
+					}
+					tags.After, _ = removeEntries(tags.After, appName)
+					config.ToolOrdering[group][entry] = tags
+				}
+			}
+		}
+
+		if len(config.AppListings(appName)) == 0 {
+			for entry := range config.ToolPlatforms {
+				if matchesApp(entry, appName) {
+					delete(config.ToolPlatforms, entry)
+				}
+			}
+		}
+		return nil
+	})
+	return untracked, err
+}
@@ -20,8 +20,10 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"sort"
 	"strings"
 
+	"github.com/0xjuanma/anvil/internal/constants"
 	"github.com/0xjuanma/palantir"
 )
 
@@ -87,8 +89,9 @@ func (cv *ConfigValidator) ValidateGroupName(groupName string) error {
 
 // ValidateAppName validates an application name
 func (cv *ConfigValidator) ValidateAppName(appName string) error {
-	if err := validateString(appName, "application name", 100, `^[a-zA-Z0-9_.-]+$`); err != nil {
-		return fmt.Errorf("application name '%s' contains invalid characters. Only alphanumeric, underscore, dot, and dash are allowed", appName)
+	// Entries may carry an explicit install type annotation, e.g. "cask:docker-desktop"
+	if err := validateString(appName, "application name", 100, `^((cask|formula|mas):)?[a-zA-Z0-9_.-]+$`); err != nil {
+		return fmt.Errorf("application name '%s' contains invalid characters. Only alphanumeric, underscore, dot, and dash are allowed, with an optional cask:, formula: or mas: prefix", appName)
 	}
 	return nil
 }
@@ -135,6 +138,53 @@ func (cv *ConfigValidator) ValidateConfig(config interface{}) error {
 		return fmt.Errorf("groups validation failed: %w", err)
 	}
 
+	// Validate group failure policies
+	for group, policy := range anvilConfig.FailurePolicies {
+		if err := ValidateFailurePolicy(policy); err != nil {
+			return fmt.Errorf("failure policy for group '%s': %w", group, err)
+		}
+	}
+
+	// Validate order and after tags of group entries
+	for group := range anvilConfig.ToolOrdering {
+		if unknown := anvilConfig.UnknownAfterTools(group); len(unknown) > 0 {
+			return fmt.Errorf("after tags in group '%s' name tools the group does not list: %s", group, strings.Join(unknown, ", "))
+		}
+		if _, err := anvilConfig.PlanGroupOrder(group, anvilConfig.Groups[group]); err != nil {
+			return fmt.Errorf("install order for group '%s': %w", group, err)
+		}
+	}
+
+	// Validate install hooks
+	if err := ValidateHooks(anvilConfig.Hooks); err != nil {
+		return err
+	}
+
+	// Validate tools from language ecosystems
+	if err := ValidateEcosystemTools(anvilConfig.EcosystemTools); err != nil {
+		return err
+	}
+
+	// Validate minimum app versions of pushed configs
+	if err := ValidateAppVersions(anvilConfig.AppVersions); err != nil {
+		return err
+	}
+
+	// Validate include and exclude patterns of configs entries
+	if err := ValidateConfigFilters(anvilConfig.ConfigFilters); err != nil {
+		return err
+	}
+
+	// Validate how tools added to installed groups are handled after a sync
+	if err := ValidateNewGroupTools(anvilConfig.Sync.NewGroupTools); err != nil {
+		return err
+	}
+
+	// Validate wanted service states
+	if err := ValidateServices(anvilConfig.Services); err != nil {
+		return err
+	}
+
 	// Validate git configuration
 	if err := cv.validateGitConfig(&anvilConfig.Git); err != nil {
 		return fmt.Errorf("git config validation failed: %w", err)
@@ -259,6 +309,43 @@ func (cv *ConfigValidator) validateGitConfig(git *GitConfig) error {
 		return fmt.Errorf("invalid git email format: %s", git.Email)
 	}
 
+	return ValidateGitExtraConfig(*git)
+}
+
+// gitKeyPattern matches git config keys: a section, an optional subsection and a name
+var gitKeyPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9-]*(\.[^\s=]+)?\.[A-Za-z][A-Za-z0-9-]*$`)
+
+// ValidateGitExtraConfig checks the keys of git.extra_config and that core.excludesfile
+// is not set there when git.global_ignore manages it
+func ValidateGitExtraConfig(git GitConfig) error {
+	var problems []string
+	for key := range git.ExtraConfig {
+		if !gitKeyPattern.MatchString(key) {
+			problems = append(problems, fmt.Sprintf("'%s' is not a git config key such as alias.co or core.editor", key))
+		}
+		if git.GlobalIgnore != "" && strings.EqualFold(key, constants.GitExcludes) {
+			problems = append(problems, fmt.Sprintf("'%s' is set by global_ignore, remove it from extra_config", key))
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("git.extra_config: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// ValidateServices checks that every service in the services section is started or stopped
+func ValidateServices(services map[string]string) error {
+	var problems []string
+	for name, state := range services {
+		if state != ServiceStarted && state != ServiceStopped {
+			problems = append(problems, fmt.Sprintf("'%s' wants '%s', use %s or %s", name, state, ServiceStarted, ServiceStopped))
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("services: %s", strings.Join(problems, "; "))
+	}
 	return nil
 }
 
@@ -18,34 +18,67 @@ package constants
 
 // Command operation constants
 const (
-	OpInit    = "init"
-	OpInstall = "install"
-	OpConfig  = "config"
-	OpImport  = "import"
-	OpPull    = "pull"
-	OpPush    = "push"
-	OpShow    = "show"
-	OpSync    = "sync"
-	OpDoctor  = "doctor"
-	OpClean   = "clean"
-	OpUpdate  = "update"
+	OpInit      = "init"
+	OpInstall   = "install"
+	OpConfig    = "config"
+	OpImport    = "import"
+	OpPull      = "pull"
+	OpPush      = "push"
+	OpShow      = "show"
+	OpSync      = "sync"
+	OpDoctor    = "doctor"
+	OpClean     = "clean"
+	OpUpdate    = "update"
+	OpSelf      = "self"
+	OpProvision = "provision"
+	OpBootstrap = "bootstrap"
+	OpInfo      = "info"
+	OpSessions  = "sessions"
+	OpHosts     = "hosts"
+	OpRelease   = "release"
+	OpSearch    = "search"
+	OpUndo      = "undo"
+	OpProject   = "project"
+	OpUninstall = "uninstall"
+	OpServices  = "services"
+	OpExport    = "export"
+	OpTour      = "tour"
 )
 
 // System command constants
 const (
-	BrewCommand = "brew"
-	GitCommand  = "git"
-	CurlCommand = "curl"
+	BrewCommand   = "brew"
+	GitCommand    = "git"
+	CurlCommand   = "curl"
+	AptGetCommand = "apt-get"
+	DpkgCommand   = "dpkg"
+	DnfCommand    = "dnf"
+	RpmCommand    = "rpm"
+	GoCommand     = "go"
+	CargoCommand  = "cargo"
+	NpmCommand    = "npm"
+	PipxCommand   = "pipx"
 )
 
 // Brew subcommand constants
 const (
-	BrewInstall = "install"
-	BrewList    = "list"
-	BrewInfo    = "info"
-	BrewUpdate  = "update"
-	BrewUpgrade = "upgrade"
-	BrewSearch  = "search"
+	BrewInstall   = "install"
+	BrewList      = "list"
+	BrewInfo      = "info"
+	BrewUpdate    = "update"
+	BrewUpgrade   = "upgrade"
+	BrewUninstall = "uninstall"
+	BrewSearch    = "search"
+	BrewCleanup   = "cleanup"
+	BrewServices  = "services"
+)
+
+// Homebrew environment variables set from the brew policy in settings.yaml
+const (
+	BrewNoAnalyticsEnvVar  = "HOMEBREW_NO_ANALYTICS"
+	BrewBottleDomainEnvVar = "HOMEBREW_BOTTLE_DOMAIN"
+	BrewAPIDomainEnvVar    = "HOMEBREW_API_DOMAIN"
+	BrewNoAutoUpdateEnvVar = "HOMEBREW_NO_AUTO_UPDATE"
 )
 
 // Git subcommand constants
@@ -54,6 +87,8 @@ const (
 	GitGlobal    = "--global"
 	GitUserName  = "user.name"
 	GitUserEmail = "user.email"
+	GitGet       = "--get"
+	GitExcludes  = "core.excludesfile"
 )
 
 // Directory constants
@@ -105,6 +140,55 @@ const (
 	ANVIL             = "anvil"
 	ANVIL_CONFIG_FILE = "settings.yaml"
 	ANVIL_CONFIG_DIR  = ".anvil"
+	LOCAL_CONFIG_FILE = "settings.local.yaml" // Machine-local overlay merged over settings.yaml, never pushed
+	TEAM_CONFIG_FILE  = "team.yaml"           // Team layer with organization-wide protected settings
+	TOOLS_LOCK_FILE   = "tools.lock"          // Versions of tools installed with go, cargo, npm or pipx
+	GROUPS_LOCK_FILE  = "groups.lock"         // Groups installed on this machine and the tools they listed then
+	PUSH_BASES_FILE   = "push-bases.yaml"     // Where app directories stood in the config repository when last pulled or pushed here
+
+	AssumeYesEnvVar = "ANVIL_ASSUME_YES" // Answer confirmation prompts with yes, same as --yes
+	StrictEnvVar    = "ANVIL_STRICT"     // Treat warnings as errors, same as --strict
+	PlainEnvVar     = "ANVIL_PLAIN"      // Print progress as timestamped lines, same as --plain
+	QuietEnvVar     = "ANVIL_QUIET"      // Leave out the end-of-command summary footer, same as --quiet
+
+	SafeModeAnnotation = "anvil_safe_mode" // Command annotation for read-only commands that run with corrupt settings
+
+	DefaultSettingsBackups = 10 // Versions of settings.yaml kept under ~/.anvil/backups/settings
+	SettingsTransactions   = 50 // Changes to settings.yaml kept under ~/.anvil/transactions for 'anvil undo'
+)
+
+// Git clone constants
+const (
+	DefaultCloneDepth = 1 // Shallow clone by default, pull operations don't need history
+)
+
+// Markers and names for files anvil writes outside ~/.anvil
+const (
+	LaunchAgentPrefix  = "com.0xjuanma.anvil"
+	CompletionFileName = "anvil"
+)
+
+// Push size preflight limits
+const (
+	DefaultMaxPushSizeMB = 100  // Abort pushes larger than this many megabytes
+	DefaultMaxPushFiles  = 5000 // Abort pushes with more files than this
+)
+
+// Chunked and resumable push settings
+const (
+	PushBranchPrefix      = "config-push"
+	AppBranchPrefix       = "configs"
+	PushChunkMaxFiles     = 200 // Split commits with more staged files than this
+	PushChunkMaxSizeMB    = 25  // Split commits larger than this many megabytes
+	PushRetryAttempts     = 4   // Attempts per branch push before giving up
+	PushRetryInitialDelay = 2   // Seconds before the first retry, doubled after each attempt
+)
+
+// Push branch timestamp layouts
+const (
+	BranchTimestampISO    = "20060102-150405" // YYYYMMDD-HHMMSS, sorts chronologically
+	BranchTimestampLegacy = "02012006-1504"   // DDMMYYYY-HHMM, used by earlier releases
+	BranchTimestampUS     = "01022006-1504"   // MMDDYYYY-HHMM
 )
 
 // Common directory permissions
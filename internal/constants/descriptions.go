@@ -33,7 +33,21 @@ What it does:
 
 const INSTALL_COMMAND_LONG_DESCRIPTION = `Install development tools individually or in groups using Homebrew.
 
-Define custom groups in settings.yaml`
+Define custom groups in settings.yaml
+
+Use --from-url <url> to install an app that isn't in Homebrew from a DMG, PKG, ZIP, DEB,
+RPM, AppImage or tarball, e.g. 'anvil install moom --from-url https://example.com/Moom.dmg'.
+The URL is saved under sources and the app is tracked like any other install.`
+
+const UNINSTALL_COMMAND_LONG_DESCRIPTION = `Uninstall an app or every app of a group with 'brew uninstall', then stop tracking it.
+
+Casks and formulas are told apart from annotations or from what Homebrew has installed.
+An uninstalled app is removed from installed_apps and from every group listing it. A group
+keeps its definition so 'anvil install <group>' can reinstall it; its apps leave installed_apps.
+
+Apps under managed_externally are never removed. Required tools, and apps of a group that
+another group still lists, are kept unless --force is given. --force also skips the prompt.
+Use --dry-run to preview what would be removed.`
 
 const CONFIG_COMMAND_LONG_DESCRIPTION = `Manage configuration files and dotfiles for your anvil environment.
 
@@ -41,50 +55,355 @@ Configure 'github.config_repo' in settings.yaml to use this command.`
 
 const PUSH_COMMAND_LONG_DESCRIPTION = `Upload local configuration files to GitHub with automated branch creation.
 
-Configure 'github.config_repo' in settings.yaml to use this command.`
+Configure 'github.config_repo' in settings.yaml to use this command.
+
+Use --create-pr to open the pull request for the pushed branch, or --auto-merge to also
+merge it once required checks pass. Both need the token named by 'github.token_env_var'.
+
+When another machine changed the app since it was last pulled here, push lists the changes
+and asks whether to merge them in. Use --force to replace them instead.
+
+Use --remote <name> to push to a repository listed under 'github.remotes' instead.`
+
+const INFO_COMMAND_LONG_DESCRIPTION = `Show the status of one or more apps in a single invocation.
+
+For each app anvil reports whether it is available on this machine, its installed Homebrew
+version, whether it is a cask, formula or App Store app, which groups and settings sections
+list it, and its configs mapping. Lookups run concurrently and share cached brew results.
+
+Use --json for structured output, e.g. 'anvil info --json git slack obsidian'.
+Add --metadata for descriptions, licenses and homepages from one batched 'brew info' call,
+and --sizes for download sizes. Metadata is cached by package revision.`
+
+const COMPONENTS_COMMAND_LONG_DESCRIPTION = `Verify the plugins and extensions that app configs list are installed on this machine.
+
+Some apps keep part of their setup as installable components rather than files:
+  • Obsidian: community plugins enabled in .obsidian/community-plugins.json
+  • VS Code and Cursor: extensions listed in extensions.txt, written on 'config push'
+
+'config sync' installs missing components after copying the config. Use --install
+to install them here, with --dry-run to only list them, or 'config sync --skip-components'
+to only copy files.`
+
+const UNDO_COMMAND_LONG_DESCRIPTION = `Revert the most recent changes anvil made to settings.yaml.
+
+Every command that rewrites settings.yaml, such as tracking installed apps, updating
+groups or removing duplicates, records the change with the file before and after it
+under ~/.anvil/transactions. The last 50 changes are kept.
+
+Examples:
+  anvil undo --list      # List changes with their time and originating command
+  anvil undo             # Revert the most recent change
+  anvil undo --steps 3   # Revert the three most recent changes
+
+Undos are recorded too but never undone themselves, so repeating 'anvil undo' keeps
+walking back. anvil refuses to revert when settings.yaml was edited since the change,
+pass --force to revert anyway.`
+
+const RESTORE_SETTINGS_COMMAND_LONG_DESCRIPTION = `Restore settings.yaml from the automatic backup ring.
+
+Each time anvil rewrites settings.yaml, the previous version is kept under
+~/.anvil/backups/settings. The last 10 versions are kept, change this with
+'settings_backups' in settings.yaml (-1 disables backups).
+
+Examples:
+  anvil config restore-settings --list        # List backups, 1 is the most recent
+  anvil config restore-settings               # Restore the most recent backup
+  anvil config restore-settings --version 3
+
+The replaced settings.yaml is backed up first, so a restore can be undone. When
+settings.yaml is corrupted, any command offers to restore the latest valid backup.`
+
+const ROLLBACK_COMMAND_LONG_DESCRIPTION = `Restore an app's configs from an archive made by 'config sync'.
+
+Before overwriting configs, sync copies the old ones to ~/.anvil/archive. Rollback
+lists the app's archives with their date and how many files differ from the current
+ones, shows the diff of the archive you pick, and restores it after confirmation.
+
+Examples:
+  anvil config rollback zsh                # Pick an archive, preview and restore it
+  anvil config rollback zsh --list         # List archives, 1 is the most recent
+  anvil config rollback zsh --latest       # Restore the most recent archive
+  anvil config rollback anvil --archive 2 --dry-run
+  anvil config rollback                    # Pick from the apps that have archives
+
+Files the archive holds are restored, files it doesn't hold are left in place. The
+files being replaced are archived first, so a rollback can itself be rolled back.`
+
+const SNAPSHOT_DIFF_COMMAND_LONG_DESCRIPTION = `Show what changed in your configuration, settings.yaml and app configs, between two snapshots.
+
+A snapshot is one of:
+  • local                   - the configs currently on this machine
+  • a commit, branch or tag - of the config repository's local clone
+  • a date (YYYY-MM-DD)     - the last commit on the configured branch before that day ended
+  • an archive name         - a directory under ~/.anvil/archive created by 'config sync'
+
+Examples:
+  anvil config snapshot-diff 2025-06-01 local
+  anvil config snapshot-diff HEAD~5 HEAD --app zsh --pattern '*.zsh'
+  anvil config snapshot-diff cursor-configs-2025-06-01-10-00-00 local --stat`
+
+const CONFIG_DIFF_COMMAND_LONG_DESCRIPTION = `Preview what a push or sync would change by diffing local configs against the config repository.
+
+The local clone is updated from the configured branch first. Without an app name the
+anvil settings.yaml is compared, otherwise the path under 'configs' for that app.
+Lines starting with + exist only locally and would be pushed, lines starting with -
+exist only in the repository and would be restored by 'config sync'.
+
+Examples:
+  anvil config diff               # settings.yaml
+  anvil config diff zsh           # the zsh configs
+  anvil config diff nvim --name-only`
+
+const PATHS_COMMAND_LONG_DESCRIPTION = `Manage the 'configs' section of settings.yaml, which maps each app to its local config path.
+
+'anvil config paths edit' lists every entry in a table and lets you add, edit and remove
+them. Paths are checked as you type them and "~" is expanded. Saving rewrites only the
+configs section, so comments in the rest of settings.yaml are kept.`
+
+const DEFAULTS_COMMAND_LONG_DESCRIPTION = `Default flags per command, read from the 'defaults' section of settings.yaml.
+
+defaults:
+  install:
+    concurrent: true
+  doctor:
+    verbose: true
+
+Defaults are applied at startup to flags not given on the command line, so
+'anvil install dev --concurrent=false' still overrides them.`
+
+const SESSIONS_COMMAND_LONG_DESCRIPTION = `Review install and provision runs recorded with --record.
+
+Each session keeps the order tools were installed in, how long every step took, the output
+of each brew command and any errors, stored as JSON under ~/.anvil/sessions.
+
+Use 'anvil sessions show' for the latest run or 'anvil sessions show <id>' for an older one.`
+
+const HOSTS_COMMAND_LONG_DESCRIPTION = `Manage custom /etc/hosts entries defined in settings.yaml.
+
+hosts:
+  - ip: 127.0.0.1
+    names: [api.local, web.local]
+
+Entries are written between '# BEGIN anvil managed hosts' and '# END anvil managed hosts'
+markers, the rest of /etc/hosts is never touched. Writing the file uses sudo when needed.
+
+'anvil hosts apply' writes the block, 'anvil hosts remove' takes it out again and
+'anvil hosts status' shows it. Provision profiles with 'hosts: true' and syncing settings.yaml
+apply the block too, and 'anvil doctor hosts-block' reports when it is out of date.`
+
+const SERVICES_COMMAND_LONG_DESCRIPTION = `List, start and stop Homebrew services, such as postgresql or redis, with 'brew services'.
+
+The services section of settings.yaml names the services that should be running:
+
+services:
+  postgresql@16: started
+  redis: stopped
+
+'anvil services list' shows every service with the state settings.yaml wants, 'anvil services
+start' and 'stop' change one service, and 'anvil services apply' brings all listed services in
+line. Provision profiles with 'services: true' apply them too, and 'anvil doctor services'
+reports services in the wrong state.`
+
+const RELEASE_COMMAND_LONG_DESCRIPTION = `Build, sign and package anvil release binaries. For maintainers, run from the root
+of the anvil source tree.
+
+For each target (darwin/arm64 and darwin/amd64 by default) the binary is built with the
+version injected through ldflags, then written to the output directory as:
+
+  anvil-darwin-arm64                  Raw binary, downloaded by install.sh
+  anvil-v1.2.0-darwin-arm64.tar.gz    Tarball with 'anvil' at its root, used by the Homebrew formula
+
+checksums.txt lists the SHA-256 of every artifact. With --sign-identity macOS binaries are
+signed with codesign and the hardened runtime, and --notary-profile also notarizes them
+with notarytool before they are archived.`
+
+const PROVISION_COMMAND_LONG_DESCRIPTION = `Set up this machine from a profile defined in settings.yaml.
+
+A profile installs groups and apps, then pulls and syncs configs from your config repository.
+Listing "anvil" under configs syncs settings.yaml first so the team's groups are used.
+
+provision:
+  profiles:
+    work:
+      description: Work laptop
+      groups: [essentials, dev]
+      apps: [slack]
+      configs: [anvil, cursor]
+
+Steps the machine already satisfies are skipped, installed tools and configs whose files
+match the pulled copy, so re-running a profile only changes what drifted. The summary
+reports each step as satisfied, changed or failed.
+
+Use --plan to preview the taps, packages, configs and hosts entries a profile would apply,
+as a tree or, with --json, as a JSON document. Nothing is installed or written.
+
+Each applied run is recorded under ~/.anvil/provision. Use --diff to list only the steps whose
+inputs changed since then, such as tools added to a group, changed config files or command
+defaults, along with steps that failed last time.
+
+Run without a profile to list the available profiles. Combine with --yes for unattended runs.`
+
+const BOOTSTRAP_COMMAND_LONG_DESCRIPTION = `Generate a script that sets up a brand-new Mac with a single curl | bash.
+
+The script installs anvil, runs 'anvil init' with your config repository, pulls and syncs
+your settings, and provisions the given profile, all without prompts.
+
+Values not baked in with flags are read from ANVIL_CONFIG_REPO, ANVIL_CONFIG_BRANCH and
+ANVIL_PROFILE when the script runs.`
+
+const CONFLICTS_COMMAND_LONG_DESCRIPTION = `Find apps listed inconsistently across settings.yaml.
+
+Reports apps that are:
+  • Spelled with different casing, e.g. "Slack" in a group and "slack" in installed_apps
+  • Listed more than once in the same section
+  • Tracked in tools.installed_apps while already covered by required_tools or a group
+
+Use --fix to lowercase app names, remove duplicates and drop redundant installed_apps entries.
+Listing the same app in several groups is allowed and left untouched.`
+
+const IMPORT_COMMAND_LONG_DESCRIPTION = `Import tool groups from a local YAML file or remote URL into your anvil configuration.
+
+Use --suggest, without a file, to sort a long tools.installed_apps list into groups. Apps are
+matched against built-in categories (developer tools, browsers, communication, media and
+productivity), and each suggested group can be accepted, renamed, trimmed or skipped.
+Accepted apps move from installed_apps into their group. Unknown apps are left as they are.
+
+Use --brewfile to import a Brewfile's brew, cask and mas lines as one group, named with
+--group (default "brewfile"). Entries keep a cask:, formula: or mas: annotation unless
+the known packages table already implies their type. Taps, other Brewfile lines and names
+settings.yaml can't hold, such as python@3.12, are reported and skipped.`
+
+const HISTORY_COMMAND_LONG_DESCRIPTION = `List the config-push branches in your GitHub repository, newest first.
+
+Use --prune to delete branches older than --older-than days (default 30) and
+unfinished pushes abandoned for more than a day.
+
+Use --cleanup to delete branches already merged into the configured branch, found in the
+local clone or, for squash and rebase merges, through GitHub pull requests. With
+--older-than, only merged branches at least that many days old are deleted.`
+
+const TIMELINE_COMMAND_LONG_DESCRIPTION = `Show when an app's configs changed, newest first, or those of every app.
+
+Pushes, pulls, syncs and rollbacks made on this machine are recorded in ~/.anvil/timeline.jsonl
+and listed with the machine and commit they involved. Commits of the config repository that
+changed the app are added from the local clone, so run 'anvil config pull' first to see changes
+pushed from other machines. Use --local to leave them out and --json for the raw entries.`
 
 const PULL_COMMAND_LONG_DESCRIPTION = `Download configuration files from your GitHub repository.
 
-Configure 'github.config_repo' in settings.yaml to use this command.`
+Pass several directories, or --all for every directory in the repository, to fetch
+the repository once and copy the directories concurrently (--workers, default 4).
+
+Pulled copies record when and from which commit they were pulled. Sync warns about
+copies older than temp.max_age_days (default 30), and --prune-temp removes them.
+
+Configure 'github.config_repo' in settings.yaml to use this command, or pull from a
+repository listed under 'github.remotes' with --remote <name>.`
+
+const SHOW_COMMAND_LONG_DESCRIPTION = `Display configuration files and settings with intelligent formatting.
+
+Pulled directories are shown as a tree with file sizes, directory totals, file counts and
+modification dates. Use --sort size or --sort mtime to find the largest or newest files.`
+
+const PROJECT_COMMAND_LONG_DESCRIPTION = `Check a machine against the requirements a repository declares in .anvil.yaml.
+
+name: payments-api
+tools: [go, golangci-lint, cask:docker]
+groups: [dev]
+env: [AWS_PROFILE, GITHUB_TOKEN]
+
+The file is found in the working directory or its closest parent, or given with --file.
+Tools install the same way as 'anvil install <app>', groups come from settings.yaml.
 
-const SHOW_COMMAND_LONG_DESCRIPTION = `Display configuration files and settings with intelligent formatting.`
+'anvil project check' lists each requirement and exits non-zero when any is not met,
+'anvil project install' installs the missing tools and reports what is still left.`
+
+const INSTALL_HOOKS_COMMAND_LONG_DESCRIPTION = `Install pre-commit and pre-push hooks into the local clone of your config repository.
+
+The hooks apply the protections of 'anvil config push' to plain git usage of the repository:
+staged and pushed files are scanned for secrets, app directories are checked against the
+push size limits, and when github.generate_readme is set commits refresh the README index.
+
+Hooks not written by anvil are left alone unless --force is given, in which case they are
+kept as <name>.anvil-backup. Use --dry-run to see which hooks would be written. Re-run the
+command after moving the anvil binary.`
+
+const VALIDATE_COMMAND_LONG_DESCRIPTION = `Check settings.yaml and report what needs fixing.
+
+Each top-level section is parsed on its own, so a syntax error or a wrong value type is
+reported for the section it is in rather than for the whole file. Sections that parse are
+then checked for valid values, such as group and app names and failure policies.
+
+When the file is corrupt, read-only commands ('config show', 'install --list', 'doctor',
+'info') still run in safe mode with defaults for the broken sections, and nothing is saved
+until the file is fixed or restored with 'anvil config restore-settings'.`
 
 const SYNC_COMMAND_LONG_DESCRIPTION = `Apply pulled configuration files to their local destinations with automatic archiving.
 
-Safely applies configs with automatic backup of existing files.`
+Safely applies configs with automatic backup of existing files.
+
+With --remote <name>, sync refuses copies that were pulled from another repository.`
 
 const DOCTOR_COMMAND_LONG_DESCRIPTION = `Run health checks to validate your anvil environment.
 
 Health Check Categories:
 
-ENVIRONMENT (3 checks)
+ENVIRONMENT (4 checks)
   • anvil-init       - Verify anvil initialization is complete
   • settings-valid   - Validate settings.yaml structure and content
+  • anvil-version    - Check anvil meets the min_anvil_version of team and synced settings
   • directory-structure - Check ~/.anvil directory structure
 
-DEPENDENCIES (2 checks)
+DEPENDENCIES (6 checks)
   • homebrew         - Verify Homebrew installation and updates (auto-fixable)
+  • brew-architecture - Check Homebrew's prefix matches this Mac's architecture (auto-fixable)
   • required-tools   - Check git and curl are installed
+  • brew-policy      - Verify brew.analytics and mirror settings apply (auto-fixable)
+  • brew-renames     - Find renamed and deprecated packages in settings (auto-fixable)
+  • services         - Check Homebrew services are started or stopped as settings ask (auto-fixable)
 
-CONFIGURATION (3 checks)
+CONFIGURATION (7 checks)
   • git-config       - Validate git user.name and user.email (auto-fixable)
   • github-config    - Verify GitHub repository configuration
-  • sync-config      - Check config sync settings (not yet implemented)
+  • sync-config      - Validate machine-scoped sync rules
+  • protected-settings - Detect local overrides of protected team settings (auto-fixable)
+  • hosts-block      - Check the anvil block in /etc/hosts matches settings (auto-fixable)
+  • git-settings     - Check git extra_config and the global ignore file are applied (auto-fixable)
+  • clone-health     - Detect a broken local clone (auto-fixable)
 
 CONNECTIVITY (3 checks)
   • github-auth      - Test GitHub authentication and access
   • github-repo      - Verify repository accessibility
   • git-operations   - Test git clone and pull operations
 
+APPS (4 checks)
+  • config-paths     - Verify every configs entry path exists
+  • remote-apps      - Verify apps are in the config repository or local-only
+  • app-state        - Check pulled configs and tracked apps match settings (auto-fixable)
+  • config-overlap   - Verify no two apps map to overlapping paths
+
 Each check can be run independently by name or grouped by category.
 Add --fix flag to auto-fix issues where supported.
 
 Examples:
-  anvil doctor                    # Run all 11 checks
+  anvil doctor                    # Run all 17 checks
   anvil doctor environment        # Run category (3 checks)
   anvil doctor git-config         # Run specific check
   anvil doctor git-config --fix   # Run check and auto-fix
-  anvil doctor --fix              # Run all checks and auto-fix issues`
+  anvil doctor --fix              # Run all checks and auto-fix issues
+  anvil doctor --fix-all          # Pick the issues to fix from a checklist
+  anvil doctor --output json      # Print one JSON report for CI (exit 0 healthy, 2 warnings, 3 failures)
+  anvil doctor summary            # One cached status line for shell prompts, e.g. "anvil ✓"`
+
+const DOCTOR_SUMMARY_LONG_DESCRIPTION = `Print a one-line health status for shell prompts and status lines.
+
+The line comes from the results of the last doctor run, e.g. "anvil ✓" or "anvil ✗ 2 issues".
+Warnings count as issues, ✗ means at least one check failed and ⚠ only warnings.
+
+When the cached results are older than --max-age, or there are none yet, the checks that
+don't need the network run again. Connectivity checks never run here: their results carry
+over from the last full 'anvil doctor' run.`
 
 // Clean command descriptions
 const CLEAN_COMMAND_LONG_DESCRIPTION = `Remove all content inside .anvil directories while preserving settings.yaml.
@@ -95,8 +414,29 @@ What it does:
 • Removes dotfiles/ directory for clean git state
 • Preserves settings.yaml file
 
+Targets:
+• anvil clean dotfiles            - Remove only the local dotfiles clone
+• anvil clean dotfiles --compact  - Run git gc/prune on the local clone instead of removing it
+• anvil clean --stale             - Remove only staging directories left by interrupted pulls and syncs
+• anvil clean remnants [cask...]  - Move preferences, caches and launch agents of removed casks to the Trash
+
 Safe operation that never deletes your main configuration file.`
 
+const CLEAN_REMNANTS_COMMAND_LONG_DESCRIPTION = `Find and remove what uninstalled casks left behind.
+
+When anvil installs a cask it records what the cask put on the system in ~/.anvil/casks:
+its apps, launch agents, installer package receipts and the preference, cache and support
+files from its zap stanza. Once the cask is removed, with brew or any other way, this
+command lists whatever of that is still on disk and moves it to the Trash.
+
+Casks that are still installed are skipped. Installer package receipts and files outside
+your home directory need administrator rights, the command prints how to remove them.
+
+Examples:
+  anvil clean remnants              # Check every recorded cask
+  anvil clean remnants slack zoom   # Check specific casks
+  anvil clean remnants --dry-run    # List leftovers without removing them`
+
 // Update command descriptions
 const UPDATE_COMMAND_LONG_DESCRIPTION = `Update Anvil to the latest version from GitHub releases.
 
@@ -104,3 +444,68 @@ What it does:
 • Downloads latest release information
 • Runs official installation script
 • Replaces current installation`
+
+// Self command descriptions
+const SELF_COMMAND_LONG_DESCRIPTION = `Manage the anvil installation itself.
+
+Subcommands:
+• anvil self destruct  - Remove everything anvil has created on this machine`
+
+const SELF_DESTRUCT_COMMAND_LONG_DESCRIPTION = `Remove everything anvil has created on this machine so trying it out is fully reversible.
+
+What it removes:
+• The ~/.anvil directory (settings, pulled configs, archives and the dotfiles clone)
+• Generated shell completion scripts under your home directory
+• The anvil block of /etc/hosts, after confirming the sudo write
+
+A clone at github.local_path outside ~/.anvil is listed with the command to remove it,
+since it may be a repository you work in. Completions installed by Homebrew go with
+'brew uninstall anvil'.
+
+Use --backup to export ~/.anvil to a tar.gz archive in your home directory first.
+The anvil binary itself is not removed; the command prints how to remove it.
+
+This cannot be undone. You will be asked to type 'destruct' to confirm.`
+
+const SEARCH_COMMAND_LONG_DESCRIPTION = `Search Homebrew formulae and casks, then pick results to install or add to a group.
+
+Results are ranked with exact and prefix matches first and show each package's description,
+whether it is a formula or cask, and a ✓ when it is already installed. Pick results by number
+(e.g. 1,3-5), then install them now or add them to a group in settings.yaml for later.
+Names that exist as both a formula and a cask are shown with a 'formula:' or 'cask:' prefix,
+which is kept when they are installed or added to a group.
+
+Results are cached for 6 hours under ~/.anvil/cache/search, use --refresh to search again.
+Use --list, or pipe the output, to print results without picking.
+
+Examples:
+  anvil search postgres             Search and pick interactively
+  anvil search font --limit 50      Show more results
+  anvil search k9s --group devops   Add picked results straight to the devops group`
+
+const EXPORT_COMMAND_LONG_DESCRIPTION = `Export the apps anvil tracks in a format other tools understand.
+
+--format brewfile writes required_tools, every group and installed_apps as a Brewfile for
+'brew bundle'. Entries become brew, cask or mas lines using their cask:, formula: or mas:
+annotation or the known packages table, and apps listed in several places are written once.
+
+The Brewfile is printed to stdout unless --output is given. Existing files are only
+replaced with --force. Use 'anvil config import --brewfile' to read a Brewfile back into groups.
+
+Examples:
+  anvil export --format brewfile                  Print a Brewfile
+  anvil export --format brewfile -o Brewfile      Write ./Brewfile`
+
+const TOUR_COMMAND_LONG_DESCRIPTION = `A guided, hands-on tour for new users of groups, installs and the config lifecycle.
+
+The tour walks through four steps, asking before each one:
+  1. Create a group in settings.yaml
+  2. Dry-run install the group
+  3. Register an app config in the configs section
+  4. Push the config to a config repository
+
+Everything runs in a sandbox under ~/.anvil/tour with its own settings.yaml and a local
+git repository standing in for GitHub, so your own settings and configs are never touched.
+
+Completed steps are recorded, and running 'anvil tour' again continues where you left off.
+Use --reset to remove the sandbox and start over.`
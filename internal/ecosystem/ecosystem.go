@@ -0,0 +1,301 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ecosystem installs command line tools with the installers of language ecosystems,
+// go install, cargo install, npm install -g and pipx install, for tools listed under
+// ecosystem_tools in settings.yaml. Installed versions are recorded in ~/.anvil/tools.lock.
+package ecosystem
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/system"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+)
+
+// installTimeout bounds one install, go and cargo build the tool from source
+const installTimeout = 15 * time.Minute
+
+// Overridden in tests
+var (
+	runCommand = system.RunCommandWithEnv
+	lookPath   = exec.LookPath
+	fileExists = func(path string) bool {
+		info, err := os.Stat(path)
+		return err == nil && !info.IsDir()
+	}
+)
+
+// installLocks serializes installs per installer, npm and pipx share global state
+var installLocks sync.Map
+
+// Lookup returns how a tool is installed when settings list it under ecosystem_tools
+func Lookup(toolName string) (config.EcosystemTool, bool) {
+	return config.GetEcosystemTool(toolName)
+}
+
+// IsInstalled reports whether a tool's binary is on this machine and, when its version is
+// pinned, whether the installed version is the pinned one
+func IsInstalled(toolName string, tool config.EcosystemTool) bool {
+	if binaryPath(toolName, tool) == "" {
+		return false
+	}
+	if tool.Version == "" {
+		return true
+	}
+	return SameVersion(InstalledVersion(toolName, tool), tool.Version)
+}
+
+// Install installs a tool with its ecosystem's installer and records the installed version
+func Install(toolName string, tool config.EcosystemTool) error {
+	if _, err := lookPath(tool.Installer); err != nil {
+		return fmt.Errorf("%s is needed to install %s, install it first (e.g. anvil install %s)", tool.Installer, toolName, installerPackage(tool.Installer))
+	}
+
+	lock, _ := installLocks.LoadOrStore(tool.Installer, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	args := installArgs(toolName, tool)
+	spinner := charm.NewDotsSpinner(fmt.Sprintf("Installing %s with %s", toolName, tool.Installer))
+	spinner.Start()
+
+	ctx, cancel := context.WithTimeout(context.Background(), installTimeout)
+	defer cancel()
+	result, err := runCommand(ctx, "", nil, tool.Installer, args...)
+	if err != nil || !result.Success {
+		spinner.Error(fmt.Sprintf("Failed to install %s", toolName))
+		output := ""
+		if result != nil {
+			output = lastLines(result.Output, 3)
+		}
+		return fmt.Errorf("%s %s failed: %s", tool.Installer, strings.Join(args, " "), output)
+	}
+	spinner.Success(fmt.Sprintf("%s installed successfully", toolName))
+
+	version := InstalledVersion(toolName, tool)
+	if version == "" {
+		version = tool.Version
+	}
+	if err := RecordLock(toolName, LockEntry{
+		Installer:   tool.Installer,
+		Package:     tool.PackageName(toolName),
+		Version:     version,
+		Pinned:      tool.Version != "",
+		InstalledAt: time.Now(),
+	}); err != nil {
+		return fmt.Errorf("%s installed but %s could not be updated: %w", toolName, constants.TOOLS_LOCK_FILE, err)
+	}
+	return nil
+}
+
+// installArgs returns the installer arguments for a tool, reinstalling when a version is pinned
+// so a different installed version is replaced
+func installArgs(toolName string, tool config.EcosystemTool) []string {
+	pkg := tool.PackageName(toolName)
+	switch tool.Installer {
+	case config.EcosystemGo:
+		version := tool.Version
+		if version == "" {
+			version = "latest"
+		}
+		return []string{"install", pkg + "@" + version}
+	case config.EcosystemCargo:
+		if tool.Version == "" {
+			return []string{"install", "--locked", pkg}
+		}
+		return []string{"install", "--locked", "--force", pkg, "--version", strings.TrimPrefix(tool.Version, "v")}
+	case config.EcosystemNpm:
+		if tool.Version == "" {
+			return []string{"install", "-g", pkg}
+		}
+		return []string{"install", "-g", pkg + "@" + strings.TrimPrefix(tool.Version, "v")}
+	case config.EcosystemPipx:
+		if tool.Version == "" {
+			return []string{"install", pkg}
+		}
+		return []string{"install", "--force", pkg + "==" + strings.TrimPrefix(tool.Version, "v")}
+	}
+	return nil
+}
+
+// InstalledVersion asks the ecosystem which version of a tool is installed, "" when unknown
+func InstalledVersion(toolName string, tool config.EcosystemTool) string {
+	pkg := tool.PackageName(toolName)
+	switch tool.Installer {
+	case config.EcosystemGo:
+		path := binaryPath(toolName, tool)
+		if path == "" {
+			return ""
+		}
+		return goModuleVersion(query(constants.GoCommand, "version", "-m", path))
+	case config.EcosystemCargo:
+		return cargoVersion(query(constants.CargoCommand, "install", "--list"), pkg)
+	case config.EcosystemNpm:
+		return npmVersion(query(constants.NpmCommand, "ls", "-g", "--depth=0", "--json", pkg), pkg)
+	case config.EcosystemPipx:
+		return pipxVersion(query(constants.PipxCommand, "list", "--json"), pkg)
+	}
+	return ""
+}
+
+// SameVersion compares versions ignoring a leading v, v1.2.0 matches 1.2.0
+func SameVersion(a, b string) bool {
+	return a != "" && strings.TrimPrefix(a, "v") == strings.TrimPrefix(b, "v")
+}
+
+// binaryPath finds a tool's command on PATH or in its installer's bin directory, "" when missing
+func binaryPath(toolName string, tool config.EcosystemTool) string {
+	binary := tool.BinaryName(toolName)
+	if path, err := lookPath(binary); err == nil {
+		return path
+	}
+	if dir := binDir(tool.Installer); dir != "" && fileExists(filepath.Join(dir, binary)) {
+		return filepath.Join(dir, binary)
+	}
+	return ""
+}
+
+// binDir returns where an installer puts commands, which may not be on PATH yet
+func binDir(installer string) string {
+	homeDir, _ := system.GetHomeDir()
+	switch installer {
+	case config.EcosystemGo:
+		if dir := query(constants.GoCommand, "env", "GOBIN"); dir != "" {
+			return dir
+		}
+		if dir := query(constants.GoCommand, "env", "GOPATH"); dir != "" {
+			return filepath.Join(strings.Split(dir, string(os.PathListSeparator))[0], "bin")
+		}
+		return filepath.Join(homeDir, "go", "bin")
+	case config.EcosystemCargo:
+		if dir := os.Getenv("CARGO_HOME"); dir != "" {
+			return filepath.Join(dir, "bin")
+		}
+		return filepath.Join(homeDir, ".cargo", "bin")
+	case config.EcosystemNpm:
+		if prefix := query(constants.NpmCommand, "prefix", "-g"); prefix != "" {
+			return filepath.Join(prefix, "bin")
+		}
+	case config.EcosystemPipx:
+		if dir := os.Getenv("PIPX_BIN_DIR"); dir != "" {
+			return dir
+		}
+		return filepath.Join(homeDir, ".local", "bin")
+	}
+	return ""
+}
+
+// installerPackage returns the package that provides an installer command
+func installerPackage(installer string) string {
+	switch installer {
+	case config.EcosystemCargo:
+		return "rust"
+	case config.EcosystemNpm:
+		return "node"
+	}
+	return installer
+}
+
+// query runs a read-only installer command and returns its trimmed output, "" when it fails
+func query(command string, args ...string) string {
+	if _, err := lookPath(command); err != nil {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	result, err := runCommand(ctx, "", nil, command, args...)
+	if err != nil || !result.Success {
+		return ""
+	}
+	return strings.TrimSpace(result.Output)
+}
+
+// goModuleVersion reads the main module version from 'go version -m' output
+func goModuleVersion(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && fields[0] == "mod" {
+			return fields[2]
+		}
+	}
+	return ""
+}
+
+// cargoVersion reads a crate's version from 'cargo install --list' output, e.g. "ripgrep v14.1.0:"
+func cargoVersion(output, crate string) string {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == crate && !strings.HasPrefix(line, " ") {
+			return strings.TrimPrefix(strings.TrimSuffix(fields[1], ":"), "v")
+		}
+	}
+	return ""
+}
+
+// npmVersion reads a package's version from 'npm ls -g --json' output
+func npmVersion(output, pkg string) string {
+	var listing struct {
+		Dependencies map[string]struct {
+			Version string `json:"version"`
+		} `json:"dependencies"`
+	}
+	if json.Unmarshal([]byte(output), &listing) != nil {
+		return ""
+	}
+	return listing.Dependencies[pkg].Version
+}
+
+// pipxVersion reads a package's version from 'pipx list --json' output
+func pipxVersion(output, pkg string) string {
+	var listing struct {
+		Venvs map[string]struct {
+			Metadata struct {
+				MainPackage struct {
+					PackageVersion string `json:"package_version"`
+				} `json:"main_package"`
+			} `json:"metadata"`
+		} `json:"venvs"`
+	}
+	if json.Unmarshal([]byte(output), &listing) != nil {
+		return ""
+	}
+	return listing.Venvs[pkg].Metadata.MainPackage.PackageVersion
+}
+
+// lastLines returns the last n non-empty lines of output, where installers print the error
+func lastLines(output string, n int) string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "; ")
+}
@@ -0,0 +1,130 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ecosystem
+
+import (
+	"context"
+	"os/exec"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/system"
+)
+
+func TestInstallArgs(t *testing.T) {
+	tests := []struct {
+		tool config.EcosystemTool
+		want []string
+	}{
+		{config.EcosystemTool{Installer: "go", Package: "github.com/golangci/golangci-lint/cmd/golangci-lint"},
+			[]string{"install", "github.com/golangci/golangci-lint/cmd/golangci-lint@latest"}},
+		{config.EcosystemTool{Installer: "go", Package: "golang.org/x/tools/gopls", Version: "v0.16.1"},
+			[]string{"install", "golang.org/x/tools/gopls@v0.16.1"}},
+		{config.EcosystemTool{Installer: "cargo", Package: "ripgrep", Version: "v14.1.0"},
+			[]string{"install", "--locked", "--force", "ripgrep", "--version", "14.1.0"}},
+		{config.EcosystemTool{Installer: "npm"}, []string{"install", "-g", "tldr"}},
+		{config.EcosystemTool{Installer: "npm", Version: "3.4.0"}, []string{"install", "-g", "tldr@3.4.0"}},
+		{config.EcosystemTool{Installer: "pipx", Version: "1.2.0"}, []string{"install", "--force", "tldr==1.2.0"}},
+	}
+
+	for _, tt := range tests {
+		if got := installArgs("tldr", tt.tool); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("installArgs(%+v) = %v, want %v", tt.tool, got, tt.want)
+		}
+	}
+}
+
+func TestVersionParsers(t *testing.T) {
+	goOutput := "/home/me/go/bin/gopls: go1.23.6\n\tpath\tgolang.org/x/tools/gopls\n\tmod\tgolang.org/x/tools/gopls\tv0.16.1\th1:abc=\n\tdep\tgolang.org/x/mod\tv0.20.0\n"
+	if got := goModuleVersion(goOutput); got != "v0.16.1" {
+		t.Errorf("goModuleVersion = %q", got)
+	}
+
+	cargoOutput := "bat v0.24.0:\n    bat\nripgrep v14.1.0:\n    rg\n"
+	if got := cargoVersion(cargoOutput, "ripgrep"); got != "14.1.0" {
+		t.Errorf("cargoVersion = %q", got)
+	}
+	if got := cargoVersion(cargoOutput, "rg"); got != "" {
+		t.Errorf("cargoVersion matched a binary line: %q", got)
+	}
+
+	npmOutput := `{"dependencies": {"tldr": {"version": "3.4.0", "overridden": false}}}`
+	if got := npmVersion(npmOutput, "tldr"); got != "3.4.0" {
+		t.Errorf("npmVersion = %q", got)
+	}
+
+	pipxOutput := `{"venvs": {"httpie": {"metadata": {"main_package": {"package_version": "3.2.2"}}}}}`
+	if got := pipxVersion(pipxOutput, "httpie"); got != "3.2.2" {
+		t.Errorf("pipxVersion = %q", got)
+	}
+
+	if !SameVersion("v1.2.0", "1.2.0") || SameVersion("", "") || SameVersion("1.2.0", "1.3.0") {
+		t.Error("SameVersion compared versions wrongly")
+	}
+}
+
+func TestIsInstalledPinned(t *testing.T) {
+	defer func(run func(context.Context, string, []string, string, ...string) (*system.CommandResult, error), look func(string) (string, error)) {
+		runCommand, lookPath = run, look
+	}(runCommand, lookPath)
+
+	lookPath = func(file string) (string, error) {
+		if file == "tldr" || file == "npm" {
+			return "/usr/local/bin/" + file, nil
+		}
+		return "", exec.ErrNotFound
+	}
+	runCommand = func(ctx context.Context, dir string, env []string, command string, args ...string) (*system.CommandResult, error) {
+		return &system.CommandResult{Success: true, Output: `{"dependencies": {"tldr": {"version": "3.3.0"}}}`}, nil
+	}
+
+	if !IsInstalled("tldr", config.EcosystemTool{Installer: "npm"}) {
+		t.Error("unpinned tool with its binary on PATH should be installed")
+	}
+	if IsInstalled("tldr", config.EcosystemTool{Installer: "npm", Version: "3.4.0"}) {
+		t.Error("tool at another version than the pinned one should not be installed")
+	}
+	if !IsInstalled("tldr", config.EcosystemTool{Installer: "npm", Version: "3.3.0"}) {
+		t.Error("tool at the pinned version should be installed")
+	}
+	if IsInstalled("tsc", config.EcosystemTool{Installer: "npm"}) {
+		t.Error("tool without its binary should not be installed")
+	}
+}
+
+func TestRecordLock(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	installedAt := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	entry := LockEntry{Installer: "go", Package: "golang.org/x/tools/gopls", Version: "v0.16.1", Pinned: true, InstalledAt: installedAt}
+	if err := RecordLock("gopls", entry); err != nil {
+		t.Fatalf("RecordLock failed: %v", err)
+	}
+	if err := RecordLock("tldr", LockEntry{Installer: "npm", Package: "tldr", Version: "3.4.0", InstalledAt: installedAt}); err != nil {
+		t.Fatalf("RecordLock failed: %v", err)
+	}
+
+	lock, err := LoadLock()
+	if err != nil {
+		t.Fatalf("LoadLock failed: %v", err)
+	}
+	if len(lock.Tools) != 2 || !reflect.DeepEqual(lock.Tools["gopls"], entry) {
+		t.Errorf("lock = %+v, want both tools with gopls unchanged", lock.Tools)
+	}
+}
@@ -0,0 +1,84 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ecosystem
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"gopkg.in/yaml.v2"
+)
+
+// LockEntry records the version of an ecosystem tool anvil installed
+type LockEntry struct {
+	Installer   string    `yaml:"installer"`
+	Package     string    `yaml:"package"`
+	Version     string    `yaml:"version,omitempty"`
+	Pinned      bool      `yaml:"pinned,omitempty"` // The version came from ecosystem_tools rather than latest
+	InstalledAt time.Time `yaml:"installed_at"`
+}
+
+// Lock is the content of ~/.anvil/tools.lock
+type Lock struct {
+	Tools map[string]LockEntry `yaml:"tools"`
+}
+
+// GetLockPath returns the path of the tools lock file
+func GetLockPath() string {
+	return filepath.Join(config.GetAnvilConfigDirectory(), constants.TOOLS_LOCK_FILE)
+}
+
+// LoadLock reads the tools lock file, an empty lock when there is none yet
+func LoadLock() (Lock, error) {
+	lock := Lock{Tools: make(map[string]LockEntry)}
+	data, err := os.ReadFile(GetLockPath())
+	if os.IsNotExist(err) {
+		return lock, nil
+	}
+	if err != nil {
+		return lock, err
+	}
+	if err := yaml.Unmarshal(data, &lock); err != nil {
+		return lock, fmt.Errorf("failed to parse %s: %w", constants.TOOLS_LOCK_FILE, err)
+	}
+	if lock.Tools == nil {
+		lock.Tools = make(map[string]LockEntry)
+	}
+	return lock, nil
+}
+
+// RecordLock stores the installed version of a tool in the lock file
+func RecordLock(toolName string, entry LockEntry) error {
+	lock, err := LoadLock()
+	if err != nil {
+		return err
+	}
+	lock.Tools[toolName] = entry
+
+	data, err := yaml.Marshal(lock)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(GetLockPath()), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(GetLockPath(), data, 0644)
+}
@@ -19,6 +19,8 @@ package errors
 import (
 	"fmt"
 	"strings"
+
+	"github.com/0xjuanma/anvil/internal/i18n"
 )
 
 // ErrorType represents different categories of errors
@@ -61,13 +63,20 @@ func (et ErrorType) String() string {
 	}
 }
 
+// Label returns the error type as shown to users, in the active language
+func (et ErrorType) Label() string {
+	return i18n.T("errors.type." + et.String())
+}
+
 // AnvilError represents a structured error with operation, command, and type context
 type AnvilError struct {
-	Op      string    // The operation being performed (init, setup, config, etc.)
-	Command string    // The specific command or subcommand
-	Type    ErrorType // The category of error
-	Err     error     // The underlying error
-	Context string    // Additional context information
+	Op         string    // The operation being performed (init, setup, config, etc.)
+	Command    string    // The specific command or subcommand
+	Type       ErrorType // The category of error
+	Kind       Kind      // What caused the error, KindUnknown lets KindOf classify the wrapped error
+	Err        error     // The underlying error
+	Context    string    // Additional context information
+	Suggestion string    // How to resolve the error, shown below it by the output handler
 }
 
 // Error implements the error interface with improved formatting
@@ -83,7 +92,7 @@ func (e *AnvilError) Error() string {
 
 	// Add error type if not general
 	if e.Type != ErrorTypeGeneral {
-		parts = append(parts, fmt.Sprintf("[%s]", e.Type.String()))
+		parts = append(parts, fmt.Sprintf("[%s]", e.Type.Label()))
 	}
 
 	// Add context if available
@@ -101,6 +110,18 @@ func (e *AnvilError) Unwrap() error {
 	return e.Err
 }
 
+// WithKind records what caused the error, for errors whose cause can't be read from the wrapped error
+func (e *AnvilError) WithKind(kind Kind) *AnvilError {
+	e.Kind = kind
+	return e
+}
+
+// WithSuggestion adds a remediation hint shown below the error
+func (e *AnvilError) WithSuggestion(suggestion string) *AnvilError {
+	e.Suggestion = suggestion
+	return e
+}
+
 // Is checks if the error matches the target error type
 func (e *AnvilError) Is(target error) bool {
 	if t, ok := target.(*AnvilError); ok {
@@ -0,0 +1,231 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package errors
+
+import (
+	"context"
+	stderrors "errors"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/0xjuanma/anvil/internal/i18n"
+)
+
+// Kind is what caused an error, independent of the operation that failed. Callers branch
+// on it to decide whether to retry, which exit code to use and which hint to show.
+type Kind int
+
+const (
+	// KindUnknown is an error whose cause could not be classified
+	KindUnknown Kind = iota
+	// KindNetwork is a failed connection, DNS lookup or download
+	KindNetwork
+	// KindTimeout is an operation that ran out of time
+	KindTimeout
+	// KindPermission is a denied file access or rejected credentials
+	KindPermission
+	// KindNotFound is a missing file, package or remote ref
+	KindNotFound
+	// KindCancelled is an operation stopped by the user
+	KindCancelled
+)
+
+// String returns a string representation of the kind
+func (k Kind) String() string {
+	switch k {
+	case KindNetwork:
+		return "network"
+	case KindTimeout:
+		return "timeout"
+	case KindPermission:
+		return "permission"
+	case KindNotFound:
+		return "not-found"
+	case KindCancelled:
+		return "cancelled"
+	default:
+		return "unknown"
+	}
+}
+
+// Exit codes returned for failed commands, by the kind or type of the error
+const (
+	ExitFailure    = 1   // Any error not covered below
+	ExitValidation = 2   // Invalid arguments or input
+	ExitConfig     = 3   // settings.yaml is missing, invalid or incomplete
+	ExitNetwork    = 4   // Network failures and timeouts
+	ExitPermission = 5   // Denied file access or rejected credentials
+	ExitNotFound   = 6   // Missing files, packages or refs
+	ExitCancelled  = 130 // Cancelled by the user, as shells report Ctrl-C
+)
+
+// outputSignatures maps fragments of command output, such as git or curl errors, to kinds.
+// They are checked in order and case-insensitively.
+var outputSignatures = []struct {
+	kind      Kind
+	fragments []string
+}{
+	{KindTimeout, []string{"timed out", "i/o timeout", "deadline exceeded"}},
+	{KindNetwork, []string{"could not resolve host", "no such host", "temporary failure in name resolution",
+		"failed to connect", "connection refused", "connection reset", "network is unreachable",
+		"unable to access", "the remote end hung up unexpectedly", "early eof", "rpc failed",
+		"curl: (6)", "curl: (7)", "api unreachable"}},
+	{KindPermission, []string{"permission denied", "operation not permitted", "authentication failed",
+		"could not read username", "access denied", "403 forbidden", "authentication required", "authorization failed"}},
+	{KindNotFound, []string{"no such file or directory", "repository not found", "couldn't find remote ref",
+		"no available formula", "no available cask"}},
+}
+
+// KindOf classifies what caused err. A kind set on an AnvilError wins, then network errors by
+// type, then standard library errors such as context.Canceled or os.ErrPermission, and
+// finally known fragments of the error message.
+func KindOf(err error) Kind {
+	if err == nil {
+		return KindUnknown
+	}
+
+	for current := err; current != nil; current = stderrors.Unwrap(current) {
+		if anvilErr, ok := current.(*AnvilError); ok {
+			if anvilErr.Kind != KindUnknown {
+				return anvilErr.Kind
+			}
+			if anvilErr.Type == ErrorTypeNetwork {
+				return KindNetwork
+			}
+		}
+	}
+
+	var netErr net.Error
+	switch {
+	case stderrors.Is(err, context.Canceled):
+		return KindCancelled
+	case stderrors.Is(err, context.DeadlineExceeded), stderrors.Is(err, os.ErrDeadlineExceeded):
+		return KindTimeout
+	case stderrors.As(err, &netErr):
+		if netErr.Timeout() {
+			return KindTimeout
+		}
+		return KindNetwork
+	case stderrors.Is(err, os.ErrPermission):
+		return KindPermission
+	case stderrors.Is(err, os.ErrNotExist):
+		return KindNotFound
+	}
+
+	message := strings.ToLower(err.Error())
+	for _, signature := range outputSignatures {
+		for _, fragment := range signature.fragments {
+			if strings.Contains(message, fragment) {
+				return signature.kind
+			}
+		}
+	}
+	return KindUnknown
+}
+
+// IsNetwork reports whether err was caused by a failed connection, DNS lookup or download
+func IsNetwork(err error) bool {
+	return KindOf(err) == KindNetwork
+}
+
+// IsTimeout reports whether err was caused by an operation running out of time
+func IsTimeout(err error) bool {
+	return KindOf(err) == KindTimeout
+}
+
+// IsPermission reports whether err was caused by denied access or rejected credentials
+func IsPermission(err error) bool {
+	return KindOf(err) == KindPermission
+}
+
+// IsNotFound reports whether err was caused by a missing file, package or ref
+func IsNotFound(err error) bool {
+	return KindOf(err) == KindNotFound
+}
+
+// IsCancelled reports whether err was caused by the user stopping the operation
+func IsCancelled(err error) bool {
+	return KindOf(err) == KindCancelled
+}
+
+// IsPermanent reports whether retrying err can't help: missing things, denied access,
+// cancellation, and invalid input or settings
+func IsPermanent(err error) bool {
+	switch KindOf(err) {
+	case KindNotFound, KindPermission, KindCancelled:
+		return true
+	case KindNetwork, KindTimeout:
+		return false
+	}
+	errType := typeOf(err)
+	return errType == ErrorTypeValidation || errType == ErrorTypeConfiguration
+}
+
+// ExitCode maps err to the exit code of a failed command, 0 when err is nil
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	switch KindOf(err) {
+	case KindCancelled:
+		return ExitCancelled
+	case KindNetwork, KindTimeout:
+		return ExitNetwork
+	case KindPermission:
+		return ExitPermission
+	case KindNotFound:
+		return ExitNotFound
+	}
+
+	switch typeOf(err) {
+	case ErrorTypeValidation:
+		return ExitValidation
+	case ErrorTypeConfiguration:
+		return ExitConfig
+	}
+	return ExitFailure
+}
+
+// Suggestion returns how to resolve err: the first suggestion set on an AnvilError it wraps,
+// or a general hint for its kind. It is empty when there is nothing useful to add.
+func Suggestion(err error) string {
+	if err == nil {
+		return ""
+	}
+	for current := err; current != nil; current = stderrors.Unwrap(current) {
+		if anvilErr, ok := current.(*AnvilError); ok && anvilErr.Suggestion != "" {
+			return anvilErr.Suggestion
+		}
+	}
+
+	switch kind := KindOf(err); kind {
+	case KindNetwork, KindTimeout, KindPermission:
+		return i18n.T("errors.suggestion." + kind.String())
+	}
+	return ""
+}
+
+// typeOf returns the type of the outermost AnvilError in err's chain
+func typeOf(err error) ErrorType {
+	var anvilErr *AnvilError
+	if stderrors.As(err, &anvilErr) {
+		return anvilErr.Type
+	}
+	return ErrorTypeGeneral
+}
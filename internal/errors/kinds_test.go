@@ -0,0 +1,127 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package errors
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+)
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want Kind
+	}{
+		{"nil", nil, KindUnknown},
+		{"plain", stderrors.New("something broke"), KindUnknown},
+		{"explicit kind", NewInstallationError("install", "brew", stderrors.New("exit 1")).WithKind(KindNotFound), KindNotFound},
+		{"network type", NewNetworkError("update", "download", stderrors.New("bad gateway")), KindNetwork},
+		{"cancelled", fmt.Errorf("install: %w", context.Canceled), KindCancelled},
+		{"deadline", fmt.Errorf("install: %w", context.DeadlineExceeded), KindTimeout},
+		{"dns", &net.DNSError{Err: "no such host", Name: "github.com"}, KindNetwork},
+		{"permission", NewFileSystemError("config", "write", os.ErrPermission), KindPermission},
+		{"not exist", fmt.Errorf("read settings: %w", os.ErrNotExist), KindNotFound},
+		{"git output", stderrors.New("fatal: unable to access 'https://github.com/x/y/': Could not resolve host: github.com"), KindNetwork},
+		{"ssh output", stderrors.New("git@github.com: Permission denied (publickey)."), KindPermission},
+		{"brew output", stderrors.New("Error: No available formula with the name \"nope\"."), KindNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := KindOf(tt.err); got != tt.want {
+				t.Errorf("KindOf() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKindOfOuterKindWins(t *testing.T) {
+	inner := NewFileSystemError("config", "read", os.ErrNotExist)
+	outer := NewConfigurationError("config", "pull", inner).WithKind(KindNetwork)
+	if !IsNetwork(outer) {
+		t.Errorf("expected the outer kind to win, got %s", KindOf(outer))
+	}
+	if !IsNotFound(inner) {
+		t.Errorf("expected the inner error to stay not-found, got %s", KindOf(inner))
+	}
+}
+
+func TestIsPermanent(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{stderrors.New("exit status 1"), false},
+		{stderrors.New("connection reset by peer"), false},
+		{stderrors.New("Repository not found."), true},
+		{NewValidationError("install", "args", stderrors.New("bad name")), true},
+		{NewConfigurationError("config", "load", stderrors.New("invalid yaml")), true},
+		{NewConfigurationError("config", "pull", stderrors.New("early EOF")), false},
+	}
+
+	for _, tt := range tests {
+		if got := IsPermanent(tt.err); got != tt.want {
+			t.Errorf("IsPermanent(%q) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, 0},
+		{stderrors.New("boom"), ExitFailure},
+		{NewValidationError("install", "args", stderrors.New("bad name")), ExitValidation},
+		{NewConfigurationError("config", "load", stderrors.New("invalid yaml")), ExitConfig},
+		{NewConfigurationError("config", "pull", stderrors.New("Could not resolve host: github.com")), ExitNetwork},
+		{fmt.Errorf("push: %w", context.DeadlineExceeded), ExitNetwork},
+		{NewFileSystemError("config", "write", os.ErrPermission), ExitPermission},
+		{fmt.Errorf("read: %w", os.ErrNotExist), ExitNotFound},
+		{context.Canceled, ExitCancelled},
+	}
+
+	for _, tt := range tests {
+		if got := ExitCode(tt.err); got != tt.want {
+			t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestSuggestion(t *testing.T) {
+	inner := NewInstallationError("install", "brew", stderrors.New("exit 1")).WithSuggestion("run 'brew update' first")
+	wrapped := fmt.Errorf("install failed: %w", NewInstallationError("install", "group", inner).WithSuggestion("retry the group"))
+	if got := Suggestion(wrapped); got != "retry the group" {
+		t.Errorf("expected the outermost suggestion, got %q", got)
+	}
+
+	if got := Suggestion(stderrors.New("connection refused")); got == "" {
+		t.Error("expected a default suggestion for network errors")
+	}
+	if got := Suggestion(stderrors.New("boom")); got != "" {
+		t.Errorf("expected no suggestion for unclassified errors, got %q", got)
+	}
+	if got := Suggestion(nil); got != "" {
+		t.Errorf("expected no suggestion for nil, got %q", got)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package events is an in-process bus for changes a command makes, such as a tool being
+// installed or a branch pushed. Publishers don't know who listens; the end-of-command
+// summary is built from these events.
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Kind names what happened
+type Kind string
+
+const (
+	Installed   Kind = "installed"   // A tool was installed
+	Failed      Kind = "failed"      // A tool failed to install
+	Uninstalled Kind = "uninstalled" // A tool was removed
+	Tracked     Kind = "tracked"     // An app was added to settings.yaml
+	Pulled      Kind = "pulled"      // A config directory was pulled from the config repository
+	Synced      Kind = "synced"      // Pulled configs were applied locally
+	Pushed      Kind = "pushed"      // A branch was pushed to the config repository
+)
+
+// Event is a single change made by the running command
+type Event struct {
+	Kind    Kind
+	Target  string // Tool, app or branch the event is about
+	Details string // Optional, e.g. the pull request link of a pushed branch
+	Time    time.Time
+}
+
+// Handler receives published events. Installs publish from several goroutines, so handlers
+// must be safe for concurrent use.
+type Handler func(Event)
+
+var (
+	mu       sync.Mutex
+	handlers = make(map[int]Handler)
+	nextID   int
+)
+
+// Subscribe registers a handler for every event published from now on and returns a
+// function that removes it
+func Subscribe(handler Handler) (unsubscribe func()) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	id := nextID
+	nextID++
+	handlers[id] = handler
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		delete(handlers, id)
+	}
+}
+
+// Publish sends an event to all subscribers; it is a no-op when nobody listens
+func Publish(kind Kind, target, details string) {
+	mu.Lock()
+	subscribed := make([]Handler, 0, len(handlers))
+	for _, handler := range handlers {
+		subscribed = append(subscribed, handler)
+	}
+	mu.Unlock()
+
+	event := Event{Kind: kind, Target: target, Details: details, Time: time.Now()}
+	for _, handler := range subscribed {
+		handler(event)
+	}
+}
@@ -0,0 +1,389 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/system"
+)
+
+const (
+	// APIBaseURL is the GitHub REST API
+	APIBaseURL = "https://api.github.com"
+
+	apiVersion      = "2022-11-28"
+	apiResponseSize = 10 * 1024 * 1024
+)
+
+// APIClient calls the GitHub REST API. Responses are cached on disk and revalidated with
+// conditional requests, which GitHub does not count against the rate limit when unchanged.
+// Once the rate limit is hit, further calls fail fast until it resets instead of retrying.
+type APIClient struct {
+	BaseURL    string
+	Token      string        // Optional, raises the limit from 60 to 5000 requests per hour
+	CacheDir   string        // Directory of the response cache, empty disables it
+	MaxAge     time.Duration // Cached responses younger than this are used without a request
+	HTTPClient *http.Client  // Nil uses the client configured by the network settings
+}
+
+// NewAPIClient creates an API client with a response cache in cacheDir
+func NewAPIClient(token, cacheDir string) *APIClient {
+	return &APIClient{BaseURL: APIBaseURL, Token: token, CacheDir: cacheDir}
+}
+
+// RateLimit is the rate limit state reported with a response
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// APIResponse is a successful, or stale cached, API response
+type APIResponse struct {
+	Status    int
+	Body      []byte
+	Cached    bool      // Served from the cache, fresh or revalidated
+	Stale     bool      // Served from the cache because the API could not be asked
+	FetchedAt time.Time // When the body was last fetched or revalidated
+	RateLimit RateLimit
+}
+
+// APIError is an error status returned by the API, such as 404 for a repository that does
+// not exist or is not visible with the current credentials
+type APIError struct {
+	Status  int
+	Message string
+}
+
+// Error returns the status with GitHub's message
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("GitHub API returned %d", e.Status)
+	}
+	return fmt.Sprintf("GitHub API returned %d: %s", e.Status, e.Message)
+}
+
+// RateLimitError is returned while the API rate limit is exhausted
+type RateLimitError struct {
+	Reset         time.Time
+	Authenticated bool
+}
+
+// Error explains when the limit resets and how to raise it
+func (e *RateLimitError) Error() string {
+	message := "GitHub API rate limit reached"
+	if !e.Reset.IsZero() {
+		message += fmt.Sprintf(", it resets at %s (in %s)", e.Reset.Local().Format("15:04"), time.Until(e.Reset).Round(time.Minute))
+	}
+	if !e.Authenticated {
+		message += ". Set the variable named by 'github.token_env_var' to a token for a higher limit"
+	}
+	return message
+}
+
+// IsRateLimited reports whether err is, or wraps, a RateLimitError
+func IsRateLimited(err error) bool {
+	var limited *RateLimitError
+	return stderrors.As(err, &limited)
+}
+
+// rateLimited remembers, per credential, until when the API refuses requests
+var rateLimited = struct {
+	sync.Mutex
+	until map[bool]time.Time
+}{until: make(map[bool]time.Time)}
+
+// cachedResponse is a cache entry with the validators for conditional requests
+type cachedResponse struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	FetchedAt    time.Time `json:"fetched_at"`
+	Body         []byte    `json:"body"`
+}
+
+// Get requests an API path such as /repos/owner/name and decodes the JSON body into v when
+// v is not nil. When the API is rate limited or unreachable and the response is cached, the
+// stale response is returned together with the error so callers can choose to degrade.
+func (c *APIClient) Get(ctx context.Context, path string, v any) (*APIResponse, error) {
+	url := strings.TrimRight(c.baseURL(), "/") + "/" + strings.TrimLeft(path, "/")
+	authenticated := c.Token != ""
+	cached := c.loadCached(url)
+
+	if cached != nil && c.MaxAge > 0 && time.Since(cached.FetchedAt) < c.MaxAge {
+		return decodeResponse(&APIResponse{Status: http.StatusOK, Body: cached.Body, Cached: true, FetchedAt: cached.FetchedAt}, v)
+	}
+	if reset, limited := rateLimitedUntil(authenticated); limited {
+		return staleResponse(cached, v, &RateLimitError{Reset: reset, Authenticated: authenticated})
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.setHeaders(req)
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return staleResponse(cached, v, fmt.Errorf("GitHub API unreachable: %w", err))
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, apiResponseSize))
+	if err != nil {
+		return staleResponse(cached, v, fmt.Errorf("failed to read GitHub API response: %w", err))
+	}
+	limit := parseRateLimit(resp.Header)
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		cached.FetchedAt = time.Now()
+		c.saveCached(cached)
+		return decodeResponse(&APIResponse{Status: http.StatusOK, Body: cached.Body, Cached: true, FetchedAt: cached.FetchedAt, RateLimit: limit}, v)
+	case isRateLimitResponse(resp, limit):
+		reset := limit.Reset
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			reset = time.Now().Add(time.Duration(seconds) * time.Second)
+		}
+		markRateLimited(authenticated, reset)
+		return staleResponse(cached, v, &RateLimitError{Reset: reset, Authenticated: authenticated})
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		entry := &cachedResponse{
+			URL:          url,
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			FetchedAt:    time.Now(),
+			Body:         body,
+		}
+		c.saveCached(entry)
+		return decodeResponse(&APIResponse{Status: resp.StatusCode, Body: body, FetchedAt: entry.FetchedAt, RateLimit: limit}, v)
+	}
+
+	return nil, newAPIError(resp.StatusCode, body)
+}
+
+// Post sends a JSON request body to an API path and decodes the JSON response into v when v
+// is not nil. Writes always need a token and are never cached.
+func (c *APIClient) Post(ctx context.Context, path string, payload, v any) (*APIResponse, error) {
+	if c.Token == "" {
+		return nil, fmt.Errorf("GitHub API writes need a token, set the variable named by 'github.token_env_var'")
+	}
+	if reset, limited := rateLimitedUntil(true); limited {
+		return nil, &RateLimitError{Reset: reset, Authenticated: true}
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode GitHub API request: %w", err)
+	}
+	url := strings.TrimRight(c.baseURL(), "/") + "/" + strings.TrimLeft(path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	c.setHeaders(req)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("GitHub API unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, apiResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GitHub API response: %w", err)
+	}
+	limit := parseRateLimit(resp.Header)
+
+	if isRateLimitResponse(resp, limit) {
+		markRateLimited(true, limit.Reset)
+		return nil, &RateLimitError{Reset: limit.Reset, Authenticated: true}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, newAPIError(resp.StatusCode, body)
+	}
+	return decodeResponse(&APIResponse{Status: resp.StatusCode, Body: body, FetchedAt: time.Now(), RateLimit: limit}, v)
+}
+
+// setHeaders sets the headers every API request carries
+func (c *APIClient) setHeaders(req *http.Request) {
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", apiVersion)
+	req.Header.Set("User-Agent", "anvil")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+}
+
+// newAPIError reads GitHub's message, and the first validation error detail, from an error response
+func newAPIError(status int, body []byte) *APIError {
+	var apiMessage struct {
+		Message string `json:"message"`
+		Errors  []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	_ = json.Unmarshal(body, &apiMessage)
+	message := apiMessage.Message
+	if len(apiMessage.Errors) > 0 && apiMessage.Errors[0].Message != "" {
+		message += ": " + apiMessage.Errors[0].Message
+	}
+	return &APIError{Status: status, Message: message}
+}
+
+// baseURL returns the API root, GitHub's unless overridden
+func (c *APIClient) baseURL() string {
+	if c.BaseURL == "" {
+		return APIBaseURL
+	}
+	return c.BaseURL
+}
+
+// httpClient returns the configured client, honoring proxy and CA settings by default
+func (c *APIClient) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return system.HTTPClient()
+}
+
+// cachePath returns the cache file of a URL. The token is part of the key so responses only
+// visible to one credential are never served to another.
+func (c *APIClient) cachePath(url string) string {
+	hash := sha256.Sum256([]byte(c.Token + "\x00" + url))
+	return filepath.Join(c.CacheDir, hex.EncodeToString(hash[:16])+".json")
+}
+
+// loadCached returns the cached response of a URL, or nil
+func (c *APIClient) loadCached(url string) *cachedResponse {
+	if c.CacheDir == "" {
+		return nil
+	}
+	data, err := os.ReadFile(c.cachePath(url))
+	if err != nil {
+		return nil
+	}
+	var entry cachedResponse
+	if err := json.Unmarshal(data, &entry); err != nil || entry.URL != url {
+		return nil
+	}
+	return &entry
+}
+
+// saveCached stores a response, failures only cost the next call its conditional request
+func (c *APIClient) saveCached(entry *cachedResponse) {
+	if c.CacheDir == "" {
+		return
+	}
+	if err := os.MkdirAll(c.CacheDir, 0700); err != nil {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	// Responses about private repositories are readable by the owner only
+	_ = os.WriteFile(c.cachePath(entry.URL), data, 0600)
+}
+
+// staleResponse returns the cached response, marked stale, along with the error
+func staleResponse(cached *cachedResponse, v any, err error) (*APIResponse, error) {
+	if cached == nil {
+		return nil, err
+	}
+	resp, decodeErr := decodeResponse(&APIResponse{Status: http.StatusOK, Body: cached.Body, Cached: true, Stale: true, FetchedAt: cached.FetchedAt}, v)
+	if decodeErr != nil {
+		return nil, err
+	}
+	return resp, err
+}
+
+// decodeResponse decodes the body into v when v is not nil
+func decodeResponse(resp *APIResponse, v any) (*APIResponse, error) {
+	if v == nil {
+		return resp, nil
+	}
+	if err := json.Unmarshal(resp.Body, v); err != nil {
+		return nil, fmt.Errorf("failed to decode GitHub API response: %w", err)
+	}
+	return resp, nil
+}
+
+// parseRateLimit reads the X-RateLimit headers, missing values stay zero
+func parseRateLimit(header http.Header) RateLimit {
+	var limit RateLimit
+	limit.Limit, _ = strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	limit.Remaining, _ = strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		limit.Reset = time.Unix(reset, 0)
+	}
+	return limit
+}
+
+// isRateLimitResponse distinguishes rate limiting from other 403 responses such as missing scopes
+func isRateLimitResponse(resp *http.Response, limit RateLimit) bool {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	if resp.StatusCode != http.StatusForbidden {
+		return false
+	}
+	return resp.Header.Get("Retry-After") != "" || (resp.Header.Get("X-RateLimit-Remaining") != "" && limit.Remaining == 0)
+}
+
+// markRateLimited records until when the API refuses requests for a credential
+func markRateLimited(authenticated bool, reset time.Time) {
+	if reset.IsZero() {
+		reset = time.Now().Add(time.Minute)
+	}
+	rateLimited.Lock()
+	defer rateLimited.Unlock()
+	rateLimited.until[authenticated] = reset
+}
+
+// rateLimitedUntil returns the reset time while the API is known to refuse requests
+func rateLimitedUntil(authenticated bool) (time.Time, bool) {
+	rateLimited.Lock()
+	defer rateLimited.Unlock()
+	reset, ok := rateLimited.until[authenticated]
+	if !ok || time.Now().After(reset) {
+		return time.Time{}, false
+	}
+	return reset, true
+}
@@ -25,7 +25,6 @@ import (
 
 	"github.com/0xjuanma/anvil/internal/constants"
 	"github.com/0xjuanma/anvil/internal/errors"
-	"github.com/0xjuanma/anvil/internal/system"
 	"github.com/0xjuanma/anvil/internal/utils"
 )
 
@@ -126,17 +125,6 @@ func (gc *GitHubClient) generateNewAppDiff(ctx context.Context, sourcePath, targ
 
 // generateGitDiff handles diff generation using Git's native capabilities (simplified)
 func (gc *GitHubClient) generateGitDiff(ctx context.Context, sourcePath, targetPath string) (*DiffSummary, error) {
-	// Change to repo directory
-	originalDir, err := os.Getwd()
-	if err != nil {
-		return nil, errors.NewFileSystemError(constants.OpPush, "getwd", err)
-	}
-	defer os.Chdir(originalDir)
-
-	if err := os.Chdir(gc.LocalPath); err != nil {
-		return nil, errors.NewFileSystemError(constants.OpPush, "chdir", err)
-	}
-
 	// Check if this is a new app (target doesn't exist in repo)
 	repoTargetPath := filepath.Join(gc.LocalPath, targetPath)
 	isNewApp := false
@@ -172,40 +160,96 @@ func (gc *GitHubClient) generateGitDiff(ctx context.Context, sourcePath, targetP
 	}
 
 	// Stage the target files
-	if _, err := system.RunCommandWithTimeout(ctx, constants.GitCommand, "add", targetPath); err != nil {
+	if _, err := gc.git(ctx, "add", targetPath); err != nil {
 		return nil, errors.NewInstallationError(constants.OpPush, "git-add", err)
 	}
 
 	// Get Git's native stat output
-	statResult, err := system.RunCommandWithTimeout(ctx, constants.GitCommand,
-		"diff", "--cached", "--stat", "--stat-width=80")
+	statOutput, err := gc.git(ctx, "diff", "--cached", "--stat", "--stat-width=80")
 	if err != nil {
 		return nil, errors.NewInstallationError(constants.OpPush, "git-diff-stat", err)
 	}
 
 	// Get full diff only for small single files
 	var fullDiff string
-	if gc.isSingleSmallFile(statResult.Output) {
-		diffResult, err := system.RunCommandWithTimeout(ctx, constants.GitCommand,
-			"diff", "--cached", "--no-color")
-		if err == nil {
-			fullDiff = diffResult.Output
+	if gc.isSingleSmallFile(statOutput) {
+		if diffOutput, err := gc.git(ctx, "diff", "--cached", "--no-color"); err == nil {
+			fullDiff = diffOutput
 		}
 	}
 
 	// Always reset staging area
-	system.RunCommandWithTimeout(ctx, constants.GitCommand, "reset", "HEAD")
+	gc.git(ctx, "reset", "HEAD")
 
 	// Revert any changes we made to the working directory during diff generation
 	// This safely reverts only the changes we made, without removing existing files
-	system.RunCommandWithTimeout(ctx, constants.GitCommand, "checkout", "--", targetPath)
+	gc.git(ctx, "checkout", "--", targetPath)
 
 	// Clean up any untracked files that were created during diff generation
-	system.RunCommandWithTimeout(ctx, constants.GitCommand, "clean", "-fd", targetPath)
+	gc.git(ctx, "clean", "-fd", targetPath)
 
 	return &DiffSummary{
-		GitStatOutput: statResult.Output,
+		GitStatOutput: statOutput,
 		FullDiff:      fullDiff,
-		TotalFiles:    gc.extractFileCount(statResult.Output),
+		TotalFiles:    gc.extractFileCount(statOutput),
 	}, nil
 }
+
+// ConfigChanges lists the files that differ between a local config and its copy in the repository.
+// Paths are relative to LocalRoot and RepoRoot; added files exist only locally, removed files only in the repository.
+type ConfigChanges struct {
+	utils.DirChanges
+	LocalRoot string
+	RepoRoot  string
+}
+
+// CompareConfig updates the local clone and compares localPath with targetPath inside it.
+// A single file is compared by its name within the parent directories, so it shows up as one changed path.
+func (gc *GitHubClient) CompareConfig(ctx context.Context, localPath, targetPath string) (*ConfigChanges, error) {
+	if err := gc.CloneRepository(ctx); err != nil {
+		return nil, fmt.Errorf("failed to clone repository: %w", err)
+	}
+	if err := gc.switchToMainBranch(ctx); err != nil {
+		return nil, fmt.Errorf("failed to switch to main branch: %w", err)
+	}
+	if err := gc.PullChanges(ctx); err != nil {
+		return nil, fmt.Errorf("failed to pull latest changes: %w", err)
+	}
+
+	repoPath := filepath.Join(gc.LocalPath, targetPath)
+	localInfo, localErr := os.Stat(localPath)
+	if localErr != nil && !os.IsNotExist(localErr) {
+		return nil, fmt.Errorf("failed to stat local path %s: %w", localPath, localErr)
+	}
+	repoInfo, repoErr := os.Stat(repoPath)
+	if os.IsNotExist(localErr) && os.IsNotExist(repoErr) {
+		return nil, fmt.Errorf("neither %s nor %s exists in the repository", localPath, targetPath)
+	}
+
+	singleFile := (localErr == nil && !localInfo.IsDir()) || (repoErr == nil && !repoInfo.IsDir())
+	if !singleFile {
+		changes, err := gc.directoryChanges(localPath, repoPath)
+		if err != nil {
+			return nil, err
+		}
+		return &ConfigChanges{DirChanges: changes, LocalRoot: localPath, RepoRoot: repoPath}, nil
+	}
+
+	result := &ConfigChanges{LocalRoot: filepath.Dir(localPath), RepoRoot: filepath.Dir(repoPath)}
+	name := filepath.Base(localPath)
+	switch {
+	case os.IsNotExist(repoErr):
+		result.Added = []string{name}
+	case os.IsNotExist(localErr):
+		result.Removed = []string{name}
+	default:
+		changed, err := gc.hasFileOrDirChanges(localPath, repoPath)
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			result.Modified = []string{name}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,151 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"strings"
+
+	"github.com/0xjuanma/palantir"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// maxDivergenceCommits bounds the commits listed when explaining a divergence
+const maxDivergenceCommits = 10
+
+// AppDivergence describes changes other machines pushed to an app directory on the configured
+// branch after this machine last pulled or pushed it. Pushing over them would undo them.
+type AppDivergence struct {
+	App      string
+	Base     string   // Branch head the directory was last read at
+	Commits  []string // Commits that changed the directory since, newest first
+	Files    []string // Files of the directory that changed since
+	CanMerge bool     // Base is in the clone's history, a push can start from it and merge the branch
+}
+
+// AppTree returns the tree hash of an app directory at a revision, empty when the directory
+// does not exist there
+func (gc *GitHubClient) AppTree(revision, appName string) (string, error) {
+	repo, err := gc.openRepository()
+	if err != nil {
+		return "", err
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		return "", err
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return "", err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return "", err
+	}
+	entry, err := tree.FindEntry(appName)
+	if stderrors.Is(err, object.ErrEntryNotFound) || stderrors.Is(err, object.ErrDirectoryNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return entry.Hash.String(), nil
+}
+
+// BranchHead returns the commit of the configured branch in the local clone
+func (gc *GitHubClient) BranchHead() (string, error) {
+	repo, err := gc.openRepository()
+	if err != nil {
+		return "", err
+	}
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName(gc.Branch), true)
+	if err != nil {
+		return "", err
+	}
+	return ref.Hash().String(), nil
+}
+
+// CheckAppDivergence compares an app directory on the configured branch with the trees this
+// machine knows it in, and returns nil when it is one of them. The clone must be up to date,
+// as after GetDiffPreview. Otherwise the commits and files that moved it since base are listed.
+func (gc *GitHubClient) CheckAppDivergence(ctx context.Context, appName, base string, knownTrees ...string) (*AppDivergence, error) {
+	current, err := gc.AppTree(gc.Branch, appName)
+	if err != nil {
+		return nil, err
+	}
+	for _, tree := range knownTrees {
+		if tree == current {
+			return nil, nil
+		}
+	}
+
+	divergence := &AppDivergence{App: appName, Base: base}
+	logArgs := []string{"log", fmt.Sprintf("-%d", maxDivergenceCommits), "--date=short", "--format=%h %ad %an: %s"}
+	if base != "" && gc.commitExists(ctx, base) {
+		divergence.CanMerge = true
+		logArgs = append(logArgs, base+".."+gc.Branch)
+		if out, err := gc.git(ctx, "diff", "--name-only", base, gc.Branch, "--", appName); err == nil {
+			divergence.Files = nonEmptyLines(out)
+		}
+	} else {
+		// The base was rewritten away, e.g. by a force push, only recent history can be shown
+		logArgs = append(logArgs, gc.Branch)
+	}
+	if out, err := gc.git(ctx, append(logArgs, "--", appName)...); err == nil {
+		divergence.Commits = nonEmptyLines(out)
+	}
+	return divergence, nil
+}
+
+// commitExists reports whether a commit is in the local clone's history
+func (gc *GitHubClient) commitExists(ctx context.Context, commit string) bool {
+	_, err := gc.git(ctx, "cat-file", "-e", commit+"^{commit}")
+	return err == nil
+}
+
+// nonEmptyLines splits command output into its non-empty lines
+func nonEmptyLines(out string) []string {
+	var lines []string
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// mergeConfiguredBranch merges the configured branch into a push branch started at MergeBase,
+// so the push keeps what other machines pushed since. A conflicting merge is aborted and left
+// to the pull request, which then shows the conflicts.
+func (gc *GitHubClient) mergeConfiguredBranch(ctx context.Context, branchName string) error {
+	output := palantir.GetGlobalOutputHandler()
+	message := fmt.Sprintf("anvil[push]: merge %s into %s", gc.Branch, branchName)
+	if _, err := gc.git(ctx, "merge", "--no-edit", "-m", message, gc.Branch); err != nil {
+		conflicts, _ := gc.git(ctx, "diff", "--name-only", "--diff-filter=U")
+		gc.git(ctx, "merge", "--abort")
+		output.PrintWarning("Could not merge '%s' into '%s', resolve these conflicts in the pull request:", gc.Branch, branchName)
+		for _, file := range nonEmptyLines(conflicts) {
+			output.PrintInfo("  • %s", file)
+		}
+		return nil
+	}
+	output.PrintSuccess(fmt.Sprintf("Merged changes from '%s' into '%s'", gc.Branch, branchName))
+	return gc.pushBranchWithRetry(ctx, branchName)
+}
@@ -0,0 +1,43 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// HasUnpushedChanges reports whether a local config differs from its copy in the local clone.
+// It only reads the filesystem, so the answer reflects the clone as of the last pull or push.
+func (gc *GitHubClient) HasUnpushedChanges(appName, configPath string) (bool, error) {
+	localInfo, err := os.Stat(configPath)
+	if err != nil {
+		return false, err
+	}
+
+	repoPath := filepath.Join(gc.LocalPath, appName)
+	if _, err := os.Stat(repoPath); os.IsNotExist(err) {
+		return false, nil // Never pushed, nothing to drift from
+	}
+
+	// Single-file configs are stored inside the app directory under their own name
+	if !localInfo.IsDir() {
+		repoPath = filepath.Join(repoPath, filepath.Base(configPath))
+	}
+
+	return gc.hasFileOrDirChanges(configPath, repoPath)
+}
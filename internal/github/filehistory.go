@@ -0,0 +1,194 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+)
+
+// FileCommit is a commit of the config repository that touched a file
+type FileCommit struct {
+	Commit  string
+	Date    time.Time
+	Author  string
+	Subject string
+}
+
+// ShortCommit returns the abbreviated commit hash
+func (c FileCommit) ShortCommit() string {
+	if len(c.Commit) > 7 {
+		return c.Commit[:7]
+	}
+	return c.Commit
+}
+
+// fileLogFormat separates fields with the unit separator so subjects can contain any text
+const fileLogFormat = "--format=%H%x1f%aI%x1f%an%x1f%s"
+
+// FileHistory lists the last limit commits of the configured branch that touched a file of the
+// config repository, newest first. Renames are followed.
+func (gc *GitHubClient) FileHistory(ctx context.Context, file string, limit int) ([]FileCommit, error) {
+	repoPath, err := gc.prepareFileLookup(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"log", fileLogFormat, "--follow"}
+	if limit > 0 {
+		args = append(args, fmt.Sprintf("-n%d", limit))
+	}
+	args = append(args, gc.historyRef(ctx), "--", repoPath)
+	out, err := gc.git(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history of %s: %w", repoPath, err)
+	}
+	return parseFileLog(out), nil
+}
+
+// AppCommit is a commit of the config repository with the app directories it changed
+type AppCommit struct {
+	FileCommit
+	Apps []string
+}
+
+// AppHistory lists the last limit commits of the configured branch that changed an app
+// directory, or any app directory when app is empty, newest first. The local clone is read
+// as it is, so commits newer than the last pull are missing. A shallow clone is fetched in
+// full first when the remote can be reached.
+func (gc *GitHubClient) AppHistory(ctx context.Context, app string, limit int) ([]AppCommit, error) {
+	if !gc.isValidGitRepository() {
+		return nil, errors.NewConfigurationError(constants.OpConfig, "app-history",
+			fmt.Errorf("no local clone at %s, run 'anvil config pull' first", gc.LocalPath))
+	}
+	_ = gc.ensureFullHistory(ctx)
+
+	args := []string{"log", fileLogFormat, "--name-only"}
+	if limit > 0 {
+		args = append(args, fmt.Sprintf("-n%d", limit))
+	}
+	args = append(args, gc.historyRef(ctx))
+	if app != "" {
+		args = append(args, "--", app)
+	}
+	out, err := gc.git(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repository history: %w", err)
+	}
+	return parseAppLog(out), nil
+}
+
+// parseAppLog parses git log --name-only output written with fileLogFormat. Files at the
+// repository root, such as the README, belong to no app and commits touching only them are dropped.
+func parseAppLog(out string) []AppCommit {
+	var commits []AppCommit
+	var current *AppCommit
+	flush := func() {
+		if current != nil && len(current.Apps) > 0 {
+			commits = append(commits, *current)
+		}
+		current = nil
+	}
+
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.Contains(line, "\x1f") {
+			flush()
+			if parsed := parseFileLog(line); len(parsed) == 1 {
+				current = &AppCommit{FileCommit: parsed[0]}
+			}
+			continue
+		}
+		app, _, nested := strings.Cut(line, "/")
+		if current != nil && nested && !slices.Contains(current.Apps, app) {
+			current.Apps = append(current.Apps, app)
+		}
+	}
+	flush()
+	return commits
+}
+
+// FileAtRevision returns the content of a config repository file at a commit, branch, tag or YYYY-MM-DD date
+func (gc *GitHubClient) FileAtRevision(ctx context.Context, file, ref string) (string, string, error) {
+	repoPath, err := gc.prepareFileLookup(ctx, file)
+	if err != nil {
+		return "", "", err
+	}
+
+	commit, err := gc.ResolveRevision(ctx, ref)
+	if err != nil {
+		return "", "", err
+	}
+	content, err := gc.git(ctx, "show", commit+":"+repoPath)
+	if err != nil {
+		return "", commit, fmt.Errorf("%s does not exist at %s", repoPath, ref)
+	}
+	return content, commit, nil
+}
+
+// prepareFileLookup checks the local clone and turns a file argument into a path inside the repository
+func (gc *GitHubClient) prepareFileLookup(ctx context.Context, file string) (string, error) {
+	if !gc.isValidGitRepository() {
+		return "", errors.NewConfigurationError(constants.OpShow, "file-history",
+			fmt.Errorf("no local clone at %s, run 'anvil config pull' first", gc.LocalPath))
+	}
+
+	repoPath := path.Clean(strings.TrimPrefix(strings.ReplaceAll(file, "\\", "/"), "./"))
+	if repoPath == "." || path.IsAbs(repoPath) || repoPath == ".." || strings.HasPrefix(repoPath, "../") {
+		return "", errors.NewValidationError(constants.OpShow, file,
+			fmt.Errorf("'%s' is not a path inside the config repository", file))
+	}
+
+	// History older than a shallow clone's depth would silently be missing
+	if err := gc.ensureFullHistory(ctx); err != nil {
+		return "", err
+	}
+	return repoPath, nil
+}
+
+// historyRef returns the remote tracking branch when it exists, so history does not depend on
+// which push branch the local clone has checked out
+func (gc *GitHubClient) historyRef(ctx context.Context) string {
+	if _, err := gc.git(ctx, "rev-parse", "--verify", "--quiet", "origin/"+gc.Branch); err == nil {
+		return "origin/" + gc.Branch
+	}
+	return "HEAD"
+}
+
+// parseFileLog parses git log output written with fileLogFormat
+func parseFileLog(out string) []FileCommit {
+	var commits []FileCommit
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(strings.TrimSpace(line), "\x1f", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		date, _ := time.Parse(time.RFC3339, fields[1])
+		commits = append(commits, FileCommit{Commit: fields[0], Date: date, Author: fields[2], Subject: fields[3]})
+	}
+	return commits
+}
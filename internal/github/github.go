@@ -19,6 +19,7 @@ package github
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -29,6 +30,8 @@ import (
 	"github.com/0xjuanma/anvil/internal/errors"
 	"github.com/0xjuanma/anvil/internal/system"
 	"github.com/0xjuanma/anvil/internal/utils"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 )
 
 // GitHubClient handles GitHub operations for config management
@@ -40,6 +43,29 @@ type GitHubClient struct {
 	SSHKeyPath string
 	Username   string
 	Email      string
+	CloneDepth int // 0 uses the default shallow depth, negative clones full history
+
+	MaterializeSymlinks bool // Copy symlink targets instead of preserving the links
+
+	Include []string // Globs relative to a pushed config directory, when set only matching files are pushed
+	Exclude []string // Globs relative to a pushed config directory that are never pushed
+
+	BranchTimestampFormat string // Preset name or Go time layout for push branch names
+	BranchIncludeHost     bool   // Append the hostname to push branch names
+	AppBranches           bool   // Push to a long-lived configs/<app> branch updated with new commits
+
+	MergeBase string // Commit a push starts from instead of the branch head, the branch is merged in after committing
+
+	MaxPushSizeMB int // 0 uses the default limit, negative disables the check
+	MaxPushFiles  int // 0 uses the default limit, negative disables the check
+
+	GenerateReadme bool // Regenerate the README index in the repository root on each push
+
+	AppVersion *AppCompatibility // Version requirement recorded in the index for the pushed app, nil keeps the recorded one
+
+	APICacheDir string // Response cache of GitHub API calls, empty disables it
+
+	Progress func(line string) // Receives git progress lines during clone and fetch, nil discards them
 }
 
 // NewGitHubClient creates a new GitHub client
@@ -72,19 +98,32 @@ func (gc *GitHubClient) CloneRepository(ctx context.Context) error {
 		return errors.NewFileSystemError(constants.OpPull, "mkdir-parent", err)
 	}
 
-	// Determine clone URL format (HTTPS with token or SSH)
-	cloneURL := gc.getCloneURL()
-
-	// Clone the repository
-	args := []string{"clone", "--branch", gc.Branch, cloneURL, gc.LocalPath}
-	result, err := system.RunCommandWithTimeout(ctx, constants.GitCommand, args...)
+	auth, err := gc.remoteAuth()
+	if err != nil {
+		return errors.NewConfigurationError(constants.OpPull, "git-auth", err)
+	}
+
+	// Clone the repository (shallow by default, pull operations don't need history). The
+	// URL keeps the token, as the history commands run by the git binary fetch through it.
+	depth := gc.getCloneDepth()
+	useNetworkTransports()
+	_, err = git.PlainCloneContext(ctx, gc.LocalPath, false, &git.CloneOptions{
+		URL:           gc.getCloneURL(),
+		Auth:          auth,
+		RemoteName:    remoteName,
+		ReferenceName: plumbing.NewBranchReferenceName(gc.Branch),
+		SingleBranch:  depth > 0,
+		Depth:         depth,
+		Progress:      gc.progressWriter(),
+	})
 	if err != nil {
+		os.RemoveAll(gc.LocalPath)
 		// Enhanced error message for branch issues
-		if strings.Contains(result.Error, "Remote branch") || strings.Contains(result.Error, "not found") {
-			return gc.createBranchNotFoundError("clone", result.Error)
+		if isMissingRemoteRef(err) {
+			return gc.createBranchNotFoundError("clone", err.Error())
 		}
 		return errors.NewInstallationError(constants.OpPull, "git-clone",
-			fmt.Errorf("failed to clone repository: %s, error: %w", result.Error, err))
+			fmt.Errorf("failed to clone repository: %w", err))
 	}
 
 	// Verify the repository was cloned successfully
@@ -96,7 +135,40 @@ func (gc *GitHubClient) CloneRepository(ctx context.Context) error {
 	return nil
 }
 
-// PullChanges pulls the latest changes from the remote repository
+// getCloneDepth returns the effective clone depth, 0 meaning full history
+func (gc *GitHubClient) getCloneDepth() int {
+	if gc.CloneDepth == 0 {
+		return constants.DefaultCloneDepth
+	}
+	if gc.CloneDepth < 0 {
+		return 0
+	}
+	return gc.CloneDepth
+}
+
+// IsShallowRepository reports whether the local clone has truncated history
+func (gc *GitHubClient) IsShallowRepository() bool {
+	_, err := os.Stat(filepath.Join(gc.LocalPath, ".git", "shallow"))
+	return err == nil
+}
+
+// ensureFullHistory converts a shallow clone into a full clone when history is required
+func (gc *GitHubClient) ensureFullHistory(ctx context.Context) error {
+	if !gc.IsShallowRepository() {
+		return nil
+	}
+
+	result, err := system.RunCommandInDirectoryWithTimeout(ctx, gc.LocalPath, constants.GitCommand, "fetch", "--unshallow", "origin", gc.Branch)
+	if err != nil || !result.Success {
+		return errors.NewInstallationError(constants.OpPush, "git-unshallow",
+			fmt.Errorf("failed to fetch full history: %s", result.Output))
+	}
+
+	return nil
+}
+
+// PullChanges pulls the latest changes from the remote repository. Only fast-forwards are
+// applied, a local branch that diverged from the remote is reported instead of merged.
 func (gc *GitHubClient) PullChanges(ctx context.Context) error {
 	// Verify the repository exists and is valid
 	if !gc.isValidGitRepository() {
@@ -104,88 +176,89 @@ func (gc *GitHubClient) PullChanges(ctx context.Context) error {
 			fmt.Errorf("local repository at %s is not valid or doesn't exist", gc.LocalPath))
 	}
 
-	// Ensure we're in the correct directory
-	originalDir, err := os.Getwd()
-	if err != nil {
-		return errors.NewFileSystemError(constants.OpPull, "getwd", err)
-	}
-	defer os.Chdir(originalDir)
-
-	if err := os.Chdir(gc.LocalPath); err != nil {
-		return errors.NewFileSystemError(constants.OpPull, "chdir",
-			fmt.Errorf("cannot change to repository directory %s: %w", gc.LocalPath, err))
-	}
-
 	// Configure git user if provided
 	if err := gc.configureGitUser(ctx); err != nil {
 		return err
 	}
 
-	// Fetch latest changes
-	fetchResult, err := system.RunCommandWithTimeout(ctx, constants.GitCommand, "fetch", "origin", gc.Branch)
+	repo, err := gc.openRepository()
 	if err != nil {
-		// Enhanced error message for branch issues during fetch
-		if strings.Contains(fetchResult.Error, "couldn't find remote ref") || strings.Contains(fetchResult.Error, "not found") {
-			return gc.createBranchNotFoundError("fetch", fetchResult.Error)
-		}
-		return errors.NewInstallationError(constants.OpPull, "git-fetch",
-			fmt.Errorf("failed to fetch changes: %s, error: %w", fetchResult.Error, err))
+		return errors.NewFileSystemError(constants.OpPull, "open-repo", err)
 	}
-
-	// Pull changes
-	result, err := system.RunCommandWithTimeout(ctx, constants.GitCommand, "pull", "origin", gc.Branch)
+	worktree, err := repo.Worktree()
 	if err != nil {
+		return errors.NewFileSystemError(constants.OpPull, "worktree", err)
+	}
+	auth, err := gc.remoteAuth()
+	if err != nil {
+		return errors.NewConfigurationError(constants.OpPull, "git-auth", err)
+	}
+
+	// Fetch and fast-forward, keeping a shallow clone at its depth
+	options := &git.PullOptions{
+		RemoteName:    remoteName,
+		ReferenceName: plumbing.NewBranchReferenceName(gc.Branch),
+		SingleBranch:  true,
+		Auth:          auth,
+		Progress:      gc.progressWriter(),
+	}
+	if gc.IsShallowRepository() {
+		options.Depth = gc.getCloneDepth()
+	}
+	err = worktree.PullContext(ctx, options)
+	switch {
+	case err == nil, stderrors.Is(err, git.NoErrAlreadyUpToDate):
+		return nil
+	case isMissingRemoteRef(err):
 		// Enhanced error message for branch issues during pull
-		if strings.Contains(result.Error, "couldn't find remote ref") || strings.Contains(result.Error, "not found") {
-			return gc.createBranchNotFoundError("pull", result.Error)
-		}
+		return gc.createBranchNotFoundError("pull", err.Error())
+	case stderrors.Is(err, git.ErrNonFastForwardUpdate):
 		return errors.NewInstallationError(constants.OpPull, "git-pull",
-			fmt.Errorf("failed to pull changes: %s, error: %w", result.Error, err))
+			fmt.Errorf("local branch %s has commits that are not on origin and can't be fast-forwarded: %w", gc.Branch, err))
 	}
-
-	return nil
+	return errors.NewInstallationError(constants.OpPull, "git-pull",
+		fmt.Errorf("failed to pull changes: %w", err))
 }
 
 // PushChanges commits and pushes local changes to the remote repository
 func (gc *GitHubClient) PushChanges(ctx context.Context, commitMessage string) error {
-	// Ensure we're in the correct directory
-	originalDir, err := os.Getwd()
-	if err != nil {
-		return errors.NewFileSystemError(constants.OpPush, "getwd", err)
-	}
-	defer os.Chdir(originalDir)
-
-	if err := os.Chdir(gc.LocalPath); err != nil {
-		return errors.NewFileSystemError(constants.OpPush, "chdir", err)
-	}
-
 	// Configure git user if provided
 	if err := gc.configureGitUser(ctx); err != nil {
 		return err
 	}
 
+	repo, err := gc.openRepository()
+	if err != nil {
+		return errors.NewFileSystemError(constants.OpPush, "open-repo", err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return errors.NewFileSystemError(constants.OpPush, "worktree", err)
+	}
+
 	// Add all changes
-	if _, err := system.RunCommandWithTimeout(ctx, constants.GitCommand, "add", "."); err != nil {
+	if err := stageAll(worktree); err != nil {
 		return errors.NewInstallationError(constants.OpPush, "git-add", err)
 	}
 
 	// Check if there are changes to commit
-	result, err := system.RunCommandWithTimeout(ctx, constants.GitCommand, "diff", "--cached", "--exit-code")
-	if err == nil {
-		// No changes to commit
+	staged, err := stagedPaths(worktree)
+	if err != nil {
+		return errors.NewInstallationError(constants.OpPush, "git-status", err)
+	}
+	if len(staged) == 0 {
 		return nil
 	}
 
 	// Commit changes
-	if _, err := system.RunCommandWithTimeout(ctx, constants.GitCommand, "commit", "-m", commitMessage); err != nil {
+	if err := gc.commitChanges(ctx, commitMessage, nil); err != nil {
 		return errors.NewInstallationError(constants.OpPush, "git-commit", err)
 	}
 
 	// Push changes
-	result, err = system.RunCommandWithTimeout(ctx, constants.GitCommand, "push", "origin", gc.Branch)
-	if err != nil {
+	if err := gc.pushRef(ctx, gc.Branch); err != nil {
 		return errors.NewInstallationError(constants.OpPush, "git-push",
-			fmt.Errorf("failed to push changes: %s, error: %w", result.Error, err))
+			fmt.Errorf("failed to push changes: %w", err))
 	}
 
 	return nil
@@ -257,18 +330,28 @@ func (gc *GitHubClient) getCloneURL() string {
 	return gc.RepoURL
 }
 
-// configureGitUser configures git user for the repository
+// configureGitUser records the configured git user in the clone, commits are authored by it
 func (gc *GitHubClient) configureGitUser(ctx context.Context) error {
-	if gc.Username != "" {
-		if _, err := system.RunCommandWithTimeout(ctx, constants.GitCommand, "config", "user.name", gc.Username); err != nil {
-			return errors.NewConfigurationError(constants.OpConfig, "git-config-user", err)
-		}
+	if gc.Username == "" && gc.Email == "" {
+		return nil
 	}
 
+	repo, err := gc.openRepository()
+	if err != nil {
+		return errors.NewConfigurationError(constants.OpConfig, "git-config-user", err)
+	}
+	cfg, err := repo.Config()
+	if err != nil {
+		return errors.NewConfigurationError(constants.OpConfig, "git-config-user", err)
+	}
+	if gc.Username != "" {
+		cfg.User.Name = gc.Username
+	}
 	if gc.Email != "" {
-		if _, err := system.RunCommandWithTimeout(ctx, constants.GitCommand, "config", "user.email", gc.Email); err != nil {
-			return errors.NewConfigurationError(constants.OpConfig, "git-config-email", err)
-		}
+		cfg.User.Email = gc.Email
+	}
+	if err := repo.SetConfig(cfg); err != nil {
+		return errors.NewConfigurationError(constants.OpConfig, "git-config-email", err)
 	}
 
 	return nil
@@ -276,50 +359,41 @@ func (gc *GitHubClient) configureGitUser(ctx context.Context) error {
 
 // GetRepositoryStatus returns the current status of the local repository
 func (gc *GitHubClient) GetRepositoryStatus(ctx context.Context) (string, error) {
-	originalDir, err := os.Getwd()
+	out, err := gc.git(ctx, "status", "--porcelain")
 	if err != nil {
-		return "", errors.NewFileSystemError(constants.OpConfig, "getwd", err)
+		return "", errors.NewInstallationError(constants.OpConfig, "git-status", err)
 	}
-	defer os.Chdir(originalDir)
 
-	if err := os.Chdir(gc.LocalPath); err != nil {
-		return "", errors.NewFileSystemError(constants.OpConfig, "chdir", err)
-	}
+	return out, nil
+}
 
-	result, err := system.RunCommandWithTimeout(ctx, constants.GitCommand, "status", "--porcelain")
+// HeadCommit returns the commit checked out in the local repository
+func (gc *GitHubClient) HeadCommit(ctx context.Context) (string, error) {
+	repo, err := gc.openRepository()
 	if err != nil {
-		return "", errors.NewInstallationError(constants.OpConfig, "git-status", err)
+		return "", err
 	}
-
-	return result.Output, nil
+	head, err := repo.Head()
+	if err != nil {
+		return "", err
+	}
+	return head.Hash().String(), nil
 }
 
 // isValidGitRepository checks if the local path contains a valid git repository
 func (gc *GitHubClient) isValidGitRepository() bool {
-	// Check if directory exists
-	if _, err := os.Stat(gc.LocalPath); os.IsNotExist(err) {
-		return false
-	}
-
 	// Check if .git directory exists
 	gitDir := filepath.Join(gc.LocalPath, ".git")
 	if _, err := os.Stat(gitDir); os.IsNotExist(err) {
 		return false
 	}
 
-	// Try to run a simple git command to verify it's a valid repo
-	originalDir, err := os.Getwd()
+	// Open it and read the index to verify it's a valid repository
+	repo, err := gc.openRepository()
 	if err != nil {
 		return false
 	}
-	defer os.Chdir(originalDir)
-
-	if err := os.Chdir(gc.LocalPath); err != nil {
-		return false
-	}
-
-	// Run git status to verify it's a valid repository
-	_, err = system.RunCommand(constants.GitCommand, "status", "--porcelain")
+	_, err = repo.Storer.Index()
 	return err == nil
 }
 
@@ -0,0 +1,265 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+)
+
+const (
+	HookPreCommit = "pre-commit"
+	HookPrePush   = "pre-push"
+
+	// hookMarker identifies hooks written by anvil, other hooks are never overwritten silently
+	hookMarker = "# Managed by anvil"
+
+	// emptyTree is the git object of an empty tree, the base of a branch pushed for the first time
+	emptyTree = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+	zeroSHA   = "0000000000000000000000000000000000000000"
+)
+
+// HookNames lists the hooks installed into the config repository
+var HookNames = []string{HookPreCommit, HookPrePush}
+
+// InstalledHook is a hook written into the local clone
+type InstalledHook struct {
+	Name     string
+	Path     string
+	BackedUp string // Previous hook kept aside when --force replaced one not written by anvil
+}
+
+// hookScript runs the checks through anvil. When anvil is missing the hook warns and lets git
+// proceed, so a clone on a machine without anvil stays usable.
+func hookScript(name, executable string) string {
+	return fmt.Sprintf(`#!/bin/sh
+%s, written by 'anvil config install-hooks'. Re-run it to update.
+# Runs the secret, size and README checks of 'anvil config push' for manual git usage.
+ANVIL=%q
+[ -x "$ANVIL" ] || ANVIL="$(command -v anvil)"
+if [ -z "$ANVIL" ]; then
+	echo "anvil not found, skipping %s checks" >&2
+	exit 0
+fi
+exec "$ANVIL" config hook %s "$@"
+`, hookMarker, executable, name, name)
+}
+
+// hooksDirectory returns the hooks directory of the clone, honoring core.hooksPath
+func (gc *GitHubClient) hooksDirectory(ctx context.Context) (string, error) {
+	out, err := gc.git(ctx, "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+	dir := strings.TrimSpace(out)
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(gc.LocalPath, dir)
+	}
+	return dir, nil
+}
+
+// PlanHooks lists the hooks InstallHooks would write without changing the clone. Hooks not
+// written by anvil get a BackedUp path when force is set, otherwise they are refused.
+func (gc *GitHubClient) PlanHooks(ctx context.Context, force bool) ([]InstalledHook, error) {
+	if !gc.isValidGitRepository() {
+		return nil, errors.NewConfigurationError(constants.OpConfig, "install-hooks",
+			fmt.Errorf("no local clone at %s, run 'anvil config pull' first", gc.LocalPath))
+	}
+
+	dir, err := gc.hooksDirectory(ctx)
+	if err != nil {
+		return nil, errors.NewFileSystemError(constants.OpConfig, "hooks-dir", err)
+	}
+
+	hooks := make([]InstalledHook, 0, len(HookNames))
+	for _, name := range HookNames {
+		hook := InstalledHook{Name: name, Path: filepath.Join(dir, name)}
+		if existing, err := os.ReadFile(hook.Path); err == nil && !strings.Contains(string(existing), hookMarker) {
+			if !force {
+				return nil, errors.NewValidationError(constants.OpConfig, "install-hooks",
+					fmt.Errorf("%s already exists and was not written by anvil, use --force to replace it (it is kept as %s.anvil-backup)", hook.Path, name))
+			}
+			hook.BackedUp = hook.Path + ".anvil-backup"
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, nil
+}
+
+// InstallHooks writes the anvil hooks into the local clone. Hooks written by anvil are updated,
+// other existing hooks are left alone unless force is set, in which case they are kept as
+// <name>.anvil-backup.
+func (gc *GitHubClient) InstallHooks(ctx context.Context, executable string, force bool) ([]InstalledHook, error) {
+	// Every hook is checked before any is written, so a refusal leaves the clone unchanged
+	hooks, err := gc.PlanHooks(ctx, force)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(hooks[0].Path), 0755); err != nil {
+		return nil, errors.NewFileSystemError(constants.OpConfig, "hooks-dir", err)
+	}
+
+	var installed []InstalledHook
+	for _, hook := range hooks {
+		if hook.BackedUp != "" {
+			if err := os.Rename(hook.Path, hook.BackedUp); err != nil {
+				return installed, errors.NewFileSystemError(constants.OpConfig, "backup-hook", err)
+			}
+		}
+		if err := os.WriteFile(hook.Path, []byte(hookScript(hook.Name, executable)), 0755); err != nil {
+			return installed, errors.NewFileSystemError(constants.OpConfig, "write-hook", err)
+		}
+		installed = append(installed, hook)
+	}
+	return installed, nil
+}
+
+// RunPreCommitHook checks the staged files for secrets and the touched apps against the push
+// size limits, then refreshes the README index when it is enabled and stages it
+func (gc *GitHubClient) RunPreCommitHook(ctx context.Context) error {
+	out, err := gc.git(ctx, "diff", "--cached", "--name-only", "--diff-filter=ACMR", "-z")
+	if err != nil {
+		return errors.NewFileSystemError(constants.OpPush, "staged-files", err)
+	}
+	files := splitNull(out)
+	if len(files) == 0 {
+		return nil
+	}
+
+	if err := gc.scanRevisionFiles(ctx, "", files); err != nil {
+		return err
+	}
+	apps := touchedApps(files)
+	if err := gc.checkAppSizes(apps); err != nil {
+		return err
+	}
+
+	// 'anvil config push' refreshes the index itself, only manual commits need it here
+	if !gc.GenerateReadme || len(apps) == 0 || gc.LoadPushState() != nil {
+		return nil
+	}
+	now := time.Now()
+	for _, app := range apps {
+		if err := gc.updateRepoReadme(app, now); err != nil {
+			return errors.NewFileSystemError(constants.OpPush, "update-readme", err)
+		}
+	}
+	if _, err := gc.git(ctx, "add", "--", repoReadmeFile, repoIndexFile); err != nil {
+		return errors.NewFileSystemError(constants.OpPush, "stage-readme", err)
+	}
+	return nil
+}
+
+// RunPrePushHook checks the files changed by the pushed commits, read from the refs git passes
+// on stdin, so commits made with --no-verify are still caught before they leave the machine
+func (gc *GitHubClient) RunPrePushHook(ctx context.Context, refs io.Reader) error {
+	changed := make(map[string][]string)
+	var order []string
+	scanner := bufio.NewScanner(refs)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 4 || fields[1] == zeroSHA {
+			continue // Malformed, or a deleted branch
+		}
+		localSHA, remoteSHA := fields[1], fields[3]
+		base := remoteSHA
+		if base == zeroSHA {
+			base = emptyTree
+		}
+		out, err := gc.git(ctx, "diff", "--name-only", "--diff-filter=ACMR", "-z", base, localSHA)
+		if err != nil {
+			return errors.NewFileSystemError(constants.OpPush, "pushed-files", err)
+		}
+		if _, seen := changed[localSHA]; !seen {
+			order = append(order, localSHA)
+		}
+		changed[localSHA] = append(changed[localSHA], splitNull(out)...)
+	}
+
+	var all []string
+	for _, sha := range order {
+		if err := gc.scanRevisionFiles(ctx, sha, changed[sha]); err != nil {
+			return err
+		}
+		all = append(all, changed[sha]...)
+	}
+	return gc.checkAppSizes(touchedApps(all))
+}
+
+// scanRevisionFiles scans files as stored at a commit, or in the index when revision is empty
+func (gc *GitHubClient) scanRevisionFiles(ctx context.Context, revision string, files []string) error {
+	var findings []SecretFinding
+	for _, file := range files {
+		content, err := gc.git(ctx, "show", revision+":"+file)
+		if err != nil {
+			continue // Submodules and similar entries have no blob to scan
+		}
+		findings = append(findings, ScanSecrets(file, []byte(content))...)
+	}
+	return reportSecrets(findings)
+}
+
+// checkAppSizes applies the push size limits to each app directory, as 'anvil config push' does
+func (gc *GitHubClient) checkAppSizes(apps []string) error {
+	for _, app := range apps {
+		path := filepath.Join(gc.LocalPath, app)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		if err := gc.CheckPushSize(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// touchedApps returns the app directories, the visible top-level directories, of repository paths
+func touchedApps(files []string) []string {
+	seen := make(map[string]bool)
+	var apps []string
+	for _, file := range files {
+		app, rest, found := strings.Cut(file, "/")
+		if !found || rest == "" || strings.HasPrefix(app, ".") || seen[app] {
+			continue
+		}
+		seen[app] = true
+		apps = append(apps, app)
+	}
+	sort.Strings(apps)
+	return apps
+}
+
+// splitNull splits the NUL separated output of git -z
+func splitNull(out string) []string {
+	var parts []string
+	for _, part := range strings.Split(out, "\x00") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
@@ -0,0 +1,186 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/utils"
+	"github.com/0xjuanma/palantir"
+)
+
+// pushSizeWarnRatio is the fraction of a limit at which a warning is shown
+const pushSizeWarnRatio = 0.8
+
+// maxOffendingPaths is the number of largest entries listed when a limit is hit
+const maxOffendingPaths = 5
+
+// PathUsage holds the size and file count of one entry inside a config path
+type PathUsage struct {
+	Path      string
+	Size      int64
+	FileCount int
+}
+
+// PushSizeReport summarizes how much data a push would commit
+type PushSizeReport struct {
+	TotalSize int64
+	FileCount int
+	Entries   []PathUsage // Top-level entries sorted by size, largest first
+}
+
+// MeasurePushSize walks a config path and totals its size and file count per top-level entry
+func MeasurePushSize(configPath string) (*PushSizeReport, error) {
+	return measurePushSize(configPath, nil)
+}
+
+// measurePushSize is MeasurePushSize leaving out the files skip reports by relative path
+func measurePushSize(configPath string, skip func(relPath string) bool) (*PushSizeReport, error) {
+	info, err := os.Stat(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &PushSizeReport{}
+	if !info.IsDir() {
+		report.TotalSize = info.Size()
+		report.FileCount = 1
+		report.Entries = []PathUsage{{Path: configPath, Size: info.Size(), FileCount: 1}}
+		return report, nil
+	}
+
+	usage := make(map[string]*PathUsage)
+	err = filepath.WalkDir(configPath, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil || d.IsDir() {
+			return nil
+		}
+
+		fileInfo, err := d.Info()
+		if err != nil {
+			return nil
+		}
+
+		rel, _ := filepath.Rel(configPath, path)
+		if skip != nil && skip(rel) {
+			return nil
+		}
+		top := filepath.Join(configPath, strings.SplitN(rel, string(filepath.Separator), 2)[0])
+		entry, ok := usage[top]
+		if !ok {
+			entry = &PathUsage{Path: top}
+			usage[top] = entry
+		}
+		entry.Size += fileInfo.Size()
+		entry.FileCount++
+
+		report.TotalSize += fileInfo.Size()
+		report.FileCount++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range usage {
+		report.Entries = append(report.Entries, *entry)
+	}
+	sort.Slice(report.Entries, func(i, j int) bool {
+		return report.Entries[i].Size > report.Entries[j].Size
+	})
+
+	return report, nil
+}
+
+// getPushLimits returns the effective size and file limits, 0 meaning unlimited
+func (gc *GitHubClient) getPushLimits() (int64, int) {
+	sizeMB := gc.MaxPushSizeMB
+	if sizeMB == 0 {
+		sizeMB = constants.DefaultMaxPushSizeMB
+	}
+	files := gc.MaxPushFiles
+	if files == 0 {
+		files = constants.DefaultMaxPushFiles
+	}
+
+	var maxSize int64
+	if sizeMB > 0 {
+		maxSize = int64(sizeMB) * 1024 * 1024
+	}
+	if files < 0 {
+		files = 0
+	}
+	return maxSize, files
+}
+
+// CheckPushSize aborts pushes over the configured limits and warns when close to them
+func (gc *GitHubClient) CheckPushSize(configPath string) error {
+	maxSize, maxFiles := gc.getPushLimits()
+	if maxSize == 0 && maxFiles == 0 {
+		return nil
+	}
+
+	report, err := measurePushSize(configPath, gc.skipsFile)
+	if err != nil {
+		return errors.NewFileSystemError(constants.OpPush, "measure-size", err)
+	}
+
+	overSize := maxSize > 0 && report.TotalSize > maxSize
+	overFiles := maxFiles > 0 && report.FileCount > maxFiles
+	output := palantir.GetGlobalOutputHandler()
+
+	if !overSize && !overFiles {
+		nearSize := maxSize > 0 && float64(report.TotalSize) > float64(maxSize)*pushSizeWarnRatio
+		nearFiles := maxFiles > 0 && float64(report.FileCount) > float64(maxFiles)*pushSizeWarnRatio
+		if nearSize || nearFiles {
+			output.PrintWarning("Push is close to the configured limits: %s in %d files", utils.FormatSize(report.TotalSize), report.FileCount)
+		}
+		return nil
+	}
+
+	output.PrintError("Push is too large: %s in %d files (limits: %s)",
+		utils.FormatSize(report.TotalSize), report.FileCount, formatLimits(maxSize, maxFiles))
+	output.PrintInfo("Largest paths:")
+	for i, entry := range report.Entries {
+		if i == maxOffendingPaths {
+			break
+		}
+		output.PrintInfo("  • %s (%s, %d files)", entry.Path, utils.FormatSize(entry.Size), entry.FileCount)
+	}
+	output.PrintInfo("💡 Point the configs entry at a narrower path, or raise 'github.max_push_size_mb' / 'github.max_push_files' in %s", constants.ANVIL_CONFIG_FILE)
+
+	return errors.NewValidationError(constants.OpPush, "size-preflight",
+		fmt.Errorf("%s in %d files exceeds push limits", utils.FormatSize(report.TotalSize), report.FileCount))
+}
+
+// formatLimits renders the active limits, 0 meaning unlimited
+func formatLimits(maxSize int64, maxFiles int) string {
+	size, files := "unlimited size", "unlimited files"
+	if maxSize > 0 {
+		size = utils.FormatSize(maxSize)
+	}
+	if maxFiles > 0 {
+		files = fmt.Sprintf("%d files", maxFiles)
+	}
+	return fmt.Sprintf("%s, %s", size, files)
+}
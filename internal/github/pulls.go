@@ -0,0 +1,165 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Merge methods auto-merge can use, the repository must allow the chosen one
+const (
+	MergeMethodMerge  = "merge"
+	MergeMethodSquash = "squash"
+	MergeMethodRebase = "rebase"
+)
+
+// MergeMethods lists the accepted merge methods
+var MergeMethods = []string{MergeMethodMerge, MergeMethodSquash, MergeMethodRebase}
+
+// PullRequest is a pull request on the config repository
+type PullRequest struct {
+	Number   int        `json:"number"`
+	URL      string     `json:"html_url"`
+	NodeID   string     `json:"node_id"`
+	MergedAt *time.Time `json:"merged_at"`
+	Existing bool       `json:"-"` // Already open for the branch, nothing was created
+}
+
+// CreatePullRequest opens a pull request from head into base in repo (owner/name). When one
+// is already open for head, such as after a resumed push, that pull request is returned.
+func (c *APIClient) CreatePullRequest(ctx context.Context, repo, head, base, title, body string) (*PullRequest, error) {
+	payload := map[string]any{"title": title, "head": head, "base": base, "body": body}
+	var pr PullRequest
+	_, err := c.Post(ctx, "/repos/"+repo+"/pulls", payload, &pr)
+	if err == nil {
+		return &pr, nil
+	}
+
+	apiErr, ok := err.(*APIError)
+	if !ok || apiErr.Status != http.StatusUnprocessableEntity || !strings.Contains(apiErr.Message, "already exists") {
+		return nil, err
+	}
+	owner, _, _ := strings.Cut(repo, "/")
+	var open []PullRequest
+	query := url.Values{"head": {owner + ":" + head}, "base": {base}, "state": {"open"}}
+	if _, listErr := c.Get(ctx, "/repos/"+repo+"/pulls?"+query.Encode(), &open); listErr != nil || len(open) == 0 {
+		return nil, err
+	}
+	open[0].Existing = true
+	return &open[0], nil
+}
+
+// MergedPullRequest returns the merged pull request from head into base in repo (owner/name),
+// or nil when head has none
+func (c *APIClient) MergedPullRequest(ctx context.Context, repo, head, base string) (*PullRequest, error) {
+	owner, _, _ := strings.Cut(repo, "/")
+	var closed []PullRequest
+	query := url.Values{"head": {owner + ":" + head}, "base": {base}, "state": {"closed"}}
+	if _, err := c.Get(ctx, "/repos/"+repo+"/pulls?"+query.Encode(), &closed); err != nil {
+		return nil, err
+	}
+	for i := range closed {
+		if closed[i].MergedAt != nil {
+			return &closed[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// EnableAutoMerge turns on auto-merge, GitHub merges the pull request once its required checks
+// and reviews pass. It fails when the repository does not allow auto-merge.
+func (c *APIClient) EnableAutoMerge(ctx context.Context, pr *PullRequest, method string) error {
+	payload := map[string]any{
+		"query": `mutation($id: ID!, $method: PullRequestMergeMethod!) {
+  enablePullRequestAutoMerge(input: {pullRequestId: $id, mergeMethod: $method}) { clientMutationId }
+}`,
+		"variables": map[string]string{"id": pr.NodeID, "method": strings.ToUpper(method)},
+	}
+	var result struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if _, err := c.Post(ctx, "/graphql", payload, &result); err != nil {
+		return err
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("failed to enable auto-merge: %s", result.Errors[0].Message)
+	}
+	return nil
+}
+
+// OpenPullRequest opens a pull request for a pushed branch, titled with its commit message,
+// and enables auto-merge when autoMerge is set
+func (gc *GitHubClient) OpenPullRequest(ctx context.Context, result *PushConfigResult, autoMerge bool, mergeMethod string) (*PullRequest, error) {
+	repo, err := gc.repoSlug()
+	if err != nil {
+		return nil, err
+	}
+
+	client := NewAPIClient(gc.Token, "")
+	pr, err := client.CreatePullRequest(ctx, repo, result.BranchName, gc.Branch, result.CommitMessage, pullRequestBody(result))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pull request: %w", err)
+	}
+	if autoMerge {
+		if err := client.EnableAutoMerge(ctx, pr, mergeMethod); err != nil {
+			return pr, err
+		}
+	}
+	return pr, nil
+}
+
+// pullRequestBody describes a push: where it came from and the files it committed
+func pullRequestBody(result *PushConfigResult) string {
+	var body strings.Builder
+	host, _ := os.Hostname()
+	if host == "" {
+		body.WriteString("Configuration pushed by anvil.\n")
+	} else {
+		body.WriteString(fmt.Sprintf("Configuration pushed by anvil from `%s`.\n", host))
+	}
+	if len(result.FilesCommitted) > 0 {
+		body.WriteString("\nFiles:\n")
+		for _, file := range result.FilesCommitted {
+			body.WriteString(fmt.Sprintf("- `%s`\n", file))
+		}
+	}
+	return body.String()
+}
+
+// repoSlug returns the owner/name of the config repository, which the pull request API needs
+func (gc *GitHubClient) repoSlug() (string, error) {
+	slug := strings.TrimPrefix(strings.TrimSuffix(gc.RepoURL, ".git"), "git@github.com:")
+	if strings.Contains(slug, "://") {
+		parsed, err := url.Parse(slug)
+		if err != nil || parsed.Host != "github.com" {
+			return "", fmt.Errorf("pull requests are only available for GitHub repositories, not %s", gc.RepoURL)
+		}
+		slug = strings.Trim(parsed.Path, "/")
+	}
+	if strings.Count(slug, "/") != 1 {
+		return "", fmt.Errorf("'github.config_repo' must be owner/name to use pull requests, got %s", gc.RepoURL)
+	}
+	return slug, nil
+}
@@ -20,16 +20,20 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/0xjuanma/anvil/internal/constants"
 	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/events"
 	"github.com/0xjuanma/anvil/internal/system"
+	"github.com/0xjuanma/anvil/internal/timeline"
 	"github.com/0xjuanma/anvil/internal/utils"
 	"github.com/0xjuanma/palantir"
 )
@@ -40,6 +44,7 @@ type PushConfigResult struct {
 	CommitMessage  string
 	RepositoryURL  string
 	FilesCommitted []string
+	AppTree        string // Tree of the app directory as pushed
 }
 
 // verifyRepositoryPrivacy ensures the repository is private before allowing push operations
@@ -56,10 +61,7 @@ func (gc *GitHubClient) verifyRepositoryPrivacy(ctx context.Context) error {
 	}
 
 	// Test if repository is publicly accessible (this should FAIL for private repos)
-	repoURL := fmt.Sprintf("https://github.com/%s", gc.RepoURL)
-	httpResult, httpErr := system.RunCommandWithTimeout(ctx, "curl", "-s", "-f", "-I", repoURL)
-
-	if httpErr == nil && httpResult.Success {
+	if !gc.repositoryIsPrivate(ctx) {
 		// 🚨 CRITICAL: Repository is public - BLOCK the push
 		output := palantir.GetGlobalOutputHandler()
 		output.PrintError("🚨 SECURITY VIOLATION: Configuration push BLOCKED")
@@ -83,7 +85,29 @@ func (gc *GitHubClient) verifyRepositoryPrivacy(ctx context.Context) error {
 	return nil
 }
 
-// PushConfig pushes configuration files to the repository (unified function for both anvil and app configs)
+// repositoryIsPrivate asks the GitHub API for the repository visibility. A repository the API
+// does not show, while git can reach it, is private. When the API is rate limited or
+// unreachable, the public repository page is checked instead, a stale answer is never trusted.
+func (gc *GitHubClient) repositoryIsPrivate(ctx context.Context) bool {
+	var repo struct {
+		Private bool `json:"private"`
+	}
+	_, err := NewAPIClient(gc.Token, gc.APICacheDir).Get(ctx, "/repos/"+gc.RepoURL, &repo)
+	if err == nil {
+		return repo.Private
+	}
+	if apiErr, ok := err.(*APIError); ok && apiErr.Status == http.StatusNotFound {
+		return true
+	}
+
+	palantir.GetGlobalOutputHandler().PrintWarning("%v, checking the repository page instead", err)
+	repoURL := fmt.Sprintf("https://github.com/%s", gc.RepoURL)
+	httpResult, httpErr := system.RunCommandWithTimeout(ctx, "curl", "-s", "-f", "-I", repoURL)
+	return httpErr != nil || !httpResult.Success
+}
+
+// PushConfig pushes configuration files to the repository (unified function for both anvil and app configs).
+// Callers run CheckPushSize and CheckSecrets first, before the diff preview copies anything into the clone.
 func (gc *GitHubClient) PushConfig(ctx context.Context, appName, configPath string) (*PushConfigResult, error) {
 	// 🚨 CRITICAL SECURITY CHECK: Verify repository is private before ANY push operations
 	if err := gc.verifyRepositoryPrivacy(ctx); err != nil {
@@ -176,13 +200,12 @@ func (gc *GitHubClient) handleExistingApp(appName, configPath, targetPath string
 	return true, nil
 }
 
-// performPushOperation executes the actual push operation
+// performPushOperation executes the actual push operation. Large changes are committed and
+// pushed in chunks, and an interrupted push is resumed on its existing branch next time.
 func (gc *GitHubClient) performPushOperation(ctx context.Context, appName, configPath string) (*PushConfigResult, error) {
-	// Generate branch name with timestamp
-	branchName := generateTimestampedBranchName("config-push")
-
-	// Create and checkout new branch
-	if err := gc.createAndCheckoutBranch(ctx, branchName); err != nil {
+	// Reuse the branch of an unfinished push, or create a new timestamped one
+	branchName, resumed, err := gc.checkoutPushBranch(ctx, appName)
+	if err != nil {
 		return nil, err
 	}
 
@@ -197,16 +220,45 @@ func (gc *GitHubClient) performPushOperation(ctx context.Context, appName, confi
 		return nil, err
 	}
 
-	// Commit changes
-	commitMessage := fmt.Sprintf("anvil[push]: %s", appName)
-	if err := gc.commitChanges(ctx, commitMessage); err != nil {
-		return nil, err
+	// Keep the repository README index current, it is committed along with the app
+	if gc.GenerateReadme {
+		if err := gc.updateRepoReadme(appName, time.Now()); err != nil {
+			palantir.GetGlobalOutputHandler().PrintWarning("Failed to update repository README: %v", err)
+		}
+	} else if gc.needsIndexUpdate(appName) {
+		if _, err := gc.updateRepoIndex(appName, time.Now()); err != nil {
+			palantir.GetGlobalOutputHandler().PrintWarning("Failed to record the %s version requirement: %v", appName, err)
+		}
+	}
+
+	// Record the branch so an interrupted push can pick up where it left off
+	if err := gc.savePushState(&PushState{App: appName, Branch: branchName, StartedAt: time.Now()}); err != nil {
+		palantir.GetGlobalOutputHandler().PrintWarning("Failed to record push progress: %v", err)
 	}
 
-	// Push branch
-	if err := gc.pushBranch(ctx, branchName); err != nil {
+	// Commit and push changes, in chunks when they are large
+	commitMessage := fmt.Sprintf("anvil[push]: %s", appName)
+	commits, err := gc.commitAndPushInChunks(ctx, branchName, commitMessage)
+	if err != nil {
 		return nil, err
 	}
+	if commits == 0 {
+		if !resumed {
+			gc.ClearPushState()
+			return nil, fmt.Errorf("no changes to commit")
+		}
+		// Everything was committed by the interrupted push, make sure it reached the remote
+		if err := gc.pushBranchWithRetry(ctx, branchName); err != nil {
+			return nil, err
+		}
+	}
+	// Bring in what other machines pushed after the commit the branch started from
+	if gc.MergeBase != "" {
+		if err := gc.mergeConfiguredBranch(ctx, branchName); err != nil {
+			return nil, err
+		}
+	}
+	gc.ClearPushState()
 
 	// Determine files committed
 	filesCommitted, err := gc.getCommittedFiles(targetDir, appName)
@@ -220,6 +272,11 @@ func (gc *GitHubClient) performPushOperation(ctx context.Context, appName, confi
 		RepositoryURL:  gc.getRepositoryURL(),
 		FilesCommitted: filesCommitted,
 	}
+	if tree, err := gc.AppTree("HEAD", appName); err == nil {
+		result.AppTree = tree
+	}
+	events.Publish(events.Pushed, branchName, fmt.Sprintf("%s/compare/%s...%s", result.RepositoryURL, gc.Branch, branchName))
+	timeline.Record(timeline.Entry{Action: timeline.Pushed, App: appName, Repo: gc.RepoURL, Branch: branchName})
 
 	return result, nil
 }
@@ -250,6 +307,11 @@ func (gc *GitHubClient) ensureRepositoryReady(ctx context.Context) error {
 		return fmt.Errorf("failed to pull latest changes: %w", err)
 	}
 
+	// Pushing a new branch needs the full history, unshallow if the clone is shallow
+	if err := gc.ensureFullHistory(ctx); err != nil {
+		return fmt.Errorf("failed to unshallow repository: %w", err)
+	}
+
 	// Ensure repository is in a clean state before starting push operations
 	if err := gc.ensureCleanState(ctx); err != nil {
 		return fmt.Errorf("failed to ensure clean repository state: %w", err)
@@ -260,19 +322,7 @@ func (gc *GitHubClient) ensureRepositoryReady(ctx context.Context) error {
 
 // switchToMainBranch switches to the main branch specified in config
 func (gc *GitHubClient) switchToMainBranch(ctx context.Context) error {
-	originalDir, err := os.Getwd()
-	if err != nil {
-		return errors.NewFileSystemError(constants.OpPush, "getwd", err)
-	}
-	defer os.Chdir(originalDir)
-
-	if err := os.Chdir(gc.LocalPath); err != nil {
-		return errors.NewFileSystemError(constants.OpPush, "chdir", err)
-	}
-
-	// Checkout main branch
-	_, err = system.RunCommandWithTimeout(ctx, constants.GitCommand, "checkout", gc.Branch)
-	if err != nil {
+	if err := gc.checkoutBranch(gc.Branch, false); err != nil {
 		return errors.NewInstallationError(constants.OpPush, "git-checkout-main", err)
 	}
 
@@ -281,19 +331,7 @@ func (gc *GitHubClient) switchToMainBranch(ctx context.Context) error {
 
 // createAndCheckoutBranch creates a new branch and checks it out
 func (gc *GitHubClient) createAndCheckoutBranch(ctx context.Context, branchName string) error {
-	originalDir, err := os.Getwd()
-	if err != nil {
-		return errors.NewFileSystemError(constants.OpPush, "getwd", err)
-	}
-	defer os.Chdir(originalDir)
-
-	if err := os.Chdir(gc.LocalPath); err != nil {
-		return errors.NewFileSystemError(constants.OpPush, "chdir", err)
-	}
-
-	// Create and checkout new branch
-	_, err = system.RunCommandWithTimeout(ctx, constants.GitCommand, "checkout", "-b", branchName)
-	if err != nil {
+	if err := gc.checkoutBranch(branchName, true); err != nil {
 		return errors.NewInstallationError(constants.OpPush, "git-checkout-new-branch", err)
 	}
 
@@ -301,80 +339,85 @@ func (gc *GitHubClient) createAndCheckoutBranch(ctx context.Context, branchName
 	return nil
 }
 
-// commitChanges adds and commits all changes in the repository
-func (gc *GitHubClient) commitChanges(ctx context.Context, commitMessage string) error {
-	originalDir, err := os.Getwd()
-	if err != nil {
-		return errors.NewFileSystemError(constants.OpPush, "getwd", err)
-	}
-	defer os.Chdir(originalDir)
-
-	if err := os.Chdir(gc.LocalPath); err != nil {
-		return errors.NewFileSystemError(constants.OpPush, "chdir", err)
-	}
-
-	// Configure git user if provided
-	if err := gc.configureGitUser(ctx); err != nil {
-		return err
+// pushBranch pushes the current branch to origin
+func (gc *GitHubClient) pushBranch(ctx context.Context, branchName string) error {
+	if err := gc.pushRef(ctx, branchName); err != nil {
+		return errors.NewInstallationError(constants.OpPush, "git-push",
+			fmt.Errorf("failed to push branch: %w", err))
 	}
 
-	// Add all changes
-	if _, err := system.RunCommandWithTimeout(ctx, constants.GitCommand, "add", "."); err != nil {
-		return errors.NewInstallationError(constants.OpPush, "git-add", err)
-	}
+	palantir.GetGlobalOutputHandler().PrintSuccess(fmt.Sprintf("Pushed branch '%s' to origin", branchName))
+	return nil
+}
 
-	// Check if there are changes to commit
-	result, err := system.RunCommandWithTimeout(ctx, constants.GitCommand, "diff", "--cached", "--exit-code")
-	if err != nil {
-		return errors.NewInstallationError(constants.OpPush, "git-diff-check", err)
+// generateTimestampedBranchName generates a branch name with current date and time
+func (gc *GitHubClient) generateTimestampedBranchName(prefix string) string {
+	host := ""
+	if gc.BranchIncludeHost {
+		host, _ = os.Hostname()
 	}
+	return formatBranchName(prefix, resolveBranchTimestampLayout(gc.BranchTimestampFormat), host, time.Now())
+}
 
-	if result.ExitCode == 0 {
-		// Exit code 0 means no differences
-		return fmt.Errorf("no changes to commit")
+// formatBranchName builds prefix-timestamp[-host] using a git-safe rendering of the layout
+func formatBranchName(prefix, layout, host string, now time.Time) string {
+	name := fmt.Sprintf("%s-%s", prefix, sanitizeBranchComponent(now.Format(layout)))
+	if host = sanitizeBranchComponent(strings.TrimSuffix(strings.ToLower(host), ".local")); host != "" {
+		name = fmt.Sprintf("%s-%s", name, host)
 	}
+	return name
+}
 
-	// Exit code 1 means there are differences - proceed with commit
-	palantir.GetGlobalOutputHandler().PrintInfo("Changes detected, proceeding with commit...")
-
-	// Commit changes
-	if _, err := system.RunCommandWithTimeout(ctx, constants.GitCommand, "commit", "-m", commitMessage); err != nil {
-		return errors.NewInstallationError(constants.OpPush, "git-commit", err)
+// resolveBranchTimestampLayout maps a configured preset name to its time layout
+func resolveBranchTimestampLayout(format string) string {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", "iso":
+		return constants.BranchTimestampISO
+	case "legacy":
+		return constants.BranchTimestampLegacy
+	case "us":
+		return constants.BranchTimestampUS
+	default:
+		return format
 	}
-
-	palantir.GetGlobalOutputHandler().PrintSuccess(fmt.Sprintf("Committed changes: %s", commitMessage))
-	return nil
 }
 
-// pushBranch pushes the current branch to origin
-func (gc *GitHubClient) pushBranch(ctx context.Context, branchName string) error {
-	originalDir, err := os.Getwd()
-	if err != nil {
-		return errors.NewFileSystemError(constants.OpPush, "getwd", err)
-	}
-	defer os.Chdir(originalDir)
+// sanitizeBranchComponent replaces characters git does not allow in ref names
+func sanitizeBranchComponent(value string) string {
+	value = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		default:
+			return '-'
+		}
+	}, value)
+	return strings.Trim(value, "-")
+}
 
-	if err := os.Chdir(gc.LocalPath); err != nil {
-		return errors.NewFileSystemError(constants.OpPush, "chdir", err)
+// ParseBranchTimestamp extracts the creation time from a push branch name.
+// The configured format is tried first, then the ISO and legacy layouts so
+// branches created by earlier releases still sort correctly in listings.
+func ParseBranchTimestamp(branchName, prefix, format string) (time.Time, bool) {
+	rest, found := strings.CutPrefix(branchName, prefix+"-")
+	if !found {
+		return time.Time{}, false
 	}
 
-	// Push branch to origin
-	result, err := system.RunCommandWithTimeout(ctx, constants.GitCommand, "push", "--set-upstream", "origin", branchName)
-	if err != nil {
-		return errors.NewInstallationError(constants.OpPush, "git-push",
-			fmt.Errorf("failed to push branch: %s, error: %w", result.Error, err))
+	layouts := []string{resolveBranchTimestampLayout(format), constants.BranchTimestampISO, constants.BranchTimestampLegacy}
+	for _, layout := range layouts {
+		if len(rest) < len(layout) {
+			continue
+		}
+		if len(rest) > len(layout) && rest[len(layout)] != '-' {
+			continue
+		}
+		if t, err := time.ParseInLocation(layout, rest[:len(layout)], time.Local); err == nil {
+			return t, true
+		}
 	}
 
-	palantir.GetGlobalOutputHandler().PrintSuccess(fmt.Sprintf("Pushed branch '%s' to origin", branchName))
-	return nil
-}
-
-// generateTimestampedBranchName generates a branch name with current date and time
-func generateTimestampedBranchName(prefix string) string {
-	now := time.Now()
-	dateStr := now.Format("02012006") // DDMMYYYY
-	timeStr := now.Format("1504")     // HHMM (24h format)
-	return fmt.Sprintf("%s-%s-%s", prefix, dateStr, timeStr)
+	return time.Time{}, false
 }
 
 // getRepositoryURL returns the GitHub repository URL for display
@@ -442,56 +485,46 @@ func (gc *GitHubClient) hasFileOrDirChanges(localPath, repoPath string) (bool, e
 
 // hasDirectoryChanges recursively compares two directories
 func (gc *GitHubClient) hasDirectoryChanges(localDir, repoDir string) (bool, error) {
-	// Get all files in both directories
-	localFiles := make(map[string]os.FileInfo)
-	repoFiles := make(map[string]os.FileInfo)
-
-	// Walk local directory
-	err := filepath.Walk(localDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		relPath, err := filepath.Rel(localDir, path)
-		if err != nil {
-			return err
-		}
-		localFiles[relPath] = info
-		return nil
-	})
+	changes, err := gc.directoryChanges(localDir, repoDir)
 	if err != nil {
-		return false, fmt.Errorf("failed to walk local directory: %w", err)
+		return false, err
 	}
+	return !changes.IsEmpty(), nil
+}
 
-	// Walk repo directory
-	err = filepath.Walk(repoDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		relPath, err := filepath.Rel(repoDir, path)
-		if err != nil {
-			return err
-		}
-		repoFiles[relPath] = info
-		return nil
-	})
+// directoryChanges lists the files that differ between two directories. Added files exist only
+// locally and removed files only in the repository, a missing directory counts as empty.
+func (gc *GitHubClient) directoryChanges(localDir, repoDir string) (utils.DirChanges, error) {
+	var changes utils.DirChanges
+
+	// Get all files in both directories
+	localFiles, err := walkTree(localDir)
 	if err != nil {
-		return false, fmt.Errorf("failed to walk repo directory: %w", err)
+		return changes, fmt.Errorf("failed to walk local directory: %w", err)
 	}
-
-	// Check if file lists differ
-	if len(localFiles) != len(repoFiles) {
-		return true, nil
+	// Files left out by the include and exclude patterns are never pushed, the repository
+	// copies of such files count as removed since a push deletes them
+	for relPath := range gc.filteredOut(localDir) {
+		delete(localFiles, relPath)
+	}
+	repoFiles, err := walkTree(repoDir)
+	if err != nil {
+		return changes, fmt.Errorf("failed to walk repo directory: %w", err)
 	}
 
 	// Compare each file
 	for relPath, localInfo := range localFiles {
-		_, exists := repoFiles[relPath]
+		repoInfo, exists := repoFiles[relPath]
 		if !exists {
-			return true, nil
+			changes.Added = append(changes.Added, relPath)
+			continue
 		}
 
-		// Skip directories for content comparison
-		if localInfo.IsDir() {
+		// Symlinks and executable bits are tracked by git, so they count as changes too
+		if changed, handled := gc.hasModeOrLinkChanges(filepath.Join(localDir, relPath), filepath.Join(repoDir, relPath), localInfo, repoInfo); handled {
+			if changed {
+				changes.Modified = append(changes.Modified, relPath)
+			}
 			continue
 		}
 
@@ -500,14 +533,69 @@ func (gc *GitHubClient) hasDirectoryChanges(localDir, repoDir string) (bool, err
 		repoFilePath := filepath.Join(repoDir, relPath)
 		hasChanges, err := gc.hasFileChanges(localFilePath, repoFilePath)
 		if err != nil {
-			return false, err
+			return changes, err
 		}
 		if hasChanges {
-			return true, nil
+			changes.Modified = append(changes.Modified, relPath)
 		}
 	}
 
-	return false, nil
+	for relPath := range repoFiles {
+		if _, exists := localFiles[relPath]; !exists {
+			changes.Removed = append(changes.Removed, relPath)
+		}
+	}
+
+	sort.Strings(changes.Added)
+	sort.Strings(changes.Removed)
+	sort.Strings(changes.Modified)
+	return changes, nil
+}
+
+// walkTree returns the files and symlinks under dir keyed by relative path. Directories are skipped
+// since git only tracks what they contain, and a missing dir yields no files.
+func walkTree(dir string) (map[string]os.FileInfo, error) {
+	files := make(map[string]os.FileInfo)
+	if _, err := os.Lstat(dir); os.IsNotExist(err) {
+		return files, nil
+	}
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[relPath] = info
+		return nil
+	})
+	return files, err
+}
+
+// hasModeOrLinkChanges compares symlink targets and executable bits, handled is true when no content comparison is needed
+func (gc *GitHubClient) hasModeOrLinkChanges(localPath, repoPath string, localInfo, repoInfo os.FileInfo) (changed bool, handled bool) {
+	localIsLink := utils.IsSymlink(localInfo)
+	repoIsLink := utils.IsSymlink(repoInfo)
+
+	if !gc.MaterializeSymlinks && (localIsLink || repoIsLink) {
+		if localIsLink != repoIsLink {
+			return true, true
+		}
+		localTarget, _ := os.Readlink(localPath)
+		repoTarget, _ := os.Readlink(repoPath)
+		return localTarget != repoTarget, true
+	}
+
+	if !localInfo.IsDir() && !localIsLink && localInfo.Mode().Perm()&0111 != repoInfo.Mode().Perm()&0111 {
+		return true, true
+	}
+
+	return false, false
 }
 
 // hasFileChanges compares two files for differences
@@ -538,32 +626,57 @@ func (gc *GitHubClient) copyConfigToRepo(sourcePath, targetDir string) error {
 	} else {
 		// Copy single file to target directory
 		fileName := filepath.Base(sourcePath)
+		if fileName == constants.LOCAL_CONFIG_FILE {
+			return fmt.Errorf("%s holds machine-local settings and is never pushed", constants.LOCAL_CONFIG_FILE)
+		}
 		targetFile := filepath.Join(targetDir, fileName)
-		return utils.CopyFileSimple(sourcePath, targetFile)
+		return utils.CopyFile(sourcePath, targetFile, utils.ConfigCopyOptions())
 	}
 }
 
-// copyDirectoryContents recursively copies directory contents
+// copyDirectoryContents recursively copies directory contents, preserving file modes and symlinks
 func (gc *GitHubClient) copyDirectoryContents(sourceDir, targetDir string) error {
-	return filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
+	options := utils.ConfigCopyOptions()
+	options.PreserveSymlinks = !gc.MaterializeSymlinks
+	// The machine-local settings overlay stays on this machine
+	options.Exclude = append(append(options.Exclude, gc.Exclude...), constants.LOCAL_CONFIG_FILE)
+	options.Include = gc.Include
+	if err := utils.CopyDirectory(sourceDir, targetDir, options); err != nil {
+		return err
+	}
+	return gc.removeFilteredFiles(targetDir)
+}
 
-		// Get relative path
-		relPath, err := filepath.Rel(sourceDir, path)
-		if err != nil {
-			return err
+// removeFilteredFiles deletes files the include and exclude patterns leave out from an app's
+// directory in the repository, such as a .DS_Store pushed before it was excluded
+func (gc *GitHubClient) removeFilteredFiles(targetDir string) error {
+	for relPath := range gc.filteredOut(targetDir) {
+		if err := os.Remove(filepath.Join(targetDir, relPath)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove filtered file %s: %w", relPath, err)
 		}
+	}
+	return nil
+}
 
-		targetPath := filepath.Join(targetDir, relPath)
-
-		if info.IsDir() {
-			return os.MkdirAll(targetPath, info.Mode())
-		} else {
-			return utils.CopyFileSimple(path, targetPath)
+// filteredOut returns the files below dir the include and exclude patterns leave out
+func (gc *GitHubClient) filteredOut(dir string) map[string]bool {
+	filtered := make(map[string]bool)
+	if len(gc.Include) == 0 && len(gc.Exclude) == 0 {
+		return filtered
+	}
+	files, _ := walkTree(dir)
+	for relPath := range files {
+		if gc.skipsFile(relPath) {
+			filtered[relPath] = true
 		}
-	})
+	}
+	return filtered
+}
+
+// skipsFile reports whether the include and exclude patterns leave out a file, by its path
+// relative to the pushed config directory
+func (gc *GitHubClient) skipsFile(relPath string) bool {
+	return utils.SkipsPath(relPath, false, gc.Include, gc.Exclude)
 }
 
 // getCommittedFiles returns a list of files that were committed in the target directory
@@ -631,34 +744,22 @@ func (gc *GitHubClient) extractFileCount(statOutput string) int {
 
 // ensureCleanState ensures the repository is in a clean state before push operations
 func (gc *GitHubClient) ensureCleanState(ctx context.Context) error {
-	originalDir, err := os.Getwd()
-	if err != nil {
-		return errors.NewFileSystemError(constants.OpPush, "getwd", err)
-	}
-	defer os.Chdir(originalDir)
-
-	if err := os.Chdir(gc.LocalPath); err != nil {
-		return errors.NewFileSystemError(constants.OpPush, "chdir", err)
-	}
-
-	// Check if there are any staged changes
-	stagedResult, err := system.RunCommandWithTimeout(ctx, constants.GitCommand, "diff", "--cached", "--exit-code")
-	if err != nil && stagedResult.ExitCode != 0 {
-		// There are staged changes, reset them
-		if _, err := system.RunCommandWithTimeout(ctx, constants.GitCommand, "reset", "HEAD"); err != nil {
+	// Check if there are any staged changes, reset them
+	if _, err := gc.git(ctx, "diff", "--cached", "--quiet"); err != nil {
+		if _, err := gc.git(ctx, "reset", "HEAD"); err != nil {
 			return errors.NewInstallationError(constants.OpPush, "git-reset", err)
 		}
 	}
 
 	// Check if there are any untracked files
-	statusResult, err := system.RunCommandWithTimeout(ctx, constants.GitCommand, "status", "--porcelain")
+	status, err := gc.git(ctx, "status", "--porcelain")
 	if err != nil {
 		return errors.NewInstallationError(constants.OpPush, "git-status", err)
 	}
 
 	// If there are untracked files, clean them
-	if strings.TrimSpace(statusResult.Output) != "" {
-		if _, err := system.RunCommandWithTimeout(ctx, constants.GitCommand, "clean", "-fd"); err != nil {
+	if strings.TrimSpace(status) != "" {
+		if _, err := gc.git(ctx, "clean", "-fd"); err != nil {
 			return errors.NewInstallationError(constants.OpPush, "git-clean", err)
 		}
 	}
@@ -669,34 +770,24 @@ func (gc *GitHubClient) ensureCleanState(ctx context.Context) error {
 // CleanupStagedChanges removes any staged changes from the repository
 // This is called when a push operation is cancelled to ensure clean state
 func (gc *GitHubClient) CleanupStagedChanges(ctx context.Context) error {
-	originalDir, err := os.Getwd()
-	if err != nil {
-		return errors.NewFileSystemError(constants.OpPush, "getwd", err)
-	}
-	defer os.Chdir(originalDir)
-
-	if err := os.Chdir(gc.LocalPath); err != nil {
-		return errors.NewFileSystemError(constants.OpPush, "chdir", err)
-	}
-
 	// Reset any staged changes
-	if _, err := system.RunCommandWithTimeout(ctx, constants.GitCommand, "reset", "HEAD"); err != nil {
+	if _, err := gc.git(ctx, "reset", "HEAD"); err != nil {
 		return errors.NewInstallationError(constants.OpPush, "git-reset", err)
 	}
 
 	// Clean any untracked files that might have been created during diff preview
-	if _, err := system.RunCommandWithTimeout(ctx, constants.GitCommand, "clean", "-fd"); err != nil {
+	if _, err := gc.git(ctx, "clean", "-fd"); err != nil {
 		return errors.NewInstallationError(constants.OpPush, "git-clean", err)
 	}
 
 	// Also reset any working directory changes that might have been left behind
-	if _, err := system.RunCommandWithTimeout(ctx, constants.GitCommand, "checkout", "--", "."); err != nil {
+	if _, err := gc.git(ctx, "checkout", "--", "."); err != nil {
 		return errors.NewInstallationError(constants.OpPush, "git-checkout", err)
 	}
 
 	// Switch back to main branch to ensure we're in a clean state
-	if _, err := system.RunCommandWithTimeout(ctx, constants.GitCommand, "checkout", gc.Branch); err != nil {
-		return errors.NewInstallationError(constants.OpPush, "git-checkout-main", err)
+	if err := gc.switchToMainBranch(ctx); err != nil {
+		return err
 	}
 
 	return nil
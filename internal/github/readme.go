@@ -0,0 +1,204 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// repoIndexFile is committed next to the app directories and remembers who pushed each app,
+	// and the app versions their configs need
+	repoIndexFile  = ".anvil-index.json"
+	repoReadmeFile = "README.md"
+
+	readmeIndexStart = "<!-- anvil:index:start -->"
+	readmeIndexEnd   = "<!-- anvil:index:end -->"
+)
+
+// RepoIndexEntry records the last push of an app directory
+type RepoIndexEntry struct {
+	PushedAt time.Time `json:"pushed_at"`
+	Host     string    `json:"host"`
+	AppCompatibility
+}
+
+// AppCompatibility is the app version a config was pushed from and the oldest it works with
+type AppCompatibility struct {
+	AppVersion    string `json:"app_version,omitempty"`     // Installed on the pushing machine, "" when unknown
+	MinAppVersion string `json:"min_app_version,omitempty"` // Checked by sync, "" when not required
+	OnMismatch    string `json:"on_mismatch,omitempty"`     // warn or block
+}
+
+// LoadAppCompatibility returns the version requirement recorded for an app in the manifest of
+// a repository clone
+func LoadAppCompatibility(repoPath, appName string) (AppCompatibility, bool) {
+	entry, ok := loadRepoIndex(repoPath)[appName]
+	if !ok || entry.MinAppVersion == "" {
+		return AppCompatibility{}, false
+	}
+	return entry.AppCompatibility, true
+}
+
+// RepoAppSummary describes an app directory for the generated README
+type RepoAppSummary struct {
+	Name      string
+	FileCount int
+	PushedAt  time.Time // Zero when the app was never pushed with the index enabled
+	Host      string
+}
+
+// updateRepoReadme records the push of appName and regenerates the index section of the
+// repository README. Content outside the anvil markers is left untouched.
+func (gc *GitHubClient) updateRepoReadme(appName string, now time.Time) error {
+	index, err := gc.updateRepoIndex(appName, now)
+	if err != nil {
+		return err
+	}
+
+	apps, err := summarizeRepoApps(gc.LocalPath, index)
+	if err != nil {
+		return err
+	}
+
+	readmePath := filepath.Join(gc.LocalPath, repoReadmeFile)
+	existing, err := os.ReadFile(readmePath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", repoReadmeFile, err)
+	}
+	content := replaceIndexSection(string(existing), renderRepoIndex(apps, now))
+	if err := os.WriteFile(readmePath, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", repoReadmeFile, err)
+	}
+	return nil
+}
+
+// updateRepoIndex records the push of appName in the index, with the client's version
+// requirement or, when it has none, the one already recorded
+func (gc *GitHubClient) updateRepoIndex(appName string, now time.Time) (map[string]RepoIndexEntry, error) {
+	index := loadRepoIndex(gc.LocalPath)
+	host, _ := os.Hostname()
+	entry := RepoIndexEntry{PushedAt: now, Host: strings.TrimSuffix(host, ".local"), AppCompatibility: index[appName].AppCompatibility}
+	if gc.AppVersion != nil {
+		entry.AppCompatibility = *gc.AppVersion
+	}
+	index[appName] = entry
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(gc.LocalPath, repoIndexFile), append(data, '\n'), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write repository index: %w", err)
+	}
+	return index, nil
+}
+
+// needsIndexUpdate reports whether a push without the README index must still write the
+// index, to record a version requirement or drop one that was removed
+func (gc *GitHubClient) needsIndexUpdate(appName string) bool {
+	if gc.AppVersion != nil && gc.AppVersion.MinAppVersion != "" {
+		return true
+	}
+	_, recorded := LoadAppCompatibility(gc.LocalPath, appName)
+	return recorded
+}
+
+// loadRepoIndex reads the push index, returning an empty index when missing or unreadable
+func loadRepoIndex(repoPath string) map[string]RepoIndexEntry {
+	index := make(map[string]RepoIndexEntry)
+	data, err := os.ReadFile(filepath.Join(repoPath, repoIndexFile))
+	if err != nil {
+		return index
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return make(map[string]RepoIndexEntry)
+	}
+	return index
+}
+
+// summarizeRepoApps lists the visible top-level directories of the repository with their file counts
+func summarizeRepoApps(repoPath string, index map[string]RepoIndexEntry) ([]RepoAppSummary, error) {
+	entries, err := os.ReadDir(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repository: %w", err)
+	}
+
+	var apps []RepoAppSummary
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		count := 0
+		filepath.WalkDir(filepath.Join(repoPath, entry.Name()), func(_ string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				count++
+			}
+			return nil
+		})
+		pushed := index[entry.Name()]
+		apps = append(apps, RepoAppSummary{Name: entry.Name(), FileCount: count, PushedAt: pushed.PushedAt, Host: pushed.Host})
+	}
+	sort.Slice(apps, func(i, j int) bool { return apps[i].Name < apps[j].Name })
+	return apps, nil
+}
+
+// renderRepoIndex renders the marked README section listing every app directory
+func renderRepoIndex(apps []RepoAppSummary, generatedAt time.Time) string {
+	var b strings.Builder
+	b.WriteString(readmeIndexStart + "\n")
+	b.WriteString("## Configurations\n\n")
+	b.WriteString("| App | Files | Last updated | Pushed from |\n")
+	b.WriteString("|-----|-------|--------------|-------------|\n")
+	for _, app := range apps {
+		updated, host := "-", "-"
+		if !app.PushedAt.IsZero() {
+			updated = app.PushedAt.Format("2006-01-02 15:04 MST")
+		}
+		if app.Host != "" {
+			host = app.Host
+		}
+		fmt.Fprintf(&b, "| [%s](%s/) | %d | %s | %s |\n", app.Name, app.Name, app.FileCount, updated, host)
+	}
+	fmt.Fprintf(&b, "\n_Generated by anvil on %s._\n", generatedAt.Format("2006-01-02 15:04 MST"))
+	b.WriteString(readmeIndexEnd + "\n")
+	return b.String()
+}
+
+// replaceIndexSection swaps the marked section in an existing README, appending it when the
+// markers are missing and starting a new README when there is none
+func replaceIndexSection(existing, section string) string {
+	if strings.TrimSpace(existing) == "" {
+		return "# Dotfiles\n\nConfigurations managed with [anvil](https://github.com/0xjuanma/anvil).\n\n" + section
+	}
+
+	start := strings.Index(existing, readmeIndexStart)
+	end := strings.Index(existing, readmeIndexEnd)
+	if start == -1 || end < start {
+		return strings.TrimRight(existing, "\n") + "\n\n" + section
+	}
+
+	rest := strings.TrimPrefix(existing[end+len(readmeIndexEnd):], "\n")
+	return existing[:start] + section + rest
+}
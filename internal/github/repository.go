@@ -0,0 +1,291 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/system"
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// Clone, pull and checkout run in-process with go-git. They never change the working
+// directory, so concurrent operations on different clones are safe, and they stop when the
+// context is cancelled. Commits and pushes use the git binary when it is installed, so the
+// config repository's hooks run. History queries still run the git binary through gc.git.
+
+// remoteName is the remote every clone is created with
+const remoteName = "origin"
+
+var installTransports sync.Once
+
+// useNetworkTransports makes go-git use the HTTP client configured by the network settings,
+// so proxies and the CA bundle apply to clones and pushes as they do to the git binary
+func useNetworkTransports() {
+	installTransports.Do(func() {
+		httpClient := githttp.NewClient(system.HTTPClient())
+		client.InstallProtocol("https", httpClient)
+		client.InstallProtocol("http", httpClient)
+	})
+}
+
+// openRepository opens the local clone
+func (gc *GitHubClient) openRepository() (*git.Repository, error) {
+	useNetworkTransports()
+	return git.PlainOpen(gc.LocalPath)
+}
+
+// remoteAuth returns the credentials for the clone URL: the token over HTTPS, the configured
+// SSH key or the SSH agent over SSH, and nil for public repositories and local paths
+func (gc *GitHubClient) remoteAuth() (transport.AuthMethod, error) {
+	endpoint, err := transport.NewEndpoint(gc.getCloneURL())
+	if err != nil {
+		return nil, err
+	}
+
+	switch endpoint.Protocol {
+	case "https", "http":
+		if gc.Token != "" {
+			return &githttp.BasicAuth{Username: "x-access-token", Password: gc.Token}, nil
+		}
+	case "ssh":
+		return gc.sshAuth(endpoint.User)
+	}
+	return nil, nil
+}
+
+// sshAuth uses the configured SSH key, or the SSH agent when there is none or the key can't be
+// read without a passphrase, which the agent holds for encrypted keys
+func (gc *GitHubClient) sshAuth(user string) (transport.AuthMethod, error) {
+	var keyErr error
+	if gc.SSHKeyPath != "" {
+		if _, err := os.Stat(gc.SSHKeyPath); err == nil {
+			keys, err := gitssh.NewPublicKeysFromFile(user, gc.SSHKeyPath, "")
+			if err == nil {
+				keys.HostKeyCallback = acceptNewHostKeys()
+				return keys, nil
+			}
+			keyErr = err
+		}
+	}
+
+	agent, err := gitssh.NewSSHAgentAuth(user)
+	if err != nil {
+		if keyErr != nil {
+			return nil, fmt.Errorf("failed to read SSH key %s: %w (add it to the SSH agent with 'ssh-add %s')", gc.SSHKeyPath, keyErr, gc.SSHKeyPath)
+		}
+		return nil, nil
+	}
+	agent.HostKeyCallback = acceptNewHostKeys()
+	return agent, nil
+}
+
+// acceptNewHostKeys checks host keys against known_hosts like StrictHostKeyChecking=no, which
+// the git binary runs with: hosts that are not listed yet, as on a fresh machine, are accepted,
+// while a key that differs from the listed one is still rejected
+func acceptNewHostKeys() ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		callback, err := gitssh.NewKnownHostsCallback()
+		if err != nil {
+			return nil // No known_hosts file yet
+		}
+		err = callback(hostname, remote, key)
+		var keyErr *knownhosts.KeyError
+		if stderrors.As(err, &keyErr) && len(keyErr.Want) == 0 {
+			return nil
+		}
+		return err
+	}
+}
+
+// useGitBinary reports whether commits and pushes run through the git binary. Only git runs
+// the pre-commit and pre-push hooks of 'anvil config install-hooks', go-git is the fallback
+// on machines without it.
+func useGitBinary() bool {
+	return system.CommandExists(constants.GitCommand)
+}
+
+// progressWriter passes git progress lines to gc.Progress, nil when progress is discarded
+func (gc *GitHubClient) progressWriter() io.Writer {
+	if gc.Progress == nil {
+		return nil
+	}
+	return &progressLines{progress: gc.Progress}
+}
+
+// progressLines splits the sideband progress of the remote into lines. Counters are
+// redrawn with carriage returns, each redraw is passed on as its own line.
+type progressLines struct {
+	progress func(line string)
+	pending  []byte
+}
+
+// Write implements io.Writer
+func (p *progressLines) Write(data []byte) (int, error) {
+	for _, b := range data {
+		if b != '\n' && b != '\r' {
+			p.pending = append(p.pending, b)
+			continue
+		}
+		if line := strings.TrimSpace(string(p.pending)); line != "" {
+			p.progress(line)
+		}
+		p.pending = p.pending[:0]
+	}
+	return len(data), nil
+}
+
+// checkoutBranch switches the worktree to an existing local branch, or to a new one created
+// at HEAD when create is set. Staged and unstaged changes are carried over, as with git checkout.
+func (gc *GitHubClient) checkoutBranch(branchName string, create bool) error {
+	repo, err := gc.openRepository()
+	if err != nil {
+		return err
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	return worktree.Checkout(&git.CheckoutOptions{
+		Branch: plumbing.NewBranchReferenceName(branchName),
+		Create: create,
+		Keep:   true,
+	})
+}
+
+// stageAll stages every change of the worktree, including deletions, honoring .gitignore
+func stageAll(worktree *git.Worktree) error {
+	return worktree.AddWithOptions(&git.AddOptions{All: true})
+}
+
+// stagedPaths returns the paths whose staged content differs from HEAD
+func stagedPaths(worktree *git.Worktree) ([]string, error) {
+	status, err := worktree.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for path, file := range status {
+		if file.Staging != git.Unmodified && file.Staging != git.Untracked {
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+// commitChanges commits the index with message. When paths is set, only those paths are
+// staged on top of HEAD, which lets a large change be committed in several parts.
+func (gc *GitHubClient) commitChanges(ctx context.Context, message string, paths []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo, err := gc.openRepository()
+	if err != nil {
+		return err
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+
+	if paths != nil {
+		if err := worktree.Reset(&git.ResetOptions{Mode: git.MixedReset}); err != nil {
+			return fmt.Errorf("failed to reset the index: %w", err)
+		}
+		for _, path := range paths {
+			if _, err := worktree.Add(path); err != nil {
+				return fmt.Errorf("failed to stage %s: %w", path, err)
+			}
+		}
+	}
+
+	if useGitBinary() {
+		_, err = gc.git(ctx, "commit", "-q", "-m", message)
+		return err
+	}
+	_, err = worktree.Commit(message, &git.CommitOptions{})
+	return err
+}
+
+// pushRef pushes a local branch to the branch of the same name on origin and tracks it.
+// A branch that is already up to date counts as pushed.
+func (gc *GitHubClient) pushRef(ctx context.Context, branchName string) error {
+	if useGitBinary() {
+		_, err := gc.git(ctx, "push", "--set-upstream", remoteName, branchName)
+		return pushHookError(err)
+	}
+
+	repo, err := gc.openRepository()
+	if err != nil {
+		return err
+	}
+	auth, err := gc.remoteAuth()
+	if err != nil {
+		return err
+	}
+
+	ref := plumbing.NewBranchReferenceName(branchName)
+	err = repo.PushContext(ctx, &git.PushOptions{
+		RemoteName: remoteName,
+		RefSpecs:   []gitconfig.RefSpec{gitconfig.RefSpec(fmt.Sprintf("%s:%s", ref, ref))},
+		Auth:       auth,
+		Progress:   gc.progressWriter(),
+	})
+	if err != nil && !stderrors.Is(err, git.NoErrAlreadyUpToDate) {
+		return err
+	}
+
+	// Equivalent of --set-upstream, so plain git pull and push work on the branch too
+	if err := repo.CreateBranch(&gitconfig.Branch{Name: branchName, Remote: remoteName, Merge: ref}); err != nil && !stderrors.Is(err, git.ErrBranchExists) {
+		return err
+	}
+	return nil
+}
+
+// pushHookError marks a push the pre-push hook refused as a validation error, retrying can't help
+func pushHookError(err error) error {
+	if err != nil && strings.Contains(err.Error(), "hook declined") {
+		return errors.NewValidationError(constants.OpPush, "git-hook", err)
+	}
+	return err
+}
+
+// isMissingRemoteRef reports whether a clone or pull failed because the branch doesn't exist
+func isMissingRemoteRef(err error) bool {
+	var missing git.NoMatchingRefSpecError
+	return stderrors.As(err, &missing) || stderrors.Is(err, plumbing.ErrReferenceNotFound) ||
+		strings.Contains(err.Error(), "couldn't find remote ref")
+}
@@ -0,0 +1,424 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/system"
+	"github.com/0xjuanma/palantir"
+)
+
+// pushStateFile lives inside .git so it never gets committed
+const pushStateFile = "anvil-push-state.json"
+
+// PushState records an unfinished push so the next attempt can reuse its branch
+type PushState struct {
+	App       string    `json:"app"`
+	Branch    string    `json:"branch"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// pushStatePath returns the location of the unfinished push record
+func (gc *GitHubClient) pushStatePath() string {
+	return filepath.Join(gc.LocalPath, ".git", pushStateFile)
+}
+
+// LoadPushState returns the unfinished push record, or nil if the last push completed
+func (gc *GitHubClient) LoadPushState() *PushState {
+	data, err := os.ReadFile(gc.pushStatePath())
+	if err != nil {
+		return nil
+	}
+	var state PushState
+	if err := json.Unmarshal(data, &state); err != nil || state.Branch == "" {
+		return nil
+	}
+	return &state
+}
+
+// savePushState records the branch of a push in progress
+func (gc *GitHubClient) savePushState(state *PushState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(gc.pushStatePath(), data, constants.FilePerm)
+}
+
+// ClearPushState forgets the unfinished push record
+func (gc *GitHubClient) ClearPushState() {
+	os.Remove(gc.pushStatePath())
+}
+
+// git runs a git command in the local clone and returns its output, failing on a non-zero exit
+func (gc *GitHubClient) git(ctx context.Context, args ...string) (string, error) {
+	result, _ := system.RunCommandInDirectoryWithTimeout(ctx, gc.LocalPath, constants.GitCommand, args...)
+	if !result.Success {
+		return result.Output, fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(result.Output+" "+result.Error))
+	}
+	return result.Output, nil
+}
+
+// localBranchExists reports whether a branch exists in the local clone
+func (gc *GitHubClient) localBranchExists(ctx context.Context, branch string) bool {
+	_, err := gc.git(ctx, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch)
+	return err == nil
+}
+
+// remoteBranchExists reports whether a branch exists on origin
+func (gc *GitHubClient) remoteBranchExists(ctx context.Context, branch string) bool {
+	_, err := gc.git(ctx, "ls-remote", "--exit-code", "--heads", "origin", branch)
+	return err == nil
+}
+
+// checkoutPushBranch starts a new push branch, or reuses the partial branch of an unfinished push of the same app
+func (gc *GitHubClient) checkoutPushBranch(ctx context.Context, appName string) (string, bool, error) {
+	output := palantir.GetGlobalOutputHandler()
+
+	if state := gc.LoadPushState(); state != nil && state.App == appName {
+		switch {
+		case gc.localBranchExists(ctx, state.Branch):
+			if _, err := gc.git(ctx, "checkout", state.Branch); err == nil {
+				output.PrintInfo("Resuming unfinished push on branch: %s", state.Branch)
+				return state.Branch, true, nil
+			}
+		case gc.remoteBranchExists(ctx, state.Branch):
+			if _, err := gc.git(ctx, "fetch", "origin", fmt.Sprintf("%s:%s", state.Branch, state.Branch)); err == nil {
+				if _, err := gc.git(ctx, "checkout", state.Branch); err == nil {
+					output.PrintInfo("Resuming unfinished push on branch: %s", state.Branch)
+					return state.Branch, true, nil
+				}
+			}
+		}
+		output.PrintWarning("Could not reuse unfinished push branch '%s', starting a new one", state.Branch)
+	}
+
+	if gc.AppBranches {
+		return gc.checkoutAppBranch(ctx, appName)
+	}
+
+	branchName := gc.generateTimestampedBranchName(constants.PushBranchPrefix)
+	if gc.MergeBase != "" {
+		return branchName, false, gc.checkoutBranchAt(ctx, branchName, gc.MergeBase)
+	}
+	if err := gc.createAndCheckoutBranch(ctx, branchName); err != nil {
+		return "", false, err
+	}
+	return branchName, false, nil
+}
+
+// checkoutBranchAt creates or resets a branch at start, HEAD when empty, and switches to it
+func (gc *GitHubClient) checkoutBranchAt(ctx context.Context, branchName, start string) error {
+	args := []string{"checkout", "-B", branchName}
+	if start != "" {
+		args = append(args, start)
+	}
+	if _, err := gc.git(ctx, args...); err != nil {
+		return errors.NewInstallationError(constants.OpPush, "git-checkout-new-branch", err)
+	}
+	palantir.GetGlobalOutputHandler().PrintInfo("Created and switched to branch: %s", branchName)
+	return nil
+}
+
+// appBranchName returns the long-lived push branch of an app
+func appBranchName(appName string) string {
+	return constants.AppBranchPrefix + "/" + sanitizeBranchComponent(appName)
+}
+
+// checkoutAppBranch switches to the app's long-lived push branch so the push adds commits on
+// top of the earlier ones. The configured branch is merged in first, the pull request then
+// only shows changes that are not there yet. The branch is created from it on the first push.
+func (gc *GitHubClient) checkoutAppBranch(ctx context.Context, appName string) (string, bool, error) {
+	output := palantir.GetGlobalOutputHandler()
+	branchName := appBranchName(appName)
+
+	if !gc.remoteBranchExists(ctx, branchName) {
+		// Resets a local branch left behind after the remote one was deleted
+		if err := gc.checkoutBranchAt(ctx, branchName, gc.MergeBase); err != nil {
+			return "", false, err
+		}
+		return branchName, false, nil
+	}
+
+	if _, err := gc.git(ctx, "fetch", "origin", fmt.Sprintf("+refs/heads/%s:refs/remotes/origin/%s", branchName, branchName)); err != nil {
+		return "", false, errors.NewInstallationError(constants.OpPush, "git-fetch", err)
+	}
+	if _, err := gc.git(ctx, "checkout", "-B", branchName, "origin/"+branchName); err != nil {
+		return "", false, errors.NewInstallationError(constants.OpPush, "git-checkout", err)
+	}
+	if gc.MergeBase != "" {
+		// The configured branch is merged after committing, merging it first would let the
+		// copied config undo what other machines pushed
+		output.PrintInfo("Switched to app branch: %s", branchName)
+		return branchName, false, nil
+	}
+	if err := gc.configureGitUser(ctx); err != nil {
+		return "", false, err
+	}
+	if _, err := gc.git(ctx, "merge", "--no-edit", gc.Branch); err != nil {
+		gc.git(ctx, "merge", "--abort")
+		output.PrintWarning("Could not merge '%s' into '%s', committing on top of the branch as is: %v", gc.Branch, branchName, err)
+	}
+	output.PrintInfo("Switched to app branch: %s", branchName)
+	return branchName, false, nil
+}
+
+// stagedChange is a staged path and its size on disk (0 for deletions)
+type stagedChange struct {
+	path string
+	size int64
+}
+
+// planCommitChunks splits staged changes into chunks bounded by file count and size
+func planCommitChunks(changes []stagedChange, maxFiles int, maxSize int64) [][]stagedChange {
+	var chunks [][]stagedChange
+	var current []stagedChange
+	var currentSize int64
+
+	for _, change := range changes {
+		if len(current) > 0 && (len(current) >= maxFiles || currentSize+change.size > maxSize) {
+			chunks = append(chunks, current)
+			current, currentSize = nil, 0
+		}
+		current = append(current, change)
+		currentSize += change.size
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
+
+// stagedChanges stages everything and returns the staged paths with their sizes
+func (gc *GitHubClient) stagedChanges(ctx context.Context) ([]stagedChange, error) {
+	repo, err := gc.openRepository()
+	if err != nil {
+		return nil, errors.NewFileSystemError(constants.OpPush, "open-repo", err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, errors.NewFileSystemError(constants.OpPush, "worktree", err)
+	}
+	if err := stageAll(worktree); err != nil {
+		return nil, errors.NewInstallationError(constants.OpPush, "git-add", err)
+	}
+	paths, err := stagedPaths(worktree)
+	if err != nil {
+		return nil, errors.NewInstallationError(constants.OpPush, "git-status", err)
+	}
+	sort.Strings(paths)
+
+	var changes []stagedChange
+	for _, path := range paths {
+		change := stagedChange{path: path}
+		if info, err := os.Lstat(filepath.Join(gc.LocalPath, path)); err == nil {
+			change.size = info.Size()
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// commitAndPushInChunks commits staged changes in bounded chunks, pushing after each one so
+// an interrupted push keeps its progress on the remote branch. It returns the number of commits made.
+func (gc *GitHubClient) commitAndPushInChunks(ctx context.Context, branchName, commitMessage string) (int, error) {
+	output := palantir.GetGlobalOutputHandler()
+
+	if err := gc.configureGitUser(ctx); err != nil {
+		return 0, err
+	}
+
+	changes, err := gc.stagedChanges(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if len(changes) == 0 {
+		return 0, nil
+	}
+
+	chunks := planCommitChunks(changes, constants.PushChunkMaxFiles, int64(constants.PushChunkMaxSizeMB)*1024*1024)
+	if len(chunks) == 1 {
+		if err := gc.commitChanges(ctx, commitMessage, nil); err != nil {
+			return 0, errors.NewInstallationError(constants.OpPush, "git-commit", err)
+		}
+		output.PrintSuccess(fmt.Sprintf("Committed changes: %s", commitMessage))
+		return 1, gc.pushBranchWithRetry(ctx, branchName)
+	}
+
+	output.PrintInfo("Large push: splitting %d files into %d commits", len(changes), len(chunks))
+	for i, chunk := range chunks {
+		paths := make([]string, 0, len(chunk))
+		for _, change := range chunk {
+			paths = append(paths, change.path)
+		}
+
+		message := fmt.Sprintf("%s (part %d/%d)", commitMessage, i+1, len(chunks))
+		if err := gc.commitChanges(ctx, message, paths); err != nil {
+			return i, errors.NewInstallationError(constants.OpPush, "git-commit", err)
+		}
+		output.PrintSuccess(fmt.Sprintf("Committed %s", message))
+
+		if err := gc.pushBranchWithRetry(ctx, branchName); err != nil {
+			return i + 1, err
+		}
+	}
+
+	return len(chunks), nil
+}
+
+// pushBranchWithRetry pushes a branch, retrying with exponential backoff unless the failure
+// is permanent, such as rejected credentials. Pushing an already up-to-date branch succeeds,
+// so retries and resumed pushes are idempotent.
+func (gc *GitHubClient) pushBranchWithRetry(ctx context.Context, branchName string) error {
+	delay := time.Duration(constants.PushRetryInitialDelay) * time.Second
+	var lastErr error
+
+	for attempt := 1; attempt <= constants.PushRetryAttempts; attempt++ {
+		if lastErr = gc.pushBranch(ctx, branchName); lastErr == nil {
+			return nil
+		}
+		if attempt == constants.PushRetryAttempts || errors.IsPermanent(lastErr) {
+			break
+		}
+
+		palantir.GetGlobalOutputHandler().PrintWarning("Push attempt %d/%d failed, retrying in %s...",
+			attempt, constants.PushRetryAttempts, delay)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+
+	return fmt.Errorf("%w (progress is kept on branch '%s', run the push again to resume)", lastErr, branchName)
+}
+
+// PushBranch describes a push branch on the remote
+type PushBranch struct {
+	Name      string
+	CreatedAt time.Time
+	HasTime   bool
+	Partial   bool // Branch of an unfinished push that can still be resumed
+}
+
+// ListPushBranches returns the push branches on origin, newest first
+func (gc *GitHubClient) ListPushBranches(ctx context.Context) ([]PushBranch, error) {
+	out, err := gc.git(ctx, "ls-remote", "--heads", "origin", constants.PushBranchPrefix+"-*")
+	if err != nil {
+		return nil, errors.NewInstallationError(constants.OpConfig, "git-ls-remote", err)
+	}
+
+	state := gc.LoadPushState()
+	var branches []PushBranch
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		name := strings.TrimPrefix(fields[1], "refs/heads/")
+		branch := PushBranch{Name: name, Partial: state != nil && state.Branch == name}
+		branch.CreatedAt, branch.HasTime = ParseBranchTimestamp(name, constants.PushBranchPrefix, gc.BranchTimestampFormat)
+		branches = append(branches, branch)
+	}
+
+	sortPushBranches(branches)
+	return branches, nil
+}
+
+// FetchPushBranches updates the clone's remote-tracking refs of the configured branch and the push
+// branches, which IsMergedLocally compares. A shallow clone is fetched in full first, merges
+// older than its history would be missed otherwise.
+func (gc *GitHubClient) FetchPushBranches(ctx context.Context) error {
+	if err := gc.ensureFullHistory(ctx); err != nil {
+		return err
+	}
+	_, err := gc.git(ctx, "fetch", "--prune", "origin",
+		fmt.Sprintf("+refs/heads/%s:refs/remotes/origin/%s", gc.Branch, gc.Branch),
+		fmt.Sprintf("+refs/heads/%s-*:refs/remotes/origin/%s-*", constants.PushBranchPrefix, constants.PushBranchPrefix))
+	if err != nil {
+		return errors.NewInstallationError(constants.OpConfig, "git-fetch", err)
+	}
+	return nil
+}
+
+// IsMergedLocally reports whether a push branch's tip is in the configured branch's history,
+// as fetched by FetchPushBranches. Squash and rebase merges leave no trace there, so false
+// means "not known to be merged".
+func (gc *GitHubClient) IsMergedLocally(ctx context.Context, branchName string) bool {
+	_, err := gc.git(ctx, "merge-base", "--is-ancestor", "refs/remotes/origin/"+branchName, "refs/remotes/origin/"+gc.Branch)
+	return err == nil
+}
+
+// MergedPushBranch returns the merged pull request of a push branch, or nil when GitHub shows
+// none. It finds squash and rebase merges that IsMergedLocally can't.
+func (gc *GitHubClient) MergedPushBranch(ctx context.Context, branchName string) (*PullRequest, error) {
+	repo, err := gc.repoSlug()
+	if err != nil {
+		return nil, err
+	}
+	return NewAPIClient(gc.Token, "").MergedPullRequest(ctx, repo, branchName, gc.Branch)
+}
+
+// sortPushBranches orders branches newest first, undated branches last
+func sortPushBranches(branches []PushBranch) {
+	for i := 1; i < len(branches); i++ {
+		for j := i; j > 0 && newerPushBranch(branches[j], branches[j-1]); j-- {
+			branches[j], branches[j-1] = branches[j-1], branches[j]
+		}
+	}
+}
+
+// newerPushBranch reports whether a should be listed before b
+func newerPushBranch(a, b PushBranch) bool {
+	if a.HasTime != b.HasTime {
+		return a.HasTime
+	}
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.After(b.CreatedAt)
+	}
+	return a.Name > b.Name
+}
+
+// DeletePushBranch removes a push branch from origin and the local clone
+func (gc *GitHubClient) DeletePushBranch(ctx context.Context, branchName string) error {
+	if !strings.HasPrefix(branchName, constants.PushBranchPrefix+"-") {
+		return fmt.Errorf("refusing to delete non-push branch '%s'", branchName)
+	}
+
+	if _, err := gc.git(ctx, "push", "origin", "--delete", branchName); err != nil {
+		return errors.NewInstallationError(constants.OpConfig, "git-push-delete", err)
+	}
+	if gc.localBranchExists(ctx, branchName) {
+		gc.git(ctx, "checkout", gc.Branch)
+		gc.git(ctx, "branch", "-D", branchName)
+	}
+	if state := gc.LoadPushState(); state != nil && state.Branch == branchName {
+		gc.ClearPushState()
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/palantir"
+)
+
+// SecretAllowMarker on a line tells the scanner the match is intentional, for example a placeholder
+const SecretAllowMarker = "anvil:allow-secret"
+
+// maxScannedFileSize skips files too large to be hand-written configuration
+const maxScannedFileSize = 1024 * 1024
+
+// maxReportedSecrets is the number of findings listed when secrets are found
+const maxReportedSecrets = 10
+
+// secretRule is a pattern for one kind of credential
+type secretRule struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+// secretRules match credentials that should never reach the config repository
+var secretRules = []secretRule{
+	{"private key", regexp.MustCompile(`-----BEGIN (RSA |DSA |EC |OPENSSH |PGP |ENCRYPTED )?PRIVATE KEY( BLOCK)?-----`)},
+	{"AWS access key", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"GitHub token", regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{"Slack token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{"OpenAI key", regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{32,}`)},
+	{"credential assignment", regexp.MustCompile(`(?i)\b(api[_-]?key|secret|token|passw(or)?d)\b["']?\s*[:=]\s*["']?[A-Za-z0-9/+_.\-]{16,}`)},
+}
+
+// SecretFinding is a line that looks like it contains a credential
+type SecretFinding struct {
+	Path string
+	Line int
+	Rule string
+}
+
+// String renders the finding as path:line (rule)
+func (f SecretFinding) String() string {
+	return fmt.Sprintf("%s:%d (%s)", f.Path, f.Line, f.Rule)
+}
+
+// ScanSecrets returns the lines of content that match a secret rule. Binary content is skipped,
+// and lines carrying SecretAllowMarker are ignored.
+func ScanSecrets(path string, content []byte) []SecretFinding {
+	if len(content) > maxScannedFileSize || bytes.IndexByte(content, 0) != -1 {
+		return nil
+	}
+
+	var findings []SecretFinding
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), maxScannedFileSize)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if strings.Contains(text, SecretAllowMarker) {
+			continue
+		}
+		for _, rule := range secretRules {
+			if rule.pattern.MatchString(text) {
+				findings = append(findings, SecretFinding{Path: path, Line: line, Rule: rule.name})
+				break
+			}
+		}
+	}
+	return findings
+}
+
+// ScanPathForSecrets scans a config file, or every file below a config directory
+func ScanPathForSecrets(configPath string) ([]SecretFinding, error) {
+	var findings []SecretFinding
+	err := filepath.WalkDir(configPath, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		findings = append(findings, ScanSecrets(path, content)...)
+		return nil
+	})
+	return findings, err
+}
+
+// CheckSecrets aborts pushes of config paths that appear to contain credentials
+func (gc *GitHubClient) CheckSecrets(configPath string) error {
+	findings, err := ScanPathForSecrets(configPath)
+	if err != nil {
+		return errors.NewFileSystemError(constants.OpPush, "scan-secrets", err)
+	}
+
+	// Files left out by the include and exclude patterns are never pushed
+	pushed := findings[:0]
+	for _, finding := range findings {
+		if rel, err := filepath.Rel(configPath, finding.Path); err != nil || !gc.skipsFile(rel) {
+			pushed = append(pushed, finding)
+		}
+	}
+	return reportSecrets(pushed)
+}
+
+// reportSecrets lists the findings and returns a validation error when there are any
+func reportSecrets(findings []SecretFinding) error {
+	if len(findings) == 0 {
+		return nil
+	}
+
+	output := palantir.GetGlobalOutputHandler()
+	output.PrintError("Possible secrets found in %d place(s):", len(findings))
+	for i, finding := range findings {
+		if i == maxReportedSecrets {
+			output.PrintInfo("  ... and %d more", len(findings)-maxReportedSecrets)
+			break
+		}
+		output.PrintInfo("  • %s", finding)
+	}
+	output.PrintInfo("💡 Move credentials to environment variables or a secrets manager, or add '%s' to a line that is a false positive", SecretAllowMarker)
+
+	return errors.NewValidationError(constants.OpPush, "secret-scan",
+		fmt.Errorf("%d possible secret(s) found", len(findings)))
+}
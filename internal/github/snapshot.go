@@ -0,0 +1,86 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package github
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/system"
+)
+
+// snapshotDateLayout is the date form accepted in place of a revision, e.g. 2025-06-01
+const snapshotDateLayout = "2006-01-02"
+
+// ResolveRevision turns a commit, branch, tag or YYYY-MM-DD date into a commit of the local
+// clone. A date resolves to the last commit on the configured branch before that day ended.
+func (gc *GitHubClient) ResolveRevision(ctx context.Context, ref string) (string, error) {
+	if !gc.isValidGitRepository() {
+		return "", errors.NewConfigurationError(constants.OpConfig, "snapshot",
+			fmt.Errorf("no local clone at %s, run 'anvil config pull' first", gc.LocalPath))
+	}
+
+	// Older commits and dates need the history a shallow clone leaves out
+	if err := gc.ensureFullHistory(ctx); err != nil {
+		return "", err
+	}
+
+	if day, err := time.ParseInLocation(snapshotDateLayout, ref, time.Local); err == nil {
+		before := day.Add(24*time.Hour - time.Second).Format(time.RFC3339)
+		for _, branch := range []string{"origin/" + gc.Branch, "HEAD"} {
+			out, err := gc.git(ctx, "rev-list", "-1", "--before="+before, branch)
+			if commit := strings.TrimSpace(out); err == nil && commit != "" {
+				return commit, nil
+			}
+		}
+		return "", fmt.Errorf("no commit on %s before the end of %s", gc.Branch, ref)
+	}
+
+	out, err := gc.git(ctx, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil || strings.TrimSpace(out) == "" {
+		return "", fmt.Errorf("'%s' is not a commit, branch, tag or YYYY-MM-DD date in %s", ref, gc.LocalPath)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// ExportRevision writes the files of a commit into dest without touching the working tree
+func (gc *GitHubClient) ExportRevision(ctx context.Context, commit, dest string) error {
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return err
+	}
+
+	tarball, err := os.CreateTemp("", "anvil-snapshot-*.tar")
+	if err != nil {
+		return err
+	}
+	tarball.Close()
+	defer os.Remove(tarball.Name())
+
+	if _, err := gc.git(ctx, "archive", "--format=tar", "-o", tarball.Name(), commit); err != nil {
+		return fmt.Errorf("failed to export %s: %w", commit, err)
+	}
+	result, _ := system.RunCommandWithTimeout(ctx, "tar", "-xf", tarball.Name(), "-C", dest)
+	if !result.Success {
+		return fmt.Errorf("failed to extract %s: %s", commit, strings.TrimSpace(result.Error))
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package gitsettings applies the git section of settings.yaml beyond the user name and email:
+// values under extra_config, such as aliases or core.editor, and the global ignore file set as
+// core.excludesfile. Only the listed keys are written, the rest of the global git config is left alone.
+package gitsettings
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/system"
+)
+
+// Overridden in tests
+var (
+	runGit  = system.RunCommand
+	homeDir = system.GetHomeDir
+)
+
+// Setting is a global git config value settings.yaml asks for
+type Setting struct {
+	Key     string
+	Want    string
+	Current string // Empty when the key is not set
+}
+
+// InSync reports whether git already has the wanted value
+func (s Setting) InSync() bool {
+	return s.Current == s.Want
+}
+
+// Wanted returns the git config values the git section asks for
+func Wanted(git config.GitConfig) (map[string]string, error) {
+	if err := config.ValidateGitExtraConfig(git); err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]string, len(git.ExtraConfig)+1)
+	for key, value := range git.ExtraConfig {
+		wanted[key] = value
+	}
+	if git.GlobalIgnore != "" {
+		path, err := IgnoreFilePath(git)
+		if err != nil {
+			return nil, err
+		}
+		wanted[constants.GitExcludes] = path
+	}
+	return wanted, nil
+}
+
+// IgnoreFilePath returns the global ignore file with a leading ~ expanded, or "" when none is set
+func IgnoreFilePath(git config.GitConfig) (string, error) {
+	path := git.GlobalIgnore
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := homeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve %s: %w", path, err)
+		}
+		path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+	}
+	return path, nil
+}
+
+// Plan reads the current value of every wanted key, sorted by key
+func Plan(git config.GitConfig) ([]Setting, error) {
+	wanted, err := Wanted(git)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := make([]Setting, 0, len(wanted))
+	for key, want := range wanted {
+		current, err := current(key)
+		if err != nil {
+			return nil, err
+		}
+		settings = append(settings, Setting{Key: key, Want: want, Current: current})
+	}
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Key < settings[j].Key })
+	return settings, nil
+}
+
+// Drifted returns the settings git does not have yet
+func Drifted(settings []Setting) []Setting {
+	var drifted []Setting
+	for _, setting := range settings {
+		if !setting.InSync() {
+			drifted = append(drifted, setting)
+		}
+	}
+	return drifted
+}
+
+// Apply writes the settings git does not have yet and returns them
+func Apply(git config.GitConfig) ([]Setting, error) {
+	settings, err := Plan(git)
+	if err != nil {
+		return nil, err
+	}
+
+	drifted := Drifted(settings)
+	for _, setting := range drifted {
+		result, err := runGit(constants.GitCommand, constants.GitConfig, constants.GitGlobal, setting.Key, setting.Want)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set %s: %w", setting.Key, err)
+		}
+		if !result.Success {
+			return nil, fmt.Errorf("failed to set %s: %s", setting.Key, failure(result))
+		}
+	}
+	return drifted, nil
+}
+
+// IgnoreFileMissing reports whether global_ignore names a file that does not exist yet
+func IgnoreFileMissing(git config.GitConfig) bool {
+	if git.GlobalIgnore == "" {
+		return false
+	}
+	path, err := IgnoreFilePath(git)
+	if err != nil {
+		return true
+	}
+	_, err = os.Stat(path)
+	return os.IsNotExist(err)
+}
+
+// current returns the global value of key, "" when it is not set
+func current(key string) (string, error) {
+	result, err := runGit(constants.GitCommand, constants.GitConfig, constants.GitGlobal, constants.GitGet, key)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	if !result.Success {
+		// git config exits with 1 when the key is not set
+		if result.ExitCode == 1 {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read %s: %s", key, failure(result))
+	}
+	return strings.TrimSpace(result.Output), nil
+}
+
+// failure describes a failed git command by its output, or its exit status when it printed nothing
+func failure(result *system.CommandResult) string {
+	if output := strings.TrimSpace(result.Output); output != "" {
+		return output
+	}
+	return result.Error
+}
@@ -0,0 +1,98 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitsettings
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/system"
+)
+
+// fakeGit stands in for the global git config, keys set in it are returned by --get
+func fakeGit(t *testing.T, global map[string]string) {
+	t.Helper()
+	originalRun, originalHome := runGit, homeDir
+	t.Cleanup(func() { runGit, homeDir = originalRun, originalHome })
+
+	homeDir = func() (string, error) { return "/home/me", nil }
+	runGit = func(command string, args ...string) (*system.CommandResult, error) {
+		if args[2] == "--get" {
+			value, ok := global[args[3]]
+			if !ok {
+				return &system.CommandResult{ExitCode: 1, Error: "exit status 1"}, nil
+			}
+			return &system.CommandResult{Output: value + "\n", Success: true}, nil
+		}
+		global[args[2]] = args[3]
+		return &system.CommandResult{Success: true}, nil
+	}
+}
+
+func TestApply(t *testing.T) {
+	global := map[string]string{"alias.co": "checkout", "core.editor": "vim"}
+	fakeGit(t, global)
+
+	git := config.GitConfig{
+		ExtraConfig:  map[string]string{"alias.co": "checkout", "core.editor": "nvim", "alias.st": "status -sb"},
+		GlobalIgnore: "~/.gitignore_global",
+	}
+
+	settings, err := Plan(git)
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+	want := []Setting{
+		{Key: "alias.co", Want: "checkout", Current: "checkout"},
+		{Key: "alias.st", Want: "status -sb"},
+		{Key: "core.editor", Want: "nvim", Current: "vim"},
+		{Key: "core.excludesfile", Want: filepath.Join("/home/me", ".gitignore_global")},
+	}
+	if !reflect.DeepEqual(settings, want) {
+		t.Fatalf("Plan() = %+v, want %+v", settings, want)
+	}
+
+	applied, err := Apply(git)
+	if err != nil {
+		t.Fatalf("Apply() failed: %v", err)
+	}
+	if len(applied) != 3 || global["core.editor"] != "nvim" || global["alias.st"] != "status -sb" {
+		t.Errorf("Apply() applied %+v, global config is %v", applied, global)
+	}
+
+	if applied, err := Apply(git); err != nil || len(applied) != 0 {
+		t.Errorf("second Apply() = %+v, %v, want nothing to apply", applied, err)
+	}
+}
+
+func TestWantedRejectsInvalidKeys(t *testing.T) {
+	for _, git := range []config.GitConfig{
+		{ExtraConfig: map[string]string{"editor": "nvim"}},
+		{ExtraConfig: map[string]string{"alias co": "checkout"}},
+		{ExtraConfig: map[string]string{"core.excludesFile": "~/.ignore"}, GlobalIgnore: "~/.gitignore_global"},
+	} {
+		if _, err := Wanted(git); err == nil {
+			t.Errorf("Wanted(%v) succeeded, want an error", git.ExtraConfig)
+		}
+	}
+
+	if _, err := Wanted(config.GitConfig{ExtraConfig: map[string]string{"url.git@github.com:.insteadOf": "https://github.com/"}}); err != nil {
+		t.Errorf("Wanted() rejected a key with a subsection: %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package hosts keeps a block of entries from settings.yaml in /etc/hosts. The block sits
+// between marker comments so anvil only ever rewrites its own lines and leaves the rest
+// of the file as the user or the system wrote it.
+package hosts
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/system"
+)
+
+// Markers delimiting the managed block
+const (
+	BeginMarker = "# BEGIN anvil managed hosts"
+	EndMarker   = "# END anvil managed hosts"
+)
+
+// Overridden in tests
+var (
+	hostsPath     = "/etc/hosts"
+	runPrivileged = system.RunInteractiveCommand
+)
+
+// Path returns the hosts file anvil manages
+func Path() string {
+	return hostsPath
+}
+
+// Validate checks every entry has a valid IP address and at least one host name. Names
+// can't hold whitespace, line breaks or comments, which would add lines to the hosts file.
+func Validate(entries []config.HostEntry) error {
+	var problems []string
+	for i, entry := range entries {
+		if net.ParseIP(entry.IP) == nil {
+			problems = append(problems, fmt.Sprintf("hosts[%d]: invalid ip '%s'", i, entry.IP))
+		}
+		if len(entry.Names) == 0 {
+			problems = append(problems, fmt.Sprintf("hosts[%d]: no names for %s", i, entry.IP))
+		}
+		for _, name := range entry.Names {
+			if name == "" || strings.ContainsAny(name, " \t\r\n#") {
+				problems = append(problems, fmt.Sprintf("hosts[%d]: invalid name '%s'", i, name))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Render returns the managed block for entries, markers included, or "" when there are none
+func Render(entries []config.HostEntry) string {
+	if len(entries) == 0 {
+		return ""
+	}
+	var block strings.Builder
+	block.WriteString(BeginMarker + "\n")
+	for _, entry := range entries {
+		block.WriteString(fmt.Sprintf("%s\t%s\n", entry.IP, strings.Join(entry.Names, " ")))
+	}
+	block.WriteString(EndMarker + "\n")
+	return block.String()
+}
+
+// Block returns the managed block found in content, or "" when there is none
+func Block(content string) string {
+	start, end, found := blockBounds(content)
+	if !found {
+		return ""
+	}
+	return content[start:end]
+}
+
+// Merge replaces the managed block in content with block, appending it when content has
+// none. An empty block removes the managed block.
+func Merge(content, block string) string {
+	start, end, found := blockBounds(content)
+	if found {
+		before, after := content[:start], content[end:]
+		if block == "" && after == "" && strings.HasSuffix(before, "\n\n") {
+			// Drop the blank line added in front of the block when it was appended
+			before = before[:len(before)-1]
+		}
+		return before + block + after
+	}
+
+	if block == "" {
+		return content
+	}
+	if content != "" {
+		if !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		content += "\n"
+	}
+	return content + block
+}
+
+// blockBounds locates the managed block, end includes the newline after the end marker
+func blockBounds(content string) (start, end int, found bool) {
+	start = strings.Index(content, BeginMarker)
+	if start < 0 {
+		return 0, 0, false
+	}
+	offset := strings.Index(content[start:], EndMarker)
+	if offset < 0 {
+		return 0, 0, false
+	}
+	end = start + offset + len(EndMarker)
+	if end < len(content) && content[end] == '\n' {
+		end++
+	}
+	return start, end, true
+}
+
+// Plan returns the current hosts file and the content it should have for entries
+func Plan(entries []config.HostEntry) (current, next string, err error) {
+	if err := Validate(entries); err != nil {
+		return "", "", err
+	}
+	data, err := os.ReadFile(hostsPath)
+	if err != nil && !os.IsNotExist(err) {
+		return "", "", fmt.Errorf("failed to read %s: %w", hostsPath, err)
+	}
+	current = string(data)
+	return current, Merge(current, Render(entries)), nil
+}
+
+// InSync reports whether the hosts file already holds exactly the block for entries
+func InSync(entries []config.HostEntry) (bool, error) {
+	current, next, err := Plan(entries)
+	if err != nil {
+		return false, err
+	}
+	return current == next, nil
+}
+
+// Apply writes the managed block for entries, removing it when there are none.
+// It reports whether the hosts file changed.
+func Apply(entries []config.HostEntry) (bool, error) {
+	current, next, err := Plan(entries)
+	if err != nil {
+		return false, err
+	}
+	if current == next {
+		return false, nil
+	}
+	return true, write(next)
+}
+
+// Remove deletes the managed block and reports whether there was one
+func Remove() (bool, error) {
+	return Apply(nil)
+}
+
+// write replaces the hosts file, falling back to sudo when it is not writable by the user
+func write(content string) error {
+	err := os.WriteFile(hostsPath, []byte(content), 0644)
+	if err == nil || !os.IsPermission(err) {
+		return err
+	}
+
+	temp, err := os.CreateTemp("", "anvil-hosts-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.WriteString(content); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+
+	// cp keeps the owner and mode of the existing hosts file
+	if err := runPrivileged("sudo", "cp", temp.Name(), hostsPath); err != nil {
+		return fmt.Errorf("failed to update %s with sudo: %w", hostsPath, err)
+	}
+	return nil
+}
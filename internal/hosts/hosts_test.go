@@ -0,0 +1,118 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hosts
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/0xjuanma/anvil/internal/config"
+)
+
+const systemHosts = "127.0.0.1\tlocalhost\n::1\tlocalhost\n"
+
+func TestMerge(t *testing.T) {
+	block := Render([]config.HostEntry{{IP: "127.0.0.1", Names: []string{"api.local", "web.local"}}})
+	want := BeginMarker + "\n127.0.0.1\tapi.local web.local\n" + EndMarker + "\n"
+	if block != want {
+		t.Fatalf("Render() = %q, want %q", block, want)
+	}
+
+	merged := Merge(systemHosts, block)
+	if merged != systemHosts+"\n"+block {
+		t.Errorf("Merge() appended %q", merged)
+	}
+	if Block(merged) != block {
+		t.Errorf("Block() = %q, want %q", Block(merged), block)
+	}
+
+	// Replacing keeps the lines around the block untouched
+	edited := merged + "10.0.0.5\tnas\n"
+	replaced := Merge(edited, Render([]config.HostEntry{{IP: "10.0.0.9", Names: []string{"db.local"}}}))
+	if !strings.HasPrefix(replaced, systemHosts) || !strings.HasSuffix(replaced, "10.0.0.9\tdb.local\n"+EndMarker+"\n10.0.0.5\tnas\n") {
+		t.Errorf("Merge() replaced block as %q", replaced)
+	}
+
+	if removed := Merge(merged, ""); removed != systemHosts {
+		t.Errorf("Merge() removed block as %q, want original file", removed)
+	}
+	if unchanged := Merge(systemHosts, ""); unchanged != systemHosts {
+		t.Errorf("Merge() without block changed content to %q", unchanged)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := []config.HostEntry{{IP: "127.0.0.1", Names: []string{"api.local"}}, {IP: "::1", Names: []string{"api.local"}}}
+	if err := Validate(valid); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	err := Validate([]config.HostEntry{{IP: "localhost", Names: []string{"api.local"}}, {IP: "10.0.0.1"}, {IP: "10.0.0.2", Names: []string{"a b"}}})
+	if err == nil {
+		t.Fatal("Validate() accepted invalid entries")
+	}
+	for _, want := range []string{"invalid ip 'localhost'", "no names for 10.0.0.1", "invalid name 'a b'"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+
+	// Line breaks would let a synced entry add lines to the file written with sudo
+	for _, name := range []string{"api.local\nbank.com", "api.local\rbank.com"} {
+		if err := Validate([]config.HostEntry{{IP: "127.0.0.1", Names: []string{name}}}); err == nil {
+			t.Errorf("Validate() accepted the name %q", name)
+		}
+	}
+}
+
+func TestApplyAndRemove(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts")
+	if err := os.WriteFile(path, []byte(systemHosts), 0644); err != nil {
+		t.Fatal(err)
+	}
+	originalPath, originalRun := hostsPath, runPrivileged
+	hostsPath = path
+	runPrivileged = func(command string, args ...string) error {
+		t.Errorf("sudo used for a writable file: %s %v", command, args)
+		return nil
+	}
+	t.Cleanup(func() { hostsPath, runPrivileged = originalPath, originalRun })
+
+	entries := []config.HostEntry{{IP: "127.0.0.1", Names: []string{"api.local"}}}
+	if inSync, _ := InSync(entries); inSync {
+		t.Error("InSync() before apply = true")
+	}
+	if changed, err := Apply(entries); err != nil || !changed {
+		t.Fatalf("Apply() = %v, %v, want a change", changed, err)
+	}
+	if changed, _ := Apply(entries); changed {
+		t.Error("second Apply() changed the file")
+	}
+	if inSync, err := InSync(entries); err != nil || !inSync {
+		t.Errorf("InSync() after apply = %v, %v", inSync, err)
+	}
+
+	if changed, err := Remove(); err != nil || !changed {
+		t.Fatalf("Remove() = %v, %v, want a change", changed, err)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != systemHosts {
+		t.Errorf("hosts after remove = %q, want original", data)
+	}
+}
@@ -0,0 +1,143 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package i18n looks up user-facing messages in the catalog of the active language.
+// Catalogs are flat YAML maps from message keys to fmt format strings, one file per
+// language under locales/, with English as the reference every key falls back to.
+package i18n
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultLanguage is used when neither settings nor the environment pick a supported language
+const DefaultLanguage = "en"
+
+//go:embed locales/*.yaml
+var localeFiles embed.FS
+
+var (
+	catalogs     map[string]map[string]string
+	catalogsOnce sync.Once
+
+	activeMutex sync.RWMutex
+	active      = DefaultLanguage
+)
+
+// loadCatalogs parses every embedded locale once, keyed by language code
+func loadCatalogs() map[string]map[string]string {
+	catalogsOnce.Do(func() {
+		catalogs = make(map[string]map[string]string)
+		entries, err := localeFiles.ReadDir("locales")
+		if err != nil {
+			return
+		}
+		for _, entry := range entries {
+			data, err := localeFiles.ReadFile(path.Join("locales", entry.Name()))
+			if err != nil {
+				continue
+			}
+			messages := make(map[string]string)
+			if err := yaml.Unmarshal(data, &messages); err != nil {
+				continue
+			}
+			catalogs[strings.TrimSuffix(entry.Name(), ".yaml")] = messages
+		}
+	})
+	return catalogs
+}
+
+// Languages returns the supported language codes, sorted
+func Languages() []string {
+	var languages []string
+	for language := range loadCatalogs() {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// SetLanguage makes language, e.g. "es", the active one. An empty language detects it from
+// LC_ALL, LC_MESSAGES and LANG, falling back to English when none is supported.
+func SetLanguage(language string) error {
+	if language == "" {
+		language = Detect()
+	}
+	language = strings.ToLower(language)
+	if _, ok := loadCatalogs()[language]; !ok {
+		return fmt.Errorf("unsupported language '%s', use one of: %s", language, strings.Join(Languages(), ", "))
+	}
+
+	activeMutex.Lock()
+	defer activeMutex.Unlock()
+	active = language
+	return nil
+}
+
+// Language returns the active language code
+func Language() string {
+	activeMutex.RLock()
+	defer activeMutex.RUnlock()
+	return active
+}
+
+// Detect returns the first supported language named by the locale environment variables
+func Detect() string {
+	for _, variable := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if language := parseLocale(os.Getenv(variable)); language != "" {
+			if _, ok := loadCatalogs()[language]; ok {
+				return language
+			}
+			// POSIX stops at the first variable set, an unsupported locale means English
+			return DefaultLanguage
+		}
+	}
+	return DefaultLanguage
+}
+
+// parseLocale extracts the language from a locale such as "es_ES.UTF-8" or "es-MX"
+func parseLocale(locale string) string {
+	fields := strings.FieldsFunc(locale, func(r rune) bool { return r == '_' || r == '-' || r == '.' || r == '@' })
+	if len(fields) == 0 || fields[0] == "C" || fields[0] == "POSIX" {
+		return ""
+	}
+	return strings.ToLower(fields[0])
+}
+
+// T returns the message for key in the active language, formatted with args. Keys missing
+// from the active catalog fall back to English, and unknown keys are returned as is.
+// Without args the message is returned unformatted, so it can be passed on as a format string.
+func T(key string, args ...interface{}) string {
+	all := loadCatalogs()
+	message, ok := all[Language()][key]
+	if !ok {
+		if message, ok = all[DefaultLanguage][key]; !ok {
+			message = key
+		}
+	}
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
@@ -0,0 +1,190 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package i18n
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestParseLocale(t *testing.T) {
+	tests := map[string]string{
+		"es_ES.UTF-8": "es",
+		"es-MX":       "es",
+		"EN_us":       "en",
+		"de@euro":     "de",
+		"C":           "",
+		"POSIX":       "",
+		"":            "",
+	}
+	for locale, want := range tests {
+		if got := parseLocale(locale); got != want {
+			t.Errorf("parseLocale(%q) = %q, want %q", locale, got, want)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name                    string
+		lcAll, lcMessages, lang string
+		want                    string
+	}{
+		{"lang only", "", "", "es_ES.UTF-8", "es"},
+		{"lc_all wins", "en_US.UTF-8", "", "es_ES.UTF-8", "en"},
+		{"lc_messages before lang", "", "es_MX", "en_US", "es"},
+		{"unsupported is english", "", "", "fr_FR.UTF-8", "en"},
+		{"c locale is skipped", "C", "", "es_ES", "es"},
+		{"nothing set", "", "", "", "en"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LC_ALL", tt.lcAll)
+			t.Setenv("LC_MESSAGES", tt.lcMessages)
+			t.Setenv("LANG", tt.lang)
+			if got := Detect(); got != tt.want {
+				t.Errorf("Detect() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetLanguage(t *testing.T) {
+	defer SetLanguage(DefaultLanguage)
+
+	if err := SetLanguage("fr"); err == nil {
+		t.Error("expected an error for an unsupported language")
+	}
+	if err := SetLanguage("ES"); err != nil {
+		t.Fatalf("SetLanguage(ES) failed: %v", err)
+	}
+	if Language() != "es" {
+		t.Errorf("Language() = %q, want es", Language())
+	}
+}
+
+func TestT(t *testing.T) {
+	defer SetLanguage(DefaultLanguage)
+
+	if err := SetLanguage("en"); err != nil {
+		t.Fatal(err)
+	}
+	if got := T("doctor.check.title", "git-config"); got != "Running Check: git-config" {
+		t.Errorf("unexpected english message: %q", got)
+	}
+	if got := T("doctor.summary.overall"); !strings.Contains(got, "%d") {
+		t.Errorf("expected the raw format string without args, got %q", got)
+	}
+	if got := T("no.such.key"); got != "no.such.key" {
+		t.Errorf("unknown keys should be returned as is, got %q", got)
+	}
+
+	if err := SetLanguage("es"); err != nil {
+		t.Fatal(err)
+	}
+	if got := T("pull.complete"); got != "¡Pull terminado!" {
+		t.Errorf("unexpected spanish message: %q", got)
+	}
+}
+
+var verbPattern = regexp.MustCompile(`%[-+# 0-9.]*([a-zA-Z%])`)
+
+// verbs lists the conversions in a format string, ignoring widths that translations may adjust
+func verbs(format string) string {
+	var found []string
+	for _, match := range verbPattern.FindAllStringSubmatch(format, -1) {
+		found = append(found, match[1])
+	}
+	return strings.Join(found, " ")
+}
+
+// TestLocalesMatchEnglish keeps every translation in step with the English catalog,
+// so a message never silently loses or reorders its arguments
+func TestLocalesMatchEnglish(t *testing.T) {
+	all := loadCatalogs()
+	english := all[DefaultLanguage]
+	if len(english) == 0 {
+		t.Fatal("english catalog is empty")
+	}
+
+	for language, messages := range all {
+		if language == DefaultLanguage {
+			continue
+		}
+		for key, message := range messages {
+			reference, ok := english[key]
+			if !ok {
+				t.Errorf("%s: key %q is not in the english catalog", language, key)
+				continue
+			}
+			want := verbs(reference)
+			if got := verbs(message); got != want {
+				t.Errorf("%s: %q uses verbs %q, english uses %q", language, key, got, want)
+			}
+		}
+		for key := range english {
+			if _, ok := messages[key]; !ok {
+				t.Errorf("%s: missing translation for %q", language, key)
+			}
+		}
+	}
+}
+
+// TestKeysExist makes sure every key looked up in the code is in the english catalog
+func TestKeysExist(t *testing.T) {
+	english := loadCatalogs()[DefaultLanguage]
+	lookup := regexp.MustCompile(`i18n\.T\("([^"]+)"`)
+
+	for _, root := range []string{"../../cmd", "../../internal"} {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+				return err
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			for _, match := range lookup.FindAllStringSubmatch(string(data), -1) {
+				// Keys built at runtime, like "doctor.category."+category, are checked below
+				if strings.HasSuffix(match[1], ".") {
+					continue
+				}
+				if _, ok := english[match[1]]; !ok {
+					t.Errorf("%s: key %q is not in the english catalog", path, match[1])
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, category := range []string{"environment", "dependencies", "configuration", "connectivity", "apps"} {
+		if _, ok := english["doctor.category."+category]; !ok {
+			t.Errorf("missing category title for %q", category)
+		}
+	}
+	for _, label := range []string{"general", "platform", "validation", "configuration", "installation", "network", "filesystem"} {
+		if _, ok := english["errors.type."+label]; !ok {
+			t.Errorf("missing error type label for %q", label)
+		}
+	}
+}
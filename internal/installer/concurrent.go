@@ -24,25 +24,56 @@ import (
 	"context"
 	"fmt"
 	"runtime"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/0xjuanma/anvil/internal/audit"
 	"github.com/0xjuanma/anvil/internal/brew"
 	"github.com/0xjuanma/anvil/internal/config"
 	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/ecosystem"
 	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/events"
+	"github.com/0xjuanma/anvil/internal/pkgmanager"
 	"github.com/0xjuanma/anvil/internal/terminal/charm"
 	"github.com/0xjuanma/palantir"
 )
 
 // InstallationResult represents the result of a single tool installation
 type InstallationResult struct {
-	ToolName  string
-	Success   bool
-	Error     error
-	Duration  time.Duration
-	StartTime time.Time
-	EndTime   time.Time
+	ToolName         string
+	Success          bool
+	Error            error
+	Duration         time.Duration
+	StartTime        time.Time
+	EndTime          time.Time
+	Aborted          bool // Not attempted because the failure handler stopped the run
+	AlreadyAvailable bool // Found on the machine, nothing was installed
+}
+
+// ResultStatus is the outcome of a single tool in a concurrent run
+type ResultStatus string
+
+const (
+	ResultInstalled        ResultStatus = "installed"
+	ResultAlreadyAvailable ResultStatus = "already-available"
+	ResultFailed           ResultStatus = "failed"
+	ResultNotAttempted     ResultStatus = "not-attempted"
+)
+
+// Status returns the outcome of the tool. Dry runs report tools that would install as installed.
+func (r InstallationResult) Status() ResultStatus {
+	switch {
+	case r.Aborted:
+		return ResultNotAttempted
+	case !r.Success:
+		return ResultFailed
+	case r.AlreadyAvailable:
+		return ResultAlreadyAvailable
+	default:
+		return ResultInstalled
+	}
 }
 
 // InstallationStats provides statistics about the installation process
@@ -55,8 +86,25 @@ type InstallationStats struct {
 	MaxDuration     time.Duration
 	MinDuration     time.Duration
 	ConcurrentJobs  int
+	InstalledTools  []string             // Tools that installed successfully or were already available, in completion order
+	AbortedTools    int                  // Tools never attempted because the run was stopped
+	Results         []InstallationResult // Per-tool outcome, in completion order
+}
+
+// NewlyInstalled returns the tools the run installed, leaving out tools that were already available
+func (s *InstallationStats) NewlyInstalled() []string {
+	var tools []string
+	for _, result := range s.Results {
+		if result.Status() == ResultInstalled {
+			tools = append(tools, result.ToolName)
+		}
+	}
+	return tools
 }
 
+// FailureHandler is called for each failed tool and returns true to stop installing the remaining tools
+type FailureHandler func(tool string, err error) bool
+
 // ConcurrentInstaller handles concurrent tool installation
 type ConcurrentInstaller struct {
 	maxWorkers    int
@@ -64,6 +112,10 @@ type ConcurrentInstaller struct {
 	dryRun        bool
 	timeout       time.Duration
 	retryAttempts int
+	onFailure     FailureHandler
+	failureMu     sync.Mutex          // Serializes failure handler calls so prompts never overlap
+	waitFor       map[string][]string // Tools that must finish before a tool starts
+	after         map[string][]string // Tools that must install successfully before a tool starts
 }
 
 // NewConcurrentInstaller creates a new concurrent installer
@@ -94,30 +146,92 @@ func (ci *ConcurrentInstaller) InstallTools(ctx context.Context, tools []string)
 	toolChan := make(chan string, len(tools))
 	resultChan := make(chan InstallationResult, len(tools))
 
+	// Closed when the failure handler stops the run, tools still queued are reported as aborted
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+	halt := func() { stopOnce.Do(func() { close(stop) }) }
+
 	// Start worker goroutines
 	var wg sync.WaitGroup
 	for i := 0; i < ci.maxWorkers; i++ {
 		wg.Add(1)
-		go ci.worker(ctx, i+1, toolChan, resultChan, &wg)
+		go ci.worker(ctx, i+1, toolChan, resultChan, stop, halt, &wg)
 	}
 
-	// Send tools to workers
-	for _, tool := range tools {
-		toolChan <- tool
+	// Send tools to workers as soon as the tools they wait for have finished
+	results := make([]InstallationResult, 0, len(tools))
+	finished := make(map[string]InstallationResult, len(tools))
+	queued := make(map[string]bool, len(tools))
+	inFlight := 0
+	record := func(result InstallationResult) {
+		results = append(results, result)
+		finished[result.ToolName] = result
+		ci.printProgress(result, len(results), len(tools))
+	}
+	dispatch := func() {
+		for progress := true; progress; {
+			progress = false
+			for _, tool := range tools {
+				if queued[tool] || !ci.ready(tool, tools, finished) {
+					continue
+				}
+				queued[tool] = true
+				progress = true
+				if dep, failed := ci.failedPrerequisite(tool, finished); failed {
+					now := time.Now()
+					record(InstallationResult{
+						ToolName:  tool,
+						Error:     fmt.Errorf("not attempted, %s did not install", dep),
+						StartTime: now,
+						EndTime:   now,
+						Aborted:   true,
+					})
+					continue
+				}
+				toolChan <- tool
+				inFlight++
+			}
+		}
 	}
-	close(toolChan)
 
-	// Wait for all workers to complete
+	workersDone := make(chan struct{})
 	go func() {
 		wg.Wait()
-		close(resultChan)
+		close(workersDone)
 	}()
 
 	// Collect results
-	results := make([]InstallationResult, 0, len(tools))
+	dispatch()
+collect:
+	for inFlight > 0 {
+		select {
+		case result := <-resultChan:
+			inFlight--
+			record(result)
+			dispatch()
+		case <-workersDone:
+			break collect
+		}
+	}
+	close(toolChan)
+	<-workersDone
+	close(resultChan)
 	for result := range resultChan {
-		results = append(results, result)
-		ci.printProgress(result, len(results), len(tools))
+		record(result)
+	}
+
+	// Tools still waiting were never reachable and are reported as not attempted
+	for _, tool := range tools {
+		if !queued[tool] {
+			now := time.Now()
+			record(InstallationResult{
+				ToolName:  tool,
+				Error:     fmt.Errorf("not attempted, waiting on tools that never finished"),
+				StartTime: now,
+				EndTime:   now,
+				Aborted:   true,
+			})
+		}
 	}
 
 	// Calculate statistics
@@ -126,7 +240,11 @@ func (ci *ConcurrentInstaller) InstallTools(ctx context.Context, tools []string)
 	// Print summary
 	ci.printSummary(stats, results)
 
-	// Return error if any installations failed
+	// Return error if any installations failed or were never attempted
+	if stats.AbortedTools > 0 {
+		return stats, errors.NewInstallationError(constants.OpInstall, "concurrent",
+			fmt.Errorf("failed to install %d of %d tools, %d not attempted", stats.FailedTools, stats.TotalTools, stats.AbortedTools))
+	}
 	if stats.FailedTools > 0 {
 		return stats, errors.NewInstallationError(constants.OpInstall, "concurrent",
 			fmt.Errorf("failed to install %d of %d tools", stats.FailedTools, stats.TotalTools))
@@ -135,11 +253,50 @@ func (ci *ConcurrentInstaller) InstallTools(ctx context.Context, tools []string)
 	return stats, nil
 }
 
+// ready reports whether every tool a tool waits for has finished, tools outside the run are not waited on
+func (ci *ConcurrentInstaller) ready(tool string, tools []string, finished map[string]InstallationResult) bool {
+	for _, dep := range ci.waitFor[tool] {
+		if _, done := finished[dep]; done {
+			continue
+		}
+		for _, other := range tools {
+			if other == dep {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// failedPrerequisite returns the first after tool of a tool that finished without installing
+func (ci *ConcurrentInstaller) failedPrerequisite(tool string, finished map[string]InstallationResult) (string, bool) {
+	for _, dep := range ci.after[tool] {
+		if result, done := finished[dep]; done && !result.Success {
+			return dep, true
+		}
+	}
+	return "", false
+}
+
 // worker processes tools from the channel
-func (ci *ConcurrentInstaller) worker(ctx context.Context, workerID int, toolChan <-chan string, resultChan chan<- InstallationResult, wg *sync.WaitGroup) {
+func (ci *ConcurrentInstaller) worker(ctx context.Context, workerID int, toolChan <-chan string, resultChan chan<- InstallationResult, stop <-chan struct{}, halt func(), wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	for tool := range toolChan {
+		// Drain the queue once the run was stopped
+		select {
+		case <-stop:
+			resultChan <- InstallationResult{
+				ToolName:  tool,
+				Error:     fmt.Errorf("not attempted, installation stopped"),
+				StartTime: time.Now(),
+				EndTime:   time.Now(),
+				Aborted:   true,
+			}
+			continue
+		default:
+		}
+
 		// Check for context cancellation
 		select {
 		case <-ctx.Done():
@@ -155,11 +312,34 @@ func (ci *ConcurrentInstaller) worker(ctx context.Context, workerID int, toolCha
 		}
 
 		// Install the tool with timeout
+		if charm.IsPlain() {
+			charm.PlainEvent("start", fmt.Sprintf("%s (worker %d)", tool, workerID), time.Time{})
+		}
 		result := ci.installWithTimeout(ctx, tool, workerID)
+		if !result.Success && ci.stopAfterFailure(result, stop) {
+			halt()
+		}
 		resultChan <- result
 	}
 }
 
+// stopAfterFailure asks the failure handler whether a failed tool stops the run.
+// Calls are serialized and skipped once the run is already stopping.
+func (ci *ConcurrentInstaller) stopAfterFailure(result InstallationResult, stop <-chan struct{}) bool {
+	if ci.onFailure == nil {
+		return false
+	}
+
+	ci.failureMu.Lock()
+	defer ci.failureMu.Unlock()
+	select {
+	case <-stop:
+		return false
+	default:
+	}
+	return ci.onFailure(result.ToolName, result.Error)
+}
+
 // installWithTimeout installs a single tool with timeout and retry logic
 func (ci *ConcurrentInstaller) installWithTimeout(ctx context.Context, tool string, workerID int) InstallationResult {
 	startTime := time.Now()
@@ -169,29 +349,36 @@ func (ci *ConcurrentInstaller) installWithTimeout(ctx context.Context, tool stri
 	defer cancel()
 
 	var lastErr error
+	attempts := 0
 
 	// Retry logic
 	for attempt := 0; attempt <= ci.retryAttempts; attempt++ {
+		attempts++
 		if attempt > 0 {
 			ci.output.PrintInfo("Worker %d: Retrying %s (attempt %d/%d)", workerID, tool, attempt+1, ci.retryAttempts+1)
 			time.Sleep(time.Second * time.Duration(attempt)) // Exponential backoff
 		}
 
 		// Use unified availability checking logic (ensures consistency with other installation methods)
-		if brew.IsApplicationAvailable(tool) {
+		if pkgmanager.IsAvailable(tool) {
 			ci.output.PrintAlreadyAvailable("Worker %d: %s is already available", workerID, tool)
 			return InstallationResult{
-				ToolName:  tool,
-				Success:   true,
-				StartTime: startTime,
-				EndTime:   time.Now(),
-				Duration:  time.Since(startTime),
+				ToolName:         tool,
+				Success:          true,
+				AlreadyAvailable: true,
+				StartTime:        startTime,
+				EndTime:          time.Now(),
+				Duration:         time.Since(startTime),
 			}
 		}
 
 		// Handle dry-run consistently with other installation methods
 		if ci.dryRun {
 			ci.output.PrintInfo("Worker %d: Would install %s", workerID, tool)
+			if hook, ok := HookFor(tool); ok && len(HookPhases(hook)) > 0 {
+				ci.output.PrintInfo("Worker %d: Would run %s hooks for %s", workerID, strings.Join(HookPhases(hook), " and "), tool)
+			}
+			audit.Record("install", "install-package", tool, "")
 			return InstallationResult{
 				ToolName:  tool,
 				Success:   true,
@@ -201,11 +388,18 @@ func (ci *ConcurrentInstaller) installWithTimeout(ctx context.Context, tool stri
 			}
 		}
 
-		// Install the tool
-		err := ci.installSingleTool(toolCtx, tool, workerID)
+		// Install the tool between its install hooks
+		err := InstallWithHooks(tool, func(phase, line string) {
+			ci.output.PrintInfo("Worker %d: %s %s │ %s", workerID, tool, phase, line)
+		}, func(hookErr error) {
+			ci.output.PrintWarning("Worker %d: %v, continuing", workerID, hookErr)
+		}, func() error {
+			return ci.installSingleTool(toolCtx, tool, workerID)
+		})
 		if err == nil {
 			endTime := time.Now()
 			ci.output.PrintSuccess(fmt.Sprintf("Worker %d: %s installed successfully", workerID, tool))
+			events.Publish(events.Installed, tool, "")
 			return InstallationResult{
 				ToolName:  tool,
 				Success:   true,
@@ -220,6 +414,7 @@ func (ci *ConcurrentInstaller) installWithTimeout(ctx context.Context, tool stri
 		// Check if context was cancelled
 		select {
 		case <-toolCtx.Done():
+			events.Publish(events.Failed, tool, "")
 			return InstallationResult{
 				ToolName:  tool,
 				Success:   false,
@@ -230,13 +425,19 @@ func (ci *ConcurrentInstaller) installWithTimeout(ctx context.Context, tool stri
 			}
 		default:
 		}
+
+		// Retrying can't fix a missing package or denied access
+		if errors.IsPermanent(err) {
+			break
+		}
 	}
 
 	// All retries failed
+	events.Publish(events.Failed, tool, "")
 	return InstallationResult{
 		ToolName:  tool,
 		Success:   false,
-		Error:     fmt.Errorf("failed to install %s after %d attempts: %w", tool, ci.retryAttempts+1, lastErr),
+		Error:     fmt.Errorf("failed to install %s after %d attempts: %w", tool, attempts, lastErr),
 		StartTime: startTime,
 		EndTime:   time.Now(),
 		Duration:  time.Since(startTime),
@@ -245,45 +446,41 @@ func (ci *ConcurrentInstaller) installWithTimeout(ctx context.Context, tool stri
 
 // installSingleTool installs a single tool (similar to the original logic)
 func (ci *ConcurrentInstaller) installSingleTool(ctx context.Context, tool string, workerID int) error {
+	// Strip any cask:/formula: annotation, brew still receives the annotated entry
+	name, _ := brew.ParsePackageName(tool)
+
+	// Tools from language ecosystems are installed with go, cargo, npm or pipx
+	if spec, ok := ecosystem.Lookup(name); ok {
+		return ecosystem.Install(name, spec)
+	}
+
 	// Check if source is configured for this app (user explicitly configured it)
-	sourceURL, exists, sourceErr := GetSourceURL(tool)
+	sourceURL, exists, sourceErr := GetSourceURL(name)
 	if sourceErr != nil {
 		ci.output.PrintWarning("Worker %d: Failed to check source URL for %s: %v", workerID, tool, sourceErr)
-		// Fall back to brew if we can't check source
-		return brew.InstallPackageDirectly(tool)
+		// Fall back to the package manager if we can't check source
+		return pkgmanager.Install(tool)
 	}
 
 	// If source exists, try it first (user explicitly configured it)
 	if exists && sourceURL != "" {
 		ci.output.PrintInfo("Worker %d: Installing %s from configured source", workerID, tool)
-		if err := InstallFromSource(tool, sourceURL); err != nil {
-			// Source installation failed, fall back to brew
-			ci.output.PrintInfo("Worker %d: Source installation failed, falling back to brew for %s", workerID, tool)
-			return brew.InstallPackageDirectly(tool)
+		if err := InstallFromSource(name, sourceURL); err != nil {
+			// Source installation failed, fall back to the package manager
+			ci.output.PrintInfo("Worker %d: Source installation failed, falling back to the package manager for %s", workerID, tool)
+			return pkgmanager.Install(tool)
 		}
 		// Source installation succeeded, continue with post-install steps
 	} else {
-		// No source configured, use brew (default for majority of apps)
-		if err := brew.InstallPackageDirectly(tool); err != nil {
+		// No source configured, use the package manager (default for majority of apps)
+		if err := pkgmanager.Install(tool); err != nil {
 			return errors.NewInstallationError(constants.OpInstall, tool, err)
 		}
 	}
 
-	// Handle special cases for specific tools
-	if tool == "zsh" {
-		spinner := charm.NewLineSpinner(fmt.Sprintf("Worker %d: Installing Oh My Zsh", workerID))
-		spinner.Start()
-		ohMyZshScript := `sh -c "$(curl -fsSL https://raw.github.com/ohmyzsh/ohmyzsh/master/tools/install.sh)" "" --unattended`
-		if err := ci.runPostInstallScript(ohMyZshScript); err != nil {
-			spinner.Warning(fmt.Sprintf("Worker %d: Oh My Zsh setup skipped", workerID))
-		} else {
-			spinner.Success(fmt.Sprintf("Worker %d: Oh My Zsh installed", workerID))
-		}
-	}
-
 	// Handle config check for git
-	if tool == "git" {
-		if err := ci.checkToolConfiguration(tool); err != nil {
+	if name == "git" {
+		if err := ci.checkToolConfiguration(name); err != nil {
 			ci.output.PrintWarning("Worker %d: Configuration check failed for %s: %v", workerID, tool, err)
 		}
 	}
@@ -291,14 +488,6 @@ func (ci *ConcurrentInstaller) installSingleTool(ctx context.Context, tool strin
 	return nil
 }
 
-// runPostInstallScript runs a post-install script for a tool
-func (ci *ConcurrentInstaller) runPostInstallScript(script string) error {
-	// For now, just provide instructions to the user
-	ci.output.PrintInfo("To complete setup, run:")
-	ci.output.PrintInfo("  %s", script)
-	return nil
-}
-
 // checkToolConfiguration checks if a tool is properly configured
 func (ci *ConcurrentInstaller) checkToolConfiguration(toolName string) error {
 	switch toolName {
@@ -323,8 +512,21 @@ func (ci *ConcurrentInstaller) checkGitConfiguration() error {
 
 // printProgress prints installation progress
 func (ci *ConcurrentInstaller) printProgress(result InstallationResult, completed, total int) {
+	if charm.IsPlain() {
+		event := "done"
+		if result.Aborted {
+			event = "skip"
+		} else if !result.Success {
+			event = "fail"
+		}
+		charm.PlainEvent(event, fmt.Sprintf("%s [%d/%d]", result.ToolName, completed, total), result.StartTime)
+		return
+	}
+
 	status := "✓"
-	if !result.Success {
+	if result.Aborted {
+		status = "⊘"
+	} else if !result.Success {
 		status = "✗"
 	}
 
@@ -338,14 +540,20 @@ func (ci *ConcurrentInstaller) calculateStats(results []InstallationResult, star
 		TotalTools:     len(results),
 		TotalDuration:  time.Since(startTime),
 		ConcurrentJobs: ci.maxWorkers,
+		Results:        results,
 	}
 
 	var durations []time.Duration
 	for _, result := range results {
+		if result.Aborted {
+			stats.AbortedTools++
+			continue
+		}
 		durations = append(durations, result.Duration)
 
 		if result.Success {
 			stats.SuccessfulTools++
+			stats.InstalledTools = append(stats.InstalledTools, result.ToolName)
 		} else {
 			stats.FailedTools++
 		}
@@ -387,12 +595,16 @@ func (ci *ConcurrentInstaller) printSummary(stats *InstallationStats, results []
 	if stats.FailedTools > 0 {
 		ci.output.PrintWarning("Failed installations:")
 		for _, result := range results {
-			if !result.Success {
+			if !result.Success && !result.Aborted {
 				ci.output.PrintError("  • %s: %v", result.ToolName, result.Error)
 			}
 		}
 	}
 
+	if stats.AbortedTools > 0 {
+		ci.output.PrintWarning("%d tools were not attempted", stats.AbortedTools)
+	}
+
 	// Performance comparison estimate
 	if stats.TotalTools > 1 {
 		estimatedSerialTime := stats.AverageDuration * time.Duration(stats.TotalTools)
@@ -406,6 +618,18 @@ func (ci *ConcurrentInstaller) SetTimeout(timeout time.Duration) {
 	ci.timeout = timeout
 }
 
+// SetFailureHandler sets the handler deciding whether a failed tool stops the remaining installs
+func (ci *ConcurrentInstaller) SetFailureHandler(handler FailureHandler) {
+	ci.onFailure = handler
+}
+
+// SetOrder makes tools wait for others before starting. Tools wait for every tool in waitFor to finish,
+// and are not attempted when a tool in after fails.
+func (ci *ConcurrentInstaller) SetOrder(waitFor, after map[string][]string) {
+	ci.waitFor = waitFor
+	ci.after = after
+}
+
 // SetRetryAttempts sets the number of retry attempts for failed installations
 func (ci *ConcurrentInstaller) SetRetryAttempts(attempts int) {
 	ci.retryAttempts = attempts
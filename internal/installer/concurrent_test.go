@@ -19,6 +19,7 @@ package installer
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"runtime"
 	"testing"
 	"time"
@@ -218,6 +219,66 @@ func TestConcurrentInstaller_Timeout(t *testing.T) {
 	}
 }
 
+func TestConcurrentInstaller_FailureHandlerStops(t *testing.T) {
+	mockOutput := &MockOutputHandler{}
+	installer := NewConcurrentInstaller(1, mockOutput, false)
+	installer.SetRetryAttempts(0)
+
+	var failed []string
+	installer.SetFailureHandler(func(tool string, err error) bool {
+		failed = append(failed, tool)
+		return true
+	})
+
+	tools := []string{"nonexistent-tool-1", "nonexistent-tool-2", "nonexistent-tool-3"}
+	stats, err := installer.InstallTools(context.Background(), tools)
+
+	if err == nil {
+		t.Error("Expected error when the failure handler stops the run")
+	}
+	if len(failed) != 1 {
+		t.Errorf("Expected the handler to run once, got %v", failed)
+	}
+	if stats.FailedTools != 1 || stats.AbortedTools != 2 {
+		t.Errorf("Expected 1 failed and 2 aborted tools, got %d failed and %d aborted", stats.FailedTools, stats.AbortedTools)
+	}
+}
+
+func TestConcurrentInstaller_SetOrder(t *testing.T) {
+	mockOutput := &MockOutputHandler{}
+	installer := NewConcurrentInstaller(4, mockOutput, true)
+	installer.SetOrder(map[string][]string{"omz": {"zsh"}, "compose": {"docker", "omz"}}, nil)
+
+	stats, err := installer.InstallTools(context.Background(), []string{"compose", "omz", "docker", "zsh"})
+	if err != nil {
+		t.Fatalf("Expected no error for dry run, got %v", err)
+	}
+
+	position := make(map[string]int)
+	for i, tool := range stats.InstalledTools {
+		position[tool] = i
+	}
+	if len(position) != 4 || position["zsh"] > position["omz"] || position["omz"] > position["compose"] || position["docker"] > position["compose"] {
+		t.Errorf("InstalledTools = %v, want zsh before omz and docker and omz before compose", stats.InstalledTools)
+	}
+}
+
+func TestConcurrentInstaller_SetOrderSkipsDependents(t *testing.T) {
+	mockOutput := &MockOutputHandler{}
+	installer := NewConcurrentInstaller(2, mockOutput, false)
+	installer.SetRetryAttempts(0)
+	deps := map[string][]string{"nonexistent-plugin": {"nonexistent-tool"}}
+	installer.SetOrder(deps, deps)
+
+	stats, err := installer.InstallTools(context.Background(), []string{"nonexistent-plugin", "nonexistent-tool"})
+	if err == nil {
+		t.Error("Expected error when a prerequisite fails")
+	}
+	if stats.FailedTools != 1 || stats.AbortedTools != 1 {
+		t.Errorf("Expected 1 failed and 1 aborted tool, got %d failed and %d aborted", stats.FailedTools, stats.AbortedTools)
+	}
+}
+
 func TestConcurrentInstaller_SetTimeout(t *testing.T) {
 	mockOutput := &MockOutputHandler{}
 	installer := NewConcurrentInstaller(2, mockOutput, false)
@@ -302,6 +363,29 @@ func TestInstallationStats(t *testing.T) {
 	}
 }
 
+func TestInstallationStats_NewlyInstalled(t *testing.T) {
+	installer := NewConcurrentInstaller(2, &MockOutputHandler{}, false)
+	results := []InstallationResult{
+		{ToolName: "git", Success: true},
+		{ToolName: "jq", Success: true, AlreadyAvailable: true},
+		{ToolName: "slack", Error: fmt.Errorf("installation failed")},
+		{ToolName: "docker", Error: fmt.Errorf("not attempted"), Aborted: true},
+		{ToolName: "curl", Success: true},
+	}
+
+	stats := installer.calculateStats(results, time.Now())
+
+	want := []ResultStatus{ResultInstalled, ResultAlreadyAvailable, ResultFailed, ResultNotAttempted, ResultInstalled}
+	for i, result := range stats.Results {
+		if result.Status() != want[i] {
+			t.Errorf("%s: expected status %s, got %s", result.ToolName, want[i], result.Status())
+		}
+	}
+	if got := stats.NewlyInstalled(); !reflect.DeepEqual(got, []string{"git", "curl"}) {
+		t.Errorf("Expected newly installed [git curl], got %v", got)
+	}
+}
+
 func TestConcurrentInstaller_calculateStats(t *testing.T) {
 	mockOutput := &MockOutputHandler{}
 	installer := NewConcurrentInstaller(2, mockOutput, false)
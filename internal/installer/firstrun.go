@@ -0,0 +1,164 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package installer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/brew"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/system"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+)
+
+// quarantineAttribute is the extended attribute Gatekeeper checks before an app's first launch
+const quarantineAttribute = "com.apple.quarantine"
+
+// firstRunLaunchWait is how long a launched app gets to register before it is asked to quit
+var firstRunLaunchWait = 5 * time.Second
+
+// FirstRunResult records what the first-run steps did for one installed cask
+type FirstRunResult struct {
+	App           string
+	Bundle        string
+	BundleID      string
+	Dequarantined bool
+	Launched      bool
+	Problems      []string
+}
+
+// OK reports whether every step succeeded and the bundle was verified
+func (r FirstRunResult) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// Details lists what was done, e.g. "com.microsoft.VSCode, quarantine removed, launched"
+func (r FirstRunResult) Details() string {
+	var parts []string
+	if r.BundleID != "" {
+		parts = append(parts, r.BundleID)
+	}
+	if r.Dequarantined {
+		parts = append(parts, "quarantine removed")
+	}
+	if r.Launched {
+		parts = append(parts, "launched")
+	}
+	parts = append(parts, r.Problems...)
+	return strings.Join(parts, ", ")
+}
+
+// FirstRunSteps returns the first-run steps configured for a tool, which may carry a cask: annotation
+func FirstRunSteps(tool string) (config.FirstRunConfig, bool) {
+	if steps, ok := config.GetFirstRunConfig(tool); ok {
+		return steps, true
+	}
+	name, _ := brew.ParsePackageName(tool)
+	return config.GetFirstRunConfig(name)
+}
+
+// RunFirstRun runs the first-run steps for a cask that was just installed: removing the
+// quarantine flag with consent, reading the bundle identifier and launching the app once
+func RunFirstRun(tool string, steps config.FirstRunConfig) FirstRunResult {
+	result := FirstRunResult{App: tool}
+	if !system.IsMacOS() {
+		result.Problems = append(result.Problems, "first-run steps need macOS")
+		return result
+	}
+
+	name, _ := brew.ParsePackageName(tool)
+	bundles, err := brew.CaskAppBundles(name)
+	if err != nil {
+		result.Problems = append(result.Problems, err.Error())
+		return result
+	}
+	result.Bundle = bundles[0]
+	appName := filepath.Base(result.Bundle)
+
+	if steps.RemoveQuarantine && isQuarantined(result.Bundle) {
+		message := fmt.Sprintf("Remove the Gatekeeper quarantine flag from %s? Only do this for apps you trust.", appName)
+		if !charm.Confirm(charm.ConfirmQuarantine, message) {
+			result.Problems = append(result.Problems, "quarantine kept")
+		} else if err := removeQuarantine(result.Bundle); err != nil {
+			result.Problems = append(result.Problems, err.Error())
+		} else {
+			result.Dequarantined = true
+		}
+	}
+
+	result.BundleID, err = bundleIdentifier(result.Bundle)
+	switch {
+	case err != nil:
+		result.Problems = append(result.Problems, err.Error())
+	case steps.BundleID != "" && result.BundleID != steps.BundleID:
+		result.Problems = append(result.Problems, fmt.Sprintf("expected bundle id %s", steps.BundleID))
+	}
+
+	if steps.Launch {
+		if err := launchOnce(result.Bundle, result.BundleID); err != nil {
+			result.Problems = append(result.Problems, err.Error())
+		} else {
+			result.Launched = true
+		}
+	}
+
+	return result
+}
+
+// isQuarantined reports whether a bundle still carries the quarantine attribute
+func isQuarantined(bundle string) bool {
+	result, _ := system.RunCommand("xattr", "-p", quarantineAttribute, bundle)
+	return result.Success
+}
+
+// removeQuarantine clears the quarantine attribute from a bundle and everything inside it
+func removeQuarantine(bundle string) error {
+	result, _ := system.RunCommand("xattr", "-dr", quarantineAttribute, bundle)
+	if !result.Success {
+		return fmt.Errorf("failed to remove quarantine: %s", strings.TrimSpace(result.Error))
+	}
+	return nil
+}
+
+// bundleIdentifier reads a bundle's identifier from Spotlight metadata
+func bundleIdentifier(bundle string) (string, error) {
+	result, _ := system.RunCommand("mdls", "-name", "kMDItemCFBundleIdentifier", "-raw", bundle)
+	id := strings.TrimSpace(result.Output)
+	if !result.Success || id == "" || id == "(null)" {
+		return "", fmt.Errorf("no bundle id found in Spotlight metadata")
+	}
+	return id, nil
+}
+
+// launchOnce opens an app hidden in the background, gives it time to register and quits it
+func launchOnce(bundle, bundleID string) error {
+	result, _ := system.RunCommand("open", "-g", "-j", bundle)
+	if !result.Success {
+		return fmt.Errorf("launch failed: %s", strings.TrimSpace(result.Error))
+	}
+	time.Sleep(firstRunLaunchWait)
+
+	target := fmt.Sprintf("app %q", strings.TrimSuffix(filepath.Base(bundle), ".app"))
+	if bundleID != "" {
+		target = fmt.Sprintf("application id %q", bundleID)
+	}
+	system.RunCommand("osascript", "-e", fmt.Sprintf("tell %s to quit", target))
+	return nil
+}
@@ -0,0 +1,145 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package installer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/0xjuanma/anvil/internal/brew"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/errors"
+	"github.com/0xjuanma/anvil/internal/rcguard"
+	"github.com/0xjuanma/anvil/internal/system"
+)
+
+// Hook phases, named like their settings.yaml keys
+const (
+	HookPreInstall  = "pre_install"
+	HookPostInstall = "post_install"
+)
+
+// HookFor returns the install hooks configured for a tool, which may carry a cask: annotation
+func HookFor(tool string) (config.HookConfig, bool) {
+	if hook, ok := config.GetHookConfig(tool); ok {
+		return hook, true
+	}
+	name, _ := brew.ParsePackageName(tool)
+	return config.GetHookConfig(name)
+}
+
+// HookPhases lists the phases a hook has scripts for, in the order they run
+func HookPhases(hook config.HookConfig) []string {
+	var phases []string
+	if hook.PreInstall != "" {
+		phases = append(phases, HookPreInstall)
+	}
+	if hook.PostInstall != "" {
+		phases = append(phases, HookPostInstall)
+	}
+	return phases
+}
+
+// InstallWithHooks runs install between the pre_install and post_install hooks of a tool.
+// Hook output is passed to progress line by line and failures of warn hooks go to warn.
+// A failing abort hook fails the install, before install runs for pre_install.
+func InstallWithHooks(tool string, progress func(phase, line string), warn func(err error), install func() error) error {
+	hook, ok := HookFor(tool)
+	if !ok {
+		return install()
+	}
+
+	if err := runHookPhase(tool, HookPreInstall, hook, progress, warn); err != nil {
+		return err
+	}
+	if err := install(); err != nil {
+		return err
+	}
+	if hook.PostInstall == "" {
+		return nil
+	}
+
+	// Setup scripts commonly append to shell rc files, watch them like source installs
+	guard := rcguard.Begin()
+	err := runHookPhase(tool, HookPostInstall, hook, progress, warn)
+	changes, guardErr := guard.Finish()
+	reportRCChanges(tool, changes, guardErr)
+	return err
+}
+
+// runHookPhase runs one phase of a hook and applies its failure policy
+func runHookPhase(tool, phase string, hook config.HookConfig, progress func(phase, line string), warn func(err error)) error {
+	err := RunHook(tool, phase, hook, func(line string) { progress(phase, line) })
+	if err == nil {
+		return nil
+	}
+	if hook.Aborts() {
+		// Hook scripts are settings, retrying the install would run them again for nothing
+		return errors.NewConfigurationError(constants.OpInstall, tool, err)
+	}
+	warn(err)
+	return nil
+}
+
+// RunHook runs the script of one hook phase with sh -c. The script sees ANVIL_APP, ANVIL_PACKAGE,
+// ANVIL_HOOK and ANVIL_PLATFORM along with the hook's env, and is stopped after its timeout.
+func RunHook(tool, phase string, hook config.HookConfig, progress func(line string)) error {
+	script := hook.PreInstall
+	if phase == HookPostInstall {
+		script = hook.PostInstall
+	}
+	if script == "" {
+		return nil
+	}
+
+	timeout := hook.TimeoutDuration()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	result, _ := system.RunCommandWithEnvAndProgress(ctx, "", hookEnv(tool, phase, hook), progress, "sh", "-c", script)
+	switch {
+	case ctx.Err() != nil:
+		return fmt.Errorf("%s hook for %s did not finish within %s", phase, tool, timeout)
+	case !result.Success:
+		return fmt.Errorf("%s hook for %s exited with code %d", phase, tool, result.ExitCode)
+	}
+	return nil
+}
+
+// hookEnv returns the environment added for a hook script, the hook's own env last so it can override
+func hookEnv(tool, phase string, hook config.HookConfig) []string {
+	name, _ := brew.ParsePackageName(tool)
+	env := []string{
+		"ANVIL_APP=" + name,
+		"ANVIL_PACKAGE=" + tool,
+		"ANVIL_HOOK=" + phase,
+		"ANVIL_PLATFORM=" + system.Platform(),
+	}
+
+	keys := make([]string, 0, len(hook.Env))
+	for key := range hook.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		env = append(env, key+"="+os.ExpandEnv(hook.Env[key]))
+	}
+	return env
+}
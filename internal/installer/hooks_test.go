@@ -0,0 +1,100 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package installer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/0xjuanma/anvil/internal/config"
+)
+
+func TestRunHook(t *testing.T) {
+	t.Setenv("HOOK_TEST_DIR", "/opt/tools")
+	hook := config.HookConfig{
+		PreInstall:  `echo "$ANVIL_HOOK $ANVIL_APP $ANVIL_PACKAGE $TOOL_DIR"; echo done`,
+		PostInstall: "exit 3",
+		Env:         map[string]string{"TOOL_DIR": "$HOOK_TEST_DIR/bin"},
+	}
+
+	var lines []string
+	if err := RunHook("cask:docker", HookPreInstall, hook, func(line string) { lines = append(lines, line) }); err != nil {
+		t.Fatalf("RunHook failed: %v", err)
+	}
+	if want := "pre_install docker cask:docker /opt/tools/bin|done"; strings.Join(lines, "|") != want {
+		t.Errorf("streamed %q, want %q", strings.Join(lines, "|"), want)
+	}
+
+	err := RunHook("docker", HookPostInstall, hook, func(string) {})
+	if err == nil || !strings.Contains(err.Error(), "exited with code 3") {
+		t.Errorf("RunHook(post_install) = %v, want exit code 3", err)
+	}
+
+	hook.PreInstall = "sleep 5"
+	hook.Timeout = "100ms"
+	err = RunHook("docker", HookPreInstall, hook, func(string) {})
+	if err == nil || !strings.Contains(err.Error(), "did not finish within 100ms") {
+		t.Errorf("RunHook(sleep) = %v, want timeout", err)
+	}
+}
+
+func TestRunHookPhasePolicy(t *testing.T) {
+	hook := config.HookConfig{PreInstall: "exit 1"}
+
+	var warned error
+	if err := runHookPhase("jq", HookPreInstall, hook, func(string, string) {}, func(err error) { warned = err }); err != nil {
+		t.Errorf("warn policy returned %v, want nil", err)
+	}
+	if warned == nil {
+		t.Error("warn policy did not report the failure")
+	}
+
+	hook.OnFailure = config.HookFailureAbort
+	if err := runHookPhase("jq", HookPreInstall, hook, func(string, string) {}, func(error) {}); err == nil {
+		t.Error("abort policy returned nil, want the hook error")
+	}
+}
+
+func TestInstallWithHooksGuardsPostInstall(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	os.MkdirAll(filepath.Join(home, ".anvil"), 0755)
+	settings := "version: \"1\"\nhooks:\n  jq:\n    post_install: echo 'export PATH=\"/opt/jq/bin:$PATH\"' >> \"$HOME/.zshrc\"\n"
+	if err := os.WriteFile(filepath.Join(home, ".anvil", "settings.yaml"), []byte(settings), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config.InvalidateConfigCache()
+	t.Cleanup(config.InvalidateConfigCache)
+
+	zshrc := filepath.Join(home, ".zshrc")
+	existing := "export PATH=\"/opt/jq/bin:$PATH\"\n"
+	os.WriteFile(zshrc, []byte(existing), 0644)
+
+	installed := false
+	err := InstallWithHooks("jq", func(string, string) {}, func(err error) { t.Errorf("hook warned: %v", err) }, func() error {
+		installed = true
+		return nil
+	})
+	if err != nil || !installed {
+		t.Fatalf("InstallWithHooks() = %v, installed = %v", err, installed)
+	}
+	if data, _ := os.ReadFile(zshrc); string(data) != existing {
+		t.Errorf("post_install duplicate was kept in .zshrc:\n%s", data)
+	}
+}
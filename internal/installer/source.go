@@ -29,9 +29,11 @@ import (
 	"time"
 
 	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/rcguard"
 	"github.com/0xjuanma/anvil/internal/system"
 	"github.com/0xjuanma/anvil/internal/terminal/charm"
 	"github.com/0xjuanma/anvil/internal/utils"
+	"github.com/0xjuanma/palantir"
 )
 
 // InstallFromSource installs an application from a source URL or command
@@ -66,19 +68,54 @@ func installFromCommand(appName, command string) error {
 		return fmt.Errorf("invalid command: %w", err)
 	}
 
+	system.ApplyNetworkEnv(cmd)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
-	if err := cmd.Run(); err != nil {
+	// Install scripts commonly append to shell rc files, watch what they add
+	guard := rcguard.Begin()
+	runErr := cmd.Run()
+	changes, guardErr := guard.Finish()
+
+	if runErr != nil {
 		spinner.Error(fmt.Sprintf("Failed to install %s", appName))
-		return fmt.Errorf("command execution failed: %w", err)
+		reportRCChanges(appName, changes, guardErr)
+		return fmt.Errorf("command execution failed: %w", runErr)
 	}
 
 	spinner.Success(fmt.Sprintf("%s installed successfully", appName))
+	reportRCChanges(appName, changes, guardErr)
 	return nil
 }
 
+// reportRCChanges lists the lines an install script added to shell rc files and the
+// repeated blocks that were removed again
+func reportRCChanges(appName string, changes []rcguard.Change, guardErr error) {
+	o := palantir.GetGlobalOutputHandler()
+	if guardErr != nil {
+		o.PrintWarning("Could not check shell rc files after installing %s: %v", appName, guardErr)
+	}
+
+	homeDir, _ := system.GetHomeDir()
+	for _, change := range changes {
+		path := change.Path
+		if rel, err := filepath.Rel(homeDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = filepath.Join("~", rel)
+		}
+
+		if len(change.Added) > 0 {
+			o.PrintInfo("%s added %d line(s) to %s:", appName, len(change.Added), path)
+			for _, line := range change.Added {
+				o.PrintInfo("    + %s", line)
+			}
+		}
+		if len(change.Duplicates) > 0 {
+			o.PrintWarning("%s repeated %d line(s) already in %s, the duplicates were removed", appName, len(change.Duplicates), path)
+		}
+	}
+}
+
 // parseShellCommand parses a shell command string into an exec.Cmd
 func parseShellCommand(command string) (*exec.Cmd, error) {
 	trimmed := strings.TrimSpace(command)
@@ -140,7 +177,9 @@ func installFromURL(appName, sourceURL string) error {
 	spinner := charm.NewDotsSpinner(fmt.Sprintf("Downloading %s from source", appName))
 	spinner.Start()
 
-	downloadedFile, err := downloadFile(sourceURL, appName)
+	downloadedFile, err := downloadFile(sourceURL, appName, func(written, total int64) {
+		spinner.SetDetail(formatDownloadProgress(written, total))
+	})
 	if err != nil {
 		spinner.Error(fmt.Sprintf("Failed to download %s", appName))
 		return fmt.Errorf("failed to download %s: %w", appName, err)
@@ -150,17 +189,25 @@ func installFromURL(appName, sourceURL string) error {
 	spinner = charm.NewDotsSpinner(fmt.Sprintf("Installing %s", appName))
 	spinner.Start()
 
-	if err := installDownloadedFile(downloadedFile, appName); err != nil {
+	// Installer packages and scripts may run post-install steps that edit shell rc files
+	guard := rcguard.Begin()
+	installErr := installDownloadedFile(downloadedFile, appName)
+	changes, guardErr := guard.Finish()
+
+	if installErr != nil {
 		spinner.Error(fmt.Sprintf("Failed to install %s", appName))
-		return fmt.Errorf("failed to install %s: %w", appName, err)
+		reportRCChanges(appName, changes, guardErr)
+		return fmt.Errorf("failed to install %s: %w", appName, installErr)
 	}
 
 	spinner.Success(fmt.Sprintf("%s installed successfully", appName))
+	reportRCChanges(appName, changes, guardErr)
 	return nil
 }
 
-// downloadFile downloads a file from URL to a temporary location
-func downloadFile(fileURL, appName string) (string, error) {
+// downloadFile downloads a file from URL to a temporary location, reporting the bytes
+// written so far to progress. total is -1 when the server does not send a length.
+func downloadFile(fileURL, appName string, progress func(written, total int64)) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
 	defer cancel()
 
@@ -171,7 +218,7 @@ func downloadFile(fileURL, appName string) (string, error) {
 
 	req.Header.Set("User-Agent", "anvil-cli/1.0")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := system.HTTPClient().Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to download file: %w", err)
 	}
@@ -196,7 +243,8 @@ func downloadFile(fileURL, appName string) (string, error) {
 	}
 	defer file.Close()
 
-	if _, err := io.Copy(file, resp.Body); err != nil {
+	counter := &downloadCounter{total: resp.ContentLength, progress: progress}
+	if _, err := io.Copy(io.MultiWriter(file, counter), resp.Body); err != nil {
 		os.Remove(filePath)
 		return "", fmt.Errorf("failed to write file: %w", err)
 	}
@@ -204,6 +252,30 @@ func downloadFile(fileURL, appName string) (string, error) {
 	return filePath, nil
 }
 
+// downloadCounter reports download progress as bytes pass through it
+type downloadCounter struct {
+	written  int64
+	total    int64
+	progress func(written, total int64)
+}
+
+// Write implements io.Writer
+func (c *downloadCounter) Write(p []byte) (int, error) {
+	c.written += int64(len(p))
+	if c.progress != nil {
+		c.progress(c.written, c.total)
+	}
+	return len(p), nil
+}
+
+// formatDownloadProgress describes download progress, e.g. "12.0 MB of 48.0 MB (25%)"
+func formatDownloadProgress(written, total int64) string {
+	if total <= 0 {
+		return utils.FormatSize(written)
+	}
+	return fmt.Sprintf("%s of %s (%d%%)", utils.FormatSize(written), utils.FormatSize(total), written*100/total)
+}
+
 // getFileNameFromURL extracts filename from URL or uses app name
 func getFileNameFromURL(fileURL, appName string) string {
 	parsedURL, err := url.Parse(fileURL)
@@ -0,0 +1,343 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package pkgmanager installs packages with the package manager of the machine: Homebrew on
+// macOS, and apt or dnf on Linux distributions that ship them. Homebrew stays in use on Linux
+// machines that already have it, and the 'package_manager' setting overrides the detection.
+package pkgmanager
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/brew"
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/ecosystem"
+	"github.com/0xjuanma/anvil/internal/system"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+)
+
+// Package manager names accepted by the 'package_manager' setting
+const (
+	Auto = "auto"
+	Brew = "brew"
+	Apt  = "apt"
+	Dnf  = "dnf"
+)
+
+// Names lists the valid values of the 'package_manager' setting
+var Names = []string{Auto, Brew, Apt, Dnf}
+
+// Manager installs and finds packages with one package manager
+type Manager interface {
+	Name() string
+	Ensure() error                 // Makes the manager ready to install, Homebrew is installed when missing
+	Install(entry string) error    // Installs a group or installed_apps entry
+	IsInstalled(entry string) bool // Whether the manager has the package installed
+	Exists(entry string) bool      // Whether the manager can install the package
+	SearchCommand(name string) string
+}
+
+// Overridden in tests
+var (
+	osReleasePath = "/etc/os-release"
+	platform      = system.Platform
+	commandExists = system.CommandExists
+	brewInstalled = brew.IsBrewInstalled
+	runCommand    = system.RunCommandWithEnv
+	isRoot        = func() bool { return os.Geteuid() == 0 }
+	confirm       = charm.Confirm
+)
+
+// distroManagers maps os-release IDs to the package manager of the distribution
+var distroManagers = map[string]string{
+	"debian":    Apt,
+	"ubuntu":    Apt,
+	"linuxmint": Apt,
+	"pop":       Apt,
+	"raspbian":  Apt,
+	"fedora":    Dnf,
+	"rhel":      Dnf,
+	"centos":    Dnf,
+	"rocky":     Dnf,
+	"almalinux": Dnf,
+	"amzn":      Dnf,
+}
+
+var (
+	currentOnce    sync.Once
+	currentManager Manager
+	currentErr     error
+)
+
+// Current returns the package manager for this machine, detected once per run
+func Current() (Manager, error) {
+	currentOnce.Do(func() {
+		configured := Auto
+		if cfg, err := config.LoadConfig(); err == nil && cfg.PackageManager != "" {
+			configured = cfg.PackageManager
+		}
+		currentManager, currentErr = Detect(configured)
+	})
+	return currentManager, currentErr
+}
+
+// Detect picks the package manager named by configured, or the machine's when it is auto
+func Detect(configured string) (Manager, error) {
+	if configured != "" && configured != Auto {
+		return New(configured)
+	}
+	if platform() != "linux" || brewInstalled() {
+		return New(Brew)
+	}
+
+	if data, err := os.ReadFile(osReleasePath); err == nil {
+		if name := DistroManager(string(data)); name != "" {
+			return New(name)
+		}
+	}
+	// Unknown distributions still work when they ship apt or dnf
+	switch {
+	case commandExists(constants.AptGetCommand):
+		return New(Apt)
+	case commandExists(constants.DnfCommand):
+		return New(Dnf)
+	}
+	return New(Brew)
+}
+
+// New returns the named package manager
+func New(name string) (Manager, error) {
+	switch name {
+	case Brew:
+		return brewManager{}, nil
+	case Apt:
+		return &systemManager{
+			name:    Apt,
+			command: constants.AptGetCommand,
+			install: []string{"install", "-y"},
+			refresh: []string{"update"},
+			query:   []string{constants.DpkgCommand, "-s"},
+			info:    []string{"apt-cache", "show"},
+			search:  "apt-cache search",
+			env:     []string{"DEBIAN_FRONTEND=noninteractive"},
+		}, nil
+	case Dnf:
+		return &systemManager{
+			name:    Dnf,
+			command: constants.DnfCommand,
+			install: []string{"install", "-y"},
+			query:   []string{constants.RpmCommand, "-q"},
+			info:    []string{constants.DnfCommand, "info"},
+			search:  "dnf search",
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown package manager '%s' (use %s)", name, strings.Join(Names, ", "))
+	}
+}
+
+// DistroManager returns the package manager for the distribution an os-release file describes,
+// matching ID first and then ID_LIKE, or "" when it is not known
+func DistroManager(osRelease string) string {
+	var ids []string
+	fields := make(map[string]string)
+	for _, line := range strings.Split(osRelease, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if ok {
+			fields[key] = strings.Trim(value, `"'`)
+		}
+	}
+	ids = append(ids, fields["ID"])
+	ids = append(ids, strings.Fields(fields["ID_LIKE"])...)
+
+	for _, id := range ids {
+		if name, ok := distroManagers[strings.ToLower(id)]; ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// IsAvailable reports whether a tool is already on this machine, found by Homebrew's checks
+// or installed by the current package manager. Ecosystem tools are checked by their installer.
+func IsAvailable(entry string) bool {
+	if tool, ok := ecosystem.Lookup(entry); ok {
+		return ecosystem.IsInstalled(entry, tool)
+	}
+	if brew.IsApplicationAvailable(entry) {
+		return true
+	}
+	manager, err := Current()
+	return err == nil && manager.Name() != Brew && manager.IsInstalled(entry)
+}
+
+// InstalledVersion returns the installed version of a tool as reported by its installer or
+// Homebrew, "" when it is not installed or its version is unknown
+func InstalledVersion(entry string) string {
+	if tool, ok := ecosystem.Lookup(entry); ok {
+		return ecosystem.InstalledVersion(entry, tool)
+	}
+	name, _ := brew.ParsePackageName(entry)
+	return brew.GetInstalledVersions()[name]
+}
+
+// Install installs an entry with the current package manager
+func Install(entry string) error {
+	manager, err := Current()
+	if err != nil {
+		return err
+	}
+	return manager.Install(entry)
+}
+
+// brewManager installs with Homebrew
+type brewManager struct{}
+
+func (brewManager) Name() string                     { return Brew }
+func (brewManager) Ensure() error                    { return brew.EnsureBrewIsInstalled() }
+func (brewManager) Install(entry string) error       { return brew.InstallPackageDirectly(entry) }
+func (brewManager) IsInstalled(entry string) bool    { return brew.IsPackageInstalled(entry) }
+func (brewManager) Exists(entry string) bool         { return brew.PackageExists(entry) }
+func (brewManager) SearchCommand(name string) string { return "brew search " + name }
+
+// systemManager installs with a distribution package manager such as apt or dnf, using sudo
+// unless anvil runs as root. Installs run one at a time since these managers lock their database.
+type systemManager struct {
+	name    string
+	command string
+	install []string // Arguments before the package name
+	refresh []string // Refreshes the package index once per run before the first install, if set
+	query   []string // Command and arguments that succeed when the package is installed
+	info    []string // Command and arguments that succeed when the package can be installed
+	search  string
+	env     []string
+
+	mu        sync.Mutex
+	refreshed bool
+	approved  bool // Whether running the manager through sudo was confirmed this run
+}
+
+func (m *systemManager) Name() string                     { return m.name }
+func (m *systemManager) SearchCommand(name string) string { return m.search + " " + name }
+
+// Ensure checks the manager's command is available
+func (m *systemManager) Ensure() error {
+	if !commandExists(m.command) {
+		return fmt.Errorf("%s not found, set package_manager in %s to the package manager of this machine", m.command, constants.ANVIL_CONFIG_FILE)
+	}
+	return nil
+}
+
+// Install installs the package named by an entry. Casks and App Store apps only exist for Homebrew.
+func (m *systemManager) Install(entry string) error {
+	name, packageType := brew.ParsePackageName(entry)
+	if packageType == brew.PackageTypeCask || packageType == brew.PackageTypeAppStore {
+		return fmt.Errorf("%s is not a %s package, tag it with 'platforms: [darwin]' or set a source for it", entry, m.name)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !isRoot() && !m.approved {
+		if !confirm(charm.ConfirmPrivileged, fmt.Sprintf("Install packages with 'sudo %s'? This may ask for your password.", m.command)) {
+			return fmt.Errorf("installing %s with %s declined", name, m.name)
+		}
+		m.approved = true
+	}
+
+	spinner := charm.NewDotsSpinner(fmt.Sprintf("Installing %s with %s", name, m.name))
+	spinner.Start()
+	if len(m.refresh) > 0 && !m.refreshed {
+		spinner.SetDetail("refreshing package lists")
+		if err := m.run(m.refresh...); err != nil {
+			spinner.Error(fmt.Sprintf("Failed to refresh %s package lists", m.name))
+			return err
+		}
+		m.refreshed = true
+		spinner.SetDetail("")
+	}
+	if err := m.run(append(slices.Clone(m.install), name)...); err != nil {
+		spinner.Error(fmt.Sprintf("Failed to install %s", name))
+		return err
+	}
+	spinner.Success(fmt.Sprintf("%s installed successfully", name))
+	return nil
+}
+
+// IsInstalled asks the package database whether the package is installed
+func (m *systemManager) IsInstalled(entry string) bool {
+	return m.succeeds(m.query, entry)
+}
+
+// Exists asks the package index whether the package can be installed, casks never can
+func (m *systemManager) Exists(entry string) bool {
+	if _, packageType := brew.ParsePackageName(entry); packageType == brew.PackageTypeCask || packageType == brew.PackageTypeAppStore {
+		return false
+	}
+	return m.succeeds(m.info, entry)
+}
+
+// succeeds runs a read-only query command for an entry's package and reports whether it succeeded
+func (m *systemManager) succeeds(query []string, entry string) bool {
+	name, _ := brew.ParsePackageName(entry)
+	result, err := runCommand(context.Background(), "", nil, query[0], append(slices.Clone(query[1:]), name)...)
+	return err == nil && result.Success
+}
+
+// run runs the manager with args, through sudo unless anvil runs as root. sudo resets the
+// environment, so the manager's variables are passed through env on its command line.
+func (m *systemManager) run(args ...string) error {
+	command, env := m.command, m.env
+	if !isRoot() {
+		prefix := []string{m.command}
+		if len(env) > 0 {
+			prefix = append(append([]string{"env"}, env...), m.command)
+		}
+		args = append(prefix, args...)
+		command, env = "sudo", nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	result, err := runCommand(ctx, "", env, command, args...)
+	if err != nil {
+		return fmt.Errorf("failed to run %s: %w", m.command, err)
+	}
+	if !result.Success {
+		return fmt.Errorf("%s %s failed: %s", m.command, strings.Join(args, " "), lastLines(result.Output, 3))
+	}
+	return nil
+}
+
+// lastLines returns the last n non-empty lines of output, where package managers print the error
+func lastLines(output string, n int) string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "; ")
+}
@@ -0,0 +1,166 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkgmanager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/0xjuanma/anvil/internal/system"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+)
+
+func TestDistroManager(t *testing.T) {
+	tests := []struct {
+		osRelease string
+		want      string
+	}{
+		{"NAME=\"Ubuntu\"\nID=ubuntu\nID_LIKE=debian\n", Apt},
+		{"ID=fedora\nVERSION_ID=40\n", Dnf},
+		{"ID=\"rocky\"\nID_LIKE=\"rhel centos fedora\"\n", Dnf},
+		{"ID=elementary\nID_LIKE=\"ubuntu debian\"\n", Apt},
+		{"ID=arch\n", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := DistroManager(tt.osRelease); got != tt.want {
+			t.Errorf("DistroManager(%q) = %q, want %q", tt.osRelease, got, tt.want)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	originalPath, originalPlatform, originalExists, originalBrew := osReleasePath, platform, commandExists, brewInstalled
+	t.Cleanup(func() {
+		osReleasePath, platform, commandExists, brewInstalled = originalPath, originalPlatform, originalExists, originalBrew
+	})
+
+	osReleasePath = filepath.Join(t.TempDir(), "os-release")
+	if err := os.WriteFile(osReleasePath, []byte("ID=debian\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	platform = func() string { return "linux" }
+	commandExists = func(command string) bool { return command == "dnf" }
+	brewInstalled = func() bool { return false }
+
+	detect := func(configured string) string {
+		t.Helper()
+		manager, err := Detect(configured)
+		if err != nil {
+			t.Fatalf("Detect(%q) failed: %v", configured, err)
+		}
+		return manager.Name()
+	}
+
+	if got := detect(Auto); got != Apt {
+		t.Errorf("Detect on Debian = %s, want apt", got)
+	}
+	if got := detect(Brew); got != Brew {
+		t.Errorf("Detect with package_manager: brew = %s, want brew", got)
+	}
+
+	os.Remove(osReleasePath)
+	if got := detect(""); got != Dnf {
+		t.Errorf("Detect on an unknown distribution with dnf = %s, want dnf", got)
+	}
+
+	brewInstalled = func() bool { return true }
+	if got := detect(Auto); got != Brew {
+		t.Errorf("Detect on Linux with Homebrew installed = %s, want brew", got)
+	}
+
+	platform = func() string { return "darwin" }
+	brewInstalled = func() bool { return false }
+	if got := detect(Auto); got != Brew {
+		t.Errorf("Detect on macOS = %s, want brew", got)
+	}
+
+	if _, err := Detect("pacman"); err == nil {
+		t.Error("expected an error for an unknown package manager")
+	}
+}
+
+func TestSystemManagerInstall(t *testing.T) {
+	originalRun, originalRoot, originalConfirm := runCommand, isRoot, confirm
+	t.Cleanup(func() { runCommand, isRoot, confirm = originalRun, originalRoot, originalConfirm })
+
+	var prompts []string
+	confirm = func(action, message string) bool {
+		if action != charm.ConfirmPrivileged {
+			t.Errorf("sudo install confirmed as %q, want %q", action, charm.ConfirmPrivileged)
+		}
+		prompts = append(prompts, message)
+		return true
+	}
+
+	var commands []string
+	runCommand = func(ctx context.Context, dir string, env []string, command string, args ...string) (*system.CommandResult, error) {
+		commands = append(commands, strings.TrimSpace(strings.Join(env, " ")+" "+command+" "+strings.Join(args, " ")))
+		return &system.CommandResult{Success: !strings.Contains(strings.Join(args, " "), "missing")}, nil
+	}
+
+	isRoot = func() bool { return false }
+	apt, _ := New(Apt)
+	if err := apt.Install("ripgrep"); err != nil {
+		t.Fatalf("Install() failed: %v", err)
+	}
+	if err := apt.Install("formula:jq"); err != nil {
+		t.Fatalf("Install() failed: %v", err)
+	}
+	want := []string{
+		"sudo env DEBIAN_FRONTEND=noninteractive apt-get update",
+		"sudo env DEBIAN_FRONTEND=noninteractive apt-get install -y ripgrep",
+		"sudo env DEBIAN_FRONTEND=noninteractive apt-get install -y jq",
+	}
+	if strings.Join(commands, "\n") != strings.Join(want, "\n") {
+		t.Errorf("commands =\n%s\nwant\n%s", strings.Join(commands, "\n"), strings.Join(want, "\n"))
+	}
+	if len(prompts) != 1 {
+		t.Errorf("sudo installs prompted %d times, want once per run", len(prompts))
+	}
+
+	if err := apt.Install("cask:visual-studio-code"); err == nil {
+		t.Error("expected an error installing a cask with apt")
+	}
+	if err := apt.Install("missing-package"); err == nil {
+		t.Error("expected an error when apt-get fails")
+	}
+
+	declined, _ := New(Apt)
+	confirm = func(string, string) bool { return false }
+	commands = nil
+	if err := declined.Install("ripgrep"); err == nil || len(commands) > 0 {
+		t.Errorf("declined sudo install ran %v, err = %v", commands, err)
+	}
+
+	commands = nil
+	isRoot = func() bool { return true }
+	dnf, _ := New(Dnf)
+	if err := dnf.Install("ripgrep"); err != nil {
+		t.Fatalf("Install() failed: %v", err)
+	}
+	if !dnf.IsInstalled("ripgrep") || dnf.Exists("cask:slack") {
+		t.Error("IsInstalled or Exists ignored the package database")
+	}
+	if got := strings.Join(commands, "\n"); got != "dnf install -y ripgrep\nrpm -q ripgrep" {
+		t.Errorf("commands as root =\n%s", got)
+	}
+}
@@ -0,0 +1,186 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package project reads the .anvil.yaml file a repository uses to declare the tools, groups
+// and environment variables its contributors need, and checks a machine against it.
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// FileName is the project file looked up from the working directory upwards
+const FileName = ".anvil.yaml"
+
+// Project is the content of a .anvil.yaml file
+type Project struct {
+	Name        string   `yaml:"name,omitempty"`
+	Description string   `yaml:"description,omitempty"`
+	Tools       []string `yaml:"tools,omitempty"`  // Apps installed the same way as 'anvil install <app>'
+	Groups      []string `yaml:"groups,omitempty"` // Groups from settings.yaml whose tools are all required
+	Env         []string `yaml:"env,omitempty"`    // Environment variables that must be set
+	Path        string   `yaml:"-"`
+}
+
+// Find returns the project file in dir or the closest parent directory that has one
+func Find(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		candidate := filepath.Join(dir, FileName)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("no %s found in this directory or its parents", FileName)
+		}
+		dir = parent
+	}
+}
+
+// Load reads and validates a project file
+func Load(path string) (*Project, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var p Project
+	if err := yaml.UnmarshalStrict(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	if len(p.Tools) == 0 && len(p.Groups) == 0 && len(p.Env) == 0 {
+		return nil, fmt.Errorf("%s declares no tools, groups or env", path)
+	}
+	for _, name := range p.Env {
+		if name == "" || strings.ContainsAny(name, "= \t") {
+			return nil, fmt.Errorf("invalid environment variable name '%s' in %s", name, path)
+		}
+	}
+	p.Path = path
+	return &p, nil
+}
+
+// DisplayName returns the project name, or its directory name when none is set
+func (p *Project) DisplayName() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return filepath.Base(filepath.Dir(p.Path))
+}
+
+// ToolState is whether a required tool is on the machine
+type ToolState string
+
+const (
+	ToolInstalled ToolState = "installed"
+	ToolMissing   ToolState = "missing"
+	ToolSkipped   ToolState = "skipped" // Tagged for other platforms
+	ToolManaged   ToolState = "managed" // Installed and updated by another tool, never by anvil
+)
+
+// ToolStatus is a required tool and where the requirement comes from
+type ToolStatus struct {
+	Name   string
+	Source string // "tools" or the group it belongs to
+	State  ToolState
+}
+
+// Report is the outcome of checking a machine against a project
+type Report struct {
+	Tools         []ToolStatus
+	UnknownGroups []string
+	MissingEnv    []string
+	SetEnv        []string
+}
+
+// Checker supplies the machine lookups a check needs, so tests can replace them
+type Checker struct {
+	Available  func(tool string) bool
+	Supported  func(tool string) bool
+	Managed    func(tool string) bool // Optional, reports tools listed under managed_externally
+	GroupTools func(group string) ([]string, error)
+	LookupEnv  func(name string) (string, bool)
+}
+
+// Check compares the machine with the project. Tools are listed once, in declaration order,
+// with tools from groups after the project's own tools.
+func (c Checker) Check(p *Project) *Report {
+	report := &Report{}
+	seen := make(map[string]bool)
+	add := func(tool, source string) {
+		if seen[tool] {
+			return
+		}
+		seen[tool] = true
+		state := ToolMissing
+		switch {
+		case c.Managed != nil && c.Managed(tool):
+			state = ToolManaged
+		case !c.Supported(tool):
+			state = ToolSkipped
+		case c.Available(tool):
+			state = ToolInstalled
+		}
+		report.Tools = append(report.Tools, ToolStatus{Name: tool, Source: source, State: state})
+	}
+
+	for _, tool := range p.Tools {
+		add(tool, "tools")
+	}
+	for _, group := range p.Groups {
+		tools, err := c.GroupTools(group)
+		if err != nil {
+			report.UnknownGroups = append(report.UnknownGroups, group)
+			continue
+		}
+		for _, tool := range tools {
+			add(tool, group)
+		}
+	}
+	for _, name := range p.Env {
+		if value, ok := c.LookupEnv(name); ok && value != "" {
+			report.SetEnv = append(report.SetEnv, name)
+		} else {
+			report.MissingEnv = append(report.MissingEnv, name)
+		}
+	}
+	return report
+}
+
+// MissingTools returns the tools that still need to be installed
+func (r *Report) MissingTools() []string {
+	var missing []string
+	for _, tool := range r.Tools {
+		if tool.State == ToolMissing {
+			missing = append(missing, tool.Name)
+		}
+	}
+	return missing
+}
+
+// Compliant reports whether the machine meets every requirement of the project
+func (r *Report) Compliant() bool {
+	return len(r.MissingTools()) == 0 && len(r.UnknownGroups) == 0 && len(r.MissingEnv) == 0
+}
@@ -0,0 +1,102 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFindAndLoad(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "services", "api")
+	os.MkdirAll(nested, 0755)
+	content := "name: payments\ntools: [go, cask:docker]\ngroups: [dev]\nenv: [AWS_PROFILE]\n"
+	os.WriteFile(filepath.Join(root, FileName), []byte(content), 0644)
+
+	path, err := Find(nested)
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if path != filepath.Join(root, FileName) {
+		t.Errorf("Find() = %s, want the file in the parent", path)
+	}
+
+	p, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if p.DisplayName() != "payments" || !reflect.DeepEqual(p.Tools, []string{"go", "cask:docker"}) {
+		t.Errorf("Load() = %+v", p)
+	}
+
+	bad := filepath.Join(root, "bad.yaml")
+	for _, content := range []string{"tool: [go]\n", "name: empty\n", "env: [\"A=B\"]\n"} {
+		os.WriteFile(bad, []byte(content), 0644)
+		if _, err := Load(bad); err == nil {
+			t.Errorf("Load(%q) should fail", content)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	p := &Project{Tools: []string{"git", "go"}, Groups: []string{"dev", "missing"}, Env: []string{"SET", "UNSET"}}
+	checker := Checker{
+		Available: func(tool string) bool { return tool == "git" },
+		Supported: func(tool string) bool { return tool != "iterm2" },
+		GroupTools: func(group string) ([]string, error) {
+			if group == "dev" {
+				return []string{"git", "iterm2", "jq"}, nil
+			}
+			return nil, fmt.Errorf("group '%s' not found", group)
+		},
+		LookupEnv: func(name string) (string, bool) { return "1", name == "SET" },
+	}
+
+	report := checker.Check(p)
+	want := []ToolStatus{
+		{Name: "git", Source: "tools", State: ToolInstalled},
+		{Name: "go", Source: "tools", State: ToolMissing},
+		{Name: "iterm2", Source: "dev", State: ToolSkipped},
+		{Name: "jq", Source: "dev", State: ToolMissing},
+	}
+	if !reflect.DeepEqual(report.Tools, want) {
+		t.Errorf("Tools = %+v, want %+v", report.Tools, want)
+	}
+	if !reflect.DeepEqual(report.MissingTools(), []string{"go", "jq"}) {
+		t.Errorf("MissingTools() = %v", report.MissingTools())
+	}
+	if !reflect.DeepEqual(report.UnknownGroups, []string{"missing"}) || !reflect.DeepEqual(report.MissingEnv, []string{"UNSET"}) {
+		t.Errorf("UnknownGroups = %v, MissingEnv = %v", report.UnknownGroups, report.MissingEnv)
+	}
+	if report.Compliant() {
+		t.Error("report with missing requirements should not be compliant")
+	}
+
+	checker.Managed = func(tool string) bool { return tool == "jq" }
+	if report := checker.Check(p); !reflect.DeepEqual(report.MissingTools(), []string{"go"}) || report.Tools[3].State != ToolManaged {
+		t.Errorf("managed tool should not be missing, got %+v", report.Tools)
+	}
+
+	checker.Available = func(string) bool { return true }
+	if report := checker.Check(&Project{Tools: []string{"git"}, Env: []string{"SET"}}); !report.Compliant() {
+		t.Errorf("report %+v should be compliant", report)
+	}
+}
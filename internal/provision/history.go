@@ -0,0 +1,275 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provision
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/config"
+)
+
+// HistoryDirName is the directory under ~/.anvil holding the last run of each profile
+const HistoryDirName = "provision"
+
+// Input keys of a step, see StepInputs
+const (
+	InputTools    = "tools"    // Comma separated tools of a group
+	InputSource   = "source"   // Download URL of an app installed from a configured source
+	InputConfig   = "config"   // Hash of the config files a sync applies
+	InputExcludes = "excludes" // Sync exclude patterns of this machine
+	InputDefaults = "defaults" // Command defaults from settings.yaml that change how the step runs
+	InputHosts    = "hosts"    // Hash of the managed hosts entries
+	InputGit      = "git"      // Hash of the git settings to apply
+	InputServices = "services" // Hash of the wanted service states
+	InputUnknown  = "unknown"  // Why the inputs could not be read, e.g. a group missing from settings
+)
+
+// StepInputs are the values that decide what a step does. A step whose inputs match the last
+// run, and which did not fail then, would do the same again.
+type StepInputs map[string]string
+
+// Hash returns a stable hash of the inputs
+func (i StepInputs) Hash() string {
+	keys := make([]string, 0, len(i))
+	for key := range i {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	for _, key := range keys {
+		fmt.Fprintf(hash, "%s=%s\n", key, i[key])
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// HashValues hashes a set of key=value pairs, for inputs such as command defaults
+func HashValues(values map[string]string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return StepInputs(values).Hash()
+}
+
+// StepRecord is the outcome of a step in a recorded run and the inputs it ran with
+type StepRecord struct {
+	Kind      StepKind   `json:"kind"`
+	Target    string     `json:"target"`
+	Outcome   Outcome    `json:"outcome"`
+	Inputs    StepInputs `json:"inputs"`
+	InputHash string     `json:"input_hash"`
+}
+
+// Step returns the step the record was made for
+func (r StepRecord) Step() Step {
+	return Step{Kind: r.Kind, Target: r.Target}
+}
+
+// RunRecord is the last applied run of a profile on this machine
+type RunRecord struct {
+	Profile      string       `json:"profile"`
+	Machine      string       `json:"machine"`
+	AnvilVersion string       `json:"anvil_version"`
+	FinishedAt   time.Time    `json:"finished_at"`
+	Steps        []StepRecord `json:"steps"`
+}
+
+// NewStepRecord records a step with its outcome and inputs
+func NewStepRecord(step Step, outcome Outcome, inputs StepInputs) StepRecord {
+	return StepRecord{Kind: step.Kind, Target: step.Target, Outcome: outcome, Inputs: inputs, InputHash: inputs.Hash()}
+}
+
+// historyPath returns the record file of a profile
+func historyPath(profile string) string {
+	name := strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r == '.' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+			return r
+		}
+		return '_'
+	}, profile)
+	return filepath.Join(config.GetAnvilConfigDirectory(), HistoryDirName, name+".json")
+}
+
+// SaveRun replaces the recorded last run of its profile
+func SaveRun(record RunRecord) error {
+	path := historyPath(record.Profile)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// LoadLastRun returns the last recorded run of a profile, or nil when it never ran here
+func LoadLastRun(profile string) (*RunRecord, error) {
+	data, err := os.ReadFile(historyPath(profile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var record RunRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to read last run of '%s': %w", profile, err)
+	}
+	return &record, nil
+}
+
+// StepDiff is a step a re-run would act on differently than the last run, and why
+type StepDiff struct {
+	Step    Step     `json:"step"`
+	Reasons []string `json:"reasons"`
+}
+
+// RunDiff compares the current steps of a profile with its last recorded run
+type RunDiff struct {
+	Profile   string     `json:"profile"`
+	LastRun   time.Time  `json:"last_run"`
+	Changed   []StepDiff `json:"changed"`
+	Removed   []Step     `json:"removed,omitempty"` // In the last run, no longer in the profile
+	Unchanged int        `json:"unchanged"`
+}
+
+// DiffRun returns the steps whose inputs changed since the last run, steps that are new or
+// failed then, and steps that were dropped from the profile
+func DiffRun(last RunRecord, current []StepRecord) RunDiff {
+	diff := RunDiff{Profile: last.Profile, LastRun: last.FinishedAt}
+	previous := make(map[Step]StepRecord, len(last.Steps))
+	for _, record := range last.Steps {
+		previous[record.Step()] = record
+	}
+
+	seen := make(map[Step]bool, len(current))
+	for _, record := range current {
+		step := record.Step()
+		seen[step] = true
+		before, ok := previous[step]
+		var reasons []string
+		switch {
+		case !ok:
+			reasons = []string{"new in the profile since the last run"}
+		default:
+			if before.Outcome == OutcomeFailed {
+				reasons = append(reasons, "failed in the last run")
+			}
+			if before.InputHash != record.InputHash {
+				reasons = append(reasons, inputChanges(before.Inputs, record.Inputs)...)
+			}
+		}
+		if len(reasons) == 0 {
+			diff.Unchanged++
+			continue
+		}
+		diff.Changed = append(diff.Changed, StepDiff{Step: step, Reasons: reasons})
+	}
+
+	for _, record := range last.Steps {
+		if !seen[record.Step()] {
+			diff.Removed = append(diff.Removed, record.Step())
+		}
+	}
+	return diff
+}
+
+// inputChanges describes how the inputs of a step changed
+func inputChanges(before, after StepInputs) []string {
+	keys := make([]string, 0, len(before)+len(after))
+	for key := range before {
+		keys = append(keys, key)
+	}
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var changes []string
+	for _, key := range keys {
+		if before[key] == after[key] {
+			continue
+		}
+		switch key {
+		case InputUnknown:
+			if after[key] != "" {
+				changes = append(changes, "inputs could not be read: "+after[key])
+			}
+		case InputTools:
+			added, removed := toolChanges(before[key], after[key])
+			for _, tool := range added {
+				changes = append(changes, fmt.Sprintf("+ %s added to the group", tool))
+			}
+			for _, tool := range removed {
+				changes = append(changes, fmt.Sprintf("- %s removed from the group (it is not uninstalled)", tool))
+			}
+		case InputSource:
+			changes = append(changes, "install source changed")
+		case InputConfig:
+			changes = append(changes, "config files changed")
+		case InputExcludes:
+			changes = append(changes, "sync excludes changed")
+		case InputDefaults:
+			changes = append(changes, "command defaults changed")
+		case InputHosts:
+			changes = append(changes, "hosts entries changed")
+		case InputGit:
+			changes = append(changes, "git settings changed")
+		case InputServices:
+			changes = append(changes, "wanted services changed")
+		default:
+			changes = append(changes, key+" changed")
+		}
+	}
+	return changes
+}
+
+// toolChanges returns the tools added to and removed from a comma separated list
+func toolChanges(before, after string) ([]string, []string) {
+	split := func(list string) []string {
+		if list == "" {
+			return nil
+		}
+		return strings.Split(list, ",")
+	}
+	old, current := split(before), split(after)
+
+	var added, removed []string
+	for _, tool := range current {
+		if !slices.Contains(old, tool) {
+			added = append(added, tool)
+		}
+	}
+	for _, tool := range old {
+		if !slices.Contains(current, tool) {
+			removed = append(removed, tool)
+		}
+	}
+	return added, removed
+}
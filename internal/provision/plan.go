@@ -0,0 +1,264 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provision
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/brew"
+	"github.com/0xjuanma/anvil/internal/utils"
+)
+
+// Document is the full, ordered plan of a profile, rendered as a tree or exported as JSON
+// for review before the profile is applied
+type Document struct {
+	Profile      string        `json:"profile"`
+	Description  string        `json:"description,omitempty"`
+	Machine      string        `json:"machine"`
+	AnvilVersion string        `json:"anvil_version"`
+	GeneratedAt  time.Time     `json:"generated_at"`
+	Taps         []PlannedTap  `json:"taps,omitempty"`
+	Steps        []PlannedStep `json:"steps"`
+	Summary      PlanSummary   `json:"summary"`
+}
+
+// PlannedTap is a third-party tap the planned packages come from
+type PlannedTap struct {
+	Name   string `json:"name"`
+	Tapped bool   `json:"tapped"`
+}
+
+// PlannedStep is a step of the plan with the details of what it would change
+type PlannedStep struct {
+	Number      int              `json:"number"`
+	Kind        StepKind         `json:"kind"`
+	Target      string           `json:"target"`
+	Description string           `json:"description"`
+	Outcome     Outcome          `json:"outcome"` // satisfied, changed or failed when the step cannot be planned
+	Packages    []PlannedPackage `json:"packages,omitempty"`
+	Files       *PlannedFiles    `json:"files,omitempty"`
+	Hosts       []string         `json:"hosts,omitempty"`
+	GitSettings []string         `json:"git_settings,omitempty"` // "key = value", "+" marks values that would be set
+	Services    []string         `json:"services,omitempty"`     // "name: wanted (status)", "+" marks services that would change
+	Note        string           `json:"note,omitempty"`
+}
+
+// PlannedPackage is a package an install step covers
+type PlannedPackage struct {
+	brew.PackageDetails
+	Present bool   `json:"present,omitempty"` // Installed outside Homebrew, e.g. an app in /Applications
+	Source  string `json:"source,omitempty"`  // Download URL when installed from a configured source
+	Skipped string `json:"skipped,omitempty"` // Why the package is not installed on this machine
+}
+
+// Pending reports whether the step would install the package
+func (p PlannedPackage) Pending() bool {
+	return p.Skipped == "" && p.InstalledVersion == "" && !p.Present
+}
+
+// PlannedFiles counts the files a sync step would add or change
+type PlannedFiles struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	Pulled      bool   `json:"pulled"`
+	Files       int    `json:"files"`
+	New         int    `json:"new"`
+	Changed     int    `json:"changed"`
+}
+
+// PlanSummary totals the plan
+type PlanSummary struct {
+	Steps        int   `json:"steps"`
+	Satisfied    int   `json:"satisfied"`
+	Changes      int   `json:"changes"`
+	Unplanned    int   `json:"unplanned"`
+	Packages     int   `json:"packages"`      // Packages to install
+	DownloadSize int64 `json:"download_size"` // Known download size of those packages in bytes
+	Files        int   `json:"files"`         // Files to add or change
+	TapsToAdd    int   `json:"taps_to_add"`
+}
+
+// Finish numbers the steps, collects taps and computes the summary
+func (d *Document) Finish(tapped map[string]bool) {
+	d.Summary = PlanSummary{Steps: len(d.Steps)}
+	seenTaps := make(map[string]bool)
+	for i := range d.Steps {
+		step := &d.Steps[i]
+		step.Number = i + 1
+		switch step.Outcome {
+		case OutcomeSatisfied:
+			d.Summary.Satisfied++
+		case OutcomeFailed:
+			d.Summary.Unplanned++
+		default:
+			d.Summary.Changes++
+		}
+
+		for _, pkg := range step.Packages {
+			if pkg.Tap != "" && !seenTaps[pkg.Tap] {
+				seenTaps[pkg.Tap] = true
+				d.Taps = append(d.Taps, PlannedTap{Name: pkg.Tap, Tapped: tapped[pkg.Tap]})
+				if !tapped[pkg.Tap] {
+					d.Summary.TapsToAdd++
+				}
+			}
+			if pkg.Pending() {
+				d.Summary.Packages++
+				d.Summary.DownloadSize += pkg.Size
+			}
+		}
+		if step.Files != nil {
+			d.Summary.Files += step.Files.New + step.Files.Changed
+		}
+	}
+}
+
+// treeNode is a line of the rendered plan and the lines nested below it
+type treeNode struct {
+	label    string
+	children []treeNode
+}
+
+// RenderTree renders the plan as a tree in step order
+func (d *Document) RenderTree() string {
+	root := treeNode{label: fmt.Sprintf("Provision '%s'", d.Profile)}
+	if d.Description != "" {
+		root.label += " - " + d.Description
+	}
+
+	if len(d.Taps) > 0 {
+		taps := treeNode{label: "Taps"}
+		for _, tap := range d.Taps {
+			state := "already tapped"
+			if !tap.Tapped {
+				state = "to add"
+			}
+			taps.children = append(taps.children, treeNode{label: fmt.Sprintf("%s (%s)", tap.Name, state)})
+		}
+		root.children = append(root.children, taps)
+	}
+
+	for _, step := range d.Steps {
+		root.children = append(root.children, step.tree())
+	}
+
+	var builder strings.Builder
+	builder.WriteString(root.label + "\n")
+	renderChildren(&builder, root.children, "")
+	builder.WriteString("\n" + d.Summary.String() + "\n")
+	return builder.String()
+}
+
+// tree renders a step with its packages, files or hosts entries
+func (s PlannedStep) tree() treeNode {
+	outcome := "would change"
+	switch s.Outcome {
+	case OutcomeSatisfied:
+		outcome = "satisfied"
+	case OutcomeFailed:
+		outcome = "unknown"
+	}
+	node := treeNode{label: fmt.Sprintf("%d. %s [%s]", s.Number, s.Description, outcome)}
+
+	for _, pkg := range s.Packages {
+		node.children = append(node.children, treeNode{label: pkg.label()})
+	}
+	if s.Files != nil {
+		label := fmt.Sprintf("%d files, %d new, %d changed → %s", s.Files.Files, s.Files.New, s.Files.Changed, s.Files.Destination)
+		if !s.Files.Pulled {
+			label += " (from the local clone, the pull may change it)"
+		}
+		node.children = append(node.children, treeNode{label: label})
+	}
+	for _, entry := range s.Hosts {
+		node.children = append(node.children, treeNode{label: entry})
+	}
+	for _, setting := range s.GitSettings {
+		node.children = append(node.children, treeNode{label: setting})
+	}
+	for _, service := range s.Services {
+		node.children = append(node.children, treeNode{label: service})
+	}
+	if s.Note != "" {
+		node.children = append(node.children, treeNode{label: s.Note})
+	}
+	return node
+}
+
+// label describes a package on one line, "+" marks packages that would be installed
+func (p PlannedPackage) label() string {
+	switch {
+	case p.Skipped != "":
+		return fmt.Sprintf("- %s skipped (%s)", p.Entry, p.Skipped)
+	case p.InstalledVersion != "":
+		return fmt.Sprintf("✓ %s %s installed", p.Entry, p.InstalledVersion)
+	case p.Present:
+		return fmt.Sprintf("✓ %s installed", p.Entry)
+	case p.Source != "":
+		return fmt.Sprintf("+ %s from %s", p.Entry, p.Source)
+	case p.Unknown:
+		return fmt.Sprintf("? %s not found in Homebrew", p.Entry)
+	}
+
+	var details []string
+	if p.Version != "" {
+		details = append(details, p.Version)
+	}
+	if p.Type != brew.PackageTypeAuto {
+		details = append(details, string(p.Type))
+	}
+	if p.Size > 0 {
+		details = append(details, utils.FormatSize(p.Size))
+	}
+	if len(p.Dependencies) > 0 {
+		details = append(details, fmt.Sprintf("%d dependencies", len(p.Dependencies)))
+	}
+	if len(details) == 0 {
+		return "+ " + p.Entry
+	}
+	return fmt.Sprintf("+ %s (%s)", p.Entry, strings.Join(details, ", "))
+}
+
+// String summarizes the plan
+func (s PlanSummary) String() string {
+	summary := fmt.Sprintf("Steps: %d satisfied, %d would change", s.Satisfied, s.Changes)
+	if s.Unplanned > 0 {
+		summary += fmt.Sprintf(", %d unknown", s.Unplanned)
+	}
+	summary += fmt.Sprintf("\nPackages to install: %d", s.Packages)
+	if s.DownloadSize > 0 {
+		summary += fmt.Sprintf(" (%s to download, dependencies not included)", utils.FormatSize(s.DownloadSize))
+	}
+	if s.TapsToAdd > 0 {
+		summary += fmt.Sprintf("\nTaps to add: %d", s.TapsToAdd)
+	}
+	return summary + fmt.Sprintf("\nFiles to sync: %d", s.Files)
+}
+
+// renderChildren writes nodes with box-drawing branches
+func renderChildren(builder *strings.Builder, nodes []treeNode, prefix string) {
+	for i, node := range nodes {
+		branch, nested := "├── ", "│   "
+		if i == len(nodes)-1 {
+			branch, nested = "└── ", "    "
+		}
+		builder.WriteString(prefix + branch + node.label + "\n")
+		renderChildren(builder, node.children, prefix+nested)
+	}
+}
@@ -0,0 +1,128 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provision
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+)
+
+// StepKind identifies what a provisioning step does
+type StepKind string
+
+const (
+	StepSyncSettings  StepKind = "sync-settings" // Pull and sync settings.yaml so later steps see the team's groups
+	StepInstallGroup  StepKind = "install-group"
+	StepInstallApp    StepKind = "install-app"
+	StepSyncConfig    StepKind = "sync-config"    // Pull and sync an app's configs
+	StepApplyHosts    StepKind = "apply-hosts"    // Write the managed /etc/hosts block
+	StepApplyGit      StepKind = "apply-git"      // Set git.extra_config and git.global_ignore in the global git config
+	StepApplyServices StepKind = "apply-services" // Start and stop Homebrew services as the services section asks
+)
+
+// Outcome is how a step ended. Steps check the machine first, so re-running a profile
+// only changes what drifted and reports everything else as satisfied.
+type Outcome string
+
+const (
+	OutcomeSatisfied Outcome = "satisfied" // Already in the wanted state, nothing was done
+	OutcomeChanged   Outcome = "changed"
+	OutcomeFailed    Outcome = "failed"
+)
+
+// Targets of the hosts, git and services steps
+const (
+	hostsFile       = "/etc/hosts"
+	gitConfigFile   = "~/.gitconfig"
+	servicesSection = "services"
+)
+
+// Step is a single action of a provisioning plan
+type Step struct {
+	Kind   StepKind `json:"kind"`
+	Target string   `json:"target"`
+}
+
+// String describes the step for progress output
+func (s Step) String() string {
+	switch s.Kind {
+	case StepSyncSettings:
+		return fmt.Sprintf("Pull and sync %s", constants.ANVIL_CONFIG_FILE)
+	case StepInstallGroup:
+		return fmt.Sprintf("Install group '%s'", s.Target)
+	case StepInstallApp:
+		return fmt.Sprintf("Install app '%s'", s.Target)
+	case StepSyncConfig:
+		return fmt.Sprintf("Pull and sync '%s' configs", s.Target)
+	case StepApplyHosts:
+		return fmt.Sprintf("Apply hosts entries to %s", s.Target)
+	case StepApplyGit:
+		return fmt.Sprintf("Apply git settings to %s", s.Target)
+	case StepApplyServices:
+		return "Start and stop Homebrew services"
+	default:
+		return fmt.Sprintf("%s %s", s.Kind, s.Target)
+	}
+}
+
+// Pending returns the tools that are not available yet, keeping their order
+func Pending(tools []string, available func(string) bool) []string {
+	var pending []string
+	for _, tool := range tools {
+		if !available(tool) {
+			pending = append(pending, tool)
+		}
+	}
+	return pending
+}
+
+// BuildPlan orders a profile's steps: settings first so installs and hosts use the synced
+// settings, then hosts entries, groups, apps, app configs once the apps are installed, git
+// settings once git and a synced global ignore file are in place and finally services, so
+// they start with their configs synced
+func BuildPlan(profile config.ProvisionProfile) []Step {
+	var steps []Step
+
+	if slices.Contains(profile.Configs, constants.ANVIL) {
+		steps = append(steps, Step{Kind: StepSyncSettings, Target: constants.ANVIL})
+	}
+	if profile.Hosts {
+		steps = append(steps, Step{Kind: StepApplyHosts, Target: hostsFile})
+	}
+	for _, group := range profile.Groups {
+		steps = append(steps, Step{Kind: StepInstallGroup, Target: group})
+	}
+	for _, app := range profile.Apps {
+		steps = append(steps, Step{Kind: StepInstallApp, Target: app})
+	}
+	for _, app := range profile.Configs {
+		if app != constants.ANVIL {
+			steps = append(steps, Step{Kind: StepSyncConfig, Target: app})
+		}
+	}
+	if profile.Git {
+		steps = append(steps, Step{Kind: StepApplyGit, Target: gitConfigFile})
+	}
+	if profile.Services {
+		steps = append(steps, Step{Kind: StepApplyServices, Target: servicesSection})
+	}
+
+	return steps
+}
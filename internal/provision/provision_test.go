@@ -0,0 +1,172 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provision
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/0xjuanma/anvil/internal/brew"
+	"github.com/0xjuanma/anvil/internal/config"
+)
+
+func TestBuildPlan(t *testing.T) {
+	profile := config.ProvisionProfile{
+		Groups:   []string{"essentials", "dev"},
+		Apps:     []string{"slack"},
+		Configs:  []string{"cursor", "anvil"},
+		Hosts:    true,
+		Git:      true,
+		Services: true,
+	}
+
+	want := []Step{
+		{Kind: StepSyncSettings, Target: "anvil"},
+		{Kind: StepApplyHosts, Target: "/etc/hosts"},
+		{Kind: StepInstallGroup, Target: "essentials"},
+		{Kind: StepInstallGroup, Target: "dev"},
+		{Kind: StepInstallApp, Target: "slack"},
+		{Kind: StepSyncConfig, Target: "cursor"},
+		{Kind: StepApplyGit, Target: "~/.gitconfig"},
+		{Kind: StepApplyServices, Target: "services"},
+	}
+
+	if got := BuildPlan(profile); !reflect.DeepEqual(got, want) {
+		t.Errorf("BuildPlan() = %v, want %v", got, want)
+	}
+
+	if got := BuildPlan(config.ProvisionProfile{}); len(got) != 0 {
+		t.Errorf("BuildPlan() of empty profile = %v, want no steps", got)
+	}
+}
+
+func TestPending(t *testing.T) {
+	installed := map[string]bool{"git": true, "slack": true}
+	available := func(tool string) bool { return installed[tool] }
+
+	if got := Pending([]string{"git", "jq", "slack", "fzf"}, available); !reflect.DeepEqual(got, []string{"jq", "fzf"}) {
+		t.Errorf("Pending() = %v, want [jq fzf]", got)
+	}
+	if got := Pending([]string{"git", "slack"}, available); len(got) != 0 {
+		t.Errorf("Pending() = %v, want nothing once every tool is installed", got)
+	}
+}
+
+func TestDocumentFinish(t *testing.T) {
+	document := &Document{
+		Profile: "work",
+		Steps: []PlannedStep{
+			{Kind: StepInstallGroup, Target: "dev", Description: "Install group 'dev'", Outcome: OutcomeChanged, Packages: []PlannedPackage{
+				{PackageDetails: brew.PackageDetails{Entry: "git", InstalledVersion: "2.45.0"}},
+				{PackageDetails: brew.PackageDetails{Entry: "neovim", Version: "0.10.0", Type: brew.PackageTypeFormula, Size: 2048, Dependencies: []string{"luajit"}}},
+				{PackageDetails: brew.PackageDetails{Entry: "nikitabobko/tap/aerospace", Tap: "nikitabobko/tap"}},
+				{PackageDetails: brew.PackageDetails{Entry: "iterm2"}, Skipped: "platform: only for darwin"},
+			}},
+			{Kind: StepSyncConfig, Target: "zsh", Description: "Pull and sync 'zsh' configs", Outcome: OutcomeChanged,
+				Files: &PlannedFiles{Destination: "~/.zsh", Pulled: true, Files: 4, New: 1, Changed: 2}},
+			{Kind: StepApplyHosts, Target: hostsFile, Description: "Apply hosts entries to /etc/hosts", Outcome: OutcomeSatisfied},
+			{Kind: StepSyncConfig, Target: "cursor", Description: "Pull and sync 'cursor' configs", Outcome: OutcomeFailed, Note: "not pulled"},
+		},
+	}
+	document.Finish(map[string]bool{})
+
+	want := PlanSummary{Steps: 4, Satisfied: 1, Changes: 2, Unplanned: 1, Packages: 2, DownloadSize: 2048, Files: 3, TapsToAdd: 1}
+	if document.Summary != want {
+		t.Errorf("Summary = %+v, want %+v", document.Summary, want)
+	}
+	if len(document.Taps) != 1 || document.Taps[0].Name != "nikitabobko/tap" || document.Taps[0].Tapped {
+		t.Errorf("Taps = %+v, want nikitabobko/tap to add", document.Taps)
+	}
+	if document.Steps[3].Number != 4 {
+		t.Errorf("last step number = %d, want 4", document.Steps[3].Number)
+	}
+
+	tree := document.RenderTree()
+	for _, line := range []string{
+		"│   └── nikitabobko/tap (to add)",
+		"├── 1. Install group 'dev' [would change]",
+		"│   ├── ✓ git 2.45.0 installed",
+		"│   ├── + neovim (0.10.0, formula, 2.0 KB, 1 dependencies)",
+		"│   └── - iterm2 skipped (platform: only for darwin)",
+		"│   └── 4 files, 1 new, 2 changed → ~/.zsh",
+		"├── 3. Apply hosts entries to /etc/hosts [satisfied]",
+		"    └── not pulled",
+	} {
+		if !strings.Contains(tree, line+"\n") {
+			t.Errorf("RenderTree() is missing %q:\n%s", line, tree)
+		}
+	}
+}
+
+func TestDiffRun(t *testing.T) {
+	dev := Step{Kind: StepInstallGroup, Target: "dev"}
+	zsh := Step{Kind: StepSyncConfig, Target: "zsh"}
+	hosts := Step{Kind: StepApplyHosts, Target: hostsFile}
+	slack := Step{Kind: StepInstallApp, Target: "slack"}
+	cursor := Step{Kind: StepSyncConfig, Target: "cursor"}
+
+	last := RunRecord{Profile: "work", Steps: []StepRecord{
+		NewStepRecord(dev, OutcomeChanged, StepInputs{InputTools: "git,htop", InputDefaults: ""}),
+		NewStepRecord(zsh, OutcomeSatisfied, StepInputs{InputConfig: "aaa"}),
+		NewStepRecord(hosts, OutcomeFailed, StepInputs{InputHosts: "bbb"}),
+		NewStepRecord(slack, OutcomeChanged, StepInputs{InputSource: ""}),
+	}}
+	current := []StepRecord{
+		NewStepRecord(dev, "", StepInputs{InputTools: "git,jq", InputDefaults: ""}),
+		NewStepRecord(zsh, "", StepInputs{InputConfig: "ccc"}),
+		NewStepRecord(hosts, "", StepInputs{InputHosts: "bbb"}),
+		NewStepRecord(cursor, "", StepInputs{InputConfig: "ddd"}),
+	}
+
+	diff := DiffRun(last, current)
+	want := []StepDiff{
+		{Step: dev, Reasons: []string{"+ jq added to the group", "- htop removed from the group (it is not uninstalled)"}},
+		{Step: zsh, Reasons: []string{"config files changed"}},
+		{Step: hosts, Reasons: []string{"failed in the last run"}},
+		{Step: cursor, Reasons: []string{"new in the profile since the last run"}},
+	}
+	if !reflect.DeepEqual(diff.Changed, want) {
+		t.Errorf("Changed = %+v, want %+v", diff.Changed, want)
+	}
+	if !reflect.DeepEqual(diff.Removed, []Step{slack}) || diff.Unchanged != 0 {
+		t.Errorf("Removed = %v, Unchanged = %d; want slack removed and nothing unchanged", diff.Removed, diff.Unchanged)
+	}
+
+	same := DiffRun(last, []StepRecord{NewStepRecord(zsh, "", StepInputs{InputConfig: "aaa"})})
+	if len(same.Changed) != 0 || same.Unchanged != 1 {
+		t.Errorf("DiffRun() with the same inputs = %+v, want the step unchanged", same)
+	}
+}
+
+func TestRunHistory(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if last, err := LoadLastRun("work"); last != nil || err != nil {
+		t.Fatalf("LoadLastRun() before any run = %v, %v; want nothing", last, err)
+	}
+	record := RunRecord{Profile: "team/work", Steps: []StepRecord{
+		NewStepRecord(Step{Kind: StepInstallGroup, Target: "dev"}, OutcomeChanged, StepInputs{InputTools: "git"}),
+	}}
+	if err := SaveRun(record); err != nil {
+		t.Fatalf("SaveRun failed: %v", err)
+	}
+	last, err := LoadLastRun("team/work")
+	if err != nil || last == nil || !reflect.DeepEqual(last.Steps, record.Steps) {
+		t.Errorf("LoadLastRun() = %+v, %v; want the saved run", last, err)
+	}
+}
@@ -0,0 +1,244 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package rcguard watches shell startup files while install scripts run. Installers such as
+// nvm and pyenv append setup lines to ~/.zshrc, and running them again on every provision
+// leaves the same block in the file several times. A guard snapshots the rc files before a
+// script, finds what the script added and removes added blocks the file already contains.
+package rcguard
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/0xjuanma/anvil/internal/system"
+)
+
+// RCFiles are the shell startup files, relative to the home directory, install scripts write to
+var RCFiles = []string{".zshrc", ".zprofile", ".zshenv", ".bashrc", ".bash_profile", ".profile", ".config/fish/config.fish"}
+
+// mu serializes guarded scripts, a concurrent script's additions would be attributed to the wrong tool
+var mu sync.Mutex
+
+// Change describes what a script did to one rc file
+type Change struct {
+	Path       string
+	Added      []string // Lines the script added and that were kept
+	Duplicates []string // Added lines removed because the file already had them
+}
+
+// Guard holds the rc file contents from before a script ran
+type Guard struct {
+	paths  []string
+	before map[string][]byte
+	locked bool
+}
+
+// Begin snapshots the rc files before a script runs. Only one guard is active at a time,
+// so every call must be paired with Finish.
+func Begin() *Guard {
+	mu.Lock()
+	homeDir, err := system.GetHomeDir()
+	if err != nil {
+		return &Guard{before: make(map[string][]byte), locked: true}
+	}
+	guard := Snapshot(homeDir)
+	guard.locked = true
+	return guard
+}
+
+// Finish checks the rc files against the snapshot taken by Begin and releases the guard
+func (g *Guard) Finish() ([]Change, error) {
+	if g.locked {
+		defer mu.Unlock()
+		g.locked = false
+	}
+	return g.Check()
+}
+
+// Snapshot records the current contents of the rc files under a home directory
+func Snapshot(homeDir string) *Guard {
+	guard := &Guard{before: make(map[string][]byte)}
+	for _, name := range RCFiles {
+		path := filepath.Join(homeDir, name)
+		guard.paths = append(guard.paths, path)
+		if data, err := os.ReadFile(path); err == nil {
+			guard.before[path] = data
+		}
+	}
+	return guard
+}
+
+// Check compares the rc files with the snapshot, removes added blocks that repeat content
+// the file already has and returns the files that changed
+func (g *Guard) Check() ([]Change, error) {
+	var changes []Change
+	for _, path := range g.paths {
+		after, err := os.ReadFile(path)
+		if err != nil {
+			continue // Missing or removed, nothing was appended
+		}
+		before := g.before[path]
+		if bytes.Equal(before, after) {
+			continue
+		}
+
+		lines, change := reconcile(splitLines(before), splitLines(after))
+		if len(change.Added) == 0 && len(change.Duplicates) == 0 {
+			continue
+		}
+		change.Path = path
+
+		if len(change.Duplicates) > 0 {
+			info, err := os.Stat(path)
+			if err != nil {
+				return changes, fmt.Errorf("failed to read %s: %w", path, err)
+			}
+			content := joinLines(lines, bytes.HasSuffix(after, []byte("\n")))
+			if err := os.WriteFile(path, []byte(content), info.Mode().Perm()); err != nil {
+				return changes, fmt.Errorf("failed to remove duplicate lines from %s: %w", path, err)
+			}
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// splitLines splits file content into lines. The final newline only ends the last line, so
+// it does not count as a blank line separating an appended block.
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+// joinLines is the reverse of splitLines
+func joinLines(lines []string, finalNewline bool) string {
+	content := strings.Join(lines, "\n")
+	if finalNewline && len(lines) > 0 {
+		content += "\n"
+	}
+	return content
+}
+
+// reconcile finds the lines added between before and after and drops added blocks whose
+// lines already appear, in order, elsewhere in the file. It returns the resulting lines.
+func reconcile(before, after []string) ([]string, Change) {
+	prefix := 0
+	for prefix < len(before) && prefix < len(after) && before[prefix] == after[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(before)-prefix && suffix < len(after)-prefix &&
+		before[len(before)-1-suffix] == after[len(after)-1-suffix] {
+		suffix++
+	}
+
+	added := after[prefix : len(after)-suffix]
+	existing := append(append([]string{}, after[:prefix]...), after[len(after)-suffix:]...)
+
+	var change Change
+	var kept []string
+	for _, block := range splitBlocks(added) {
+		content := block[leadingBlanks(block):]
+		if len(content) > 0 && containsBlock(existing, content) {
+			change.Duplicates = append(change.Duplicates, content...)
+			continue
+		}
+		kept = append(kept, block...)
+		existing = append(existing, content...)
+		for _, line := range content {
+			if strings.TrimSpace(line) != "" {
+				change.Added = append(change.Added, line)
+			}
+		}
+	}
+
+	// Blank lines left over once every block was a duplicate belong to nothing
+	if len(change.Added) == 0 {
+		kept = nil
+	}
+
+	result := append(append(append([]string{}, after[:prefix]...), kept...), after[len(after)-suffix:]...)
+	return result, change
+}
+
+// splitBlocks splits lines into blocks, each made of its leading blank lines followed by
+// the non-blank lines up to the next blank line
+func splitBlocks(lines []string) [][]string {
+	var blocks [][]string
+	var current []string
+	for _, line := range lines {
+		blank := strings.TrimSpace(line) == ""
+		if blank && len(current) > leadingBlanks(current) {
+			blocks = append(blocks, current)
+			current = nil
+		}
+		current = append(current, line)
+	}
+	if len(current) > 0 {
+		blocks = append(blocks, current)
+	}
+	return blocks
+}
+
+// leadingBlanks counts the blank lines at the start of a block
+func leadingBlanks(block []string) int {
+	n := 0
+	for n < len(block) && strings.TrimSpace(block[n]) == "" {
+		n++
+	}
+	return n
+}
+
+// containsBlock reports whether the non-blank lines of block appear consecutively among
+// the non-blank lines of lines, ignoring surrounding whitespace
+func containsBlock(lines, block []string) bool {
+	haystack := nonBlank(lines)
+	needle := nonBlank(block)
+	if len(needle) == 0 {
+		return false
+	}
+	for i := 0; i+len(needle) <= len(haystack); i++ {
+		match := true
+		for j := range needle {
+			if haystack[i+j] != needle[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// nonBlank returns the trimmed non-blank lines
+func nonBlank(lines []string) []string {
+	var result []string
+	for _, line := range lines {
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			result = append(result, trimmed)
+		}
+	}
+	return result
+}
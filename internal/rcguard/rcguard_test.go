@@ -0,0 +1,91 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rcguard
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const nvmBlock = "\nexport NVM_DIR=\"$HOME/.nvm\"\n[ -s \"$NVM_DIR/nvm.sh\" ] && \\. \"$NVM_DIR/nvm.sh\"\n"
+
+func TestReconcile(t *testing.T) {
+	tests := []struct {
+		name       string
+		before     string
+		after      string
+		want       string
+		added      int
+		duplicates int
+	}{
+		{"first install keeps the block", "alias ll='ls -l'\n", "alias ll='ls -l'\n" + nvmBlock, "alias ll='ls -l'\n" + nvmBlock, 2, 0},
+		{"repeated block is removed", "alias ll='ls -l'\n" + nvmBlock, "alias ll='ls -l'\n" + nvmBlock + nvmBlock, "alias ll='ls -l'\n" + nvmBlock, 0, 2},
+		{"only the repeated block is removed", nvmBlock, nvmBlock + nvmBlock + "\neval \"$(pyenv init -)\"\n", nvmBlock + "\neval \"$(pyenv init -)\"\n", 1, 2},
+		{"indentation is ignored", "  eval \"$(pyenv init -)\"\n", "  eval \"$(pyenv init -)\"\neval \"$(pyenv init -)\"\n", "  eval \"$(pyenv init -)\"\n", 0, 1},
+		{"new file", "", "export PATH=\"$HOME/.cargo/bin:$PATH\"\n", "export PATH=\"$HOME/.cargo/bin:$PATH\"\n", 1, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, change := reconcile(splitLines([]byte(tt.before)), splitLines([]byte(tt.after)))
+			if got := joinLines(lines, strings.HasSuffix(tt.after, "\n")); got != tt.want {
+				t.Errorf("reconcile() content = %q, want %q", got, tt.want)
+			}
+			if len(change.Added) != tt.added || len(change.Duplicates) != tt.duplicates {
+				t.Errorf("reconcile() added %d, duplicates %d, want %d and %d", len(change.Added), len(change.Duplicates), tt.added, tt.duplicates)
+			}
+		})
+	}
+}
+
+func TestGuardCheck(t *testing.T) {
+	home := t.TempDir()
+	zshrc := filepath.Join(home, ".zshrc")
+	if err := os.WriteFile(zshrc, []byte("alias ll='ls -l'\n"+nvmBlock), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	guard := Snapshot(home)
+	if err := os.WriteFile(zshrc, []byte("alias ll='ls -l'\n"+nvmBlock+nvmBlock), 0600); err != nil {
+		t.Fatal(err)
+	}
+	bashrc := filepath.Join(home, ".bashrc")
+	if err := os.WriteFile(bashrc, []byte(nvmBlock), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := guard.Check()
+	if err != nil {
+		t.Fatalf("Check() failed: %v", err)
+	}
+	if len(changes) != 2 || changes[0].Path != zshrc || changes[1].Path != bashrc {
+		t.Fatalf("Check() = %+v, want changes to .zshrc and .bashrc", changes)
+	}
+
+	data, _ := os.ReadFile(zshrc)
+	if string(data) != "alias ll='ls -l'\n"+nvmBlock {
+		t.Errorf(".zshrc = %q, want the duplicate block removed", data)
+	}
+	if info, _ := os.Stat(zshrc); info.Mode().Perm() != 0600 {
+		t.Errorf(".zshrc mode = %v, want 0600", info.Mode().Perm())
+	}
+	if data, _ := os.ReadFile(bashrc); string(data) != nvmBlock {
+		t.Errorf(".bashrc = %q, want it untouched", data)
+	}
+}
@@ -0,0 +1,245 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package release builds, signs and packages anvil release binaries. It produces the raw
+// binaries install.sh downloads, the tarballs the Homebrew formula installs from and a
+// checksums file covering both, matching what the release workflow publishes.
+package release
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/0xjuanma/anvil/internal/system"
+)
+
+const (
+	// ModulePath identifies the anvil source tree, releases only build from it
+	ModulePath = "github.com/0xjuanma/anvil"
+	// BinaryName is the name of the binary inside each archive, as the formula installs it
+	BinaryName = "anvil"
+	// ChecksumsFile lists the SHA-256 of every artifact
+	ChecksumsFile = "checksums.txt"
+
+	versionVariable = "main.appVersion"
+)
+
+// versionPattern accepts tags such as v1.2.0, v1.2.0-rc.1 and v1.2.0-beta.2
+var versionPattern = regexp.MustCompile(`^v\d+\.\d+\.\d+(-[0-9A-Za-z.]+)?$`)
+
+// Overridden in tests
+var runCommand = system.RunCommandWithEnv
+
+// Target is a platform a binary is built for
+type Target struct {
+	OS   string
+	Arch string
+}
+
+// String returns the target as GOOS/GOARCH
+func (t Target) String() string {
+	return t.OS + "/" + t.Arch
+}
+
+// DefaultTargets are the macOS builds the Homebrew formula ships
+var DefaultTargets = []Target{{OS: "darwin", Arch: "arm64"}, {OS: "darwin", Arch: "amd64"}}
+
+// ParseTargets parses GOOS/GOARCH pairs such as darwin/arm64
+func ParseTargets(values []string) ([]Target, error) {
+	var targets []Target
+	for _, value := range values {
+		goos, goarch, found := strings.Cut(strings.TrimSpace(value), "/")
+		if !found || goos == "" || goarch == "" {
+			return nil, fmt.Errorf("invalid target '%s', use GOOS/GOARCH such as darwin/arm64", value)
+		}
+		targets = append(targets, Target{OS: goos, Arch: goarch})
+	}
+	return targets, nil
+}
+
+// ValidateVersion checks version is a release tag such as v1.2.0
+func ValidateVersion(version string) error {
+	if !versionPattern.MatchString(version) {
+		return fmt.Errorf("invalid version '%s', use a tag such as v1.2.0 or v1.2.0-rc.1", version)
+	}
+	return nil
+}
+
+// LDFlags returns the linker flags that strip the binary and inject version
+func LDFlags(version string) string {
+	return fmt.Sprintf("-s -w -X %s=%s", versionVariable, version)
+}
+
+// BinaryFile returns the release name of a raw binary, the name install.sh downloads
+func BinaryFile(target Target) string {
+	return fmt.Sprintf("%s-%s-%s", BinaryName, target.OS, target.Arch)
+}
+
+// ArchiveFile returns the name of the tarball the Homebrew formula downloads
+func ArchiveFile(version string, target Target) string {
+	return fmt.Sprintf("%s-%s-%s-%s.tar.gz", BinaryName, version, target.OS, target.Arch)
+}
+
+// IsSourceTree reports whether dir is the root of the anvil source tree
+func IsSourceTree(dir string) bool {
+	data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if module, found := strings.CutPrefix(strings.TrimSpace(line), "module "); found {
+			return strings.TrimSpace(module) == ModulePath
+		}
+	}
+	return false
+}
+
+// Build cross-compiles the binary for target from sourceDir into outDir and returns its path
+func Build(ctx context.Context, sourceDir, outDir, version string, target Target) (string, error) {
+	binary := filepath.Join(outDir, BinaryFile(target))
+	env := []string{"GOOS=" + target.OS, "GOARCH=" + target.Arch, "CGO_ENABLED=0"}
+	result, err := runCommand(ctx, sourceDir, env, "go", "build", "-trimpath", "-ldflags", LDFlags(version), "-o", binary, ".")
+	if err != nil || !result.Success {
+		return "", fmt.Errorf("go build for %s failed: %s", target, commandFailure(result))
+	}
+	return binary, nil
+}
+
+// Sign signs binary with a Developer ID identity and the hardened runtime notarization requires
+func Sign(ctx context.Context, identity, binary string) error {
+	result, err := runCommand(ctx, "", nil, "codesign", "--force", "--options", "runtime", "--timestamp", "--sign", identity, binary)
+	if err != nil || !result.Success {
+		return fmt.Errorf("codesign %s failed: %s", filepath.Base(binary), commandFailure(result))
+	}
+	return nil
+}
+
+// Notarize submits a signed binary to Apple's notary service and waits for the verdict.
+// keychainProfile is a profile stored with 'xcrun notarytool store-credentials'.
+func Notarize(ctx context.Context, keychainProfile, binary string) error {
+	// notarytool only accepts archives, a bare binary is submitted inside a zip
+	zip := binary + ".zip"
+	defer os.Remove(zip)
+	result, err := runCommand(ctx, "", nil, "ditto", "-c", "-k", "--keepParent", binary, zip)
+	if err != nil || !result.Success {
+		return fmt.Errorf("failed to zip %s for notarization: %s", filepath.Base(binary), commandFailure(result))
+	}
+
+	result, err = runCommand(ctx, "", nil, "xcrun", "notarytool", "submit", zip, "--keychain-profile", keychainProfile, "--wait")
+	if err != nil || !result.Success || !strings.Contains(result.Output, "status: Accepted") {
+		return fmt.Errorf("notarization of %s failed: %s", filepath.Base(binary), commandFailure(result))
+	}
+	return nil
+}
+
+// commandFailure describes why a command failed, preferring its output
+func commandFailure(result *system.CommandResult) string {
+	if result == nil {
+		return "command did not run"
+	}
+	if output := strings.TrimSpace(result.Output); output != "" {
+		return output
+	}
+	return result.Error
+}
+
+// Archive writes binary into a gzipped tarball as BinaryName, the layout the formula's
+// 'bin.install "anvil"' expects
+func Archive(binary, archive string) error {
+	info, err := os.Stat(binary)
+	if err != nil {
+		return err
+	}
+	source, err := os.Open(binary)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	file, err := os.Create(archive)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	header := &tar.Header{
+		Name:    BinaryName,
+		Mode:    0755,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	if _, err := io.Copy(tw, source); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// WriteChecksums writes the SHA-256 of files to ChecksumsFile in dir, in the
+// 'shasum -a 256' format, and returns its path
+func WriteChecksums(dir string, files []string) (string, error) {
+	sorted := append([]string(nil), files...)
+	sort.Slice(sorted, func(i, j int) bool { return filepath.Base(sorted[i]) < filepath.Base(sorted[j]) })
+
+	var checksums strings.Builder
+	for _, path := range sorted {
+		sum, err := fileChecksum(path)
+		if err != nil {
+			return "", err
+		}
+		checksums.WriteString(fmt.Sprintf("%s  %s\n", sum, filepath.Base(path)))
+	}
+
+	path := filepath.Join(dir, ChecksumsFile)
+	if err := os.WriteFile(path, []byte(checksums.String()), 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// fileChecksum returns the hex SHA-256 of a file
+func fileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
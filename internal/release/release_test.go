@@ -0,0 +1,148 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/0xjuanma/anvil/internal/system"
+)
+
+func TestValidateVersion(t *testing.T) {
+	for _, version := range []string{"v1.2.0", "v1.2.0-rc.1", "v10.0.12-beta.2"} {
+		if err := ValidateVersion(version); err != nil {
+			t.Errorf("ValidateVersion(%q) error = %v", version, err)
+		}
+	}
+	for _, version := range []string{"1.2.0", "v1.2", "v1.2.0 ", "latest", "v1.2.0-"} {
+		if err := ValidateVersion(version); err == nil {
+			t.Errorf("ValidateVersion(%q) accepted an invalid version", version)
+		}
+	}
+}
+
+func TestParseTargets(t *testing.T) {
+	targets, err := ParseTargets([]string{"darwin/arm64", " linux/amd64"})
+	if err != nil || len(targets) != 2 || targets[1] != (Target{OS: "linux", Arch: "amd64"}) {
+		t.Errorf("ParseTargets() = %v, %v", targets, err)
+	}
+	if _, err := ParseTargets([]string{"darwin"}); err == nil {
+		t.Error("ParseTargets() accepted a target without an arch")
+	}
+}
+
+func TestNames(t *testing.T) {
+	target := Target{OS: "darwin", Arch: "arm64"}
+	if got := BinaryFile(target); got != "anvil-darwin-arm64" {
+		t.Errorf("BinaryFile() = %q", got)
+	}
+	if got := ArchiveFile("v1.2.0", target); got != "anvil-v1.2.0-darwin-arm64.tar.gz" {
+		t.Errorf("ArchiveFile() = %q", got)
+	}
+	if got := LDFlags("v1.2.0"); got != "-s -w -X main.appVersion=v1.2.0" {
+		t.Errorf("LDFlags() = %q", got)
+	}
+}
+
+func TestIsSourceTree(t *testing.T) {
+	dir := t.TempDir()
+	if IsSourceTree(dir) {
+		t.Error("IsSourceTree() = true without go.mod")
+	}
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/other\n"), 0644)
+	if IsSourceTree(dir) {
+		t.Error("IsSourceTree() = true for another module")
+	}
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("// anvil\nmodule github.com/0xjuanma/anvil\n\ngo 1.23\n"), 0644)
+	if !IsSourceTree(dir) {
+		t.Error("IsSourceTree() = false for the anvil module")
+	}
+}
+
+func TestBuild(t *testing.T) {
+	var gotDir string
+	var gotEnv, gotArgs []string
+	original := runCommand
+	runCommand = func(ctx context.Context, dir string, env []string, command string, args ...string) (*system.CommandResult, error) {
+		gotDir, gotEnv, gotArgs = dir, env, append([]string{command}, args...)
+		return &system.CommandResult{Success: true}, nil
+	}
+	t.Cleanup(func() { runCommand = original })
+
+	binary, err := Build(context.Background(), "/src", "/src/dist", "v1.2.0", Target{OS: "darwin", Arch: "amd64"})
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if binary != "/src/dist/anvil-darwin-amd64" || gotDir != "/src" {
+		t.Errorf("Build() = %q in %q", binary, gotDir)
+	}
+	if strings.Join(gotEnv, " ") != "GOOS=darwin GOARCH=amd64 CGO_ENABLED=0" {
+		t.Errorf("Build() env = %v", gotEnv)
+	}
+	if want := "go build -trimpath -ldflags -s -w -X main.appVersion=v1.2.0 -o /src/dist/anvil-darwin-amd64 ."; strings.Join(gotArgs, " ") != want {
+		t.Errorf("Build() ran %q, want %q", strings.Join(gotArgs, " "), want)
+	}
+}
+
+func TestArchiveAndChecksums(t *testing.T) {
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "anvil-darwin-arm64")
+	if err := os.WriteFile(binary, []byte("binary"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(dir, "anvil-v1.2.0-darwin-arm64.tar.gz")
+	if err := Archive(binary, archive); err != nil {
+		t.Fatalf("Archive() error = %v", err)
+	}
+
+	file, _ := os.Open(archive)
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	header, err := tr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := io.ReadAll(tr)
+	if header.Name != "anvil" || header.Mode != 0755 || string(content) != "binary" {
+		t.Errorf("archive holds %s (%o) = %q, want anvil (755) = binary", header.Name, header.Mode, content)
+	}
+	if _, err := tr.Next(); err != io.EOF {
+		t.Error("archive holds more than the binary")
+	}
+
+	path, err := WriteChecksums(dir, []string{archive, binary})
+	if err != nil {
+		t.Fatalf("WriteChecksums() error = %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	// sha256("binary")
+	if len(lines) != 2 || lines[0] != "9a3a45d01531a20e89ac6ae10b0b0beb0492acd7216a368aa062d1a5fecaf9cd  anvil-darwin-arm64" || !strings.HasSuffix(lines[1], "  anvil-v1.2.0-darwin-arm64.tar.gz") {
+		t.Errorf("checksums = %q", data)
+	}
+}
@@ -0,0 +1,110 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reminder
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/github"
+)
+
+// skippedCommands never trigger the push reminder, either because they push themselves,
+// run inside git as a hook, or because settings may not exist yet
+var skippedCommands = []string{
+	"anvil init",
+	"anvil bootstrap",
+	"anvil update",
+	"anvil push",
+	"anvil config push",
+	"anvil config hook",
+	"anvil help",
+	"anvil completion",
+}
+
+// shouldSkip reports whether the reminder is suppressed for the given command
+func shouldSkip(commandPath string) bool {
+	for _, skipped := range skippedCommands {
+		if commandPath == skipped || strings.HasPrefix(commandPath, skipped+" ") {
+			return true
+		}
+	}
+	return commandPath == constants.ANVIL
+}
+
+// FindUnpushedApps returns the apps, including anvil's own settings, whose local configs
+// differ from the local clone. Apps whose mode forbids pushing are ignored.
+func FindUnpushedApps(cfg *config.AnvilConfig) []string {
+	client := github.NewGitHubClient(cfg.GitHub.ConfigRepo, cfg.GitHub.Branch, cfg.GitHub.LocalPath, "", "", "", "")
+	client.MaterializeSymlinks = cfg.GitHub.MaterializeSymlinks
+
+	paths := map[string]string{constants.ANVIL: config.GetAnvilConfigPath()}
+	for app, path := range cfg.Configs {
+		if path != "" && cfg.CheckAppMode(app, constants.OpPush) == nil {
+			paths[app] = path
+		}
+	}
+
+	var unpushed []string
+	for app, path := range paths {
+		filter := cfg.ConfigFilters[app]
+		client.Include, client.Exclude = filter.Include, filter.Exclude
+		if changed, err := client.HasUnpushedChanges(app, path); err == nil && changed {
+			unpushed = append(unpushed, app)
+		}
+	}
+	sort.Strings(unpushed)
+	return unpushed
+}
+
+// FormatReminder renders the one-line reminder for the given apps
+func FormatReminder(apps []string) string {
+	target := "<app>"
+	if len(apps) == 1 {
+		target = apps[0]
+	}
+	return fmt.Sprintf("💡 Unpushed config changes: %s - run 'anvil config push %s'", strings.Join(apps, ", "), target)
+}
+
+// MaybeRemindPush checks for unpushed config changes at most once per configured interval
+// and writes a one-line reminder to w. Any failure is silently ignored.
+func MaybeRemindPush(commandPath string, now time.Time, w io.Writer) {
+	if shouldSkip(commandPath) {
+		return
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil || cfg.Reminders.PushDisabled || cfg.GitHub.ConfigRepo == "" || cfg.GitHub.LocalPath == "" {
+		return
+	}
+
+	if now.Sub(config.LastPushReminderCheck()) < cfg.Reminders.PushInterval() {
+		return
+	}
+	if err := config.RecordPushReminderCheck(now); err != nil {
+		return
+	}
+
+	if apps := FindUnpushedApps(cfg); len(apps) > 0 {
+		fmt.Fprintln(w, FormatReminder(apps))
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reminder
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/config"
+)
+
+// setupReminderEnv creates settings with a local clone holding pushed copies of two apps,
+// one of which has since been edited locally
+func setupReminderEnv(t *testing.T, extraSettings string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	clone := filepath.Join(home, "dotfiles")
+	local := filepath.Join(home, "local")
+	for _, dir := range []string{filepath.Join(home, ".anvil"), filepath.Join(clone, "zsh"), filepath.Join(clone, "git"), filepath.Join(local, "zsh")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeFile(t, filepath.Join(local, "zsh", ".zshrc"), "export EDITOR=vim\n")
+	writeFile(t, filepath.Join(clone, "zsh", ".zshrc"), "export EDITOR=nano\n")
+	writeFile(t, filepath.Join(local, ".gitconfig"), "[user]\n")
+	writeFile(t, filepath.Join(clone, "git", ".gitconfig"), "[user]\n")
+
+	settings := fmt.Sprintf(`version: "1"
+tools:
+  required_tools: [git]
+groups:
+  dev: [git]
+  essentials: [git]
+configs:
+  zsh: %s
+  git: %s
+github:
+  config_repo: user/dotfiles
+  branch: main
+  local_path: %s
+%s`, filepath.Join(local, "zsh"), filepath.Join(local, ".gitconfig"), clone, extraSettings)
+	writeFile(t, filepath.Join(home, ".anvil", "settings.yaml"), settings)
+	config.InvalidateConfigCache()
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindUnpushedApps(t *testing.T) {
+	setupReminderEnv(t, "")
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// settings.yaml was never pushed, git matches its pushed copy, zsh was edited
+	got := FindUnpushedApps(cfg)
+	if strings.Join(got, ",") != "zsh" {
+		t.Errorf("FindUnpushedApps() = %v, want [zsh]", got)
+	}
+}
+
+func TestMaybeRemindPush(t *testing.T) {
+	setupReminderEnv(t, "")
+	now := time.Now()
+
+	var out bytes.Buffer
+	MaybeRemindPush("anvil install", now, &out)
+	if !strings.Contains(out.String(), "zsh") {
+		t.Fatalf("expected a reminder for zsh, got %q", out.String())
+	}
+
+	out.Reset()
+	MaybeRemindPush("anvil install", now.Add(time.Hour), &out)
+	if out.Len() != 0 {
+		t.Errorf("reminder shown twice within the interval: %q", out.String())
+	}
+
+	MaybeRemindPush("anvil install", now.Add(25*time.Hour), &out)
+	if out.Len() == 0 {
+		t.Error("expected a reminder once the interval passed")
+	}
+
+	out.Reset()
+	MaybeRemindPush("anvil config push zsh", now.Add(50*time.Hour), &out)
+	if out.Len() != 0 {
+		t.Errorf("reminder shown for config push: %q", out.String())
+	}
+}
+
+func TestMaybeRemindPushDisabled(t *testing.T) {
+	setupReminderEnv(t, "reminders:\n  push_disabled: true\n")
+
+	var out bytes.Buffer
+	MaybeRemindPush("anvil install", time.Now(), &out)
+	if out.Len() != 0 {
+		t.Errorf("reminder shown while disabled: %q", out.String())
+	}
+}
@@ -0,0 +1,199 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package services lists, starts and stops Homebrew services and compares them with the
+// services section of settings.yaml, which names the services that should be started or stopped.
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/system"
+)
+
+// Overridden in tests
+var runBrew = system.RunCommand
+
+// Status values reported by 'brew services'
+const (
+	StatusStarted   = "started"
+	StatusScheduled = "scheduled" // Runs on an interval rather than continuously
+	StatusStopped   = "stopped"
+	StatusNone      = "none" // Installed but never registered
+	StatusError     = "error"
+)
+
+// Service is a formula with a service definition, as listed by 'brew services'
+type Service struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	User   string `json:"user"`
+	File   string `json:"file"`
+}
+
+// Running reports whether launchd or systemd is running the service
+func (s Service) Running() bool {
+	return s.Status == StatusStarted || s.Status == StatusScheduled
+}
+
+// State is a service from settings.yaml next to what brew reports for it
+type State struct {
+	Name      string
+	Want      string // config.ServiceStarted or config.ServiceStopped
+	Status    string // Empty when the formula is not installed
+	Installed bool
+}
+
+// InSync reports whether the service is already in the wanted state. A service that is
+// not installed counts as stopped.
+func (s State) InSync() bool {
+	running := Service{Status: s.Status}.Running()
+	if s.Want == config.ServiceStarted {
+		return running
+	}
+	return !running
+}
+
+// List returns the services brew knows about, sorted by name
+func List() ([]Service, error) {
+	result, err := runBrew(constants.BrewCommand, constants.BrewServices, "list", "--json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+	if !result.Success {
+		return nil, fmt.Errorf("failed to list services: %s", failure(result))
+	}
+	return parseList(result.Output)
+}
+
+// Start starts a service now and at login
+func Start(name string) error {
+	return run("start", name)
+}
+
+// Stop stops a service and keeps it from starting at login
+func Stop(name string) error {
+	return run("stop", name)
+}
+
+// Plan pairs every service in wanted with its current status, sorted by name
+func Plan(wanted map[string]string) ([]State, error) {
+	if err := config.ValidateServices(wanted); err != nil {
+		return nil, err
+	}
+	if len(wanted) == 0 {
+		return nil, nil
+	}
+
+	listed, err := List()
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]Service, len(listed))
+	for _, service := range listed {
+		byName[service.Name] = service
+	}
+
+	states := make([]State, 0, len(wanted))
+	for name, want := range wanted {
+		service, installed := byName[name]
+		states = append(states, State{Name: name, Want: want, Status: service.Status, Installed: installed})
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	return states, nil
+}
+
+// Drifted returns the services that are not in their wanted state
+func Drifted(states []State) []State {
+	var drifted []State
+	for _, state := range states {
+		if !state.InSync() {
+			drifted = append(drifted, state)
+		}
+	}
+	return drifted
+}
+
+// Apply starts and stops services until they match wanted and returns the ones it changed.
+// Services that are not installed cannot be started and are reported as an error after the rest are applied.
+func Apply(wanted map[string]string) ([]State, error) {
+	states, err := Plan(wanted)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []State
+	var missing []string
+	for _, state := range Drifted(states) {
+		if !state.Installed {
+			missing = append(missing, state.Name)
+			continue
+		}
+		action := Stop
+		if state.Want == config.ServiceStarted {
+			action = Start
+		}
+		if err := action(state.Name); err != nil {
+			return changed, err
+		}
+		changed = append(changed, state)
+	}
+
+	if len(missing) > 0 {
+		return changed, fmt.Errorf("not installed, cannot start: %s", strings.Join(missing, ", "))
+	}
+	return changed, nil
+}
+
+// run executes a 'brew services' action for a single service
+func run(action, name string) error {
+	result, err := runBrew(constants.BrewCommand, constants.BrewServices, action, name)
+	if err != nil {
+		return fmt.Errorf("failed to %s %s: %w", action, name, err)
+	}
+	if !result.Success {
+		return fmt.Errorf("failed to %s %s: %s", action, name, failure(result))
+	}
+	return nil
+}
+
+// parseList decodes the output of 'brew services list --json'
+func parseList(output string) ([]Service, error) {
+	output = strings.TrimSpace(output)
+	if output == "" {
+		return nil, nil
+	}
+
+	var listed []Service
+	if err := json.Unmarshal([]byte(output), &listed); err != nil {
+		return nil, fmt.Errorf("failed to parse brew services output: %w", err)
+	}
+	sort.Slice(listed, func(i, j int) bool { return listed[i].Name < listed[j].Name })
+	return listed, nil
+}
+
+// failure describes a failed brew command by its output, or its exit status when it printed nothing
+func failure(result *system.CommandResult) string {
+	if output := strings.TrimSpace(result.Output); output != "" {
+		return output
+	}
+	return result.Error
+}
@@ -0,0 +1,105 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package services
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/system"
+)
+
+// fakeBrew stands in for 'brew services', start and stop update the statuses it lists
+func fakeBrew(t *testing.T, statuses map[string]string) {
+	t.Helper()
+	original := runBrew
+	t.Cleanup(func() { runBrew = original })
+
+	runBrew = func(command string, args ...string) (*system.CommandResult, error) {
+		switch args[1] {
+		case "list":
+			var listed []Service
+			for name, status := range statuses {
+				listed = append(listed, Service{Name: name, Status: status})
+			}
+			data, _ := json.Marshal(listed)
+			return &system.CommandResult{Output: string(data), Success: true}, nil
+		case "start":
+			statuses[args[2]] = StatusStarted
+		case "stop":
+			statuses[args[2]] = StatusNone
+		}
+		return &system.CommandResult{Success: true}, nil
+	}
+}
+
+func TestApply(t *testing.T) {
+	statuses := map[string]string{"postgresql@16": StatusNone, "redis": StatusStarted, "nginx": StatusError}
+	fakeBrew(t, statuses)
+
+	wanted := map[string]string{
+		"postgresql@16": config.ServiceStarted,
+		"redis":         config.ServiceStopped,
+		"nginx":         config.ServiceStopped,
+	}
+
+	states, err := Plan(wanted)
+	if err != nil {
+		t.Fatalf("Plan() failed: %v", err)
+	}
+	want := []State{
+		{Name: "nginx", Want: config.ServiceStopped, Status: StatusError, Installed: true},
+		{Name: "postgresql@16", Want: config.ServiceStarted, Status: StatusNone, Installed: true},
+		{Name: "redis", Want: config.ServiceStopped, Status: StatusStarted, Installed: true},
+	}
+	if !reflect.DeepEqual(states, want) {
+		t.Fatalf("Plan() = %+v, want %+v", states, want)
+	}
+
+	changed, err := Apply(wanted)
+	if err != nil {
+		t.Fatalf("Apply() failed: %v", err)
+	}
+	if len(changed) != 2 || statuses["postgresql@16"] != StatusStarted || statuses["redis"] != StatusNone {
+		t.Errorf("Apply() changed %+v, statuses are %v", changed, statuses)
+	}
+
+	if changed, err := Apply(wanted); err != nil || len(changed) != 0 {
+		t.Errorf("second Apply() = %+v, %v, want nothing to change", changed, err)
+	}
+}
+
+func TestApplyReportsMissingServices(t *testing.T) {
+	fakeBrew(t, map[string]string{"redis": StatusNone})
+
+	changed, err := Apply(map[string]string{"redis": config.ServiceStarted, "mysql": config.ServiceStarted, "mongodb": config.ServiceStopped})
+	if err == nil || !strings.Contains(err.Error(), "mysql") {
+		t.Fatalf("Apply() error = %v, want mysql reported as not installed", err)
+	}
+	if len(changed) != 1 || changed[0].Name != "redis" {
+		t.Errorf("Apply() changed %+v, want redis started", changed)
+	}
+}
+
+func TestPlanRejectsUnknownStates(t *testing.T) {
+	if _, err := Plan(map[string]string{"redis": "running"}); err == nil {
+		t.Error("Plan() accepted 'running', want an error")
+	}
+}
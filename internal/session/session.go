@@ -0,0 +1,263 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/system"
+	"github.com/0xjuanma/anvil/internal/version"
+)
+
+// DirName is the directory under ~/.anvil where recorded sessions are kept
+const DirName = "sessions"
+
+// idFormat names sessions by their start time, e.g. 20261016-150405
+const idFormat = "20060102-150405"
+
+// Command is an external command run during a session, such as a brew install
+type Command struct {
+	Command   string        `json:"command"`
+	ExitCode  int           `json:"exit_code"`
+	Success   bool          `json:"success"`
+	Duration  time.Duration `json:"duration"`
+	Output    string        `json:"output,omitempty"`
+	StartedAt time.Time     `json:"started_at"`
+}
+
+// Step is a tracked unit of work, such as installing one tool or one provisioning step
+type Step struct {
+	Name      string        `json:"name"`
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
+	Error     string        `json:"error,omitempty"`
+	Commands  []Command     `json:"commands,omitempty"`
+	Steps     []*Step       `json:"steps,omitempty"`
+}
+
+// Failed reports whether the step returned an error
+func (s *Step) Failed() bool {
+	return s.Error != ""
+}
+
+// Session is the transcript of a recorded install or provision run
+type Session struct {
+	ID           string    `json:"id"`
+	AnvilVersion string    `json:"anvil_version"`
+	Invocation   string    `json:"invocation"`
+	Host         string    `json:"host"`
+	StartedAt    time.Time `json:"started_at"`
+	FinishedAt   time.Time `json:"finished_at"`
+	Error        string    `json:"error,omitempty"`
+	Steps        []*Step   `json:"steps"`
+	Commands     []Command `json:"commands,omitempty"` // Commands run outside of any step
+}
+
+// Duration is the wall time of the whole session
+func (s *Session) Duration() time.Duration {
+	return s.FinishedAt.Sub(s.StartedAt)
+}
+
+var (
+	mu        sync.Mutex
+	current   *Session
+	openSteps []*Step
+)
+
+// Start begins recording a session for the current process
+func Start(invocation string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	host, _ := os.Hostname()
+	now := time.Now()
+	current = &Session{
+		ID:           now.Format(idFormat),
+		AnvilVersion: version.GetVersion(),
+		Invocation:   invocation,
+		Host:         host,
+		StartedAt:    now,
+		Steps:        []*Step{},
+	}
+	openSteps = nil
+	system.SetCommandObserver(recordCommand)
+}
+
+// IsRecording reports whether a session is being recorded
+func IsRecording() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return current != nil
+}
+
+// Track runs fn as a named step of the session, recording its duration, error and the
+// commands it runs. Steps started inside fn are nested. Without a session it just runs fn.
+func Track(name string, fn func() error) error {
+	step := beginStep(name)
+	start := time.Now()
+	err := fn()
+	endStep(step, time.Since(start), err)
+	return err
+}
+
+// beginStep opens a step under the innermost open step, or returns nil when not recording
+func beginStep(name string) *Step {
+	mu.Lock()
+	defer mu.Unlock()
+	if current == nil {
+		return nil
+	}
+
+	step := &Step{Name: name, StartedAt: time.Now()}
+	if len(openSteps) > 0 {
+		parent := openSteps[len(openSteps)-1]
+		parent.Steps = append(parent.Steps, step)
+	} else {
+		current.Steps = append(current.Steps, step)
+	}
+	openSteps = append(openSteps, step)
+	return step
+}
+
+// endStep closes a step opened by beginStep
+func endStep(step *Step, duration time.Duration, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if step == nil {
+		return
+	}
+
+	step.Duration = duration
+	if err != nil {
+		step.Error = err.Error()
+	}
+	for i := len(openSteps) - 1; i >= 0; i-- {
+		if openSteps[i] == step {
+			openSteps = append(openSteps[:i], openSteps[i+1:]...)
+			break
+		}
+	}
+}
+
+// recordCommand attaches a finished command to the innermost open step
+func recordCommand(result *system.CommandResult, started time.Time, duration time.Duration) {
+	mu.Lock()
+	defer mu.Unlock()
+	if current == nil {
+		return
+	}
+
+	command := Command{
+		Command:   result.Command,
+		ExitCode:  result.ExitCode,
+		Success:   result.Success,
+		Duration:  duration,
+		Output:    result.Output,
+		StartedAt: started,
+	}
+	if len(openSteps) > 0 {
+		step := openSteps[len(openSteps)-1]
+		step.Commands = append(step.Commands, command)
+		return
+	}
+	current.Commands = append(current.Commands, command)
+}
+
+// Finish stops recording and writes the session to ~/.anvil/sessions, returning its path
+func Finish(runErr error) (string, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if current == nil {
+		return "", fmt.Errorf("no session is being recorded")
+	}
+	system.SetCommandObserver(nil)
+
+	recorded := current
+	current = nil
+	openSteps = nil
+
+	recorded.FinishedAt = time.Now()
+	if runErr != nil {
+		recorded.Error = runErr.Error()
+	}
+
+	dir := Dir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create sessions directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(recorded, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode session: %w", err)
+	}
+
+	path := filepath.Join(dir, recorded.ID+".json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write session: %w", err)
+	}
+	return path, nil
+}
+
+// Dir returns the directory recorded sessions are written to
+func Dir() string {
+	return filepath.Join(config.GetAnvilConfigDirectory(), DirName)
+}
+
+// List returns the ids of recorded sessions, newest first
+func List() ([]string, error) {
+	entries, err := os.ReadDir(Dir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		if id, ok := strings.CutSuffix(entry.Name(), ".json"); ok && !entry.IsDir() {
+			ids = append(ids, id)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+	return ids, nil
+}
+
+// Load reads a recorded session by id
+func Load(id string) (*Session, error) {
+	data, err := os.ReadFile(filepath.Join(Dir(), filepath.Base(id)+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("session '%s' not found, run 'anvil sessions list' to see recorded sessions", id)
+		}
+		return nil, err
+	}
+
+	var recorded Session
+	if err := json.Unmarshal(data, &recorded); err != nil {
+		return nil, fmt.Errorf("failed to decode session '%s': %w", id, err)
+	}
+	return &recorded, nil
+}
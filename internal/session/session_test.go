@@ -0,0 +1,84 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package session
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/system"
+)
+
+func TestRecordSession(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	// Without a session Track only runs the function
+	if err := Track("untracked", func() error { return nil }); err != nil || IsRecording() {
+		t.Fatalf("Track without a session: err = %v, recording = %v", err, IsRecording())
+	}
+
+	Start("anvil provision work --record")
+	Track("install group dev", func() error {
+		Track("git", func() error {
+			system.RunCommand("sh", "-c", "echo installed git")
+			return nil
+		})
+		return Track("jq", func() error {
+			system.RunCommand("sh", "-c", "echo boom; exit 3")
+			return errors.New("brew install failed")
+		})
+	})
+	system.RunCommand("true")
+
+	path, err := Finish(errors.New("1 step failed"))
+	if err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+	if IsRecording() {
+		t.Error("session still recording after Finish")
+	}
+
+	ids, err := List()
+	if err != nil || len(ids) != 1 {
+		t.Fatalf("List() = %v, %v, want one session", ids, err)
+	}
+	recorded, err := Load(ids[0])
+	if err != nil {
+		t.Fatalf("Load(%s) failed: %v (written to %s)", ids[0], err, path)
+	}
+
+	if len(recorded.Steps) != 1 || len(recorded.Steps[0].Steps) != 2 {
+		t.Fatalf("steps = %+v, want one group step with two tools", recorded.Steps)
+	}
+	git, jq := recorded.Steps[0].Steps[0], recorded.Steps[0].Steps[1]
+	if git.Failed() || len(git.Commands) != 1 || git.Commands[0].Output != "installed git\n" {
+		t.Errorf("git step = %+v, want one successful command with its output", git)
+	}
+	if !jq.Failed() || len(jq.Commands) != 1 || jq.Commands[0].ExitCode != 3 {
+		t.Errorf("jq step = %+v, want a failed step with exit code 3", jq)
+	}
+	if !recorded.Steps[0].Failed() {
+		t.Error("group step should record the error returned by its last tool")
+	}
+	if len(recorded.Commands) != 1 || recorded.Error != "1 step failed" {
+		t.Errorf("session commands = %v, error = %q", recorded.Commands, recorded.Error)
+	}
+	if recorded.Duration() < 0 || recorded.StartedAt.After(time.Now()) {
+		t.Errorf("unexpected timing: started %v, duration %v", recorded.StartedAt, recorded.Duration())
+	}
+}
@@ -0,0 +1,154 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package summary builds the footer printed after a command: what the command changed,
+// how long it took and what to do next. It listens on the events bus.
+package summary
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/events"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+)
+
+// maxSuggestions keeps the footer to a glance
+const maxSuggestions = 2
+
+// Recorder collects the events published while a command runs
+type Recorder struct {
+	mu          sync.Mutex
+	started     time.Time
+	events      []events.Event
+	unsubscribe func()
+}
+
+// Start subscribes a new recorder to the events bus
+func Start(now time.Time) *Recorder {
+	r := &Recorder{started: now}
+	r.unsubscribe = events.Subscribe(r.record)
+	return r
+}
+
+// record keeps an event, called by the bus from any goroutine
+func (r *Recorder) record(event events.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+// Stop unsubscribes the recorder and returns what it collected and how long the command ran
+func (r *Recorder) Stop(now time.Time) ([]events.Event, time.Duration) {
+	r.unsubscribe()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]events.Event(nil), r.events...), now.Sub(r.started)
+}
+
+// targets returns the distinct targets of one kind of event, in the order they happened
+func targets(recorded []events.Event, kind events.Kind) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, event := range recorded {
+		if event.Kind == kind && !seen[event.Target] {
+			seen[event.Target] = true
+			names = append(names, event.Target)
+		}
+	}
+	return names
+}
+
+// Changes describes what the events changed, e.g. ["2 installed", "1 tracked"]
+func Changes(recorded []events.Event) []string {
+	var changes []string
+	for _, kind := range []events.Kind{events.Installed, events.Uninstalled, events.Failed, events.Tracked, events.Pulled, events.Synced} {
+		if n := len(targets(recorded, kind)); n > 0 {
+			changes = append(changes, fmt.Sprintf("%d %s", n, kind))
+		}
+	}
+	switch branches := targets(recorded, events.Pushed); len(branches) {
+	case 0:
+	case 1:
+		changes = append(changes, "pushed "+branches[0])
+	default:
+		changes = append(changes, fmt.Sprintf("%d branches pushed", len(branches)))
+	}
+	return changes
+}
+
+// Suggestions returns up to two next steps for what the events changed. unpushed lists the
+// apps whose local configs differ from the config repository after the command.
+func Suggestions(recorded []events.Event, unpushed []string) []string {
+	var suggestions []string
+
+	switch failed := targets(recorded, events.Failed); len(failed) {
+	case 0:
+	case 1:
+		suggestions = append(suggestions, fmt.Sprintf("Retry with 'anvil install %s' - it failed to install", failed[0]))
+	default:
+		suggestions = append(suggestions, fmt.Sprintf("Run 'anvil doctor' - %d tools failed to install", len(failed)))
+	}
+
+	for _, event := range recorded {
+		if event.Kind == events.Pushed && event.Details != "" {
+			suggestions = append(suggestions, fmt.Sprintf("Open a pull request for %s: %s", event.Target, event.Details))
+			break
+		}
+	}
+
+	if pulled := targets(recorded, events.Pulled); len(pulled) > 0 && len(targets(recorded, events.Synced)) == 0 {
+		suggestions = append(suggestions, fmt.Sprintf("Run 'anvil config sync %s' - pulled configs are not applied yet", singleOr(pulled, "<app>")))
+	}
+
+	if len(unpushed) > 0 && len(targets(recorded, events.Pushed)) == 0 {
+		noun := "app configs"
+		if len(unpushed) == 1 {
+			noun = "app config"
+		}
+		suggestions = append(suggestions, fmt.Sprintf("Run 'anvil config push %s' - %d %s changed", singleOr(unpushed, "<app>"), len(unpushed), noun))
+	}
+
+	if len(suggestions) > maxSuggestions {
+		suggestions = suggestions[:maxSuggestions]
+	}
+	return suggestions
+}
+
+// singleOr returns the only value, or the placeholder when there are several
+func singleOr(values []string, placeholder string) string {
+	if len(values) == 1 {
+		return values[0]
+	}
+	return placeholder
+}
+
+// Render formats the footer, or returns an empty string when the command changed nothing
+func Render(recorded []events.Event, elapsed time.Duration, suggestions []string) string {
+	changes := Changes(recorded)
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var footer strings.Builder
+	footer.WriteString(fmt.Sprintf("── %s · took %s\n", strings.Join(changes, " · "), charm.FormatElapsed(elapsed)))
+	for _, suggestion := range suggestions {
+		footer.WriteString("💡 " + suggestion + "\n")
+	}
+	return footer.String()
+}
@@ -0,0 +1,113 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package summary
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/events"
+)
+
+func TestRecorder(t *testing.T) {
+	start := time.Now()
+	r := Start(start)
+	events.Publish(events.Installed, "git", "")
+	events.Publish(events.Installed, "git", "")
+	events.Publish(events.Tracked, "git", "dev")
+	recorded, elapsed := r.Stop(start.Add(3 * time.Second))
+	events.Publish(events.Installed, "jq", "")
+
+	if len(recorded) != 3 || elapsed != 3*time.Second {
+		t.Fatalf("Stop() = %d events in %v, want 3 in 3s", len(recorded), elapsed)
+	}
+	if got := Changes(recorded); !reflect.DeepEqual(got, []string{"1 installed", "1 tracked"}) {
+		t.Errorf("Changes() = %v", got)
+	}
+}
+
+func TestSuggestions(t *testing.T) {
+	tests := []struct {
+		name     string
+		recorded []events.Event
+		unpushed []string
+		want     []string
+	}{
+		{
+			name:     "tracked app with unpushed settings",
+			recorded: []events.Event{{Kind: events.Tracked, Target: "git"}},
+			unpushed: []string{"anvil"},
+			want:     []string{"Run 'anvil config push anvil' - 1 app config changed"},
+		},
+		{
+			name:     "pulled but not synced",
+			recorded: []events.Event{{Kind: events.Pulled, Target: "zsh"}, {Kind: events.Pulled, Target: "nvim"}},
+			want:     []string{"Run 'anvil config sync <app>' - pulled configs are not applied yet"},
+		},
+		{
+			name:     "pull followed by sync",
+			recorded: []events.Event{{Kind: events.Pulled, Target: "zsh"}, {Kind: events.Synced, Target: "/home/me/.zshrc"}},
+		},
+		{
+			name: "pushed branch never suggests pushing again",
+			recorded: []events.Event{
+				{Kind: events.Pushed, Target: "config-push-zsh", Details: "https://github.com/me/dotfiles/compare/main...config-push-zsh"},
+			},
+			unpushed: []string{"zsh"},
+			want:     []string{"Open a pull request for config-push-zsh: https://github.com/me/dotfiles/compare/main...config-push-zsh"},
+		},
+		{
+			name: "at most two suggestions",
+			recorded: []events.Event{
+				{Kind: events.Failed, Target: "docker"}, {Kind: events.Failed, Target: "slack"}, {Kind: events.Pulled, Target: "zsh"},
+			},
+			unpushed: []string{"anvil", "zsh"},
+			want: []string{
+				"Run 'anvil doctor' - 2 tools failed to install",
+				"Run 'anvil config sync zsh' - pulled configs are not applied yet",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Suggestions(tt.recorded, tt.unpushed); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Suggestions() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	if footer := Render(nil, time.Second, nil); footer != "" {
+		t.Errorf("Render() without events = %q, want empty", footer)
+	}
+
+	recorded := []events.Event{
+		{Kind: events.Installed, Target: "git"},
+		{Kind: events.Installed, Target: "jq"},
+		{Kind: events.Pushed, Target: "config-push-anvil"},
+	}
+	footer := Render(recorded, 2500*time.Millisecond, []string{"Run 'anvil doctor'"})
+	for _, want := range []string{"2 installed · pushed config-push-anvil · took 2.5s", "💡 Run 'anvil doctor'"} {
+		if !strings.Contains(footer, want) {
+			t.Errorf("Render() = %q, missing %q", footer, want)
+		}
+	}
+}
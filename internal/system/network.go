@@ -0,0 +1,208 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package system
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Network holds the proxy and TLS settings applied to anvil's HTTP requests and to every
+// command it runs, for machines behind a corporate proxy with TLS interception
+type Network struct {
+	HTTPProxy  string // Proxy for http:// requests
+	HTTPSProxy string // Proxy for https:// requests
+	NoProxy    string // Comma separated hosts, domains (".corp.com") or "*" reached directly
+	CABundle   string // PEM file with extra trusted certificates, e.g. the proxy's CA
+}
+
+var (
+	// networkEnv holds the proxy and CA variables applied to every command
+	networkEnv []string
+	// httpClient is used for every download anvil makes itself
+	httpClient = http.DefaultClient
+)
+
+// IsZero reports whether no network setting is configured
+func (n Network) IsZero() bool {
+	return n == Network{}
+}
+
+// Env returns the variables that apply the settings to git, brew, curl and other commands.
+// Both spellings of the proxy variables are set since tools disagree on which they read.
+func (n Network) Env() []string {
+	var env []string
+	add := func(value string, names ...string) {
+		if value == "" {
+			return
+		}
+		for _, name := range names {
+			env = append(env, name+"="+value)
+		}
+	}
+	add(n.HTTPProxy, "HTTP_PROXY", "http_proxy")
+	add(n.HTTPSProxy, "HTTPS_PROXY", "https_proxy")
+	add(n.NoProxy, "NO_PROXY", "no_proxy")
+	add(n.CABundle, "SSL_CERT_FILE", "CURL_CA_BUNDLE", "GIT_SSL_CAINFO")
+	return env
+}
+
+// SetNetwork applies the settings to HTTPClient and to every command run through this
+// package. Settings that are not configured keep the environment's behavior.
+func SetNetwork(n Network) error {
+	client, err := newHTTPClient(n)
+	if err != nil {
+		return err
+	}
+
+	commandHooksMutex.Lock()
+	defer commandHooksMutex.Unlock()
+	networkEnv = n.Env()
+	httpClient = client
+	return nil
+}
+
+// HTTPClient returns the client for downloads, configured by SetNetwork
+func HTTPClient() *http.Client {
+	commandHooksMutex.RLock()
+	defer commandHooksMutex.RUnlock()
+	return httpClient
+}
+
+// ApplyNetworkEnv adds the network settings to a command built outside this package
+func ApplyNetworkEnv(cmd *exec.Cmd) {
+	env := getNetworkEnv()
+	if len(env) == 0 {
+		return
+	}
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, env...)
+}
+
+// getNetworkEnv returns the proxy and CA variables set by SetNetwork
+func getNetworkEnv() []string {
+	commandHooksMutex.RLock()
+	defer commandHooksMutex.RUnlock()
+	return networkEnv
+}
+
+// newHTTPClient builds a client using the configured proxies and trusting the CA bundle
+func newHTTPClient(n Network) (*http.Client, error) {
+	if n.IsZero() {
+		return http.DefaultClient, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if n.HTTPProxy != "" || n.HTTPSProxy != "" {
+		httpProxy, err := parseProxyURL(n.HTTPProxy)
+		if err != nil {
+			return nil, fmt.Errorf("http_proxy: %w", err)
+		}
+		httpsProxy, err := parseProxyURL(n.HTTPSProxy)
+		if err != nil {
+			return nil, fmt.Errorf("https_proxy: %w", err)
+		}
+		transport.Proxy = proxyFunc(httpProxy, httpsProxy, n.NoProxy)
+	}
+
+	if n.CABundle != "" {
+		pool, err := certPool(n.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("ca_bundle: %w", err)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	return &http.Client{Transport: transport}, nil
+}
+
+// parseProxyURL parses a proxy setting, defaulting to http:// when no scheme is given
+func parseProxyURL(value string) (*url.URL, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if !strings.Contains(value, "://") {
+		value = "http://" + value
+	}
+	proxyURL, err := url.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("%q has no host", value)
+	}
+	return proxyURL, nil
+}
+
+// proxyFunc returns the proxy for each request, honoring the no_proxy list
+func proxyFunc(httpProxy, httpsProxy *url.URL, noProxy string) func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		if bypassProxy(req.URL.Hostname(), noProxy) {
+			return nil, nil
+		}
+		if req.URL.Scheme == "https" {
+			return httpsProxy, nil
+		}
+		return httpProxy, nil
+	}
+}
+
+// bypassProxy reports whether host matches an entry of the comma separated no_proxy list.
+// An entry matches the host itself and its subdomains, "*" matches every host.
+func bypassProxy(host, noProxy string) bool {
+	host = strings.ToLower(host)
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "*" {
+			return true
+		}
+		if h, _, err := net.SplitHostPort(entry); err == nil {
+			entry = h
+		}
+		entry = strings.TrimPrefix(strings.TrimPrefix(entry, "*"), ".")
+		if entry != "" && (host == entry || strings.HasSuffix(host, "."+entry)) {
+			return true
+		}
+	}
+	return false
+}
+
+// certPool returns the system roots with the certificates of the PEM bundle added
+func certPool(bundle string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(bundle)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM certificates found in %s", bundle)
+	}
+	return pool, nil
+}
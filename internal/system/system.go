@@ -20,12 +20,75 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"strings"
+	"sync"
 	"time"
 )
 
+var (
+	// commandEnv holds extra environment variables applied to every run of a command
+	commandEnv        = make(map[string][]string)
+	commandHooksMutex sync.RWMutex
+
+	// commandObserver is notified of every finished command, used to record sessions
+	commandObserver CommandObserver
+)
+
+// CommandObserver receives each finished command with its start time and duration
+type CommandObserver func(result *CommandResult, started time.Time, duration time.Duration)
+
+// SetCommandObserver sets the observer notified of every command run through this package,
+// pass nil to remove it
+func SetCommandObserver(observer CommandObserver) {
+	commandHooksMutex.Lock()
+	defer commandHooksMutex.Unlock()
+	commandObserver = observer
+}
+
+// notifyCommandObserver reports a finished command to the observer, if one is set
+func notifyCommandObserver(result *CommandResult, started time.Time) {
+	commandHooksMutex.RLock()
+	observer := commandObserver
+	commandHooksMutex.RUnlock()
+	if observer != nil {
+		observer(result, started, time.Since(started))
+	}
+}
+
+// SetCommandEnv sets extra "KEY=value" environment variables for every run of command,
+// e.g. Homebrew policy variables. Passing no variables clears them.
+func SetCommandEnv(command string, env []string) {
+	commandHooksMutex.Lock()
+	defer commandHooksMutex.Unlock()
+	if len(env) == 0 {
+		delete(commandEnv, command)
+		return
+	}
+	commandEnv[command] = append([]string(nil), env...)
+}
+
+// GetCommandEnv returns the extra environment variables set for command
+func GetCommandEnv(command string) []string {
+	commandHooksMutex.RLock()
+	defer commandHooksMutex.RUnlock()
+	return commandEnv[command]
+}
+
+// applyCommandEnv adds the network settings and the extra environment variables set for command to cmd
+func applyCommandEnv(cmd *exec.Cmd, command string) {
+	env := append(append([]string(nil), getNetworkEnv()...), GetCommandEnv(command)...)
+	if len(env) == 0 {
+		return
+	}
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, env...)
+}
+
 // CommandResult represents the result of a command execution
 type CommandResult struct {
 	Command  string
@@ -44,7 +107,37 @@ func RunCommand(command string, args ...string) (*CommandResult, error) {
 
 // RunCommandWithTimeout executes a system command with the given context
 func RunCommandWithTimeout(ctx context.Context, command string, args ...string) (*CommandResult, error) {
+	return runCommand(newCommand(ctx, "", command, args...), nil)
+}
+
+// RunCommandWithProgress executes a system command with the given context, passing each
+// line of output to progress as it is written. Carriage returns also end a line so
+// redrawn progress such as git's 'Receiving objects' arrives as it changes.
+func RunCommandWithProgress(ctx context.Context, progress func(line string), command string, args ...string) (*CommandResult, error) {
+	return runCommand(newCommand(ctx, "", command, args...), progress)
+}
+
+// RunCommandWithEnv executes a command in dir with extra "KEY=value" environment variables,
+// e.g. GOOS and GOARCH for a cross-compiled build
+func RunCommandWithEnv(ctx context.Context, dir string, env []string, command string, args ...string) (*CommandResult, error) {
+	return RunCommandWithEnvAndProgress(ctx, dir, env, nil, command, args...)
+}
+
+// RunCommandWithEnvAndProgress executes a command like RunCommandWithEnv, passing each line of
+// output to progress as it is written, e.g. for install hooks whose output is streamed
+func RunCommandWithEnvAndProgress(ctx context.Context, dir string, env []string, progress func(line string), command string, args ...string) (*CommandResult, error) {
+	cmd := newCommand(ctx, dir, command, args...)
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, env...)
+	return runCommand(cmd, progress)
+}
+
+// newCommand prepares command to run in dir, or the current directory when dir is empty
+func newCommand(ctx context.Context, dir, command string, args ...string) *exec.Cmd {
 	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Dir = dir
 
 	// For git commands, ensure non-interactive mode to prevent credential prompts
 	if command == "git" {
@@ -56,13 +149,35 @@ func RunCommandWithTimeout(ctx context.Context, command string, args ...string)
 			"GIT_SSH_COMMAND=ssh -o BatchMode=yes -o StrictHostKeyChecking=no", // Non-interactive SSH
 		)
 	}
+	applyCommandEnv(cmd, command)
+	return cmd
+}
+
+// runCommand runs cmd capturing stdout and stderr together, streaming lines to progress when set
+func runCommand(cmd *exec.Cmd, progress func(line string)) (*CommandResult, error) {
+	var output bytes.Buffer
+	writer := io.Writer(&output)
+	var lines *lineWriter
+	if progress != nil {
+		lines = &lineWriter{output: &output, progress: progress}
+		writer = lines
+	}
+	cmd.Stdout = writer
+	cmd.Stderr = writer
 
-	// Capture both stdout and stderr
-	output, err := cmd.CombinedOutput()
+	started := time.Now()
+	err := cmd.Run()
+	if lines != nil {
+		lines.flush()
+	}
 
+	display := strings.Join(cmd.Args, " ")
+	if cmd.Dir != "" {
+		display = fmt.Sprintf("cd %s && %s", cmd.Dir, display)
+	}
 	result := &CommandResult{
-		Command: strings.Join(append([]string{command}, args...), " "),
-		Output:  string(output),
+		Command: display,
+		Output:  output.String(),
 		Success: err == nil,
 	}
 
@@ -72,10 +187,39 @@ func RunCommandWithTimeout(ctx context.Context, command string, args ...string)
 		}
 		result.Error = err.Error()
 	}
+	notifyCommandObserver(result, started)
 
 	return result, nil
 }
 
+// lineWriter captures output and passes every complete, non-empty line to progress
+type lineWriter struct {
+	output   *bytes.Buffer
+	progress func(line string)
+	pending  []byte
+}
+
+// Write implements io.Writer
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.output.Write(p)
+	for _, b := range p {
+		if b == '\n' || b == '\r' {
+			w.flush()
+			continue
+		}
+		w.pending = append(w.pending, b)
+	}
+	return len(p), nil
+}
+
+// flush passes the buffered partial line to progress
+func (w *lineWriter) flush() {
+	if line := strings.TrimSpace(string(w.pending)); line != "" {
+		w.progress(line)
+	}
+	w.pending = w.pending[:0]
+}
+
 // CommandExists checks if a command exists in the system PATH
 func CommandExists(command string) bool {
 	_, err := exec.LookPath(command)
@@ -96,36 +240,7 @@ func RunCommandInDirectory(dir, command string, args ...string) (*CommandResult,
 
 // RunCommandInDirectoryWithTimeout executes a command in a specific directory with context
 func RunCommandInDirectoryWithTimeout(ctx context.Context, dir, command string, args ...string) (*CommandResult, error) {
-	cmd := exec.CommandContext(ctx, command, args...)
-	cmd.Dir = dir
-
-	// For git commands, ensure non-interactive mode to prevent credential prompts
-	if command == "git" {
-		// Set environment variables to prevent credential prompts
-		cmd.Env = append(os.Environ(),
-			"GIT_TERMINAL_PROMPT=0",  // Disable terminal prompts
-			"GIT_ASKPASS=/bin/false", // Disable credential prompts
-			"SSH_ASKPASS=/bin/false", // Disable SSH passphrase prompts
-			"GIT_SSH_COMMAND=ssh -o BatchMode=yes -o StrictHostKeyChecking=no", // Non-interactive SSH
-		)
-	}
-
-	output, err := cmd.CombinedOutput()
-
-	result := &CommandResult{
-		Command: fmt.Sprintf("cd %s && %s", dir, strings.Join(append([]string{command}, args...), " ")),
-		Output:  string(output),
-		Success: err == nil,
-	}
-
-	if err != nil {
-		if exitError, ok := err.(*exec.ExitError); ok {
-			result.ExitCode = exitError.ExitCode()
-		}
-		result.Error = err.Error()
-	}
-
-	return result, nil
+	return runCommand(newCommand(ctx, dir, command, args...), nil)
 }
 
 // GetEnvironmentVariable gets an environment variable with a default value
@@ -159,6 +274,7 @@ func RunInteractiveCommand(command string, args ...string) error {
 	// This forces tools like Homebrew installer to run in interactive mode
 	// even when they can't detect a TTY through the subprocess chain
 	cmd.Env = append(os.Environ(), "INTERACTIVE=1")
+	applyCommandEnv(cmd, command)
 
 	err := cmd.Run()
 
@@ -17,6 +17,8 @@ limitations under the License.
 package system
 
 import (
+	"context"
+	"net/http"
 	"os"
 	"os/exec"
 	"strings"
@@ -171,3 +173,109 @@ func TestRunCommand(t *testing.T) {
 		}
 	})
 }
+
+func TestSetCommandEnv(t *testing.T) {
+	SetCommandEnv("sh", []string{"ANVIL_TEST_POLICY=on"})
+	defer SetCommandEnv("sh", nil)
+
+	result, _ := RunCommand("sh", "-c", "echo $ANVIL_TEST_POLICY")
+	if got := strings.TrimSpace(result.Output); got != "on" {
+		t.Errorf("sh output = %q, want the injected variable", got)
+	}
+
+	// Other commands run with the unchanged environment
+	result, _ = RunCommand("env")
+	if strings.Contains(result.Output, "ANVIL_TEST_POLICY") {
+		t.Error("variables set for sh leaked into another command")
+	}
+
+	SetCommandEnv("sh", nil)
+	if env := GetCommandEnv("sh"); env != nil {
+		t.Errorf("GetCommandEnv after clearing = %v, want nil", env)
+	}
+}
+
+func TestSetNetwork(t *testing.T) {
+	defer SetNetwork(Network{})
+
+	if err := SetNetwork(Network{HTTPSProxy: "proxy.corp.com:8080", CABundle: "/nonexistent/ca.pem"}); err == nil {
+		t.Error("expected an error for a missing CA bundle")
+	}
+
+	network := Network{HTTPProxy: "http://proxy.corp.com:8080", HTTPSProxy: "proxy.corp.com:8443", NoProxy: "localhost, .internal.corp.com"}
+	if err := SetNetwork(network); err != nil {
+		t.Fatalf("SetNetwork failed: %v", err)
+	}
+
+	// Every command sees the proxy, git and brew included
+	result, _ := RunCommand("sh", "-c", "echo $https_proxy $NO_PROXY")
+	if got := strings.TrimSpace(result.Output); got != "proxy.corp.com:8443 localhost, .internal.corp.com" {
+		t.Errorf("sh output = %q, want the proxy variables", got)
+	}
+
+	transport, ok := HTTPClient().Transport.(*http.Transport)
+	if !ok || transport.Proxy == nil {
+		t.Fatal("HTTPClient should use a transport with the configured proxy")
+	}
+	tests := map[string]string{
+		"https://github.com/x":          "http://proxy.corp.com:8443",
+		"http://example.com/x":          "http://proxy.corp.com:8080",
+		"https://git.internal.corp.com": "",
+		"http://localhost:8000":         "",
+	}
+	for rawURL, want := range tests {
+		req, _ := http.NewRequest("GET", rawURL, nil)
+		proxyURL, err := transport.Proxy(req)
+		got := ""
+		if proxyURL != nil {
+			got = proxyURL.String()
+		}
+		if err != nil || got != want {
+			t.Errorf("proxy for %s = %q (%v), want %q", rawURL, got, err, want)
+		}
+	}
+
+	SetNetwork(Network{})
+	if HTTPClient() != http.DefaultClient {
+		t.Error("clearing the settings should restore the default client")
+	}
+}
+
+func TestBypassProxy(t *testing.T) {
+	tests := []struct {
+		host    string
+		noProxy string
+		want    bool
+	}{
+		{"github.com", "", false},
+		{"github.com", "*", true},
+		{"api.github.com", "github.com", true},
+		{"api.github.com", "*.github.com", true},
+		{"notgithub.com", "github.com", false},
+		{"intranet", "localhost,intranet:443", true},
+		{"github.com", "*.corp.com,", false},
+	}
+	for _, tt := range tests {
+		if got := bypassProxy(tt.host, tt.noProxy); got != tt.want {
+			t.Errorf("bypassProxy(%q, %q) = %v, want %v", tt.host, tt.noProxy, got, tt.want)
+		}
+	}
+}
+
+func TestRunCommandWithProgress(t *testing.T) {
+	var lines []string
+	result, _ := RunCommandWithProgress(context.Background(), func(line string) {
+		lines = append(lines, line)
+	}, "sh", "-c", `printf 'Receiving 10%%\rReceiving 100%%\ndone\n\nlast'`)
+
+	if !result.Success {
+		t.Fatalf("command failed: %s", result.Error)
+	}
+	want := []string{"Receiving 10%", "Receiving 100%", "done", "last"}
+	if strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Errorf("progress lines = %q, want %q", lines, want)
+	}
+	if result.Output != "Receiving 10%\rReceiving 100%\ndone\n\nlast" {
+		t.Errorf("output not captured in full: %q", result.Output)
+	}
+}
@@ -10,6 +10,11 @@ func getType() string {
 	return runtime.GOOS
 }
 
+// Platform returns the operating system name used by platform tags, e.g. "darwin" or "linux"
+func Platform() string {
+	return getType()
+}
+
 func IsMacOS() bool {
 	return getType() == "darwin"
 }
@@ -17,8 +17,12 @@ limitations under the License.
 package charm
 
 import (
+	"io"
+	"os"
+	"regexp"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewCharmOutputHandler(t *testing.T) {
@@ -175,3 +179,204 @@ func BenchmarkRenderList(b *testing.B) {
 		RenderList(items, "•", "#87CEEB")
 	}
 }
+
+// resetTheme restores the default theme and color state after a test
+func resetTheme(t *testing.T) {
+	t.Cleanup(func() {
+		colorDisabled = false
+		applyTheme(themes["default"])
+	})
+}
+
+func TestSetTheme(t *testing.T) {
+	resetTheme(t)
+
+	if err := SetTheme("light"); err != nil {
+		t.Fatalf("SetTheme(light) failed: %v", err)
+	}
+	if ActiveTheme().Name != "light" {
+		t.Errorf("active theme = %s, want light", ActiveTheme().Name)
+	}
+	if err := SetTheme("neon"); err == nil {
+		t.Error("expected an error for an unknown theme")
+	}
+	if ActiveTheme().Name != "light" {
+		t.Error("an unknown theme must not replace the active one")
+	}
+
+	for _, name := range ThemeNames() {
+		theme := themes[name]
+		if theme.Name != name {
+			t.Errorf("theme %s has name %s", name, theme.Name)
+		}
+		if name != "monochrome" && (theme.Primary == "" || theme.Accent == "" || theme.Error == "") {
+			t.Errorf("theme %s is missing colors", name)
+		}
+	}
+}
+
+func TestDisableColor(t *testing.T) {
+	resetTheme(t)
+	InitCharmOutput()
+
+	DisableColor()
+	if !ColorDisabled() || ActiveTheme().Name != "monochrome" {
+		t.Fatalf("expected monochrome output, got %s", ActiveTheme().Name)
+	}
+
+	// A configured theme is validated but cannot bring colors back
+	if err := SetTheme("high-contrast"); err != nil {
+		t.Errorf("SetTheme failed: %v", err)
+	}
+	if ActiveTheme().Name != "monochrome" {
+		t.Errorf("theme changed to %s while color is disabled", ActiveTheme().Name)
+	}
+
+	box := RenderBox("Title", "content", ActiveTheme().Accent, false)
+	if strings.Contains(box, "\x1b[") {
+		t.Errorf("monochrome box contains escape sequences: %q", box)
+	}
+	handler := GetCharmHandler().(*CharmOutputHandler)
+	if rendered := handler.styles.Success.Render("ok"); strings.Contains(rendered, "\x1b[3") {
+		t.Errorf("monochrome handler still colors output: %q", rendered)
+	}
+}
+
+func TestResolveConfirm(t *testing.T) {
+	policy := ConfirmPolicy{
+		Modes:      map[string]string{ConfirmSync: PolicyAlways, ConfirmSettings: PolicyNever},
+		YesAllowed: []string{ConfirmInstall},
+	}
+
+	tests := []struct {
+		name   string
+		action string
+		yes    bool
+		policy ConfirmPolicy
+		want   confirmDecision
+	}{
+		{"default ask prompts", ConfirmPush, false, ConfirmPolicy{}, confirmPrompt},
+		{"yes approves without allowlist", ConfirmPush, true, ConfirmPolicy{}, confirmAssumed},
+		{"yes approves unnamed prompts without allowlist", "", true, ConfirmPolicy{}, confirmAssumed},
+		{"tracking is silent by default", ConfirmTracking, false, ConfirmPolicy{}, confirmApproved},
+		{"privileged prompts by default", ConfirmPrivileged, false, ConfirmPolicy{}, confirmPrompt},
+		{"always ignores yes", ConfirmSync, true, policy, confirmPrompt},
+		{"never approves", ConfirmSettings, false, policy, confirmApproved},
+		{"allowlisted action", ConfirmInstall, true, policy, confirmAssumed},
+		{"action outside allowlist", ConfirmDelete, true, policy, confirmPrompt},
+		{"unnamed prompt outside allowlist", "", true, policy, confirmPrompt},
+	}
+	for _, tt := range tests {
+		if got := resolveConfirm(tt.action, tt.yes, tt.policy); got != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSetConfirmPolicy(t *testing.T) {
+	t.Cleanup(func() { confirmPolicy = ConfirmPolicy{} })
+
+	err := SetConfirmPolicy(ConfirmPolicy{
+		Modes:      map[string]string{ConfirmSync: "Always", "deploy": PolicyNever, ConfirmPush: "sometimes"},
+		YesAllowed: []string{ConfirmFix, "everything"},
+	})
+	if err == nil {
+		t.Fatal("expected an error for invalid entries")
+	}
+	for _, want := range []string{"deploy", "sometimes", "everything"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+	if confirmPolicy.Modes[ConfirmSync] != PolicyAlways || len(confirmPolicy.Modes) != 1 {
+		t.Errorf("valid modes not applied: %v", confirmPolicy.Modes)
+	}
+	if len(confirmPolicy.YesAllowed) != 1 || confirmPolicy.YesAllowed[0] != ConfirmFix {
+		t.Errorf("valid allowlist not applied: %v", confirmPolicy.YesAllowed)
+	}
+}
+
+func TestStrictWarnings(t *testing.T) {
+	t.Cleanup(func() { SetStrict(false) })
+
+	handler := NewCharmOutputHandler()
+	handler.PrintWarning("before strict %d", 1)
+	if len(StrictWarnings()) != 0 {
+		t.Fatal("warnings recorded without strict mode")
+	}
+
+	SetStrict(true)
+	handler.PrintWarning("missing %s", "tool")
+	RecordWarning("doctor git-config: user.email not set")
+	warnings := StrictWarnings()
+	if len(warnings) != 2 || warnings[0] != "missing tool" || warnings[1] != "doctor git-config: user.email not set" {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+
+	SetStrict(true)
+	if len(StrictWarnings()) != 0 {
+		t.Error("SetStrict should clear recorded warnings")
+	}
+}
+
+func TestSpinnerLabel(t *testing.T) {
+	SetTerminalWidth(80)
+	t.Cleanup(func() { SetTerminalWidth(0) })
+
+	spinner := NewDotsSpinner("Installing wget")
+	if got := spinner.label(); got != "Installing wget" {
+		t.Errorf("label() = %q", got)
+	}
+
+	spinner.SetProgress(2, 4)
+	spinner.SetDetail("Installing gettext")
+	if got := spinner.label(); got != "Installing wget (2/4): Installing gettext" {
+		t.Errorf("label() = %q", got)
+	}
+
+	spinner.UpdateMessage("Installing curl")
+	spinner.SetProgress(0, 0)
+	spinner.SetDetail(strings.Repeat("x", 200))
+	if got := spinner.label(); !strings.HasPrefix(got, "Installing curl: x") || len([]rune(got)) != 74 {
+		t.Errorf("label() = %q, want truncated to the terminal width", got)
+	}
+}
+
+func TestPlainSpinner(t *testing.T) {
+	SetPlain(true)
+	t.Cleanup(func() { SetPlain(false) })
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	spinner := NewDotsSpinner("Installing wget")
+	spinner.Start()
+	spinner.SetDetail("Pouring wget")
+	spinner.Success("wget installed successfully")
+	os.Stdout = stdout
+	writer.Close()
+	data, _ := io.ReadAll(reader)
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	start := regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}Z start Installing wget$`)
+	done := regexp.MustCompile(`^\S+Z done  wget installed successfully \(\d+(\.\d+)?[µnm]?s\)$`)
+	if len(lines) != 2 || !start.MatchString(lines[0]) || !done.MatchString(lines[1]) {
+		t.Errorf("plain spinner printed %q", data)
+	}
+}
+
+func TestFormatElapsed(t *testing.T) {
+	tests := map[time.Duration]string{
+		340 * time.Millisecond:  "340ms",
+		3249 * time.Millisecond: "3.2s",
+		62 * time.Second:        "1m2s",
+	}
+	for d, want := range tests {
+		if got := FormatElapsed(d); got != want {
+			t.Errorf("FormatElapsed(%v) = %s, want %s", d, got, want)
+		}
+	}
+}
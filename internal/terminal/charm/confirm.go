@@ -0,0 +1,160 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package charm
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/0xjuanma/palantir"
+)
+
+// Confirmation actions, each with its own policy under 'confirmations' in settings.yaml
+const (
+	ConfirmSync       = "sync"       // Overwriting local configs with pulled ones
+	ConfirmPush       = "push"       // Pushing configs to the repository
+	ConfirmInstall    = "install"    // Installing apps, plugins or retrying failed installs
+	ConfirmSettings   = "settings"   // Rewriting settings.yaml, e.g. imports, renames and cleanups
+	ConfirmTracking   = "tracking"   // Adding newly installed apps to settings.yaml
+	ConfirmDelete     = "delete"     // Removing files, branches or anvil data
+	ConfirmFix        = "fix"        // Applying doctor fixes
+	ConfirmPrivileged = "privileged" // Operations that may ask for your password
+	ConfirmQuarantine = "quarantine" // Removing the Gatekeeper quarantine flag from installed apps
+)
+
+// Confirmation policy modes
+const (
+	PolicyAsk    = "ask"    // Prompt, unless --yes may approve the action
+	PolicyAlways = "always" // Always prompt, even with --yes
+	PolicyNever  = "never"  // Never prompt, approve automatically
+)
+
+// defaultPolicies keeps the prompts anvil has always shown and asks before privileged
+// operations, only adding newly installed apps is approved silently
+var defaultPolicies = map[string]string{
+	ConfirmSync:       PolicyAsk,
+	ConfirmPush:       PolicyAsk,
+	ConfirmInstall:    PolicyAsk,
+	ConfirmSettings:   PolicyAsk,
+	ConfirmTracking:   PolicyNever,
+	ConfirmDelete:     PolicyAsk,
+	ConfirmFix:        PolicyAsk,
+	ConfirmPrivileged: PolicyAsk,
+	ConfirmQuarantine: PolicyAsk,
+}
+
+// ConfirmPolicy decides which confirmations are shown and which --yes may approve
+type ConfirmPolicy struct {
+	Modes      map[string]string // Action to mode, missing actions use the defaults
+	YesAllowed []string          // Actions --yes may approve, empty allows all
+}
+
+// confirmPolicy is the policy consulted by every confirmation prompt
+var confirmPolicy ConfirmPolicy
+
+// ConfirmActions returns the known confirmation actions
+func ConfirmActions() []string {
+	actions := make([]string, 0, len(defaultPolicies))
+	for action := range defaultPolicies {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	return actions
+}
+
+// SetConfirmPolicy validates and applies a confirmation policy. Invalid entries are
+// dropped and reported in the returned error, the rest of the policy still applies.
+func SetConfirmPolicy(policy ConfirmPolicy) error {
+	var problems []string
+	valid := ConfirmPolicy{Modes: make(map[string]string)}
+	for _, action := range sortedKeys(policy.Modes) {
+		mode := strings.ToLower(strings.TrimSpace(policy.Modes[action]))
+		switch {
+		case defaultPolicies[action] == "":
+			problems = append(problems, fmt.Sprintf("unknown action '%s'", action))
+		case mode != PolicyAsk && mode != PolicyAlways && mode != PolicyNever:
+			problems = append(problems, fmt.Sprintf("%s: unknown mode '%s' (use ask, always or never)", action, mode))
+		default:
+			valid.Modes[action] = mode
+		}
+	}
+	for _, action := range policy.YesAllowed {
+		if defaultPolicies[action] == "" {
+			problems = append(problems, fmt.Sprintf("yes_allowed: unknown action '%s'", action))
+			continue
+		}
+		valid.YesAllowed = append(valid.YesAllowed, action)
+	}
+
+	confirmPolicy = valid
+	if len(problems) > 0 {
+		return fmt.Errorf("%s (actions: %s)", strings.Join(problems, "; "), strings.Join(ConfirmActions(), ", "))
+	}
+	return nil
+}
+
+// Confirm asks for confirmation of an action, applying the confirmation policy
+func Confirm(action, message string) bool {
+	if handler, ok := palantir.GetGlobalOutputHandler().(*CharmOutputHandler); ok {
+		return handler.confirm(action, message)
+	}
+	// Other handlers, such as test doubles, only see the prompt
+	if resolveConfirm(action, assumeYes, confirmPolicy) == confirmPrompt {
+		return palantir.GetGlobalOutputHandler().Confirm(message)
+	}
+	return true
+}
+
+// confirmDecision is how a confirmation is answered
+type confirmDecision int
+
+const (
+	confirmPrompt   confirmDecision = iota // Ask the user
+	confirmAssumed                         // Approved by --yes
+	confirmApproved                        // Approved by a 'never' policy
+)
+
+// resolveConfirm decides how to answer a confirmation for an action. Prompts without an
+// action follow the 'ask' mode, so --yes approves them unless an allowlist is configured.
+func resolveConfirm(action string, yes bool, policy ConfirmPolicy) confirmDecision {
+	mode := policy.Modes[action]
+	if mode == "" {
+		mode = defaultPolicies[action]
+	}
+	switch mode {
+	case PolicyNever:
+		return confirmApproved
+	case PolicyAlways:
+		return confirmPrompt
+	}
+	if yes && (len(policy.YesAllowed) == 0 || slices.Contains(policy.YesAllowed, action)) {
+		return confirmAssumed
+	}
+	return confirmPrompt
+}
+
+// sortedKeys returns map keys in a stable order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
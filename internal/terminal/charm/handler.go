@@ -18,7 +18,9 @@ package charm
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/0xjuanma/anvil/internal/errors"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/0xjuanma/palantir"
 )
@@ -64,59 +66,59 @@ func createDefaultStyles() *StyleConfig {
 		// Header: Large, bold, gradient-colored banner
 		Header: lipgloss.NewStyle().
 			Bold(true).
-			Foreground(lipgloss.Color("#FF6B9D")).
-			Background(lipgloss.Color("#2D2D2D")).
+			Foreground(lipgloss.Color(activeTheme.Primary)).
+			Background(lipgloss.Color(activeTheme.Surface)).
 			Padding(0, 2).
 			MarginTop(1).
 			MarginBottom(1).
 			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color("#FF6B9D")),
+			BorderForeground(lipgloss.Color(activeTheme.Primary)),
 
 		// Stage: Cyan with arrow, indicates progress stage
 		Stage: lipgloss.NewStyle().
 			Bold(true).
-			Foreground(lipgloss.Color("#00D9FF")).
+			Foreground(lipgloss.Color(activeTheme.Accent)).
 			PaddingLeft(1),
 
 		// Success: Green with checkmark, celebratory
 		Success: lipgloss.NewStyle().
 			Bold(true).
-			Foreground(lipgloss.Color("#00FF87")).
+			Foreground(lipgloss.Color(activeTheme.Success)).
 			PaddingLeft(1),
 
 		// Error: Red with X mark, critical attention
 		Error: lipgloss.NewStyle().
 			Bold(true).
-			Foreground(lipgloss.Color("#FF5F87")).
+			Foreground(lipgloss.Color(activeTheme.Error)).
 			PaddingLeft(1),
 
 		// Warning: Yellow/Orange with warning sign
 		Warning: lipgloss.NewStyle().
 			Bold(true).
-			Foreground(lipgloss.Color("#FFD700")).
+			Foreground(lipgloss.Color(activeTheme.Warning)).
 			PaddingLeft(1),
 
 		// Info: Blue with info icon, neutral information
 		Info: lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#87CEEB")).
+			Foreground(lipgloss.Color(activeTheme.Info)).
 			PaddingLeft(1),
 
 		// AlreadyAvailable: Purple/Magenta, showing existing state
 		AlreadyAvailable: lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#C792EA")).
+			Foreground(lipgloss.Color(activeTheme.Special)).
 			Italic(true).
 			PaddingLeft(1),
 
 		// Progress: Cyan with bold counter
 		Progress: lipgloss.NewStyle().
 			Bold(true).
-			Foreground(lipgloss.Color("#00CED1")).
+			Foreground(lipgloss.Color(activeTheme.Progress)).
 			PaddingLeft(1),
 
 		// Confirm: Yellow with question mark
 		Confirm: lipgloss.NewStyle().
 			Bold(true).
-			Foreground(lipgloss.Color("#FFA500")),
+			Foreground(lipgloss.Color(activeTheme.Confirm)),
 	}
 }
 
@@ -139,11 +141,30 @@ func (c *CharmOutputHandler) PrintSuccess(message string) {
 func (c *CharmOutputHandler) PrintError(format string, args ...interface{}) {
 	message := fmt.Sprintf(format, args...)
 	fmt.Println(c.styles.Error.Render("✗ " + message))
+	if suggestion := errorSuggestion(message, args); suggestion != "" {
+		fmt.Println(c.styles.Info.Render("💡 " + suggestion))
+	}
+}
+
+// errorSuggestion returns the remediation hint for the first error among args, unless the
+// message already includes it
+func errorSuggestion(message string, args []interface{}) string {
+	for _, arg := range args {
+		if err, ok := arg.(error); ok {
+			suggestion := errors.Suggestion(err)
+			if strings.Contains(message, suggestion) {
+				return ""
+			}
+			return suggestion
+		}
+	}
+	return ""
 }
 
 // PrintWarning prints a warning message with a warning sign
 func (c *CharmOutputHandler) PrintWarning(format string, args ...interface{}) {
 	message := fmt.Sprintf(format, args...)
+	RecordWarning(message)
 	fmt.Println(c.styles.Warning.Render("⚠ " + message))
 }
 
@@ -196,8 +217,21 @@ func createProgressBar(current, total, width int) string {
 	return bar
 }
 
-// Confirm prompts the user for confirmation
+// Confirm prompts the user for confirmation, following the policy for prompts without an action
 func (c *CharmOutputHandler) Confirm(message string) bool {
+	return c.confirm("", message)
+}
+
+// confirm prompts for confirmation of an action unless the confirmation policy answers it
+func (c *CharmOutputHandler) confirm(action, message string) bool {
+	switch resolveConfirm(action, assumeYes, confirmPolicy) {
+	case confirmApproved:
+		return true
+	case confirmAssumed:
+		fmt.Println(c.styles.Confirm.Render("? " + message + " (y/N): y (assumed)"))
+		return true
+	}
+
 	fmt.Print(c.styles.Confirm.Render("? " + message + " (y/N): "))
 
 	var response string
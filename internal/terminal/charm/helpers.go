@@ -23,30 +23,73 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
-// RenderBox creates a beautiful box around content with max width of 100 chars
+// RenderBox creates a beautiful box around content, up to MaxBoxWidth wide and never wider than
+// the terminal. Below NarrowWidth it falls back to a borderless vertical layout.
 func RenderBox(title, content string, borderColor string, centered bool) string {
+	return renderBox(title, content, borderColor, centered, TerminalWidth())
+}
+
+// renderBox renders a box for the given terminal width
+func renderBox(title, content string, borderColor string, centered bool, termWidth int) string {
 	if borderColor == "" {
-		borderColor = "#FF6B9D"
+		borderColor = activeTheme.Primary
+	}
+
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color(borderColor))
+
+	if termWidth < NarrowWidth {
+		return renderNarrowBox(titleStyle.Render(Wrap(title, termWidth)), content, borderColor, termWidth)
 	}
 
+	// Leave room for the left and right border
+	width := min(MaxBoxWidth, termWidth-2)
 	boxStyle := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
 		BorderForeground(lipgloss.Color(borderColor)).
 		Padding(0, 1).
 		MarginTop(0).
 		MarginBottom(0).
-		Width(100)
+		Width(width)
 
 	if centered {
 		boxStyle = boxStyle.Align(lipgloss.Center)
 	}
 
-	titleStyle := lipgloss.NewStyle().
-		Bold(true).
-		Foreground(lipgloss.Color(borderColor))
+	// Lines that cannot wrap, such as tables and paths, are truncated instead of overflowing the border
+	innerWidth := boxContentWidth(termWidth)
+	header := titleStyle.Render(Truncate(title, innerWidth))
+	return boxStyle.Render(header + "\n\n" + TruncateLines(Wrap(content, innerWidth), innerWidth))
+}
+
+// BoxContentWidth returns the width available to content rendered inside RenderBox
+func BoxContentWidth() int {
+	return boxContentWidth(TerminalWidth())
+}
+
+// boxContentWidth returns the content width of a box for the given terminal width
+func boxContentWidth(termWidth int) int {
+	if termWidth < NarrowWidth {
+		return termWidth
+	}
+	// Border and padding take two cells on each side
+	return min(MaxBoxWidth, termWidth-2) - 2
+}
+
+// renderNarrowBox renders a title, a rule and wrapped content without borders for narrow terminals
+func renderNarrowBox(header, content, borderColor string, width int) string {
+	rule := lipgloss.NewStyle().Foreground(lipgloss.Color(borderColor)).Render(strings.Repeat("─", width))
 
-	header := titleStyle.Render(title)
-	return boxStyle.Render(header + "\n\n" + content)
+	var b strings.Builder
+	if strings.TrimSpace(header) != "" {
+		b.WriteString(header + "\n")
+	}
+	b.WriteString(rule)
+	if strings.TrimSpace(content) != "" {
+		b.WriteString("\n" + TruncateLines(Wrap(strings.Trim(content, "\n"), width), width))
+	}
+	return b.String()
 }
 
 // RenderList creates a styled list of items
@@ -55,7 +98,7 @@ func RenderList(items []string, bullet string, color string) string {
 		bullet = "•"
 	}
 	if color == "" {
-		color = "#87CEEB"
+		color = activeTheme.Info
 	}
 
 	itemStyle := lipgloss.NewStyle().
@@ -73,10 +116,10 @@ func RenderList(items []string, bullet string, color string) string {
 func RenderTable(headers []string, rows [][]string) string {
 	headerStyle := lipgloss.NewStyle().
 		Bold(true).
-		Foreground(lipgloss.Color("#FF6B9D")).
+		Foreground(lipgloss.Color(activeTheme.Primary)).
 		BorderStyle(lipgloss.NormalBorder()).
 		BorderBottom(true).
-		BorderForeground(lipgloss.Color("#FF6B9D")).
+		BorderForeground(lipgloss.Color(activeTheme.Primary)).
 		Padding(0, 2)
 
 	cellStyle := lipgloss.NewStyle().
@@ -106,13 +149,13 @@ func RenderTable(headers []string, rows [][]string) string {
 func RenderBanner(text string) string {
 	bannerStyle := lipgloss.NewStyle().
 		Bold(true).
-		Foreground(lipgloss.Color("#FF6B9D")).
-		Background(lipgloss.Color("#2D2D2D")).
+		Foreground(lipgloss.Color(activeTheme.Primary)).
+		Background(lipgloss.Color(activeTheme.Surface)).
 		Padding(1, 4).
 		MarginTop(1).
 		MarginBottom(1).
 		Border(lipgloss.DoubleBorder()).
-		BorderForeground(lipgloss.Color("#FF6B9D")).
+		BorderForeground(lipgloss.Color(activeTheme.Primary)).
 		Align(lipgloss.Center)
 
 	return bannerStyle.Render(text)
@@ -122,12 +165,12 @@ func RenderBanner(text string) string {
 func RenderKeyValue(key, value string) string {
 	keyStyle := lipgloss.NewStyle().
 		Bold(true).
-		Foreground(lipgloss.Color("#00D9FF")).
+		Foreground(lipgloss.Color(activeTheme.Accent)).
 		Width(20).
 		Align(lipgloss.Right)
 
 	valueStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#87CEEB"))
+		Foreground(lipgloss.Color(activeTheme.Info))
 
 	return keyStyle.Render(key) + " " + valueStyle.Render(value)
 }
@@ -138,7 +181,7 @@ func RenderSeparator(width int, char string, color string) string {
 		char = "─"
 	}
 	if color == "" {
-		color = "#666666"
+		color = activeTheme.Muted
 	}
 
 	line := strings.Repeat(char, width)
@@ -153,13 +196,13 @@ func RenderSeparator(width int, char string, color string) string {
 // RenderHighlight highlights important text
 func RenderHighlight(text string, color string) string {
 	if color == "" {
-		color = "#FFD700"
+		color = activeTheme.Warning
 	}
 
 	style := lipgloss.NewStyle().
 		Bold(true).
 		Foreground(lipgloss.Color(color)).
-		Background(lipgloss.Color("#2D2D2D")).
+		Background(lipgloss.Color(activeTheme.Surface)).
 		Padding(0, 1)
 
 	return style.Render(text)
@@ -168,8 +211,8 @@ func RenderHighlight(text string, color string) string {
 // RenderCode renders text as code
 func RenderCode(code string) string {
 	codeStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#C792EA")).
-		Background(lipgloss.Color("#1E1E1E")).
+		Foreground(lipgloss.Color(activeTheme.Special)).
+		Background(lipgloss.Color(activeTheme.Surface)).
 		Padding(0, 1).
 		Italic(true)
 
@@ -179,15 +222,15 @@ func RenderCode(code string) string {
 // RenderQuote renders text as a quote
 func RenderQuote(quote string, author string) string {
 	quoteStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#87CEEB")).
+		Foreground(lipgloss.Color(activeTheme.Info)).
 		Italic(true).
 		PaddingLeft(4).
 		BorderLeft(true).
 		BorderStyle(lipgloss.ThickBorder()).
-		BorderForeground(lipgloss.Color("#00D9FF"))
+		BorderForeground(lipgloss.Color(activeTheme.Accent))
 
 	authorStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#666666")).
+		Foreground(lipgloss.Color(activeTheme.Muted)).
 		PaddingLeft(6).
 		Italic(true)
 
@@ -202,11 +245,11 @@ func RenderQuote(quote string, author string) string {
 // RenderBadge creates a distinct, highly readable badge/tag
 func RenderBadge(text string, color string) string {
 	if color == "" {
-		color = "#00D9FF"
+		color = activeTheme.Accent
 	}
 
 	badgeStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#000000")).
+		Foreground(lipgloss.Color(activeTheme.OnAccent)).
 		Background(lipgloss.Color(color)).
 		Padding(0, 2).
 		Bold(true).
@@ -220,13 +263,13 @@ func RenderBadge(text string, color string) string {
 func RenderSteps(steps []string) string {
 	stepNumberStyle := lipgloss.NewStyle().
 		Bold(true).
-		Foreground(lipgloss.Color("#00D9FF")).
-		Background(lipgloss.Color("#2D2D2D")).
+		Foreground(lipgloss.Color(activeTheme.Accent)).
+		Background(lipgloss.Color(activeTheme.Surface)).
 		Padding(0, 1).
 		MarginRight(1)
 
 	stepTextStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#87CEEB"))
+		Foreground(lipgloss.Color(activeTheme.Info))
 
 	var result strings.Builder
 	for i, step := range steps {
@@ -244,10 +287,10 @@ func RenderStatus(status string, isPositive bool) string {
 	var icon string
 
 	if isPositive {
-		color = "#00FF87"
+		color = activeTheme.Success
 		icon = "●"
 	} else {
-		color = "#FF5F87"
+		color = activeTheme.Error
 		icon = "●"
 	}
 
@@ -262,11 +305,11 @@ func RenderStatus(status string, isPositive bool) string {
 func RenderPercentage(value float64) string {
 	var color string
 	if value >= 80 {
-		color = "#00FF87"
+		color = activeTheme.Success
 	} else if value >= 50 {
-		color = "#FFD700"
+		color = activeTheme.Warning
 	} else {
-		color = "#FF5F87"
+		color = activeTheme.Error
 	}
 
 	percentStyle := lipgloss.NewStyle().
@@ -23,11 +23,17 @@ import (
 var (
 	// globalCharmHandler is the global enhanced output handler
 	globalCharmHandler palantir.OutputHandler
+
+	// assumeYes answers every confirmation prompt with yes, for unattended runs
+	assumeYes bool
 )
 
 // InitCharmOutput initializes the enhanced Charm output handler globally
 // Call this once at the start of your application
 func InitCharmOutput() {
+	if NoColorRequested() {
+		DisableColor()
+	}
 	globalCharmHandler = NewCharmOutputHandler()
 	palantir.SetGlobalOutputHandler(globalCharmHandler)
 }
@@ -45,3 +51,13 @@ func IsCharmEnabled() bool {
 	return globalCharmHandler != nil
 }
 
+
+// SetAssumeYes makes confirmation prompts answer yes without reading input
+func SetAssumeYes(enabled bool) {
+	assumeYes = enabled
+}
+
+// AssumeYes reports whether confirmation prompts are answered automatically
+func AssumeYes() bool {
+	return assumeYes
+}
@@ -0,0 +1,75 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package charm
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/x/term"
+)
+
+// Plain mode replaces spinners and dashboards with timestamped single lines, for output
+// that goes to a log file or a script instead of a terminal
+var (
+	plainMode  bool
+	plainMutex sync.Mutex
+)
+
+// SetPlain enables or disables plain progress output
+func SetPlain(enabled bool) {
+	plainMutex.Lock()
+	defer plainMutex.Unlock()
+	plainMode = enabled
+}
+
+// IsPlain reports whether progress is printed as plain lines
+func IsPlain() bool {
+	plainMutex.Lock()
+	defer plainMutex.Unlock()
+	return plainMode
+}
+
+// StdoutIsTerminal reports whether stdout is a terminal that can redraw spinners and dashboards
+func StdoutIsTerminal() bool {
+	return term.IsTerminal(os.Stdout.Fd())
+}
+
+// PlainLine prints a progress line prefixed with the time, e.g. "2026-10-16T09:30:00Z start git"
+func PlainLine(format string, args ...interface{}) {
+	fmt.Printf("%s %s\n", time.Now().UTC().Format(time.RFC3339), fmt.Sprintf(format, args...))
+}
+
+// PlainEvent prints a progress line for an event such as "start" or "done", followed by
+// the time since started unless started is zero
+func PlainEvent(event, message string, started time.Time) {
+	if started.IsZero() {
+		PlainLine("%-5s %s", event, message)
+		return
+	}
+	PlainLine("%-5s %s (%s)", event, message, FormatElapsed(time.Since(started)))
+}
+
+// FormatElapsed renders a duration for progress lines, e.g. "3.2s"
+func FormatElapsed(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
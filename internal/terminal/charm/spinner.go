@@ -18,6 +18,7 @@ package charm
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/charmbracelet/lipgloss"
@@ -36,6 +37,13 @@ type Spinner struct {
 	style   lipgloss.Style
 	done    chan bool
 	running bool
+	started time.Time
+
+	// Progress shown while the spinner runs, updated from the operation's goroutine
+	mu      sync.Mutex
+	current int
+	total   int
+	detail  string
 }
 
 // Common spinner frame sets (these are fun!)
@@ -71,7 +79,7 @@ func NewSpinner(frames []string, message string) *Spinner {
 		},
 		message: message,
 		style: lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#00D9FF")).
+			Foreground(lipgloss.Color(activeTheme.Accent)).
 			Bold(true),
 		done:    make(chan bool),
 		running: false,
@@ -100,6 +108,11 @@ func (s *Spinner) Start() {
 	}
 
 	s.running = true
+	s.started = time.Now()
+	if IsPlain() {
+		PlainEvent("start", s.message, time.Time{})
+		return
+	}
 	go s.animate()
 }
 
@@ -110,6 +123,9 @@ func (s *Spinner) Stop() {
 	}
 
 	s.running = false
+	if IsPlain() {
+		return
+	}
 	s.done <- true
 
 	// Clear the line
@@ -118,36 +134,92 @@ func (s *Spinner) Stop() {
 
 // Success stops the spinner and shows a success message
 func (s *Spinner) Success(message string) {
+	if s.finishPlain("done", message) {
+		return
+	}
 	s.Stop()
 	successStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#00FF87")).
+		Foreground(lipgloss.Color(activeTheme.Success)).
 		Bold(true)
 	fmt.Println(successStyle.Render("✓ " + message))
 }
 
 // Error stops the spinner and shows an error message
 func (s *Spinner) Error(message string) {
+	if s.finishPlain("fail", message) {
+		return
+	}
 	s.Stop()
 	errorStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#FF5F87")).
+		Foreground(lipgloss.Color(activeTheme.Error)).
 		Bold(true)
 	fmt.Println(errorStyle.Render("✗ " + message))
 }
 
 // Warning stops the spinner and shows a warning message
 func (s *Spinner) Warning(message string) {
+	RecordWarning(message)
+	if s.finishPlain("warn", message) {
+		return
+	}
 	s.Stop()
 	warningStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#FFD700")).
+		Foreground(lipgloss.Color(activeTheme.Warning)).
 		Bold(true)
 	fmt.Println(warningStyle.Render("⚠ " + message))
 }
 
+// finishPlain prints the final line of a spinner in plain mode with how long it ran
+func (s *Spinner) finishPlain(event, message string) bool {
+	if !IsPlain() {
+		return false
+	}
+	started := s.started
+	if !s.running {
+		started = time.Time{}
+	}
+	PlainEvent(event, message, started)
+	s.running = false
+	return true
+}
+
 // UpdateMessage updates the spinner message without stopping it
 func (s *Spinner) UpdateMessage(message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.message = message
 }
 
+// SetProgress shows sub-step progress as (current/total) after the message, a total of 0 hides it
+func (s *Spinner) SetProgress(current, total int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current, s.total = current, total
+}
+
+// SetDetail shows what the operation is doing right now, such as the file being
+// downloaded, after the message. An empty detail hides it.
+func (s *Spinner) SetDetail(detail string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.detail = detail
+}
+
+// label returns the message with progress and detail, fitted to the terminal width
+func (s *Spinner) label() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	label := s.message
+	if s.total > 0 {
+		label += fmt.Sprintf(" (%d/%d)", s.current, s.total)
+	}
+	if s.detail != "" {
+		label += ": " + s.detail
+	}
+	// Leave room for the frame so the line never wraps and breaks the carriage return
+	return Truncate(label, TerminalWidth()-6)
+}
+
 // animate runs the spinner animation loop
 func (s *Spinner) animate() {
 	ticker := time.NewTicker(80 * time.Millisecond)
@@ -167,8 +239,9 @@ func (s *Spinner) animate() {
 // render displays the current frame of the spinner
 func (s *Spinner) render() {
 	frame := s.frame.frames[s.frame.index]
-	output := s.style.Render(frame + " " + s.message)
-	fmt.Print("\r" + output + " ")
+	output := s.style.Render(frame + " " + s.label())
+	// Clear the rest of the line, a shorter label would otherwise leave old text behind
+	fmt.Print("\r" + output + " \033[K")
 }
 
 // WithStyle sets a custom style for the spinner
@@ -0,0 +1,59 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package charm
+
+import "sync"
+
+// Strict mode turns warnings into a failing exit for CI runs. Every warning printed
+// through the global handler or a spinner is recorded so the run can list them at the end.
+var (
+	strictMode     bool
+	strictWarnings []string
+	strictMutex    sync.Mutex
+)
+
+// SetStrict enables or disables strict mode and clears recorded warnings
+func SetStrict(enabled bool) {
+	strictMutex.Lock()
+	defer strictMutex.Unlock()
+	strictMode = enabled
+	strictWarnings = nil
+}
+
+// IsStrict reports whether strict mode is enabled
+func IsStrict() bool {
+	strictMutex.Lock()
+	defer strictMutex.Unlock()
+	return strictMode
+}
+
+// RecordWarning notes a warning for strict mode. Warnings printed with PrintWarning or
+// Spinner.Warning are recorded already, use this for conditions that are only shown as info.
+func RecordWarning(message string) {
+	strictMutex.Lock()
+	defer strictMutex.Unlock()
+	if strictMode {
+		strictWarnings = append(strictWarnings, message)
+	}
+}
+
+// StrictWarnings returns the warnings recorded since strict mode was enabled
+func StrictWarnings() []string {
+	strictMutex.Lock()
+	defer strictMutex.Unlock()
+	return append([]string(nil), strictWarnings...)
+}
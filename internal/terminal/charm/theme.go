@@ -0,0 +1,126 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package charm
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// Theme holds the colors used by every styled element. Colors are lipgloss color strings,
+// an empty string renders without color.
+type Theme struct {
+	Name      string
+	Primary   string // Headers, banners and the main help boxes
+	Accent    string // Stages, spinners and informational boxes
+	Success   string
+	Error     string
+	Warning   string
+	Info      string
+	Special   string // Already available items, code and git settings
+	Highlight string // Groups and config sources
+	Muted     string // Separators and secondary text
+	Progress  string
+	Confirm   string
+	Surface   string // Background of headers, badges and code
+	OnAccent  string // Text drawn on an accent background, such as badges
+}
+
+// Built-in themes selectable with 'ui.theme' in settings.yaml
+var themes = map[string]Theme{
+	"default": {
+		Name: "default", Primary: "#FF6B9D", Accent: "#00D9FF", Success: "#00FF87", Error: "#FF5F87",
+		Warning: "#FFD700", Info: "#87CEEB", Special: "#C792EA", Highlight: "#E0C867", Muted: "#666666",
+		Progress: "#00CED1", Confirm: "#FFA500", Surface: "#2D2D2D", OnAccent: "#000000",
+	},
+	"light": {
+		Name: "light", Primary: "#C2255C", Accent: "#0B7285", Success: "#2B8A3E", Error: "#C92A2A",
+		Warning: "#B35C00", Info: "#1C5D99", Special: "#7048E8", Highlight: "#8F6B00", Muted: "#868E96",
+		Progress: "#0C8599", Confirm: "#D9480F", Surface: "#F1F3F5", OnAccent: "#FFFFFF",
+	},
+	"high-contrast": {
+		Name: "high-contrast", Primary: "#FF00FF", Accent: "#00FFFF", Success: "#00FF00", Error: "#FF0000",
+		Warning: "#FFFF00", Info: "#FFFFFF", Special: "#FF00FF", Highlight: "#FFFF00", Muted: "#C0C0C0",
+		Progress: "#00FFFF", Confirm: "#FFFF00", Surface: "#000000", OnAccent: "#000000",
+	},
+	"monochrome": {Name: "monochrome"},
+}
+
+var (
+	// activeTheme is used by every render helper, spinner and the output handler
+	activeTheme = themes["default"]
+
+	// colorDisabled keeps output monochrome regardless of the configured theme
+	colorDisabled bool
+)
+
+// ActiveTheme returns the theme currently used for output
+func ActiveTheme() Theme {
+	return activeTheme
+}
+
+// ThemeNames returns the names of the built-in themes
+func ThemeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetTheme switches to a built-in theme. While color is disabled the name is only validated.
+func SetTheme(name string) error {
+	theme, ok := themes[name]
+	if !ok {
+		return fmt.Errorf("unknown theme '%s', available: %v", name, ThemeNames())
+	}
+	if colorDisabled {
+		return nil
+	}
+	applyTheme(theme)
+	return nil
+}
+
+// DisableColor switches to monochrome output and stops lipgloss from emitting colors
+func DisableColor() {
+	colorDisabled = true
+	lipgloss.SetColorProfile(termenv.Ascii)
+	applyTheme(themes["monochrome"])
+}
+
+// ColorDisabled reports whether output is monochrome because of NO_COLOR or settings
+func ColorDisabled() bool {
+	return colorDisabled
+}
+
+// NoColorRequested reports whether the NO_COLOR convention (https://no-color.org) asks for plain output
+func NoColorRequested() bool {
+	return os.Getenv("NO_COLOR") != ""
+}
+
+// applyTheme makes theme active and restyles the global output handler
+func applyTheme(theme Theme) {
+	activeTheme = theme
+	if handler, ok := globalCharmHandler.(*CharmOutputHandler); ok {
+		handler.styles = createDefaultStyles()
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package charm
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/charmbracelet/x/term"
+)
+
+const (
+	// MaxBoxWidth caps box width on wide terminals
+	MaxBoxWidth = 100
+	// NarrowWidth is the terminal width below which a simplified vertical layout is used
+	NarrowWidth = 60
+	// defaultTerminalWidth is assumed when output is not a terminal and COLUMNS is unset
+	defaultTerminalWidth = 100
+	// minRenderWidth keeps layouts usable on absurdly small terminals
+	minRenderWidth = 20
+)
+
+var (
+	widthOverride int
+	widthMutex    sync.RWMutex
+)
+
+// SetTerminalWidth forces the width used for rendering, 0 restores detection
+func SetTerminalWidth(width int) {
+	widthMutex.Lock()
+	defer widthMutex.Unlock()
+	widthOverride = width
+}
+
+// TerminalWidth returns the width available for rendering: an override set with
+// SetTerminalWidth, then the attached terminal, then COLUMNS, then a default
+func TerminalWidth() int {
+	widthMutex.RLock()
+	override := widthOverride
+	widthMutex.RUnlock()
+	if override > 0 {
+		return max(override, minRenderWidth)
+	}
+
+	if width, _, err := term.GetSize(os.Stdout.Fd()); err == nil && width > 0 {
+		return max(width, minRenderWidth)
+	}
+
+	if columns, err := strconv.Atoi(strings.TrimSpace(os.Getenv("COLUMNS"))); err == nil && columns > 0 {
+		return max(columns, minRenderWidth)
+	}
+
+	return defaultTerminalWidth
+}
+
+// IsNarrow reports whether the simplified vertical layout should be used
+func IsNarrow() bool {
+	return TerminalWidth() < NarrowWidth
+}
+
+// Truncate shortens text to width display cells, ending with an ellipsis. ANSI styling is preserved.
+func Truncate(text string, width int) string {
+	if width <= 0 || ansi.StringWidth(text) <= width {
+		return text
+	}
+	return ansi.Truncate(text, width, "…")
+}
+
+// TruncateLines truncates every line of text to width display cells
+func TruncateLines(text string, width int) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = Truncate(line, width)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Wrap wraps text at word boundaries to width display cells, breaking words that are too long
+func Wrap(text string, width int) string {
+	if width <= 0 {
+		return text
+	}
+	return ansi.Wrap(text, width, "")
+}
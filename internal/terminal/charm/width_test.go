@@ -0,0 +1,111 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package charm
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files")
+
+// assertGolden compares rendered output, stripped of ANSI styling, with testdata/<name>.golden
+func assertGolden(t *testing.T, name, got string) {
+	t.Helper()
+	got = ansi.Strip(got) + "\n"
+	path := filepath.Join("testdata", name+".golden")
+
+	if *updateGolden {
+		if err := os.MkdirAll("testdata", 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("%s mismatch\n--- got ---\n%s\n--- want ---\n%s", name, got, want)
+	}
+}
+
+func TestRenderBoxWidths(t *testing.T) {
+	content := "Install group 'essentials', then sync /Users/someone/Library/Application Support/Code/User/settings.json\n" +
+		"  git, curl, slack, firefox, visual-studio-code, docker, 1password, raycast, obsidian, iterm2"
+
+	for _, tc := range []struct {
+		name  string
+		width int
+	}{
+		{"box_wide", 140},
+		{"box_medium", 72},
+		{"box_narrow", 44},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := renderBox("🔨 PROVISIONING: work laptop with a rather long title", content, "", false, tc.width)
+			assertGolden(t, tc.name, got)
+
+			for _, line := range splitLines(got) {
+				if w := ansi.StringWidth(line); w > tc.width {
+					t.Errorf("line is %d cells wide, terminal is %d: %q", w, tc.width, ansi.Strip(line))
+				}
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := Truncate("visual-studio-code", 10); got != "visual-st…" {
+		t.Errorf("Truncate() = %q, want %q", got, "visual-st…")
+	}
+	if got := Truncate("git", 10); got != "git" {
+		t.Errorf("Truncate() of short text = %q, want unchanged", got)
+	}
+}
+
+func TestTerminalWidthOverride(t *testing.T) {
+	SetTerminalWidth(50)
+	defer SetTerminalWidth(0)
+
+	if got := TerminalWidth(); got != 50 {
+		t.Errorf("TerminalWidth() = %d, want 50", got)
+	}
+	if !IsNarrow() {
+		t.Error("IsNarrow() should be true below NarrowWidth")
+	}
+}
+
+// splitLines splits rendered output into lines
+func splitLines(s string) []string {
+	var lines []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\n' {
+			lines = append(lines, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(lines, s[start:])
+}
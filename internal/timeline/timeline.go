@@ -0,0 +1,153 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package timeline keeps a log of the config pushes, pulls and syncs made on this machine, so
+// they can be listed per app next to the commits of the config repository.
+package timeline
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/config"
+)
+
+// FileName is the log under ~/.anvil, one JSON entry per line
+const FileName = "timeline.jsonl"
+
+// Action is what happened to an app's configs
+type Action string
+
+const (
+	Pushed     Action = "pushed"      // Local configs were pushed to a branch of the config repository
+	Pulled     Action = "pulled"      // Configs were pulled from the config repository
+	Synced     Action = "synced"      // Pulled configs were applied locally
+	RolledBack Action = "rolled-back" // Archived configs were restored
+	Committed  Action = "committed"   // A commit of the config repository changed the app, from any machine
+)
+
+// Where an entry comes from
+const (
+	SourceLocal      = "local"
+	SourceRepository = "repository"
+)
+
+// Entry is one change of an app's configs
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Action  Action    `json:"action"`
+	App     string    `json:"app"`
+	Source  string    `json:"source"`
+	Machine string    `json:"machine,omitempty"` // Hostname the change was made on, for local entries
+	Repo    string    `json:"repo,omitempty"`
+	Branch  string    `json:"branch,omitempty"`
+	Commit  string    `json:"commit,omitempty"`
+	Author  string    `json:"author,omitempty"`  // Commit author, for repository entries
+	Details string    `json:"details,omitempty"` // e.g. the commit subject or the restored archive
+}
+
+// GetPath returns the path of the timeline log
+func GetPath() string {
+	return filepath.Join(config.GetAnvilConfigDirectory(), FileName)
+}
+
+// Record appends a local entry for this machine. The timeline is informational, so failing
+// to write it never fails the push, pull or sync being recorded.
+func Record(entry Entry) {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	entry.Source = SourceLocal
+	if entry.Machine == "" {
+		host, _ := os.Hostname()
+		entry.Machine = strings.TrimSuffix(host, ".local")
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(GetPath()), 0755); err != nil {
+		return
+	}
+	file, err := os.OpenFile(GetPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	file.Write(append(data, '\n'))
+}
+
+// Load reads the local entries of an app, or of every app when app is empty, oldest first.
+// Lines that don't parse are skipped.
+func Load(app string) ([]Entry, error) {
+	file, err := os.Open(GetPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry Entry
+		if json.Unmarshal(scanner.Bytes(), &entry) != nil || entry.App == "" {
+			continue
+		}
+		if app == "" || entry.App == app {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// Merge combines entries newest first, keeping at most limit of them (0 keeps all)
+func Merge(limit int, lists ...[]Entry) []Entry {
+	var merged []Entry
+	for _, list := range lists {
+		merged = append(merged, list...)
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Time.After(merged[j].Time)
+	})
+	if limit > 0 && len(merged) > limit {
+		merged = merged[:limit]
+	}
+	return merged
+}
+
+// LastChanges returns the newest entry of each app that changed its configs, a push, sync,
+// rollback or commit, by app name
+func LastChanges(entries []Entry) map[string]Entry {
+	last := make(map[string]Entry)
+	for _, entry := range entries {
+		if entry.Action == Pulled {
+			continue
+		}
+		if current, ok := last[entry.App]; !ok || entry.Time.After(current.Time) {
+			last[entry.App] = entry
+		}
+	}
+	return last
+}
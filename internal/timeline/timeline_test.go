@@ -0,0 +1,104 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package timeline
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordAndLoad(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	Record(Entry{Time: base, Action: Pulled, App: "nvim", Machine: "work", Commit: "abc123"})
+	Record(Entry{Time: base.Add(time.Minute), Action: Synced, App: "nvim"})
+	Record(Entry{Time: base.Add(2 * time.Minute), Action: Pushed, App: "zsh", Branch: "config-push-zsh"})
+
+	entries, err := Load("nvim")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Load(nvim) returned %d entries, want 2", len(entries))
+	}
+	if entries[0].Action != Pulled || entries[0].Machine != "work" || entries[0].Source != SourceLocal {
+		t.Errorf("first entry = %+v", entries[0])
+	}
+	if entries[1].Machine == "" {
+		t.Error("Record() should fill in the machine name")
+	}
+
+	all, err := Load("")
+	if err != nil || len(all) != 3 {
+		t.Fatalf("Load(\"\") = %d entries, %v; want 3", len(all), err)
+	}
+}
+
+func TestLoadSkipsBadLines(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if entries, err := Load(""); err != nil || entries != nil {
+		t.Fatalf("Load() without a timeline = %v, %v; want nothing", entries, err)
+	}
+
+	Record(Entry{Action: Synced, App: "nvim"})
+	file, err := os.OpenFile(GetPath(), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString("not json\n{\"action\":\"synced\"}\n")
+	file.Close()
+
+	entries, err := Load("")
+	if err != nil || len(entries) != 1 {
+		t.Errorf("Load() = %d entries, %v; want 1", len(entries), err)
+	}
+	if filepath.Base(GetPath()) != FileName {
+		t.Errorf("GetPath() = %s", GetPath())
+	}
+}
+
+func TestMergeAndLastChanges(t *testing.T) {
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	local := []Entry{
+		{Time: base, Action: Synced, App: "nvim"},
+		{Time: base.Add(2 * time.Hour), Action: Pulled, App: "nvim"},
+	}
+	repository := []Entry{
+		{Time: base.Add(time.Hour), Action: Committed, App: "nvim", Source: SourceRepository},
+		{Time: base.Add(3 * time.Hour), Action: Committed, App: "zsh", Source: SourceRepository},
+	}
+
+	merged := Merge(0, local, repository)
+	if len(merged) != 4 || merged[0].App != "zsh" || merged[3].Action != Synced {
+		t.Fatalf("Merge() = %+v, want newest first", merged)
+	}
+	if limited := Merge(2, local, repository); len(limited) != 2 || limited[1].Action != Pulled {
+		t.Errorf("Merge(2) = %+v", limited)
+	}
+
+	last := LastChanges(merged)
+	if last["nvim"].Action != Committed {
+		t.Errorf("LastChanges()[nvim] = %s, want committed (pulls don't change configs)", last["nvim"].Action)
+	}
+	if _, ok := last["zsh"]; !ok || len(last) != 2 {
+		t.Errorf("LastChanges() = %v", last)
+	}
+}
@@ -19,8 +19,8 @@ package tools
 import (
 	"fmt"
 
-	"github.com/0xjuanma/anvil/internal/brew"
 	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/pkgmanager"
 	"github.com/0xjuanma/anvil/internal/system"
 	"github.com/0xjuanma/palantir"
 )
@@ -58,12 +58,16 @@ func GetRequiredTools() []Tool {
 // ValidateAndInstallTools validates and installs required tools on macOS
 func ValidateAndInstallTools() error {
 
-	// Phase 1: Install Homebrew as a prerequisite (required for other tool installations)
-	if err := brew.EnsureBrewIsInstalled(); err != nil {
+	// Phase 1: Make the package manager ready, Homebrew is installed when missing
+	manager, err := pkgmanager.Current()
+	if err != nil {
+		return fmt.Errorf("tools: %w", err)
+	}
+	if err := manager.Ensure(); err != nil {
 		return fmt.Errorf("tools: %w", err)
 	}
 
-	// Phase 2: Validate and install other required tools (using Homebrew when needed)
+	// Phase 2: Validate and install other required tools (using the package manager when needed)
 	requiredTools := GetRequiredTools()
 	for _, tool := range requiredTools {
 		if err := validateTool(tool); err != nil {
@@ -92,8 +96,8 @@ func validateTool(tool Tool) error {
 
 	switch tool.InstallWith {
 	case "brew":
-		if err := brew.InstallPackage(tool.Command); err != nil {
-			return fmt.Errorf("failed to install %s with brew: %w", tool.Name, err)
+		if err := pkgmanager.Install(tool.Command); err != nil {
+			return fmt.Errorf("failed to install %s: %w", tool.Name, err)
 		}
 	case "system":
 		// cURL should be available
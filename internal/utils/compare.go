@@ -0,0 +1,151 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// compareChunkSize is how much of each file is read at a time when comparing contents
+const compareChunkSize = 64 * 1024
+
+// DirChanges lists files that differ between two directory trees, as paths relative to the trees
+type DirChanges struct {
+	Added    []string
+	Removed  []string
+	Modified []string
+}
+
+// IsEmpty reports whether the trees hold the same files with the same contents
+func (c DirChanges) IsEmpty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Modified) == 0
+}
+
+// CompareDirectories compares the files under oldDir and newDir by content.
+// A missing oldDir is treated as empty, so every file in newDir is reported as added.
+func CompareDirectories(oldDir, newDir string) (DirChanges, error) {
+	var changes DirChanges
+
+	oldFiles, err := listFiles(oldDir)
+	if err != nil && !os.IsNotExist(err) {
+		return changes, err
+	}
+	newFiles, err := listFiles(newDir)
+	if err != nil {
+		return changes, err
+	}
+
+	for rel := range newFiles {
+		if _, existed := oldFiles[rel]; !existed {
+			changes.Added = append(changes.Added, rel)
+			continue
+		}
+		same, err := sameContents(filepath.Join(oldDir, rel), filepath.Join(newDir, rel))
+		if err != nil {
+			return changes, err
+		}
+		if !same {
+			changes.Modified = append(changes.Modified, rel)
+		}
+	}
+	for rel := range oldFiles {
+		if _, exists := newFiles[rel]; !exists {
+			changes.Removed = append(changes.Removed, rel)
+		}
+	}
+
+	sort.Strings(changes.Added)
+	sort.Strings(changes.Removed)
+	sort.Strings(changes.Modified)
+	return changes, nil
+}
+
+// listFiles returns the relative paths of all regular files and symlinks under dir
+func listFiles(dir string) (map[string]struct{}, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]struct{})
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[rel] = struct{}{}
+		return nil
+	})
+	return files, err
+}
+
+// sameContents reports whether two files hold identical bytes
+func sameContents(a, b string) (bool, error) {
+	infoA, err := os.Lstat(a)
+	if err != nil {
+		return false, err
+	}
+	infoB, err := os.Lstat(b)
+	if err != nil {
+		return false, err
+	}
+	if infoA.Size() != infoB.Size() {
+		return false, nil
+	}
+
+	fileA, err := os.Open(a)
+	if err != nil {
+		return false, err
+	}
+	defer fileA.Close()
+	fileB, err := os.Open(b)
+	if err != nil {
+		return false, err
+	}
+	defer fileB.Close()
+
+	// Compare chunk by chunk, large files are never held in memory whole
+	chunkA := make([]byte, compareChunkSize)
+	chunkB := make([]byte, compareChunkSize)
+	for {
+		nA, errA := io.ReadFull(fileA, chunkA)
+		nB, errB := io.ReadFull(fileB, chunkB)
+		if !bytes.Equal(chunkA[:nA], chunkB[:nB]) {
+			return false, nil
+		}
+		doneA := errA == io.EOF || errA == io.ErrUnexpectedEOF
+		doneB := errB == io.EOF || errB == io.ErrUnexpectedEOF
+		if errA != nil && !doneA {
+			return false, errA
+		}
+		if errB != nil && !doneB {
+			return false, errB
+		}
+		if doneA || doneB {
+			return doneA == doneB, nil
+		}
+	}
+}
@@ -0,0 +1,226 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// DiffContextLines is how many unchanged lines surround each hunk, matching git's default
+const DiffContextLines = 3
+
+// diffOp is one line of an edit script: ' ' kept, '-' removed from the old text, '+' added by the new one
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// UnifiedDiff renders the changes from oldText to newText as a unified diff with the given file labels.
+// It returns an empty string when both texts are equal.
+func UnifiedDiff(oldLabel, newLabel, oldText, newText string) string {
+	ops := diffLines(splitLines(oldText), splitLines(newText))
+
+	var b strings.Builder
+	for _, hunk := range groupHunks(ops, DiffContextLines) {
+		if b.Len() == 0 {
+			fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldLabel, newLabel)
+		}
+		b.WriteString(hunk)
+	}
+	return b.String()
+}
+
+// ColorizeDiff styles the added, removed and hunk header lines of a unified diff with the active theme
+func ColorizeDiff(diff string) string {
+	theme := charm.ActiveTheme()
+	added := lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Success))
+	removed := lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Error))
+	header := lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Accent))
+	labels := lipgloss.NewStyle().Bold(true)
+
+	lines := strings.Split(diff, "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "+++ "), strings.HasPrefix(line, "--- "):
+			lines[i] = labels.Render(line)
+		case strings.HasPrefix(line, "@@"):
+			lines[i] = header.Render(line)
+		case strings.HasPrefix(line, "+"):
+			lines[i] = added.Render(line)
+		case strings.HasPrefix(line, "-"):
+			lines[i] = removed.Render(line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// splitLines splits text into lines that keep their newline, so a missing final newline counts as a change
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes the shortest edit script between a and b with Myers' algorithm
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+2)
+	var trace [][]int
+
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace, offset)
+			}
+		}
+	}
+	return nil
+}
+
+// backtrack walks the Myers trace from the end of both texts back to the start, building the edit script
+func backtrack(a, b []string, trace [][]int, offset int) []diffOp {
+	x, y := len(a), len(b)
+	var ops []diffOp
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			ops = append(ops, diffOp{' ', a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, diffOp{'+', b[y-1]})
+			} else {
+				ops = append(ops, diffOp{'-', a[x-1]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// groupHunks renders the changed regions of ops as hunks, merging changes closer than twice the context
+func groupHunks(ops []diffOp, context int) []string {
+	var changes []int
+	for i, op := range ops {
+		if op.kind != ' ' {
+			changes = append(changes, i)
+		}
+	}
+
+	var hunks []string
+	for i := 0; i < len(changes); {
+		j := i
+		for j+1 < len(changes) && changes[j+1]-changes[j]-1 <= 2*context {
+			j++
+		}
+		start := changes[i] - context
+		if start < 0 {
+			start = 0
+		}
+		end := changes[j] + context + 1
+		if end > len(ops) {
+			end = len(ops)
+		}
+		hunks = append(hunks, renderHunk(ops, start, end))
+		i = j + 1
+	}
+	return hunks
+}
+
+// renderHunk renders ops[start:end] with its @@ header
+func renderHunk(ops []diffOp, start, end int) string {
+	oldStart, newStart := 0, 0
+	for _, op := range ops[:start] {
+		if op.kind != '+' {
+			oldStart++
+		}
+		if op.kind != '-' {
+			newStart++
+		}
+	}
+
+	var body strings.Builder
+	oldCount, newCount := 0, 0
+	for _, op := range ops[start:end] {
+		if op.kind != '+' {
+			oldCount++
+		}
+		if op.kind != '-' {
+			newCount++
+		}
+		body.WriteByte(op.kind)
+		body.WriteString(strings.TrimSuffix(op.line, "\n"))
+		body.WriteString("\n")
+		if !strings.HasSuffix(op.line, "\n") {
+			body.WriteString("\\ No newline at end of file\n")
+		}
+	}
+
+	return fmt.Sprintf("@@ -%s +%s @@\n%s", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount), body.String())
+}
+
+// hunkRange formats a hunk side as git does: a lone line omits the count, an empty side points at the line before it
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
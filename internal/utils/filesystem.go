@@ -17,25 +17,51 @@ limitations under the License.
 package utils
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
+	"sync"
 
 	"github.com/0xjuanma/anvil/internal/constants"
 )
 
+// copyBufferSize is the chunk size file contents are streamed in
+const copyBufferSize = 1 << 20
+
+// copyTempSuffix marks the temporary file a copy is written to before it replaces the destination
+const copyTempSuffix = ".anvil-copy-*"
+
+// copyBuffers reuses copy buffers across files and concurrent copies
+var copyBuffers = sync.Pool{
+	New: func() interface{} {
+		buffer := make([]byte, copyBufferSize)
+		return &buffer
+	},
+}
+
 // CopyOptions holds options for file and directory copying operations
 type CopyOptions struct {
 	// Common options
-	Overwrite     bool
-	PreservePerms bool
-	FileMode      os.FileMode
+	Overwrite      bool
+	PreservePerms  bool
+	FileMode       os.FileMode
+	PreserveXattrs bool
+	PreserveTimes  bool // Keep the modification time of copied files
+	Verify         bool // Re-read each copy and compare its SHA-256 with the source
 
 	// Directory-specific options (ignored for files)
-	IncludeHidden bool
-	DirMode       os.FileMode
-	Merge         bool
+	IncludeHidden    bool
+	DirMode          os.FileMode
+	Merge            bool
+	PreserveSymlinks bool     // Recreate nested symlinks instead of copying their targets
+	Exclude          []string // Glob patterns relative to src that are neither copied nor removed from dst
+	Include          []string // Glob patterns relative to src, when set other files are neither copied nor removed from dst
 
 	// File-specific options (ignored for directories)
 	CreateDirs bool
@@ -54,8 +80,29 @@ func DefaultCopyOptions() CopyOptions {
 	}
 }
 
+// ConfigCopyOptions returns the options config push, pull and sync copy app configs with. Unlike
+// the defaults they keep modes, extended attributes, modification times and nested symlinks, and
+// verify every copy.
+func ConfigCopyOptions() CopyOptions {
+	options := DefaultCopyOptions()
+	options.PreservePerms = true
+	options.PreserveXattrs = true
+	options.PreserveTimes = true
+	options.Verify = true
+	options.PreserveSymlinks = true
+	return options
+}
+
 // CopyFile copies a file from src to dst with configurable options.
 func CopyFile(src, dst string, options CopyOptions) error {
+	return CopyFileContext(context.Background(), src, dst, options)
+}
+
+// CopyFileContext copies a file from src to dst, streaming it through a fixed-size buffer into a
+// temporary file next to dst. The temporary file replaces dst only once it is complete and its
+// size, and with Verify its SHA-256, matches the source, so cancelling ctx or a failed copy never
+// leaves a half-written dst behind.
+func CopyFileContext(ctx context.Context, src, dst string, options CopyOptions) error {
 	srcInfo, err := os.Stat(src)
 	if err != nil {
 		return fmt.Errorf("source file error: %w", err)
@@ -66,7 +113,8 @@ func CopyFile(src, dst string, options CopyOptions) error {
 	}
 
 	// Check if destination exists and handle overwrite
-	if _, err := os.Stat(dst); err == nil && !options.Overwrite {
+	dstInfo, err := os.Stat(dst)
+	if err == nil && !options.Overwrite {
 		return fmt.Errorf("destination exists: %s", dst)
 	}
 
@@ -76,30 +124,164 @@ func CopyFile(src, dst string, options CopyOptions) error {
 		}
 	}
 
+	// Replace the file a symlink at dst points to rather than the link, e.g. dotfiles linked into place
+	if resolved, err := filepath.EvalSymlinks(dst); err == nil {
+		dst = resolved
+	}
+
+	fileMode := options.FileMode
+	switch {
+	case options.PreservePerms:
+		fileMode = srcInfo.Mode().Perm()
+	case dstInfo != nil:
+		// Overwriting keeps the mode of the existing file, as writing into it in place did
+		fileMode = dstInfo.Mode().Perm()
+	}
+
 	srcFile, err := os.Open(src)
 	if err != nil {
 		return fmt.Errorf("failed to open source file: %w", err)
 	}
 	defer srcFile.Close()
 
-	fileMode := options.FileMode
-	if options.PreservePerms {
-		fileMode = srcInfo.Mode()
-	}
-
-	dstFile, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fileMode)
+	tmpFile, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+copyTempSuffix)
 	if err != nil {
 		return fmt.Errorf("failed to create destination file: %w", err)
 	}
-	defer dstFile.Close()
+	tmpPath := tmpFile.Name()
+	committed := false
+	defer func() {
+		if !committed {
+			tmpFile.Close()
+			os.Remove(tmpPath)
+		}
+	}()
 
-	if _, err := io.Copy(dstFile, srcFile); err != nil {
+	hash := sha256.New()
+	written, err := streamCopy(ctx, tmpFile, io.TeeReader(srcFile, hash))
+	if err != nil {
 		return fmt.Errorf("failed to copy file contents: %w", err)
 	}
+	if written != srcInfo.Size() {
+		return fmt.Errorf("copied %d of %d bytes from %s, the file changed while copying", written, srcInfo.Size(), src)
+	}
+
+	if err := tmpFile.Chmod(fileMode); err != nil {
+		return fmt.Errorf("failed to set file mode: %w", err)
+	}
+	if err := tmpFile.Sync(); err != nil {
+		return fmt.Errorf("failed to flush destination file: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to close destination file: %w", err)
+	}
+
+	if options.Verify {
+		copied, err := FileSHA256(ctx, tmpPath)
+		if err != nil {
+			return fmt.Errorf("failed to verify copy: %w", err)
+		}
+		if copied != hex.EncodeToString(hash.Sum(nil)) {
+			return fmt.Errorf("verification failed, the copy of %s does not match the source", src)
+		}
+	}
 
+	if options.PreserveXattrs {
+		if err := copyXattrs(src, tmpPath); err != nil {
+			return fmt.Errorf("failed to copy extended attributes: %w", err)
+		}
+	}
+
+	if options.PreserveTimes {
+		if err := os.Chtimes(tmpPath, srcInfo.ModTime(), srcInfo.ModTime()); err != nil {
+			return fmt.Errorf("failed to set modification time: %w", err)
+		}
+	}
+
+	if err := os.Rename(tmpPath, dst); err != nil {
+		return fmt.Errorf("failed to replace destination file: %w", err)
+	}
+	committed = true
 	return nil
 }
 
+// streamCopy copies r to w through a pooled fixed-size buffer, so memory use does not grow
+// with file size, and stops between chunks once ctx is done
+func streamCopy(ctx context.Context, w io.Writer, r io.Reader) (int64, error) {
+	buffer := copyBuffers.Get().(*[]byte)
+	defer copyBuffers.Put(buffer)
+
+	var written int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+		n, readErr := r.Read(*buffer)
+		if n > 0 {
+			m, err := w.Write((*buffer)[:n])
+			written += int64(m)
+			if err != nil {
+				return written, err
+			}
+			if m != n {
+				return written, io.ErrShortWrite
+			}
+		}
+		if readErr == io.EOF {
+			return written, nil
+		}
+		if readErr != nil {
+			return written, readErr
+		}
+	}
+}
+
+// FileSHA256 returns the hex SHA-256 of a file, streamed through the copy buffer
+func FileSHA256(ctx context.Context, path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := streamCopy(ctx, hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// CopySymlink recreates the symlink at src as dst, pointing to the same target
+func CopySymlink(src, dst string, overwrite bool) error {
+	target, err := os.Readlink(src)
+	if err != nil {
+		return fmt.Errorf("failed to read symlink: %w", err)
+	}
+
+	if dstInfo, err := os.Lstat(dst); err == nil {
+		if !overwrite {
+			return fmt.Errorf("destination exists: %s", dst)
+		}
+		if dstInfo.IsDir() {
+			return fmt.Errorf("refusing to replace directory with symlink: %s", dst)
+		}
+		if err := os.Remove(dst); err != nil {
+			return fmt.Errorf("failed to remove existing destination: %w", err)
+		}
+	}
+
+	if err := EnsureDirectory(filepath.Dir(dst)); err != nil {
+		return fmt.Errorf("failed to create destination directory: %w", err)
+	}
+
+	return os.Symlink(target, dst)
+}
+
+// IsSymlink reports whether the file info describes a symbolic link
+func IsSymlink(info os.FileInfo) bool {
+	return info.Mode()&os.ModeSymlink != 0
+}
+
 // CopyFileSimple copies a file using default options.
 func CopyFileSimple(src, dst string) error {
 	return CopyFile(src, dst, DefaultCopyOptions())
@@ -107,6 +289,12 @@ func CopyFileSimple(src, dst string) error {
 
 // CopyDirectory recursively copies a directory from src to dst with configurable options.
 func CopyDirectory(src, dst string, options CopyOptions) error {
+	return CopyDirectoryContext(context.Background(), src, dst, options)
+}
+
+// CopyDirectoryContext recursively copies a directory like CopyDirectory, stopping once ctx is
+// done. Files already copied stay in place, but none is left half-written.
+func CopyDirectoryContext(ctx context.Context, src, dst string, options CopyOptions) error {
 	srcInfo, err := os.Stat(src)
 	if err != nil {
 		return fmt.Errorf("source directory error: %w", err)
@@ -117,7 +305,7 @@ func CopyDirectory(src, dst string, options CopyOptions) error {
 	}
 
 	if options.Overwrite && !options.Merge {
-		if err := os.RemoveAll(dst); err != nil {
+		if err := removeExceptExcluded(dst, options.Include, options.Exclude); err != nil {
 			return fmt.Errorf("failed to remove existing destination: %w", err)
 		}
 	}
@@ -126,6 +314,9 @@ func CopyDirectory(src, dst string, options CopyOptions) error {
 		if err != nil {
 			return fmt.Errorf("walk %s: %w", path, err)
 		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 
 		if !options.IncludeHidden && isHidden(info.Name()) {
 			if info.IsDir() {
@@ -138,31 +329,188 @@ func CopyDirectory(src, dst string, options CopyOptions) error {
 		if err != nil {
 			return fmt.Errorf("failed to get relative path: %w", err)
 		}
+		if SkipsPath(relPath, info.IsDir(), options.Include, options.Exclude) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
 		destPath := filepath.Join(dst, relPath)
 
+		if IsSymlink(info) {
+			if options.PreserveSymlinks {
+				return CopySymlink(path, destPath, options.Overwrite)
+			}
+			return materializeSymlink(ctx, path, destPath, options)
+		}
+
 		if info.IsDir() {
+			// Only directories holding included files are created, by the copies themselves
+			if len(options.Include) > 0 {
+				return nil
+			}
 			dirMode := options.DirMode
 			if options.PreservePerms {
-				dirMode = info.Mode()
+				dirMode = info.Mode().Perm()
 			}
 			return os.MkdirAll(destPath, dirMode)
 		}
 
-		fileOptions := CopyOptions{
-			CreateDirs:    true,
-			Overwrite:     options.Overwrite,
-			PreservePerms: options.PreservePerms,
-			FileMode:      options.FileMode,
-		}
-		return CopyFile(path, destPath, fileOptions)
+		return CopyFileContext(ctx, path, destPath, options.fileOptions())
 	})
 }
 
+// materializeSymlink copies the content a symlink points to instead of the link itself
+func materializeSymlink(ctx context.Context, path, destPath string, options CopyOptions) error {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return fmt.Errorf("broken symlink %s: %w", path, err)
+	}
+
+	targetInfo, err := os.Stat(resolved)
+	if err != nil {
+		return fmt.Errorf("failed to stat symlink target %s: %w", resolved, err)
+	}
+
+	if !targetInfo.IsDir() {
+		return CopyFileContext(ctx, resolved, destPath, options.fileOptions())
+	}
+
+	// A link back into one of its own parents would recurse forever
+	absPath, err := filepath.Abs(filepath.Dir(path))
+	if err == nil {
+		if realParent, err := filepath.EvalSymlinks(absPath); err == nil {
+			if realParent == resolved || strings.HasPrefix(realParent, resolved+string(filepath.Separator)) {
+				return fmt.Errorf("symlink cycle detected: %s -> %s", path, resolved)
+			}
+		}
+	}
+
+	return CopyDirectoryContext(ctx, resolved, destPath, options)
+}
+
+// fileOptions returns the options of a directory copy that apply to each file in it
+func (options CopyOptions) fileOptions() CopyOptions {
+	return CopyOptions{
+		CreateDirs:     true,
+		Overwrite:      options.Overwrite,
+		PreservePerms:  options.PreservePerms,
+		FileMode:       options.FileMode,
+		PreserveXattrs: options.PreserveXattrs,
+		PreserveTimes:  options.PreserveTimes,
+		Verify:         options.Verify,
+	}
+}
+
 // CopyDirectorySimple copies a directory using default options.
 func CopyDirectorySimple(src, dst string) error {
 	return CopyDirectory(src, dst, DefaultCopyOptions())
 }
 
+// MatchesExclude reports whether a relative path matches any exclude pattern.
+// Patterns without a slash match a file or directory name at any depth, and a "**"
+// segment matches any number of directories.
+func MatchesExclude(relPath string, patterns []string) bool {
+	if relPath == "." || len(patterns) == 0 {
+		return false
+	}
+
+	relPath = filepath.ToSlash(relPath)
+	for _, pattern := range patterns {
+		pattern = strings.TrimSuffix(filepath.ToSlash(pattern), "/")
+		if matchSegments(strings.Split(pattern, "/"), strings.Split(relPath, "/")) {
+			return true
+		}
+		if !strings.Contains(pattern, "/") {
+			if matched, _ := path.Match(pattern, path.Base(relPath)); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchSegments matches a path against a pattern segment by segment, "**" matching zero or more segments
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(pattern[0], segments[0]); !matched {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
+// SkipsPath reports whether a relative path is left out by include and exclude patterns.
+// Directories are only left out when excluded, so included files below them are still found.
+// A path is matched when it or one of its parent directories matches a pattern.
+func SkipsPath(relPath string, isDir bool, include, exclude []string) bool {
+	if matchesPathOrParent(relPath, exclude) {
+		return true
+	}
+	return !isDir && relPath != "." && len(include) > 0 && !matchesPathOrParent(relPath, include)
+}
+
+// matchesPathOrParent reports whether a relative path or one of its parent directories matches a pattern
+func matchesPathOrParent(relPath string, patterns []string) bool {
+	for p := relPath; p != "." && p != string(filepath.Separator); p = filepath.Dir(p) {
+		if MatchesExclude(p, patterns) {
+			return true
+		}
+	}
+	return false
+}
+
+// removeExceptExcluded removes a directory tree, keeping entries left out by the patterns and their parent directories
+func removeExceptExcluded(dir string, include, exclude []string) error {
+	if len(include) == 0 && len(exclude) == 0 {
+		return os.RemoveAll(dir)
+	}
+
+	var dirs []string
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		relPath, _ := filepath.Rel(dir, p)
+		if SkipsPath(relPath, info.IsDir(), include, exclude) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			dirs = append(dirs, p)
+			return nil
+		}
+		return os.Remove(p)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Deepest directories first; those still holding excluded entries stay
+	for i := len(dirs) - 1; i >= 0; i-- {
+		os.Remove(dirs[i])
+	}
+	return nil
+}
+
 // isHidden checks if a file/directory name represents a hidden item
 func isHidden(name string) bool {
 	return len(name) > 0 && name[0] == '.'
@@ -17,9 +17,14 @@ limitations under the License.
 package utils
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestCopyDirectoryMergeBehavior(t *testing.T) {
@@ -218,3 +223,404 @@ func TestCopyDirectorySourceNotExists(t *testing.T) {
 		t.Error("Expected error for non-existent source, got nil")
 	}
 }
+
+func TestCopyDirectoryPreservesExecutableBit(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	destDir := filepath.Join(tempDir, "dest")
+
+	if err := os.MkdirAll(filepath.Join(sourceDir, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, "bin", "helper.sh"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CopyDirectory(sourceDir, destDir, ConfigCopyOptions()); err != nil {
+		t.Fatalf("CopyDirectory failed: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(destDir, "bin", "helper.sh"))
+	if err != nil {
+		t.Fatalf("Script not copied: %v", err)
+	}
+	if info.Mode().Perm()&0100 == 0 {
+		t.Errorf("Executable bit lost: got mode %v", info.Mode().Perm())
+	}
+}
+
+func TestCopyDirectorySimpleKeepsDefaults(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	destDir := filepath.Join(tempDir, "dest")
+
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, "helper.sh"), []byte("#!/bin/sh\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("helper.sh", filepath.Join(sourceDir, "current.sh")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CopyDirectorySimple(sourceDir, destDir); err != nil {
+		t.Fatalf("CopyDirectorySimple failed: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(destDir, "helper.sh"))
+	if err != nil {
+		t.Fatalf("Script not copied: %v", err)
+	}
+	if want := DefaultCopyOptions().FileMode; info.Mode().Perm() != want {
+		t.Errorf("mode = %v, want the default %v", info.Mode().Perm(), want)
+	}
+	if info, err := os.Lstat(filepath.Join(destDir, "current.sh")); err != nil || IsSymlink(info) {
+		t.Errorf("expected current.sh to be copied as a regular file, got %v, %v", info, err)
+	}
+}
+
+func TestCopyDirectorySymlinks(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+
+	if err := os.MkdirAll(filepath.Join(sourceDir, "themes"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, "themes", "dark.json"), []byte("dark"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join("themes", "dark.json"), filepath.Join(sourceDir, "current.json")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("themes", filepath.Join(sourceDir, "active-themes")); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("preserve", func(t *testing.T) {
+		destDir := filepath.Join(tempDir, "preserved")
+		if err := CopyDirectory(sourceDir, destDir, ConfigCopyOptions()); err != nil {
+			t.Fatalf("CopyDirectory failed: %v", err)
+		}
+
+		for _, link := range []string{"current.json", "active-themes"} {
+			info, err := os.Lstat(filepath.Join(destDir, link))
+			if err != nil {
+				t.Fatalf("Symlink %s not copied: %v", link, err)
+			}
+			if !IsSymlink(info) {
+				t.Errorf("Expected %s to be preserved as a symlink", link)
+			}
+		}
+	})
+
+	t.Run("materialize", func(t *testing.T) {
+		destDir := filepath.Join(tempDir, "materialized")
+		options := ConfigCopyOptions()
+		options.PreserveSymlinks = false
+		if err := CopyDirectory(sourceDir, destDir, options); err != nil {
+			t.Fatalf("CopyDirectory failed: %v", err)
+		}
+
+		info, err := os.Lstat(filepath.Join(destDir, "current.json"))
+		if err != nil {
+			t.Fatalf("Materialized file not copied: %v", err)
+		}
+		if IsSymlink(info) {
+			t.Error("Expected current.json to be materialized as a regular file")
+		}
+
+		content, err := os.ReadFile(filepath.Join(destDir, "active-themes", "dark.json"))
+		if err != nil {
+			t.Fatalf("Materialized directory not copied: %v", err)
+		}
+		if string(content) != "dark" {
+			t.Errorf("Content mismatch: got %q, want %q", string(content), "dark")
+		}
+	})
+}
+
+func TestCopyDirectoryExclude(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	destDir := filepath.Join(tempDir, "dest")
+
+	files := map[string]string{
+		filepath.Join(sourceDir, "init.lua"):       "remote",
+		filepath.Join(sourceDir, "work.local"):     "remote",
+		filepath.Join(sourceDir, "secrets", "key"): "remote",
+		filepath.Join(destDir, "init.lua"):         "local",
+		filepath.Join(destDir, "work.local"):       "local",
+		filepath.Join(destDir, "secrets", "key"):   "local",
+		filepath.Join(destDir, "stale.lua"):        "local",
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Replace mode removes everything but the excluded entries before copying
+	options := DefaultCopyOptions()
+	options.Merge = false
+	options.Exclude = []string{"*.local", "secrets"}
+	if err := CopyDirectory(sourceDir, destDir, options); err != nil {
+		t.Fatalf("CopyDirectory() error = %v", err)
+	}
+
+	expected := map[string]string{
+		"init.lua":    "remote",
+		"work.local":  "local",
+		"secrets/key": "local",
+	}
+	for rel, want := range expected {
+		data, err := os.ReadFile(filepath.Join(destDir, rel))
+		if err != nil || string(data) != want {
+			t.Errorf("Expected %s to contain %q, got %q (err: %v)", rel, want, data, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(destDir, "stale.lua")); !os.IsNotExist(err) {
+		t.Error("Expected files missing from source to be removed")
+	}
+}
+
+func TestCopyDirectoryInclude(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	destDir := filepath.Join(tempDir, "dest")
+
+	files := map[string]string{
+		filepath.Join(sourceDir, "app.json"):                        "remote",
+		filepath.Join(sourceDir, ".DS_Store"):                       "remote",
+		filepath.Join(sourceDir, "notes.md"):                        "remote",
+		filepath.Join(sourceDir, "plugins", "git", "data.json"):     "remote",
+		filepath.Join(sourceDir, "plugins", "git", "main.js"):       "remote",
+		filepath.Join(sourceDir, "plugins", "git", "manifest.json"): "remote",
+		filepath.Join(destDir, "workspace.md"):                      "local",
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Files outside the include patterns are neither copied nor removed
+	options := DefaultCopyOptions()
+	options.Merge = false
+	options.Include = []string{"*.json", "plugins/**/main.js"}
+	options.Exclude = []string{".DS_Store", "plugins/**/manifest.json"}
+	if err := CopyDirectory(sourceDir, destDir, options); err != nil {
+		t.Fatalf("CopyDirectory() error = %v", err)
+	}
+
+	for _, rel := range []string{"app.json", "plugins/git/data.json", "plugins/git/main.js", "workspace.md"} {
+		if _, err := os.Stat(filepath.Join(destDir, rel)); err != nil {
+			t.Errorf("Expected %s to exist: %v", rel, err)
+		}
+	}
+	for _, rel := range []string{".DS_Store", "notes.md", "plugins/git/manifest.json"} {
+		if _, err := os.Stat(filepath.Join(destDir, rel)); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be left out", rel)
+		}
+	}
+}
+
+func TestMatchesExcludeDoubleStar(t *testing.T) {
+	for _, tt := range []struct {
+		path    string
+		pattern string
+		want    bool
+	}{
+		{"plugins/git/main.js", "plugins/**/main.js", true},
+		{"plugins/main.js", "plugins/**/main.js", true},
+		{"plugins/a/b/main.js", "plugins/**/main.js", true},
+		{"themes/main.js", "plugins/**/main.js", false},
+		{"plugins/git/main.json", "plugins/**/main.js", false},
+		{"a/b/.DS_Store", ".DS_Store", true},
+		{"a/b/c.json", "**/*.json", true},
+	} {
+		if got := MatchesExclude(tt.path, []string{tt.pattern}); got != tt.want {
+			t.Errorf("MatchesExclude(%q, %q) = %v, want %v", tt.path, tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestSkipsPath(t *testing.T) {
+	include := []string{"plugins", "*.json"}
+	exclude := []string{".DS_Store", "plugins/cache"}
+	for _, tt := range []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"app.json", false, false},
+		{"notes.md", false, true},
+		{"plugins/git/main.js", false, false},
+		{"plugins/git/styles.css", false, false},
+		{"plugins/cache", true, true},
+		{"plugins/cache/index.js", false, true},
+		{"themes", true, false},
+		{"themes/dark.css", false, true},
+		{"themes/plugins.css", false, true},
+		{"plugins/.DS_Store", false, true},
+	} {
+		if got := SkipsPath(tt.path, tt.isDir, include, exclude); got != tt.want {
+			t.Errorf("SkipsPath(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestCompareDirectories(t *testing.T) {
+	oldDir := filepath.Join(t.TempDir(), "old")
+	newDir := filepath.Join(t.TempDir(), "new")
+
+	write := func(dir, rel, content string) {
+		path := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// First pull: nothing to compare against, everything is added
+	write(newDir, "settings.json", "{}")
+	changes, err := CompareDirectories(oldDir, newDir)
+	if err != nil {
+		t.Fatalf("CompareDirectories failed: %v", err)
+	}
+	if len(changes.Added) != 1 || len(changes.Removed) != 0 || len(changes.Modified) != 0 {
+		t.Errorf("first pull changes = %+v, want one added file", changes)
+	}
+
+	write(oldDir, "settings.json", "{}")
+	write(oldDir, "keybindings.json", "[]")
+	write(oldDir, "snippets/go.json", "{}")
+	write(newDir, "snippets/go.json", `{"main": {}}`)
+	write(newDir, "snippets/rust.json", "{}")
+
+	changes, err = CompareDirectories(oldDir, newDir)
+	if err != nil {
+		t.Fatalf("CompareDirectories failed: %v", err)
+	}
+	want := DirChanges{
+		Added:    []string{filepath.Join("snippets", "rust.json")},
+		Removed:  []string{"keybindings.json"},
+		Modified: []string{filepath.Join("snippets", "go.json")},
+	}
+	if fmt.Sprint(changes) != fmt.Sprint(want) {
+		t.Errorf("changes = %+v, want %+v", changes, want)
+	}
+
+	if changes, _ := CompareDirectories(newDir, newDir); !changes.IsEmpty() {
+		t.Errorf("identical trees reported changes: %+v", changes)
+	}
+}
+
+func TestCopyFileStreamsLargeFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceFile := filepath.Join(tempDir, "attachment.bin")
+	destFile := filepath.Join(tempDir, "copy", "attachment.bin")
+
+	// Several buffers plus a partial one, so chunk boundaries are exercised
+	content := make([]byte, 3*copyBufferSize+12345)
+	for i := range content {
+		content[i] = byte(i * 7)
+	}
+	if err := os.WriteFile(sourceFile, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(sourceFile, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CopyFile(sourceFile, destFile, ConfigCopyOptions()); err != nil {
+		t.Fatalf("CopyFile failed: %v", err)
+	}
+
+	got, err := os.ReadFile(destFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Fatal("copied content differs from the source")
+	}
+	info, err := os.Stat(destFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(modTime) {
+		t.Errorf("modification time not preserved: got %v, want %v", info.ModTime(), modTime)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode not preserved: got %v", info.Mode().Perm())
+	}
+	if same, err := sameContents(sourceFile, destFile); err != nil || !same {
+		t.Errorf("sameContents = %v, %v, want true", same, err)
+	}
+}
+
+func TestCopyFileContextCancelled(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceFile := filepath.Join(tempDir, "source.txt")
+	destFile := filepath.Join(tempDir, "dest.txt")
+	if err := os.WriteFile(sourceFile, []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(destFile, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := CopyFileContext(ctx, sourceFile, destFile, DefaultCopyOptions()); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a cancellation error, got %v", err)
+	}
+
+	if content, _ := os.ReadFile(destFile); string(content) != "old" {
+		t.Errorf("cancelled copy changed the destination: %q", content)
+	}
+	entries, _ := os.ReadDir(tempDir)
+	if len(entries) != 2 {
+		t.Errorf("cancelled copy left temporary files behind: %v", entries)
+	}
+}
+
+func TestCopyFileThroughSymlink(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceFile := filepath.Join(tempDir, "source.txt")
+	targetFile := filepath.Join(tempDir, "dotfiles", "zshrc")
+	linkFile := filepath.Join(tempDir, ".zshrc")
+
+	if err := os.WriteFile(sourceFile, []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(targetFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(targetFile, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(targetFile, linkFile); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CopyFileSimple(sourceFile, linkFile); err != nil {
+		t.Fatalf("CopyFileSimple failed: %v", err)
+	}
+
+	if info, err := os.Lstat(linkFile); err != nil || !IsSymlink(info) {
+		t.Fatal("the symlink at the destination should be kept")
+	}
+	if content, _ := os.ReadFile(targetFile); string(content) != "new" {
+		t.Errorf("link target not updated: %q", content)
+	}
+}
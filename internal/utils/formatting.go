@@ -28,3 +28,17 @@ func ColorGroupNameWithIcon(text string) string {
 func ColoredName(text string, color string) string {
 	return BoldText(text, color)
 }
+
+// FormatSize formats a byte count in a human readable form
+func FormatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
@@ -21,7 +21,9 @@ import (
 	"strings"
 
 	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
 	"github.com/0xjuanma/palantir"
+	"github.com/charmbracelet/x/ansi"
 )
 
 // AppTreeNode represents a node in the applications tree
@@ -32,8 +34,14 @@ type AppTreeNode struct {
 	Children []*AppTreeNode
 }
 
-// RenderListView renders applications in a flat list format
+// RenderListView renders applications in a flat list format sized to fit inside a box
 func RenderListView(groups map[string][]string, builtInGroupNames []string, customGroupNames []string, installedApps []string) string {
+	return renderListView(groups, builtInGroupNames, customGroupNames, installedApps, charm.BoxContentWidth())
+}
+
+// renderListView renders the list view for the given width. Long app lists wrap under their group,
+// and below charm.NarrowWidth each app gets its own line.
+func renderListView(groups map[string][]string, builtInGroupNames []string, customGroupNames []string, installedApps []string, width int) string {
 	var content strings.Builder
 	content.WriteString("\n")
 
@@ -41,7 +49,7 @@ func RenderListView(groups map[string][]string, builtInGroupNames []string, cust
 	content.WriteString(ColorSectionHeader("Built-in Groups") + "\n\n")
 	for _, groupName := range builtInGroupNames {
 		if tools, exists := groups[groupName]; exists {
-			content.WriteString(fmt.Sprintf("  %s  %s\n", ColorGroupNameWithIcon(groupName), strings.Join(tools, ", ")))
+			content.WriteString(formatGroupLine(groupName, tools, width))
 		}
 	}
 
@@ -49,7 +57,7 @@ func RenderListView(groups map[string][]string, builtInGroupNames []string, cust
 	if len(customGroupNames) > 0 {
 		content.WriteString("\n" + ColorSectionHeader("Custom Groups") + "\n\n")
 		for _, groupName := range customGroupNames {
-			content.WriteString(fmt.Sprintf("  %s  %s\n", ColorGroupNameWithIcon(groupName), strings.Join(groups[groupName], ", ")))
+			content.WriteString(formatGroupLine(groupName, groups[groupName], width))
 		}
 	} else {
 		content.WriteString(fmt.Sprintf("\n%sNo custom groups defined%s\n", palantir.ColorBold+palantir.ColorYellow, palantir.ColorReset))
@@ -60,7 +68,7 @@ func RenderListView(groups map[string][]string, builtInGroupNames []string, cust
 	if len(installedApps) > 0 {
 		content.WriteString("\n" + ColorSectionHeader("Individually Tracked Apps") + "\n\n")
 		for _, app := range installedApps {
-			content.WriteString(fmt.Sprintf("  %s\n", ColorAppName(app)))
+			content.WriteString(fmt.Sprintf("  %s\n", ColorAppName(charm.Truncate(app, width-2))))
 		}
 	}
 
@@ -68,8 +76,35 @@ func RenderListView(groups map[string][]string, builtInGroupNames []string, cust
 	return content.String()
 }
 
-// RenderTreeView renders applications in a hierarchical tree format
+// formatGroupLine renders a group and its apps, wrapping the app list with a hanging indent
+func formatGroupLine(groupName string, tools []string, width int) string {
+	label := fmt.Sprintf("  %s  ", ColorGroupNameWithIcon(groupName))
+
+	if width < charm.NarrowWidth {
+		var b strings.Builder
+		b.WriteString(strings.TrimRight(label, " ") + "\n")
+		for _, tool := range tools {
+			b.WriteString(fmt.Sprintf("    • %s\n", charm.Truncate(tool, width-6)))
+		}
+		return b.String()
+	}
+
+	indent := ansi.StringWidth(label)
+	wrapped := charm.Wrap(strings.Join(tools, ", "), max(width-indent, charm.NarrowWidth/2))
+	lines := strings.Split(wrapped, "\n")
+	for i := 1; i < len(lines); i++ {
+		lines[i] = strings.Repeat(" ", indent) + lines[i]
+	}
+	return label + strings.Join(lines, "\n") + "\n"
+}
+
+// RenderTreeView renders applications in a hierarchical tree format sized to fit inside a box
 func RenderTreeView(groups map[string][]string, builtInGroupNames []string, customGroupNames []string, installedApps []string) string {
+	return renderTreeView(groups, builtInGroupNames, customGroupNames, installedApps, charm.BoxContentWidth())
+}
+
+// renderTreeView renders the tree view for the given width, truncating lines that do not fit
+func renderTreeView(groups map[string][]string, builtInGroupNames []string, customGroupNames []string, installedApps []string, width int) string {
 	// Create root node
 	root := &AppTreeNode{
 		Name:     "Applications",
@@ -146,7 +181,7 @@ func RenderTreeView(groups map[string][]string, builtInGroupNames []string, cust
 	buildTreeString(&content, root, "", true, true)
 	content.WriteString("\n")
 
-	return content.String()
+	return charm.TruncateLines(content.String(), width)
 }
 
 // buildTreeString writes an app tree node to a string builder with ASCII art and colors
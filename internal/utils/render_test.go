@@ -0,0 +1,90 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files")
+
+// assertGolden compares rendered output, stripped of ANSI styling, with testdata/<name>.golden
+func assertGolden(t *testing.T, name, got string) {
+	t.Helper()
+	got = ansi.Strip(got)
+	path := filepath.Join("testdata", name+".golden")
+
+	if *updateGolden {
+		if err := os.MkdirAll("testdata", 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("%s mismatch\n--- got ---\n%s\n--- want ---\n%s", name, got, want)
+	}
+}
+
+// sampleAppData returns groups with a long app list and a long app name
+func sampleAppData() (map[string][]string, []string, []string, []string) {
+	groups := map[string][]string{
+		"dev":        {"git", "zsh", "iterm2", "visual-studio-code", "docker", "kubectl", "terraform", "postman", "tableplus", "github-desktop"},
+		"essentials": {"slack", "firefox", "1password", "raycast"},
+		"design":     {"figma", "sketch"},
+	}
+	return groups, []string{"dev", "essentials"}, []string{"design"}, []string{"obsidian", "a-really-long-application-name-that-does-not-fit"}
+}
+
+func TestRenderViewsWidths(t *testing.T) {
+	groups, builtIn, custom, installed := sampleAppData()
+
+	for _, width := range []struct {
+		suffix string
+		width  int
+	}{
+		{"wide", 96},
+		{"narrow", 40},
+	} {
+		t.Run(width.suffix, func(t *testing.T) {
+			list := renderListView(groups, builtIn, custom, installed, width.width)
+			tree := renderTreeView(groups, builtIn, custom, installed, width.width)
+			assertGolden(t, "list_"+width.suffix, list)
+			assertGolden(t, "tree_"+width.suffix, tree)
+
+			for _, out := range []string{list, tree} {
+				for _, line := range strings.Split(out, "\n") {
+					if w := ansi.StringWidth(line); w > width.width {
+						t.Errorf("line is %d cells wide, limit is %d: %q", w, width.width, ansi.Strip(line))
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseSelection turns "1,3-5" into zero-based indexes below count, in order and without repeats
+func ParseSelection(input string, count int) ([]int, error) {
+	var indexes []int
+	seen := make(map[int]bool)
+	for _, part := range strings.FieldsFunc(input, func(r rune) bool { return r == ',' || r == ' ' }) {
+		first, last := part, part
+		if before, after, isRange := strings.Cut(part, "-"); isRange {
+			first, last = before, after
+		}
+		start, startErr := strconv.Atoi(first)
+		end, endErr := strconv.Atoi(last)
+		if startErr != nil || endErr != nil || start > end {
+			return nil, fmt.Errorf("'%s' is not a number or range", part)
+		}
+		if start < 1 || end > count {
+			return nil, fmt.Errorf("'%s' is outside 1-%d", part, count)
+		}
+		for i := start - 1; i < end; i++ {
+			if !seen[i] {
+				seen[i] = true
+				indexes = append(indexes, i)
+			}
+		}
+	}
+	if len(indexes) == 0 {
+		return nil, fmt.Errorf("nothing picked")
+	}
+	return indexes, nil
+}
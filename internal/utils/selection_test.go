@@ -0,0 +1,47 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSelection(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    []int
+		wantErr bool
+	}{
+		{"1", []int{0}, false},
+		{"1,3-5", []int{0, 2, 3, 4}, false},
+		{"2 1 2", []int{1, 0}, false},
+		{"4-4, 6", []int{3, 5}, false},
+		{"0", nil, true},
+		{"7", nil, true},
+		{"5-3", nil, true},
+		{"a", nil, true},
+		{",", nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSelection(tt.input, 6)
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseSelection(%q) = %v, %v, want %v (error %v)", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// StagingPrefix marks per-operation staging directories. Readers of a base directory
+// already skip dot entries, so staged content is never mistaken for a finished copy.
+const StagingPrefix = ".staging-"
+
+// promoteAttempts bounds retries when another process promotes the same destination
+const promoteAttempts = 5
+
+// NewStagingDir creates a uniquely named staging directory inside baseDir for one operation.
+// Staging next to the destination keeps the final rename on the same filesystem.
+func NewStagingDir(baseDir, name string) (string, error) {
+	if err := EnsureDirectory(baseDir); err != nil {
+		return "", err
+	}
+	name = strings.ReplaceAll(name, string(filepath.Separator), "_")
+	return os.MkdirTemp(baseDir, StagingPrefix+name+"-*")
+}
+
+// PromoteDirectory moves a finished staged directory to destDir with a rename, so readers
+// see either the old or the new copy and never a mix. The old copy is moved to previousDir
+// when given, otherwise removed. Concurrent promotions of the same destination retry
+// until one of them wins the rename, the last one to finish is kept.
+func PromoteDirectory(stagedDir, destDir, previousDir string) error {
+	if err := EnsureDirectory(filepath.Dir(destDir)); err != nil {
+		return err
+	}
+
+	var err error
+	for attempt := 0; attempt < promoteAttempts; attempt++ {
+		if err = setAside(destDir, previousDir); err != nil {
+			return err
+		}
+		if err = os.Rename(stagedDir, destDir); err == nil || !os.IsExist(err) {
+			// Anything but another copy landing at the destination first is final
+			return err
+		}
+	}
+	return fmt.Errorf("failed to promote %s after %d attempts: %w", destDir, promoteAttempts, err)
+}
+
+// setAside moves destDir out of the way, to previousDir or to a staging directory that is removed
+func setAside(destDir, previousDir string) error {
+	// A previous copy never outlives the copy it was replaced by
+	if previousDir != "" {
+		if err := os.RemoveAll(previousDir); err != nil {
+			return err
+		}
+	}
+	if _, err := os.Lstat(destDir); os.IsNotExist(err) {
+		return nil
+	}
+
+	target := previousDir
+	if target == "" {
+		trash, err := NewStagingDir(filepath.Dir(destDir), filepath.Base(destDir)+"-old")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(trash)
+		target = filepath.Join(trash, filepath.Base(destDir))
+	} else if err := EnsureDirectory(filepath.Dir(previousDir)); err != nil {
+		return err
+	}
+
+	// A concurrent promotion may have moved or replaced the copy first, the caller retries
+	if err := os.Rename(destDir, target); err != nil && !os.IsNotExist(err) && !os.IsExist(err) {
+		return err
+	}
+	return nil
+}
+
+// StaleStagingDirs lists staging directories in baseDir untouched for longer than maxAge,
+// left behind by interrupted operations. Younger ones may belong to a running operation.
+func StaleStagingDirs(baseDir string, maxAge time.Duration) ([]string, error) {
+	entries, err := os.ReadDir(baseDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var stale []string
+	cutoff := time.Now().Add(-maxAge)
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), StagingPrefix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		stale = append(stale, filepath.Join(baseDir, entry.Name()))
+	}
+	return stale, nil
+}
@@ -0,0 +1,136 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stageFiles creates a staging directory in baseDir holding the given files
+func stageFiles(t *testing.T, baseDir, name string, files map[string]string) string {
+	t.Helper()
+	stagingDir, err := NewStagingDir(baseDir, name)
+	if err != nil {
+		t.Fatalf("NewStagingDir() error = %v", err)
+	}
+	staged := filepath.Join(stagingDir, name)
+	for file, content := range files {
+		if err := os.MkdirAll(staged, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(staged, file), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return staged
+}
+
+func TestPromoteDirectory(t *testing.T) {
+	baseDir := t.TempDir()
+	destDir := filepath.Join(baseDir, "nvim")
+	previousDir := filepath.Join(baseDir, ".previous", "nvim")
+
+	first := stageFiles(t, baseDir, "nvim", map[string]string{"init.lua": "v1"})
+	if err := PromoteDirectory(first, destDir, previousDir); err != nil {
+		t.Fatalf("PromoteDirectory() error = %v", err)
+	}
+	if _, err := os.Stat(previousDir); !os.IsNotExist(err) {
+		t.Errorf("previous copy exists after the first promotion")
+	}
+
+	second := stageFiles(t, baseDir, "nvim", map[string]string{"init.lua": "v2"})
+	if err := PromoteDirectory(second, destDir, previousDir); err != nil {
+		t.Fatalf("PromoteDirectory() error = %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(destDir, "init.lua")); string(data) != "v2" {
+		t.Errorf("promoted copy = %q, want v2", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(previousDir, "init.lua")); string(data) != "v1" {
+		t.Errorf("previous copy = %q, want v1", data)
+	}
+}
+
+func TestPromoteDirectoryConcurrent(t *testing.T) {
+	baseDir := t.TempDir()
+	destDir := filepath.Join(baseDir, "zsh")
+	previousDir := filepath.Join(baseDir, ".previous", "zsh")
+
+	const promotions = 8
+	var staged []string
+	for i := 0; i < promotions; i++ {
+		staged = append(staged, stageFiles(t, baseDir, "zsh", map[string]string{
+			"a.zsh": fmt.Sprint(i),
+			"b.zsh": fmt.Sprint(i),
+		}))
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, promotions)
+	for i := range staged {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = PromoteDirectory(staged[i], destDir, previousDir)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("promotion %d error = %v", i, err)
+		}
+	}
+	// Whichever promotion won, its files must not be mixed with another one's
+	a, _ := os.ReadFile(filepath.Join(destDir, "a.zsh"))
+	b, _ := os.ReadFile(filepath.Join(destDir, "b.zsh"))
+	if len(a) == 0 || string(a) != string(b) {
+		t.Errorf("promoted copy mixes promotions: a=%q b=%q", a, b)
+	}
+}
+
+func TestStaleStagingDirs(t *testing.T) {
+	baseDir := t.TempDir()
+	fresh, _ := NewStagingDir(baseDir, "git")
+	old, _ := NewStagingDir(baseDir, "nvim")
+	if err := os.Mkdir(filepath.Join(baseDir, "tmux"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(old, past, past); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filepath.Join(baseDir, "tmux"), past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	stale, err := StaleStagingDirs(baseDir, time.Hour)
+	if err != nil {
+		t.Fatalf("StaleStagingDirs() error = %v", err)
+	}
+	if len(stale) != 1 || stale[0] != old {
+		t.Errorf("StaleStagingDirs() = %v, want [%s] and not %s", stale, old, fresh)
+	}
+
+	if stale, err := StaleStagingDirs(filepath.Join(baseDir, "missing"), time.Hour); err != nil || stale != nil {
+		t.Errorf("StaleStagingDirs(missing) = %v, %v, want nothing", stale, err)
+	}
+}
@@ -0,0 +1,77 @@
+//go:build darwin || linux
+
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"bytes"
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+// skippedXattrs are attributes that should never follow a copied config file
+var skippedXattrs = map[string]bool{
+	"com.apple.quarantine": true,
+}
+
+// copyXattrs copies extended attributes from src to dst, ignoring filesystems that don't support them
+func copyXattrs(src, dst string) error {
+	size, err := unix.Listxattr(src, nil)
+	if err != nil || size <= 0 {
+		return ignoreUnsupportedXattr(err)
+	}
+
+	buf := make([]byte, size)
+	size, err = unix.Listxattr(src, buf)
+	if err != nil {
+		return ignoreUnsupportedXattr(err)
+	}
+
+	for _, name := range bytes.Split(buf[:size], []byte{0}) {
+		attr := string(name)
+		if attr == "" || skippedXattrs[attr] {
+			continue
+		}
+
+		valueSize, err := unix.Getxattr(src, attr, nil)
+		if err != nil {
+			continue
+		}
+		value := make([]byte, valueSize)
+		if _, err := unix.Getxattr(src, attr, value); err != nil {
+			continue
+		}
+
+		if err := unix.Setxattr(dst, attr, value, 0); err != nil {
+			if err := ignoreUnsupportedXattr(err); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ignoreUnsupportedXattr treats missing xattr support or permission limits as non-fatal
+func ignoreUnsupportedXattr(err error) error {
+	if err == nil || errors.Is(err, unix.ENOTSUP) || errors.Is(err, unix.EPERM) || errors.Is(err, unix.EACCES) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,368 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validators
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/palantir"
+)
+
+// ConfigPathsValidator checks that every configs entry points at an existing path
+type ConfigPathsValidator struct{}
+
+func (v *ConfigPathsValidator) Name() string        { return "config-paths" }
+func (v *ConfigPathsValidator) Category() string    { return "apps" }
+func (v *ConfigPathsValidator) Description() string { return "Verify every configs entry path exists" }
+func (v *ConfigPathsValidator) CanFix() bool        { return false }
+func (v *ConfigPathsValidator) DependsOn() []string { return []string{"settings-file"} }
+
+func (v *ConfigPathsValidator) Validate(ctx context.Context, cfg *config.AnvilConfig) *ValidationResult {
+	if len(cfg.Configs) == 0 {
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   SKIP,
+			Message:  "No app configs registered",
+			FixHint:  "Run 'anvil config push <app>' to register an app",
+			AutoFix:  false,
+		}
+	}
+
+	var missing, details []string
+	for _, app := range sortedApps(cfg.Configs) {
+		path := cfg.Configs[app]
+		switch {
+		case strings.TrimSpace(path) == "":
+			missing = append(missing, app)
+			details = append(details, fmt.Sprintf("%s: empty path", app))
+		case strings.HasPrefix(path, "~"):
+			missing = append(missing, app)
+			details = append(details, fmt.Sprintf("%s: %s uses '~', which is not expanded", app, path))
+		default:
+			if _, err := os.Stat(path); err != nil && cfg.AppMode(app) == config.ModeRepoOnly {
+				// Repo-only apps are restored on demand, their destination may not exist yet
+				details = append(details, fmt.Sprintf("%s: %s does not exist yet, repo-only", app, path))
+			} else if err != nil {
+				missing = append(missing, app)
+				details = append(details, fmt.Sprintf("%s: %s does not exist", app, path))
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   FAIL,
+			Message:  fmt.Sprintf("%d of %d config paths are invalid: %s", len(missing), len(cfg.Configs), strings.Join(missing, ", ")),
+			Details:  details,
+			FixHint:  "Update or remove these entries under 'configs' in settings.yaml, using absolute paths",
+			AutoFix:  false,
+		}
+	}
+
+	return &ValidationResult{
+		Name:     v.Name(),
+		Category: v.Category(),
+		Status:   PASS,
+		Message:  fmt.Sprintf("All %d config paths exist", len(cfg.Configs)-len(details)),
+		Details:  details,
+		AutoFix:  false,
+	}
+}
+
+func (v *ConfigPathsValidator) Fix(ctx context.Context, cfg *config.AnvilConfig) error {
+	return fmt.Errorf("config paths must be fixed manually in settings.yaml")
+}
+
+// RemoteAppsValidator checks that every registered app has been pushed or is marked local-only
+type RemoteAppsValidator struct{}
+
+func (v *RemoteAppsValidator) Name() string     { return "remote-apps" }
+func (v *RemoteAppsValidator) Category() string { return "apps" }
+func (v *RemoteAppsValidator) Description() string {
+	return "Verify registered apps exist in the config repository or are marked local-only"
+}
+func (v *RemoteAppsValidator) CanFix() bool        { return false }
+func (v *RemoteAppsValidator) DependsOn() []string { return []string{"clone-health"} }
+
+func (v *RemoteAppsValidator) Validate(ctx context.Context, cfg *config.AnvilConfig) *ValidationResult {
+	localPath := cfg.GitHub.LocalPath
+	if len(cfg.Configs) == 0 || cfg.GitHub.ConfigRepo == "" || localPath == "" {
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   SKIP,
+			Message:  "No app configs or GitHub repository configured",
+			FixHint:  "Configure 'github.config_repo' and register apps under 'configs'",
+			AutoFix:  false,
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(localPath, ".git")); err != nil {
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   SKIP,
+			Message:  "Local clone not created yet",
+			Details:  []string{"Path: " + localPath},
+			FixHint:  "Run 'anvil config pull <app>' to create the local clone",
+			AutoFix:  false,
+		}
+	}
+
+	var notPushed, details []string
+	for _, app := range sortedApps(cfg.Configs) {
+		if slices.Contains(cfg.LocalOnly, app) {
+			details = append(details, fmt.Sprintf("%s: local-only", app))
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(localPath, app)); err != nil {
+			notPushed = append(notPushed, app)
+			details = append(details, fmt.Sprintf("%s: missing from %s", app, cfg.GitHub.ConfigRepo))
+		}
+	}
+
+	if len(notPushed) > 0 {
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   WARN,
+			Message:  fmt.Sprintf("%d apps not found in the config repository: %s", len(notPushed), strings.Join(notPushed, ", ")),
+			Details:  details,
+			FixHint:  "Run 'anvil config push <app>' for each app, or list it under 'local_only' in settings.yaml",
+			AutoFix:  false,
+		}
+	}
+
+	return &ValidationResult{
+		Name:     v.Name(),
+		Category: v.Category(),
+		Status:   PASS,
+		Message:  "All registered apps are in the config repository or local-only",
+		Details:  details,
+		AutoFix:  false,
+	}
+}
+
+func (v *RemoteAppsValidator) Fix(ctx context.Context, cfg *config.AnvilConfig) error {
+	return fmt.Errorf("push the missing apps or mark them local-only in settings.yaml")
+}
+
+// AppStateValidator checks that pulled configs, local-only markers and tracked apps agree with the configs mappings
+type AppStateValidator struct{}
+
+func (v *AppStateValidator) Name() string     { return "app-state" }
+func (v *AppStateValidator) Category() string { return "apps" }
+func (v *AppStateValidator) Description() string {
+	return "Verify pulled configs and tracked apps are consistent with settings"
+}
+func (v *AppStateValidator) CanFix() bool        { return true }
+func (v *AppStateValidator) DependsOn() []string { return []string{"settings-file"} }
+
+// appStateIssues holds the inconsistencies found between settings and local state
+type appStateIssues struct {
+	unmappedPulls  []string             // Pulled into temp/ but no configs entry, so sync has no destination
+	staleLocalOnly []string             // Marked local-only but not registered under configs
+	conflictModes  []string             // Marked both local-only and repo-only
+	appConflicts   []config.AppConflict // Duplicated, redundant or inconsistently cased app names
+}
+
+// inspectAppState compares settings with the pulled configs on disk
+func inspectAppState(cfg *config.AnvilConfig) *appStateIssues {
+	issues := &appStateIssues{}
+
+	tempDir := filepath.Join(config.GetAnvilConfigDirectory(), "temp")
+	if entries, err := os.ReadDir(tempDir); err == nil {
+		for _, entry := range entries {
+			name := entry.Name()
+			if !entry.IsDir() || name == "anvil" || strings.HasPrefix(name, ".") {
+				continue
+			}
+			if _, ok := cfg.Configs[name]; !ok {
+				issues.unmappedPulls = append(issues.unmappedPulls, name)
+			}
+		}
+	}
+
+	for _, app := range cfg.LocalOnly {
+		if _, ok := cfg.Configs[app]; !ok {
+			issues.staleLocalOnly = append(issues.staleLocalOnly, app)
+		}
+	}
+
+	issues.conflictModes = cfg.AppsInBothModes()
+	issues.appConflicts = config.FindAppConflicts(cfg)
+
+	return issues
+}
+
+func (v *AppStateValidator) Validate(ctx context.Context, cfg *config.AnvilConfig) *ValidationResult {
+	issues := inspectAppState(cfg)
+
+	var details []string
+	for _, app := range issues.unmappedPulls {
+		details = append(details, fmt.Sprintf("%s: pulled but has no 'configs' entry, sync has no destination", app))
+	}
+	for _, app := range issues.staleLocalOnly {
+		details = append(details, fmt.Sprintf("%s: marked local-only but not registered under 'configs'", app))
+	}
+	for _, app := range issues.conflictModes {
+		details = append(details, fmt.Sprintf("%s: listed under both 'local_only' and 'repo_only', local_only wins", app))
+	}
+	for _, conflict := range issues.appConflicts {
+		details = append(details, fmt.Sprintf("%s: %s", conflict.Name, strings.Join(conflict.Issues(), "; ")))
+	}
+
+	if len(details) > 0 {
+		fixHint := "Add a 'configs' entry for pulled apps and remove stale or conflicting 'local_only' and 'repo_only' entries in settings.yaml"
+		if len(issues.appConflicts) > 0 {
+			fixHint = "Run 'anvil config conflicts --fix' to clean up duplicate app names; " + fixHint
+		}
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   WARN,
+			Message:  fmt.Sprintf("Found %d app state inconsistencies", len(details)),
+			Details:  details,
+			FixHint:  fixHint,
+			AutoFix:  len(issues.appConflicts) > 0,
+		}
+	}
+
+	return &ValidationResult{
+		Name:     v.Name(),
+		Category: v.Category(),
+		Status:   PASS,
+		Message:  "App state is consistent with settings",
+		AutoFix:  false,
+	}
+}
+
+func (v *AppStateValidator) Fix(ctx context.Context, cfg *config.AnvilConfig) error {
+	currentConfig, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load current configuration: %w", err)
+	}
+
+	changes := config.CleanupAppConflicts(currentConfig)
+	if changes == 0 {
+		return fmt.Errorf("remaining app state issues must be fixed manually in settings.yaml")
+	}
+
+	if err := config.SaveConfig(currentConfig); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+
+	palantir.GetGlobalOutputHandler().PrintInfo("Cleaned up %d duplicate or conflicting app entries", changes)
+	return nil
+}
+
+// ConfigOverlapValidator checks that no two apps map to the same or nested paths
+type ConfigOverlapValidator struct{}
+
+func (v *ConfigOverlapValidator) Name() string     { return "config-overlap" }
+func (v *ConfigOverlapValidator) Category() string { return "apps" }
+func (v *ConfigOverlapValidator) Description() string {
+	return "Verify no two apps map to overlapping config paths"
+}
+func (v *ConfigOverlapValidator) CanFix() bool        { return false }
+func (v *ConfigOverlapValidator) DependsOn() []string { return []string{"settings-file"} }
+
+// findOverlappingConfigs returns a description of each pair of apps sharing or nesting paths
+func findOverlappingConfigs(configs map[string]string) []string {
+	apps := sortedApps(configs)
+	var overlaps []string
+	for i, a := range apps {
+		pathA := filepath.Clean(configs[a])
+		for _, b := range apps[i+1:] {
+			pathB := filepath.Clean(configs[b])
+			switch {
+			case configs[a] == "" || configs[b] == "":
+				continue
+			case pathA == pathB:
+				overlaps = append(overlaps, fmt.Sprintf("%s and %s both map to %s", a, b, pathA))
+			case isNestedPath(pathA, pathB):
+				overlaps = append(overlaps, fmt.Sprintf("%s (%s) is inside %s (%s)", b, pathB, a, pathA))
+			case isNestedPath(pathB, pathA):
+				overlaps = append(overlaps, fmt.Sprintf("%s (%s) is inside %s (%s)", a, pathA, b, pathB))
+			}
+		}
+	}
+	return overlaps
+}
+
+// isNestedPath reports whether child is located inside parent
+func isNestedPath(parent, child string) bool {
+	rel, err := filepath.Rel(parent, child)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func (v *ConfigOverlapValidator) Validate(ctx context.Context, cfg *config.AnvilConfig) *ValidationResult {
+	if len(cfg.Configs) < 2 {
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   SKIP,
+			Message:  "Fewer than two app configs registered",
+			AutoFix:  false,
+		}
+	}
+
+	overlaps := findOverlappingConfigs(cfg.Configs)
+	if len(overlaps) > 0 {
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   FAIL,
+			Message:  fmt.Sprintf("Found %d overlapping config mappings", len(overlaps)),
+			Details:  overlaps,
+			FixHint:  "Point each app at a distinct path in settings.yaml; overlapping paths are pushed twice and sync overwrites one with the other",
+			AutoFix:  false,
+		}
+	}
+
+	return &ValidationResult{
+		Name:     v.Name(),
+		Category: v.Category(),
+		Status:   PASS,
+		Message:  "No overlapping config mappings",
+		AutoFix:  false,
+	}
+}
+
+func (v *ConfigOverlapValidator) Fix(ctx context.Context, cfg *config.AnvilConfig) error {
+	return fmt.Errorf("overlapping config paths must be fixed manually in settings.yaml")
+}
+
+// sortedApps returns the app names of a configs map in a stable order
+func sortedApps(configs map[string]string) []string {
+	apps := make([]string, 0, len(configs))
+	for app := range configs {
+		apps = append(apps, app)
+	}
+	sort.Strings(apps)
+	return apps
+}
@@ -0,0 +1,233 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validators
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/0xjuanma/anvil/internal/config"
+)
+
+// checkResult compares a validation result with the expected status and message fragment
+func checkResult(t *testing.T, result *ValidationResult, status ValidationStatus, message string) {
+	t.Helper()
+	if result.Status != status {
+		t.Errorf("status = %s, want %s (%s)", result.Status, status, result.Message)
+	}
+	if !strings.Contains(result.Message, message) {
+		t.Errorf("message = %q, want it to contain %q", result.Message, message)
+	}
+}
+
+func TestConfigPathsValidator(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "nvim")
+	os.MkdirAll(existing, 0755)
+	missing := filepath.Join(dir, "missing")
+
+	for _, tc := range []struct {
+		name     string
+		configs  map[string]string
+		repoOnly []string
+		status   ValidationStatus
+		message  string
+	}{
+		{name: "no configs", status: SKIP, message: "No app configs registered"},
+		{name: "all paths exist", configs: map[string]string{"nvim": existing}, status: PASS, message: "All 1 config paths exist"},
+		{name: "empty path", configs: map[string]string{"nvim": existing, "zsh": " "}, status: FAIL, message: "1 of 2 config paths are invalid: zsh"},
+		{name: "unexpanded home", configs: map[string]string{"zsh": "~/.zshrc"}, status: FAIL, message: "invalid: zsh"},
+		{name: "missing path", configs: map[string]string{"nvim": existing, "git": missing}, status: FAIL, message: "invalid: git"},
+		{name: "missing repo-only path", configs: map[string]string{"nvim": existing, "git": missing}, repoOnly: []string{"git"}, status: PASS, message: "All 1 config paths exist"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &config.AnvilConfig{Configs: tc.configs, RepoOnly: tc.repoOnly}
+			checkResult(t, (&ConfigPathsValidator{}).Validate(context.Background(), cfg), tc.status, tc.message)
+		})
+	}
+
+	if err := (&ConfigPathsValidator{}).Fix(context.Background(), &config.AnvilConfig{}); err == nil {
+		t.Error("Fix() should ask for a manual fix")
+	}
+}
+
+func TestRemoteAppsValidator(t *testing.T) {
+	clone := t.TempDir()
+	os.MkdirAll(filepath.Join(clone, ".git"), 0755)
+	os.MkdirAll(filepath.Join(clone, "nvim"), 0755)
+
+	for _, tc := range []struct {
+		name      string
+		repo      string
+		localPath string
+		localOnly []string
+		status    ValidationStatus
+		message   string
+	}{
+		{name: "no repository", localPath: clone, status: SKIP, message: "No app configs or GitHub repository"},
+		{name: "no clone yet", repo: "me/dotfiles", localPath: filepath.Join(clone, "missing"), status: SKIP, message: "Local clone not created yet"},
+		{name: "app not pushed", repo: "me/dotfiles", localPath: clone, status: WARN, message: "1 apps not found in the config repository: zsh"},
+		{name: "unpushed app is local-only", repo: "me/dotfiles", localPath: clone, localOnly: []string{"zsh"}, status: PASS, message: "All registered apps"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &config.AnvilConfig{
+				Configs:   map[string]string{"nvim": "/home/me/.config/nvim", "zsh": "/home/me/.zshrc"},
+				LocalOnly: tc.localOnly,
+			}
+			cfg.GitHub.ConfigRepo = tc.repo
+			cfg.GitHub.LocalPath = tc.localPath
+			checkResult(t, (&RemoteAppsValidator{}).Validate(context.Background(), cfg), tc.status, tc.message)
+		})
+	}
+
+	if err := (&RemoteAppsValidator{}).Fix(context.Background(), &config.AnvilConfig{}); err == nil {
+		t.Error("Fix() should ask for a manual fix")
+	}
+}
+
+// setupAppStateEnv points the anvil directory at a temp HOME with the given pulled apps
+func setupAppStateEnv(t *testing.T, pulled ...string) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, app := range append(pulled, "anvil") {
+		os.MkdirAll(filepath.Join(home, ".anvil", "temp", app), 0755)
+	}
+	config.InvalidateConfigCache()
+	t.Cleanup(config.InvalidateConfigCache)
+	return filepath.Join(home, ".anvil")
+}
+
+func TestAppStateValidator(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		pulled    []string
+		cfg       config.AnvilConfig
+		status    ValidationStatus
+		message   string
+		wantFixes bool
+	}{
+		{
+			name:    "consistent",
+			pulled:  []string{"nvim"},
+			cfg:     config.AnvilConfig{Configs: map[string]string{"nvim": "/home/me/.config/nvim"}},
+			status:  PASS,
+			message: "App state is consistent",
+		},
+		{
+			name:    "pulled app without configs entry",
+			pulled:  []string{"nvim", "zsh"},
+			cfg:     config.AnvilConfig{Configs: map[string]string{"nvim": "/home/me/.config/nvim"}},
+			status:  WARN,
+			message: "Found 1 app state inconsistencies",
+		},
+		{
+			name:    "stale local-only entry",
+			cfg:     config.AnvilConfig{Configs: map[string]string{"nvim": "/home/me/.config/nvim"}, LocalOnly: []string{"zsh"}},
+			status:  WARN,
+			message: "Found 1 app state inconsistencies",
+		},
+		{
+			name:    "local-only and repo-only",
+			cfg:     config.AnvilConfig{Configs: map[string]string{"nvim": "/home/me/.config/nvim"}, LocalOnly: []string{"nvim"}, RepoOnly: []string{"nvim"}},
+			status:  WARN,
+			message: "Found 1 app state inconsistencies",
+		},
+		{
+			name: "duplicate app names",
+			cfg: config.AnvilConfig{Tools: config.AnvilTools{
+				RequiredTools: []string{"git", "Git"},
+				InstalledApps: []string{"git"},
+			}},
+			status:    WARN,
+			message:   "Found 1 app state inconsistencies",
+			wantFixes: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			setupAppStateEnv(t, tc.pulled...)
+			result := (&AppStateValidator{}).Validate(context.Background(), &tc.cfg)
+			checkResult(t, result, tc.status, tc.message)
+			if result.AutoFix != tc.wantFixes {
+				t.Errorf("AutoFix = %t, want %t", result.AutoFix, tc.wantFixes)
+			}
+		})
+	}
+}
+
+func TestAppStateValidatorFix(t *testing.T) {
+	t.Run("cleans up duplicate app names", func(t *testing.T) {
+		anvilDir := setupAppStateEnv(t)
+		settings := "version: \"1\"\ntools:\n  required_tools: [git, Git]\n  installed_apps: [git, slack]\n"
+		os.WriteFile(filepath.Join(anvilDir, "settings.yaml"), []byte(settings), 0644)
+
+		if err := (&AppStateValidator{}).Fix(context.Background(), nil); err != nil {
+			t.Fatalf("Fix() failed: %v", err)
+		}
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(cfg.Tools.RequiredTools, []string{"git"}) || !reflect.DeepEqual(cfg.Tools.InstalledApps, []string{"slack"}) {
+			t.Errorf("after Fix() required_tools = %v, installed_apps = %v", cfg.Tools.RequiredTools, cfg.Tools.InstalledApps)
+		}
+	})
+
+	t.Run("other issues need a manual fix", func(t *testing.T) {
+		anvilDir := setupAppStateEnv(t)
+		settings := "version: \"1\"\ntools:\n  required_tools: [git]\nlocal_only: [zsh]\n"
+		os.WriteFile(filepath.Join(anvilDir, "settings.yaml"), []byte(settings), 0644)
+
+		err := (&AppStateValidator{}).Fix(context.Background(), nil)
+		if err == nil || !strings.Contains(err.Error(), "fixed manually") {
+			t.Errorf("Fix() error = %v, want a manual fix", err)
+		}
+	})
+}
+
+func TestConfigOverlapValidator(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		configs map[string]string
+		status  ValidationStatus
+		message string
+	}{
+		{name: "single config", configs: map[string]string{"nvim": "/home/me/.config/nvim"}, status: SKIP, message: "Fewer than two"},
+		{name: "distinct paths", configs: map[string]string{"nvim": "/home/me/.config/nvim", "nvim-old": "/home/me/.config/nvim-old"}, status: PASS, message: "No overlapping"},
+		{name: "empty path is ignored", configs: map[string]string{"nvim": "/home/me/.config/nvim", "zsh": ""}, status: PASS, message: "No overlapping"},
+		{name: "same path", configs: map[string]string{"nvim": "/home/me/.config/nvim", "vim": "/home/me/.config/nvim/"}, status: FAIL, message: "Found 1 overlapping"},
+		{name: "nested path", configs: map[string]string{"config": "/home/me/.config", "nvim": "/home/me/.config/nvim", "zsh": "/home/me/.zshrc"}, status: FAIL, message: "Found 1 overlapping"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &config.AnvilConfig{Configs: tc.configs}
+			checkResult(t, (&ConfigOverlapValidator{}).Validate(context.Background(), cfg), tc.status, tc.message)
+		})
+	}
+
+	overlaps := findOverlappingConfigs(map[string]string{"config": "/home/me/.config", "nvim": "/home/me/.config/nvim"})
+	if want := []string{"nvim (/home/me/.config/nvim) is inside config (/home/me/.config)"}; !reflect.DeepEqual(overlaps, want) {
+		t.Errorf("findOverlappingConfigs() = %v, want %v", overlaps, want)
+	}
+
+	if err := (&ConfigOverlapValidator{}).Fix(context.Background(), &config.AnvilConfig{}); err == nil {
+		t.Error("Fix() should ask for a manual fix")
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validators
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/config"
+)
+
+// NetworkCategory holds the checks that reach GitHub, which 'doctor summary' never runs
+const NetworkCategory = "connectivity"
+
+// resultsCacheFile is the last doctor results under the cache directory
+const resultsCacheFile = "results.json"
+
+// CachedResults is the last doctor run, kept for 'anvil doctor summary'
+type CachedResults struct {
+	Time    time.Time           `json:"time"`
+	Results []*ValidationResult `json:"results"`
+}
+
+// ResultsCacheDir returns the directory doctor results are cached in
+func ResultsCacheDir() string {
+	return filepath.Join(config.GetAnvilConfigDirectory(), "cache", "doctor")
+}
+
+// LoadCachedResults returns the last cached doctor results, false when there are none
+func LoadCachedResults(dir string) (*CachedResults, bool) {
+	data, err := os.ReadFile(filepath.Join(dir, resultsCacheFile))
+	if err != nil {
+		return nil, false
+	}
+	var cached CachedResults
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, false
+	}
+	return &cached, true
+}
+
+// SaveCachedResults stores the results of a doctor run, failures only cost the next summary
+// a run of the local checks
+func SaveCachedResults(dir string, results []*ValidationResult) {
+	data, err := json.MarshalIndent(CachedResults{Time: time.Now(), Results: results}, "", "  ")
+	if err != nil || os.MkdirAll(dir, 0755) != nil {
+		return
+	}
+	_ = os.WriteFile(filepath.Join(dir, resultsCacheFile), data, 0644)
+}
+
+// RunOffline executes every validator that doesn't need the network
+func (d *DoctorEngine) RunOffline(ctx context.Context) []*ValidationResult {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return []*ValidationResult{{
+			Name:     "config-load",
+			Category: "environment",
+			Status:   FAIL,
+			Message:  "Failed to load configuration",
+			Details:  []string{err.Error()},
+			FixHint:  "Run 'anvil init' to initialize your environment",
+			AutoFix:  false,
+		}}
+	}
+
+	var offline []Validator
+	for _, validator := range d.registry.GetAllValidators() {
+		if validator.Category() != NetworkCategory {
+			offline = append(offline, validator)
+		}
+	}
+	return d.runValidators(ctx, cfg, offline)
+}
+
+// WithNetworkResults adds the network check results of a previous run to offline results
+func WithNetworkResults(offline, previous []*ValidationResult) []*ValidationResult {
+	results := append([]*ValidationResult{}, offline...)
+	for _, result := range previous {
+		if result.Category == NetworkCategory {
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// SummaryLine condenses results into one short line for shell prompts, e.g. "anvil ✓" or
+// "anvil ✗ 2 issues". Warnings count as issues, failures decide the mark.
+func SummaryLine(results []*ValidationResult) string {
+	_, warned, failed, _ := GetSummary(results)
+	issues := warned + failed
+
+	switch {
+	case issues == 0:
+		return "anvil ✓"
+	case failed > 0:
+		return fmt.Sprintf("anvil ✗ %s", pluralIssues(issues))
+	default:
+		return fmt.Sprintf("anvil ⚠ %s", pluralIssues(issues))
+	}
+}
+
+// pluralIssues returns "1 issue" or "n issues"
+func pluralIssues(count int) string {
+	if count == 1 {
+		return "1 issue"
+	}
+	return fmt.Sprintf("%d issues", count)
+}
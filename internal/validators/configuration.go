@@ -19,10 +19,18 @@ package validators
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 
 	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/github"
+	"github.com/0xjuanma/anvil/internal/gitsettings"
+	"github.com/0xjuanma/anvil/internal/hosts"
+	"github.com/0xjuanma/anvil/internal/system"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
 )
 
 // GitConfigValidator checks if git configuration is properly set
@@ -32,6 +40,7 @@ func (v *GitConfigValidator) Name() string        { return "git-config" }
 func (v *GitConfigValidator) Category() string    { return "configuration" }
 func (v *GitConfigValidator) Description() string { return "Verify git configuration is properly set" }
 func (v *GitConfigValidator) CanFix() bool        { return true }
+func (v *GitConfigValidator) DependsOn() []string { return []string{"required-tools"} }
 
 func (v *GitConfigValidator) Validate(ctx context.Context, cfg *config.AnvilConfig) *ValidationResult {
 	var issues []string
@@ -159,7 +168,8 @@ func (v *GitHubConfigValidator) Category() string { return "configuration" }
 func (v *GitHubConfigValidator) Description() string {
 	return "Verify GitHub configuration is properly set"
 }
-func (v *GitHubConfigValidator) CanFix() bool { return false }
+func (v *GitHubConfigValidator) CanFix() bool        { return false }
+func (v *GitHubConfigValidator) DependsOn() []string { return []string{"settings-file"} }
 
 func (v *GitHubConfigValidator) Validate(ctx context.Context, cfg *config.AnvilConfig) *ValidationResult {
 	var issues []string
@@ -226,15 +236,49 @@ func (v *SyncConfigValidator) Name() string        { return "sync-config" }
 func (v *SyncConfigValidator) Category() string    { return "configuration" }
 func (v *SyncConfigValidator) Description() string { return "Verify sync configuration is valid" }
 func (v *SyncConfigValidator) CanFix() bool        { return false }
+func (v *SyncConfigValidator) DependsOn() []string { return []string{"settings-file"} }
 
 func (v *SyncConfigValidator) Validate(ctx context.Context, cfg *config.AnvilConfig) *ValidationResult {
-	// For now, skip sync config validation until the field is properly added
+	rules := cfg.Sync.Rules
+	if len(rules) == 0 {
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   SKIP,
+			Message:  "No sync rules configured",
+			FixHint:  "Add a 'sync.rules' section to settings.yaml for selective synchronization",
+			AutoFix:  false,
+		}
+	}
+
+	machineID := config.CurrentMachineID()
+	details := []string{"Machine: " + machineID}
+	for i, rule := range rules {
+		status := "inactive"
+		if rule.MatchesHost(machineID) {
+			status = "active"
+		}
+		details = append(details, fmt.Sprintf("Rule %d (%s): exclude %s", i+1, status, strings.Join(rule.Exclude, ", ")))
+	}
+
+	if problems := config.ValidateSyncRules(rules); len(problems) > 0 {
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   FAIL,
+			Message:  "Invalid sync rules: " + strings.Join(problems, "; "),
+			Details:  details,
+			FixHint:  "Fix the 'sync.rules' section in settings.yaml",
+			AutoFix:  false,
+		}
+	}
+
 	return &ValidationResult{
 		Name:     v.Name(),
 		Category: v.Category(),
-		Status:   SKIP,
-		Message:  "Sync configuration validation not yet implemented",
-		FixHint:  "Add _sync_config section to settings.yaml for selective synchronization",
+		Status:   PASS,
+		Message:  fmt.Sprintf("%d sync rules configured", len(rules)),
+		Details:  details,
 		AutoFix:  false,
 	}
 }
@@ -242,3 +286,442 @@ func (v *SyncConfigValidator) Validate(ctx context.Context, cfg *config.AnvilCon
 func (v *SyncConfigValidator) Fix(ctx context.Context, cfg *config.AnvilConfig) error {
 	return fmt.Errorf("sync configuration issues must be fixed manually in settings.yaml")
 }
+
+// ProtectedSettingsValidator checks that local settings have not been edited to override protected team settings
+type ProtectedSettingsValidator struct{}
+
+func (v *ProtectedSettingsValidator) Name() string     { return "protected-settings" }
+func (v *ProtectedSettingsValidator) Category() string { return "configuration" }
+func (v *ProtectedSettingsValidator) Description() string {
+	return "Verify local settings do not override protected team settings"
+}
+func (v *ProtectedSettingsValidator) CanFix() bool        { return true }
+func (v *ProtectedSettingsValidator) DependsOn() []string { return []string{"settings-file"} }
+
+func (v *ProtectedSettingsValidator) Validate(ctx context.Context, cfg *config.AnvilConfig) *ValidationResult {
+	layer, err := config.LoadTeamLayer()
+	if err != nil {
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   FAIL,
+			Message:  "Team settings could not be loaded",
+			Details:  []string{err.Error()},
+			FixHint:  fmt.Sprintf("Ask your team for a valid %s", constants.TEAM_CONFIG_FILE),
+			AutoFix:  false,
+		}
+	}
+	if layer == nil || len(layer.Protected) == 0 {
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   SKIP,
+			Message:  "No protected team settings installed",
+			FixHint:  fmt.Sprintf("Place your team's %s in %s to enforce protected settings", constants.TEAM_CONFIG_FILE, config.GetAnvilConfigDirectory()),
+			AutoFix:  false,
+		}
+	}
+
+	if err := config.ValidateProtectedSettings(); err != nil {
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   FAIL,
+			Message:  "Local settings override protected team settings",
+			Details:  []string{err.Error(), "Protected values are enforced at runtime regardless of local edits"},
+			FixHint:  "Run 'anvil doctor protected-settings --fix' to restore the protected values",
+			AutoFix:  true,
+		}
+	}
+
+	return &ValidationResult{
+		Name:     v.Name(),
+		Category: v.Category(),
+		Status:   PASS,
+		Message:  "Protected team settings are intact",
+		Details:  []string{"Team settings: " + config.GetTeamSettingsPath()},
+		AutoFix:  false,
+	}
+}
+
+func (v *ProtectedSettingsValidator) Fix(ctx context.Context, cfg *config.AnvilConfig) error {
+	return config.RestoreProtectedSettings()
+}
+
+// HostsBlockValidator checks that the managed /etc/hosts block matches the hosts entries in settings
+type HostsBlockValidator struct{}
+
+func (v *HostsBlockValidator) Name() string     { return "hosts-block" }
+func (v *HostsBlockValidator) Category() string { return "configuration" }
+func (v *HostsBlockValidator) Description() string {
+	return "Verify the anvil block in /etc/hosts matches the hosts entries in settings"
+}
+func (v *HostsBlockValidator) CanFix() bool        { return true }
+func (v *HostsBlockValidator) DependsOn() []string { return []string{"settings-file"} }
+
+func (v *HostsBlockValidator) Validate(ctx context.Context, cfg *config.AnvilConfig) *ValidationResult {
+	current, next, err := hosts.Plan(cfg.Hosts)
+	if err != nil {
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   FAIL,
+			Message:  "Hosts entries cannot be applied",
+			Details:  []string{err.Error()},
+			FixHint:  fmt.Sprintf("Fix the hosts section in %s", constants.ANVIL_CONFIG_FILE),
+			AutoFix:  false,
+		}
+	}
+
+	if current == next {
+		if len(cfg.Hosts) == 0 {
+			return &ValidationResult{
+				Name:     v.Name(),
+				Category: v.Category(),
+				Status:   SKIP,
+				Message:  "No hosts entries configured",
+				FixHint:  fmt.Sprintf("Add a hosts section to %s to manage /etc/hosts entries", constants.ANVIL_CONFIG_FILE),
+				AutoFix:  false,
+			}
+		}
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   PASS,
+			Message:  fmt.Sprintf("%d hosts entries are applied", len(cfg.Hosts)),
+			Details:  []string{"Hosts file: " + hosts.Path()},
+			AutoFix:  false,
+		}
+	}
+
+	message := "Hosts block is out of date with settings"
+	if len(cfg.Hosts) == 0 {
+		message = "Hosts block remains after its entries were removed from settings"
+	} else if hosts.Block(current) == "" {
+		message = "Hosts entries are not applied"
+	}
+	return &ValidationResult{
+		Name:     v.Name(),
+		Category: v.Category(),
+		Status:   WARN,
+		Message:  message,
+		Details:  []string{"Hosts file: " + hosts.Path()},
+		FixHint:  "Run 'anvil hosts apply' or 'anvil doctor hosts-block --fix'",
+		AutoFix:  true,
+	}
+}
+
+func (v *HostsBlockValidator) Fix(ctx context.Context, cfg *config.AnvilConfig) error {
+	_, err := hosts.Apply(cfg.Hosts)
+	return err
+}
+
+// GitSettingsValidator checks that the global git config has the extra_config values and
+// global ignore file from settings
+type GitSettingsValidator struct{}
+
+func (v *GitSettingsValidator) Name() string     { return "git-settings" }
+func (v *GitSettingsValidator) Category() string { return "configuration" }
+func (v *GitSettingsValidator) Description() string {
+	return "Verify git extra_config values and the global ignore file are applied"
+}
+func (v *GitSettingsValidator) CanFix() bool        { return true }
+func (v *GitSettingsValidator) DependsOn() []string { return []string{"required-tools"} }
+
+func (v *GitSettingsValidator) Validate(ctx context.Context, cfg *config.AnvilConfig) *ValidationResult {
+	if len(cfg.Git.ExtraConfig) == 0 && cfg.Git.GlobalIgnore == "" {
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   SKIP,
+			Message:  "No extra git settings configured",
+			FixHint:  fmt.Sprintf("Add git.extra_config or git.global_ignore to %s to manage aliases and other git settings", constants.ANVIL_CONFIG_FILE),
+			AutoFix:  false,
+		}
+	}
+
+	settings, err := gitsettings.Plan(cfg.Git)
+	if err != nil {
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   FAIL,
+			Message:  "Git settings cannot be applied",
+			Details:  []string{err.Error()},
+			FixHint:  fmt.Sprintf("Fix the git section in %s", constants.ANVIL_CONFIG_FILE),
+			AutoFix:  false,
+		}
+	}
+
+	var details []string
+	drifted := gitsettings.Drifted(settings)
+	for _, setting := range drifted {
+		current := setting.Current
+		if current == "" {
+			current = "(unset)"
+		}
+		details = append(details, fmt.Sprintf("%s: %s → %s", setting.Key, current, setting.Want))
+	}
+	if gitsettings.IgnoreFileMissing(cfg.Git) {
+		details = append(details, fmt.Sprintf("Global ignore file %s does not exist", cfg.Git.GlobalIgnore))
+	}
+
+	if len(details) > 0 {
+		message := fmt.Sprintf("%d of %d git settings are not applied", len(drifted), len(settings))
+		if len(drifted) == 0 {
+			message = "Global ignore file is missing"
+		}
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   WARN,
+			Message:  message,
+			Details:  details,
+			FixHint:  "Run 'anvil doctor git-settings --fix', sync the ignore file with 'anvil config sync' if it lives in your config repo",
+			AutoFix:  len(drifted) > 0,
+		}
+	}
+
+	return &ValidationResult{
+		Name:     v.Name(),
+		Category: v.Category(),
+		Status:   PASS,
+		Message:  fmt.Sprintf("%d git settings are applied", len(settings)),
+		AutoFix:  false,
+	}
+}
+
+func (v *GitSettingsValidator) Fix(ctx context.Context, cfg *config.AnvilConfig) error {
+	_, err := gitsettings.Apply(cfg.Git)
+	return err
+}
+
+// confirm asks before a fix discards data, overridden in tests
+var confirm = charm.Confirm
+
+// CloneHealthValidator checks if the local dotfiles clone is in a usable state
+type CloneHealthValidator struct{}
+
+func (v *CloneHealthValidator) Name() string     { return "clone-health" }
+func (v *CloneHealthValidator) Category() string { return "configuration" }
+func (v *CloneHealthValidator) Description() string {
+	return "Verify local dotfiles clone is not stuck in a broken git state"
+}
+func (v *CloneHealthValidator) CanFix() bool { return true }
+func (v *CloneHealthValidator) DependsOn() []string {
+	return []string{"required-tools", "github-config"}
+}
+
+// cloneIssues holds the problems detected in the local clone
+type cloneIssues struct {
+	notRepository    bool
+	indexLock        bool
+	mergeInProgress  bool
+	rebaseInProgress bool
+	conflicts        []string
+	detachedHead     bool
+	wrongBranch      string
+}
+
+// messages returns a human readable description of each detected issue
+func (c *cloneIssues) messages() []string {
+	var issues []string
+	if c.notRepository {
+		return []string{"local path is not a valid git repository"}
+	}
+	if c.indexLock {
+		issues = append(issues, "stale index.lock file left behind")
+	}
+	if c.mergeInProgress {
+		issues = append(issues, "merge in progress")
+	}
+	if c.rebaseInProgress {
+		issues = append(issues, "rebase in progress")
+	}
+	if len(c.conflicts) > 0 {
+		issues = append(issues, fmt.Sprintf("%d conflicted files", len(c.conflicts)))
+	}
+	if c.detachedHead {
+		issues = append(issues, "detached HEAD")
+	}
+	if c.wrongBranch != "" {
+		issues = append(issues, fmt.Sprintf("left on branch '%s'", c.wrongBranch))
+	}
+	return issues
+}
+
+// inspectClone detects broken states in the local clone
+func inspectClone(localPath, branch string) *cloneIssues {
+	issues := &cloneIssues{}
+	gitDir := filepath.Join(localPath, ".git")
+
+	if info, err := os.Stat(gitDir); err != nil || !info.IsDir() {
+		issues.notRepository = true
+		return issues
+	}
+
+	if _, err := os.Stat(filepath.Join(gitDir, "index.lock")); err == nil {
+		issues.indexLock = true
+	}
+	if _, err := os.Stat(filepath.Join(gitDir, "MERGE_HEAD")); err == nil {
+		issues.mergeInProgress = true
+	}
+	for _, dir := range []string{"rebase-merge", "rebase-apply"} {
+		if _, err := os.Stat(filepath.Join(gitDir, dir)); err == nil {
+			issues.rebaseInProgress = true
+		}
+	}
+
+	if result, err := system.RunCommandInDirectory(localPath, constants.GitCommand, "diff", "--name-only", "--diff-filter=U"); err == nil && result.Success {
+		for _, file := range strings.Split(strings.TrimSpace(result.Output), "\n") {
+			if file != "" {
+				issues.conflicts = append(issues.conflicts, file)
+			}
+		}
+	}
+
+	result, err := system.RunCommandInDirectory(localPath, constants.GitCommand, "symbolic-ref", "--short", "-q", "HEAD")
+	if err != nil || !result.Success {
+		issues.detachedHead = true
+	} else if current := strings.TrimSpace(result.Output); branch != "" && current != branch {
+		issues.wrongBranch = current
+	}
+
+	return issues
+}
+
+func (v *CloneHealthValidator) Validate(ctx context.Context, cfg *config.AnvilConfig) *ValidationResult {
+	localPath := cfg.GitHub.LocalPath
+	if cfg.GitHub.ConfigRepo == "" || localPath == "" {
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   SKIP,
+			Message:  "No GitHub repository configured",
+			FixHint:  "Configure GitHub repository in settings.yaml",
+			AutoFix:  false,
+		}
+	}
+
+	if _, err := os.Stat(localPath); os.IsNotExist(err) {
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   SKIP,
+			Message:  "Local clone not created yet",
+			Details:  []string{"Path: " + localPath},
+			FixHint:  "Run 'anvil config pull <app>' to create the local clone",
+			AutoFix:  false,
+		}
+	}
+
+	issues := inspectClone(localPath, cfg.GitHub.Branch)
+	messages := issues.messages()
+	details := []string{"Path: " + localPath}
+	for _, file := range issues.conflicts {
+		details = append(details, "Conflict: "+file)
+	}
+
+	if len(messages) > 0 {
+		status := FAIL
+		// Being left on an old push branch is recoverable by push/pull themselves
+		if len(messages) == 1 && issues.wrongBranch != "" {
+			status = WARN
+		}
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   status,
+			Message:  "Local clone needs repair: " + strings.Join(messages, ", "),
+			Details:  details,
+			FixHint:  "Run 'anvil doctor clone-health --fix' to repair or re-clone",
+			AutoFix:  true,
+		}
+	}
+
+	return &ValidationResult{
+		Name:     v.Name(),
+		Category: v.Category(),
+		Status:   PASS,
+		Message:  "Local clone is healthy",
+		Details:  details,
+		AutoFix:  false,
+	}
+}
+
+func (v *CloneHealthValidator) Fix(ctx context.Context, cfg *config.AnvilConfig) error {
+	localPath := cfg.GitHub.LocalPath
+	issues := inspectClone(localPath, cfg.GitHub.Branch)
+
+	if !issues.notRepository {
+		if issues.indexLock {
+			if err := os.Remove(filepath.Join(localPath, ".git", "index.lock")); err != nil {
+				return fmt.Errorf("failed to remove stale index.lock: %w", err)
+			}
+		}
+		if issues.mergeInProgress {
+			if err := runCloneGit(ctx, localPath, "merge", "--abort"); err != nil {
+				return fmt.Errorf("failed to abort merge: %w", err)
+			}
+		}
+		if issues.rebaseInProgress {
+			if err := runCloneGit(ctx, localPath, "rebase", "--abort"); err != nil {
+				return fmt.Errorf("failed to abort rebase: %w", err)
+			}
+		}
+		if issues.mergeInProgress || issues.rebaseInProgress {
+			// Aborting also resolves the conflicts the merge or rebase left behind
+			issues = inspectClone(localPath, cfg.GitHub.Branch)
+		}
+		if len(issues.conflicts) > 0 || issues.detachedHead || issues.wrongBranch != "" {
+			// Resetting throws away uncommitted edits in the clone, so ask first
+			if !confirm(charm.ConfirmDelete, fmt.Sprintf("Discard uncommitted changes in %s and check out '%s'?", localPath, cfg.GitHub.Branch)) {
+				return fmt.Errorf("local clone still unhealthy, reset declined")
+			}
+			if err := runCloneGit(ctx, localPath, "reset", "--hard"); err != nil {
+				return fmt.Errorf("failed to reset local clone: %w", err)
+			}
+			if err := runCloneGit(ctx, localPath, "checkout", cfg.GitHub.Branch); err != nil {
+				return fmt.Errorf("failed to check out %s: %w", cfg.GitHub.Branch, err)
+			}
+		}
+
+		if len(inspectClone(localPath, cfg.GitHub.Branch).messages()) == 0 {
+			return nil
+		}
+	}
+
+	// In-place repair was not enough, re-cloning discards the local clone so ask first
+	if !confirm(charm.ConfirmDelete, fmt.Sprintf("Local clone at %s could not be repaired. Remove it and re-clone?", localPath)) {
+		return fmt.Errorf("local clone still unhealthy, re-clone declined")
+	}
+
+	if err := os.RemoveAll(localPath); err != nil {
+		return fmt.Errorf("failed to remove local clone: %w", err)
+	}
+
+	var token string
+	if cfg.GitHub.TokenEnvVar != "" {
+		token = os.Getenv(cfg.GitHub.TokenEnvVar)
+	}
+	client := github.NewGitHubClient(cfg.GitHub.ConfigRepo, cfg.GitHub.Branch, localPath, token,
+		cfg.Git.SSHKeyPath, cfg.Git.Username, cfg.Git.Email)
+	client.CloneDepth = cfg.GitHub.CloneDepth
+	if err := client.CloneRepository(ctx); err != nil {
+		return fmt.Errorf("failed to re-clone repository: %w", err)
+	}
+
+	return nil
+}
+
+// runCloneGit runs a git command in the local clone, returning git's output when it fails
+func runCloneGit(ctx context.Context, localPath string, args ...string) error {
+	result, err := system.RunCommandInDirectoryWithTimeout(ctx, localPath, constants.GitCommand, args...)
+	if err != nil {
+		return err
+	}
+	if !result.Success {
+		return fmt.Errorf("git %s: %s", strings.Join(args, " "), strings.TrimSpace(result.Output))
+	}
+	return nil
+}
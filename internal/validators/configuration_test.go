@@ -0,0 +1,178 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validators
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
+)
+
+// newCloneRepo creates a clone-like repository on main with one committed file
+func newCloneRepo(t *testing.T) (string, func(args ...string)) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	repo := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@t", "GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@t")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q", "-b", "main")
+	os.WriteFile(filepath.Join(repo, ".zshrc"), []byte("export EDITOR=vim\n"), 0644)
+	git("add", "-A")
+	git("commit", "-q", "-m", "initial")
+	return repo, git
+}
+
+func TestCloneHealthFix(t *testing.T) {
+	original := confirm
+	t.Cleanup(func() { confirm = original })
+
+	cloneConfig := func(repo string) *config.AnvilConfig {
+		cfg := &config.AnvilConfig{}
+		cfg.GitHub.ConfigRepo = "me/dotfiles"
+		cfg.GitHub.Branch = "main"
+		cfg.GitHub.LocalPath = repo
+		return cfg
+	}
+	validator := &CloneHealthValidator{}
+	ctx := context.Background()
+
+	t.Run("declined reset keeps local changes", func(t *testing.T) {
+		repo, git := newCloneRepo(t)
+		git("checkout", "-q", "-b", "config-push-1")
+		os.WriteFile(filepath.Join(repo, ".zshrc"), []byte("export EDITOR=nano\n"), 0644)
+
+		var prompts []string
+		confirm = func(action, message string) bool {
+			if action != charm.ConfirmDelete {
+				t.Errorf("reset confirmed as %q, want %q", action, charm.ConfirmDelete)
+			}
+			prompts = append(prompts, message)
+			return false
+		}
+		if err := validator.Fix(ctx, cloneConfig(repo)); err == nil {
+			t.Fatal("expected an error when the reset is declined")
+		}
+		if len(prompts) != 1 || !strings.Contains(prompts[0], "Discard uncommitted changes") {
+			t.Errorf("prompts = %v, want one reset prompt", prompts)
+		}
+		if data, _ := os.ReadFile(filepath.Join(repo, ".zshrc")); string(data) != "export EDITOR=nano\n" {
+			t.Errorf("declined reset changed the file: %q", data)
+		}
+	})
+
+	t.Run("approved reset returns to the configured branch", func(t *testing.T) {
+		repo, git := newCloneRepo(t)
+		git("checkout", "-q", "-b", "config-push-1")
+		os.WriteFile(filepath.Join(repo, ".zshrc"), []byte("export EDITOR=nano\n"), 0644)
+
+		confirm = func(string, string) bool { return true }
+		cfg := cloneConfig(repo)
+		if err := validator.Fix(ctx, cfg); err != nil {
+			t.Fatalf("Fix failed: %v", err)
+		}
+		if result := validator.Validate(ctx, cfg); result.Status != PASS {
+			t.Errorf("clone after fix = %s: %s", result.Status, result.Message)
+		}
+		if data, _ := os.ReadFile(filepath.Join(repo, ".zshrc")); string(data) != "export EDITOR=vim\n" {
+			t.Errorf("reset kept local changes: %q", data)
+		}
+	})
+
+	t.Run("merge in progress is aborted", func(t *testing.T) {
+		repo, git := newCloneRepo(t)
+		git("checkout", "-q", "-b", "other")
+		os.WriteFile(filepath.Join(repo, ".zshrc"), []byte("export EDITOR=emacs\n"), 0644)
+		git("commit", "-q", "-am", "other")
+		git("checkout", "-q", "main")
+		os.WriteFile(filepath.Join(repo, ".zshrc"), []byte("export EDITOR=nano\n"), 0644)
+		git("commit", "-q", "-am", "main")
+		merge := exec.Command("git", "merge", "other")
+		merge.Dir = repo
+		merge.Env = append(os.Environ(), "GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@t")
+		if err := merge.Run(); err == nil {
+			t.Fatal("expected the merge to conflict")
+		}
+
+		cfg := cloneConfig(repo)
+		if result := validator.Validate(ctx, cfg); result.Status != FAIL {
+			t.Fatalf("conflicted merge = %s, want FAIL", result.Status)
+		}
+		confirm = func(string, string) bool {
+			t.Error("aborting a merge should not ask")
+			return false
+		}
+		if err := validator.Fix(ctx, cfg); err != nil {
+			t.Fatalf("Fix failed: %v", err)
+		}
+		if result := validator.Validate(ctx, cfg); result.Status != PASS {
+			t.Errorf("clone after fix = %s: %s", result.Status, result.Message)
+		}
+	})
+
+	t.Run("failed abort is reported", func(t *testing.T) {
+		repo, _ := newCloneRepo(t)
+		// A rebase directory git can't read makes 'git rebase --abort' fail
+		os.Mkdir(filepath.Join(repo, ".git", "rebase-merge"), 0755)
+
+		confirm = func(string, string) bool {
+			t.Error("a failed abort should not fall through to a prompt")
+			return false
+		}
+		err := validator.Fix(ctx, cloneConfig(repo))
+		if err == nil || !strings.Contains(err.Error(), "failed to abort rebase") {
+			t.Errorf("Fix() error = %v, want a failed rebase abort", err)
+		}
+	})
+}
+
+func TestProtectedSettingsValidator(t *testing.T) {
+	anvilDir := setupAppStateEnv(t)
+	settings := "version: \"1\"\ntools:\n  required_tools: [git]\n"
+	os.WriteFile(filepath.Join(anvilDir, "settings.yaml"), []byte(settings), 0644)
+	validator := &ProtectedSettingsValidator{}
+	ctx := context.Background()
+
+	checkResult(t, validator.Validate(ctx, nil), SKIP, "No protected team settings")
+
+	team := "protected:\n  tools:\n    required_tools: [git, curl]\n"
+	os.WriteFile(filepath.Join(anvilDir, "team.yaml"), []byte(team), 0644)
+	result := validator.Validate(ctx, nil)
+	checkResult(t, result, FAIL, "Local settings override protected team settings")
+	if !result.AutoFix {
+		t.Error("a conflict should be fixable")
+	}
+
+	if err := validator.Fix(ctx, nil); err != nil {
+		t.Fatalf("Fix() failed: %v", err)
+	}
+	checkResult(t, validator.Validate(ctx, nil), PASS, "Protected team settings are intact")
+}
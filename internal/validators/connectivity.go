@@ -20,9 +20,11 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/github"
 	"github.com/0xjuanma/anvil/internal/system"
 )
 
@@ -69,7 +71,8 @@ func (v *GitHubAccessValidator) Category() string { return "connectivity" }
 func (v *GitHubAccessValidator) Description() string {
 	return "Verify GitHub API access and authentication"
 }
-func (v *GitHubAccessValidator) CanFix() bool { return false }
+func (v *GitHubAccessValidator) CanFix() bool        { return false }
+func (v *GitHubAccessValidator) DependsOn() []string { return []string{"github-config"} }
 
 func (v *GitHubAccessValidator) Validate(ctx context.Context, cfg *config.AnvilConfig) *ValidationResult {
 	// Skip if no GitHub config
@@ -127,11 +130,25 @@ func (v *GitHubAccessValidator) Validate(ctx context.Context, cfg *config.AnvilC
 		}
 	}
 
-	// Test GitHub API with token
+	// Test GitHub API with token, through the shared client so repeated doctor runs revalidate
+	// a cached response instead of spending the rate limit
 	details = append(details, "Testing GitHub API access with token...")
-	result, err := system.RunCommand("curl", "-s", "-f", "-H", fmt.Sprintf("Authorization: token %s", token), "https://api.github.com/user")
-	if err != nil || !result.Success {
-		details = append(details, "✗ GitHub API request failed")
+	api := github.NewAPIClient(token, filepath.Join(config.GetAnvilConfigDirectory(), "cache", "github"))
+	resp, err := api.Get(ctx, "/user", nil)
+	if github.IsRateLimited(err) {
+		details = append(details, fmt.Sprintf("⚠ %v", err))
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   WARN,
+			Message:  "GitHub API rate limited, access could not be verified",
+			Details:  details,
+			FixHint:  "Run doctor again after the limit resets",
+			AutoFix:  false,
+		}
+	}
+	if err != nil {
+		details = append(details, fmt.Sprintf("✗ GitHub API request failed: %v", err))
 		return &ValidationResult{
 			Name:     v.Name(),
 			Category: v.Category(),
@@ -144,6 +161,10 @@ func (v *GitHubAccessValidator) Validate(ctx context.Context, cfg *config.AnvilC
 	}
 
 	details = append(details, "✓ GitHub API access successful")
+	if resp.RateLimit.Limit > 0 {
+		details = append(details, fmt.Sprintf("API requests left: %d of %d, resets at %s",
+			resp.RateLimit.Remaining, resp.RateLimit.Limit, resp.RateLimit.Reset.Local().Format("15:04")))
+	}
 	return &ValidationResult{
 		Name:     v.Name(),
 		Category: v.Category(),
@@ -166,7 +187,8 @@ func (v *RepositoryValidator) Category() string { return "connectivity" }
 func (v *RepositoryValidator) Description() string {
 	return "Verify configured repository exists and is accessible"
 }
-func (v *RepositoryValidator) CanFix() bool { return false }
+func (v *RepositoryValidator) CanFix() bool        { return false }
+func (v *RepositoryValidator) DependsOn() []string { return []string{"github-access"} }
 
 func (v *RepositoryValidator) Validate(ctx context.Context, cfg *config.AnvilConfig) *ValidationResult {
 	// Skip if no GitHub config
@@ -280,7 +302,8 @@ func (v *GitConnectivityValidator) Category() string { return "connectivity" }
 func (v *GitConnectivityValidator) Description() string {
 	return "Verify git operations are functional"
 }
-func (v *GitConnectivityValidator) CanFix() bool { return false }
+func (v *GitConnectivityValidator) CanFix() bool        { return false }
+func (v *GitConnectivityValidator) DependsOn() []string { return []string{"required-tools"} }
 
 func (v *GitConnectivityValidator) Validate(ctx context.Context, cfg *config.AnvilConfig) *ValidationResult {
 	var details []string
@@ -19,11 +19,16 @@ package validators
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"sort"
 	"strings"
 
 	"github.com/0xjuanma/anvil/internal/brew"
 	"github.com/0xjuanma/anvil/internal/config"
+	"github.com/0xjuanma/anvil/internal/constants"
+	"github.com/0xjuanma/anvil/internal/services"
 	"github.com/0xjuanma/anvil/internal/system"
+	"github.com/0xjuanma/anvil/internal/terminal/charm"
 	"github.com/0xjuanma/palantir"
 )
 
@@ -65,7 +70,7 @@ func (v *BrewValidator) Validate(ctx context.Context, cfg *config.AnvilConfig) *
 
 	// Check if brew needs updating (warn only)
 	updateResult, err := system.RunCommand("brew", "outdated", "--quiet")
-	if err == nil && strings.TrimSpace(updateResult.Output) != "" {
+	if err == nil && len(outdatedPackages(cfg, updateResult.Output)) > 0 {
 		return &ValidationResult{
 			Name:     v.Name(),
 			Category: v.Category(),
@@ -87,6 +92,19 @@ func (v *BrewValidator) Validate(ctx context.Context, cfg *config.AnvilConfig) *
 	}
 }
 
+// outdatedPackages parses 'brew outdated --quiet' output, leaving out packages managed externally
+func outdatedPackages(cfg *config.AnvilConfig, output string) []string {
+	var packages []string
+	for _, line := range strings.Split(output, "\n") {
+		pkg := strings.TrimSpace(line)
+		if pkg == "" || (cfg != nil && cfg.IsManagedExternally(pkg)) {
+			continue
+		}
+		packages = append(packages, pkg)
+	}
+	return packages
+}
+
 func (v *BrewValidator) Fix(ctx context.Context, cfg *config.AnvilConfig) error {
 	o := palantir.GetGlobalOutputHandler()
 
@@ -117,14 +135,11 @@ func (v *BrewValidator) Fix(ctx context.Context, cfg *config.AnvilConfig) error
 		return nil
 	}
 
-	outdatedPackages := strings.TrimSpace(outdatedResult.Output)
-	if outdatedPackages == "" {
+	packages := outdatedPackages(cfg, outdatedResult.Output)
+	if len(packages) == 0 {
 		o.PrintSuccess("All Homebrew packages are up to date!")
 		return nil
 	}
-
-	// Parse and display outdated packages
-	packages := strings.Split(outdatedPackages, "\n")
 	packageCount := len(packages)
 
 	fmt.Println("")
@@ -146,6 +161,351 @@ func (v *BrewValidator) Fix(ctx context.Context, cfg *config.AnvilConfig) error
 	return nil
 }
 
+// BrewPolicyValidator checks that the Homebrew policy in settings.yaml is in effect
+type BrewPolicyValidator struct{}
+
+func (v *BrewPolicyValidator) Name() string     { return "brew-policy" }
+func (v *BrewPolicyValidator) Category() string { return "dependencies" }
+func (v *BrewPolicyValidator) Description() string {
+	return "Verify Homebrew analytics and mirror settings are applied"
+}
+func (v *BrewPolicyValidator) CanFix() bool        { return true }
+func (v *BrewPolicyValidator) DependsOn() []string { return []string{"homebrew"} }
+
+func (v *BrewPolicyValidator) Validate(ctx context.Context, cfg *config.AnvilConfig) *ValidationResult {
+	policy := cfg.Brew
+	if !policy.HasPolicy() {
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   PASS,
+			Message:  "No Homebrew policy configured",
+			AutoFix:  false,
+		}
+	}
+
+	// Mirrors must be absolute http(s) URLs or brew silently falls back to the defaults
+	var invalid []string
+	for key, domain := range map[string]string{"brew.bottle_domain": policy.BottleDomain, "brew.api_domain": policy.APIDomain} {
+		if domain == "" {
+			continue
+		}
+		if u, err := url.Parse(domain); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			invalid = append(invalid, fmt.Sprintf("%s: '%s' is not an http(s) URL", key, domain))
+		}
+	}
+	if len(invalid) > 0 {
+		sort.Strings(invalid)
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   FAIL,
+			Message:  "Homebrew policy has invalid mirror URLs",
+			Details:  invalid,
+			FixHint:  fmt.Sprintf("Set full URLs in %s, e.g. 'https://mirror.example.com/bottles'", constants.ANVIL_CONFIG_FILE),
+			AutoFix:  false,
+		}
+	}
+
+	// 'brew config' reports the environment anvil's brew calls run with
+	var details, missing []string
+	result, _ := system.RunCommand(constants.BrewCommand, "config")
+	for envVar, domain := range map[string]string{constants.BrewBottleDomainEnvVar: policy.BottleDomain, constants.BrewAPIDomainEnvVar: policy.APIDomain} {
+		if domain == "" {
+			continue
+		}
+		if result.Success && strings.Contains(result.Output, fmt.Sprintf("%s: %s", envVar, domain)) {
+			details = append(details, fmt.Sprintf("%s: %s", envVar, domain))
+		} else {
+			missing = append(missing, envVar)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   FAIL,
+			Message:  fmt.Sprintf("Homebrew is not using the configured mirror: %s", strings.Join(missing, ", ")),
+			Details:  []string{"Check 'brew config' and make sure no other setting overrides the mirror"},
+			AutoFix:  false,
+		}
+	}
+
+	if policy.AnalyticsDisabled() {
+		// anvil always sets HOMEBREW_NO_ANALYTICS, check the persistent state used by manual brew calls
+		state, _ := system.RunCommand("env", "-u", constants.BrewNoAnalyticsEnvVar, constants.BrewCommand, "analytics", "state")
+		if !state.Success || !strings.Contains(strings.ToLower(state.Output), "disabled") {
+			return &ValidationResult{
+				Name:     v.Name(),
+				Category: v.Category(),
+				Status:   WARN,
+				Message:  "Homebrew analytics are only disabled for anvil's brew calls",
+				Details:  append(details, "Manual brew commands still send analytics"),
+				FixHint:  "Run 'brew analytics off' to disable analytics for every brew command",
+				AutoFix:  true,
+			}
+		}
+		details = append(details, "Analytics disabled")
+	}
+
+	return &ValidationResult{
+		Name:     v.Name(),
+		Category: v.Category(),
+		Status:   PASS,
+		Message:  "Homebrew policy is in effect",
+		Details:  details,
+		AutoFix:  false,
+	}
+}
+
+func (v *BrewPolicyValidator) Fix(ctx context.Context, cfg *config.AnvilConfig) error {
+	if !cfg.Brew.AnalyticsDisabled() {
+		return nil
+	}
+
+	result, _ := system.RunCommand(constants.BrewCommand, "analytics", "off")
+	if !result.Success {
+		return fmt.Errorf("brew analytics off failed: %s", strings.TrimSpace(result.Output))
+	}
+	palantir.GetGlobalOutputHandler().PrintSuccess("Homebrew analytics disabled")
+	return nil
+}
+
+// BrewRenamesValidator checks settings for packages Homebrew renamed, deprecated or disabled
+type BrewRenamesValidator struct{}
+
+func (v *BrewRenamesValidator) Name() string     { return "brew-renames" }
+func (v *BrewRenamesValidator) Category() string { return "dependencies" }
+func (v *BrewRenamesValidator) Description() string {
+	return "Find renamed and deprecated Homebrew packages in settings"
+}
+func (v *BrewRenamesValidator) CanFix() bool        { return true }
+func (v *BrewRenamesValidator) DependsOn() []string { return []string{"homebrew"} }
+
+func (v *BrewRenamesValidator) Validate(ctx context.Context, cfg *config.AnvilConfig) *ValidationResult {
+	notices, err := brew.FindPackageNotices(config.AppEntries(cfg))
+	if err != nil {
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   SKIP,
+			Message:  "Could not read installed Homebrew packages",
+			Details:  []string{err.Error()},
+			AutoFix:  false,
+		}
+	}
+
+	if len(notices) == 0 {
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   PASS,
+			Message:  "No renamed or deprecated packages in settings",
+			AutoFix:  false,
+		}
+	}
+
+	var details []string
+	renamed := 0
+	for _, notice := range notices {
+		details = append(details, notice.Describe())
+		if notice.RenamedTo != "" {
+			renamed++
+		}
+	}
+
+	result := &ValidationResult{
+		Name:     v.Name(),
+		Category: v.Category(),
+		Status:   WARN,
+		Message:  fmt.Sprintf("%d settings entries are renamed or deprecated in Homebrew", len(notices)),
+		Details:  details,
+		FixHint:  "Replace deprecated packages with the alternative Homebrew suggests",
+		AutoFix:  false,
+	}
+	if renamed > 0 {
+		result.FixHint = fmt.Sprintf("Renamed entries will be updated to their new names in %s", constants.ANVIL_CONFIG_FILE)
+		result.AutoFix = true
+	}
+	return result
+}
+
+func (v *BrewRenamesValidator) Fix(ctx context.Context, cfg *config.AnvilConfig) error {
+	notices, err := brew.FindPackageNotices(config.AppEntries(cfg))
+	if err != nil {
+		return err
+	}
+
+	o := palantir.GetGlobalOutputHandler()
+	for _, notice := range notices {
+		if notice.RenamedTo == "" {
+			continue
+		}
+		name, _ := brew.ParsePackageName(notice.Name)
+		if _, err := config.RenameAppEntries(name, notice.RenamedTo); err != nil {
+			return fmt.Errorf("failed to rename %s: %w", notice.Name, err)
+		}
+		o.PrintSuccess(fmt.Sprintf("Renamed %s to %s", notice.Name, notice.RenamedTo))
+	}
+	return nil
+}
+
+// ServicesValidator checks that Homebrew services are in the state the services section of settings asks for
+type ServicesValidator struct{}
+
+func (v *ServicesValidator) Name() string     { return "services" }
+func (v *ServicesValidator) Category() string { return "dependencies" }
+func (v *ServicesValidator) Description() string {
+	return "Verify Homebrew services are started or stopped as settings ask"
+}
+func (v *ServicesValidator) CanFix() bool        { return true }
+func (v *ServicesValidator) DependsOn() []string { return []string{"homebrew"} }
+
+func (v *ServicesValidator) Validate(ctx context.Context, cfg *config.AnvilConfig) *ValidationResult {
+	if len(cfg.Services) == 0 {
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   SKIP,
+			Message:  "No services configured",
+			FixHint:  fmt.Sprintf("Add a services section to %s to keep services such as postgresql running", constants.ANVIL_CONFIG_FILE),
+			AutoFix:  false,
+		}
+	}
+
+	states, err := services.Plan(cfg.Services)
+	if err != nil {
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   FAIL,
+			Message:  "Service states cannot be checked",
+			Details:  []string{err.Error()},
+			FixHint:  fmt.Sprintf("Fix the services section in %s", constants.ANVIL_CONFIG_FILE),
+			AutoFix:  false,
+		}
+	}
+
+	drifted := services.Drifted(states)
+	if len(drifted) == 0 {
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   PASS,
+			Message:  fmt.Sprintf("%d services are in their wanted state", len(states)),
+			AutoFix:  false,
+		}
+	}
+
+	var details []string
+	fixable := false
+	for _, state := range drifted {
+		if !state.Installed {
+			details = append(details, fmt.Sprintf("%s: not installed, wanted %s", state.Name, state.Want))
+			continue
+		}
+		fixable = true
+		details = append(details, fmt.Sprintf("%s: %s, wanted %s", state.Name, state.Status, state.Want))
+	}
+	return &ValidationResult{
+		Name:     v.Name(),
+		Category: v.Category(),
+		Status:   WARN,
+		Message:  fmt.Sprintf("%d of %d services are in the wrong state", len(drifted), len(states)),
+		Details:  details,
+		FixHint:  "Run 'anvil services apply' or 'anvil doctor services --fix', install missing formulas first",
+		AutoFix:  fixable,
+	}
+}
+
+func (v *ServicesValidator) Fix(ctx context.Context, cfg *config.AnvilConfig) error {
+	_, err := services.Apply(cfg.Services)
+	return err
+}
+
+// BrewArchitectureValidator checks that Homebrew's prefix matches the Mac's architecture
+type BrewArchitectureValidator struct{}
+
+func (v *BrewArchitectureValidator) Name() string     { return "brew-architecture" }
+func (v *BrewArchitectureValidator) Category() string { return "dependencies" }
+func (v *BrewArchitectureValidator) Description() string {
+	return "Verify Homebrew's prefix matches this Mac's architecture"
+}
+func (v *BrewArchitectureValidator) CanFix() bool        { return true }
+func (v *BrewArchitectureValidator) DependsOn() []string { return []string{"homebrew"} }
+
+func (v *BrewArchitectureValidator) Validate(ctx context.Context, cfg *config.AnvilConfig) *ValidationResult {
+	setup, ok := brew.DetectSetup()
+	if !ok {
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   SKIP,
+			Message:  "Homebrew architecture checks apply to macOS with Homebrew installed",
+			AutoFix:  false,
+		}
+	}
+
+	issues := setup.Issues()
+	if len(issues) == 0 {
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   PASS,
+			Message:  fmt.Sprintf("Homebrew at %s matches this Mac's architecture", setup.Prefix),
+			AutoFix:  false,
+		}
+	}
+
+	result := &ValidationResult{
+		Name:     v.Name(),
+		Category: v.Category(),
+		Status:   WARN,
+		Message:  "Homebrew's prefix or architecture doesn't match this Mac",
+		Details:  issues,
+		FixHint:  "See https://docs.brew.sh/Installation for the prefix Homebrew expects on each architecture",
+		AutoFix:  false,
+	}
+	if setup.NeedsMigration() {
+		tracked := brew.InstalledEntries(config.AppEntries(cfg), brew.GetInstalledVersions())
+		result.Details = append(result.Details,
+			fmt.Sprintf("%d tracked packages are installed by the Intel Homebrew", len(tracked)),
+			"Migrating installs the arm64 Homebrew under /opt/homebrew and reinstalls them with it")
+		result.FixHint = "The arm64 Homebrew will be installed and tracked packages reinstalled, the Intel install is kept until you remove it"
+		result.AutoFix = true
+	}
+	return result
+}
+
+func (v *BrewArchitectureValidator) Fix(ctx context.Context, cfg *config.AnvilConfig) error {
+	setup, ok := brew.DetectSetup()
+	if !ok || !setup.NeedsMigration() {
+		return fmt.Errorf("only an Intel Homebrew on Apple Silicon can be migrated automatically")
+	}
+
+	o := palantir.GetGlobalOutputHandler()
+	tracked := brew.InstalledEntries(config.AppEntries(cfg), brew.GetInstalledVersions())
+	if !charm.Confirm(charm.ConfirmPrivileged, fmt.Sprintf("Install the arm64 Homebrew under /opt/homebrew and reinstall %d tracked packages?", len(tracked))) {
+		return fmt.Errorf("Homebrew migration declined")
+	}
+
+	failed, err := brew.MigrateToNative(tracked)
+	if err != nil {
+		return err
+	}
+	o.PrintSuccess(fmt.Sprintf("Reinstalled %d of %d tracked packages with the arm64 Homebrew", len(tracked)-len(failed), len(tracked)))
+	if len(failed) > 0 {
+		o.PrintWarning("Failed to reinstall: %s", strings.Join(failed, ", "))
+	}
+
+	o.PrintInfo("To finish the migration:")
+	o.PrintInfo("  1. Put the arm64 brew first on PATH: add 'eval \"$(/opt/homebrew/bin/brew shellenv)\"' to ~/.zprofile")
+	o.PrintInfo("  2. Open a new terminal and check 'brew --prefix' prints /opt/homebrew")
+	o.PrintInfo("  3. Remove the Intel install with Homebrew's uninstall script, passing --path=/usr/local")
+	return nil
+}
+
 // RequiredToolsValidator checks if all required tools are installed
 type RequiredToolsValidator struct{}
 
@@ -154,7 +514,8 @@ func (v *RequiredToolsValidator) Category() string { return "dependencies" }
 func (v *RequiredToolsValidator) Description() string {
 	return "Verify all required tools are installed"
 }
-func (v *RequiredToolsValidator) CanFix() bool { return true }
+func (v *RequiredToolsValidator) CanFix() bool        { return true }
+func (v *RequiredToolsValidator) DependsOn() []string { return []string{"homebrew"} }
 
 func (v *RequiredToolsValidator) Validate(ctx context.Context, cfg *config.AnvilConfig) *ValidationResult {
 	requiredTools := cfg.Tools.RequiredTools
@@ -170,8 +531,13 @@ func (v *RequiredToolsValidator) Validate(ctx context.Context, cfg *config.Anvil
 
 	var missingTools []string
 	var installedTools []string
+	var managedTools []string
 
 	for _, tool := range requiredTools {
+		if cfg.IsManagedExternally(tool) {
+			managedTools = append(managedTools, tool)
+			continue
+		}
 		if brew.IsApplicationAvailable(tool) {
 			installedTools = append(installedTools, tool)
 		} else {
@@ -185,7 +551,7 @@ func (v *RequiredToolsValidator) Validate(ctx context.Context, cfg *config.Anvil
 			Category: v.Category(),
 			Status:   FAIL,
 			Message:  fmt.Sprintf("Missing required tools: %s", strings.Join(missingTools, ", ")),
-			Details:  []string{fmt.Sprintf("Installed: %d/%d", len(installedTools), len(requiredTools))},
+			Details:  append([]string{fmt.Sprintf("Installed: %d/%d", len(installedTools), len(requiredTools))}, managedDetails(managedTools)...),
 			FixHint:  "Missing tools will be installed automatically",
 			AutoFix:  true,
 		}
@@ -195,8 +561,8 @@ func (v *RequiredToolsValidator) Validate(ctx context.Context, cfg *config.Anvil
 		Name:     v.Name(),
 		Category: v.Category(),
 		Status:   PASS,
-		Message:  fmt.Sprintf("All required tools installed (%d/%d)", len(installedTools), len(requiredTools)),
-		Details:  installedTools,
+		Message:  fmt.Sprintf("All required tools installed (%d/%d)", len(installedTools), len(requiredTools)-len(managedTools)),
+		Details:  append(installedTools, managedDetails(managedTools)...),
 		AutoFix:  false,
 	}
 }
@@ -205,7 +571,7 @@ func (v *RequiredToolsValidator) Fix(ctx context.Context, cfg *config.AnvilConfi
 	requiredTools := cfg.Tools.RequiredTools
 	var installErrors []string
 
-	for _, tool := range requiredTools {
+	for _, tool := range cfg.ExcludeManagedExternally(requiredTools) {
 		if !brew.IsApplicationAvailable(tool) {
 			if err := brew.InstallPackageWithCheck(tool); err != nil {
 				installErrors = append(installErrors, fmt.Sprintf("%s: %v", tool, err))
@@ -219,3 +585,16 @@ func (v *RequiredToolsValidator) Fix(ctx context.Context, cfg *config.AnvilConfi
 
 	return nil
 }
+
+// managedDetails notes tools that are left to another tool, present or not
+func managedDetails(tools []string) []string {
+	details := make([]string, len(tools))
+	for i, tool := range tools {
+		state := "not installed"
+		if brew.IsApplicationAvailable(tool) {
+			state = "installed"
+		}
+		details[i] = fmt.Sprintf("%s: managed externally (%s)", tool, state)
+	}
+	return details
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/0xjuanma/anvil/internal/config"
 	"github.com/0xjuanma/anvil/internal/constants"
 	"github.com/0xjuanma/anvil/internal/utils"
+	"github.com/0xjuanma/anvil/internal/version"
 )
 
 // InitRunValidator checks if anvil init has been run successfully
@@ -89,7 +90,8 @@ func (v *SettingsFileValidator) Category() string { return "environment" }
 func (v *SettingsFileValidator) Description() string {
 	return "Validate settings.yaml file exists and is valid"
 }
-func (v *SettingsFileValidator) CanFix() bool { return false }
+func (v *SettingsFileValidator) CanFix() bool        { return false }
+func (v *SettingsFileValidator) DependsOn() []string { return []string{"init-run"} }
 
 func (v *SettingsFileValidator) Validate(ctx context.Context, cfg *config.AnvilConfig) *ValidationResult {
 	configPath := config.GetAnvilConfigPath()
@@ -121,16 +123,39 @@ func (v *SettingsFileValidator) Validate(ctx context.Context, cfg *config.AnvilC
 		}
 	}
 
-	// Check if file is readable and valid YAML
-	_, err = config.LoadConfig()
+	// Check if file is readable and valid YAML. Read directly, in safe mode LoadConfig accepts corrupt settings.
+	data, err := os.ReadFile(configPath)
+	if err == nil {
+		err = config.ValidSettings(data)
+	}
+	problems, _ := config.SettingsProblems()
 	if err != nil {
+		details := []string{err.Error()}
+		for _, problem := range problems {
+			details = append(details, "Section "+problem.String())
+		}
 		return &ValidationResult{
 			Name:     v.Name(),
 			Category: v.Category(),
 			Status:   FAIL,
 			Message:  "Settings file is not valid YAML",
-			Details:  []string{err.Error()},
-			FixHint:  "Check YAML syntax in " + configPath,
+			Details:  details,
+			FixHint:  "Run 'anvil config validate' for details, or 'anvil config restore-settings' to restore a backup",
+			AutoFix:  false,
+		}
+	}
+	if len(problems) > 0 {
+		var details []string
+		for _, problem := range problems {
+			details = append(details, "Section "+problem.String())
+		}
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   WARN,
+			Message:  "Settings file is missing sections",
+			Details:  details,
+			FixHint:  "Run 'anvil config validate' for details",
 			AutoFix:  false,
 		}
 	}
@@ -156,6 +181,81 @@ func (v *SettingsFileValidator) Fix(ctx context.Context, cfg *config.AnvilConfig
 	return nil
 }
 
+// AnvilVersionValidator checks the running anvil against the min_anvil_version of the team layer and settings
+type AnvilVersionValidator struct{}
+
+func (v *AnvilVersionValidator) Name() string     { return "anvil-version" }
+func (v *AnvilVersionValidator) Category() string { return "environment" }
+func (v *AnvilVersionValidator) Description() string {
+	return "Verify this anvil is new enough for the team and synced settings"
+}
+func (v *AnvilVersionValidator) CanFix() bool { return false }
+
+func (v *AnvilVersionValidator) Validate(ctx context.Context, cfg *config.AnvilConfig) *ValidationResult {
+	current := version.GetVersion()
+	requirement, err := config.GetVersionRequirement()
+	if err != nil {
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   WARN,
+			Message:  "Minimum anvil version could not be read",
+			Details:  []string{err.Error(), "The requirement is ignored until it is fixed"},
+			FixHint:  "Set min_anvil_version to a version such as \"1.4.0\"",
+			AutoFix:  false,
+		}
+	}
+	if requirement.Minimum == "" {
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   PASS,
+			Message:  fmt.Sprintf("anvil %s, no minimum version required", current),
+			AutoFix:  false,
+		}
+	}
+
+	details := []string{
+		"Running: " + current,
+		fmt.Sprintf("Required: %s or newer (%s)", requirement.Minimum, requirement.Source),
+	}
+	if !version.IsRelease() {
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   WARN,
+			Message:  "Development build, minimum version not enforced",
+			Details:  details,
+			FixHint:  "Install a release build to check compatibility",
+			AutoFix:  false,
+		}
+	}
+	if ok, _ := version.AtLeast(requirement.Minimum); !ok {
+		return &ValidationResult{
+			Name:     v.Name(),
+			Category: v.Category(),
+			Status:   FAIL,
+			Message:  fmt.Sprintf("anvil %s is older than the required %s", current, requirement.Minimum),
+			Details:  append(details, "Commands are blocked until anvil is upgraded"),
+			FixHint:  "Run 'anvil update' or 'brew upgrade anvil'",
+			AutoFix:  false,
+		}
+	}
+
+	return &ValidationResult{
+		Name:     v.Name(),
+		Category: v.Category(),
+		Status:   PASS,
+		Message:  fmt.Sprintf("anvil %s meets the required %s", current, requirement.Minimum),
+		Details:  details,
+		AutoFix:  false,
+	}
+}
+
+func (v *AnvilVersionValidator) Fix(ctx context.Context, cfg *config.AnvilConfig) error {
+	return fmt.Errorf("automatic upgrade not supported, run 'anvil update' manually")
+}
+
 // DirectoryStructureValidator validates the anvil directory structure
 type DirectoryStructureValidator struct{}
 
@@ -164,7 +264,8 @@ func (v *DirectoryStructureValidator) Category() string { return "environment" }
 func (v *DirectoryStructureValidator) Description() string {
 	return "Verify anvil directory structure is correct"
 }
-func (v *DirectoryStructureValidator) CanFix() bool { return true }
+func (v *DirectoryStructureValidator) CanFix() bool        { return true }
+func (v *DirectoryStructureValidator) DependsOn() []string { return []string{"init-run"} }
 
 func (v *DirectoryStructureValidator) Validate(ctx context.Context, cfg *config.AnvilConfig) *ValidationResult {
 	anvilDir := config.GetAnvilConfigDirectory()
@@ -0,0 +1,124 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validators
+
+import (
+	"time"
+)
+
+// Exit codes of 'anvil doctor --output json'. 1 stays reserved for errors running doctor itself.
+const (
+	ExitHealthy  = 0 // Every check passed or was skipped
+	ExitWarnings = 2 // Warnings, but no failures
+	ExitFailures = 3 // At least one check failed
+)
+
+// Report is the machine-readable form of a doctor run, grouped by category
+type Report struct {
+	GeneratedAt time.Time        `json:"generated_at"`
+	Summary     ReportSummary    `json:"summary"`
+	Categories  []CategoryReport `json:"categories"`
+}
+
+// ReportSummary counts results by status and gives the overall outcome
+type ReportSummary struct {
+	Status     ValidationStatus `json:"status"`
+	ExitCode   int              `json:"exit_code"`
+	Total      int              `json:"total"`
+	Passed     int              `json:"passed"`
+	Warned     int              `json:"warned"`
+	Failed     int              `json:"failed"`
+	Skipped    int              `json:"skipped"`
+	Fixable    int              `json:"fixable"`
+	DurationMS int64            `json:"duration_ms"`
+}
+
+// CategoryReport holds the checks of one category with their own summary
+type CategoryReport struct {
+	Name    string        `json:"name"`
+	Summary ReportSummary `json:"summary"`
+	Checks  []CheckReport `json:"checks"`
+}
+
+// CheckReport is a single result with its timing and whether 'doctor --fix' can repair it
+type CheckReport struct {
+	*ValidationResult
+	Fixable    bool  `json:"fixable"`
+	DurationMS int64 `json:"duration_ms"`
+}
+
+// NewReport groups results by category, listing categories in the given order first and
+// any others after them in the order they were seen
+func NewReport(results []*ValidationResult, categoryOrder []string) *Report {
+	report := &Report{
+		GeneratedAt: time.Now().UTC(),
+		Summary:     Summarize(results),
+	}
+
+	grouped := FormatResultsTable(results)
+	order := append([]string{}, categoryOrder...)
+	listed := make(map[string]bool, len(order))
+	for _, category := range order {
+		listed[category] = true
+	}
+	for _, result := range results {
+		if !listed[result.Category] {
+			listed[result.Category] = true
+			order = append(order, result.Category)
+		}
+	}
+
+	for _, category := range order {
+		categoryResults, exists := grouped[category]
+		if !exists {
+			continue
+		}
+		categoryReport := CategoryReport{Name: category, Summary: Summarize(categoryResults)}
+		for _, result := range categoryResults {
+			categoryReport.Checks = append(categoryReport.Checks, CheckReport{
+				ValidationResult: result,
+				Fixable:          result.AutoFix && result.Status != PASS,
+				DurationMS:       result.Duration.Milliseconds(),
+			})
+		}
+		report.Categories = append(report.Categories, categoryReport)
+	}
+
+	return report
+}
+
+// Summarize counts results by status and derives the overall status and exit code
+func Summarize(results []*ValidationResult) ReportSummary {
+	summary := ReportSummary{Total: len(results), Fixable: len(GetFixableIssues(results))}
+	summary.Passed, summary.Warned, summary.Failed, summary.Skipped = GetSummary(results)
+
+	var duration time.Duration
+	for _, result := range results {
+		duration += result.Duration
+	}
+	summary.DurationMS = duration.Milliseconds()
+
+	switch {
+	case summary.Failed > 0:
+		summary.Status, summary.ExitCode = FAIL, ExitFailures
+	case summary.Warned > 0:
+		summary.Status, summary.ExitCode = WARN, ExitWarnings
+	default:
+		summary.Status, summary.ExitCode = PASS, ExitHealthy
+	}
+	return summary
+}
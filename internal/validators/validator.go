@@ -20,6 +20,8 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/0xjuanma/anvil/internal/config"
 	"github.com/0xjuanma/palantir"
@@ -50,6 +52,22 @@ func (vs ValidationStatus) String() string {
 	}
 }
 
+// MarshalText encodes a status as its lowercase name, e.g. "warn" in JSON reports
+func (vs ValidationStatus) MarshalText() ([]byte, error) {
+	return []byte(strings.ToLower(vs.String())), nil
+}
+
+// UnmarshalText decodes a status written by MarshalText, e.g. from cached doctor results
+func (vs *ValidationStatus) UnmarshalText(text []byte) error {
+	for _, status := range []ValidationStatus{PASS, WARN, FAIL, SKIP} {
+		if strings.EqualFold(string(text), status.String()) {
+			*vs = status
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown status '%s'", text)
+}
+
 // ValidationResult represents the result of a validation check
 type ValidationResult struct {
 	Name     string           `json:"name"`
@@ -59,6 +77,7 @@ type ValidationResult struct {
 	Details  []string         `json:"details,omitempty"`
 	FixHint  string           `json:"fix_hint,omitempty"`
 	AutoFix  bool             `json:"auto_fix"`
+	Duration time.Duration    `json:"-"` // How long the check took, set by the doctor engine
 }
 
 // Validator interface defines the contract for all validation checks
@@ -71,10 +90,17 @@ type Validator interface {
 	Fix(ctx context.Context, config *config.AnvilConfig) error
 }
 
+// DependentValidator is implemented by validators whose check or fix relies on other checks
+// passing first, e.g. required tools are installed with Homebrew
+type DependentValidator interface {
+	DependsOn() []string
+}
+
 // ValidationRegistry manages all available validators
 type ValidationRegistry struct {
 	validators map[string]Validator
 	categories map[string][]string
+	order      []string // Registration order, used to keep dependency ordering stable
 }
 
 // NewValidationRegistry creates a new validator registry
@@ -92,6 +118,79 @@ func (vr *ValidationRegistry) Register(validator Validator) {
 
 	vr.validators[name] = validator
 	vr.categories[category] = append(vr.categories[category], name)
+	vr.order = append(vr.order, name)
+}
+
+// dependenciesOf returns the registered checks a validator directly depends on
+func (vr *ValidationRegistry) dependenciesOf(name string) []string {
+	dependent, ok := vr.validators[name].(DependentValidator)
+	if !ok {
+		return nil
+	}
+
+	var deps []string
+	for _, dep := range dependent.DependsOn() {
+		if _, exists := vr.validators[dep]; exists {
+			deps = append(deps, dep)
+		}
+	}
+	return deps
+}
+
+// DependencyOrder returns all registered checks ordered so that every check comes after
+// the checks it depends on. Independent checks keep their registration order.
+func (vr *ValidationRegistry) DependencyOrder() ([]string, error) {
+	ordered := make([]string, 0, len(vr.order))
+	state := make(map[string]int) // 0 unvisited, 1 visiting, 2 done
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case 1:
+			return fmt.Errorf("dependency cycle between checks: %s", strings.Join(append(path, name), " -> "))
+		case 2:
+			return nil
+		}
+
+		state[name] = 1
+		for _, dep := range vr.dependenciesOf(name) {
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = 2
+		ordered = append(ordered, name)
+		return nil
+	}
+
+	for _, name := range vr.order {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// TransitiveDependencies returns every check the named check depends on, directly or
+// indirectly, in the order they should be fixed
+func (vr *ValidationRegistry) TransitiveDependencies(name string) []string {
+	seen := make(map[string]bool)
+	var deps []string
+
+	var visit func(string)
+	visit = func(current string) {
+		for _, dep := range vr.dependenciesOf(current) {
+			if seen[dep] {
+				continue
+			}
+			seen[dep] = true
+			visit(dep)
+			deps = append(deps, dep)
+		}
+	}
+
+	visit(name)
+	return deps
 }
 
 // GetValidator retrieves a validator by name
@@ -240,7 +339,7 @@ func (d *DoctorEngine) RunCheck(ctx context.Context, checkName string) *Validati
 		}
 	}
 
-	return validator.Validate(ctx, config)
+	return runValidator(ctx, config, validator)
 }
 
 // FixCheck attempts to fix a specific validation issue
@@ -262,6 +361,37 @@ func (d *DoctorEngine) FixCheck(ctx context.Context, checkName string) error {
 	return validator.Fix(ctx, config)
 }
 
+// OrderFixes sorts fixable results so each repair runs after the repairs it depends on
+func (d *DoctorEngine) OrderFixes(results []*ValidationResult) ([]*ValidationResult, error) {
+	order, err := d.registry.DependencyOrder()
+	if err != nil {
+		return nil, err
+	}
+
+	position := make(map[string]int, len(order))
+	for i, name := range order {
+		position[name] = i
+	}
+	rank := func(name string) int {
+		if pos, ok := position[name]; ok {
+			return pos
+		}
+		return len(order)
+	}
+
+	ordered := make([]*ValidationResult, len(results))
+	copy(ordered, results)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return rank(ordered[i].Name) < rank(ordered[j].Name)
+	})
+	return ordered, nil
+}
+
+// Dependencies returns every check the named check depends on, in fix order
+func (d *DoctorEngine) Dependencies(checkName string) []string {
+	return d.registry.TransitiveDependencies(checkName)
+}
+
 // ListChecks returns available categories and checks
 func (d *DoctorEngine) ListChecks() map[string][]string {
 	return d.registry.ListChecks()
@@ -272,33 +402,55 @@ func (d *DoctorEngine) runValidators(ctx context.Context, config *config.AnvilCo
 	var results []*ValidationResult
 
 	for _, validator := range validators {
-		result := validator.Validate(ctx, config)
-		results = append(results, result)
+		results = append(results, runValidator(ctx, config, validator))
 	}
 
 	return results
 }
 
+// runValidator runs a single validator and records how long it took
+func runValidator(ctx context.Context, config *config.AnvilConfig, validator Validator) *ValidationResult {
+	start := time.Now()
+	result := validator.Validate(ctx, config)
+	result.Duration = time.Since(start)
+	return result
+}
+
 // registerDefaultValidators registers all built-in validators
 func (d *DoctorEngine) registerDefaultValidators() {
 	// Environment validators
 	d.registry.Register(&InitRunValidator{})
 	d.registry.Register(&SettingsFileValidator{})
+	d.registry.Register(&AnvilVersionValidator{})
 	d.registry.Register(&DirectoryStructureValidator{})
 
 	// Dependency validators
 	d.registry.Register(&BrewValidator{})
+	d.registry.Register(&BrewArchitectureValidator{})
 	d.registry.Register(&RequiredToolsValidator{})
+	d.registry.Register(&BrewPolicyValidator{})
+	d.registry.Register(&BrewRenamesValidator{})
+	d.registry.Register(&ServicesValidator{})
 
 	// Configuration validators
 	d.registry.Register(&GitConfigValidator{})
 	d.registry.Register(&GitHubConfigValidator{})
 	d.registry.Register(&SyncConfigValidator{})
+	d.registry.Register(&ProtectedSettingsValidator{})
+	d.registry.Register(&HostsBlockValidator{})
+	d.registry.Register(&GitSettingsValidator{})
+	d.registry.Register(&CloneHealthValidator{})
 
 	// Connectivity validators
 	d.registry.Register(&GitHubAccessValidator{})
 	d.registry.Register(&RepositoryValidator{})
 	d.registry.Register(&GitConnectivityValidator{})
+
+	// App validators
+	d.registry.Register(&ConfigPathsValidator{})
+	d.registry.Register(&RemoteAppsValidator{})
+	d.registry.Register(&AppStateValidator{})
+	d.registry.Register(&ConfigOverlapValidator{})
 }
 
 // GetSummary creates a summary of validation results
@@ -433,7 +585,7 @@ func (d *DoctorEngine) RunCheckWithProgress(ctx context.Context, checkName strin
 		o.PrintInfo("   Category: %s", validator.Category())
 	}
 
-	result := validator.Validate(ctx, config)
+	result := runValidator(ctx, config, validator)
 
 	// Show immediate result
 	statusEmoji := getStatusEmoji(result.Status)
@@ -456,7 +608,7 @@ func (d *DoctorEngine) runValidatorsWithProgress(ctx context.Context, config *co
 			o.PrintInfo("   Category: %s", validator.Category())
 		}
 
-		result := validator.Validate(ctx, config)
+		result := runValidator(ctx, config, validator)
 		results = append(results, result)
 
 		// Show immediate result
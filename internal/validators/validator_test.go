@@ -0,0 +1,213 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validators
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/0xjuanma/anvil/internal/config"
+)
+
+// stubValidator is a minimal validator used to exercise dependency ordering
+type stubValidator struct {
+	name string
+	deps []string
+}
+
+func (v *stubValidator) Name() string        { return v.name }
+func (v *stubValidator) Category() string    { return "test" }
+func (v *stubValidator) Description() string { return v.name }
+func (v *stubValidator) CanFix() bool        { return true }
+func (v *stubValidator) DependsOn() []string { return v.deps }
+func (v *stubValidator) Validate(ctx context.Context, cfg *config.AnvilConfig) *ValidationResult {
+	return &ValidationResult{Name: v.name, Status: PASS}
+}
+func (v *stubValidator) Fix(ctx context.Context, cfg *config.AnvilConfig) error { return nil }
+
+func TestDependencyOrderDefaultValidators(t *testing.T) {
+	engine := NewDoctorEngine(nil)
+
+	order, err := engine.registry.DependencyOrder()
+	if err != nil {
+		t.Fatalf("DependencyOrder() failed: %v", err)
+	}
+	if len(order) != len(engine.registry.validators) {
+		t.Fatalf("DependencyOrder() returned %d checks, want %d", len(order), len(engine.registry.validators))
+	}
+
+	position := make(map[string]int)
+	for i, name := range order {
+		position[name] = i
+	}
+	for _, name := range order {
+		for _, dep := range engine.registry.dependenciesOf(name) {
+			if position[dep] > position[name] {
+				t.Errorf("%s is ordered before its dependency %s", name, dep)
+			}
+		}
+	}
+
+	if position["homebrew"] > position["required-tools"] {
+		t.Error("homebrew must be fixed before required-tools")
+	}
+	if position["init-run"] > position["settings-file"] {
+		t.Error("init-run must be fixed before settings-file")
+	}
+}
+
+func TestDependencyOrderCycle(t *testing.T) {
+	registry := NewValidationRegistry()
+	registry.Register(&stubValidator{name: "a", deps: []string{"b"}})
+	registry.Register(&stubValidator{name: "b", deps: []string{"a"}})
+
+	if _, err := registry.DependencyOrder(); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("expected cycle error, got %v", err)
+	}
+}
+
+func TestOrderFixes(t *testing.T) {
+	engine := &DoctorEngine{registry: NewValidationRegistry()}
+	engine.registry.Register(&stubValidator{name: "tools", deps: []string{"brew"}})
+	engine.registry.Register(&stubValidator{name: "git", deps: []string{"tools"}})
+	engine.registry.Register(&stubValidator{name: "brew"})
+
+	results := []*ValidationResult{{Name: "git"}, {Name: "unknown"}, {Name: "brew"}}
+	ordered, err := engine.OrderFixes(results)
+	if err != nil {
+		t.Fatalf("OrderFixes() failed: %v", err)
+	}
+
+	var names []string
+	for _, result := range ordered {
+		names = append(names, result.Name)
+	}
+	if got := strings.Join(names, ","); got != "brew,git,unknown" {
+		t.Errorf("OrderFixes() = %s, want brew,git,unknown", got)
+	}
+
+	if deps := strings.Join(engine.Dependencies("git"), ","); deps != "brew,tools" {
+		t.Errorf("Dependencies(git) = %s, want brew,tools", deps)
+	}
+}
+
+func TestBrewPolicyValidatorInvalidMirror(t *testing.T) {
+	cfg := &config.AnvilConfig{Brew: config.BrewConfig{BottleDomain: "mirror.internal/bottles"}}
+
+	result := (&BrewPolicyValidator{}).Validate(context.Background(), cfg)
+	if result.Status != FAIL || len(result.Details) != 1 || !strings.Contains(result.Details[0], "brew.bottle_domain") {
+		t.Errorf("Validate() = %+v, want a failure naming brew.bottle_domain", result)
+	}
+
+	if result := (&BrewPolicyValidator{}).Validate(context.Background(), &config.AnvilConfig{}); result.Status != PASS {
+		t.Errorf("Validate() without a policy = %v, want PASS", result.Status)
+	}
+}
+
+func TestNewReport(t *testing.T) {
+	results := []*ValidationResult{
+		{Name: "git-config", Category: "configuration", Status: WARN, AutoFix: true, Duration: 5 * time.Millisecond},
+		{Name: "brew", Category: "dependencies", Status: PASS, Duration: 20 * time.Millisecond},
+		{Name: "custom", Category: "extra", Status: SKIP},
+	}
+
+	report := NewReport(results, []string{"dependencies", "configuration"})
+	var names []string
+	for _, category := range report.Categories {
+		names = append(names, category.Name)
+	}
+	if got := strings.Join(names, ","); got != "dependencies,configuration,extra" {
+		t.Errorf("categories = %s, want dependencies,configuration,extra", got)
+	}
+
+	summary := report.Summary
+	if summary.Status != WARN || summary.ExitCode != ExitWarnings || summary.Fixable != 1 || summary.DurationMS != 25 {
+		t.Errorf("Summary = %+v, want warn, exit %d, 1 fixable, 25ms", summary, ExitWarnings)
+	}
+
+	results[1].Status = FAIL
+	if summary := Summarize(results); summary.ExitCode != ExitFailures {
+		t.Errorf("Summarize() exit code = %d, want %d", summary.ExitCode, ExitFailures)
+	}
+	if summary := Summarize(results[2:]); summary.ExitCode != ExitHealthy {
+		t.Errorf("Summarize(skipped) exit code = %d, want %d", summary.ExitCode, ExitHealthy)
+	}
+
+	data, err := json.Marshal(report.Categories[1].Checks[0])
+	if err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+	for _, want := range []string{`"status":"warn"`, `"fixable":true`, `"duration_ms":5`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("check JSON %s missing %s", data, want)
+		}
+	}
+}
+
+func TestSummaryLine(t *testing.T) {
+	tests := []struct {
+		statuses []ValidationStatus
+		want     string
+	}{
+		{[]ValidationStatus{PASS, SKIP}, "anvil ✓"},
+		{[]ValidationStatus{PASS, WARN}, "anvil ⚠ 1 issue"},
+		{[]ValidationStatus{FAIL, WARN, PASS}, "anvil ✗ 2 issues"},
+	}
+	for _, tt := range tests {
+		var results []*ValidationResult
+		for _, status := range tt.statuses {
+			results = append(results, &ValidationResult{Status: status})
+		}
+		if got := SummaryLine(results); got != tt.want {
+			t.Errorf("SummaryLine(%v) = %q, want %q", tt.statuses, got, tt.want)
+		}
+	}
+}
+
+func TestCachedResults(t *testing.T) {
+	dir := t.TempDir()
+	if _, found := LoadCachedResults(dir); found {
+		t.Fatal("expected no cached results in an empty directory")
+	}
+
+	SaveCachedResults(dir, []*ValidationResult{
+		{Name: "git-config", Category: "configuration", Status: WARN, Message: "user.email not set"},
+		{Name: "github-auth", Category: NetworkCategory, Status: FAIL},
+	})
+	cached, found := LoadCachedResults(dir)
+	if !found {
+		t.Fatal("expected cached results")
+	}
+	if time.Since(cached.Time) > time.Minute || len(cached.Results) != 2 {
+		t.Fatalf("cached = %+v", cached)
+	}
+	if cached.Results[0].Status != WARN || cached.Results[1].Status != FAIL {
+		t.Errorf("statuses = %v, %v, want WARN, FAIL", cached.Results[0].Status, cached.Results[1].Status)
+	}
+
+	// Offline runs keep the network results of the previous run only
+	merged := WithNetworkResults([]*ValidationResult{{Name: "git-config", Category: "configuration", Status: PASS}}, cached.Results)
+	if len(merged) != 2 || merged[1].Name != "github-auth" {
+		t.Errorf("merged = %v, want git-config and github-auth", merged)
+	}
+	if got := SummaryLine(merged); got != "anvil ✗ 1 issue" {
+		t.Errorf("SummaryLine(merged) = %q", got)
+	}
+}
@@ -16,6 +16,12 @@ limitations under the License.
 
 package version
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 // appVersion holds the application version set at build time
 var appVersion = "dev"
 
@@ -28,3 +34,81 @@ func SetVersion(v string) {
 func GetVersion() string {
 	return appVersion
 }
+
+// IsRelease reports whether the running binary carries a release version. Local and
+// development builds such as "dev-local" do not.
+func IsRelease() bool {
+	_, _, err := parse(appVersion)
+	return err == nil
+}
+
+// Validate checks that a version such as "1.4.0" or "v1.4.0-rc.1" can be compared
+func Validate(v string) error {
+	_, _, err := parse(v)
+	return err
+}
+
+// Compare returns -1, 0 or 1 when a is older than, equal to or newer than b.
+// Missing minor and patch numbers count as 0, and a prerelease is older than its release.
+func Compare(a, b string) (int, error) {
+	coreA, preA, err := parse(a)
+	if err != nil {
+		return 0, err
+	}
+	coreB, preB, err := parse(b)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range coreA {
+		if coreA[i] != coreB[i] {
+			if coreA[i] < coreB[i] {
+				return -1, nil
+			}
+			return 1, nil
+		}
+	}
+	switch {
+	case preA == preB:
+		return 0, nil
+	case preA == "":
+		return 1, nil
+	case preB == "":
+		return -1, nil
+	}
+	return strings.Compare(preA, preB), nil
+}
+
+// AtLeast reports whether the running binary is minimum or newer. Builds without a
+// release version cannot be compared and are assumed to be current.
+func AtLeast(minimum string) (bool, error) {
+	if err := Validate(minimum); err != nil {
+		return false, err
+	}
+	if !IsRelease() {
+		return true, nil
+	}
+	cmp, err := Compare(appVersion, minimum)
+	return cmp >= 0, err
+}
+
+// parse splits a version into its major, minor and patch numbers and its prerelease
+func parse(v string) ([3]int, string, error) {
+	var core [3]int
+	trimmed := strings.TrimPrefix(strings.TrimSpace(v), "v")
+	trimmed, _, _ = strings.Cut(trimmed, "+")
+	trimmed, prerelease, _ := strings.Cut(trimmed, "-")
+
+	parts := strings.Split(trimmed, ".")
+	if trimmed == "" || len(parts) > len(core) {
+		return core, "", fmt.Errorf("invalid version %q, expected a version such as 1.4.0", v)
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return core, "", fmt.Errorf("invalid version %q, expected a version such as 1.4.0", v)
+		}
+		core[i] = n
+	}
+	return core, prerelease, nil
+}
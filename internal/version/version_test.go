@@ -0,0 +1,67 @@
+/*
+Copyright © 2022 Juanma Roca juanmaxroca@gmail.com
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package version
+
+import "testing"
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.4.0", "1.4.0", 0},
+		{"v1.4.0", "1.4", 0},
+		{"1.4.1", "1.4.0", 1},
+		{"1.10.0", "1.9.9", 1},
+		{"1.4.0-rc.1", "1.4.0", -1},
+		{"1.4.0-rc.2", "1.4.0-rc.1", 1},
+		{"2", "1.99.99", 1},
+		{"1.4.0+build.7", "1.4.0", 0},
+	}
+	for _, tt := range tests {
+		got, err := Compare(tt.a, tt.b)
+		if err != nil || got != tt.want {
+			t.Errorf("Compare(%q, %q) = %d, %v, want %d", tt.a, tt.b, got, err, tt.want)
+		}
+	}
+
+	for _, invalid := range []string{"", "dev-local", "1.2.3.4", "1.x", "v"} {
+		if err := Validate(invalid); err == nil {
+			t.Errorf("Validate(%q) accepted an invalid version", invalid)
+		}
+	}
+}
+
+func TestAtLeast(t *testing.T) {
+	defer SetVersion(GetVersion())
+
+	SetVersion("v1.5.0")
+	if ok, err := AtLeast("1.5.0"); !ok || err != nil {
+		t.Errorf("AtLeast(1.5.0) on v1.5.0 = %v, %v", ok, err)
+	}
+	if ok, _ := AtLeast("1.6"); ok {
+		t.Error("AtLeast(1.6) on v1.5.0 = true")
+	}
+
+	SetVersion("dev-local")
+	if ok, err := AtLeast("9.0.0"); !ok || err != nil {
+		t.Errorf("AtLeast(9.0.0) on a development build = %v, %v, want true", ok, err)
+	}
+	if _, err := AtLeast("soon"); err == nil {
+		t.Error("AtLeast(soon) accepted an invalid minimum")
+	}
+}